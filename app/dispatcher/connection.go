@@ -0,0 +1,139 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+)
+
+type trackedConnection struct {
+	id         uint64
+	inboundTag string
+	source     net.Destination
+	start      time.Time
+
+	uplink   int64
+	downlink int64
+
+	access      sync.Mutex
+	outboundTag string
+	destination net.Destination
+}
+
+func (c *trackedConnection) setRoute(outboundTag string, destination net.Destination) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.outboundTag = outboundTag
+	c.destination = destination
+}
+
+func (c *trackedConnection) snapshot() *routing.Connection {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return &routing.Connection{
+		ID:          c.id,
+		InboundTag:  c.inboundTag,
+		OutboundTag: c.outboundTag,
+		Source:      c.source,
+		Destination: c.destination,
+		Uplink:      atomic.LoadInt64(&c.uplink),
+		Downlink:    atomic.LoadInt64(&c.downlink),
+		Start:       c.start,
+	}
+}
+
+// connectionRegistry keeps the live connections of a dispatcher. The zero
+// value is ready to use.
+type connectionRegistry struct {
+	access      sync.RWMutex
+	lastID      uint64
+	connections map[uint64]*trackedConnection
+}
+
+// track registers a connection to destination of the inbound in ctx, and
+// returns it with the function to remove it. The function can be called more
+// than once.
+func (r *connectionRegistry) track(ctx context.Context, destination net.Destination) (*trackedConnection, func()) {
+	c := &trackedConnection{
+		start:       time.Now(),
+		destination: destination,
+	}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		c.inboundTag = inbound.Tag
+		c.source = inbound.Source
+	}
+
+	r.access.Lock()
+	r.lastID++
+	c.id = r.lastID
+	if r.connections == nil {
+		r.connections = make(map[uint64]*trackedConnection)
+	}
+	r.connections[c.id] = c
+	r.access.Unlock()
+
+	return c, func() {
+		r.access.Lock()
+		delete(r.connections, c.id)
+		r.access.Unlock()
+	}
+}
+
+func (r *connectionRegistry) list() []*routing.Connection {
+	r.access.RLock()
+	connections := make([]*trackedConnection, 0, len(r.connections))
+	for _, c := range r.connections {
+		connections = append(connections, c)
+	}
+	r.access.RUnlock()
+
+	result := make([]*routing.Connection, 0, len(connections))
+	for _, c := range connections {
+		result = append(result, c.snapshot())
+	}
+	return result
+}
+
+type trackedConnectionKey struct{}
+
+func contextWithTrackedConnection(ctx context.Context, c *trackedConnection) context.Context {
+	return context.WithValue(ctx, trackedConnectionKey{}, c)
+}
+
+func trackedConnectionFromContext(ctx context.Context) *trackedConnection {
+	c, _ := ctx.Value(trackedConnectionKey{}).(*trackedConnection)
+	return c
+}
+
+// countingWriter counts the bytes written to a connection. Unlike
+// SizeStatWriter, it reports whether the underlying writer is a pipe, so that
+// tracking connections doesn't change how outbounds handle links.
+type countingWriter struct {
+	counter *int64
+	writer  buf.Writer
+}
+
+func (w *countingWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	atomic.AddInt64(w.counter, int64(mb.Len()))
+	return w.writer.WriteMultiBuffer(mb)
+}
+
+func (w *countingWriter) Close() error {
+	return common.Close(w.writer)
+}
+
+func (w *countingWriter) Interrupt() {
+	common.Interrupt(w.writer)
+}
+
+func (w *countingWriter) IsPipe() bool {
+	return pipe.IsPipe(w.writer)
+}
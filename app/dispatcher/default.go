@@ -31,14 +31,27 @@ import (
 
 var errSniffingTimeout = newError("timeout on sniffing")
 
+// defaultSniffingTimeout is the time to wait for content to sniff if the
+// inbound doesn't set one.
+const defaultSniffingTimeout = time.Millisecond * 200
+
+func sniffingTimeout(request session.SniffingRequest) time.Duration {
+	if request.Timeout > 0 {
+		return request.Timeout
+	}
+	return defaultSniffingTimeout
+}
+
 type cachedReader struct {
 	sync.Mutex
 	reader buf.TimeoutReader
 	cache  buf.MultiBuffer
 }
 
-func (r *cachedReader) Cache(b *buf.Buffer) {
-	mb, _ := r.reader.ReadMultiBufferTimeout(time.Millisecond * 100)
+// Cache reads the content arriving within timeout into the cache, and copies
+// the cache into b.
+func (r *cachedReader) Cache(b *buf.Buffer, timeout time.Duration) error {
+	mb, err := r.reader.ReadMultiBufferTimeout(timeout)
 	r.Lock()
 	if !mb.IsEmpty() {
 		r.cache, _ = buf.MergeMulti(r.cache, mb)
@@ -48,6 +61,7 @@ func (r *cachedReader) Cache(b *buf.Buffer) {
 	n := r.cache.Copy(rawBytes)
 	b.Resize(0, int32(n))
 	r.Unlock()
+	return err
 }
 
 func (r *cachedReader) readInternal() buf.MultiBuffer {
@@ -106,6 +120,12 @@ type DefaultDispatcher struct {
 	router routing.Router
 	policy policy.Manager
 	stats  stats.Manager
+
+	access      sync.Mutex
+	connections map[uint32]uint32
+
+	live     connectionRegistry
+	limiters rateLimiters
 }
 
 func init() {
@@ -134,6 +154,13 @@ func (*DefaultDispatcher) Type() interface{} {
 	return routing.DispatcherType()
 }
 
+// Connections implements routing.ConnectionTracker. Uplink traffic is not
+// counted for links passed into DispatchLink, and no traffic is counted for
+// connections passed into DispatchConn that outbounds handle as net.Conn.
+func (d *DefaultDispatcher) Connections() []*routing.Connection {
+	return d.live.list()
+}
+
 // Start implements common.Runnable.
 func (*DefaultDispatcher) Start() error {
 	return nil
@@ -163,9 +190,9 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 		user = sessionInbound.User
 	}
 
-	if user != nil && len(user.Email) > 0 {
+	if user != nil {
 		p := d.policy.ForLevel(user.Level)
-		if p.Stats.UserUplink {
+		if len(user.Email) > 0 && p.Stats.UserUplink {
 			name := "user>>>" + user.Email + ">>>traffic>>>uplink"
 			if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
 				inboundLink.Writer = &SizeStatWriter{
@@ -174,7 +201,7 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 				}
 			}
 		}
-		if p.Stats.UserDownlink {
+		if len(user.Email) > 0 && p.Stats.UserDownlink {
 			name := "user>>>" + user.Email + ">>>traffic>>>downlink"
 			if c, _ := stats.GetOrRegisterCounter(d.stats, name); c != nil {
 				outboundLink.Writer = &SizeStatWriter{
@@ -183,11 +210,75 @@ func (d *DefaultDispatcher) getLink(ctx context.Context) (*transport.Link, *tran
 				}
 			}
 		}
+		if p.Bandwidth.Uplink > 0 {
+			limiter := d.limiters.get(user, false, p.Bandwidth.Uplink, p.Bandwidth.UplinkBurst)
+			inboundLink.Writer = newRateLimitWriter(ctx, inboundLink.Writer, limiter)
+		}
+		if p.Bandwidth.Downlink > 0 {
+			limiter := d.limiters.get(user, true, p.Bandwidth.Downlink, p.Bandwidth.DownlinkBurst)
+			outboundLink.Writer = newRateLimitWriter(ctx, outboundLink.Writer, limiter)
+		}
 	}
 
 	return inboundLink, outboundLink
 }
 
+// markOnline records the user of the inbound in ctx, and its source IP, in
+// online counters, if enabled by the policy.
+func (d *DefaultDispatcher) markOnline(ctx context.Context) {
+	sessionInbound := session.InboundFromContext(ctx)
+	if sessionInbound == nil || sessionInbound.User == nil || len(sessionInbound.User.Email) == 0 {
+		return
+	}
+	user := sessionInbound.User
+	if !d.policy.ForLevel(user.Level).Stats.UserOnline {
+		return
+	}
+	if c, _ := stats.GetOrRegisterOnlineCounter(d.stats, "user>>>online", 0); c != nil {
+		c.Add(user.Email)
+	}
+	if sessionInbound.Source.IsValid() {
+		if c, _ := stats.GetOrRegisterOnlineCounter(d.stats, "user>>>"+user.Email+">>>online", 0); c != nil {
+			c.Add(sessionInbound.Source.Address.String())
+		}
+	}
+}
+
+// acquireConnection takes a connection slot of the user level in ctx, and
+// returns the function to release it.
+func (d *DefaultDispatcher) acquireConnection(ctx context.Context) (func(), error) {
+	sessionInbound := session.InboundFromContext(ctx)
+	if sessionInbound == nil || sessionInbound.User == nil {
+		return func() {}, nil
+	}
+	level := sessionInbound.User.Level
+	limit := d.policy.ForLevel(level).MaxConnections
+	if limit == 0 {
+		return func() {}, nil
+	}
+
+	d.access.Lock()
+	defer d.access.Unlock()
+	if d.connections == nil {
+		d.connections = make(map[uint32]uint32)
+	}
+	if d.connections[level] >= limit {
+		return nil, newError("too many connections of level ", level, ", limit: ", limit).AtWarning()
+	}
+	d.connections[level]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.access.Lock()
+			defer d.access.Unlock()
+			if d.connections[level]--; d.connections[level] == 0 {
+				delete(d.connections, level)
+			}
+		})
+	}, nil
+}
+
 func shouldOverride(result SniffResult, domainOverride []string) bool {
 	if result.Domain() == "" {
 		return false
@@ -214,12 +305,21 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 	if !destination.IsValid() {
 		panic("Dispatcher: Invalid destination.")
 	}
+	release, err := d.acquireConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.markOnline(ctx)
 	ob := &session.Outbound{
 		Target: destination,
 	}
 	ctx = session.ContextWithOutbound(ctx, ob)
+	tracked, untrack := d.live.track(ctx, destination)
+	ctx = contextWithTrackedConnection(ctx, tracked)
 
 	inbound, outbound := d.getLink(ctx)
+	inbound.Writer = &countingWriter{counter: &tracked.uplink, writer: inbound.Writer}
+	outbound.Writer = &countingWriter{counter: &tracked.downlink, writer: outbound.Writer}
 	content := session.ContentFromContext(ctx)
 	if content == nil {
 		content = new(session.Content)
@@ -227,25 +327,31 @@ func (d *DefaultDispatcher) Dispatch(ctx context.Context, destination net.Destin
 	}
 	sniffingRequest := content.SniffingRequest
 	sniffer := defaultSniffers
-	if content.Protocol != "" || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
-		go d.routedDispatch(ctx, outbound, destination)
+	if content.Protocol != "" || sniffingRequest.MetadataOnly || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
+		go func() {
+			defer release()
+			defer untrack()
+			d.routedDispatch(ctx, outbound, destination)
+		}()
 		return inbound, nil
 	}
 	if !sniffingRequest.Enabled {
 		sniffer = udpOnlyDnsSniffers
 	}
 	go func() {
+		defer release()
+		defer untrack()
 		cReader := &cachedReader{
 			reader: outbound.Reader.(buf.TimeoutReader),
 		}
 		outbound.Reader = cReader
-		result, err := sniff(ctx, cReader, destination.Network, sniffer)
+		result, err := sniff(ctx, cReader, destination.Network, sniffer, sniffingTimeout(sniffingRequest))
 		if err == nil {
 			content.Protocol = result.Protocol()
 		}
 		if err == nil && shouldOverride(result, sniffingRequest.OverrideDestinationForProtocol) {
 			domain := result.Domain()
-			newError("sniffed domain: ", domain).WriteToLog(session.ExportIDToError(ctx))
+			newError("sniffed domain: ", domain).WriteToLog(session.ExportContextToError(ctx))
 			destination.Address = net.ParseAddress(domain)
 			if sniffingRequest.RouteOnly {
 				ob.RouteTarget = destination
@@ -264,11 +370,21 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	if !destination.IsValid() {
 		return newError("Dispatcher: Invalid destination.")
 	}
+	release, err := d.acquireConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	d.markOnline(ctx)
 	newError("dispatch link to ", destination).AtDebug().WriteToLog()
 	ob := &session.Outbound{
 		Target: destination,
 	}
 	ctx = session.ContextWithOutbound(ctx, ob)
+	tracked, untrack := d.live.track(ctx, destination)
+	defer untrack()
+	ctx = contextWithTrackedConnection(ctx, tracked)
+	outbound.Writer = &countingWriter{counter: &tracked.downlink, writer: outbound.Writer}
 	content := session.ContentFromContext(ctx)
 	if content == nil {
 		content = new(session.Content)
@@ -288,7 +404,7 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 	sniffingRequest := content.SniffingRequest
 
 	sniffer := defaultSniffers
-	if content.Protocol != "" || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
+	if content.Protocol != "" || sniffingRequest.MetadataOnly || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
 		d.routedDispatch(ctx, outbound, destination)
 		return nil
 	}
@@ -299,13 +415,13 @@ func (d *DefaultDispatcher) DispatchLink(ctx context.Context, destination net.De
 		reader: outbound.Reader.(buf.TimeoutReader),
 	}
 	outbound.Reader = cReader
-	result, err := sniff(ctx, cReader, destination.Network, sniffer)
+	result, err := sniff(ctx, cReader, destination.Network, sniffer, sniffingTimeout(sniffingRequest))
 	if err == nil {
 		content.Protocol = result.Protocol()
 	}
 	if err == nil && shouldOverride(result, sniffingRequest.OverrideDestinationForProtocol) {
 		domain := result.Domain()
-		newError("sniffed domain: ", domain).WriteToLog(session.ExportIDToError(ctx))
+		newError("sniffed domain: ", domain).WriteToLog(session.ExportContextToError(ctx))
 		destination.Address = net.ParseAddress(domain)
 		if sniffingRequest.RouteOnly {
 			ob.RouteTarget = destination
@@ -323,8 +439,10 @@ var defaultSniffers = &Sniffer{
 		{func(c context.Context, b []byte) (SniffResult, error) { return tls.SniffTLS(b) }, false, net.Network_TCP},
 		{func(c context.Context, b []byte) (SniffResult, error) { return quic.SniffQUIC(b) }, false, net.Network_UDP},
 		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffBittorrent(b) }, false, net.Network_TCP},
-		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffUTP(b) }, false, net.Network_UDP},
 		{func(c context.Context, b []byte) (SniffResult, error) { return dns.SniffDNS(b) }, false, net.Network_UDP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffUTP(b) }, false, net.Network_UDP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffUDPTracker(b) }, false, net.Network_UDP},
+		{func(c context.Context, b []byte) (SniffResult, error) { return bittorrent.SniffDHT(b) }, false, net.Network_UDP},
 		{func(c context.Context, b []byte) (SniffResult, error) { return dns.SniffTCPDNS(b) }, false, net.Network_TCP},
 	},
 }
@@ -335,36 +453,40 @@ var udpOnlyDnsSniffers = &Sniffer{
 	},
 }
 
-func sniff(ctx context.Context, cReader *cachedReader, network net.Network, sniffer *Sniffer) (SniffResult, error) {
-	payload := buf.New()
+// sniff sniffs the content read from cReader within timeout. The content is
+// kept in cReader to be forwarded afterwards, whether sniffing succeeds or not.
+func sniff(ctx context.Context, cReader *cachedReader, network net.Network, sniffer *Sniffer, timeout time.Duration) (SniffResult, error) {
+	sniffer = sniffer.fork()
+	payload := buf.NewSize(buf.Size)
 	defer payload.Release()
 
-	contentResult, contentErr := func() (SniffResult, error) {
-		totalAttempt := 0
-		for {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			default:
-				totalAttempt++
-				if totalAttempt > 2 {
-					return nil, errSniffingTimeout
-				}
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
 
-				cReader.Cache(payload)
-				if !payload.IsEmpty() {
-					result, err := sniffer.Sniff(ctx, payload.Bytes(), network)
-					if err != common.ErrNoClue {
-						return result, err
-					}
-				}
-				if payload.IsFull() {
-					return nil, errUnknownContent
-				}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errSniffingTimeout
+		}
+		err := cReader.Cache(payload, remaining)
+		if !payload.IsEmpty() {
+			result, err := sniffer.Sniff(ctx, payload.Bytes(), network)
+			if err != common.ErrNoClue {
+				return result, err
 			}
 		}
-	}()
-	return contentResult, contentErr
+		if payload.IsFull() {
+			return nil, errUnknownContent
+		}
+		if err != nil && err != buf.ErrReadTimeout {
+			// No more content will arrive.
+			return nil, errUnknownContent
+		}
+	}
 }
 
 func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.Link, destination net.Destination) {
@@ -373,10 +495,10 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
 		if h := d.ohm.GetHandler(forcedOutboundTag); h != nil {
-			newError("taking platform initialized detour [", forcedOutboundTag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
+			newError("taking platform initialized detour [", forcedOutboundTag, "] for [", destination, "]").WriteToLog(session.ExportContextToError(ctx))
 			handler = h
 		} else {
-			newError("non existing tag for platform initialized detour: ", forcedOutboundTag).AtError().WriteToLog(session.ExportIDToError(ctx))
+			newError("non existing tag for platform initialized detour: ", forcedOutboundTag).AtError().WriteToLog(session.ExportContextToError(ctx))
 			common.Close(link.Writer)
 			common.Interrupt(link.Reader)
 			return
@@ -385,13 +507,13 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 		if route, err := d.router.PickRoute(routing_session.AsRoutingContext(ctx)); err == nil {
 			tag := route.GetOutboundTag()
 			if h := d.ohm.GetHandler(tag); h != nil {
-				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
+				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportContextToError(ctx))
 				handler = h
 			} else {
-				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportContextToError(ctx))
 			}
 		} else {
-			newError("default route for ", destination).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+			newError("default route for ", destination).AtWarning().WriteToLog(session.ExportContextToError(ctx))
 		}
 	}
 
@@ -400,7 +522,7 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 	}
 
 	if handler == nil {
-		newError("default outbound handler not exist").WriteToLog(session.ExportIDToError(ctx))
+		newError("default outbound handler not exist").WriteToLog(session.ExportContextToError(ctx))
 		common.Close(link.Writer)
 		common.Interrupt(link.Reader)
 		return
@@ -410,8 +532,13 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 		if tag := handler.Tag(); tag != "" {
 			accessMessage.Detour = tag
 		}
+		accessMessage.SessionID = uint32(session.IDFromContext(ctx))
 		log.Record(accessMessage)
 	}
 
+	if tracked := trackedConnectionFromContext(ctx); tracked != nil {
+		tracked.setRoute(handler.Tag(), destination)
+	}
+
 	handler.Dispatch(ctx, link)
 }
@@ -0,0 +1,544 @@
+package dispatcher_test
+
+import (
+	"context"
+	"io"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/v2fly/v2ray-core/v5/app/dispatcher"
+	"github.com/v2fly/v2ray-core/v5/app/policy"
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/testing/mocks"
+	"github.com/v2fly/v2ray-core/v5/transport"
+)
+
+// blockingHandler holds each connection until its context is done.
+type blockingHandler struct{}
+
+func (blockingHandler) Start() error { return nil }
+func (blockingHandler) Close() error { return nil }
+func (blockingHandler) Tag() string  { return "" }
+
+func (blockingHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	<-ctx.Done()
+	common.Close(link.Writer)
+	common.Interrupt(link.Reader)
+}
+
+func TestMaxConnections(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(blockingHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			1: {MaxConnections: 2},
+		},
+	})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, nil))
+
+	userContext := func(level uint32) (context.Context, context.CancelFunc) {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			User: &protocol.MemoryUser{Level: level},
+		})
+		return context.WithCancel(ctx)
+	}
+	destination := net.TCPDestination(net.DomainAddress("v2fly.org"), 443)
+
+	var cancels []context.CancelFunc
+	for i := 0; i < 2; i++ {
+		ctx, cancel := userContext(1)
+		defer cancel()
+		cancels = append(cancels, cancel)
+		if _, err := d.Dispatch(ctx, destination); err != nil {
+			t.Fatal("unexpected error dispatching connection ", i, ": ", err)
+		}
+	}
+
+	ctx, cancel := userContext(1)
+	defer cancel()
+	if _, err := d.Dispatch(ctx, destination); err == nil {
+		t.Error("expected the connection beyond the limit to be rejected")
+	}
+
+	// Other levels are not limited.
+	for i := 0; i < 3; i++ {
+		ctx, cancel := userContext(0)
+		defer cancel()
+		if _, err := d.Dispatch(ctx, destination); err != nil {
+			t.Error("unexpected error dispatching connection of level 0: ", err)
+		}
+	}
+
+	// Closing a connection frees a slot.
+	cancels[0]()
+	deadline := time.Now().Add(time.Second)
+	for {
+		ctx, cancel := userContext(1)
+		defer cancel()
+		if _, err = d.Dispatch(ctx, destination); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Error("expected a slot after closing a connection, but got ", err)
+	}
+}
+
+// echoHandler echoes the uplink of each connection back until it is closed.
+type echoHandler struct{}
+
+func (echoHandler) Start() error { return nil }
+func (echoHandler) Close() error { return nil }
+func (echoHandler) Tag() string  { return "echo" }
+
+func (echoHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	buf.Copy(link.Reader, link.Writer)
+	common.Close(link.Writer)
+}
+
+func TestConnections(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(echoHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, nil))
+
+	source := net.TCPDestination(net.LocalHostIP, 10000)
+	destinations := []net.Destination{
+		net.TCPDestination(net.DomainAddress("v2fly.org"), 443),
+		net.TCPDestination(net.DomainAddress("example.com"), 80),
+	}
+	var links []*transport.Link
+	for _, destination := range destinations {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			Tag:    "in",
+			Source: source,
+		})
+		link, err := d.Dispatch(ctx, destination)
+		common.Must(err)
+		links = append(links, link)
+	}
+
+	common.Must(links[0].Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("hello"))))
+	mb, err := links[0].Reader.ReadMultiBuffer()
+	common.Must(err)
+	buf.ReleaseMulti(mb)
+
+	connections := d.Connections()
+	if len(connections) != 2 {
+		t.Fatal("expected 2 connections, but got ", len(connections))
+	}
+	byDestination := make(map[net.Destination]*routing.Connection)
+	for _, c := range connections {
+		byDestination[c.Destination] = c
+		if c.InboundTag != "in" || c.Source != source || c.Start.IsZero() {
+			t.Error("unexpected connection ", c)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		c := byDestination[destinations[1]]
+		if c != nil && c.OutboundTag == "echo" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+		for _, c := range d.Connections() {
+			byDestination[c.Destination] = c
+		}
+	}
+	if c := byDestination[destinations[0]]; c == nil || c.OutboundTag != "echo" || c.Uplink != 5 || c.Downlink != 5 {
+		t.Error("unexpected connection to ", destinations[0], ": ", c)
+	}
+	if c := byDestination[destinations[1]]; c == nil || c.OutboundTag != "echo" || c.Uplink != 0 {
+		t.Error("unexpected connection to ", destinations[1], ": ", c)
+	}
+
+	// Connections disappear after they are closed.
+	for i, link := range links {
+		common.Close(link.Writer)
+		deadline := time.Now().Add(time.Second)
+		for len(d.Connections()) != len(links)-i-1 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := len(d.Connections()); n != len(links)-i-1 {
+			t.Error("expected ", len(links)-i-1, " connections after closing ", i+1, ", but got ", n)
+		}
+	}
+}
+
+// notifyingHandler reports when each connection is routed to it, then echoes
+// its uplink back.
+type notifyingHandler struct {
+	routed chan net.Destination
+}
+
+func (notifyingHandler) Start() error { return nil }
+func (notifyingHandler) Close() error { return nil }
+func (notifyingHandler) Tag() string  { return "notify" }
+
+func (h notifyingHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	h.routed <- session.OutboundFromContext(ctx).Target
+	buf.Copy(link.Reader, link.Writer)
+	common.Close(link.Writer)
+}
+
+func TestSniffingTimeout(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	handler := notifyingHandler{routed: make(chan net.Destination, 1)}
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(handler).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, nil))
+
+	destination := net.TCPDestination(net.LocalHostIP, 443)
+	dispatch := func(request session.SniffingRequest) *transport.Link {
+		ctx := session.ContextWithContent(context.Background(), &session.Content{SniffingRequest: request})
+		link, err := d.Dispatch(ctx, destination)
+		common.Must(err)
+		return link
+	}
+	readAll := func(link *transport.Link, n int32) string {
+		var mb buf.MultiBuffer
+		for mb.Len() < n {
+			b, err := link.Reader.ReadMultiBuffer()
+			common.Must(err)
+			mb = append(mb, b...)
+		}
+		defer buf.ReleaseMulti(mb)
+		return mb.String()
+	}
+
+	t.Run("slow client", func(t *testing.T) {
+		const timeout = 300 * time.Millisecond
+		link := dispatch(session.SniffingRequest{
+			Enabled:                        true,
+			OverrideDestinationForProtocol: []string{"tls"},
+			Timeout:                        timeout,
+		})
+		defer common.Close(link.Writer)
+
+		// The beginning of a TLS record, which isn't enough to sniff.
+		start := time.Now()
+		partial := "\x16\x03\x01\x02\x00\x01"
+		common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte(partial))))
+
+		select {
+		case target := <-handler.routed:
+			if elapsed := time.Since(start); elapsed < timeout {
+				t.Error("routed after ", elapsed, ", before the sniffing timeout")
+			}
+			if target != destination {
+				t.Error("expected target ", destination, ", but got ", target)
+			}
+		case <-time.After(timeout * 5):
+			t.Fatal("connection not routed after the sniffing timeout")
+		}
+
+		// The content read while sniffing is forwarded, followed by the rest.
+		common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("rest"))))
+		if s := readAll(link, int32(len(partial)+4)); s != partial+"rest" {
+			t.Errorf("unexpected content forwarded: %q", s)
+		}
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		link := dispatch(session.SniffingRequest{
+			Enabled:      true,
+			MetadataOnly: true,
+			Timeout:      time.Minute,
+		})
+		defer common.Close(link.Writer)
+
+		// The connection is routed without waiting for any content.
+		select {
+		case <-handler.routed:
+		case <-time.After(time.Second * 5):
+			t.Fatal("connection not routed without content")
+		}
+		common.Must(link.Writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("hello"))))
+		if s := readAll(link, 5); s != "hello" {
+			t.Error("unexpected content forwarded: ", s)
+		}
+	})
+}
+
+func TestDispatchConnUserStats(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(echoHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {Stats: &policy.Policy_Stats{UserUplink: true, UserDownlink: true}},
+		},
+	})
+	common.Must(err)
+	sm, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, sm))
+
+	client, server := gonet.Pipe()
+	defer client.Close()
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		User: &protocol.MemoryUser{Email: "love@v2fly.org"},
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- d.DispatchConn(ctx, net.TCPDestination(net.DomainAddress("v2fly.org"), 443), server, true)
+	}()
+
+	common.Must2(client.Write([]byte("hello")))
+	b := make([]byte, 5)
+	common.Must2(client.Read(b))
+	common.Must(client.Close())
+	common.Must(<-done)
+
+	for _, name := range []string{
+		"user>>>love@v2fly.org>>>traffic>>>uplink",
+		"user>>>love@v2fly.org>>>traffic>>>downlink",
+	} {
+		if c := sm.GetCounter(name); c == nil || c.Value() != 5 {
+			t.Error("expected 5 bytes in ", name, ", but got ", c)
+		}
+	}
+}
+
+func TestDispatchConnMaxConnections(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(echoHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {MaxConnections: 1},
+		},
+	})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, nil))
+
+	// dispatch relays a new connection of a user, and returns the client
+	// side of it.
+	dispatch := func() gonet.Conn {
+		client, server := gonet.Pipe()
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			User: &protocol.MemoryUser{Email: "love@v2fly.org"},
+		})
+		common.Must(d.DispatchConn(ctx, net.TCPDestination(net.DomainAddress("v2fly.org"), 443), server, false))
+		return client
+	}
+	echo := func(conn gonet.Conn) error {
+		common.Must(conn.SetDeadline(time.Now().Add(time.Second)))
+		if _, err := conn.Write([]byte("hello")); err != nil {
+			return err
+		}
+		b := make([]byte, 5)
+		_, err := conn.Read(b)
+		return err
+	}
+
+	first := dispatch()
+	if err := echo(first); err != nil {
+		t.Fatal("unexpected error relaying the first connection: ", err)
+	}
+
+	second := dispatch()
+	defer second.Close()
+	if err := echo(second); err == nil {
+		t.Error("expected the connection beyond the limit to be rejected")
+	}
+
+	// Closing a connection ends its relay and frees the slot.
+	common.Must(first.Close())
+	deadline := time.Now().Add(time.Second)
+	for {
+		third := dispatch()
+		err = echo(third)
+		third.Close()
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Error("expected a slot after closing a connection, but got ", err)
+	}
+}
+
+func TestDispatchUserOnline(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(echoHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {Stats: &policy.Policy_Stats{UserOnline: true}},
+		},
+	})
+	common.Must(err)
+	sm, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, sm))
+
+	for _, inbound := range []struct {
+		email  string
+		source net.Address
+	}{
+		{email: "love@v2fly.org", source: net.ParseAddress("10.0.0.1")},
+		{email: "love@v2fly.org", source: net.ParseAddress("10.0.0.2")},
+		{email: "love@v2fly.org", source: net.ParseAddress("10.0.0.1")},
+		{email: "hello@v2fly.org", source: net.ParseAddress("10.0.0.3")},
+	} {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			Source: net.TCPDestination(inbound.source, 10000),
+			User:   &protocol.MemoryUser{Email: inbound.email},
+		})
+		link, err := d.Dispatch(ctx, net.TCPDestination(net.DomainAddress("v2fly.org"), 443))
+		common.Must(err)
+		common.Interrupt(link.Writer)
+	}
+
+	for name, count := range map[string]int64{
+		"user>>>online":                   2,
+		"user>>>love@v2fly.org>>>online":  2,
+		"user>>>hello@v2fly.org>>>online": 1,
+	} {
+		if c := sm.GetOnlineCounter(name); c == nil || c.Count() != count {
+			t.Error("expected ", count, " in ", name, ", but got ", c)
+		}
+	}
+}
+
+func TestDispatchSharesUserBandwidth(t *testing.T) {
+	const limit = 64 * 1024
+	const burst = 16 * 1024
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(blockingHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {Bandwidth: &policy.Policy_Bandwidth{Uplink: limit, UplinkBurst: burst}},
+		},
+	})
+	common.Must(err)
+	sm, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, sm))
+
+	// write sends one burst through a new connection of the user with
+	// email, and returns how long it took.
+	write := func(email string) time.Duration {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			User: &protocol.MemoryUser{Email: email},
+		})
+		link, err := d.Dispatch(ctx, net.TCPDestination(net.DomainAddress("v2fly.org"), 443))
+		common.Must(err)
+		defer common.Interrupt(link.Writer)
+
+		start := time.Now()
+		common.Must(link.Writer.WriteMultiBuffer(newMultiBuffer(burst)))
+		return time.Since(start)
+	}
+
+	write("love@v2fly.org")
+	if elapsed := write("hello@v2fly.org"); elapsed > time.Second/8 {
+		t.Error("expected another user to have their own limit, but writing took ", elapsed)
+	}
+	// The first connection used up the burst of the user.
+	expected := time.Duration(burst) * time.Second / limit
+	if elapsed := write("love@v2fly.org"); elapsed < expected*9/10 {
+		t.Error("expected the connections of a user to share the limit, but writing took ", elapsed)
+	}
+}
+
+func TestDispatchConnSharesUserBandwidth(t *testing.T) {
+	const limit = 64 * 1024
+	const burst = 16 * 1024
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockOhm := mocks.NewOutboundManager(mockCtl)
+	mockOhm.EXPECT().GetDefaultHandler().Return(echoHandler{}).AnyTimes()
+
+	pm, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			0: {Bandwidth: &policy.Policy_Bandwidth{Uplink: limit, UplinkBurst: burst}},
+		},
+	})
+	common.Must(err)
+
+	d := new(DefaultDispatcher)
+	common.Must(d.Init(&Config{}, mockOhm, nil, pm, nil))
+
+	// echo sends one burst through a new connection of the user with
+	// email, and returns how long it took to be echoed back.
+	echo := func(email string) time.Duration {
+		client, server := gonet.Pipe()
+		defer client.Close()
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+			User: &protocol.MemoryUser{Email: email},
+		})
+		common.Must(d.DispatchConn(ctx, net.TCPDestination(net.DomainAddress("v2fly.org"), 443), server, false))
+
+		start := time.Now()
+		go client.Write(make([]byte, burst))
+		common.Must2(io.ReadFull(client, make([]byte, burst)))
+		return time.Since(start)
+	}
+
+	echo("love@v2fly.org")
+	if elapsed := echo("hello@v2fly.org"); elapsed > time.Second/8 {
+		t.Error("expected another user to have their own limit, but echoing took ", elapsed)
+	}
+	// The first connection used up the burst of the user.
+	expected := time.Duration(burst) * time.Second / limit
+	if elapsed := echo("love@v2fly.org"); elapsed < expected*9/10 {
+		t.Error("expected the connections of a user to share the limit, but echoing took ", elapsed)
+	}
+}
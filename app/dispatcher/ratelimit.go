@@ -0,0 +1,195 @@
+package dispatcher
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitWriter limits the throughput of the underlying Writer by a token
+// bucket.
+type RateLimitWriter struct {
+	Limiter *rate.Limiter
+	Writer  buf.Writer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRateLimitWriter creates a RateLimitWriter that writes at most limit
+// bytes per second, allowing bursts of burst bytes. burst defaults to limit
+// if it's 0. Pending writes are aborted when ctx is done or the writer is
+// closed.
+func NewRateLimitWriter(ctx context.Context, writer buf.Writer, limit uint64, burst uint64) *RateLimitWriter {
+	return newRateLimitWriter(ctx, writer, rate.NewLimiter(rate.Limit(limit), burstOf(limit, burst)))
+}
+
+func newRateLimitWriter(ctx context.Context, writer buf.Writer, limiter *rate.Limiter) *RateLimitWriter {
+	ctx, cancel := context.WithCancel(ctx)
+	return &RateLimitWriter{
+		Limiter: limiter,
+		Writer:  writer,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+type rateLimiterKey struct {
+	email    string
+	level    uint32
+	downlink bool
+}
+
+// rateLimiters holds the token buckets shared by all links of a user, or of
+// a level for users without an email.
+type rateLimiters struct {
+	sync.Mutex
+	limiters map[rateLimiterKey]*rate.Limiter
+}
+
+// get returns the limiter of user in the given direction, updated to limit
+// and burst in case the policy has changed since it was created.
+func (l *rateLimiters) get(user *protocol.MemoryUser, downlink bool, limit uint64, burst uint64) *rate.Limiter {
+	key := rateLimiterKey{downlink: downlink}
+	if len(user.Email) > 0 {
+		key.email = user.Email
+	} else {
+		key.level = user.Level
+	}
+	b := burstOf(limit, burst)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if limiter, found := l.limiters[key]; found {
+		if limiter.Limit() != rate.Limit(limit) {
+			limiter.SetLimit(rate.Limit(limit))
+		}
+		if limiter.Burst() != b {
+			limiter.SetBurst(b)
+		}
+		return limiter
+	}
+	if l.limiters == nil {
+		l.limiters = make(map[rateLimiterKey]*rate.Limiter)
+	}
+	limiter := rate.NewLimiter(rate.Limit(limit), b)
+	l.limiters[key] = limiter
+	return limiter
+}
+
+// burstOf returns the burst of a limiter of limit bytes per second. burst
+// defaults to limit, and is capped to fit in an int.
+func burstOf(limit uint64, burst uint64) int {
+	if burst == 0 {
+		burst = limit
+	}
+	if burst == 0 {
+		return 1
+	}
+	if burst > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(burst)
+}
+
+// waitN waits until n bytes are allowed by limiter, in chunks of at most its
+// burst. The burst is read for each chunk, as limiters shared by a user are
+// updated when the policy changes.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	for n > 0 {
+		tokens := limiter.Burst()
+		if tokens > n {
+			tokens = n
+		}
+		if tokens < 1 {
+			tokens = 1
+		}
+		if err := limiter.WaitN(ctx, tokens); err != nil {
+			if ctx.Err() == nil && tokens > limiter.Burst() && limiter.Burst() > 0 {
+				// The burst shrank after it was read.
+				continue
+			}
+			return err
+		}
+		n -= tokens
+	}
+	return nil
+}
+
+// WriteMultiBuffer implements buf.Writer.
+func (w *RateLimitWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	if err := waitN(w.ctx, w.Limiter, int(mb.Len())); err != nil {
+		buf.ReleaseMulti(mb)
+		return newError("failed to wait for bandwidth").Base(err)
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// Close implements common.Closable.
+func (w *RateLimitWriter) Close() error {
+	w.cancel()
+	return common.Close(w.Writer)
+}
+
+// Interrupt implements common.Interruptible.
+func (w *RateLimitWriter) Interrupt() {
+	w.cancel()
+	common.Interrupt(w.Writer)
+}
+
+// rateLimitConn limits the reads of a net.Conn by the uplink limiter, and
+// the writes by the downlink limiter. Either of them may be nil.
+type rateLimitConn struct {
+	net.Conn
+
+	uplink   *rate.Limiter
+	downlink *rate.Limiter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newRateLimitConn(ctx context.Context, conn net.Conn, uplink, downlink *rate.Limiter) *rateLimitConn {
+	ctx, cancel := context.WithCancel(ctx)
+	return &rateLimitConn{
+		Conn:     conn,
+		uplink:   uplink,
+		downlink: downlink,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Read implements net.Conn.
+func (c *rateLimitConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && c.uplink != nil {
+		if err := waitN(c.ctx, c.uplink, n); err != nil {
+			return n, newError("failed to wait for bandwidth").Base(err)
+		}
+	}
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *rateLimitConn) Write(b []byte) (int, error) {
+	if c.downlink != nil {
+		if err := waitN(c.ctx, c.downlink, len(b)); err != nil {
+			return 0, newError("failed to wait for bandwidth").Base(err)
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+// Close implements net.Conn.
+func (c *rateLimitConn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
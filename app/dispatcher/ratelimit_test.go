@@ -0,0 +1,131 @@
+package dispatcher_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/v2fly/v2ray-core/v5/app/dispatcher"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+)
+
+type discardWriter struct {
+	n int32
+}
+
+func (w *discardWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.n += mb.Len()
+	buf.ReleaseMulti(mb)
+	return nil
+}
+
+func newMultiBuffer(size int32) buf.MultiBuffer {
+	var mb buf.MultiBuffer
+	for size > 0 {
+		b := buf.New()
+		n := size
+		if n > buf.Size {
+			n = buf.Size
+		}
+		b.Extend(n)
+		mb = append(mb, b)
+		size -= n
+	}
+	return mb
+}
+
+func TestRateLimitWriter(t *testing.T) {
+	const limit = 256 * 1024
+	const burst = 32 * 1024
+	const total = 160 * 1024
+
+	var w discardWriter
+	writer := NewRateLimitWriter(context.Background(), &w, limit, burst)
+
+	start := time.Now()
+	for written := 0; written < total; written += 16 * 1024 {
+		common.Must(writer.WriteMultiBuffer(newMultiBuffer(16 * 1024)))
+	}
+	elapsed := time.Since(start)
+
+	if w.n != total {
+		t.Error("expected ", total, " bytes written, but got ", w.n)
+	}
+	// The first burst goes through at once, and the rest is limited.
+	expected := time.Duration(total-burst) * time.Second / limit
+	if elapsed < expected*9/10 {
+		t.Error("expected the throughput to be limited to ", limit, " bytes per second, but writing took ", elapsed)
+	}
+	if elapsed > expected*3 {
+		t.Error("writing is too slow: ", elapsed)
+	}
+}
+
+func TestRateLimitWriterLargeBuffer(t *testing.T) {
+	var w discardWriter
+	writer := NewRateLimitWriter(context.Background(), &w, 64*1024, 0)
+	limited := NewRateLimitWriter(context.Background(), &w, 1024*1024, 4*1024)
+
+	// A write larger than the burst must not fail.
+	common.Must(writer.WriteMultiBuffer(newMultiBuffer(32 * 1024)))
+	common.Must(limited.WriteMultiBuffer(newMultiBuffer(32 * 1024)))
+	if w.n != 64*1024 {
+		t.Error("expected ", 64*1024, " bytes written, but got ", w.n)
+	}
+}
+
+func TestRateLimitWriterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var w discardWriter
+	writer := NewRateLimitWriter(ctx, &w, 1024, 1024)
+	common.Must(writer.WriteMultiBuffer(newMultiBuffer(1024)))
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	if err := writer.WriteMultiBuffer(newMultiBuffer(8 * 1024)); err == nil {
+		t.Error("expected error after the context is canceled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("expected the write to be aborted on cancel, but it took ", elapsed)
+	}
+	if w.n != 1024 {
+		t.Error("unexpected bytes written after cancel: ", w.n)
+	}
+
+	writer = NewRateLimitWriter(context.Background(), &w, 1024, 1024)
+	common.Must(writer.WriteMultiBuffer(newMultiBuffer(1024)))
+	time.AfterFunc(100*time.Millisecond, writer.Interrupt)
+	if err := writer.WriteMultiBuffer(newMultiBuffer(8 * 1024)); err == nil {
+		t.Error("expected error after the writer is interrupted")
+	}
+}
+
+func TestRateLimitWriterBurstChange(t *testing.T) {
+	const limit = 64 * 1024
+	const burst = 32 * 1024
+
+	var w discardWriter
+	writer := NewRateLimitWriter(context.Background(), &w, limit, burst)
+
+	// The burst shrinks while the second chunk of the write is waiting.
+	time.AfterFunc(100*time.Millisecond, func() {
+		writer.Limiter.SetBurst(burst / 4)
+	})
+	if err := writer.WriteMultiBuffer(newMultiBuffer(3 * burst)); err != nil {
+		t.Fatal("unexpected error after the burst is changed: ", err)
+	}
+	if w.n != 3*burst {
+		t.Error("expected ", 3*burst, " bytes written, but got ", w.n)
+	}
+}
+
+func TestRateLimitWriterHugeBurst(t *testing.T) {
+	var w discardWriter
+	writer := NewRateLimitWriter(context.Background(), &w, 1024, math.MaxUint64)
+	if burst := writer.Limiter.Burst(); burst <= 0 {
+		t.Fatal("expected a positive burst, but got ", burst)
+	}
+	common.Must(writer.WriteMultiBuffer(newMultiBuffer(64 * 1024)))
+}
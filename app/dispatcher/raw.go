@@ -14,7 +14,10 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"golang.org/x/time/rate"
 )
 
 func (d *DefaultDispatcher) DispatchConn(ctx context.Context, destination net.Destination, conn net.Conn, wait bool) error {
@@ -33,7 +36,7 @@ func (d *DefaultDispatcher) DispatchConn(ctx context.Context, destination net.De
 	}
 	sniffingRequest := content.SniffingRequest
 
-	if content.Protocol != "" || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
+	if content.Protocol != "" || sniffingRequest.MetadataOnly || !sniffingRequest.Enabled && destination.Network != net.Network_UDP {
 		d.routedDispatchConn(ctx, conn, destination, wait)
 		return nil
 	}
@@ -42,7 +45,7 @@ func (d *DefaultDispatcher) DispatchConn(ctx context.Context, destination net.De
 		sniffer = udpOnlyDnsSniffers
 	}
 
-	err := conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	err := conn.SetReadDeadline(time.Now().Add(sniffingTimeout(sniffingRequest)))
 	if err != nil {
 		d.routedDispatchConn(ctx, conn, destination, wait)
 		return nil
@@ -77,7 +80,7 @@ func (d *DefaultDispatcher) DispatchConn(ctx context.Context, destination net.De
 
 	conn = bufio.NewCachedConn(conn, header)
 
-	result, err := sniffer.Sniff(ctx, header.Bytes(), net.Network_TCP)
+	result, err := sniffer.fork().Sniff(ctx, header.Bytes(), net.Network_TCP)
 	if err != nil {
 		d.routedDispatchConn(ctx, conn, destination, wait)
 		return nil
@@ -86,7 +89,7 @@ func (d *DefaultDispatcher) DispatchConn(ctx context.Context, destination net.De
 	content.Protocol = result.Protocol()
 	if shouldOverride(result, sniffingRequest.OverrideDestinationForProtocol) {
 		domain := result.Domain()
-		newError("sniffed domain: ", domain).WriteToLog(session.ExportIDToError(ctx))
+		newError("sniffed domain: ", domain).WriteToLog(session.ExportContextToError(ctx))
 		destination.Address = net.ParseAddress(domain)
 		if sniffingRequest.RouteOnly {
 			ob.RouteTarget = destination
@@ -108,15 +111,28 @@ func (d *DefaultDispatcher) routedDispatchConn(ctx context.Context, conn net.Con
 }
 
 func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Conn, destination net.Destination) {
+	release, err := d.acquireConnection(ctx)
+	if err != nil {
+		newError("failed to dispatch conn to ", destination).Base(err).WriteToLog(session.ExportContextToError(ctx))
+		common.Close(conn)
+		return
+	}
+	// The handlers below return only after the relay has ended.
+	defer release()
+
+	d.markOnline(ctx)
+	tracked, untrack := d.live.track(ctx, destination)
+	defer untrack()
+
 	var handler outbound.Handler
 
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
 		if h := d.ohm.GetHandler(forcedOutboundTag); h != nil {
-			newError("taking platform initialized detour [", forcedOutboundTag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
+			newError("taking platform initialized detour [", forcedOutboundTag, "] for [", destination, "]").WriteToLog(session.ExportContextToError(ctx))
 			handler = h
 		} else {
-			newError("non existing tag for platform initialized detour: ", forcedOutboundTag).AtError().WriteToLog(session.ExportIDToError(ctx))
+			newError("non existing tag for platform initialized detour: ", forcedOutboundTag).AtError().WriteToLog(session.ExportContextToError(ctx))
 			common.Close(conn)
 			return
 		}
@@ -124,13 +140,13 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 		if route, err := d.router.PickRoute(routing_session.AsRoutingContext(ctx)); err == nil {
 			tag := route.GetOutboundTag()
 			if h := d.ohm.GetHandler(tag); h != nil {
-				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
+				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportContextToError(ctx))
 				handler = h
 			} else {
-				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportContextToError(ctx))
 			}
 		} else {
-			newError("default route for ", destination).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+			newError("default route for ", destination).AtWarning().WriteToLog(session.ExportContextToError(ctx))
 		}
 	}
 
@@ -139,7 +155,7 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 	}
 
 	if handler == nil {
-		newError("default outbound handler not exist").WriteToLog(session.ExportIDToError(ctx))
+		newError("default outbound handler not exist").WriteToLog(session.ExportContextToError(ctx))
 		common.Close(conn)
 		return
 	}
@@ -148,9 +164,14 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 		if tag := handler.Tag(); tag != "" {
 			accessMessage.Detour = tag
 		}
+		accessMessage.SessionID = uint32(session.IDFromContext(ctx))
 		log.Record(accessMessage)
 	}
 
+	tracked.setRoute(handler.Tag(), destination)
+	conn = d.userStatConn(ctx, conn)
+	conn = d.userRateLimitConn(ctx, conn)
+
 	if connHandler, ok := handler.(outbound.ConnHandler); ok && connHandler.IsConnDispatcher() {
 		connHandler.DispatchConn(ctx, conn)
 		return
@@ -158,6 +179,56 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 
 	handler.Dispatch(ctx, &transport.Link{
 		Reader: buf.NewReader(conn),
-		Writer: buf.NewWriter(conn),
+		Writer: &countingWriter{counter: &tracked.downlink, writer: buf.NewWriter(conn)},
 	})
 }
+
+// userStatConn counts the traffic of conn in the counters of the user of the
+// inbound, the same as the links of Dispatch, if enabled by the policy.
+func (d *DefaultDispatcher) userStatConn(ctx context.Context, conn net.Conn) net.Conn {
+	sessionInbound := session.InboundFromContext(ctx)
+	if sessionInbound == nil || sessionInbound.User == nil || len(sessionInbound.User.Email) == 0 {
+		return conn
+	}
+	user := sessionInbound.User
+
+	p := d.policy.ForLevel(user.Level)
+	var uplink, downlink stats.Counter
+	if p.Stats.UserUplink {
+		uplink, _ = stats.GetOrRegisterCounter(d.stats, "user>>>"+user.Email+">>>traffic>>>uplink")
+	}
+	if p.Stats.UserDownlink {
+		downlink, _ = stats.GetOrRegisterCounter(d.stats, "user>>>"+user.Email+">>>traffic>>>downlink")
+	}
+	if uplink == nil && downlink == nil {
+		return conn
+	}
+	return &internet.StatCounterConn{
+		Connection:   conn,
+		ReadCounter:  uplink,
+		WriteCounter: downlink,
+	}
+}
+
+// userRateLimitConn limits the bandwidth of conn by the limiters shared with
+// the links of Dispatch of the same user, if enabled by the policy.
+func (d *DefaultDispatcher) userRateLimitConn(ctx context.Context, conn net.Conn) net.Conn {
+	sessionInbound := session.InboundFromContext(ctx)
+	if sessionInbound == nil || sessionInbound.User == nil {
+		return conn
+	}
+	user := sessionInbound.User
+
+	p := d.policy.ForLevel(user.Level)
+	var uplink, downlink *rate.Limiter
+	if p.Bandwidth.Uplink > 0 {
+		uplink = d.limiters.get(user, false, p.Bandwidth.Uplink, p.Bandwidth.UplinkBurst)
+	}
+	if p.Bandwidth.Downlink > 0 {
+		downlink = d.limiters.get(user, true, p.Bandwidth.Downlink, p.Bandwidth.DownlinkBurst)
+	}
+	if uplink == nil && downlink == nil {
+		return conn
+	}
+	return newRateLimitConn(ctx, conn, uplink, downlink)
+}
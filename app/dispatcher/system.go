@@ -3,7 +3,6 @@ package dispatcher
 import (
 	"context"
 
-	"github.com/sagernet/sing/common/bufio"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/task"
@@ -57,9 +56,9 @@ func (s *SystemDispatcher) DispatchConn(ctx context.Context, dest net.Destinatio
 		return err
 	}
 	if wait {
-		return bufio.CopyConn(ctx, conn, destConn)
+		return internet.CopyConn(ctx, conn, destConn)
 	} else {
-		go bufio.CopyConn(ctx, conn, destConn)
+		go internet.CopyConn(ctx, conn, destConn)
 		return nil
 	}
 }
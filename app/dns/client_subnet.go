@@ -0,0 +1,170 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// optionCodeClientSubnet is the EDNS option code of client subnet, see RFC 7871.
+const optionCodeClientSubnet = 0x08
+
+const (
+	defaultClientSubnetPrefixV4 = 24
+	defaultClientSubnetPrefixV6 = 56
+)
+
+// clientSubnet is the content of an EDNS client subnet option.
+type clientSubnet struct {
+	ip           net.IP
+	sourcePrefix uint8
+	scopePrefix  uint8
+}
+
+// newClientSubnet returns the client subnet of ip with the prefix length of its
+// family. A nil or invalid prefix length means the default one, while 0 sends
+// no bits of the address.
+func newClientSubnet(ip net.IP, prefixV4, prefixV6 *uint32) *clientSubnet {
+	if ip4 := ip.To4(); ip4 != nil {
+		prefix := uint32(defaultClientSubnetPrefixV4)
+		if prefixV4 != nil && *prefixV4 <= 32 {
+			prefix = *prefixV4
+		}
+		return &clientSubnet{
+			ip:           ip4.Mask(net.CIDRMask(int(prefix), 32)),
+			sourcePrefix: uint8(prefix),
+		}
+	}
+	prefix := uint32(defaultClientSubnetPrefixV6)
+	if prefixV6 != nil && *prefixV6 <= 128 {
+		prefix = *prefixV6
+	}
+	return &clientSubnet{
+		ip:           ip.To16().Mask(net.CIDRMask(int(prefix), 128)),
+		sourcePrefix: uint8(prefix),
+	}
+}
+
+func (s *clientSubnet) option() dnsmessage.Option {
+	family := uint16(1)
+	if len(s.ip) == net.IPv6len {
+		family = 2
+	}
+	data := make([]byte, 4, 4+len(s.ip))
+	binary.BigEndian.PutUint16(data, family)
+	data[2] = s.sourcePrefix
+	data[3] = s.scopePrefix
+	// Only significant bytes of the address are sent.
+	data = append(data, s.ip[:(int(s.sourcePrefix)+7)/8]...)
+	return dnsmessage.Option{
+		Code: optionCodeClientSubnet,
+		Data: data,
+	}
+}
+
+func parseClientSubnet(data []byte) (*clientSubnet, error) {
+	if len(data) < 4 {
+		return nil, newError("client subnet option too short")
+	}
+	var length, maxPrefix int
+	switch binary.BigEndian.Uint16(data) {
+	case 1:
+		length, maxPrefix = net.IPv4len, 32
+	case 2:
+		length, maxPrefix = net.IPv6len, 128
+	default:
+		return nil, newError("unknown client subnet family ", binary.BigEndian.Uint16(data))
+	}
+	subnet := &clientSubnet{
+		sourcePrefix: data[2],
+		scopePrefix:  data[3],
+	}
+	if int(subnet.sourcePrefix) > maxPrefix || int(subnet.scopePrefix) > maxPrefix {
+		return nil, newError("invalid client subnet prefix ", subnet.sourcePrefix, "/", subnet.scopePrefix)
+	}
+	address := data[4:]
+	if len(address) > length {
+		return nil, newError("client subnet address too long")
+	}
+	subnet.ip = make(net.IP, length)
+	copy(subnet.ip, address)
+	return subnet, nil
+}
+
+// findClientSubnet returns the client subnet in the OPT record of message, or
+// nil if there is none.
+func findClientSubnet(message *dnsmessage.Message) (*clientSubnet, error) {
+	for _, resource := range message.Additionals {
+		opt, ok := resource.Body.(*dnsmessage.OPTResource)
+		if !ok {
+			continue
+		}
+		for _, option := range opt.Options {
+			if option.Code == optionCodeClientSubnet {
+				return parseClientSubnet(option.Data)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// withClientSubnet returns a copy of message with the client subnet option
+// set, replacing the one in the existing OPT record if any.
+func withClientSubnet(message *dnsmessage.Message, subnet *clientSubnet) *dnsmessage.Message {
+	newMessage := *message
+	newMessage.Additionals = make([]dnsmessage.Resource, 0, len(message.Additionals)+1)
+	var opt *dnsmessage.Resource
+	for _, resource := range message.Additionals {
+		if body, ok := resource.Body.(*dnsmessage.OPTResource); ok && opt == nil {
+			options := make([]dnsmessage.Option, 0, len(body.Options)+1)
+			for _, option := range body.Options {
+				if option.Code != optionCodeClientSubnet {
+					options = append(options, option)
+				}
+			}
+			resource.Body = &dnsmessage.OPTResource{Options: options}
+			newMessage.Additionals = append(newMessage.Additionals, resource)
+			opt = &newMessage.Additionals[len(newMessage.Additionals)-1]
+			continue
+		}
+		newMessage.Additionals = append(newMessage.Additionals, resource)
+	}
+	if opt == nil {
+		resource := dnsmessage.Resource{Body: &dnsmessage.OPTResource{}}
+		common.Must(resource.Header.SetEDNS0(1232, dnsmessage.RCodeSuccess, false))
+		newMessage.Additionals = append(newMessage.Additionals, resource)
+		opt = &newMessage.Additionals[len(newMessage.Additionals)-1]
+	}
+	body := opt.Body.(*dnsmessage.OPTResource)
+	body.Options = append(body.Options, subnet.option())
+	return &newMessage
+}
+
+// clientSubnet returns the client subnet to send to the server for a query in
+// ctx, or nil if client subnet is not enabled.
+func (s *Server) clientSubnet(ctx context.Context) *clientSubnet {
+	ip := s.clientIP
+	if len(ip) == 0 && s.clientIPFromSource {
+		if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.Address != nil && inbound.Source.Address.Family().IsIP() {
+			ip = inbound.Source.Address.IP()
+		}
+	}
+	if len(ip) == 0 {
+		return nil
+	}
+	return newClientSubnet(ip, s.clientIPPrefixV4, s.clientIPPrefixV6)
+}
+
+// prepareQuery returns a copy of message to be sent to the server, carrying the
+// client subnet if enabled.
+func (s *Server) prepareQuery(ctx context.Context, message *dnsmessage.Message) *dnsmessage.Message {
+	if subnet := s.clientSubnet(ctx); subnet != nil {
+		return withClientSubnet(message, subnet)
+	}
+	newMessage := *message
+	return &newMessage
+}
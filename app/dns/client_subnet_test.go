@@ -0,0 +1,145 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newTestQuery() *dnsmessage.Message {
+	return &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 1, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+}
+
+// packAndFindClientSubnet sends message through the wire format, so that the
+// OPT record is checked as the server sees it.
+func packAndFindClientSubnet(t *testing.T, message *dnsmessage.Message) (*clientSubnet, []byte) {
+	packed, err := message.Pack()
+	common.Must(err)
+	var parsed dnsmessage.Message
+	common.Must(parsed.Unpack(packed))
+	var data []byte
+	for _, resource := range parsed.Additionals {
+		if opt, ok := resource.Body.(*dnsmessage.OPTResource); ok {
+			for _, option := range opt.Options {
+				if option.Code == optionCodeClientSubnet {
+					data = option.Data
+				}
+			}
+		}
+	}
+	subnet, err := findClientSubnet(&parsed)
+	if err != nil {
+		t.Fatal("failed to parse client subnet: ", err)
+	}
+	return subnet, data
+}
+
+func prefixLength(n uint32) *uint32 {
+	return &n
+}
+
+func TestClientSubnetQuery(t *testing.T) {
+	inbound := session.ContextWithInbound(context.Background(), &session.Inbound{
+		Source: net.TCPDestination(net.ParseAddress("203.0.113.77"), 12345),
+	})
+
+	cases := []struct {
+		server *Server
+		ctx    context.Context
+		data   []byte
+	}{
+		{&Server{}, inbound, nil},
+		{&Server{clientIP: net.ParseIP("192.0.2.123").To4()}, context.Background(), []byte{0, 1, 24, 0, 192, 0, 2}},
+		{&Server{clientIP: net.ParseIP("192.0.2.123").To4(), clientIPPrefixV4: prefixLength(20)}, context.Background(), []byte{0, 1, 20, 0, 192, 0, 0}},
+		{&Server{clientIP: net.ParseIP("192.0.2.123").To4(), clientIPPrefixV4: prefixLength(0)}, context.Background(), []byte{0, 1, 0, 0}},
+		{&Server{clientIP: net.ParseIP("192.0.2.123").To4(), clientIPPrefixV4: prefixLength(33)}, context.Background(), []byte{0, 1, 24, 0, 192, 0, 2}},
+		{&Server{clientIP: net.ParseIP("2001:db8:1234:5678::1")}, context.Background(), []byte{0, 2, 56, 0, 0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34, 0x56}},
+		{&Server{clientIP: net.ParseIP("2001:db8:1234:5678::1"), clientIPPrefixV6: prefixLength(48)}, context.Background(), []byte{0, 2, 48, 0, 0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34}},
+		{&Server{clientIP: net.ParseIP("2001:db8:1234:5678::1"), clientIPPrefixV6: prefixLength(0)}, context.Background(), []byte{0, 2, 0, 0}},
+		{&Server{clientIPFromSource: true}, context.Background(), nil},
+		{&Server{clientIPFromSource: true, clientIPPrefixV4: prefixLength(16)}, inbound, []byte{0, 1, 16, 0, 203, 0}},
+		{&Server{clientIP: net.ParseIP("192.0.2.123").To4(), clientIPFromSource: true}, inbound, []byte{0, 1, 24, 0, 192, 0, 2}},
+	}
+	for i, c := range cases {
+		query := newTestQuery()
+		prepared := c.server.prepareQuery(c.ctx, query)
+		if len(query.Additionals) != 0 {
+			t.Error("case ", i, ": the original query is modified")
+		}
+		_, data := packAndFindClientSubnet(t, prepared)
+		if !bytes.Equal(data, c.data) {
+			t.Error("case ", i, ": expected client subnet option ", c.data, ", but got ", data)
+		}
+		if c.data == nil && len(prepared.Additionals) != 0 {
+			t.Error("case ", i, ": expected no OPT record, but got ", prepared.Additionals)
+		}
+	}
+}
+
+func TestClientSubnetReplacesExisting(t *testing.T) {
+	query := newTestQuery()
+	opt := dnsmessage.Resource{Body: &dnsmessage.OPTResource{Options: []dnsmessage.Option{
+		{Code: 10, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Code: optionCodeClientSubnet, Data: []byte{0, 1, 32, 0, 10, 0, 0, 1}},
+	}}}
+	common.Must(opt.Header.SetEDNS0(4096, dnsmessage.RCodeSuccess, true))
+	query.Additionals = append(query.Additionals, opt)
+
+	server := &Server{clientIP: net.ParseIP("192.0.2.123").To4()}
+	prepared := server.prepareQuery(context.Background(), query)
+	if len(prepared.Additionals) != 1 {
+		t.Fatal("expected one OPT record, but got ", prepared.Additionals)
+	}
+	options := prepared.Additionals[0].Body.(*dnsmessage.OPTResource).Options
+	if len(options) != 2 || options[0].Code != 10 {
+		t.Error("unexpected options: ", options)
+	}
+	if prepared.Additionals[0].Header.Class != 4096 || !prepared.Additionals[0].Header.DNSSECAllowed() {
+		t.Error("OPT record header is not kept: ", prepared.Additionals[0].Header)
+	}
+	if _, data := packAndFindClientSubnet(t, prepared); !bytes.Equal(data, []byte{0, 1, 24, 0, 192, 0, 2}) {
+		t.Error("unexpected client subnet option ", data)
+	}
+	if original := query.Additionals[0].Body.(*dnsmessage.OPTResource).Options; len(original) != 2 || original[1].Data[2] != 32 {
+		t.Error("the original query is modified: ", original)
+	}
+}
+
+func TestClientSubnetResponse(t *testing.T) {
+	response := &dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}}
+	if subnet, err := findClientSubnet(response); subnet != nil || err != nil {
+		t.Error("expected no client subnet, but got ", subnet, err)
+	}
+
+	opt := dnsmessage.Resource{Body: &dnsmessage.OPTResource{Options: []dnsmessage.Option{
+		{Code: optionCodeClientSubnet, Data: []byte{0, 1, 24, 16, 192, 0, 2}},
+	}}}
+	common.Must(opt.Header.SetEDNS0(1232, dnsmessage.RCodeSuccess, false))
+	response.Additionals = append(response.Additionals, opt)
+	subnet, _ := packAndFindClientSubnet(t, response)
+	if subnet == nil {
+		t.Fatal("expected client subnet in response")
+	}
+	if !subnet.ip.Equal(net.ParseIP("192.0.2.0")) || subnet.sourcePrefix != 24 || subnet.scopePrefix != 16 {
+		t.Error("unexpected client subnet: ", subnet.ip, "/", subnet.sourcePrefix, " scope ", subnet.scopePrefix)
+	}
+
+	for _, data := range [][]byte{
+		{0, 1, 24},
+		{0, 3, 24, 0, 192, 0, 2},
+		{0, 1, 33, 0, 192, 0, 2, 1, 1},
+		{0, 1, 24, 0, 192, 0, 2, 1, 1},
+	} {
+		if _, err := parseClientSubnet(data); err == nil {
+			t.Error("expected error parsing client subnet ", data)
+		}
+	}
+}
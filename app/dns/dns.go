@@ -19,6 +19,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/strmatcher"
 	"github.com/v2fly/v2ray-core/v5/common/task"
 	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
 	"golang.org/x/net/dns/dnsmessage"
 )
 
@@ -38,11 +39,15 @@ type Client struct {
 	defaultQueryStrategy dns.QueryStrategy
 	hosts                *StaticHosts
 	servers              []*Server
+	taggedServers        map[string]*Server
+	policyManager        policy.Manager
 
 	disableCache           bool
 	disableFallback        bool
 	disableFallbackIfMatch bool
 	disableExpire          bool
+	disableNegativeCache   bool
+	negativeCacheMaxTTL    uint32
 
 	requestId int32
 	callbacks sync.Map
@@ -50,14 +55,20 @@ type Client struct {
 }
 
 type Server struct {
-	name         string
-	transport    dns.Transport
-	clientIP     net.IP
-	skipFallback bool
-	domains      []string
-	expectIPs    []*router.GeoIPMatcher
-	concurrency  bool
-	access       sync.Mutex
+	name               string
+	tag                string
+	transport          dns.Transport
+	clientIP           net.IP
+	clientIPPrefixV4   *uint32
+	clientIPPrefixV6   *uint32
+	clientIPFromSource bool
+	skipFallback       bool
+	domains            []string
+	expectIPs          []*router.GeoIPMatcher
+	concurrency        bool
+	queryStrategy      dns.QueryStrategy
+	validator          *dnssecValidator
+	access             sync.Mutex
 }
 
 type transportContext struct {
@@ -72,8 +83,14 @@ type transportContext struct {
 
 type queryCallback struct {
 	parseIPs bool
+	// anyRCode takes responses of any rcode as the answer of a raw query,
+	// instead of an error.
+	anyRCode bool
 	domain   string
 	strategy dns.QueryStrategy
+	// cacheKey is the key of the answers in the cache, which differs from
+	// the domain for lookups with the DNS server of a user level.
+	cacheKey string
 
 	wg *sync.WaitGroup
 
@@ -87,6 +104,9 @@ type queryCallback struct {
 type serverQueryCallback struct {
 	*queryCallback
 
+	// strategy is the strategy of the query restricted by the server.
+	strategy dns.QueryStrategy
+
 	ctx              context.Context
 	cancel           context.CancelFunc
 	finish4, finish6 bool
@@ -97,6 +117,10 @@ type serverQueryCallback struct {
 	message   *dnsmessage.Message
 	ips       []net.IP
 	errors    []error
+
+	// pending receives the responses to be validated by the goroutines that
+	// sent the queries.
+	pending chan *dnsmessage.Message
 }
 
 type ipCacheEntire struct {
@@ -104,6 +128,57 @@ type ipCacheEntire struct {
 	cached4, cached6 bool
 	cache4, cache6   []net.IP
 	expire4, expire6 time.Time
+	nxdomain         bool
+}
+
+const (
+	defaultCacheTTL            = 6 * 60
+	defaultNegativeCacheMaxTTL = 60 * 60
+)
+
+// negativeCacheTTL returns the TTL to cache a NXDOMAIN or empty response for,
+// which is the TTL of the SOA record in the response capped by its minimum
+// field, as RFC 2308 specifies. defaultTTL is used if there is no SOA record,
+// and the response is not cached if it is zero.
+func (c *Client) negativeCacheTTL(message *dnsmessage.Message, defaultTTL uint32) (uint32, bool) {
+	if c.disableNegativeCache {
+		return 0, false
+	}
+	ttl := defaultTTL
+	for _, authority := range message.Authorities {
+		if soa, ok := authority.Body.(*dnsmessage.SOAResource); ok {
+			ttl = authority.Header.TTL
+			if soa.MinTTL < ttl {
+				ttl = soa.MinTTL
+			}
+			break
+		}
+	}
+	maxTTL := c.negativeCacheMaxTTL
+	if maxTTL == 0 {
+		maxTTL = defaultNegativeCacheMaxTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	return ttl, ttl > 0
+}
+
+// cacheNXDomain remembers that the domain of the cache key doesn't exist.
+func (c *Client) cacheNXDomain(key string, message *dnsmessage.Message) {
+	ttl, ok := c.negativeCacheTTL(message, 0)
+	if !ok {
+		return
+	}
+	expire := time.Now().Add(time.Duration(ttl) * time.Second)
+	c.cache.Store(key, &ipCacheEntire{
+		ttl:      ttl,
+		cached4:  true,
+		cached6:  true,
+		expire4:  expire,
+		expire6:  expire,
+		nxdomain: true,
+	})
 }
 
 func (c *Client) nextRequestId() uint16 {
@@ -115,6 +190,59 @@ func (c *Client) nextRequestId() uint16 {
 	return uint16(requestId)
 }
 
+// levelServer returns the DNS server that the policy of the user level of the
+// inbound session refers to, or nil to use the default servers. The tags of
+// the servers are validated when the client is created.
+func (c *Client) levelServer(ctx context.Context) *Server {
+	if c.policyManager == nil {
+		return nil
+	}
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.User == nil {
+		return nil
+	}
+	tag := c.policyManager.ForLevel(inbound.User.Level).DNSServer
+	if tag == "" {
+		return nil
+	}
+	return c.taggedServers[tag]
+}
+
+// checkLevelServers verifies that the DNS servers the policies of user levels
+// refer to exist.
+func (c *Client) checkLevelServers() error {
+	levels, ok := c.policyManager.(interface{ Levels() []uint32 })
+	if !ok {
+		return nil
+	}
+	for _, level := range levels.Levels() {
+		tag := c.policyManager.ForLevel(level).DNSServer
+		if _, found := c.taggedServers[tag]; tag != "" && !found {
+			return newError("DNS server ", tag, " of user level ", level, " not found")
+		}
+	}
+	return nil
+}
+
+// queryServers returns the servers to query the domain with, which is
+// levelServer alone if not nil.
+func (c *Client) queryServers(ctx context.Context, domain string, levelServer *Server) []*Server {
+	if levelServer != nil {
+		newError("domain ", domain, " will use DNS ", levelServer.name, " of the user level").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+		return []*Server{levelServer}
+	}
+	return c.sortServers(domain)
+}
+
+// cacheKey returns the key of the answers of the domain in the cache. Answers
+// of the DNS servers of user levels are not shared with other lookups.
+func (c *Client) cacheKey(domain string, levelServer *Server) string {
+	if levelServer != nil {
+		return levelServer.tag + "@" + domain
+	}
+	return domain
+}
+
 func (c *Client) LookupDefault(ctx context.Context, domain string) ([]net.IP, uint32, error) {
 	return c.Lookup(ctx, domain, c.defaultQueryStrategy)
 }
@@ -125,14 +253,35 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		domain = domain[:len(domain)-1]
 	}
 
+	if c.hosts != nil {
+		switch addrs := c.hosts.Lookup(domain, hostsOption(strategy)); {
+		case addrs == nil: // Domain not recorded in static hosts
+		case len(addrs) == 0: // Domain recorded, but without IP addresses of the queried types
+			return nil, hostsTTL, dns.ErrEmptyResponse
+		case addrs[0].Family().IsDomain(): // Domain replaced, look it up instead
+			newError("domain replaced: ", domain, " -> ", addrs[0].Domain()).AtDebug().WriteToLog()
+			domain = addrs[0].Domain()
+		default:
+			newError("returning ", len(addrs), " IP(s) of static hosts for domain ", domain, " -> ", addrs).AtDebug().WriteToLog()
+			ips, err := toNetIP(addrs)
+			return ips, hostsTTL, err
+		}
+	}
+
 	var ips []net.IP
-	var cached4, cached6 bool
+	var cached4, cached6, nxdomain bool
 	now := time.Now()
 
-	cacheI, cachedHit := c.cache.Load(domain)
+	levelServer := c.levelServer(ctx)
+	cacheKey := c.cacheKey(domain, levelServer)
+	cacheI, cachedHit := c.cache.Load(cacheKey)
 	if cachedHit {
 		cache := cacheI.(*ipCacheEntire)
 		ttl = cache.ttl
+		if !c.disableExpire && !(cache.cached4 && now.Before(cache.expire4)) && !(cache.cached6 && now.Before(cache.expire6)) {
+			// Evict the entry once it's expired entirely.
+			c.cache.Delete(cacheKey)
+		}
 		if strategy != dns.QueryStrategy_USE_IP6 {
 			if cache.cached4 && (c.disableExpire || now.Before(cache.expire4)) {
 				ips = append(ips, cache.cache4...)
@@ -145,6 +294,7 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 				cached6 = true
 			}
 		}
+		nxdomain = cache.nxdomain && (cached4 || cached6)
 	}
 
 	if len(ips) > 0 {
@@ -170,12 +320,17 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			newStrategy = dns.QueryStrategy_USE_IP4
 		}
 	} else if len(ips) == 0 {
+		if nxdomain {
+			newError("dns negative cache HIT ", domain).AtDebug().WriteToLog()
+			return nil, ttl, dns.RCodeError(dnsmessage.RCodeNameError)
+		}
 		return nil, ttl, dns.ErrEmptyResponse
 	}
 
 	if query {
-		queried, ttl, err := c.lookup(ctx, domain, newStrategy)
-		if err != nil {
+		queried, ttl, err := c.lookup(ctx, domain, newStrategy, levelServer)
+		// Addresses of the other type may be cached.
+		if err != nil && (len(ips) == 0 || err != dns.ErrEmptyResponse) {
 			return nil, ttl, err
 		}
 		ips = append(ips, queried...)
@@ -210,11 +365,11 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 	return ips, ttl, nil
 }
 
-func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QueryStrategy) ([]net.IP, uint32, error) {
+func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QueryStrategy, levelServer *Server) ([]net.IP, uint32, error) {
 	if c.servers == nil {
 		return nil, 0, os.ErrClosed
 	}
-	servers := c.sortServers(domain)
+	servers := c.queryServers(ctx, domain, levelServer)
 	var messages []*dnsmessage.Message
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -257,18 +412,21 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		parseIPs: true,
 		domain:   domain,
 		strategy: strategy,
+		cacheKey: c.cacheKey(domain, levelServer),
 	}
 
 	q.wg.Add(len(servers))
 
 	var reqIds []uint16
 	var requests []*serverQueryCallback
+	var skipped int
 
 	for _, server := range servers {
 		server := server
 		ctx, cancel := context.WithCancel(ctx)
 		r := &serverQueryCallback{
 			queryCallback: q,
+			strategy:      server.restrictStrategy(strategy),
 			ctx:           ctx,
 			cancel:        cancel,
 		}
@@ -277,10 +435,20 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			r.wg.Done()
 		}()
 		requests = append(requests, r)
+		messages := server.filterQueries(messages)
+		if len(messages) == 0 {
+			// The server doesn't answer the types of the query.
+			skipped++
+			cancel()
+			continue
+		}
 		switch server.transport.Type() {
 		case dns.TransportTypeDefault:
+			if server.validator != nil {
+				r.pending = make(chan *dnsmessage.Message, len(messages))
+			}
 			for index := range messages {
-				message := messages[index]
+				message := server.prepareLookup(ctx, messages[index])
 				message.ID = c.nextRequestId()
 				reqIds = append(reqIds, message.ID)
 				r.queryType.Store(message.ID, message.Questions[0].Type)
@@ -289,12 +457,20 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 					if err := server.transport.Write(ctx, message); err != nil {
 						r.errors = append(r.errors, err)
 						cancel()
+						return
+					}
+					if r.pending != nil {
+						select {
+						case response := <-r.pending:
+							c.validateResponse(server, r, response)
+						case <-ctx.Done():
+						}
 					}
 				}()
 			}
 		case dns.TransportTypeExchange:
 			for index := range messages {
-				message := messages[index]
+				message := server.prepareLookup(ctx, messages[index])
 				message.ID = c.nextRequestId()
 				reqIds = append(reqIds, message.ID)
 				r.queryType.Store(message.ID, message.Questions[0].Type)
@@ -311,7 +487,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			}
 		case dns.TransportTypeExchangeRaw:
 			for index := range messages {
-				message := messages[index]
+				message := server.prepareLookup(ctx, messages[index])
 				message.ID = c.nextRequestId()
 				packed, err := message.Pack()
 				if err != nil {
@@ -334,7 +510,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			}
 		case dns.TransportTypeLookup:
 			go func() {
-				ips, err := server.transport.Lookup(ctx, domain, strategy)
+				ips, err := server.transport.Lookup(ctx, domain, r.strategy)
 				q.access.Lock()
 				defer q.access.Unlock()
 				if err != nil {
@@ -390,6 +566,10 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		}
 	}
 
+	if skipped == len(requests) {
+		return nil, 0, dns.ErrEmptyResponse
+	}
+
 	var errs []error
 	for _, request := range requests {
 		errs = append(errs, request.errors...)
@@ -432,7 +612,7 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	servers := c.sortServers(domain)
+	servers := c.queryServers(ctx, domain, c.levelServer(ctx))
 
 	q := &queryCallback{
 		wg:     new(sync.WaitGroup),
@@ -455,6 +635,7 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 			<-ctx.Done()
 			r.wg.Done()
 		}()
+		message := server.prepareQuery(ctx, message)
 		switch server.transport.Type() {
 		case dns.TransportTypeDefault:
 			message.ID = c.nextRequestId()
@@ -605,6 +786,65 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 	})
 }
 
+// exchange sends message to server and waits for the response, which is not
+// parsed or cached.
+func (c *Client) exchange(ctx context.Context, server *Server, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r := &serverQueryCallback{
+		queryCallback: &queryCallback{
+			ctx:      ctx,
+			cancel:   cancel,
+			anyRCode: true,
+			domain:   message.Questions[0].Name.String(),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	query := *message
+	query.ID = c.nextRequestId()
+	c.callbacks.Store(query.ID, r)
+	defer c.callbacks.Delete(query.ID)
+
+	switch server.transport.Type() {
+	case dns.TransportTypeDefault:
+		if err := server.transport.Write(ctx, &query); err != nil {
+			return nil, err
+		}
+	case dns.TransportTypeExchange:
+		response, err := server.transport.Exchange(ctx, &query)
+		if err != nil {
+			return nil, err
+		}
+		c.writeBack(server, response)
+	case dns.TransportTypeExchangeRaw:
+		packed, err := query.Pack()
+		if err != nil {
+			return nil, newError("failed to pack dns query").Base(err)
+		}
+		response, err := server.transport.ExchangeRaw(ctx, buf.FromBytes(packed))
+		if err != nil {
+			return nil, err
+		}
+		c.writeBackRaw(server, response)
+	default:
+		return nil, common.ErrNoClue
+	}
+
+	<-ctx.Done()
+
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.message != nil {
+		return r.message, nil
+	}
+	if err := errors.Combine(r.errors...); err != nil {
+		return nil, err
+	}
+	return nil, ctx.Err()
+}
+
 func packMessage(message *dnsmessage.Message) (*buf.Buffer, error) {
 	packed, err := message.Pack()
 	if err != nil {
@@ -726,17 +966,41 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 		return
 	}
 
+	if server.validator != nil && d.parseIPs && (message.RCode == dnsmessage.RCodeSuccess || message.RCode == dnsmessage.RCodeNameError) {
+		if d.pending != nil {
+			// Validation may query the server, so it mustn't block the
+			// response reader.
+			d.pending <- message
+			return
+		}
+		c.validateResponse(server, d, message)
+		return
+	}
+	c.handleResponse(server, d, message)
+}
+
+func (c *Client) handleResponse(server *Server, d *serverQueryCallback, message *dnsmessage.Message) {
 	d.access.Lock()
 	defer d.access.Unlock()
 
-	if message.RCode != dnsmessage.RCodeSuccess {
+	if message.RCode != dnsmessage.RCodeSuccess && !d.anyRCode {
 		err := dns.RCodeError(message.RCode)
+		if message.RCode == dnsmessage.RCodeNameError && d.parseIPs {
+			c.cacheNXDomain(d.cacheKey, message)
+		}
 		d.errors = append(d.errors, err)
 		d.cancel()
 		newError("failed to lookup ip for domain ", d.domain, " at server ", server.name).Base(err).AtDebug().WriteToLog(session.ExportIDToError(d.ctx))
 		return
 	}
 
+	subnet, err := findClientSubnet(message)
+	if err != nil {
+		newError("failed to parse client subnet of response ", message.ID, " from server ", server.name).Base(err).AtDebug().WriteToLog(session.ExportIDToError(d.ctx))
+	} else if subnet != nil {
+		newError(server.name, " answered for client subnet ", subnet.ip, "/", subnet.sourcePrefix, " with scope /", subnet.scopePrefix).AtDebug().WriteToLog(session.ExportIDToError(d.ctx))
+	}
+
 	if !d.parseIPs {
 		d.queryCallback.access.Lock()
 		defer d.queryCallback.access.Unlock()
@@ -786,8 +1050,10 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 			return it.AsSlice()
 		})
 		cache.cached4 = true
-		if ttl4 == 0 {
-			ttl4 = 6 * 60
+		if !has4 {
+			ttl4, cache.cached4 = c.negativeCacheTTL(message, defaultCacheTTL)
+		} else if ttl4 == 0 {
+			ttl4 = defaultCacheTTL
 		}
 		cache.ttl = ttl4
 		cache.expire4 = now.Add(time.Duration(ttl4) * time.Second)
@@ -798,8 +1064,10 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 			return it.AsSlice()
 		})
 		cache.cached6 = true
-		if ttl6 == 0 {
-			ttl6 = 6 * 60
+		if !has6 {
+			ttl6, cache.cached6 = c.negativeCacheTTL(message, defaultCacheTTL)
+		} else if ttl6 == 0 {
+			ttl6 = defaultCacheTTL
 		}
 		if cache.ttl == 0 || ttl6 < cache.ttl {
 			cache.ttl = ttl6
@@ -807,17 +1075,24 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 		cache.expire6 = now.Add(time.Duration(ttl6) * time.Second)
 		d.finish6 = true
 	}
-	cacheI, cacheExists := c.cache.LoadOrStore(d.domain, cache)
-	if cacheExists {
-		acCache := cacheI.(*ipCacheEntire)
-		if cache.cached4 {
-			acCache.cache4, acCache.cached4, acCache.expire4 = cache.cache4, cache.cached4, cache.expire4
-		}
-		if cache.cached6 {
-			acCache.cache6, acCache.cached6, acCache.expire6 = cache.cache6, cache.cached6, cache.expire6
-		}
-		if acCache.ttl == 0 || cache.ttl < acCache.ttl {
-			acCache.ttl = cache.ttl
+	// Answers for the client subnet of the query source are not shared with
+	// clients from other subnets.
+	if subnet == nil || subnet.scopePrefix == 0 || len(server.clientIP) > 0 || !server.clientIPFromSource {
+		cacheI, cacheExists := c.cache.LoadOrStore(d.cacheKey, cache)
+		if cacheExists {
+			acCache := cacheI.(*ipCacheEntire)
+			if cache.cached4 {
+				acCache.cache4, acCache.cached4, acCache.expire4 = cache.cache4, cache.cached4, cache.expire4
+			}
+			if cache.cached6 {
+				acCache.cache6, acCache.cached6, acCache.expire6 = cache.cache6, cache.cached6, cache.expire6
+			}
+			if acCache.ttl == 0 || cache.ttl < acCache.ttl {
+				acCache.ttl = cache.ttl
+			}
+			if cache.cached4 || cache.cached6 {
+				acCache.nxdomain = false
+			}
 		}
 	}
 	var ips []net.IP
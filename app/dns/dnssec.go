@@ -0,0 +1,783 @@
+package dns
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// defaultTrustAnchors contains the DS record of the root zone KSK-2017.
+var defaultTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+}
+
+// dnssecValidator validates A and AAAA lookups by the chain of DNSKEY and DS
+// records up to a trust anchor, which are queried on the same server.
+//
+// NXDOMAIN and empty responses are validated by the NSEC or NSEC3 records of
+// the zone. RRsets without signatures are accepted only if an insecure
+// delegation, which is a delegation without DS records, is proved between the
+// trust anchor and their owner.
+type dnssecValidator struct {
+	exchange func(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error)
+	anchors  map[string][]*mdns.DS
+	now      func() time.Time
+
+	access sync.Mutex
+	keys   map[string]*validatedKeys
+	cuts   map[string]*zoneCut
+}
+
+type validatedKeys struct {
+	keys   []*mdns.DNSKEY
+	expire time.Time
+}
+
+type zoneCutKind int
+
+const (
+	// notZoneCut is a name in the same zone as its parent.
+	notZoneCut zoneCutKind = iota
+	// secureZoneCut is a delegation with validated DS records.
+	secureZoneCut
+	// insecureZoneCut is a delegation proved to have no DS records.
+	insecureZoneCut
+)
+
+type zoneCut struct {
+	kind   zoneCutKind
+	expire time.Time
+}
+
+// maxNSEC3Iterations is the maximum number of NSEC3 hash iterations before
+// the record is ignored, to bound the work of an answer.
+const maxNSEC3Iterations = 150
+
+func newDNSSECValidator(anchors []string, exchange func(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error)) (*dnssecValidator, error) {
+	if len(anchors) == 0 {
+		anchors = defaultTrustAnchors
+	}
+	v := &dnssecValidator{
+		exchange: exchange,
+		anchors:  make(map[string][]*mdns.DS),
+		now:      time.Now,
+		keys:     make(map[string]*validatedKeys),
+		cuts:     make(map[string]*zoneCut),
+	}
+	for _, anchor := range anchors {
+		rr, err := mdns.NewRR(anchor)
+		if err != nil {
+			return nil, newError("invalid trust anchor: ", anchor).Base(err)
+		}
+		ds, ok := rr.(*mdns.DS)
+		if !ok {
+			return nil, newError("trust anchor is not a DS record: ", anchor)
+		}
+		zone := mdns.CanonicalName(ds.Hdr.Name)
+		v.anchors[zone] = append(v.anchors[zone], ds)
+	}
+	return v, nil
+}
+
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+}
+
+// rrsets groups the records of section by owner and type, and returns the
+// keys in the order of their first record.
+func rrsets(section []mdns.RR) ([]rrsetKey, map[rrsetKey][]mdns.RR, map[rrsetKey][]*mdns.RRSIG) {
+	var keys []rrsetKey
+	sets := make(map[rrsetKey][]mdns.RR)
+	sigs := make(map[rrsetKey][]*mdns.RRSIG)
+	for _, rr := range section {
+		name := mdns.CanonicalName(rr.Header().Name)
+		if sig, ok := rr.(*mdns.RRSIG); ok {
+			key := rrsetKey{name, sig.TypeCovered}
+			sigs[key] = append(sigs[key], sig)
+			continue
+		}
+		key := rrsetKey{name, rr.Header().Rrtype}
+		if _, found := sets[key]; !found {
+			keys = append(keys, key)
+		}
+		sets[key] = append(sets[key], rr)
+	}
+	return keys, sets, sigs
+}
+
+// validate validates all RRsets in the answer section of message. If there is
+// no answer of the queried type, the denial of its existence is validated.
+func (v *dnssecValidator) validate(ctx context.Context, message *dnsmessage.Message) error {
+	msg, err := toMsg(message)
+	if err != nil {
+		return err
+	}
+	if len(msg.Question) == 0 {
+		return newError("no question")
+	}
+	question := msg.Question[0]
+
+	keys, sets, sigs := rrsets(msg.Answer)
+	for _, key := range keys {
+		if len(sigs[key]) == 0 {
+			zone, secure, err := v.zoneOf(ctx, key.name)
+			if err != nil {
+				return newError("failed to validate unsigned ", key.name, " ", mdns.Type(key.rrtype)).Base(err)
+			}
+			if secure {
+				return newError("unsigned ", key.name, " ", mdns.Type(key.rrtype), " in signed zone ", zone)
+			}
+			continue
+		}
+		sig, err := v.verifyRRSet(ctx, sets[key], sigs[key])
+		if err != nil {
+			return newError("failed to validate ", key.name, " ", mdns.Type(key.rrtype)).Base(err)
+		}
+		if labels := int(sig.Labels); labels < mdns.CountLabel(key.name) {
+			// The RRset is expanded from a wildcard, which is only valid if
+			// the name doesn't exist.
+			if err := v.validateWildcard(ctx, msg, sig.SignerName, key.name, labels); err != nil {
+				return newError("failed to validate wildcard ", key.name, " ", mdns.Type(key.rrtype)).Base(err)
+			}
+		}
+	}
+
+	// Follow CNAMEs to the name of the queried type.
+	name := mdns.CanonicalName(question.Name)
+	for i := 0; i < 8; i++ {
+		cnames := sets[rrsetKey{name, mdns.TypeCNAME}]
+		if len(cnames) == 0 {
+			break
+		}
+		name = mdns.CanonicalName(cnames[0].(*mdns.CNAME).Target)
+	}
+	if len(sets[rrsetKey{name, question.Qtype}]) > 0 {
+		return nil
+	}
+
+	zone, secure, err := v.zoneOf(ctx, name)
+	if err != nil {
+		return newError("failed to validate denial of ", name, " ", mdns.Type(question.Qtype)).Base(err)
+	}
+	if !secure {
+		return nil
+	}
+	nsecs, nsec3s := v.denialRecords(ctx, zone, msg.Ns)
+	if msg.Rcode == mdns.RcodeNameError {
+		if nsecNameError(nsecs, name) || nsec3NameError(nsec3s, zone, name) {
+			return nil
+		}
+		return newError("no proof that ", name, " doesn't exist")
+	}
+	if nsecNoData(nsecs, name, question.Qtype) || nsec3NoData(nsec3s, zone, name, question.Qtype) {
+		return nil
+	}
+	return newError("no proof that ", name, " has no ", mdns.Type(question.Qtype))
+}
+
+// validateWildcard validates that name, which an RRset signed by zone is
+// expanded for from the wildcard of its ancestor of the given labels, doesn't
+// exist.
+func (v *dnssecValidator) validateWildcard(ctx context.Context, msg *mdns.Msg, zone string, name string, labels int) error {
+	nsecs, nsec3s := v.denialRecords(ctx, mdns.CanonicalName(zone), msg.Ns)
+	for _, nsec := range nsecs {
+		if nsecCovers(nsec, name) {
+			return nil
+		}
+	}
+	encloser := ancestors(name)[mdns.CountLabel(name)-labels]
+	nextCloser := ancestors(name)[mdns.CountLabel(name)-labels-1]
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(nextCloser) && !nsec3.Match(nextCloser) {
+			return nil
+		}
+	}
+	return newError("no proof that ", name, " doesn't exist below ", encloser)
+}
+
+func (v *dnssecValidator) verifyRRSet(ctx context.Context, rrset []mdns.RR, sigs []*mdns.RRSIG) (*mdns.RRSIG, error) {
+	if len(sigs) == 0 {
+		return nil, newError("no signature")
+	}
+	name := mdns.CanonicalName(rrset[0].Header().Name)
+	var lastErr error
+	for _, sig := range sigs {
+		if !mdns.IsSubDomain(sig.SignerName, name) {
+			lastErr = newError("signer ", sig.SignerName, " is not authoritative for ", name)
+			continue
+		}
+		keys, err := v.zoneKeys(ctx, sig.SignerName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if lastErr = v.verifyWithKeys(sig, keys, rrset); lastErr == nil {
+			return sig, nil
+		}
+	}
+	return nil, lastErr
+}
+
+func (v *dnssecValidator) verifyWithKeys(sig *mdns.RRSIG, keys []*mdns.DNSKEY, rrset []mdns.RR) error {
+	if !sig.ValidityPeriod(v.now()) {
+		return newError("signature of ", sig.SignerName, " is expired or not valid yet")
+	}
+	for _, key := range keys {
+		if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err := sig.Verify(key, rrset); err == nil {
+			return nil
+		}
+	}
+	return newError("bad signature of ", sig.SignerName, " with key ", sig.KeyTag)
+}
+
+// zoneKeys returns the DNSKEY records of zone, validated by the trust anchor
+// or DS records from the parent zone.
+func (v *dnssecValidator) zoneKeys(ctx context.Context, zone string) ([]*mdns.DNSKEY, error) {
+	zone = mdns.CanonicalName(zone)
+	now := v.now()
+
+	v.access.Lock()
+	cached := v.keys[zone]
+	v.access.Unlock()
+	if cached != nil && now.Before(cached.expire) {
+		return cached.keys, nil
+	}
+
+	trustedDS, isAnchor := v.anchors[zone]
+	if !isAnchor {
+		if zone == "." {
+			return nil, newError("no trust anchor")
+		}
+		var err error
+		trustedDS, err = v.zoneDS(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	msg, err := v.query(ctx, zone, mdns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	var keys []*mdns.DNSKEY
+	var rrset []mdns.RR
+	var sigs []*mdns.RRSIG
+	ttl := uint32(math.MaxUint32)
+	for _, rr := range msg.Answer {
+		if mdns.CanonicalName(rr.Header().Name) != zone {
+			continue
+		}
+		switch rr := rr.(type) {
+		case *mdns.DNSKEY:
+			keys = append(keys, rr)
+			rrset = append(rrset, rr)
+			if rr.Hdr.Ttl < ttl {
+				ttl = rr.Hdr.Ttl
+			}
+		case *mdns.RRSIG:
+			if rr.TypeCovered == mdns.TypeDNSKEY {
+				sigs = append(sigs, rr)
+			}
+		}
+	}
+
+	var entryKeys []*mdns.DNSKEY
+	for _, key := range keys {
+		for _, ds := range trustedDS {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			if digest := key.ToDS(ds.DigestType); digest != nil && strings.EqualFold(digest.Digest, ds.Digest) {
+				entryKeys = append(entryKeys, key)
+				break
+			}
+		}
+	}
+	if len(entryKeys) == 0 {
+		return nil, newError("no DNSKEY of ", zone, " matches its DS")
+	}
+
+	var verified bool
+	for _, sig := range sigs {
+		if err = v.verifyWithKeys(sig, entryKeys, rrset); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		if err == nil {
+			err = newError("no signature")
+		}
+		return nil, newError("failed to validate DNSKEY of ", zone).Base(err)
+	}
+
+	v.access.Lock()
+	v.keys[zone] = &validatedKeys{
+		keys:   keys,
+		expire: now.Add(time.Duration(ttl) * time.Second),
+	}
+	v.access.Unlock()
+	return keys, nil
+}
+
+// zoneDS returns the DS records of zone, validated by the keys of the parent
+// zone.
+func (v *dnssecValidator) zoneDS(ctx context.Context, zone string) ([]*mdns.DS, error) {
+	msg, err := v.query(ctx, zone, mdns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+	var dsSet []*mdns.DS
+	var rrset []mdns.RR
+	var sigs []*mdns.RRSIG
+	for _, rr := range msg.Answer {
+		if mdns.CanonicalName(rr.Header().Name) != zone {
+			continue
+		}
+		switch rr := rr.(type) {
+		case *mdns.DS:
+			dsSet = append(dsSet, rr)
+			rrset = append(rrset, rr)
+		case *mdns.RRSIG:
+			// DS records must be signed by the parent zone.
+			if rr.TypeCovered == mdns.TypeDS && mdns.CanonicalName(rr.SignerName) != zone {
+				sigs = append(sigs, rr)
+			}
+		}
+	}
+	if len(dsSet) == 0 {
+		return nil, newError("no DS of ", zone)
+	}
+	if _, err := v.verifyRRSet(ctx, rrset, sigs); err != nil {
+		return nil, newError("failed to validate DS of ", zone).Base(err)
+	}
+	return dsSet, nil
+}
+
+// zoneOf returns the zone that name belongs to, walking down the delegations
+// from the closest trust anchor, and whether the zone is signed. The zone
+// returned for an unsigned name is its insecure delegation.
+func (v *dnssecValidator) zoneOf(ctx context.Context, name string) (string, bool, error) {
+	names := ancestors(mdns.CanonicalName(name))
+	anchor := -1
+	for i := len(names) - 1; i >= 0; i-- {
+		if _, found := v.anchors[names[i]]; found {
+			anchor = i
+		}
+	}
+	if anchor < 0 {
+		return "", false, newError("no trust anchor for ", name)
+	}
+	zone := names[anchor]
+	for i := anchor - 1; i >= 0; i-- {
+		kind, err := v.zoneCut(ctx, zone, names[i])
+		if err != nil {
+			return "", false, err
+		}
+		switch kind {
+		case secureZoneCut:
+			zone = names[i]
+		case insecureZoneCut:
+			return names[i], false, nil
+		}
+	}
+	return zone, true, nil
+}
+
+// zoneCut finds out whether child is a delegation from the signed zone, by
+// its DS records or their denial of existence.
+func (v *dnssecValidator) zoneCut(ctx context.Context, zone string, child string) (zoneCutKind, error) {
+	now := v.now()
+
+	v.access.Lock()
+	cached := v.cuts[child]
+	v.access.Unlock()
+	if cached != nil && now.Before(cached.expire) {
+		return cached.kind, nil
+	}
+
+	msg, err := v.query(ctx, child, mdns.TypeDS)
+	if err != nil {
+		return 0, err
+	}
+	kind, err := v.zoneCutOf(ctx, zone, child, msg)
+	if err != nil {
+		return 0, newError("failed to find out whether ", child, " is a delegation").Base(err)
+	}
+
+	ttl := uint32(math.MaxUint32)
+	for _, rr := range append(msg.Answer, msg.Ns...) {
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	v.access.Lock()
+	v.cuts[child] = &zoneCut{
+		kind:   kind,
+		expire: now.Add(time.Duration(ttl) * time.Second),
+	}
+	v.access.Unlock()
+	return kind, nil
+}
+
+func (v *dnssecValidator) zoneCutOf(ctx context.Context, zone string, child string, msg *mdns.Msg) (zoneCutKind, error) {
+	_, sets, sigs := rrsets(msg.Answer)
+	if ds := sets[rrsetKey{child, mdns.TypeDS}]; len(ds) > 0 {
+		keys, err := v.zoneKeys(ctx, zone)
+		if err != nil {
+			return 0, err
+		}
+		for _, sig := range sigs[rrsetKey{child, mdns.TypeDS}] {
+			if mdns.CanonicalName(sig.SignerName) == zone && v.verifyWithKeys(sig, keys, ds) == nil {
+				return secureZoneCut, nil
+			}
+		}
+		return 0, newError("failed to validate DS")
+	}
+
+	nsecs, nsec3s := v.denialRecords(ctx, zone, msg.Ns)
+	for _, nsec := range nsecs {
+		if mdns.CanonicalName(nsec.Hdr.Name) == child {
+			return zoneCutByTypes(nsec.TypeBitMap)
+		}
+	}
+	for _, nsec := range nsecs {
+		if nsecCovers(nsec, child) {
+			return notZoneCut, nil
+		}
+	}
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(child) {
+			return zoneCutByTypes(nsec3.TypeBitMap)
+		}
+	}
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(child) {
+			// Opt-out NSEC3 records may skip insecure delegations.
+			if nsec3.Flags&1 != 0 {
+				return insecureZoneCut, nil
+			}
+			return notZoneCut, nil
+		}
+	}
+	return 0, newError("no DS or proof of its absence")
+}
+
+func zoneCutByTypes(types []uint16) (zoneCutKind, error) {
+	switch {
+	case hasType(types, mdns.TypeDS):
+		return 0, newError("DS exists but is not answered")
+	case hasType(types, mdns.TypeNS) && !hasType(types, mdns.TypeSOA):
+		return insecureZoneCut, nil
+	default:
+		return notZoneCut, nil
+	}
+}
+
+// denialRecords returns the NSEC and NSEC3 records in section that are
+// validated by the keys of zone.
+func (v *dnssecValidator) denialRecords(ctx context.Context, zone string, section []mdns.RR) ([]*mdns.NSEC, []*mdns.NSEC3) {
+	keys, sets, sigs := rrsets(section)
+	var zoneKeys []*mdns.DNSKEY
+	var nsecs []*mdns.NSEC
+	var nsec3s []*mdns.NSEC3
+	for _, key := range keys {
+		if key.rrtype != mdns.TypeNSEC && key.rrtype != mdns.TypeNSEC3 {
+			continue
+		}
+		if !mdns.IsSubDomain(zone, key.name) {
+			continue
+		}
+		if zoneKeys == nil {
+			var err error
+			if zoneKeys, err = v.zoneKeys(ctx, zone); err != nil {
+				return nil, nil
+			}
+		}
+		var verified bool
+		for _, sig := range sigs[key] {
+			if mdns.CanonicalName(sig.SignerName) == zone && v.verifyWithKeys(sig, zoneKeys, sets[key]) == nil {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			continue
+		}
+		for _, rr := range sets[key] {
+			switch rr := rr.(type) {
+			case *mdns.NSEC:
+				nsecs = append(nsecs, rr)
+			case *mdns.NSEC3:
+				if rr.Iterations <= maxNSEC3Iterations {
+					nsec3s = append(nsec3s, rr)
+				}
+			}
+		}
+	}
+	return nsecs, nsec3s
+}
+
+// ancestors returns name and its ancestors up to the root, starting from name.
+func ancestors(name string) []string {
+	names := []string{name}
+	for off, end := 0, false; !end; {
+		off, end = mdns.NextLabel(name, off)
+		if end {
+			names = append(names, ".")
+		} else {
+			names = append(names, name[off:])
+		}
+	}
+	if name == "." {
+		return names[:1]
+	}
+	return names
+}
+
+func hasType(types []uint16, rrtype uint16) bool {
+	for _, t := range types {
+		if t == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalCompare compares names in the canonical order of RFC 4034.
+func canonicalCompare(a, b string) int {
+	la := mdns.SplitDomainName(mdns.CanonicalName(a))
+	lb := mdns.SplitDomainName(mdns.CanonicalName(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// nsecCovers reports whether nsec proves that name doesn't exist.
+func nsecCovers(nsec *mdns.NSEC, name string) bool {
+	owner := mdns.CanonicalName(nsec.Hdr.Name)
+	if mdns.IsSubDomain(owner, name) && (hasType(nsec.TypeBitMap, mdns.TypeDNAME) ||
+		hasType(nsec.TypeBitMap, mdns.TypeNS) && !hasType(nsec.TypeBitMap, mdns.TypeSOA)) {
+		// Names below a delegation or a DNAME are not in the zone.
+		return false
+	}
+	afterOwner := canonicalCompare(owner, name) < 0
+	beforeNext := canonicalCompare(name, nsec.NextDomain) < 0
+	if canonicalCompare(owner, nsec.NextDomain) < 0 {
+		return afterOwner && beforeNext
+	}
+	// The last NSEC record of the zone points back to the apex.
+	return afterOwner || beforeNext
+}
+
+// nsecEncloser returns the closest encloser of name, which nsec covers.
+func nsecEncloser(nsec *mdns.NSEC, name string) string {
+	labels := mdns.CompareDomainName(name, nsec.Hdr.Name)
+	if n := mdns.CompareDomainName(name, nsec.NextDomain); n > labels {
+		labels = n
+	}
+	names := ancestors(mdns.CanonicalName(name))
+	return names[len(names)-1-labels]
+}
+
+func wildcardOf(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+func nsecNameError(nsecs []*mdns.NSEC, name string) bool {
+	for _, nsec := range nsecs {
+		if !nsecCovers(nsec, name) {
+			continue
+		}
+		wildcard := wildcardOf(nsecEncloser(nsec, name))
+		for _, other := range nsecs {
+			if nsecCovers(other, wildcard) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func nsecNoData(nsecs []*mdns.NSEC, name string, rrtype uint16) bool {
+	for _, nsec := range nsecs {
+		if mdns.CanonicalName(nsec.Hdr.Name) == name {
+			return !hasType(nsec.TypeBitMap, rrtype) && !hasType(nsec.TypeBitMap, mdns.TypeCNAME)
+		}
+	}
+	// The name may be answered by a wildcard without the type.
+	for _, nsec := range nsecs {
+		if !nsecCovers(nsec, name) {
+			continue
+		}
+		wildcard := wildcardOf(nsecEncloser(nsec, name))
+		for _, other := range nsecs {
+			if mdns.CanonicalName(other.Hdr.Name) == wildcard {
+				return !hasType(other.TypeBitMap, rrtype) && !hasType(other.TypeBitMap, mdns.TypeCNAME)
+			}
+		}
+	}
+	return false
+}
+
+// nsec3Encloser returns the closest encloser of name in zone, which is proved
+// by a matching NSEC3 record of it and one covering the next closer name.
+func nsec3Encloser(nsec3s []*mdns.NSEC3, zone string, name string) (string, bool) {
+	names := ancestors(name)
+	for i := 1; i < len(names) && mdns.IsSubDomain(zone, names[i]); i++ {
+		var matched bool
+		for _, nsec3 := range nsec3s {
+			if nsec3.Match(names[i]) && !hasType(nsec3.TypeBitMap, mdns.TypeDNAME) &&
+				!(hasType(nsec3.TypeBitMap, mdns.TypeNS) && !hasType(nsec3.TypeBitMap, mdns.TypeSOA)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for _, nsec3 := range nsec3s {
+			if nsec3.Cover(names[i-1]) && !nsec3.Match(names[i-1]) {
+				return names[i], true
+			}
+		}
+		return "", false
+	}
+	return "", false
+}
+
+func nsec3NameError(nsec3s []*mdns.NSEC3, zone string, name string) bool {
+	encloser, found := nsec3Encloser(nsec3s, zone, name)
+	if !found {
+		return false
+	}
+	wildcard := wildcardOf(encloser)
+	for _, nsec3 := range nsec3s {
+		if nsec3.Cover(wildcard) && !nsec3.Match(wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+func nsec3NoData(nsec3s []*mdns.NSEC3, zone string, name string, rrtype uint16) bool {
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(name) {
+			return !hasType(nsec3.TypeBitMap, rrtype) && !hasType(nsec3.TypeBitMap, mdns.TypeCNAME)
+		}
+	}
+	// The name may be answered by a wildcard without the type.
+	encloser, found := nsec3Encloser(nsec3s, zone, name)
+	if !found {
+		return false
+	}
+	wildcard := wildcardOf(encloser)
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(wildcard) {
+			return !hasType(nsec3.TypeBitMap, rrtype) && !hasType(nsec3.TypeBitMap, mdns.TypeCNAME)
+		}
+	}
+	return false
+}
+
+func (v *dnssecValidator) query(ctx context.Context, name string, rrtype uint16) (*mdns.Msg, error) {
+	queryName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	message := withDNSSECOK(&dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  queryName,
+			Type:  dnsmessage.Type(rrtype),
+			Class: dnsmessage.ClassINET,
+		}},
+	})
+	response, err := v.exchange(ctx, message)
+	if err != nil {
+		return nil, newError("failed to query ", mdns.Type(rrtype), " of ", name).Base(err)
+	}
+	if response.RCode != dnsmessage.RCodeSuccess && response.RCode != dnsmessage.RCodeNameError {
+		return nil, newError("failed to query ", mdns.Type(rrtype), " of ", name, ": ", response.RCode)
+	}
+	return toMsg(response)
+}
+
+func toMsg(message *dnsmessage.Message) (*mdns.Msg, error) {
+	packed, err := message.Pack()
+	if err != nil {
+		return nil, newError("failed to pack dns message").Base(err)
+	}
+	msg := new(mdns.Msg)
+	if err := msg.Unpack(packed); err != nil {
+		return nil, newError("failed to parse dns message").Base(err)
+	}
+	return msg, nil
+}
+
+// withDNSSECOK returns a copy of message with the DO bit set in its OPT
+// record, adding one if there is none.
+func withDNSSECOK(message *dnsmessage.Message) *dnsmessage.Message {
+	newMessage := *message
+	newMessage.Additionals = make([]dnsmessage.Resource, 0, len(message.Additionals)+1)
+	var found bool
+	for _, resource := range message.Additionals {
+		if resource.Header.Type == dnsmessage.TypeOPT && !found {
+			common.Must(resource.Header.SetEDNS0(int(resource.Header.Class), resource.Header.ExtendedRCode(dnsmessage.RCodeSuccess), true))
+			found = true
+		}
+		newMessage.Additionals = append(newMessage.Additionals, resource)
+	}
+	if !found {
+		resource := dnsmessage.Resource{Body: &dnsmessage.OPTResource{}}
+		common.Must(resource.Header.SetEDNS0(1232, dnsmessage.RCodeSuccess, true))
+		newMessage.Additionals = append(newMessage.Additionals, resource)
+	}
+	return &newMessage
+}
+
+// prepareLookup prepares message like prepareQuery, and requests DNSSEC
+// records as well if answers from the server are validated.
+func (s *Server) prepareLookup(ctx context.Context, message *dnsmessage.Message) *dnsmessage.Message {
+	message = s.prepareQuery(ctx, message)
+	if s.validator != nil {
+		return withDNSSECOK(message)
+	}
+	return message
+}
+
+// validateResponse validates the answers of a lookup, and handles it as
+// SERVFAIL if the validation fails.
+func (c *Client) validateResponse(server *Server, d *serverQueryCallback, message *dnsmessage.Message) {
+	if err := server.validator.validate(d.ctx, message); err != nil {
+		newError("DNSSEC validation failed for domain ", d.domain, " at server ", server.name).Base(err).AtWarning().WriteToLog(session.ExportIDToError(d.ctx))
+		message = &dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:       message.ID,
+				Response: true,
+				RCode:    dnsmessage.RCodeServerFailure,
+			},
+		}
+	}
+	if common.Done(d.ctx) {
+		return
+	}
+	c.handleResponse(server, d, message)
+}
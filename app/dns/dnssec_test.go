@@ -0,0 +1,351 @@
+package dns
+
+import (
+	"context"
+	"crypto"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type signedZone struct {
+	key    *mdns.DNSKEY
+	signer crypto.Signer
+}
+
+func newSignedZone(name string) *signedZone {
+	key := &mdns.DNSKEY{
+		Hdr:       mdns.RR_Header{Name: name, Rrtype: mdns.TypeDNSKEY, Class: mdns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: mdns.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	common.Must(err)
+	return &signedZone{key: key, signer: privateKey.(crypto.Signer)}
+}
+
+func (z *signedZone) sign(expiration time.Time, rrset ...mdns.RR) *mdns.RRSIG {
+	sig := &mdns.RRSIG{
+		Hdr:        mdns.RR_Header{Name: rrset[0].Header().Name, Rrtype: mdns.TypeRRSIG, Class: mdns.ClassINET, Ttl: rrset[0].Header().Ttl},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.key.Hdr.Name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(expiration.Unix()),
+	}
+	common.Must(sig.Sign(z.signer, rrset))
+	return sig
+}
+
+// signedTransport answers queries from Exchange with the records in answers
+// and authorities, and the rcode in rcodes, keyed by name and type.
+type signedTransport struct {
+	answers     map[string][]mdns.RR
+	authorities map[string][]mdns.RR
+	rcodes      map[string]int
+	queries     int32
+	noDO        int32
+}
+
+func answerKey(name string, rrtype uint16) string {
+	return mdns.CanonicalName(name) + " " + mdns.Type(rrtype).String()
+}
+
+func (t *signedTransport) Type() dns.TransportType {
+	return dns.TransportTypeExchange
+}
+
+func (t *signedTransport) Write(context.Context, *dnsmessage.Message) error {
+	return common.ErrNoClue
+}
+
+func (t *signedTransport) Exchange(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	atomic.AddInt32(&t.queries, 1)
+	query, err := toMsg(message)
+	if err != nil {
+		return nil, err
+	}
+	if opt := query.IsEdns0(); opt == nil || !opt.Do() {
+		atomic.AddInt32(&t.noDO, 1)
+	}
+	response := new(mdns.Msg)
+	response.SetReply(query)
+	key := answerKey(query.Question[0].Name, query.Question[0].Qtype)
+	response.Answer = t.answers[key]
+	response.Ns = t.authorities[key]
+	response.Rcode = t.rcodes[key]
+	packed, err := response.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var parsed dnsmessage.Message
+	if err := parsed.Unpack(packed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func (t *signedTransport) ExchangeRaw(context.Context, *buf.Buffer) (*buf.Buffer, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *signedTransport) Lookup(context.Context, string, dns.QueryStrategy) ([]net.IP, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *signedTransport) Close() error {
+	return nil
+}
+
+type dnssecTestCase struct {
+	transport     *signedTransport
+	anchor        *mdns.DS
+	root, example *signedZone
+	expiration    time.Time
+}
+
+// newDNSSECTestCase signs www.example. with the chain of the root and
+// example. zones. The A answer is passed to modify before it's served.
+func newDNSSECTestCase(modify func(root, example *signedZone, answer []mdns.RR) []mdns.RR) *dnssecTestCase {
+	root := newSignedZone(".")
+	example := newSignedZone("example.")
+	expiration := time.Now().Add(time.Hour)
+
+	ds := example.key.ToDS(mdns.SHA256)
+	ds.Hdr.Ttl = 3600
+	a := &mdns.A{
+		Hdr: mdns.RR_Header{Name: "www.example.", Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 600},
+		A:   net.ParseIP("192.0.2.1").To4(),
+	}
+	answer := []mdns.RR{a, example.sign(expiration, a)}
+	if modify != nil {
+		answer = modify(root, example, answer)
+	}
+
+	return &dnssecTestCase{
+		transport: &signedTransport{
+			answers: map[string][]mdns.RR{
+				answerKey(".", mdns.TypeDNSKEY):        {root.key, root.sign(expiration, root.key)},
+				answerKey("example.", mdns.TypeDS):     {ds, root.sign(expiration, ds)},
+				answerKey("example.", mdns.TypeDNSKEY): {example.key, example.sign(expiration, example.key)},
+				answerKey("www.example.", mdns.TypeA):  answer,
+			},
+			authorities: make(map[string][]mdns.RR),
+			rcodes:      make(map[string]int),
+		},
+		anchor:     root.key.ToDS(mdns.SHA256),
+		root:       root,
+		example:    example,
+		expiration: expiration,
+	}
+}
+
+// nsec returns the NSEC record from name to next with types, and its
+// signature by zone.
+func (c *dnssecTestCase) nsec(zone *signedZone, name, next string, types ...uint16) []mdns.RR {
+	nsec := &mdns.NSEC{
+		Hdr:        mdns.RR_Header{Name: name, Rrtype: mdns.TypeNSEC, Class: mdns.ClassINET, Ttl: 600},
+		NextDomain: next,
+		TypeBitMap: append(types, mdns.TypeRRSIG, mdns.TypeNSEC),
+	}
+	sort.Slice(nsec.TypeBitMap, func(i, j int) bool { return nsec.TypeBitMap[i] < nsec.TypeBitMap[j] })
+	return []mdns.RR{nsec, zone.sign(c.expiration, nsec)}
+}
+
+// respond serves the authorities and rcode for the query of name and rrtype.
+func (c *dnssecTestCase) respond(name string, rrtype uint16, rcode int, authorities ...mdns.RR) {
+	c.transport.authorities[answerKey(name, rrtype)] = authorities
+	c.transport.rcodes[answerKey(name, rrtype)] = rcode
+}
+
+func (c *dnssecTestCase) newClient() *Client {
+	client := newTestClient(c.transport)
+	server := client.servers[0]
+	validator, err := newDNSSECValidator([]string{c.anchor.String()}, func(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+		return client.exchange(ctx, server, message)
+	})
+	common.Must(err)
+	server.validator = validator
+	return client
+}
+
+func TestDNSSECValidLookup(t *testing.T) {
+	c := newDNSSECTestCase(nil)
+	client := c.newClient()
+	defer client.Close()
+
+	ips, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Error("unexpected IPs: ", ips)
+	}
+	if noDO := atomic.LoadInt32(&c.transport.noDO); noDO != 0 {
+		t.Error("expected the DO bit in all queries, but got ", noDO, " queries without it")
+	}
+
+	queries := atomic.LoadInt32(&c.transport.queries)
+	if queries != 4 {
+		t.Error("expected queries of A, and DNSKEY and DS in the chain, but got ", queries)
+	}
+	if _, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if actual := atomic.LoadInt32(&c.transport.queries); actual != queries {
+		t.Error("expected the validated answer to be cached, but got ", actual-queries, " more queries")
+	}
+}
+
+func TestDNSSECValidatedKeysCached(t *testing.T) {
+	c := newDNSSECTestCase(nil)
+	client := c.newClient()
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		client.expireCache("www.example")
+	}
+	if queries := atomic.LoadInt32(&c.transport.queries); queries != 5 {
+		t.Error("expected the second lookup to query A only, but got ", queries, " queries in total")
+	}
+}
+
+func TestDNSSECValidationFailure(t *testing.T) {
+	cases := map[string]*dnssecTestCase{
+		"tampered": newDNSSECTestCase(func(_, _ *signedZone, answer []mdns.RR) []mdns.RR {
+			answer[0].(*mdns.A).A = net.ParseIP("198.51.100.1").To4()
+			return answer
+		}),
+		"no signature": newDNSSECTestCase(func(_, _ *signedZone, answer []mdns.RR) []mdns.RR {
+			return answer[:1]
+		}),
+		"expired": newDNSSECTestCase(func(_, example *signedZone, answer []mdns.RR) []mdns.RR {
+			return []mdns.RR{answer[0], example.sign(time.Now().Add(-time.Minute), answer[0])}
+		}),
+		"unknown key": newDNSSECTestCase(func(_, _ *signedZone, answer []mdns.RR) []mdns.RR {
+			return []mdns.RR{answer[0], newSignedZone("example.").sign(time.Now().Add(time.Hour), answer[0])}
+		}),
+		"signer not authoritative": newDNSSECTestCase(func(_, _ *signedZone, answer []mdns.RR) []mdns.RR {
+			return []mdns.RR{answer[0], newSignedZone("other.").sign(time.Now().Add(time.Hour), answer[0])}
+		}),
+	}
+	wrongAnchor := newDNSSECTestCase(nil)
+	wrongAnchor.anchor = newSignedZone(".").key.ToDS(mdns.SHA256)
+	cases["wrong trust anchor"] = wrongAnchor
+
+	for name, c := range cases {
+		client := c.newClient()
+		ips, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4)
+		expectServerFailure(t, name, ips, err)
+		client.Close()
+	}
+}
+
+func expectServerFailure(t *testing.T, name string, ips []net.IP, err error) {
+	t.Helper()
+	if rcode, ok := err.(dns.RCodeError); !ok || dnsmessage.RCode(rcode) != dnsmessage.RCodeServerFailure {
+		t.Error(name, ": expected SERVFAIL, but got ", ips, err)
+	}
+}
+
+// newInsecureTestCase serves www.example. unsigned, with the DS query of
+// example. answered by authorities.
+func newInsecureTestCase(authorities func(c *dnssecTestCase) []mdns.RR) *dnssecTestCase {
+	c := newDNSSECTestCase(func(_, _ *signedZone, answer []mdns.RR) []mdns.RR {
+		return answer[:1]
+	})
+	delete(c.transport.answers, answerKey("example.", mdns.TypeDS))
+	delete(c.transport.answers, answerKey("example.", mdns.TypeDNSKEY))
+	c.respond("example.", mdns.TypeDS, mdns.RcodeSuccess, authorities(c)...)
+	return c
+}
+
+func TestDNSSECInsecureDelegation(t *testing.T) {
+	c := newInsecureTestCase(func(c *dnssecTestCase) []mdns.RR {
+		return c.nsec(c.root, "example.", "net.", mdns.TypeNS)
+	})
+	client := c.newClient()
+	ips, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4)
+	if err != nil {
+		t.Error("expected the answer of an insecure delegation to be accepted, but got ", err)
+	} else if len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.1")) {
+		t.Error("unexpected IPs: ", ips)
+	}
+	client.Close()
+
+	cases := map[string]*dnssecTestCase{
+		"no proof": newInsecureTestCase(func(*dnssecTestCase) []mdns.RR {
+			return nil
+		}),
+		"unsigned proof": newInsecureTestCase(func(c *dnssecTestCase) []mdns.RR {
+			return c.nsec(c.root, "example.", "net.", mdns.TypeNS)[:1]
+		}),
+		"proof signed by child": newInsecureTestCase(func(c *dnssecTestCase) []mdns.RR {
+			return c.nsec(c.example, "example.", "net.", mdns.TypeNS)
+		}),
+		"DS in proof": newInsecureTestCase(func(c *dnssecTestCase) []mdns.RR {
+			return c.nsec(c.root, "example.", "net.", mdns.TypeNS, mdns.TypeDS)
+		}),
+	}
+	for name, c := range cases {
+		client := c.newClient()
+		ips, _, err := client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP4)
+		expectServerFailure(t, name, ips, err)
+		client.Close()
+	}
+}
+
+func TestDNSSECDenialOfExistence(t *testing.T) {
+	c := newDNSSECTestCase(nil)
+	nxdomain := c.nsec(c.example, "example.", "www.example.", mdns.TypeSOA, mdns.TypeNS, mdns.TypeDNSKEY)
+	c.respond("none.example.", mdns.TypeA, mdns.RcodeNameError, nxdomain...)
+	c.respond("none.example.", mdns.TypeDS, mdns.RcodeNameError, nxdomain...)
+	nodata := c.nsec(c.example, "www.example.", "example.", mdns.TypeA)
+	c.respond("www.example.", mdns.TypeAAAA, mdns.RcodeSuccess, nodata...)
+	c.respond("www.example.", mdns.TypeDS, mdns.RcodeSuccess, nodata...)
+	client := c.newClient()
+
+	ips, _, err := client.Lookup(context.Background(), "none.example", dns.QueryStrategy_USE_IP4)
+	if rcode, ok := err.(dns.RCodeError); !ok || dnsmessage.RCode(rcode) != dnsmessage.RCodeNameError {
+		t.Error("expected NXDOMAIN, but got ", ips, err)
+	}
+	ips, _, err = client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP6)
+	if err != dns.ErrEmptyResponse {
+		t.Error("expected empty response, but got ", ips, err)
+	}
+	client.Close()
+
+	forged := newDNSSECTestCase(nil)
+	forged.respond("none.example.", mdns.TypeA, mdns.RcodeNameError)
+	forged.respond("www.example.", mdns.TypeAAAA, mdns.RcodeSuccess, forged.nsec(forged.example, "www.example.", "example.", mdns.TypeA, mdns.TypeAAAA)...)
+	forged.respond("www.example.", mdns.TypeDS, mdns.RcodeSuccess, forged.nsec(forged.example, "www.example.", "example.", mdns.TypeA)...)
+	client = forged.newClient()
+	ips, _, err = client.Lookup(context.Background(), "none.example", dns.QueryStrategy_USE_IP4)
+	expectServerFailure(t, "NXDOMAIN without proof", ips, err)
+	ips, _, err = client.Lookup(context.Background(), "www.example", dns.QueryStrategy_USE_IP6)
+	expectServerFailure(t, "NODATA of an existing type", ips, err)
+	client.Close()
+}
+
+func TestDNSSECInvalidTrustAnchor(t *testing.T) {
+	for _, anchor := range []string{"invalid", ". IN A 192.0.2.1"} {
+		if _, err := newDNSSECValidator([]string{anchor}, nil); err == nil {
+			t.Error("expected error for trust anchor ", anchor)
+		}
+	}
+	if _, err := newDNSSECValidator(nil, nil); err != nil {
+		t.Error("failed to parse the default trust anchor: ", err)
+	}
+}
@@ -1,6 +1,8 @@
 package dns
 
 import (
+	"strings"
+
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/strmatcher"
@@ -8,19 +10,34 @@ import (
 	"github.com/v2fly/v2ray-core/v5/features/dns"
 )
 
+// hostsTTL is the TTL of the records of static hosts, in seconds.
+const hostsTTL = 60
+
+// hostKey identifies the domain of mappings. Mappings of the same domain are
+// merged into one record.
+type hostKey struct {
+	t      DomainMatchingType
+	domain string
+}
+
 // StaticHosts represents static domain-ip mapping in DNS server.
 type StaticHosts struct {
 	ips      [][]net.Address
+	wildcard []string
 	matchers *strmatcher.LinearIndexMatcher
 }
 
-// NewStaticHosts creates a new StaticHosts instance.
+// NewStaticHosts creates a new StaticHosts instance. A full domain starting
+// with "*." matches the subdomains of the rest, but not itself. If a domain
+// matches several mappings, only the most specific ones are used.
 func NewStaticHosts(hosts []*HostMapping, legacy map[string]*net.IPOrDomain) (*StaticHosts, error) {
 	g := new(strmatcher.LinearIndexMatcher)
 	sh := &StaticHosts{
 		ips:      make([][]net.Address, len(hosts)+len(legacy)+16),
+		wildcard: make([]string, len(hosts)+len(legacy)+16),
 		matchers: g,
 	}
+	ids := make(map[hostKey]uint32)
 
 	if legacy != nil {
 		features.PrintDeprecatedFeatureWarning("simple host mapping")
@@ -40,11 +57,24 @@ func NewStaticHosts(hosts []*HostMapping, legacy map[string]*net.IPOrDomain) (*S
 	}
 
 	for _, mapping := range hosts {
-		matcher, err := ToStrMatcher(mapping.Type, mapping.Domain)
-		if err != nil {
-			return nil, newError("failed to create domain matcher").Base(err)
+		key := hostKey{t: mapping.Type, domain: mapping.Domain}
+		id, found := ids[key]
+		if !found {
+			t, domain, wildcard := mapping.Type, mapping.Domain, false
+			if t == DomainMatchingType_Full && strings.HasPrefix(domain, "*.") {
+				t, domain, wildcard = DomainMatchingType_Subdomain, domain[2:], true
+			}
+			matcher, err := ToStrMatcher(t, domain)
+			if err != nil {
+				return nil, newError("failed to create domain matcher").Base(err)
+			}
+			id = g.Add(matcher)
+			ids[key] = id
+			if wildcard {
+				sh.wildcard[id] = domain
+			}
 		}
-		id := g.Add(matcher)
+
 		ips := make([]net.Address, 0, len(mapping.Ip)+1)
 		switch {
 		case len(mapping.ProxiedDomain) > 0:
@@ -61,7 +91,7 @@ func NewStaticHosts(hosts []*HostMapping, legacy map[string]*net.IPOrDomain) (*S
 			return nil, newError("neither IP address nor proxied domain specified for domain: ", mapping.Domain).AtWarning()
 		}
 
-		sh.ips[id] = ips
+		sh.ips[id] = append(sh.ips[id], ips...)
 	}
 
 	return sh, nil
@@ -77,30 +107,58 @@ func filterIP(ips []net.Address, option dns.IPOption) []net.Address {
 	return filtered
 }
 
+// hostsOption returns the types of IP addresses queried by strategy.
+func hostsOption(strategy dns.QueryStrategy) dns.IPOption {
+	switch strategy {
+	case dns.QueryStrategy_USE_IP4:
+		return dns.IPOption{IPv4Enable: true}
+	case dns.QueryStrategy_USE_IP6:
+		return dns.IPOption{IPv6Enable: true}
+	default:
+		return dns.IPOption{IPv4Enable: true, IPv6Enable: true}
+	}
+}
+
+// lookupInternal returns the record of the most specific mapping of domain.
 func (h *StaticHosts) lookupInternal(domain string) []net.Address {
-	var ips []net.Address
 	for _, id := range h.matchers.Match(domain) {
-		ips = append(ips, h.ips[id]...)
+		// Wildcards don't match their apex domains.
+		if apex := h.wildcard[id]; apex != "" && apex == domain {
+			continue
+		}
+		return h.ips[id]
 	}
-	return ips
+	return nil
 }
 
 func (h *StaticHosts) lookup(domain string, option dns.IPOption, maxDepth int) []net.Address {
-	switch addrs := h.lookupInternal(domain); {
-	case len(addrs) == 0: // Not recorded in static hosts, return nil
+	addrs := h.lookupInternal(domain)
+	if len(addrs) == 0 { // Not recorded in static hosts, return nil
 		return nil
-	case len(addrs) == 1 && addrs[0].Family().IsDomain(): // Try to unwrap domain
-		newError("found replaced domain: ", domain, " -> ", addrs[0].Domain(), ". Try to unwrap it").AtDebug().WriteToLog()
-		if maxDepth > 0 {
-			unwrapped := h.lookup(addrs[0].Domain(), option, maxDepth-1)
-			if unwrapped != nil {
+	}
+
+	var ips, domains []net.Address
+	for _, addr := range addrs {
+		if addr.Family().IsDomain() {
+			domains = append(domains, addr)
+		} else {
+			ips = append(ips, addr)
+		}
+	}
+	if len(ips) > 0 { // IP record found, return a non-nil IP array
+		return filterIP(ips, option)
+	}
+
+	// Try to unwrap the domains, or leave the first one to the resolver.
+	if maxDepth > 0 {
+		for _, addr := range domains {
+			newError("found replaced domain: ", domain, " -> ", addr.Domain(), ". Try to unwrap it").AtDebug().WriteToLog()
+			if unwrapped := h.lookup(addr.Domain(), option, maxDepth-1); unwrapped != nil {
 				return unwrapped
 			}
 		}
-		return addrs
-	default: // IP record found, return a non-nil IP array
-		return filterIP(addrs, option)
 	}
+	return domains[:1]
 }
 
 // Lookup returns IP addresses or proxied domain for the given domain, if exists in this StaticHosts.
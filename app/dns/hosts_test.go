@@ -0,0 +1,142 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newTestHosts(t *testing.T) *StaticHosts {
+	hosts, err := NewStaticHosts([]*HostMapping{
+		{Type: DomainMatchingType_Full, Domain: "*.example.com", Ip: [][]byte{{10, 0, 0, 1}}},
+		{Type: DomainMatchingType_Full, Domain: "www.example.com", Ip: [][]byte{{10, 0, 0, 2}}},
+		{Type: DomainMatchingType_Full, Domain: "www.example.com", Ip: [][]byte{net.ParseIP("fd00::2")}},
+		{Type: DomainMatchingType_Subdomain, Domain: "dev.example.com", Ip: [][]byte{{10, 0, 1, 1}, {10, 0, 1, 2}}},
+		{Type: DomainMatchingType_Full, Domain: "alias.example.com", ProxiedDomain: "www.example.com"},
+		{Type: DomainMatchingType_Full, Domain: "cdn.example.com", ProxiedDomain: "missing.example.org"},
+		{Type: DomainMatchingType_Full, Domain: "cdn.example.com", ProxiedDomain: "cdn.example.net"},
+		{Type: DomainMatchingType_Full, Domain: "cdn.example.net", Ip: [][]byte{{10, 0, 2, 1}}},
+		{Type: DomainMatchingType_Full, Domain: "remote.example.com", ProxiedDomain: "v2fly.org"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hosts
+}
+
+func TestStaticHostsLookup(t *testing.T) {
+	hosts := newTestHosts(t)
+	all := dns.IPOption{IPv4Enable: true, IPv6Enable: true}
+
+	cases := []struct {
+		domain string
+		option dns.IPOption
+		addrs  []string
+	}{
+		// Wildcards match subdomains of all levels, but not the domain itself.
+		{"a.example.com", all, []string{"10.0.0.1"}},
+		{"a.b.example.com", all, []string{"10.0.0.1"}},
+		{"example.com", all, nil},
+		// Exact names win over wildcards, and their records are merged.
+		{"www.example.com", all, []string{"10.0.0.2", "[fd00::2]"}},
+		{"www.example.com", dns.IPOption{IPv6Enable: true}, []string{"[fd00::2]"}},
+		// The deeper subdomain wins.
+		{"x.dev.example.com", all, []string{"10.0.1.1", "10.0.1.2"}},
+		// Aliases are unwrapped.
+		{"alias.example.com", dns.IPOption{IPv4Enable: true}, []string{"10.0.0.2"}},
+		// The first alias in the hosts is used.
+		{"cdn.example.com", all, []string{"10.0.2.1"}},
+		// Aliases not in the hosts are left to the resolver.
+		{"remote.example.com", all, []string{"v2fly.org"}},
+	}
+	for _, test := range cases {
+		var actual []string
+		for _, addr := range hosts.Lookup(test.domain, test.option) {
+			actual = append(actual, addr.String())
+		}
+		if len(actual) != len(test.addrs) {
+			t.Error("for ", test.domain, " expected ", test.addrs, " but got ", actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != test.addrs[i] {
+				t.Error("for ", test.domain, " expected ", test.addrs, " but got ", actual)
+				break
+			}
+		}
+	}
+
+	// Recorded domains without addresses of the type have an empty result.
+	if addrs := hosts.Lookup("a.example.com", dns.IPOption{IPv6Enable: true}); addrs == nil || len(addrs) != 0 {
+		t.Error("expected an empty result, but got ", addrs)
+	}
+}
+
+// answerTransport answers A queries of name with ip.
+type answerTransport struct {
+	name string
+	ip   [4]byte
+}
+
+func (t *answerTransport) Type() dns.TransportType {
+	return dns.TransportTypeExchange
+}
+
+func (t *answerTransport) Write(context.Context, *dnsmessage.Message) error {
+	return common.ErrNoClue
+}
+
+func (t *answerTransport) Exchange(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	response := &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: message.ID, Response: true, RCode: dnsmessage.RCodeNameError},
+		Questions: message.Questions,
+	}
+	if q := message.Questions[0]; q.Type == dnsmessage.TypeA && q.Name.String() == t.name {
+		response.RCode = dnsmessage.RCodeSuccess
+		response.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   &dnsmessage.AResource{A: t.ip},
+		}}
+	}
+	return response, nil
+}
+
+func (t *answerTransport) ExchangeRaw(context.Context, *buf.Buffer) (*buf.Buffer, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *answerTransport) Lookup(context.Context, string, dns.QueryStrategy) ([]net.IP, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *answerTransport) Close() error {
+	return nil
+}
+
+func TestClientLookupHosts(t *testing.T) {
+	client := newTestClient(&answerTransport{name: "v2fly.org.", ip: [4]byte{1, 2, 3, 4}})
+	defer client.Close()
+	client.hosts = newTestHosts(t)
+
+	ips, _, err := client.Lookup(context.Background(), "www.example.com", dns.QueryStrategy_USE_IP)
+	common.Must(err)
+	if len(ips) != 2 || ips[0].String() != "10.0.0.2" || ips[1].String() != "fd00::2" {
+		t.Error("unexpected IPs of static hosts: ", ips)
+	}
+
+	// Aliases not in the hosts are resolved by the servers.
+	ips, _, err = client.Lookup(context.Background(), "remote.example.com", dns.QueryStrategy_USE_IP4)
+	common.Must(err)
+	if len(ips) != 1 || ips[0].String() != "1.2.3.4" {
+		t.Error("unexpected IPs of the alias: ", ips)
+	}
+
+	if _, _, err := client.Lookup(context.Background(), "a.example.com", dns.QueryStrategy_USE_IP6); err != dns.ErrEmptyResponse {
+		t.Error("expected an empty response, but got ", err)
+	}
+}
@@ -2,7 +2,6 @@ package dns
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
 	"net/url"
 	"strings"
@@ -17,6 +16,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/uuid"
 	"github.com/v2fly/v2ray-core/v5/features/dns"
 	"github.com/v2fly/v2ray-core/v5/features/dns/localdns"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/geodata"
@@ -65,11 +65,15 @@ func init() {
 
 		for _, v := range simplifiedConfig.NameServer {
 			nameserver := &NameServer{
-				Address:      v.Address,
-				ClientIp:     net.ParseIP(v.ClientIp),
-				SkipFallback: v.SkipFallback,
-				Geoip:        v.Geoip,
-				Concurrency:  v.Concurrency,
+				Address:            v.Address,
+				ClientIp:           net.ParseIP(v.ClientIp),
+				ClientIpPrefixV4:   v.ClientIpPrefixV4,
+				ClientIpPrefixV6:   v.ClientIpPrefixV6,
+				ClientIpFromSource: v.ClientIpFromSource,
+				SkipFallback:       v.SkipFallback,
+				Geoip:              v.Geoip,
+				Concurrency:        v.Concurrency,
+				Tag:                v.Tag,
 			}
 			for _, prioritizedDomain := range v.PrioritizedDomain {
 				nameserver.PrioritizedDomain = append(nameserver.PrioritizedDomain, &NameServer_PriorityDomain{
@@ -81,13 +85,17 @@ func init() {
 		}
 
 		fullConfig := &Config{
-			NameServer:      nameservers,
-			ClientIp:        net.ParseIP(simplifiedConfig.ClientIp),
-			StaticHosts:     simplifiedConfig.StaticHosts,
-			Tag:             simplifiedConfig.Tag,
-			DisableCache:    simplifiedConfig.DisableCache,
-			QueryStrategy:   simplifiedConfig.QueryStrategy,
-			DisableFallback: simplifiedConfig.DisableFallback,
+			NameServer:           nameservers,
+			ClientIp:             net.ParseIP(simplifiedConfig.ClientIp),
+			StaticHosts:          simplifiedConfig.StaticHosts,
+			Tag:                  simplifiedConfig.Tag,
+			DisableCache:         simplifiedConfig.DisableCache,
+			QueryStrategy:        simplifiedConfig.QueryStrategy,
+			DisableFallback:      simplifiedConfig.DisableFallback,
+			DisableNegativeCache: simplifiedConfig.DisableNegativeCache,
+			NegativeCacheMaxTtl:  simplifiedConfig.NegativeCacheMaxTtl,
+			DnssecValidation:     simplifiedConfig.DnssecValidation,
+			DnssecTrustAnchor:    simplifiedConfig.DnssecTrustAnchor,
 		}
 		return common.CreateObject(ctx, fullConfig)
 	}))
@@ -130,8 +138,8 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 	domainMatcher := strmatcher.NewMixedIndexMatcher()
 	geoipContainer := router.GeoIPMatcherContainer{}
 
-	core := core.MustFromContext(ctx)
-	dispatcher, _ := core.GetFeature(routing.DispatcherType()).(routing.Dispatcher)
+	v := core.MustFromContext(ctx)
+	dispatcher, _ := v.GetFeature(routing.DispatcherType()).(routing.Dispatcher)
 
 	ctx, cancel := context.WithCancel(ctx)
 	client := &Client{
@@ -151,6 +159,10 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		disableFallback:        config.DisableFallback,
 		disableFallbackIfMatch: config.DisableFallbackIfMatch,
 		disableExpire:          config.DisableExpire,
+		disableNegativeCache:   config.DisableNegativeCache,
+		negativeCacheMaxTTL:    config.NegativeCacheMaxTtl,
+
+		taggedServers: make(map[string]*Server),
 	}
 
 	for _, ns := range config.NameServer {
@@ -173,6 +185,24 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		if err != nil {
 			return nil, newError("failed to create client").Base(err)
 		}
+		if config.DnssecValidation {
+			if server.transport.Type() == dns.TransportTypeLookup {
+				newError("DNS: client ", server.name, " doesn't support DNSSEC validation").AtWarning().WriteToLog()
+			} else {
+				server.validator, err = newDNSSECValidator(config.DnssecTrustAnchor, func(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+					return client.exchange(ctx, server, message)
+				})
+				if err != nil {
+					return nil, newError("failed to create DNSSEC validator").Base(err)
+				}
+			}
+		}
+		if server.tag != "" {
+			if _, found := client.taggedServers[server.tag]; found {
+				return nil, newError("duplicated DNS server tag ", server.tag)
+			}
+			client.taggedServers[server.tag] = server
+		}
 		servers = append(servers, server)
 	}
 
@@ -188,6 +218,13 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		return nil, err
 	}
 	client.servers = servers
+
+	if err := core.RequireFeatures(ctx, func(pm policy.Manager) error {
+		client.policyManager = pm
+		return client.checkLevelServers()
+	}); err != nil {
+		return nil, newError("failed to set up DNS servers of user levels").Base(err)
+	}
 	return client, nil
 }
 
@@ -248,14 +285,21 @@ func newServer(
 		case *net.IPOrDomain_Ip:
 			newError("DNS: client ", ns.Address.Address.GetIp(), " uses clientIP ", clientIP.String()).AtInfo().WriteToLog()
 		}
+	} else if ns.ClientIpFromSource {
+		newError("DNS: client ", ns.Address.Address.AsAddress(), " uses clientIP from inbound source").AtInfo().WriteToLog()
 	}
 
 	server := &Server{
-		clientIP:     clientIP,
-		skipFallback: ns.SkipFallback,
-		domains:      rules,
-		expectIPs:    matchers,
-		concurrency:  ns.Concurrency,
+		tag:                ns.Tag,
+		clientIP:           clientIP,
+		clientIPPrefixV4:   ns.ClientIpPrefixV4,
+		clientIPPrefixV6:   ns.ClientIpPrefixV6,
+		clientIPFromSource: ns.ClientIpFromSource,
+		skipFallback:       ns.SkipFallback,
+		domains:            rules,
+		expectIPs:          matchers,
+		concurrency:        ns.Concurrency,
+		queryStrategy:      dns.QueryStrategy(ns.QueryStrategy),
 	}
 
 	var name string
@@ -402,6 +446,20 @@ func newServer(
 			name = "DOHL//" + link.String()
 			trans.destination = destination
 			transport = NewHTTPSLocalTransport(trans)
+		case "h3":
+			link.Scheme = "https"
+			destination.Address = net.DomainAddress(link.String())
+			link.Scheme = ""
+			name = "DOH3//" + link.String()
+			trans.destination = destination
+			transport = NewHTTP3Transport(trans, outbound)
+		case "h3+local":
+			link.Scheme = "https"
+			destination.Address = net.DomainAddress(link.String())
+			link.Scheme = ""
+			name = "DOH3L//" + link.String()
+			trans.destination = destination
+			transport = NewHTTP3LocalTransport(trans)
 		case "quic":
 			name = "DOQ//" + link.Hostname()
 			destination.Network = net.Network_UDP
@@ -528,6 +586,38 @@ func (c *Client) sortServers(domain string) []*Server {
 	return clients
 }
 
+// restrictStrategy returns strategy limited to the query strategy of the
+// server.
+func (c *Server) restrictStrategy(strategy dns.QueryStrategy) dns.QueryStrategy {
+	switch c.queryStrategy {
+	case dns.QueryStrategy_USE_IP4, dns.QueryStrategy_USE_IP6:
+		return c.queryStrategy
+	default:
+		return strategy
+	}
+}
+
+// filterQueries returns the queries of messages for the types of IP addresses
+// of the query strategy of the server.
+func (c *Server) filterQueries(messages []*dnsmessage.Message) []*dnsmessage.Message {
+	var excluded dnsmessage.Type
+	switch c.queryStrategy {
+	case dns.QueryStrategy_USE_IP4:
+		excluded = dnsmessage.TypeAAAA
+	case dns.QueryStrategy_USE_IP6:
+		excluded = dnsmessage.TypeA
+	default:
+		return messages
+	}
+	filtered := make([]*dnsmessage.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Questions[0].Type != excluded {
+			filtered = append(filtered, message)
+		}
+	}
+	return filtered
+}
+
 func (c *Server) matchExpectedIPs(domain string, ips []net.IP) ([]net.IP, error) {
 	if len(c.expectIPs) == 0 {
 		return ips, nil
@@ -612,54 +702,6 @@ func Fqdn(domain string) string {
 	return domain + "."
 }
 
-func genEDNS0Options(clientIP net.IP) *dnsmessage.Resource {
-	if len(clientIP) == 0 {
-		return nil
-	}
-
-	var netmask int
-	var family uint16
-
-	if len(clientIP) == 4 {
-		family = 1
-		netmask = 24 // 24 for IPV4, 96 for IPv6
-	} else {
-		family = 2
-		netmask = 96
-	}
-
-	b := make([]byte, 4)
-	binary.BigEndian.PutUint16(b[0:], family)
-	b[2] = byte(netmask)
-	b[3] = 0
-	switch family {
-	case 1:
-		ip := clientIP.To4().Mask(net.CIDRMask(netmask, net.IPv4len*8))
-		needLength := (netmask + 8 - 1) / 8 // division rounding up
-		b = append(b, ip[:needLength]...)
-	case 2:
-		ip := clientIP.Mask(net.CIDRMask(netmask, net.IPv6len*8))
-		needLength := (netmask + 8 - 1) / 8 // division rounding up
-		b = append(b, ip[:needLength]...)
-	}
-
-	const EDNS0SUBNET = 0x08
-
-	opt := new(dnsmessage.Resource)
-	common.Must(opt.Header.SetEDNS0(1350, 0xfe00, true))
-
-	opt.Body = &dnsmessage.OPTResource{
-		Options: []dnsmessage.Option{
-			{
-				Code: EDNS0SUBNET,
-				Data: b,
-			},
-		},
-	}
-
-	return opt
-}
-
 // DomainMatcherInfo contains information attached to index returned by Server.domainMatcher
 type DomainMatcherInfo struct {
 	ClientIdx     uint16
@@ -0,0 +1,75 @@
+package dns
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+func newTestNameServer(ip string, skipFallback bool, domains ...*NameServer_PriorityDomain) *NameServer {
+	return &NameServer{
+		Address: &net.Endpoint{
+			Network: net.Network_UDP,
+			Address: net.NewIPOrDomain(net.ParseAddress(ip)),
+		},
+		SkipFallback:      skipFallback,
+		PrioritizedDomain: domains,
+	}
+}
+
+func serverNames(servers []*Server) []string {
+	names := make([]string, 0, len(servers))
+	for _, server := range servers {
+		names = append(names, server.name)
+	}
+	return names
+}
+
+func TestSortServers(t *testing.T) {
+	config := &Config{
+		NameServer: []*NameServer{
+			newTestNameServer("8.8.8.8", false),
+			newTestNameServer("10.0.0.1", false, &NameServer_PriorityDomain{Type: DomainMatchingType_Subdomain, Domain: "corp.example.com"}),
+			newTestNameServer("10.0.0.2", true, &NameServer_PriorityDomain{Type: DomainMatchingType_Subdomain, Domain: "dev.corp.example.com"}),
+			newTestNameServer("10.0.0.3", true, &NameServer_PriorityDomain{Type: DomainMatchingType_Full, Domain: "www.dev.corp.example.com"}),
+			newTestNameServer("10.0.0.4", true,
+				&NameServer_PriorityDomain{Type: DomainMatchingType_Keyword, Domain: "corp"},
+				&NameServer_PriorityDomain{Type: DomainMatchingType_Regex, Domain: `^api\.`},
+			),
+		},
+	}
+	ctx := core.WithContext(context.Background(), &core.Instance{})
+	client, err := New(ctx, config)
+	common.Must(err)
+	defer client.Close()
+
+	cases := []struct {
+		domain  string
+		servers []string
+	}{
+		// Unmatched domains go to servers without skipFallback.
+		{"v2fly.org", []string{"UDP//8.8.8.8", "UDP//10.0.0.1"}},
+		// The server of the most specific domain goes first.
+		{"corp.example.com", []string{"UDP//10.0.0.1", "UDP//10.0.0.4", "UDP//8.8.8.8"}},
+		{"a.dev.corp.example.com", []string{"UDP//10.0.0.2", "UDP//10.0.0.1", "UDP//10.0.0.4", "UDP//8.8.8.8"}},
+		{"www.dev.corp.example.com", []string{"UDP//10.0.0.3", "UDP//10.0.0.2", "UDP//10.0.0.1", "UDP//10.0.0.4", "UDP//8.8.8.8"}},
+		{"api.example.com", []string{"UDP//10.0.0.4", "UDP//8.8.8.8", "UDP//10.0.0.1"}},
+	}
+	for _, c := range cases {
+		if actual := serverNames(client.sortServers(c.domain)); !reflect.DeepEqual(actual, c.servers) {
+			t.Error("domain ", c.domain, ": expected ", c.servers, ", but got ", actual)
+		}
+	}
+
+	client.disableFallbackIfMatch = true
+	if actual := serverNames(client.sortServers("a.dev.corp.example.com")); !reflect.DeepEqual(actual, []string{"UDP//10.0.0.2", "UDP//10.0.0.1", "UDP//10.0.0.4"}) {
+		t.Error("expected no fallback for matched domain, but got ", actual)
+	}
+	if actual := serverNames(client.sortServers("v2fly.org")); !reflect.DeepEqual(actual, []string{"UDP//8.8.8.8", "UDP//10.0.0.1"}) {
+		t.Error("expected fallback for unmatched domain, but got ", actual)
+	}
+}
@@ -0,0 +1,140 @@
+package dns
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/anypb"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/policy"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+)
+
+func contextWithLevel(level uint32) context.Context {
+	return session.ContextWithInbound(context.Background(), &session.Inbound{
+		User: &protocol.MemoryUser{Level: level},
+	})
+}
+
+func TestLevelDNSServer(t *testing.T) {
+	client := newTestClient(&answerTransport{name: "v2fly.org.", ip: [4]byte{1, 1, 1, 1}})
+	defer client.Close()
+	serverA := &Server{name: "a", tag: "a", transport: &answerTransport{name: "v2fly.org.", ip: [4]byte{10, 0, 0, 1}}, skipFallback: true}
+	serverB := &Server{name: "b", tag: "b", transport: &answerTransport{name: "v2fly.org.", ip: [4]byte{10, 0, 0, 2}}, skipFallback: true}
+	client.servers = append(client.servers, serverA, serverB)
+	client.taggedServers = map[string]*Server{"a": serverA, "b": serverB}
+	policyManager, err := policy.New(context.Background(), &policy.Config{
+		Level: map[uint32]*policy.Policy{
+			1: {DnsServer: "a"},
+			2: {DnsServer: "b"},
+			3: {DnsServer: "unknown"},
+			4: {MaxConnections: 1},
+		},
+	})
+	common.Must(err)
+	client.policyManager = policyManager
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		ip   string
+	}{
+		{name: "no user", ctx: context.Background(), ip: "1.1.1.1"},
+		{name: "level 0", ctx: contextWithLevel(0), ip: "1.1.1.1"},
+		{name: "level 1", ctx: contextWithLevel(1), ip: "10.0.0.1"},
+		{name: "level 2", ctx: contextWithLevel(2), ip: "10.0.0.2"},
+		{name: "unknown server", ctx: contextWithLevel(3), ip: "1.1.1.1"},
+		{name: "level without server", ctx: contextWithLevel(4), ip: "1.1.1.1"},
+	}
+
+	// Answers of each server are cached separately, and served from the
+	// cache in the second round.
+	for round := 0; round < 2; round++ {
+		for _, test := range cases {
+			ips, _, err := client.Lookup(test.ctx, "v2fly.org", dns.QueryStrategy_USE_IP4)
+			if err != nil {
+				t.Fatal(test.name, ": ", err)
+			}
+			if len(ips) != 1 || ips[0].String() != test.ip {
+				t.Error(test.name, ": expected ", test.ip, " but got ", ips)
+			}
+		}
+	}
+}
+
+func TestLevelDNSServerInInstance(t *testing.T) {
+	server := func(ip string, tag string) *NameServer {
+		ns := newTestNameServer(ip, tag != "")
+		ns.Tag = tag
+		return ns
+	}
+	// The DNS client is created before the policy manager it depends on.
+	instance, err := core.New(&core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&Config{
+				NameServer: []*NameServer{
+					server("8.8.8.8", ""),
+					server("10.0.0.1", "a"),
+				},
+			}),
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					1: {DnsServer: "a"},
+				},
+			}),
+		},
+	})
+	common.Must(err)
+
+	client := instance.GetFeature(dns.ClientType()).(*Client)
+	defer client.Close()
+	if client.policyManager == nil {
+		t.Fatal("expected policy manager of the instance")
+	}
+	client.servers[0].transport = &answerTransport{name: "v2fly.org.", ip: [4]byte{1, 1, 1, 1}}
+	client.servers[1].transport = &answerTransport{name: "v2fly.org.", ip: [4]byte{10, 0, 0, 1}}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		ip   string
+	}{
+		{name: "level 0", ctx: contextWithLevel(0), ip: "1.1.1.1"},
+		{name: "level 1", ctx: contextWithLevel(1), ip: "10.0.0.1"},
+	}
+	for _, test := range cases {
+		ips, _, err := client.Lookup(test.ctx, "v2fly.org", dns.QueryStrategy_USE_IP4)
+		if err != nil {
+			t.Fatal(test.name, ": ", err)
+		}
+		if len(ips) != 1 || ips[0].String() != test.ip {
+			t.Error(test.name, ": expected ", test.ip, " but got ", ips)
+		}
+	}
+}
+
+func TestLevelDNSServerNotFound(t *testing.T) {
+	ns := newTestNameServer("10.0.0.1", true)
+	ns.Tag = "a"
+	dnsConfig := serial.ToTypedMessage(&Config{NameServer: []*NameServer{ns}})
+	policyConfig := serial.ToTypedMessage(&policy.Config{
+		Level: map[uint32]*policy.Policy{
+			1: {DnsServer: "unknown"},
+		},
+	})
+
+	// The policy manager may be created before or after the DNS client.
+	for _, apps := range [][]*anypb.Any{
+		{dnsConfig, policyConfig},
+		{policyConfig, dnsConfig},
+	} {
+		if _, err := core.New(&core.Config{App: apps}); err == nil {
+			t.Error("expected error for unknown DNS server of user level")
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/strmatcher"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// staticTransport answers every query with rcode and an optional SOA record.
+type staticTransport struct {
+	rcode   dnsmessage.RCode
+	soa     bool
+	queries int32
+}
+
+func (t *staticTransport) Type() dns.TransportType {
+	return dns.TransportTypeExchange
+}
+
+func (t *staticTransport) Write(context.Context, *dnsmessage.Message) error {
+	return common.ErrNoClue
+}
+
+func (t *staticTransport) Exchange(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	atomic.AddInt32(&t.queries, 1)
+	response := &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: message.ID, Response: true, RCode: t.rcode},
+		Questions: message.Questions,
+	}
+	if t.soa {
+		response.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 7200},
+			Body: &dnsmessage.SOAResource{
+				NS:     dnsmessage.MustNewName("ns.example.com."),
+				MBox:   dnsmessage.MustNewName("hostmaster.example.com."),
+				MinTTL: 300,
+			},
+		}}
+	}
+	return response, nil
+}
+
+func (t *staticTransport) ExchangeRaw(context.Context, *buf.Buffer) (*buf.Buffer, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *staticTransport) Lookup(context.Context, string, dns.QueryStrategy) ([]net.IP, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *staticTransport) Close() error {
+	return nil
+}
+
+func newTestClient(transport dns.Transport) *Client {
+	domainMatcher := strmatcher.NewMixedIndexMatcher()
+	common.Must(domainMatcher.Build())
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		ctx:           ctx,
+		cancel:        cancel,
+		domainMatcher: domainMatcher,
+		servers:       []*Server{{name: "test", transport: transport}},
+	}
+}
+
+func (c *Client) expireCache(domain string) {
+	cacheI, _ := c.cache.Load(domain)
+	cache := cacheI.(*ipCacheEntire)
+	cache.expire4 = time.Now().Add(-time.Second)
+	cache.expire6 = cache.expire4
+}
+
+func TestNegativeCacheNXDomain(t *testing.T) {
+	transport := &staticTransport{rcode: dnsmessage.RCodeNameError, soa: true}
+	client := newTestClient(transport)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		_, ttl, err := client.Lookup(context.Background(), "nonexistent.example.com", dns.QueryStrategy_USE_IP4)
+		if rcode, ok := err.(dns.RCodeError); !ok || dnsmessage.RCode(rcode) != dnsmessage.RCodeNameError {
+			t.Fatal("expected NXDOMAIN, but got ", err)
+		}
+		if i == 1 && ttl != 300 {
+			t.Error("expected TTL of SOA minimum 300, but got ", ttl)
+		}
+	}
+	if queries := atomic.LoadInt32(&transport.queries); queries != 1 {
+		t.Error("expected the second lookup to hit the negative cache, but got ", queries, " queries")
+	}
+
+	// Other types of the name don't exist either.
+	if _, _, err := client.Lookup(context.Background(), "nonexistent.example.com", dns.QueryStrategy_USE_IP6); err == nil {
+		t.Error("expected NXDOMAIN of AAAA")
+	}
+	if queries := atomic.LoadInt32(&transport.queries); queries != 1 {
+		t.Error("unexpected query of AAAA")
+	}
+
+	client.expireCache("nonexistent.example.com")
+	client.Lookup(context.Background(), "nonexistent.example.com", dns.QueryStrategy_USE_IP4)
+	if queries := atomic.LoadInt32(&transport.queries); queries != 2 {
+		t.Error("expected a query after the negative cache expires, but got ", queries, " queries")
+	}
+}
+
+func TestNegativeCacheNoData(t *testing.T) {
+	transport := &staticTransport{rcode: dnsmessage.RCodeSuccess, soa: true}
+	client := newTestClient(transport)
+	client.negativeCacheMaxTTL = 60
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		_, ttl, err := client.Lookup(context.Background(), "nodata.example.com", dns.QueryStrategy_USE_IP4)
+		if err != dns.ErrEmptyResponse {
+			t.Fatal("expected empty response, but got ", err)
+		}
+		if i == 1 && ttl != 60 {
+			t.Error("expected TTL capped to 60, but got ", ttl)
+		}
+	}
+	if queries := atomic.LoadInt32(&transport.queries); queries != 1 {
+		t.Error("expected the second lookup to hit the negative cache, but got ", queries, " queries")
+	}
+
+	client.expireCache("nodata.example.com")
+	client.Lookup(context.Background(), "nodata.example.com", dns.QueryStrategy_USE_IP4)
+	if queries := atomic.LoadInt32(&transport.queries); queries != 2 {
+		t.Error("expected a query after the negative cache expires, but got ", queries, " queries")
+	}
+}
+
+func TestNegativeCacheDisabled(t *testing.T) {
+	for _, rcode := range []dnsmessage.RCode{dnsmessage.RCodeNameError, dnsmessage.RCodeSuccess} {
+		transport := &staticTransport{rcode: rcode, soa: true}
+		client := newTestClient(transport)
+		client.disableNegativeCache = true
+
+		for i := 0; i < 2; i++ {
+			client.Lookup(context.Background(), "nonexistent.example.com", dns.QueryStrategy_USE_IP4)
+		}
+		if queries := atomic.LoadInt32(&transport.queries); queries != 2 {
+			t.Error("expected no negative cache of ", rcode, ", but got ", queries, " queries")
+		}
+		client.Close()
+	}
+}
+
+func TestNegativeCacheWithoutSOA(t *testing.T) {
+	transport := &staticTransport{rcode: dnsmessage.RCodeNameError}
+	client := newTestClient(transport)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		client.Lookup(context.Background(), "nonexistent.example.com", dns.QueryStrategy_USE_IP4)
+	}
+	if queries := atomic.LoadInt32(&transport.queries); queries != 2 {
+		t.Error("expected NXDOMAIN without SOA not to be cached, but got ", queries, " queries")
+	}
+}
+
+func TestSimplifiedConfigJSONNames(t *testing.T) {
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: false}
+
+	config := new(SimplifiedConfig)
+	common.Must(unmarshaler.Unmarshal(strings.NewReader(`{
+		"disableNegativeCache": true,
+		"negativeCacheMaxTtl": 60,
+		"dnssecValidation": true,
+		"dnssecTrustAnchor": ["anchor"]
+	}`), config))
+	if !config.DisableNegativeCache || config.NegativeCacheMaxTtl != 60 || !config.DnssecValidation || len(config.DnssecTrustAnchor) != 1 {
+		t.Error("unexpected config: ", config)
+	}
+
+	for _, name := range []string{"disable_negative_cache", "negative_cache_max_ttl", "dnssec_validation", "dnssec_trust_anchor"} {
+		if err := unmarshaler.Unmarshal(strings.NewReader(`{"`+name+`": null}`), new(SimplifiedConfig)); err == nil {
+			t.Error("expected ", name, " to be rejected")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dns
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dualStackTransport answers A and AAAA queries, and records their types.
+type dualStackTransport struct {
+	access  sync.Mutex
+	queries []dnsmessage.Type
+}
+
+func (t *dualStackTransport) Type() dns.TransportType {
+	return dns.TransportTypeExchange
+}
+
+func (t *dualStackTransport) Write(context.Context, *dnsmessage.Message) error {
+	return common.ErrNoClue
+}
+
+func (t *dualStackTransport) Exchange(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	q := message.Questions[0]
+	t.access.Lock()
+	t.queries = append(t.queries, q.Type)
+	t.access.Unlock()
+
+	header := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 300}
+	response := &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: message.ID, Response: true},
+		Questions: message.Questions,
+	}
+	switch q.Type {
+	case dnsmessage.TypeA:
+		response.Answers = []dnsmessage.Resource{{Header: header, Body: &dnsmessage.AResource{A: [4]byte{1, 2, 3, 4}}}}
+	case dnsmessage.TypeAAAA:
+		response.Answers = []dnsmessage.Resource{{Header: header, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0xfd, 15: 1}}}}
+	}
+	return response, nil
+}
+
+func (t *dualStackTransport) ExchangeRaw(context.Context, *buf.Buffer) (*buf.Buffer, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *dualStackTransport) Lookup(context.Context, string, dns.QueryStrategy) ([]net.IP, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *dualStackTransport) Close() error {
+	return nil
+}
+
+func (t *dualStackTransport) queried() (a, aaaa int) {
+	t.access.Lock()
+	defer t.access.Unlock()
+	for _, queryType := range t.queries {
+		switch queryType {
+		case dnsmessage.TypeA:
+			a++
+		case dnsmessage.TypeAAAA:
+			aaaa++
+		}
+	}
+	return
+}
+
+func TestServerQueryStrategy(t *testing.T) {
+	cases := []struct {
+		server   dns.QueryStrategy
+		query    dns.QueryStrategy
+		a, aaaa  int
+		ips      []string
+		emptyErr bool
+	}{
+		{server: dns.QueryStrategy_USE_IP, query: dns.QueryStrategy_USE_IP, a: 1, aaaa: 1, ips: []string{"1.2.3.4", "fd00::1"}},
+		{server: dns.QueryStrategy_USE_IP4, query: dns.QueryStrategy_USE_IP, a: 1, ips: []string{"1.2.3.4"}},
+		{server: dns.QueryStrategy_USE_IP6, query: dns.QueryStrategy_USE_IP, aaaa: 1, ips: []string{"fd00::1"}},
+		{server: dns.QueryStrategy_USE_IP4, query: dns.QueryStrategy_USE_IP4, a: 1, ips: []string{"1.2.3.4"}},
+		{server: dns.QueryStrategy_USE_IP6, query: dns.QueryStrategy_USE_IP4, emptyErr: true},
+	}
+
+	for _, test := range cases {
+		transport := new(dualStackTransport)
+		client := newTestClient(transport)
+		client.servers[0].queryStrategy = test.server
+
+		ips, _, err := client.Lookup(context.Background(), "v2fly.org", test.query)
+		switch {
+		case test.emptyErr && err != dns.ErrEmptyResponse:
+			t.Error("server ", test.server, " query ", test.query, ": expected an empty response, but got ", err)
+		case !test.emptyErr && err != nil:
+			t.Error("server ", test.server, " query ", test.query, ": unexpected error ", err)
+		}
+		var actual []string
+		for _, ip := range ips {
+			actual = append(actual, ip.String())
+		}
+		// Answers of both types are merged in the order they arrive.
+		sort.Strings(actual)
+		if len(actual) != len(test.ips) || len(actual) > 0 && actual[0] != test.ips[0] || len(actual) > 1 && actual[1] != test.ips[1] {
+			t.Error("server ", test.server, " query ", test.query, ": expected ", test.ips, " but got ", actual)
+		}
+		if a, aaaa := transport.queried(); a != test.a || aaaa != test.aaaa {
+			t.Error("server ", test.server, " query ", test.query, ": unexpected queries of ", a, " A and ", aaaa, " AAAA")
+		}
+
+		// The addresses are served from the cache afterwards.
+		if ips, _, err := client.Lookup(context.Background(), "v2fly.org", test.query); !test.emptyErr && (err != nil || len(ips) != len(test.ips)) {
+			t.Error("server ", test.server, " query ", test.query, ": unexpected cached result ", ips, err)
+		}
+		if a, aaaa := transport.queried(); a != test.a || aaaa != test.aaaa {
+			t.Error("server ", test.server, " query ", test.query, ": unexpected queries after caching")
+		}
+		client.Close()
+	}
+}
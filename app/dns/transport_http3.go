@@ -0,0 +1,140 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var _ dns.Transport = (*HTTP3Transport)(nil)
+
+// http3BrokenDuration is how long HTTP/3 is not tried again after it fails,
+// during which queries are sent over HTTP/2 instead.
+var http3BrokenDuration = 5 * time.Minute
+
+// HTTP3Transport sends DOH queries over HTTP/3, and falls back to HTTP/2 if
+// HTTP/3 fails.
+type HTTP3Transport struct {
+	*transportContext
+	url          string
+	roundTripper *http3.RoundTripper
+	httpClient   *http.Client
+	fallback     *HTTPSTransport
+
+	access      sync.Mutex
+	brokenUntil time.Time
+}
+
+func NewHTTP3Transport(trans *transportContext, dispatcher routing.Dispatcher) *HTTP3Transport {
+	return newHTTP3Transport(trans, dispatcher, NewHTTPSTransport(trans, dispatcher))
+}
+
+func NewHTTP3LocalTransport(trans *transportContext) *HTTP3Transport {
+	return newHTTP3Transport(trans, nil, NewHTTPSLocalTransport(trans))
+}
+
+func newHTTP3Transport(trans *transportContext, dispatcher routing.Dispatcher, fallback *HTTPSTransport) *HTTP3Transport {
+	roundTripper := &http3.RoundTripper{
+		QuicConfig: &quic.Config{
+			HandshakeIdleTimeout: 5 * time.Second,
+		},
+		Dial: func(ctx context.Context, addr string, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+			destination, err := net.ParseDestination("udp:" + addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialQUIC(ctx, trans, dispatcher, destination, tlsConfig, quicConfig)
+		},
+	}
+	return &HTTP3Transport{
+		transportContext: trans,
+		url:              trans.destination.Address.Domain(),
+		roundTripper:     roundTripper,
+		httpClient: &http.Client{
+			Transport: roundTripper,
+			Timeout:   60 * time.Second,
+		},
+		fallback: fallback,
+	}
+}
+
+func (t *HTTP3Transport) Close() error {
+	common.Close(t.roundTripper)
+	return t.fallback.Close()
+}
+
+func (t *HTTP3Transport) Type() dns.TransportType {
+	return dns.TransportTypeExchangeRaw
+}
+
+func (t *HTTP3Transport) ExchangeRaw(ctx context.Context, message *buf.Buffer) (*buf.Buffer, error) {
+	if !t.isBroken() {
+		response, _, err := exchangeHTTPS(ctx, t.ctx, t.httpClient, t.url, message)
+		if err == nil {
+			return response, nil
+		}
+		newError("failed to query ", t.url, " over HTTP/3, falling back to HTTP/2").Base(err).AtWarning().WriteToLog()
+		t.setBroken()
+	}
+
+	response, header, err := exchangeHTTPS(ctx, t.ctx, t.fallback.httpClient, t.url, message)
+	if err != nil {
+		return nil, err
+	}
+	if !advertisesHTTP3(header) {
+		// Keep using HTTP/2 as long as the server doesn't advertise HTTP/3.
+		t.setBroken()
+	}
+	return response, nil
+}
+
+func (t *HTTP3Transport) isBroken() bool {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return time.Now().Before(t.brokenUntil)
+}
+
+func (t *HTTP3Transport) setBroken() {
+	t.access.Lock()
+	defer t.access.Unlock()
+	t.brokenUntil = time.Now().Add(http3BrokenDuration)
+	// A client of the round tripper never redials once failed.
+	common.Close(t.roundTripper)
+}
+
+// advertisesHTTP3 reports whether the Alt-Svc header advertises HTTP/3.
+func advertisesHTTP3(header http.Header) bool {
+	for _, value := range header.Values("Alt-Svc") {
+		for _, service := range strings.Split(value, ",") {
+			protocol, _, _ := strings.Cut(strings.TrimSpace(service), "=")
+			if protocol == "h3" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *HTTP3Transport) Write(ctx context.Context, message *dnsmessage.Message) error {
+	return common.ErrNoClue
+}
+
+func (t *HTTP3Transport) Exchange(ctx context.Context, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	return nil, common.ErrNoClue
+}
+
+func (t *HTTP3Transport) Lookup(ctx context.Context, domain string, strategy dns.QueryStrategy) ([]net.IP, error) {
+	return nil, common.ErrNoClue
+}
@@ -0,0 +1,165 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type dohServer struct {
+	access    sync.Mutex
+	requests  map[int]int
+	advertise string
+}
+
+func (s *dohServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.access.Lock()
+	s.requests[r.ProtoMajor]++
+	advertise := s.advertise
+	s.access.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var query dnsmessage.Message
+	if err := query.Unpack(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true},
+		Questions: query.Questions,
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{1, 2, 3, 4}},
+		}},
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if advertise != "" {
+		w.Header().Set("Alt-Svc", advertise)
+	}
+	w.Header().Set("Content-Type", "application/dns-message")
+	w.Write(packed)
+}
+
+func (s *dohServer) count(protoMajor int) int {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.requests[protoMajor]
+}
+
+func exchangeTestQuery(t *testing.T, transport *HTTP3Transport) {
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 1, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	common.Must(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	response, err := transport.ExchangeRaw(ctx, buf.FromBytes(packed))
+	if err != nil {
+		t.Fatal("failed to exchange: ", err)
+	}
+	var message dnsmessage.Message
+	common.Must(message.Unpack(response.Bytes()))
+	if len(message.Answers) != 1 || message.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{1, 2, 3, 4} {
+		t.Fatal("unexpected response: ", message.Answers)
+	}
+}
+
+func TestHTTP3Transport(t *testing.T) {
+	server := &dohServer{requests: map[int]int{}, advertise: `h3=":443"; ma=86400`}
+
+	h2Server := httptest.NewUnstartedServer(server)
+	h2Server.EnableHTTP2 = true
+	h2Server.StartTLS()
+	defer h2Server.Close()
+	port := h2Server.Listener.Addr().(*gonet.TCPAddr).Port
+
+	udpConn, err := gonet.ListenUDP("udp", &gonet.UDPAddr{IP: gonet.IPv4(127, 0, 0, 1), Port: port})
+	common.Must(err)
+	h3Server := &http3.Server{
+		Handler:   server,
+		TLSConfig: h2Server.TLS,
+	}
+	go h3Server.Serve(udpConn)
+	defer h3Server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(h2Server.Certificate())
+	transport := NewHTTP3LocalTransport(&transportContext{
+		ctx:         context.Background(),
+		destination: net.Destination{Address: net.DomainAddress(fmt.Sprintf("https://127.0.0.1:%d/dns-query", port))},
+	})
+	defer transport.Close()
+	transport.roundTripper.TLSClientConfig = &tls.Config{RootCAs: roots}
+	transport.roundTripper.QuicConfig.HandshakeIdleTimeout = time.Second
+	transport.fallback.httpClient.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+
+	exchangeTestQuery(t, transport)
+	if server.count(3) != 1 || server.count(2) != 0 {
+		t.Fatal("expected a query over HTTP/3, but got ", server.requests)
+	}
+
+	// HTTP/2 is used once HTTP/3 fails.
+	common.Must(h3Server.Close())
+	common.Must(udpConn.Close())
+	// Drop the established connection so that a new one is dialed.
+	transport.roundTripper.Close()
+	exchangeTestQuery(t, transport)
+	if server.count(2) != 1 {
+		t.Fatal("expected a query over HTTP/2, but got ", server.requests)
+	}
+	if !transport.isBroken() {
+		t.Fatal("expected HTTP/3 to be marked as broken")
+	}
+	exchangeTestQuery(t, transport)
+	if server.count(2) != 2 || server.count(3) != 1 {
+		t.Fatal("expected HTTP/3 not to be retried, but got ", server.requests)
+	}
+}
+
+func TestAdvertisesHTTP3(t *testing.T) {
+	cases := []struct {
+		altSvc []string
+		output bool
+	}{
+		{nil, false},
+		{[]string{"clear"}, false},
+		{[]string{`h2=":443"`}, false},
+		{[]string{`h3=":443"; ma=86400`}, true},
+		{[]string{`h3-29=":443", h3=":443"`}, true},
+		{[]string{`h2=":443"`, `h3=":8443"`}, true},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		for _, value := range c.altSvc {
+			header.Add("Alt-Svc", value)
+		}
+		if actual := advertisesHTTP3(header); actual != c.output {
+			t.Error("Alt-Svc ", c.altSvc, ": expected ", c.output, ", but got ", actual)
+		}
+	}
+}
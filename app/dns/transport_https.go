@@ -82,18 +82,26 @@ func (t *HTTPSTransport) Type() dns.TransportType {
 }
 
 func (t *HTTPSTransport) ExchangeRaw(ctx context.Context, message *buf.Buffer) (*buf.Buffer, error) {
+	response, _, err := exchangeHTTPS(ctx, t.ctx, t.httpClient, t.url, message)
+	return response, err
+}
+
+// exchangeHTTPS sends a DOH query to url, and returns the response along with
+// its header.
+func exchangeHTTPS(ctx context.Context, requestCtx context.Context, client *http.Client, url string, message *buf.Buffer) (*buf.Buffer, http.Header, error) {
 	body := bytes.NewBuffer(message.Bytes())
-	req, err := http.NewRequest("POST", t.url, body)
+	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req.Header.Add("Accept", "application/dns-message")
 	req.Header.Add("Content-Type", "application/dns-message")
 
 	var response *buf.Buffer
-	return response, task.Run(ctx, func() error {
-		resp, err := t.httpClient.Do(req.WithContext(t.ctx))
+	var header http.Header
+	err = task.Run(ctx, func() error {
+		resp, err := client.Do(req.WithContext(requestCtx))
 		if err != nil {
 			return err
 		}
@@ -109,8 +117,10 @@ func (t *HTTPSTransport) ExchangeRaw(ctx context.Context, message *buf.Buffer) (
 			return newError("failed to read DOH response").Base(err)
 		}
 		response = buf.FromBytes(data)
+		header = resp.Header
 		return nil
 	})
+	return response, header, err
 }
 
 func (t *HTTPSTransport) Write(ctx context.Context, message *dnsmessage.Message) error {
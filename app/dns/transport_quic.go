@@ -71,59 +71,10 @@ func (t *QUICTransport) getConnection(ctx context.Context) (quic.Connection, err
 	t.access.Lock()
 	defer t.access.Unlock()
 
-	var destinations []net.Destination
-	domain := t.destination.Address.String()
-	addr, err := netip.ParseAddr(domain)
-	if err != nil {
-		ips, _, err := t.client.LookupDefault(ctx, domain)
-		if err != nil {
-			return nil, newError("failed to lookup server address").Base(err)
-		}
-		destinations = common.Map(ips, func(it net.IP) net.Destination {
-			destination := t.destination
-			destination.Address = net.IPAddress(it)
-			return destination
-		})
-	} else {
-		destination := t.destination
-		destination.Address = net.IPAddress(addr.AsSlice())
-		destinations = []net.Destination{destination}
+	tlsConfig := &tls.Config{
+		NextProtos: []string{"http/1.1", http2.NextProtoTLS, NextProtoDQ},
 	}
-
-	index := -1
-	err = retry.ExponentialBackoff(len(destinations), 0).On(func() error {
-		index++
-		destination := destinations[index]
-		var packetConn net.PacketConn
-		if t.dispatcher != nil {
-			link, err := t.dispatcher.Dispatch(t.newContext(), destination)
-			if err != nil {
-				return err
-			}
-			packetConn = &pinnedPacketConn{
-				buf.NewConnection(buf.ConnectionInputMulti(link.Writer), buf.ConnectionOutputMulti(link.Reader)),
-				destination.UDPAddr(),
-			}
-		} else {
-			conn, err := internet.ListenSystemPacket(t.newContext(), &net.UDPAddr{IP: net.AnyIP.IP(), Port: 0}, nil)
-			if err != nil {
-				return err
-			}
-			packetConn = conn
-		}
-
-		tlsConfig := &tls.Config{
-			NextProtos: []string{"http/1.1", http2.NextProtoTLS, NextProtoDQ},
-		}
-
-		quicSession, err := quic.DialEarlyContext(t.ctx, packetConn, destination.UDPAddr(), domain, tlsConfig, nil)
-		if err != nil {
-			return err
-		}
-		session = quicSession
-		return nil
-	})
-
+	session, err := dialQUIC(ctx, t.transportContext, t.dispatcher, t.destination, tlsConfig, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +109,7 @@ func (t *QUICTransport) Exchange(ctx context.Context, message *dnsmessage.Messag
 			return err
 		}
 
-		buffer := buf.New()
+		buffer := buf.NewSize(buf.Size)
 		n, err := stream.Read(buffer.Extend(buf.Size))
 		if err != nil && err != io.EOF {
 			buffer.Release()
@@ -188,3 +139,62 @@ func (t *QUICTransport) ExchangeRaw(context.Context, *buf.Buffer) (*buf.Buffer,
 func (t *QUICTransport) Lookup(context.Context, string, dns.QueryStrategy) ([]net.IP, error) {
 	return nil, common.ErrNoClue
 }
+
+// dialQUIC establishes a QUIC connection to destination, through dispatcher if
+// it is not nil. The address of destination is resolved by the client first if
+// it is a domain.
+func dialQUIC(ctx context.Context, trans *transportContext, dispatcher routing.Dispatcher, destination net.Destination, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	var destinations []net.Destination
+	domain := destination.Address.String()
+	addr, err := netip.ParseAddr(domain)
+	if err != nil {
+		ips, _, err := trans.client.LookupDefault(ctx, domain)
+		if err != nil {
+			return nil, newError("failed to lookup server address").Base(err)
+		}
+		destinations = common.Map(ips, func(it net.IP) net.Destination {
+			destination := destination
+			destination.Address = net.IPAddress(it)
+			return destination
+		})
+	} else {
+		destination := destination
+		destination.Address = net.IPAddress(addr.AsSlice())
+		destinations = []net.Destination{destination}
+	}
+
+	var connection quic.EarlyConnection
+	index := -1
+	err = retry.ExponentialBackoff(len(destinations), 0).On(func() error {
+		index++
+		destination := destinations[index]
+		var packetConn net.PacketConn
+		if dispatcher != nil {
+			link, err := dispatcher.Dispatch(trans.newContext(), destination)
+			if err != nil {
+				return err
+			}
+			packetConn = &pinnedPacketConn{
+				buf.NewConnection(buf.ConnectionInputMulti(link.Writer), buf.ConnectionOutputMulti(link.Reader)),
+				destination.UDPAddr(),
+			}
+		} else {
+			conn, err := internet.ListenSystemPacket(trans.newContext(), &net.UDPAddr{IP: net.AnyIP.IP(), Port: 0}, nil)
+			if err != nil {
+				return err
+			}
+			packetConn = conn
+		}
+
+		quicConnection, err := quic.DialEarlyContext(trans.ctx, packetConn, destination.UDPAddr(), domain, tlsConfig, quicConfig)
+		if err != nil {
+			return err
+		}
+		connection = quicConnection
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return connection, nil
+}
@@ -4,6 +4,7 @@ package command
 
 import (
 	"context"
+	"sync"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/log"
@@ -32,8 +33,48 @@ func (s *LoggerServer) RestartLogger(ctx context.Context, request *RestartLogger
 	return &RestartLoggerResponse{}, nil
 }
 
+// followQueueSize is the number of messages queued for a client of FollowLog.
+// The oldest ones are dropped when a client doesn't keep up.
+const followQueueSize = 256
+
+// followQueue is a bounded queue of messages to send to a client.
+type followQueue struct {
+	sync.Mutex
+	messages []cmlog.Message
+	signal   chan struct{}
+}
+
+func newFollowQueue() *followQueue {
+	return &followQueue{
+		signal: make(chan struct{}, 1),
+	}
+}
+
+func (q *followQueue) push(msg cmlog.Message) {
+	q.Lock()
+	q.messages = append(q.messages, msg)
+	if n := len(q.messages) - followQueueSize; n > 0 {
+		q.messages = append(q.messages[:0], q.messages[n:]...)
+	}
+	q.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *followQueue) pop() []cmlog.Message {
+	q.Lock()
+	defer q.Unlock()
+
+	messages := q.messages
+	q.messages = nil
+	return messages
+}
+
 // FollowLog implements LoggerService.
-func (s *LoggerServer) FollowLog(_ *FollowLogRequest, stream LoggerService_FollowLogServer) error {
+func (s *LoggerServer) FollowLog(request *FollowLogRequest, stream LoggerService_FollowLogServer) error {
 	logger := s.V.GetFeature((*log.Instance)(nil))
 	if logger == nil {
 		return newError("unable to get logger instance")
@@ -42,19 +83,32 @@ func (s *LoggerServer) FollowLog(_ *FollowLogRequest, stream LoggerService_Follo
 	if !ok {
 		return newError("logger not support following")
 	}
-	done := make(chan struct{})
+	queue := newFollowQueue()
 	f := func(msg cmlog.Message) {
-		err := stream.Send(&FollowLogResponse{
-			Message: msg.String(),
-		})
-		if err != nil {
-			close(done)
-		}
+		queue.push(msg)
+	}
+	if historyFollower, ok := logger.(cmlog.HistoryFollower); ok && request.History > 0 {
+		historyFollower.AddFollowerWithHistory(f, int(request.History))
+	} else {
+		follower.AddFollower(f)
 	}
-	follower.AddFollower(f)
 	defer follower.RemoveFollower(f)
-	<-done
-	return nil
+
+	for {
+		select {
+		case <-queue.signal:
+		case <-stream.Context().Done():
+			return nil
+		}
+		for _, msg := range queue.pop() {
+			err := stream.Send(&FollowLogResponse{
+				Message: msg.String(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 }
 
 func (s *LoggerServer) mustEmbedUnimplementedLoggerServiceServer() {}
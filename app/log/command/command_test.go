@@ -3,6 +3,7 @@ package command_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/dispatcher"
@@ -12,7 +13,9 @@ import (
 	_ "github.com/v2fly/v2ray-core/v5/app/proxyman/inbound"
 	_ "github.com/v2fly/v2ray-core/v5/app/proxyman/outbound"
 	"github.com/v2fly/v2ray-core/v5/common"
+	clog "github.com/v2fly/v2ray-core/v5/common/log"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -33,3 +36,92 @@ func TestLoggerRestart(t *testing.T) {
 	}
 	common.Must2(server.RestartLogger(context.Background(), &RestartLoggerRequest{}))
 }
+
+type followLogStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages chan string
+}
+
+func (s *followLogStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *followLogStream) Send(response *FollowLogResponse) error {
+	select {
+	case s.messages <- response.Message:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *followLogStream) receive(t *testing.T) string {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for log message")
+		return ""
+	}
+}
+
+// followLog starts following logs of a new instance, with the messages
+// recorded before as history, and waits for the last of them.
+func followLog(t *testing.T, stream *followLogStream, history ...string) {
+	v, err := core.New(&core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&log.Config{
+				Error: &log.LogSpecification{Type: log.LogType_None, Level: clog.Severity_Info},
+			}),
+		},
+	})
+	common.Must(err)
+	common.Must(v.Start())
+
+	for _, content := range history {
+		clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Warning, Content: content})
+	}
+	server := &LoggerServer{V: v}
+	go server.FollowLog(&FollowLogRequest{History: uint32(len(history))}, stream)
+	for _, content := range history {
+		if msg := stream.receive(t); msg != "[Warning] "+content {
+			t.Fatal("expected history ", content, ", but got ", msg)
+		}
+	}
+}
+
+func TestFollowLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &followLogStream{ctx: ctx, messages: make(chan string, 16)}
+	followLog(t, stream, "first", "second")
+
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Debug, Content: "filtered"})
+	clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Info, Content: "third"})
+	if msg := stream.receive(t); msg != "[Info] third" {
+		t.Error("expected third message, but got ", msg)
+	}
+}
+
+func TestFollowLogSlowClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &followLogStream{ctx: ctx, messages: make(chan string)}
+	followLog(t, stream, "ready")
+
+	// Logging is not blocked by the client, which misses the oldest messages.
+	for i := 0; i < 1000; i++ {
+		clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Warning, Content: i})
+	}
+	received := 0
+	for {
+		received++
+		if msg := stream.receive(t); msg == "[Warning] 999" {
+			break
+		}
+	}
+	if received >= 1000 {
+		t.Error("expected older messages to be dropped, but got ", received)
+	}
+}
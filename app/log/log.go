@@ -11,6 +11,42 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/log"
 )
 
+// historySize is the number of recent messages an Instance keeps for followers.
+const historySize = 256
+
+// history is a ring buffer of recent messages.
+type history struct {
+	sync.Mutex
+	messages []log.Message
+	next     int
+}
+
+func (h *history) add(msg log.Message) {
+	h.Lock()
+	defer h.Unlock()
+
+	if len(h.messages) < historySize {
+		h.messages = append(h.messages, msg)
+	} else {
+		h.messages[h.next] = msg
+	}
+	h.next = (h.next + 1) % historySize
+}
+
+// last returns up to n recent messages, oldest first.
+func (h *history) last(n int) []log.Message {
+	h.Lock()
+	defer h.Unlock()
+
+	messages := make([]log.Message, 0, len(h.messages))
+	messages = append(messages, h.messages[h.next:]...)
+	messages = append(messages, h.messages[:h.next]...)
+	if len(messages) > n {
+		messages = messages[len(messages)-n:]
+	}
+	return messages
+}
+
 // Instance is a log.Handler that handles logs.
 type Instance struct {
 	sync.RWMutex
@@ -18,6 +54,7 @@ type Instance struct {
 	accessLogger log.Handler
 	errorLogger  log.Handler
 	followers    map[reflect.Value]func(msg log.Message)
+	history      history
 	active       bool
 }
 
@@ -49,7 +86,8 @@ func New(ctx context.Context, config *Config) (*Instance, error) {
 
 func (g *Instance) initAccessLogger() error {
 	handler, err := createHandler(g.config.Access.Type, HandlerCreatorOptions{
-		Path: g.config.Access.Path,
+		Path:   g.config.Access.Path,
+		Format: g.config.Access.Format,
 	})
 	if err != nil {
 		return err
@@ -60,7 +98,8 @@ func (g *Instance) initAccessLogger() error {
 
 func (g *Instance) initErrorLogger() error {
 	handler, err := createHandler(g.config.Error.Type, HandlerCreatorOptions{
-		Path: g.config.Error.Path,
+		Path:   g.config.Error.Path,
+		Format: g.config.Error.Format,
 	})
 	if err != nil {
 		return err
@@ -109,6 +148,21 @@ func (g *Instance) AddFollower(f func(msg log.Message)) {
 	g.followers[reflect.ValueOf(f)] = f
 }
 
+// AddFollowerWithHistory implements log.HistoryFollower.
+func (g *Instance) AddFollowerWithHistory(f func(msg log.Message), n int) {
+	g.Lock()
+	defer g.Unlock()
+	// New messages are handled only after the lock is released, so they
+	// reach f after the history.
+	for _, msg := range g.history.last(n) {
+		f(msg)
+	}
+	if g.followers == nil {
+		g.followers = make(map[reflect.Value]func(msg log.Message))
+	}
+	g.followers[reflect.ValueOf(f)] = f
+}
+
 // RemoveFollower implements log.Follower.
 func (g *Instance) RemoveFollower(f func(msg log.Message)) {
 	g.Lock()
@@ -125,8 +179,12 @@ func (g *Instance) Handle(msg log.Message) {
 		return
 	}
 
-	for _, f := range g.followers {
-		f(msg)
+	// Followers get the messages the error logger would, and access messages.
+	if general, ok := msg.(*log.GeneralMessage); !ok || general.Severity <= g.config.Error.Level {
+		g.history.add(msg)
+		for _, f := range g.followers {
+			f(msg)
+		}
 	}
 
 	switch msg := msg.(type) {
@@ -6,7 +6,8 @@ import (
 )
 
 type HandlerCreatorOptions struct {
-	Path string
+	Path   string
+	Format LogFormat
 }
 
 type HandlerCreator func(LogType, HandlerCreatorOptions) (log.Handler, error)
@@ -30,9 +31,16 @@ func createHandler(logType LogType, options HandlerCreatorOptions) (log.Handler,
 	return creator(logType, options)
 }
 
+func newLogger(creator log.WriterCreator, format LogFormat) log.Handler {
+	if format == LogFormat_JSON {
+		return log.NewJSONLogger(creator)
+	}
+	return log.NewLogger(creator)
+}
+
 func init() {
 	common.Must(RegisterHandlerCreator(LogType_Console, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
-		return log.NewLogger(log.CreateStdoutLogWriter()), nil
+		return newLogger(log.CreateStdoutLogWriter(), options.Format), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_File, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
@@ -40,7 +48,7 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
-		return log.NewLogger(creator), nil
+		return newLogger(creator, options.Format), nil
 	}))
 
 	common.Must(RegisterHandlerCreator(LogType_None, func(lt LogType, options HandlerCreatorOptions) (log.Handler, error) {
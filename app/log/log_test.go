@@ -2,7 +2,10 @@ package log_test
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/v2fly/v2ray-core/v5/app/log"
@@ -49,3 +52,74 @@ func TestCustomLogHandler(t *testing.T) {
 
 	common.Must(logger.Close())
 }
+
+func TestFollowerWithHistory(t *testing.T) {
+	logger, err := log.New(context.Background(), &log.Config{
+		Error:  &log.LogSpecification{Type: log.LogType_None, Level: clog.Severity_Warning},
+		Access: &log.LogSpecification{Type: log.LogType_None},
+	})
+	common.Must(err)
+	common.Must(logger.Start())
+	defer logger.Close()
+
+	// Messages are recorded until stop is closed, and the number of them is
+	// sent to recorded.
+	started := make(chan struct{})
+	stop := make(chan struct{})
+	recorded := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				recorded <- i
+				return
+			default:
+			}
+			clog.Record(&clog.GeneralMessage{Severity: clog.Severity_Warning, Content: i})
+			if i == 200 {
+				close(started)
+			}
+		}
+	}()
+
+	var access sync.Mutex
+	var received []string
+	f := func(msg clog.Message) {
+		access.Lock()
+		received = append(received, msg.String())
+		access.Unlock()
+	}
+	<-started
+	logger.AddFollowerWithHistory(f, 100)
+	defer logger.RemoveFollower(f)
+
+	// Let the follower get new messages too.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		access.Lock()
+		n := len(received)
+		access.Unlock()
+		if n >= 200 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for new messages")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	total := <-recorded
+
+	access.Lock()
+	defer access.Unlock()
+	first, err := strconv.Atoi(received[0][len("[Warning] "):])
+	common.Must(err)
+	if len(received) != total-first {
+		t.Error("expected messages from ", first, " to ", total-1, ", but got ", len(received))
+	}
+	for i, msg := range received {
+		if expected := "[Warning] " + strconv.Itoa(first+i); msg != expected {
+			t.Fatal("expected ", expected, " at ", i, ", but got ", msg)
+		}
+	}
+}
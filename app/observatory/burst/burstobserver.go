@@ -3,6 +3,7 @@ package burst
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	core "github.com/v2fly/v2ray-core/v5"
@@ -17,12 +18,15 @@ type Observer struct {
 	config *Config
 	ctx    context.Context
 
-	statusLock sync.Mutex // nolint: structcheck
+	statusLock sync.Mutex
 	hp         *HealthPing
 
 	finished *done.Instance
 
 	ohm outbound.Manager
+
+	alive  map[string]bool
+	events observatory.HealthEventHub
 }
 
 func (o *Observer) GetObservation(ctx context.Context) (proto.Message, error) {
@@ -55,6 +59,55 @@ func (o *Observer) createResult() []*observatory.OutboundStatus {
 	return result
 }
 
+// SubscribeHealthEvents implements observatory.HealthEventPublisher.
+func (o *Observer) SubscribeHealthEvents(bufferSize int) *observatory.HealthEventSubscription {
+	return o.events.Subscribe(bufferSize)
+}
+
+// publishChanges publishes health events of tags whose alive state changed
+// since the last check.
+func (o *Observer) publishChanges(tags []string) {
+	o.statusLock.Lock()
+	defer o.statusLock.Unlock()
+	if o.alive == nil {
+		o.alive = make(map[string]bool)
+	}
+
+	o.hp.access.Lock()
+	var events []*observatory.HealthEvent
+	for _, tag := range tags {
+		result, found := o.hp.Results[tag]
+		if !found {
+			continue
+		}
+		stats := result.getStatistics()
+		alive := stats.All != stats.Fail
+		if last, found := o.alive[tag]; found && last == alive {
+			continue
+		}
+		o.alive[tag] = alive
+		event := &observatory.HealthEvent{
+			OutboundTag: tag,
+			Alive:       alive,
+			Timestamp:   time.Now().Unix(),
+		}
+		if alive {
+			event.Delay = stats.Average.Milliseconds()
+		}
+		events = append(events, event)
+	}
+	for tag := range o.alive {
+		if _, found := o.hp.Results[tag]; !found {
+			delete(o.alive, tag)
+		}
+	}
+	o.hp.access.Unlock()
+
+	for _, event := range events {
+		o.events.Publish(event)
+	}
+}
+
 func (o *Observer) Type() interface{} {
 	return extension.ObservatoryType()
 }
@@ -92,12 +145,15 @@ func New(ctx context.Context, config *Config) (*Observer, error) {
 		return nil, newError("Cannot get depended features").Base(err)
 	}
 	hp := NewHealthPing(ctx, config.PingConfig)
-	return &Observer{
+	hp.Targets = config.ProbeTarget
+	o := &Observer{
 		config: config,
 		ctx:    ctx,
 		ohm:    outboundManager,
 		hp:     hp,
-	}, nil
+	}
+	hp.OnChecked = o.publishChanges
+	return o, nil
 }
 
 func init() {
@@ -0,0 +1,50 @@
+package burst
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestObserverHealthEvents(t *testing.T) {
+	hp := NewHealthPing(context.Background(), &HealthPingConfig{SamplingCount: 1})
+	o := &Observer{hp: hp}
+	hp.OnChecked = o.publishChanges
+	subscription := o.SubscribeHealthEvents(0)
+	defer subscription.Close()
+
+	tags := []string{"a", "b"}
+	for _, round := range []map[string]time.Duration{
+		{"a": time.Second, "b": rttFailed},
+		{"a": 2 * time.Second, "b": rttFailed},
+		{"a": rttFailed, "b": time.Second},
+	} {
+		for tag, rtt := range round {
+			hp.PutResult(tag, rtt)
+		}
+		hp.checked(tags)
+	}
+
+	expected := []struct {
+		tag   string
+		alive bool
+	}{{"a", true}, {"b", false}, {"a", false}, {"b", true}}
+	for _, e := range expected {
+		select {
+		case event := <-subscription.Events():
+			if event.OutboundTag != e.tag || event.Alive != e.alive {
+				t.Error("expected event of ", e.tag, " alive: ", e.alive, ", but got ", event)
+			}
+			if event.Alive && event.Delay != 1000 {
+				t.Error("unexpected delay of event ", event)
+			}
+		default:
+			t.Fatal("missing event of ", e.tag)
+		}
+	}
+	select {
+	case event := <-subscription.Events():
+		t.Error("unexpected event ", event)
+	default:
+	}
+}
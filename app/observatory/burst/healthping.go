@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
 	"github.com/v2fly/v2ray-core/v5/common/dice"
 )
 
@@ -17,6 +18,8 @@ type HealthPingSettings struct {
 	Interval      time.Duration `json:"interval"`
 	SamplingCount int           `json:"sampling"`
 	Timeout       time.Duration `json:"timeout"`
+	// Response status codes considered as success. Any status is accepted if empty.
+	ExpectedStatus []uint32 `json:"expectedStatus"`
 }
 
 // HealthPing is the health checker for balancers
@@ -27,7 +30,11 @@ type HealthPing struct {
 	tickerClose chan struct{}
 
 	Settings *HealthPingSettings
-	Results  map[string]*HealthPingRTTS
+	// Targets override Settings for selected outbounds.
+	Targets []*observatory.ProbeTarget
+	Results map[string]*HealthPingRTTS
+	// OnChecked is called after each check of tags finishes, if set.
+	OnChecked func(tags []string)
 }
 
 // NewHealthPing creates a new HealthPing with settings
@@ -35,11 +42,12 @@ func NewHealthPing(ctx context.Context, config *HealthPingConfig) *HealthPing {
 	settings := &HealthPingSettings{}
 	if config != nil {
 		settings = &HealthPingSettings{
-			Connectivity:  strings.TrimSpace(config.Connectivity),
-			Destination:   strings.TrimSpace(config.Destination),
-			Interval:      time.Duration(config.Interval),
-			SamplingCount: int(config.SamplingCount),
-			Timeout:       time.Duration(config.Timeout),
+			Connectivity:   strings.TrimSpace(config.Connectivity),
+			Destination:    strings.TrimSpace(config.Destination),
+			Interval:       time.Duration(config.Interval),
+			SamplingCount:  int(config.SamplingCount),
+			Timeout:        time.Duration(config.Timeout),
+			ExpectedStatus: config.ExpectedStatus,
 		}
 	}
 	if settings.Destination == "" {
@@ -86,6 +94,7 @@ func (h *HealthPing) StartScheduler(selector func() ([]string, error)) {
 				}
 				h.doCheck(tags, interval, h.Settings.SamplingCount)
 				h.Cleanup(tags)
+				h.checked(tags)
 			}()
 			select {
 			case <-ticker.C:
@@ -115,9 +124,16 @@ func (h *HealthPing) Check(tags []string) error {
 	}
 	newError("perform one-time health check for tags ", tags).AtInfo().WriteToLog()
 	h.doCheck(tags, 0, 1)
+	h.checked(tags)
 	return nil
 }
 
+func (h *HealthPing) checked(tags []string) {
+	if h.OnChecked != nil {
+		h.OnChecked(tags)
+	}
+}
+
 type rtt struct {
 	handler string
 	value   time.Duration
@@ -134,10 +150,14 @@ func (h *HealthPing) doCheck(tags []string, duration time.Duration, rounds int)
 
 	for _, tag := range tags {
 		handler := tag
+		settings := observatory.ProbeSettingsFor(h.Targets, handler, observatory.ProbeSettings{
+			URL:            h.Settings.Destination,
+			ExpectedStatus: h.Settings.ExpectedStatus,
+			Timeout:        h.Settings.Timeout,
+		})
 		client := newPingClient(
 			h.ctx,
-			h.Settings.Destination,
-			h.Settings.Timeout,
+			settings,
 			handler,
 		)
 		for i := 0; i < rounds; i++ {
@@ -165,7 +185,7 @@ func (h *HealthPing) doCheck(tags []string, duration time.Duration, rounds int)
 				}
 				newError(fmt.Sprintf(
 					"error ping %s with %s: %s",
-					h.Settings.Destination,
+					settings.URL,
 					handler,
 					err,
 				)).AtWarning().WriteToLog()
@@ -0,0 +1,76 @@
+package burst_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/app/observatory/burst"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+)
+
+func TestHealthPingCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate_204", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dialer := tagged.Dialer
+	defer func() { tagged.Dialer = dialer }()
+	tagged.Dialer = func(ctx context.Context, dest net.Destination, tag string) (net.Conn, error) {
+		return net.Dial(dest.Network.SystemString(), dest.NetAddr())
+	}
+
+	hp := burst.NewHealthPing(context.Background(), &burst.HealthPingConfig{
+		Destination:    server.URL + "/generate_204",
+		ExpectedStatus: []uint32{204},
+	})
+	hp.Targets = []*observatory.ProbeTarget{
+		{SubjectSelector: []string{"wrong-status"}, Url: server.URL + "/ok"},
+		{SubjectSelector: []string{"any-status"}, Url: server.URL + "/ok", ExpectedStatus: []uint32{200, 204}},
+		{SubjectSelector: []string{"slow"}, Url: server.URL + "/slow"},
+		{SubjectSelector: []string{"timeout"}, Url: server.URL + "/slow", Timeout: int64(50 * time.Millisecond)},
+	}
+
+	tags := []string{"default", "wrong-status", "any-status", "slow", "timeout"}
+	if err := hp.Check(tags); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		tag   string
+		alive bool
+	}{
+		{"default", true},
+		{"wrong-status", false},
+		{"any-status", true},
+		{"slow", true},
+		{"timeout", false},
+	} {
+		result, found := hp.Results[c.tag]
+		if !found {
+			t.Error("no result of ", c.tag)
+			continue
+		}
+		stats := result.Get()
+		if alive := stats.Fail == 0; alive != c.alive {
+			t.Error("expected ", c.tag, " alive: ", c.alive, ", but got ", stats.All, " pings with ", stats.Fail, " failures")
+		}
+		if c.tag == "slow" && stats.Average < 100*time.Millisecond {
+			t.Error("expected delay of the full request, but got ", stats.Average)
+		}
+	}
+}
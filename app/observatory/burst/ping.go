@@ -5,26 +5,27 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
 )
 
 type pingClient struct {
-	destination string
-	httpClient  *http.Client
+	settings   observatory.ProbeSettings
+	httpClient *http.Client
 }
 
-func newPingClient(ctx context.Context, destination string, timeout time.Duration, handler string) *pingClient {
+func newPingClient(ctx context.Context, settings observatory.ProbeSettings, handler string) *pingClient {
 	return &pingClient{
-		destination: destination,
-		httpClient:  newHTTPClient(ctx, handler, timeout),
+		settings:   settings,
+		httpClient: newHTTPClient(ctx, handler, settings.Timeout),
 	}
 }
 
 func newDirectPingClient(destination string, timeout time.Duration) *pingClient {
 	return &pingClient{
-		destination: destination,
-		httpClient:  &http.Client{Timeout: timeout},
+		settings:   observatory.ProbeSettings{URL: destination, Timeout: timeout},
+		httpClient: &http.Client{Timeout: timeout},
 	}
 }
 
@@ -54,16 +55,9 @@ func (s *pingClient) MeasureDelay() (time.Duration, error) {
 	if s.httpClient == nil {
 		panic("pingClient no initialized")
 	}
-	req, err := http.NewRequest(http.MethodHead, s.destination, nil)
+	delay, err := s.settings.Measure(s.httpClient, http.MethodHead)
 	if err != nil {
 		return rttFailed, err
 	}
-	start := time.Now()
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return rttFailed, err
-	}
-	// don't wait for body
-	resp.Body.Close()
-	return time.Since(start), nil
+	return delay, nil
 }
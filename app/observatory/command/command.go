@@ -49,6 +49,40 @@ func (s *service) GetOutboundStatus(ctx context.Context, request *GetOutboundSta
 	}, nil
 }
 
+func (s *service) SubscribeHealthEvents(request *SubscribeHealthEventsRequest, stream ObservatoryService_SubscribeHealthEventsServer) error {
+	var fet features.Feature = s.observatory
+	if request.Tag != "" {
+		tagged, ok := s.observatory.(features.TaggedFeatures)
+		if !ok {
+			return newError("observatory is not tagged")
+		}
+		var err error
+		fet, err = tagged.GetFeaturesByTag(request.Tag)
+		if err != nil {
+			return newError("cannot get tagged observatory").Base(err)
+		}
+	}
+	publisher, ok := fet.(observatory.HealthEventPublisher)
+	if !ok {
+		return newError("observatory does not publish health events")
+	}
+	subscription := publisher.SubscribeHealthEvents(0)
+	defer subscription.Close()
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return newError("upstream closed the subscription")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
 func (s *service) Register(server *grpc.Server) {
 	RegisterObservatoryServiceServer(server, s)
 }
@@ -8,6 +8,7 @@ package command
 
 import (
 	context "context"
+	observatory "github.com/v2fly/v2ray-core/v5/app/observatory"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -23,6 +24,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ObservatoryServiceClient interface {
 	GetOutboundStatus(ctx context.Context, in *GetOutboundStatusRequest, opts ...grpc.CallOption) (*GetOutboundStatusResponse, error)
+	SubscribeHealthEvents(ctx context.Context, in *SubscribeHealthEventsRequest, opts ...grpc.CallOption) (ObservatoryService_SubscribeHealthEventsClient, error)
 }
 
 type observatoryServiceClient struct {
@@ -42,11 +44,44 @@ func (c *observatoryServiceClient) GetOutboundStatus(ctx context.Context, in *Ge
 	return out, nil
 }
 
+func (c *observatoryServiceClient) SubscribeHealthEvents(ctx context.Context, in *SubscribeHealthEventsRequest, opts ...grpc.CallOption) (ObservatoryService_SubscribeHealthEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObservatoryService_ServiceDesc.Streams[0], "/v2ray.core.app.observatory.command.ObservatoryService/SubscribeHealthEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &observatoryServiceSubscribeHealthEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObservatoryService_SubscribeHealthEventsClient interface {
+	Recv() (*observatory.HealthEvent, error)
+	grpc.ClientStream
+}
+
+type observatoryServiceSubscribeHealthEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *observatoryServiceSubscribeHealthEventsClient) Recv() (*observatory.HealthEvent, error) {
+	m := new(observatory.HealthEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ObservatoryServiceServer is the server API for ObservatoryService service.
 // All implementations must embed UnimplementedObservatoryServiceServer
 // for forward compatibility
 type ObservatoryServiceServer interface {
 	GetOutboundStatus(context.Context, *GetOutboundStatusRequest) (*GetOutboundStatusResponse, error)
+	SubscribeHealthEvents(*SubscribeHealthEventsRequest, ObservatoryService_SubscribeHealthEventsServer) error
 	mustEmbedUnimplementedObservatoryServiceServer()
 }
 
@@ -57,6 +92,9 @@ type UnimplementedObservatoryServiceServer struct {
 func (UnimplementedObservatoryServiceServer) GetOutboundStatus(context.Context, *GetOutboundStatusRequest) (*GetOutboundStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOutboundStatus not implemented")
 }
+func (UnimplementedObservatoryServiceServer) SubscribeHealthEvents(*SubscribeHealthEventsRequest, ObservatoryService_SubscribeHealthEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeHealthEvents not implemented")
+}
 func (UnimplementedObservatoryServiceServer) mustEmbedUnimplementedObservatoryServiceServer() {}
 
 // UnsafeObservatoryServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +126,27 @@ func _ObservatoryService_GetOutboundStatus_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ObservatoryService_SubscribeHealthEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeHealthEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObservatoryServiceServer).SubscribeHealthEvents(m, &observatoryServiceSubscribeHealthEventsServer{stream})
+}
+
+type ObservatoryService_SubscribeHealthEventsServer interface {
+	Send(*observatory.HealthEvent) error
+	grpc.ServerStream
+}
+
+type observatoryServiceSubscribeHealthEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *observatoryServiceSubscribeHealthEventsServer) Send(m *observatory.HealthEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ObservatoryService_ServiceDesc is the grpc.ServiceDesc for ObservatoryService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -100,6 +159,12 @@ var ObservatoryService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ObservatoryService_GetOutboundStatus_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeHealthEvents",
+			Handler:       _ObservatoryService_SubscribeHealthEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "app/observatory/command/command.proto",
 }
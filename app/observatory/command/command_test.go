@@ -0,0 +1,88 @@
+package command
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type testObservatory struct {
+	observatory.HealthEventHub
+	subscribed chan struct{}
+}
+
+func (*testObservatory) Type() interface{} {
+	return extension.ObservatoryType()
+}
+
+func (*testObservatory) Start() error {
+	return nil
+}
+
+func (*testObservatory) Close() error {
+	return nil
+}
+
+func (*testObservatory) GetObservation(ctx context.Context) (proto.Message, error) {
+	return &observatory.ObservationResult{}, nil
+}
+
+func (o *testObservatory) SubscribeHealthEvents(bufferSize int) *observatory.HealthEventSubscription {
+	defer close(o.subscribed)
+	return o.Subscribe(bufferSize)
+}
+
+func TestSubscribeHealthEvents(t *testing.T) {
+	o := &testObservatory{subscribed: make(chan struct{})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	server := grpc.NewServer()
+	(&service{observatory: o}).Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	common.Must(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := NewObservatoryServiceClient(conn).SubscribeHealthEvents(ctx, &SubscribeHealthEventsRequest{})
+	common.Must(err)
+
+	select {
+	case <-o.subscribed:
+	case <-ctx.Done():
+		t.Fatal("subscription not registered")
+	}
+	events := []*observatory.HealthEvent{
+		{OutboundTag: "a", Alive: false, Timestamp: 1, LastErrorReason: "timeout"},
+		{OutboundTag: "a", Alive: true, Delay: 100, Timestamp: 2},
+	}
+	for _, event := range events {
+		o.Publish(event)
+	}
+	for _, expected := range events {
+		event, err := stream.Recv()
+		common.Must(err)
+		if !proto.Equal(event, expected) {
+			t.Error("expected event ", expected, ", but got ", event)
+		}
+	}
+}
+
+func TestSubscribeHealthEventsOfTaggedObservatory(t *testing.T) {
+	s := &service{observatory: &testObservatory{}}
+	err := s.SubscribeHealthEvents(&SubscribeHealthEventsRequest{Tag: "missing"}, nil)
+	if err == nil {
+		t.Error("expected error subscribing a tag of untagged observatory")
+	}
+}
@@ -0,0 +1,90 @@
+package observatory
+
+import (
+	"sync"
+)
+
+// DefaultHealthEventBufferSize is the number of events buffered for a
+// subscriber when no buffer size is given.
+const DefaultHealthEventBufferSize = 64
+
+// HealthEventPublisher is implemented by observatories that publish a
+// HealthEvent whenever an outbound becomes alive or dead.
+type HealthEventPublisher interface {
+	SubscribeHealthEvents(bufferSize int) *HealthEventSubscription
+}
+
+// HealthEventSubscription receives health events published after it is
+// created. When its buffer is full, the oldest event is dropped so that a slow
+// subscriber never blocks the observatory.
+type HealthEventSubscription struct {
+	hub    *HealthEventHub
+	events chan *HealthEvent
+}
+
+// Events returns the channel of events, which is closed when the subscription
+// is closed.
+func (s *HealthEventSubscription) Events() <-chan *HealthEvent {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *HealthEventSubscription) Close() error {
+	s.hub.unsubscribe(s)
+	return nil
+}
+
+// HealthEventHub delivers health events to subscriptions. The zero value is
+// ready to use.
+type HealthEventHub struct {
+	access        sync.Mutex
+	subscriptions []*HealthEventSubscription
+}
+
+// Subscribe creates a subscription buffering up to bufferSize events.
+func (h *HealthEventHub) Subscribe(bufferSize int) *HealthEventSubscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultHealthEventBufferSize
+	}
+	s := &HealthEventSubscription{
+		hub:    h,
+		events: make(chan *HealthEvent, bufferSize),
+	}
+	h.access.Lock()
+	defer h.access.Unlock()
+	h.subscriptions = append(h.subscriptions, s)
+	return s
+}
+
+func (h *HealthEventHub) unsubscribe(s *HealthEventSubscription) {
+	h.access.Lock()
+	defer h.access.Unlock()
+	for i, v := range h.subscriptions {
+		if v == s {
+			h.subscriptions = append(h.subscriptions[:i:i], h.subscriptions[i+1:]...)
+			close(s.events)
+			return
+		}
+	}
+}
+
+// Publish sends event to all subscriptions without blocking.
+func (h *HealthEventHub) Publish(event *HealthEvent) {
+	h.access.Lock()
+	defer h.access.Unlock()
+	for _, s := range h.subscriptions {
+		for {
+			select {
+			case s.events <- event:
+			default:
+				// Drop the oldest event to make room for the new one.
+				select {
+				case <-s.events:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
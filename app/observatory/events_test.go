@@ -0,0 +1,73 @@
+package observatory
+
+import (
+	"testing"
+)
+
+func TestHealthEventHubDropOldest(t *testing.T) {
+	var hub HealthEventHub
+	subscription := hub.Subscribe(2)
+	for _, tag := range []string{"a", "b", "c"} {
+		hub.Publish(&HealthEvent{OutboundTag: tag})
+	}
+
+	for _, expected := range []string{"b", "c"} {
+		if event := <-subscription.Events(); event.OutboundTag != expected {
+			t.Error("expected event of ", expected, ", but got ", event.OutboundTag)
+		}
+	}
+
+	subscription.Close()
+	if _, ok := <-subscription.Events(); ok {
+		t.Error("expected closed events channel")
+	}
+	// Publishing without subscribers must not block or panic.
+	hub.Publish(&HealthEvent{OutboundTag: "d"})
+	subscription.Close()
+}
+
+func TestObserverHealthEvents(t *testing.T) {
+	o := &Observer{config: &Config{}}
+	subscription := o.SubscribeHealthEvents(0)
+	defer subscription.Close()
+
+	for _, result := range []struct {
+		outbound string
+		result   *ProbeResult
+	}{
+		{"a", &ProbeResult{Alive: true, Delay: 10}},
+		{"a", &ProbeResult{Alive: true, Delay: 20}},
+		{"b", &ProbeResult{Alive: false, LastErrorReason: "timeout"}},
+		{"a", &ProbeResult{Alive: false, LastErrorReason: "refused"}},
+		{"b", &ProbeResult{Alive: false, LastErrorReason: "timeout"}},
+		{"a", &ProbeResult{Alive: true, Delay: 30}},
+	} {
+		o.updateStatusForResult(result.outbound, result.result)
+	}
+
+	expected := []*HealthEvent{
+		{OutboundTag: "a", Alive: true, Delay: 10},
+		{OutboundTag: "b", Alive: false, LastErrorReason: "timeout"},
+		{OutboundTag: "a", Alive: false, LastErrorReason: "refused"},
+		{OutboundTag: "a", Alive: true, Delay: 30},
+	}
+	for _, e := range expected {
+		var event *HealthEvent
+		select {
+		case event = <-subscription.Events():
+		default:
+			t.Fatal("missing event of ", e.OutboundTag)
+		}
+		if event.OutboundTag != e.OutboundTag || event.Alive != e.Alive || event.Delay != e.Delay || event.LastErrorReason != e.LastErrorReason {
+			t.Error("expected event ", e, ", but got ", event)
+		}
+		if event.Timestamp == 0 {
+			t.Error("missing timestamp of event ", event)
+		}
+	}
+	select {
+	case event := <-subscription.Events():
+		t.Error("unexpected event ", event)
+	default:
+	}
+}
@@ -36,12 +36,19 @@ type Observer struct {
 	ohm outbound.Manager
 
 	StatusUpdate func(result *OutboundStatus)
+
+	events HealthEventHub
 }
 
 func (o *Observer) GetObservation(ctx context.Context) (proto.Message, error) {
 	return &ObservationResult{Status: o.status}, nil
 }
 
+// SubscribeHealthEvents implements HealthEventPublisher.
+func (o *Observer) SubscribeHealthEvents(bufferSize int) *HealthEventSubscription {
+	return o.events.Subscribe(bufferSize)
+}
+
 func (o *Observer) Type() interface{} {
 	return extension.ObservatoryType()
 }
@@ -122,6 +129,15 @@ func (o *Observer) updateStatus(outbounds []string) {
 func (o *Observer) probe(outbound string) ProbeResult {
 	errorCollectorForRequest := newErrorCollector()
 
+	probeURL := "https://api.v2fly.org/checkConnection.svgz"
+	if o.config.ProbeUrl != "" {
+		probeURL = o.config.ProbeUrl
+	}
+	settings := ProbeSettingsFor(o.config.ProbeTarget, outbound, ProbeSettings{
+		URL:     probeURL,
+		Timeout: time.Second * 5,
+	})
+
 	httpTransport := http.Transport{
 		Proxy: func(*http.Request) (*url.URL, error) {
 			return nil, nil
@@ -155,24 +171,15 @@ func (o *Observer) probe(outbound string) ProbeResult {
 			return http.ErrUseLastResponse
 		},
 		Jar:     nil,
-		Timeout: time.Second * 5,
+		Timeout: settings.Timeout,
 	}
 	var GETTime time.Duration
 	err := task.Run(o.ctx, func() error {
-		startTime := time.Now()
-		probeURL := "https://api.v2fly.org/checkConnection.svgz"
-		if o.config.ProbeUrl != "" {
-			probeURL = o.config.ProbeUrl
-		}
-		response, err := httpClient.Get(probeURL)
+		delay, err := settings.Measure(httpClient, http.MethodGet)
 		if err != nil {
 			return newError("outbound failed to relay connection").Base(err)
 		}
-		if response.Body != nil {
-			response.Body.Close()
-		}
-		endTime := time.Now()
-		GETTime = endTime.Sub(startTime)
+		GETTime = delay
 		return nil
 	})
 	if err != nil {
@@ -192,8 +199,10 @@ func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
 	o.statusLock.Lock()
 	defer o.statusLock.Unlock()
 	var status *OutboundStatus
+	changed := true
 	if location := o.findStatusLocationLockHolderOnly(outbound); location != -1 {
 		status = o.status[location]
+		changed = status.Alive != result.Alive
 	} else {
 		status = &OutboundStatus{}
 		o.status = append(o.status, status)
@@ -211,6 +220,16 @@ func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
 		status.Delay = 99999999
 	}
 
+	if changed {
+		o.events.Publish(&HealthEvent{
+			OutboundTag:     outbound,
+			Alive:           status.Alive,
+			Delay:           result.Delay,
+			Timestamp:       status.LastTryTime,
+			LastErrorReason: status.LastErrorReason,
+		})
+	}
+
 	if o.StatusUpdate != nil {
 		o.StatusUpdate(status)
 	}
@@ -0,0 +1,84 @@
+package observatory
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProbeSettings are the settings to probe an outbound with HTTP requests.
+type ProbeSettings struct {
+	URL string
+	// ExpectedStatus are the status codes considered as success. Any status is accepted if empty.
+	ExpectedStatus []uint32
+	Timeout        time.Duration
+}
+
+// ProbeSettingsFor returns the probe settings of the outbound tag from the
+// first of targets whose selectors match it. Fields the target doesn't set
+// are taken from fallback.
+func ProbeSettingsFor(targets []*ProbeTarget, tag string, fallback ProbeSettings) ProbeSettings {
+	target := selectProbeTarget(targets, tag)
+	if target == nil {
+		return fallback
+	}
+	settings := fallback
+	if target.Url != "" {
+		settings.URL = target.Url
+	}
+	if len(target.ExpectedStatus) > 0 {
+		settings.ExpectedStatus = target.ExpectedStatus
+	}
+	if target.Timeout > 0 {
+		settings.Timeout = time.Duration(target.Timeout)
+	}
+	return settings
+}
+
+func selectProbeTarget(targets []*ProbeTarget, tag string) *ProbeTarget {
+	for _, target := range targets {
+		for _, selector := range target.SubjectSelector {
+			if strings.HasPrefix(tag, selector) {
+				return target
+			}
+		}
+	}
+	return nil
+}
+
+// AcceptStatus returns whether code is considered as success.
+func (s ProbeSettings) AcceptStatus(code int) bool {
+	if len(s.ExpectedStatus) == 0 {
+		return true
+	}
+	for _, expected := range s.ExpectedStatus {
+		if int(expected) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Measure sends a request of method to the URL with client, and returns the
+// time until the response is read completely. The timeout of the settings
+// should be set on client.
+func (s ProbeSettings) Measure(client *http.Client, method string) (time.Duration, error) {
+	request, err := http.NewRequest(method, s.URL, nil)
+	if err != nil {
+		return 0, newError("invalid probe URL ", s.URL).Base(err)
+	}
+	start := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if !s.AcceptStatus(response.StatusCode) {
+		return 0, newError("unexpected response status ", response.Status)
+	}
+	if _, err := io.Copy(io.Discard, response.Body); err != nil {
+		return 0, newError("failed to read response").Base(err)
+	}
+	return time.Since(start), nil
+}
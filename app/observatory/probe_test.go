@@ -0,0 +1,116 @@
+package observatory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+)
+
+func newProbeServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow response"))
+	})
+	return httptest.NewServer(mux)
+}
+
+// useDirectDialer makes tagged outbounds dial directly, except the blocked
+// one.
+func useDirectDialer(t *testing.T) {
+	dialer := tagged.Dialer
+	tagged.Dialer = func(ctx context.Context, dest net.Destination, tag string) (net.Conn, error) {
+		if tag == "blocked" {
+			return nil, newError("blocked")
+		}
+		return net.Dial(dest.Network.SystemString(), dest.NetAddr())
+	}
+	t.Cleanup(func() {
+		tagged.Dialer = dialer
+	})
+}
+
+func TestProbeSettingsFor(t *testing.T) {
+	targets := []*ProbeTarget{
+		{SubjectSelector: []string{"a-"}, Url: "http://a.example/"},
+		{SubjectSelector: []string{"a-", "b-"}, ExpectedStatus: []uint32{204}, Timeout: int64(time.Second)},
+	}
+	fallback := ProbeSettings{URL: "http://default.example/", Timeout: 5 * time.Second}
+
+	if s := ProbeSettingsFor(targets, "a-1", fallback); s.URL != "http://a.example/" || len(s.ExpectedStatus) != 0 || s.Timeout != 5*time.Second {
+		t.Error("unexpected settings for a-1: ", s)
+	}
+	if s := ProbeSettingsFor(targets, "b-1", fallback); s.URL != "http://default.example/" || len(s.ExpectedStatus) != 1 || s.Timeout != time.Second {
+		t.Error("unexpected settings for b-1: ", s)
+	}
+	if s := ProbeSettingsFor(targets, "c-1", fallback); s.URL != fallback.URL || s.Timeout != fallback.Timeout {
+		t.Error("unexpected settings for c-1: ", s)
+	}
+
+	s := ProbeSettings{ExpectedStatus: []uint32{200, 204}}
+	if !s.AcceptStatus(204) || s.AcceptStatus(500) {
+		t.Error("unexpected status acceptance of ", s.ExpectedStatus)
+	}
+	if !(ProbeSettings{}).AcceptStatus(500) {
+		t.Error("expected any status to be accepted without expected status")
+	}
+}
+
+func TestObserverProbe(t *testing.T) {
+	server := newProbeServer()
+	defer server.Close()
+	useDirectDialer(t)
+
+	o := &Observer{
+		ctx: context.Background(),
+		config: &Config{
+			ProbeUrl: server.URL + "/error",
+			ProbeTarget: []*ProbeTarget{
+				{SubjectSelector: []string{"ok"}, Url: server.URL + "/ok", ExpectedStatus: []uint32{204}},
+				{SubjectSelector: []string{"strict"}, ExpectedStatus: []uint32{204}},
+				{SubjectSelector: []string{"slow"}, Url: server.URL + "/slow", ExpectedStatus: []uint32{200}},
+				{SubjectSelector: []string{"timeout"}, Url: server.URL + "/slow", Timeout: int64(50 * time.Millisecond)},
+			},
+		},
+	}
+
+	cases := []struct {
+		outbound string
+		alive    bool
+	}{
+		// Any status is accepted by default.
+		{"default", true},
+		{"ok", true},
+		{"strict", false},
+		{"slow", true},
+		{"timeout", false},
+		{"blocked", false},
+	}
+	for _, c := range cases {
+		result := o.probe(c.outbound)
+		if result.Alive != c.alive {
+			t.Error("expected ", c.outbound, " alive: ", c.alive, ", but got ", result.Alive, " ", result.LastErrorReason)
+		}
+		if c.outbound == "slow" && result.Delay < 100 {
+			t.Error("expected delay of the full request, but got ", result.Delay, "ms")
+		}
+		o.updateStatusForResult(c.outbound, &result)
+	}
+
+	for _, status := range o.status {
+		if status.Alive && status.LastSeenTime == 0 || !status.Alive && status.LastErrorReason == "" {
+			t.Error("unexpected status: ", status)
+		}
+	}
+}
@@ -58,6 +58,20 @@ func (p *Policy) overrideWith(another *Policy) {
 			Connection: another.Buffer.Connection,
 		}
 	}
+	if another.Bandwidth != nil {
+		p.Bandwidth = &Policy_Bandwidth{
+			Uplink:        another.Bandwidth.Uplink,
+			Downlink:      another.Bandwidth.Downlink,
+			UplinkBurst:   another.Bandwidth.UplinkBurst,
+			DownlinkBurst: another.Bandwidth.DownlinkBurst,
+		}
+	}
+	if another.MaxConnections != 0 {
+		p.MaxConnections = another.MaxConnections
+	}
+	if another.DnsServer != "" {
+		p.DnsServer = another.DnsServer
+	}
 }
 
 // ToCorePolicy converts this Policy to policy.Session.
@@ -73,10 +87,19 @@ func (p *Policy) ToCorePolicy() policy.Session {
 	if p.Stats != nil {
 		cp.Stats.UserUplink = p.Stats.UserUplink
 		cp.Stats.UserDownlink = p.Stats.UserDownlink
+		cp.Stats.UserOnline = p.Stats.UserOnline
 	}
 	if p.Buffer != nil {
 		cp.Buffer.PerConnection = p.Buffer.Connection
 	}
+	if p.Bandwidth != nil {
+		cp.Bandwidth.Uplink = p.Bandwidth.Uplink
+		cp.Bandwidth.Downlink = p.Bandwidth.Downlink
+		cp.Bandwidth.UplinkBurst = p.Bandwidth.UplinkBurst
+		cp.Bandwidth.DownlinkBurst = p.Bandwidth.DownlinkBurst
+	}
+	cp.MaxConnections = p.MaxConnections
+	cp.DNSServer = p.DnsServer
 	return cp
 }
 
@@ -84,10 +107,11 @@ func (p *Policy) ToCorePolicy() policy.Session {
 func (p *SystemPolicy) ToCorePolicy() policy.System {
 	return policy.System{
 		Stats: policy.SystemStats{
-			InboundUplink:    p.Stats.InboundUplink,
-			InboundDownlink:  p.Stats.InboundDownlink,
-			OutboundUplink:   p.Stats.OutboundUplink,
-			OutboundDownlink: p.Stats.OutboundDownlink,
+			InboundUplink:       p.Stats.InboundUplink,
+			InboundDownlink:     p.Stats.InboundDownlink,
+			OutboundUplink:      p.Stats.OutboundUplink,
+			OutboundDownlink:    p.Stats.OutboundDownlink,
+			OutboundDialLatency: p.Stats.OutboundDialLatency,
 		},
 	}
 }
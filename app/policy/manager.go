@@ -43,6 +43,16 @@ func (m *Instance) ForLevel(level uint32) policy.Session {
 	return policy.SessionDefault()
 }
 
+// Levels returns the user levels with a policy configured, in no particular
+// order.
+func (m *Instance) Levels() []uint32 {
+	levels := make([]uint32, 0, len(m.levels))
+	for level := range m.levels {
+		levels = append(levels, level)
+	}
+	return levels
+}
+
 // ForSystem implements policy.Manager.
 func (m *Instance) ForSystem() policy.System {
 	if m.system == nil {
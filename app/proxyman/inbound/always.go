@@ -91,6 +91,12 @@ func NewAlwaysOnInboundHandlerWithProxy(ctx context.Context, tag string, receive
 		}
 		mss.SocketSettings.ReceiveOriginalDestAddress = true
 	}
+	// Unix domain sockets, listened without port range, never leave the host.
+	if checker, ok := p.(proxy.StreamSettingsChecker); ok && pr != nil {
+		if err := checker.CheckStreamSettings(mss); err != nil {
+			return nil, newError("invalid stream settings for inbound ", tag).Base(err).AtError()
+		}
+	}
 	if pr == nil {
 		if net.HasNetwork(nl, net.Network_UNIX) {
 			newError("creating unix domain socket worker on ", address).AtDebug().WriteToLog()
@@ -125,6 +125,12 @@ func (h *DynamicInboundHandler) refresh() error {
 			continue
 		}
 		p := rawProxy.(proxy.Inbound)
+		if checker, ok := p.(proxy.StreamSettingsChecker); ok {
+			if err := checker.CheckStreamSettings(h.streamSettings); err != nil {
+				newError("invalid stream settings").Base(err).AtWarning().WriteToLog()
+				continue
+			}
+		}
 		nl := p.Network()
 		if net.HasNetwork(nl, net.Network_TCP) {
 			worker := &tcpWorker{
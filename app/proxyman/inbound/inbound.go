@@ -35,20 +35,33 @@ func (*Manager) Type() interface{} {
 	return inbound.ManagerType()
 }
 
-// AddHandler implements inbound.Manager.
+// AddHandler implements inbound.Manager. If the Manager is running, the
+// handler is started, and it is not added if it fails to start, for example
+// because its port is in use.
 func (m *Manager) AddHandler(ctx context.Context, handler inbound.Handler) error {
 	m.access.Lock()
 	defer m.access.Unlock()
 
 	tag := handler.Tag()
 	if len(tag) > 0 {
-		m.taggedHandlers[tag] = handler
-	} else {
-		m.untaggedHandler = append(m.untaggedHandler, handler)
+		if _, found := m.taggedHandlers[tag]; found {
+			return newError("existing tag found: ", tag)
+		}
 	}
 
 	if m.running {
-		return handler.Start()
+		if err := handler.Start(); err != nil {
+			if err := handler.Close(); err != nil {
+				newError("failed to close handler ", tag).Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+			}
+			return newError("failed to start handler ", tag).Base(err)
+		}
+	}
+
+	if len(tag) > 0 {
+		m.taggedHandlers[tag] = handler
+	} else {
+		m.untaggedHandler = append(m.untaggedHandler, handler)
 	}
 
 	return nil
@@ -66,24 +79,25 @@ func (m *Manager) GetHandler(ctx context.Context, tag string) (inbound.Handler,
 	return handler, nil
 }
 
-// RemoveHandler implements inbound.Manager.
+// RemoveHandler implements inbound.Manager. The listeners of the handler are
+// closed, while the connections it has accepted run to completion.
 func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	if tag == "" {
-		return common.ErrNoClue
+		return newError("empty tag")
 	}
 
 	m.access.Lock()
 	defer m.access.Unlock()
 
-	if handler, found := m.taggedHandlers[tag]; found {
-		if err := handler.Close(); err != nil {
-			newError("failed to close handler ", tag).Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
-		}
-		delete(m.taggedHandlers, tag)
-		return nil
+	handler, found := m.taggedHandlers[tag]
+	if !found {
+		return newError("handler not found: ", tag)
 	}
-
-	return common.ErrNoClue
+	if err := handler.Close(); err != nil {
+		newError("failed to close handler ", tag).Base(err).AtWarning().WriteToLog(session.ExportIDToError(ctx))
+	}
+	delete(m.taggedHandlers, tag)
+	return nil
 }
 
 // Start implements common.Runnable.
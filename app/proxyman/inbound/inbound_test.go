@@ -0,0 +1,88 @@
+package inbound_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	. "github.com/v2fly/v2ray-core/v5/app/proxyman/inbound"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	_ "github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
+)
+
+// echoInbound writes back what the connections send.
+type echoInbound struct{}
+
+func (echoInbound) Network() []net.Network {
+	return []net.Network{net.Network_TCP}
+}
+
+func (echoInbound) Process(ctx context.Context, network net.Network, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	_, err := io.Copy(conn, conn)
+	return err
+}
+
+func newEchoHandler(ctx context.Context, tag string, port net.Port) *AlwaysOnInboundHandler {
+	handler, err := NewAlwaysOnInboundHandlerWithProxy(ctx, tag, &proxyman.ReceiverConfig{
+		PortRange: net.SinglePortRange(port),
+		Listen:    net.NewIPOrDomain(net.LocalHostIP),
+	}, echoInbound{}, false)
+	common.Must(err)
+	return handler
+}
+
+func TestAddRemoveHandler(t *testing.T) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	ctx := core.WithContext(context.Background(), instance)
+
+	manager, err := New(ctx, &proxyman.InboundConfig{})
+	common.Must(err)
+	common.Must(manager.Start())
+	defer manager.Close()
+
+	port := tcp.PickPort()
+	address := net.TCPDestination(net.LocalHostIP, port).NetAddr()
+	common.Must(manager.AddHandler(ctx, newEchoHandler(ctx, "echo", port)))
+
+	conn, err := net.Dial("tcp", address)
+	common.Must(err)
+	common.Must2(conn.Write([]byte("ping")))
+	response := make([]byte, 4)
+	common.Must(conn.SetReadDeadline(time.Now().Add(time.Second * 5)))
+	common.Must2(io.ReadFull(conn, response))
+	if string(response) != "ping" {
+		t.Error("unexpected response: ", string(response))
+	}
+	common.Must(conn.Close())
+
+	if err := manager.AddHandler(ctx, newEchoHandler(ctx, "echo", tcp.PickPort())); err == nil {
+		t.Error("expected error on duplicate tag")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	occupied := net.Port(listener.Addr().(*net.TCPAddr).Port)
+	if err := manager.AddHandler(ctx, newEchoHandler(ctx, "other", occupied)); err == nil {
+		t.Error("expected error on port in use")
+	}
+	if _, err := manager.GetHandler(ctx, "other"); err == nil {
+		t.Error("expected handler failed to start not to be added")
+	}
+
+	common.Must(manager.RemoveHandler(ctx, "echo"))
+	if conn, err := net.Dial("tcp", address); err == nil {
+		conn.Close()
+		t.Error("expected port to be closed")
+	}
+	if err := manager.RemoveHandler(ctx, "echo"); err == nil {
+		t.Error("expected error on removing nonexistent tag")
+	}
+}
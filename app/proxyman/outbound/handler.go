@@ -3,6 +3,8 @@ package outbound
 import (
 	"context"
 	"io"
+	"sync"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
@@ -53,6 +55,17 @@ func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter)
 	return uplinkCounter, downlinkCounter
 }
 
+func getDialLatencyHistogram(v *core.Instance, tag string) stats.Histogram {
+	policy := v.GetFeature(policy.ManagerType()).(policy.Manager)
+	if len(tag) == 0 || !policy.ForSystem().Stats.OutboundDialLatency {
+		return nil
+	}
+	statsManager := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	name := "outbound>>>" + tag + ">>>latency>>>dial"
+	h, _ := stats.GetOrRegisterHistogram(statsManager, name, nil)
+	return h
+}
+
 // Handler is an implements of outbound.Handler.
 type Handler struct {
 	tag               string
@@ -64,8 +77,14 @@ type Handler struct {
 	mux               *mux.ClientManager
 	uplinkCounter     stats.Counter
 	downlinkCounter   stats.Counter
+	dialLatency       stats.Histogram
 	muxPacketEncoding packetaddr.PacketAddrType
 	pingManager       ping.Manager
+
+	access sync.Mutex
+	// active is the number of connections being dispatched.
+	active int32
+	closed bool
 }
 
 // NewHandler create a new Handler based on the given configuration.
@@ -78,6 +97,7 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		dnsClient:       v.GetFeature(dns.ClientType()).(dns.NewClient),
 		uplinkCounter:   uplinkCounter,
 		downlinkCounter: downlinkCounter,
+		dialLatency:     getDialLatencyHistogram(v, config.Tag),
 	}
 	if pingManager := v.GetFeature(ping.ManagerType()); pingManager != nil {
 		h.pingManager = pingManager.(ping.Manager)
@@ -116,6 +136,12 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 		return nil, newError("not an outbound handler")
 	}
 
+	if checker, ok := proxyHandler.(proxy.StreamSettingsChecker); ok {
+		if err := checker.CheckStreamSettings(h.streamSettings); err != nil {
+			return nil, newError("invalid stream settings for outbound ", config.Tag).Base(err).AtError()
+		}
+	}
+
 	if h.senderSettings != nil && h.senderSettings.MultiplexSettings != nil {
 		config := h.senderSettings.MultiplexSettings
 		if config.Concurrency < 1 || config.Concurrency > 1024 {
@@ -132,6 +158,7 @@ func NewHandler(ctx context.Context, config *core.OutboundHandlerConfig) (outbou
 					mux.ClientStrategy{
 						MaxConcurrency: config.Concurrency,
 						MaxConnection:  128,
+						IdleTimeout:    time.Duration(config.IdleTimeout) * time.Second,
 					},
 				),
 			},
@@ -149,6 +176,16 @@ func (h *Handler) Tag() string {
 
 // Dispatch implements proxy.Outbound.Dispatch.
 func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
+	if !h.acquire() {
+		err := newError("outbound ", h.tag, " is closed")
+		err.WriteToLog(session.ExportIDToError(ctx))
+		session.SubmitOutboundErrorToOriginator(ctx, err)
+		common.Interrupt(link.Writer)
+		common.Interrupt(link.Reader)
+		return
+	}
+	defer h.release()
+
 	outbound := session.OutboundFromContext(ctx)
 	destination := outbound.Target
 
@@ -233,6 +270,15 @@ func (h *Handler) IsConnDispatcher() bool {
 }
 
 func (h *Handler) DispatchConn(ctx context.Context, conn net.Conn) {
+	if !h.acquire() {
+		err := newError("outbound ", h.tag, " is closed")
+		err.WriteToLog(session.ExportIDToError(ctx))
+		session.SubmitOutboundErrorToOriginator(ctx, err)
+		common.Close(conn)
+		return
+	}
+	defer h.release()
+
 	outbound := session.OutboundFromContext(ctx)
 	destination := outbound.Target
 
@@ -339,7 +385,7 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Conn
 				conn := buf.NewConnection(buf.ConnectionInputMulti(uplinkWriter), buf.ConnectionOutputMulti(downlinkReader))
 
 				if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
-					tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+					tlsConfig := config.GetTLSConfigWithContext(ctx, tls.WithDestination(dest))
 					conn = tls.Client(conn, tlsConfig)
 				} else if config := xtls.ConfigFromStreamSettings(h.streamSettings); config != nil {
 					return xtls.Client(conn, config.GetXTLSConfig(xtls.WithDestination(dest))), nil
@@ -389,7 +435,11 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Conn
 		return h.getStatCouterConnection(pingConn), nil
 	}
 
+	start := time.Now()
 	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	if err == nil && h.dialLatency != nil {
+		h.dialLatency.Observe(time.Since(start).Milliseconds())
+	}
 	return h.getStatCouterConnection(conn), err
 }
 
@@ -414,8 +464,48 @@ func (h *Handler) Start() error {
 	return nil
 }
 
+// acquire counts a connection to dispatch, or returns false if the handler is
+// closed.
+func (h *Handler) acquire() bool {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.active++
+	return true
+}
+
+func (h *Handler) release() {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	h.active--
+}
+
+// stopIfIdle stops the handler from dispatching connections if none is being
+// dispatched, and returns whether it is stopped.
+func (h *Handler) stopIfIdle() bool {
+	h.access.Lock()
+	defer h.access.Unlock()
+
+	if h.active != 0 {
+		return false
+	}
+	h.closed = true
+	return true
+}
+
 // Close implements common.Closable.
 func (h *Handler) Close() error {
-	common.Close(h.mux)
+	h.access.Lock()
+	h.closed = true
+	h.access.Unlock()
+
+	if h.mux != nil {
+		common.Close(h.mux)
+	}
+	common.Close(h.proxy)
 	return nil
 }
@@ -9,11 +9,15 @@ import (
 	"github.com/v2fly/v2ray-core/v5/app/policy"
 	. "github.com/v2fly/v2ray-core/v5/app/proxyman/outbound"
 	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	feature_stats "github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/proxy/freedom"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	_ "github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -44,12 +48,24 @@ func TestOutboundWithoutStatCounter(t *testing.T) {
 		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
 	})
 	conn, _ := h.(*Handler).Dial(ctx, net.TCPDestination(net.DomainAddress("localhost"), 13146))
-	_, ok := conn.(*internet.StatCouterConnection)
+	_, ok := conn.(*internet.StatCounterConn)
 	if ok {
 		t.Errorf("Expected conn to not be StatCouterConnection")
 	}
 }
 
+func TestOutboundCloseWithoutMux(t *testing.T) {
+	v, _ := core.New(&core.Config{})
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := core.WithContext(context.Background(), v)
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag:           "tag",
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	})
+	common.Must(err)
+	common.Must(h.(*Handler).Close())
+}
+
 func TestOutboundWithStatCounter(t *testing.T) {
 	config := &core.Config{
 		App: []*anypb.Any{
@@ -73,8 +89,53 @@ func TestOutboundWithStatCounter(t *testing.T) {
 		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
 	})
 	conn, _ := h.(*Handler).Dial(ctx, net.TCPDestination(net.DomainAddress("localhost"), 13146))
-	_, ok := conn.(*internet.StatCouterConnection)
+	_, ok := conn.(*internet.StatCounterConn)
 	if !ok {
 		t.Errorf("Expected conn to be StatCouterConnection")
 	}
 }
+
+func TestOutboundWithDialLatency(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(msg []byte) []byte { return msg },
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	config := &core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&policy.Config{
+				System: &policy.SystemPolicy{
+					Stats: &policy.SystemPolicy_Stats{
+						OutboundDialLatency: true,
+					},
+				},
+			}),
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+	v.AddFeature((outbound.Manager)(new(Manager)))
+	ctx := core.WithContext(context.Background(), v)
+	h, err := NewHandler(ctx, &core.OutboundHandlerConfig{
+		Tag:           "tag",
+		ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+	})
+	common.Must(err)
+
+	histogram := v.GetFeature(feature_stats.ManagerType()).(feature_stats.Manager).GetHistogram("outbound>>>tag>>>latency>>>dial")
+	if histogram == nil {
+		t.Fatal("expected dial latency histogram")
+	}
+	for i := 0; i < 2; i++ {
+		conn, err := h.(*Handler).Dial(ctx, dest)
+		common.Must(err)
+		conn.Close()
+	}
+	if count := histogram.Snapshot(false).Count; count != 2 {
+		t.Error("expected 2 dial latencies, but got ", count)
+	}
+}
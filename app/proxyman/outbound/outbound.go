@@ -6,6 +6,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
@@ -14,6 +15,27 @@ import (
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
 )
 
+// replacedHandlerCheckInterval is how often a replaced handler is checked for
+// connections.
+var replacedHandlerCheckInterval = time.Second
+
+// closeWhenIdle closes a replaced handler once it has no connections, so that
+// they can drain however long they last. Handlers that don't count their
+// connections are left open, as closing them could break their connections.
+func closeWhenIdle(handler outbound.Handler) {
+	idler, ok := handler.(interface{ stopIfIdle() bool })
+	if !ok {
+		newError("replaced outbound ", handler.Tag(), " doesn't count its connections, and is left open").AtWarning().WriteToLog()
+		return
+	}
+	for !idler.stopIfIdle() {
+		time.Sleep(replacedHandlerCheckInterval)
+	}
+	if err := common.Close(handler); err != nil {
+		newError("failed to close replaced outbound ", handler.Tag()).Base(err).WriteToLog()
+	}
+}
+
 // Manager is to manage all outbound handlers.
 type Manager struct {
 	access           sync.RWMutex
@@ -142,6 +164,61 @@ func (m *Manager) RemoveHandler(ctx context.Context, tag string) error {
 	return nil
 }
 
+// ReplaceHandlers implements outbound.HandlerReplacer.
+func (m *Manager) ReplaceHandlers(ctx context.Context, handlers []outbound.Handler, removed []string) error {
+	for _, handler := range handlers {
+		if handler.Tag() == "" {
+			return newError("cannot replace an untagged outbound")
+		}
+	}
+
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if m.running {
+		var started []outbound.Handler
+		for _, handler := range handlers {
+			if m.taggedHandler[handler.Tag()] == handler {
+				continue
+			}
+			if err := handler.Start(); err != nil {
+				for _, handler := range started {
+					_ = handler.Close()
+				}
+				return newError("failed to start outbound ", handler.Tag()).Base(err)
+			}
+			started = append(started, handler)
+		}
+	}
+
+	var displaced []outbound.Handler
+	for _, handler := range handlers {
+		if previous := m.taggedHandler[handler.Tag()]; previous != nil && previous != handler {
+			displaced = append(displaced, previous)
+		}
+		m.taggedHandler[handler.Tag()] = handler
+		if m.defaultHandler != nil && m.defaultHandler.Tag() == handler.Tag() {
+			m.defaultHandler = handler
+		}
+	}
+	for _, tag := range removed {
+		if previous := m.taggedHandler[tag]; previous != nil {
+			displaced = append(displaced, previous)
+		}
+		delete(m.taggedHandler, tag)
+		if m.defaultHandler != nil && m.defaultHandler.Tag() == tag {
+			m.defaultHandler = nil
+		}
+	}
+	if m.defaultHandler == nil && len(handlers) > 0 {
+		m.defaultHandler = handlers[0]
+	}
+	for _, handler := range displaced {
+		go closeWhenIdle(handler)
+	}
+	return nil
+}
+
 // Select implements outbound.HandlerSelector.
 func (m *Manager) Select(selectors []string) []string {
 	m.access.RLock()
@@ -0,0 +1,129 @@
+package outbound
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+)
+
+type countingHandler struct {
+	tag    string
+	active int32
+	closed chan struct{}
+}
+
+func newCountingHandler(tag string) *countingHandler {
+	return &countingHandler{tag: tag, closed: make(chan struct{})}
+}
+
+func (h *countingHandler) Start() error { return nil }
+
+func (h *countingHandler) Close() error {
+	close(h.closed)
+	return nil
+}
+
+func (h *countingHandler) Tag() string { return h.tag }
+
+func (h *countingHandler) Dispatch(context.Context, *transport.Link) {}
+
+func (h *countingHandler) stopIfIdle() bool {
+	return atomic.LoadInt32(&h.active) == 0
+}
+
+func isClosed(h *countingHandler, timeout time.Duration) bool {
+	select {
+	case <-h.closed:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestReplaceHandlersClosesReplaced(t *testing.T) {
+	defer func(checkInterval time.Duration) {
+		replacedHandlerCheckInterval = checkInterval
+	}(replacedHandlerCheckInterval)
+	replacedHandlerCheckInterval = time.Millisecond
+
+	m, err := New(context.Background(), nil)
+	common.Must(err)
+	replaced := newCountingHandler("a")
+	removed := newCountingHandler("b")
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{replaced, removed}, nil))
+
+	// The replaced handler is closed once its connection ends.
+	atomic.StoreInt32(&replaced.active, 1)
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{newCountingHandler("a")}, []string{"b"}))
+	if !isClosed(removed, time.Second) {
+		t.Error("expected removed handler to be closed")
+	}
+	if isClosed(replaced, 50*time.Millisecond) {
+		t.Fatal("unexpected close of handler with connections")
+	}
+	atomic.StoreInt32(&replaced.active, 0)
+	if !isClosed(replaced, time.Second) {
+		t.Error("expected replaced handler to be closed")
+	}
+}
+
+func TestReplaceHandlersDefault(t *testing.T) {
+	m, err := New(context.Background(), nil)
+	common.Must(err)
+	expectDefault := func(expected outbound.Handler) {
+		t.Helper()
+		if h := m.GetDefaultHandler(); h != expected {
+			t.Errorf("expected default handler %v, got %v", expected, h)
+		}
+	}
+
+	a := newCountingHandler("a")
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{a, newCountingHandler("b")}, nil))
+	expectDefault(a)
+
+	// Adding handlers keeps the default.
+	c := newCountingHandler("c")
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{c}, nil))
+	expectDefault(a)
+
+	// Replacing the default replaces it with the handler of its tag.
+	newA := newCountingHandler("a")
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{c, newA}, nil))
+	expectDefault(newA)
+
+	// Removing the default falls back to the first of handlers.
+	common.Must(m.ReplaceHandlers(context.Background(), []outbound.Handler{c}, []string{"a"}))
+	expectDefault(c)
+	common.Must(m.ReplaceHandlers(context.Background(), nil, []string{"c"}))
+	expectDefault(nil)
+}
+
+func TestHandlerStopIfIdle(t *testing.T) {
+	h := &Handler{tag: "a"}
+	if !h.acquire() {
+		t.Fatal("expected open handler to dispatch")
+	}
+	if h.stopIfIdle() {
+		t.Error("unexpected stop of handler with connections")
+	}
+	h.release()
+	if !h.stopIfIdle() {
+		t.Error("expected idle handler to stop")
+	}
+	if h.acquire() {
+		t.Error("expected stopped handler not to dispatch")
+	}
+
+	// Dispatching to a stopped handler ends the link at once.
+	r, w := pipe.New()
+	h.Dispatch(context.Background(), &transport.Link{Reader: r, Writer: w})
+	if _, err := r.ReadMultiBuffer(); err == nil {
+		t.Error("expected link to be interrupted")
+	}
+}
@@ -4,10 +4,13 @@ package command
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/router"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"google.golang.org/grpc"
@@ -17,6 +20,17 @@ import (
 type routingServer struct {
 	router       routing.Router
 	routingStats stats.Channel
+
+	ohm            outbound.Manager
+	createOutbound func(*core.OutboundHandlerConfig) (outbound.Handler, error)
+	// reload serializes ReloadConfig, which computes the outbounds to remove
+	// from the current ones.
+	reload sync.Mutex
+}
+
+// routingReloader is a router whose config can be replaced at runtime.
+type routingReloader interface {
+	PrepareReload(config *router.Config) (func(), error)
 }
 
 func (s *routingServer) GetBalancerInfo(ctx context.Context, request *GetBalancerInfoRequest) (*GetBalancerInfoResponse, error) {
@@ -107,6 +121,124 @@ func (s *routingServer) SubscribeRoutingStats(request *SubscribeRoutingStatsRequ
 	}
 }
 
+func (s *routingServer) ReloadConfig(ctx context.Context, request *ReloadConfigRequest) (*ReloadConfigResponse, error) {
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	var replacer outbound.HandlerReplacer
+	var handlers []outbound.Handler
+	var removed []string
+	closeHandlers := func() {
+		for _, handler := range handlers {
+			common.Close(handler)
+		}
+	}
+
+	// tags are the outbound tags after reload, or nil if unknown.
+	var tags []string
+	if hs, ok := s.ohm.(outbound.HandlerSelector); ok {
+		tags = hs.Select([]string{""})
+	}
+	if len(request.Outbound) > 0 {
+		var ok bool
+		replacer, ok = s.ohm.(outbound.HandlerReplacer)
+		if !ok || s.createOutbound == nil {
+			return nil, newError("outbound manager does not support reloading")
+		}
+		newTags := make(map[string]bool, len(request.Outbound))
+		for _, config := range request.Outbound {
+			if config.Tag == "" {
+				return nil, newError("outbounds to reload must be tagged")
+			}
+			if newTags[config.Tag] {
+				return nil, newError("duplicated outbound tag ", config.Tag)
+			}
+			newTags[config.Tag] = true
+		}
+		for _, config := range request.Outbound {
+			handler, err := s.createOutbound(config)
+			if err != nil {
+				closeHandlers()
+				return nil, newError("invalid outbound ", config.Tag).Base(err)
+			}
+			handlers = append(handlers, handler)
+		}
+		for _, tag := range tags {
+			if !newTags[tag] {
+				removed = append(removed, tag)
+			}
+		}
+		tags = make([]string, 0, len(request.Outbound))
+		for _, config := range request.Outbound {
+			tags = append(tags, config.Tag)
+		}
+	}
+
+	var applyRouting func()
+	if request.Routing != nil {
+		reloader, ok := s.router.(routingReloader)
+		if !ok {
+			closeHandlers()
+			return nil, newError("router does not support reloading")
+		}
+		if tags != nil {
+			if err := checkRuleTargets(request.Routing, tags); err != nil {
+				closeHandlers()
+				return nil, err
+			}
+		}
+		var err error
+		applyRouting, err = reloader.PrepareReload(request.Routing)
+		if err != nil {
+			closeHandlers()
+			return nil, newError("invalid routing config").Base(err)
+		}
+	}
+
+	// New outbounds are added before the rules referring to them, and the
+	// removed ones are dropped after no rule refers to them. The new outbounds
+	// are passed again on removal, so that the first of them replaces a removed
+	// default outbound.
+	if replacer != nil {
+		if err := replacer.ReplaceHandlers(ctx, handlers, nil); err != nil {
+			closeHandlers()
+			return nil, newError("failed to add outbounds").Base(err)
+		}
+	}
+	if applyRouting != nil {
+		applyRouting()
+	}
+	if len(removed) > 0 {
+		if err := replacer.ReplaceHandlers(ctx, handlers, removed); err != nil {
+			return nil, newError("failed to remove outbounds").Base(err)
+		}
+	}
+	newError("reloaded routing and ", len(handlers), " outbounds, removed outbounds ", removed).AtInfo().WriteToLog()
+	return &ReloadConfigResponse{}, nil
+}
+
+// checkRuleTargets checks that the rules of config only route to outbounds
+// with tags.
+func checkRuleTargets(config *router.Config, tags []string) error {
+	for _, rule := range config.Rule {
+		target := rule.GetTag()
+		if target == "" {
+			continue
+		}
+		found := false
+		for _, tag := range tags {
+			if tag == target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return newError("rule routes to unknown outbound ", target)
+		}
+	}
+	return nil
+}
+
 func (s *routingServer) mustEmbedUnimplementedRoutingServiceServer() {}
 
 type service struct {
@@ -114,8 +246,14 @@ type service struct {
 }
 
 func (s *service) Register(server *grpc.Server) {
-	common.Must(s.v.RequireFeatures(func(router routing.Router, stats stats.Manager) {
-		RegisterRoutingServiceServer(server, NewRoutingServer(router, nil))
+	common.Must(s.v.RequireFeatures(func(router routing.Router, stats stats.Manager, ohm outbound.Manager) {
+		RegisterRoutingServiceServer(server, &routingServer{
+			router: router,
+			ohm:    ohm,
+			createOutbound: func(config *core.OutboundHandlerConfig) (outbound.Handler, error) {
+				return core.CreateOutboundHandler(s.v, config)
+			},
+		})
 	}))
 }
 
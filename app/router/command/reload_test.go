@@ -0,0 +1,211 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"github.com/v2fly/v2ray-core/v5/testing/mocks"
+	"github.com/v2fly/v2ray-core/v5/transport"
+)
+
+type testHandler struct {
+	tag        string
+	started    bool
+	closed     bool
+	dispatched int
+}
+
+func (h *testHandler) Start() error {
+	h.started = true
+	return nil
+}
+
+func (h *testHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func (h *testHandler) Tag() string {
+	return h.tag
+}
+
+func (h *testHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	h.dispatched++
+}
+
+type testOutboundManager struct {
+	outbound.Manager
+	handlers       map[string]outbound.Handler
+	defaultHandler outbound.Handler
+}
+
+func (m *testOutboundManager) GetHandler(tag string) outbound.Handler {
+	return m.handlers[tag]
+}
+
+func (m *testOutboundManager) GetDefaultHandler() outbound.Handler {
+	return m.defaultHandler
+}
+
+func (m *testOutboundManager) Select(selectors []string) []string {
+	var tags []string
+	for tag := range m.handlers {
+		for _, selector := range selectors {
+			if strings.HasPrefix(tag, selector) {
+				tags = append(tags, tag)
+				break
+			}
+		}
+	}
+	return tags
+}
+
+func (m *testOutboundManager) ReplaceHandlers(ctx context.Context, handlers []outbound.Handler, removed []string) error {
+	for _, handler := range handlers {
+		if m.handlers[handler.Tag()] == handler {
+			continue
+		}
+		common.Must(handler.Start())
+		m.handlers[handler.Tag()] = handler
+		if m.defaultHandler != nil && m.defaultHandler.Tag() == handler.Tag() {
+			m.defaultHandler = handler
+		}
+	}
+	for _, tag := range removed {
+		delete(m.handlers, tag)
+		if m.defaultHandler != nil && m.defaultHandler.Tag() == tag {
+			m.defaultHandler = nil
+		}
+	}
+	if m.defaultHandler == nil && len(handlers) > 0 {
+		m.defaultHandler = handlers[0]
+	}
+	return nil
+}
+
+func routeTo(tag string, domain string) *router.RoutingRule {
+	return &router.RoutingRule{
+		TargetTag: &router.RoutingRule_Tag{Tag: tag},
+		Domain:    []*routercommon.Domain{{Type: routercommon.Domain_Full, Value: domain}},
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	old := &testHandler{tag: "old"}
+	ohm := &testOutboundManager{
+		handlers:       map[string]outbound.Handler{"old": old, "kept": &testHandler{tag: "kept"}},
+		defaultHandler: old,
+	}
+	r := new(router.Router)
+	common.Must(r.Init(context.TODO(), &router.Config{
+		Rule: []*router.RoutingRule{routeTo("old", "v2fly.org")},
+	}, mocks.NewDNSClient(mockCtl), ohm, nil))
+
+	var created []*testHandler
+	s := &routingServer{
+		router: r,
+		ohm:    ohm,
+		createOutbound: func(config *core.OutboundHandlerConfig) (outbound.Handler, error) {
+			if config.Tag == "broken" {
+				return nil, newError("broken outbound")
+			}
+			h := &testHandler{tag: config.Tag}
+			created = append(created, h)
+			return h, nil
+		},
+	}
+	pickTag := func(domain string) string {
+		ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.TCPDestination(net.DomainAddress(domain), 80)})
+		route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+		if err != nil {
+			return ""
+		}
+		return route.GetOutboundTag()
+	}
+
+	// A connection opened through the old outbound before reload.
+	connection := ohm.GetHandler("old")
+
+	for _, request := range []*ReloadConfigRequest{
+		// Rule routes to an outbound removed by the reload.
+		{
+			Routing:  &router.Config{Rule: []*router.RoutingRule{routeTo("old", "v2fly.org")}},
+			Outbound: []*core.OutboundHandlerConfig{{Tag: "new"}},
+		},
+		{
+			Routing:  &router.Config{Rule: []*router.RoutingRule{routeTo("new", "v2fly.org")}},
+			Outbound: []*core.OutboundHandlerConfig{{Tag: "new"}, {Tag: "broken"}},
+		},
+		{
+			Outbound: []*core.OutboundHandlerConfig{{Tag: "new"}, {Tag: "new"}},
+		},
+		{
+			Routing: &router.Config{Rule: []*router.RoutingRule{{
+				TargetTag: &router.RoutingRule_BalancingTag{BalancingTag: "missing"},
+			}}},
+		},
+	} {
+		if _, err := s.ReloadConfig(context.Background(), request); err == nil {
+			t.Error("expect error reloading invalid config ", request)
+		}
+		if tag := pickTag("v2fly.org"); tag != "old" {
+			t.Error("expect routing unchanged after failed reload, but got ", tag)
+		}
+		if len(ohm.handlers) != 2 || ohm.GetHandler("old") != old {
+			t.Error("expect outbounds unchanged after failed reload, but got ", ohm.handlers)
+		}
+	}
+	for _, h := range created {
+		if !h.closed {
+			t.Error("expect outbound ", h.tag, " of failed reload closed")
+		}
+	}
+	created = nil
+
+	_, err := s.ReloadConfig(context.Background(), &ReloadConfigRequest{
+		Routing: &router.Config{Rule: []*router.RoutingRule{
+			routeTo("new", "v2fly.org"),
+			routeTo("kept", "example.com"),
+		}},
+		Outbound: []*core.OutboundHandlerConfig{{Tag: "new"}, {Tag: "kept"}},
+	})
+	common.Must(err)
+
+	if tag := pickTag("v2fly.org"); tag != "new" {
+		t.Error("expect new connections to v2fly.org routed to 'new', but got ", tag)
+	}
+	if tag := pickTag("example.com"); tag != "kept" {
+		t.Error("expect new connections to example.com routed to 'kept', but got ", tag)
+	}
+	if ohm.GetHandler("old") != nil {
+		t.Error("expect outbound 'old' removed")
+	}
+	if h := ohm.GetDefaultHandler(); h == nil || h.Tag() != "new" {
+		t.Error("expect default outbound 'new', but got ", h)
+	}
+	for _, h := range created {
+		if !h.started || h.closed {
+			t.Error("expect outbound ", h.tag, " started")
+		}
+	}
+	if old.closed {
+		t.Error("expect removed outbound not closed")
+	}
+	connection.Dispatch(context.Background(), nil)
+	if old.dispatched != 1 {
+		t.Error("expect connections of removed outbound to keep working")
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
@@ -56,7 +57,7 @@ func domainToMatcher(domain *routercommon.Domain) (strmatcher.Matcher, error) {
 
 	matcher, err := matcherType.New(domain.Value)
 	if err != nil {
-		return nil, newError("failed to create domain matcher").Base(err)
+		return nil, newError("failed to create ", domain.Type, " domain matcher for ", domain.Value).Base(err)
 	}
 
 	return matcher, nil
@@ -108,6 +109,9 @@ type MultiGeoIPMatcher struct {
 }
 
 func NewMultiGeoIPMatcher(geoips []*routercommon.GeoIP, onSource bool) (*MultiGeoIPMatcher, error) {
+	globalGeoIPContainerAccess.Lock()
+	defer globalGeoIPContainerAccess.Unlock()
+
 	var matchers []*GeoIPMatcher
 	for _, geoip := range geoips {
 		matcher, err := globalGeoIPContainer.Add(geoip)
@@ -323,6 +327,42 @@ func (m *AttributeMatcher) Apply(ctx routing.Context) bool {
 	return m.Match(attributes)
 }
 
+// AttributeRegexpMatcher matches the attributes of sessions against regular
+// expressions by name. Sessions without any of the attributes don't match.
+type AttributeRegexpMatcher struct {
+	patterns map[string]*regexp.Regexp
+}
+
+func NewAttributeRegexpMatcher(patterns map[string]string) (*AttributeRegexpMatcher, error) {
+	m := &AttributeRegexpMatcher{
+		patterns: make(map[string]*regexp.Regexp, len(patterns)),
+	}
+	for name, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, newError("invalid regexp of attribute ", name).Base(err)
+		}
+		m.patterns[name] = r
+	}
+	return m, nil
+}
+
+// Match implements attributes matching.
+func (m *AttributeRegexpMatcher) Match(attrs map[string]string) bool {
+	for name, r := range m.patterns {
+		value, found := attrs[name]
+		if !found || !r.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply implements Condition.
+func (m *AttributeRegexpMatcher) Apply(ctx routing.Context) bool {
+	return m.Match(ctx.GetAttributes())
+}
+
 type UidMatcher struct {
 	uidList map[uint32]bool
 }
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"sync"
+
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"inet.af/netaddr"
@@ -14,13 +16,25 @@ type GeoIPMatcher struct {
 }
 
 func (m *GeoIPMatcher) Init(cidrs []*routercommon.CIDR) error {
-	var builder4, builder6 netaddr.IPSetBuilder
+	nets := make([]*net.IPNet, 0, len(cidrs))
 	for _, cidr := range cidrs {
-		netaddrIP, ok := netaddr.FromStdIP(net.IP(cidr.GetIp()))
-		if !ok {
+		ip := net.IP(cidr.GetIp())
+		if _, ok := netaddr.FromStdIP(ip); !ok {
 			return newError("invalid IP address ", cidr)
 		}
-		ipPrefix := netaddr.IPPrefixFrom(netaddrIP, uint8(cidr.GetPrefix()))
+		bits := len(ip) * 8
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), net.IPv4len*8
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(int(cidr.GetPrefix()), bits)})
+	}
+
+	// Large geo IP lists have lots of adjacent and overlapping CIDRs.
+	var builder4, builder6 netaddr.IPSetBuilder
+	for _, ipNet := range net.MergeIPNets(nets) {
+		netaddrIP, _ := netaddr.FromStdIP(ipNet.IP)
+		ones, _ := ipNet.Mask.Size()
+		ipPrefix := netaddr.IPPrefixFrom(netaddrIP, uint8(ones))
 		switch {
 		case netaddrIP.Is4():
 			builder4.AddPrefix(ipPrefix)
@@ -106,4 +120,19 @@ func (c *GeoIPMatcherContainer) Add(geoip *routercommon.GeoIP) (*GeoIPMatcher, e
 	return m, nil
 }
 
-var globalGeoIPContainer GeoIPMatcherContainer
+// invalidate removes the GeoIPMatchers of the given country code, so that they
+// are built again from new data.
+func (c *GeoIPMatcherContainer) invalidate(countryCode string) {
+	matchers := c.matchers[:0]
+	for _, m := range c.matchers {
+		if m.countryCode != countryCode {
+			matchers = append(matchers, m)
+		}
+	}
+	c.matchers = matchers
+}
+
+var (
+	globalGeoIPContainer       GeoIPMatcherContainer
+	globalGeoIPContainerAccess sync.Mutex
+)
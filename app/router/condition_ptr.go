@@ -0,0 +1,229 @@
+package router
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	ptrLookupTimeout = 2 * time.Second
+	ptrCacheTTL      = 10 * time.Minute
+	ptrCacheSize     = 1024
+)
+
+// ptrRecord is a cached result of a reverse lookup, empty if it failed.
+type ptrRecord struct {
+	names  []string
+	expire time.Time
+}
+
+type rawDNSClient interface {
+	QueryRaw(ctx context.Context, message *buf.Buffer) (*buf.Buffer, error)
+}
+
+type ownLinkVerifier interface {
+	IsOwnLink(ctx context.Context) bool
+}
+
+// PTRMatcher matches the reverse DNS names of destination IPs, only if the
+// destination is an IP address. Results of the lookups, including failures,
+// are cached for a while.
+type PTRMatcher struct {
+	matcher *DomainMatcher
+	lookup  func(ctx context.Context, ip string) ([]string, error)
+	ownLink ownLinkVerifier
+	now     func() time.Time
+
+	access sync.Mutex
+	cache  map[string]ptrRecord
+	group  singleflight.Group
+}
+
+// NewPTRMatcher creates a PTRMatcher looking up names with client. The system
+// resolver is used if client is nil or can't send PTR queries, as is the case
+// for the local DNS client.
+func NewPTRMatcher(matcherType string, domains []*routercommon.Domain, client dns.Client) (*PTRMatcher, error) {
+	matcher, err := NewDomainMatcher(matcherType, domains)
+	if err != nil {
+		return nil, err
+	}
+	m := &PTRMatcher{
+		matcher: matcher,
+		lookup:  net.DefaultResolver.LookupAddr,
+		now:     time.Now,
+		cache:   make(map[string]ptrRecord),
+	}
+	if raw, ok := client.(rawDNSClient); ok {
+		m.lookup = func(ctx context.Context, ip string) ([]string, error) {
+			names, err := queryPTR(ctx, raw, ip)
+			if errors.Cause(err) == common.ErrNoClue {
+				return net.DefaultResolver.LookupAddr(ctx, ip)
+			}
+			return names, err
+		}
+	}
+	if verifier, ok := client.(ownLinkVerifier); ok {
+		m.ownLink = verifier
+	}
+	return m, nil
+}
+
+// queryPTR sends a PTR query for ip through client.
+func queryPTR(ctx context.Context, client rawDNSClient, ip string) ([]string, error) {
+	name, err := reverseName(ip)
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	b := buf.New()
+	packed, err := query.AppendPack(b.Extend(0))
+	if err != nil {
+		b.Release()
+		return nil, newError("failed to pack PTR query").Base(err)
+	}
+	b.Resize(0, int32(len(packed)))
+
+	response, err := client.QueryRaw(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Release()
+
+	var message dnsmessage.Message
+	if err := message.Unpack(response.Bytes()); err != nil {
+		return nil, newError("failed to parse PTR response").Base(err)
+	}
+	if message.RCode != dnsmessage.RCodeSuccess {
+		return nil, dns.RCodeError(message.RCode)
+	}
+	var names []string
+	for _, answer := range message.Answers {
+		if ptr, ok := answer.Body.(*dnsmessage.PTRResource); ok {
+			names = append(names, ptr.PTR.String())
+		}
+	}
+	return names, nil
+}
+
+// reverseName returns the name of the PTR record of ip, in in-addr.arpa or
+// ip6.arpa.
+func reverseName(ip string) (dnsmessage.Name, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return dnsmessage.Name{}, newError("invalid IP ", ip)
+	}
+	var name strings.Builder
+	if v4 := addr.To4(); v4 != nil {
+		for i := len(v4) - 1; i >= 0; i-- {
+			name.WriteString(strconv.Itoa(int(v4[i])))
+			name.WriteByte('.')
+		}
+		name.WriteString("in-addr.arpa.")
+	} else {
+		const hexDigits = "0123456789abcdef"
+		for i := len(addr) - 1; i >= 0; i-- {
+			name.WriteByte(hexDigits[addr[i]&0xf])
+			name.WriteByte('.')
+			name.WriteByte(hexDigits[addr[i]>>4])
+			name.WriteByte('.')
+		}
+		name.WriteString("ip6.arpa.")
+	}
+	return dnsmessage.NewName(name.String())
+}
+
+// names returns the reverse DNS names of ip. Concurrent misses of the same IP
+// share one lookup.
+func (m *PTRMatcher) names(ip net.IP) []string {
+	key := ip.String()
+	m.access.Lock()
+	record, found := m.cache[key]
+	m.access.Unlock()
+	if found && m.now().Before(record.expire) {
+		return record.names
+	}
+
+	result := m.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), ptrLookupTimeout)
+		defer cancel()
+		names, err := m.lookup(ctx, key)
+		if err != nil {
+			newError("failed to look up PTR of ", key).Base(err).AtDebug().WriteToLog()
+			names = nil
+		}
+		for i, name := range names {
+			names[i] = strings.TrimSuffix(strings.ToLower(name), ".")
+		}
+		m.put(key, names)
+		return names, nil
+	})
+	timer := time.NewTimer(ptrLookupTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-result:
+		return r.Val.([]string)
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (m *PTRMatcher) put(key string, names []string) {
+	now := m.now()
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if len(m.cache) >= ptrCacheSize {
+		for k, record := range m.cache {
+			if !now.Before(record.expire) {
+				delete(m.cache, k)
+			}
+		}
+		if len(m.cache) >= ptrCacheSize {
+			m.cache = make(map[string]ptrRecord)
+		}
+	}
+	m.cache[key] = ptrRecord{
+		names:  names,
+		expire: now.Add(ptrCacheTTL),
+	}
+}
+
+// Apply implements Condition.
+func (m *PTRMatcher) Apply(ctx routing.Context) bool {
+	if len(ctx.GetTargetDomain()) > 0 {
+		return false
+	}
+	if m.ownLink != nil && m.ownLink.IsOwnLink(session.ContextWithInbound(context.Background(), &session.Inbound{Tag: ctx.GetInboundTag()})) {
+		// Queries of the DNS client would wait for their own lookups.
+		return false
+	}
+	for _, ip := range ctx.GetTargetIPs() {
+		for _, name := range m.names(ip) {
+			if m.matcher.Match(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/dns/localdns"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestPTRMatcher(t *testing.T) {
+	m, err := NewPTRMatcher("", []*routercommon.Domain{
+		{Type: routercommon.Domain_RootDomain, Value: "amazonaws.com"},
+	}, nil)
+	common.Must(err)
+
+	lookups := make(map[string]int)
+	m.lookup = func(_ context.Context, ip string) ([]string, error) {
+		lookups[ip]++
+		switch ip {
+		case "52.94.236.248":
+			return []string{"EC2-52-94-236-248.compute-1.AmazonAWS.com."}, nil
+		case "1.1.1.1":
+			return []string{"one.one.one.one."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	now := time.Unix(0, 0)
+	m.now = func() time.Time { return now }
+
+	withTarget := func(address net.Address) routing.Context {
+		ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+			Target: net.TCPDestination(address, 443),
+		})
+		return routing_session.AsRoutingContext(ctx)
+	}
+
+	cases := []struct {
+		address net.Address
+		output  bool
+	}{
+		{net.ParseAddress("52.94.236.248"), true},
+		{net.ParseAddress("1.1.1.1"), false},
+		{net.ParseAddress("8.8.8.8"), false},
+		{net.DomainAddress("compute-1.amazonaws.com"), false},
+	}
+	for _, test := range cases {
+		if actual := m.Apply(withTarget(test.address)); actual != test.output {
+			t.Error("for ", test.address, " expected ", test.output, " but got ", actual)
+		}
+	}
+	if _, found := lookups["compute-1.amazonaws.com"]; found {
+		t.Error("expected no lookup for domain destinations")
+	}
+
+	// Results, including failures, are cached until they expire.
+	m.Apply(withTarget(net.ParseAddress("52.94.236.248")))
+	m.Apply(withTarget(net.ParseAddress("8.8.8.8")))
+	if lookups["52.94.236.248"] != 1 || lookups["8.8.8.8"] != 1 {
+		t.Error("expected cached lookups, but got ", lookups)
+	}
+	now = now.Add(ptrCacheTTL)
+	if !m.Apply(withTarget(net.ParseAddress("52.94.236.248"))) || lookups["52.94.236.248"] != 2 {
+		t.Error("expected a new lookup after expiry, but got ", lookups)
+	}
+}
+
+// ptrDNSClient answers PTR queries of 52.94.236.248 and 2606:4700:4700::1111,
+// and only takes queries from links not tagged "dns".
+type ptrDNSClient struct {
+	localdns.LocalClient
+	queries int32
+}
+
+func (c *ptrDNSClient) QueryRaw(_ context.Context, b *buf.Buffer) (*buf.Buffer, error) {
+	atomic.AddInt32(&c.queries, 1)
+	var query dnsmessage.Message
+	common.Must(query.Unpack(b.Bytes()))
+	b.Release()
+
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true},
+		Questions: query.Questions,
+	}
+	var ptr string
+	switch query.Questions[0].Name.String() {
+	case "248.236.94.52.in-addr.arpa.":
+		ptr = "ec2-52-94-236-248.compute-1.amazonaws.com."
+	case "1.1.1.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.7.4.0.0.7.4.6.0.6.2.ip6.arpa.":
+		ptr = "one.one.one.one."
+	default:
+		response.RCode = dnsmessage.RCodeNameError
+	}
+	if ptr != "" {
+		response.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{
+				Name:  query.Questions[0].Name,
+				Type:  dnsmessage.TypePTR,
+				Class: dnsmessage.ClassINET,
+				TTL:   300,
+			},
+			Body: &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(ptr)},
+		}}
+	}
+	packed, err := response.Pack()
+	common.Must(err)
+	return buf.FromBytes(packed), nil
+}
+
+func (c *ptrDNSClient) IsOwnLink(ctx context.Context) bool {
+	inbound := session.InboundFromContext(ctx)
+	return inbound != nil && inbound.Tag == "dns"
+}
+
+func TestPTRMatcherWithDNSClient(t *testing.T) {
+	client := new(ptrDNSClient)
+	m, err := NewPTRMatcher("", []*routercommon.Domain{
+		{Type: routercommon.Domain_RootDomain, Value: "amazonaws.com"},
+		{Type: routercommon.Domain_Full, Value: "one.one.one.one"},
+	}, client)
+	common.Must(err)
+
+	withTarget := func(address net.Address, inboundTag string) routing.Context {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: inboundTag})
+		ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+			Target: net.TCPDestination(address, 443),
+		})
+		return routing_session.AsRoutingContext(ctx)
+	}
+
+	cases := []struct {
+		address    net.Address
+		inboundTag string
+		output     bool
+	}{
+		{net.ParseAddress("52.94.236.248"), "", true},
+		{net.ParseAddress("2606:4700:4700::1111"), "", true},
+		{net.ParseAddress("8.8.8.8"), "", false},
+		{net.ParseAddress("52.94.236.249"), "dns", false},
+	}
+	for _, test := range cases {
+		if actual := m.Apply(withTarget(test.address, test.inboundTag)); actual != test.output {
+			t.Error("for ", test.address, " expected ", test.output, " but got ", actual)
+		}
+	}
+	if n := atomic.LoadInt32(&client.queries); n != 3 {
+		t.Error("expected 3 queries and none for links of the DNS client, but got ", n)
+	}
+}
+
+func TestPTRMatcherSharesLookups(t *testing.T) {
+	m, err := NewPTRMatcher("", []*routercommon.Domain{
+		{Type: routercommon.Domain_RootDomain, Value: "amazonaws.com"},
+	}, nil)
+	common.Must(err)
+
+	var lookups int32
+	release := make(chan struct{})
+	m.lookup = func(_ context.Context, ip string) ([]string, error) {
+		atomic.AddInt32(&lookups, 1)
+		<-release
+		return []string{"ec2-52-94-236-248.compute-1.amazonaws.com."}, nil
+	}
+
+	ctx := routing_session.AsRoutingContext(session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.ParseAddress("52.94.236.248"), 443),
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !m.Apply(ctx) {
+				t.Error("expected a match")
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 100)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Error("expected concurrent misses to share one lookup, but got ", n)
+	}
+}
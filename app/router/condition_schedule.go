@@ -0,0 +1,143 @@
+package router
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+// timeRange is a range of time of day in minutes. It crosses midnight if to is
+// not greater than from.
+type timeRange struct {
+	from int
+	to   int
+}
+
+type ScheduleMatcher struct {
+	ranges   []timeRange
+	weekdays [7]bool
+	location *time.Location
+	now      func() time.Time
+}
+
+func NewScheduleMatcher(schedule *TimeSchedule) (*ScheduleMatcher, error) {
+	if len(schedule.TimeRange) == 0 && len(schedule.Weekday) == 0 {
+		return nil, newError("empty schedule")
+	}
+
+	m := &ScheduleMatcher{
+		location: time.Local,
+		now:      time.Now,
+	}
+	if schedule.Timezone != "" {
+		location, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, newError("unknown time zone: ", schedule.Timezone).Base(err)
+		}
+		m.location = location
+	}
+
+	for _, s := range schedule.TimeRange {
+		r, err := parseTimeRange(s)
+		if err != nil {
+			return nil, err
+		}
+		m.ranges = append(m.ranges, r)
+	}
+
+	if len(schedule.Weekday) == 0 {
+		for i := range m.weekdays {
+			m.weekdays[i] = true
+		}
+	}
+	for _, s := range schedule.Weekday {
+		weekday, err := parseWeekday(s)
+		if err != nil {
+			return nil, err
+		}
+		m.weekdays[weekday] = true
+	}
+
+	return m, nil
+}
+
+func parseTimeRange(s string) (timeRange, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return timeRange{}, newError("invalid time range: ", s)
+	}
+	from, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return timeRange{}, newError("invalid time range: ", s).Base(err)
+	}
+	to, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return timeRange{}, newError("invalid time range: ", s).Base(err)
+	}
+	if from == to || (from == 0 && to == 24*60) {
+		return timeRange{}, newError("time range of a whole day or nothing: ", s, ", use weekdays only instead")
+	}
+	return timeRange{from: from, to: to % (24 * 60)}, nil
+}
+
+// parseTimeOfDay parses time of day in HH:MM format into minutes. 24:00 is the
+// end of a day.
+func parseTimeOfDay(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, newError("invalid time of day: ", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, newError("invalid hour: ", s).Base(err)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, newError("invalid minute: ", s).Base(err)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, newError("time of day out of range: ", s)
+	}
+	return hour*60 + minute, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.TrimSpace(s)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := weekday.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return weekday, nil
+		}
+	}
+	return 0, newError("invalid weekday: ", s)
+}
+
+// Apply implements Condition.
+func (m *ScheduleMatcher) Apply(ctx routing.Context) bool {
+	now := m.now().In(m.location)
+	weekday := now.Weekday()
+	if len(m.ranges) == 0 {
+		return m.weekdays[weekday]
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	yesterday := (weekday + 6) % 7
+	for _, r := range m.ranges {
+		if r.from < r.to {
+			if minute >= r.from && minute < r.to && m.weekdays[weekday] {
+				return true
+			}
+			continue
+		}
+		// The part after midnight belongs to the range started yesterday.
+		if minute >= r.from && m.weekdays[weekday] {
+			return true
+		}
+		if minute < r.to && m.weekdays[yesterday] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+func TestScheduleMatcher(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	common.Must(err)
+
+	cases := []struct {
+		schedule *TimeSchedule
+		now      time.Time
+		output   bool
+	}{
+		// 2022-06-06 is a Monday.
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 9, 0, 0, 0, time.UTC), true},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 17, 59, 0, 0, time.UTC), true},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 18, 0, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 8, 59, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Weekday: []string{"Sat", "sunday"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 10, 0, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Weekday: []string{"Sat", "sunday"}, Timezone: "UTC"}, time.Date(2022, 6, 5, 10, 0, 0, 0, time.UTC), true},
+		{&TimeSchedule{TimeRange: []string{"09:00-24:00"}, Timezone: "UTC"}, time.Date(2022, 6, 6, 23, 59, 0, 0, time.UTC), true},
+
+		// Crossing midnight, the part after midnight belongs to the day before.
+		{&TimeSchedule{TimeRange: []string{"22:00-02:00"}, Weekday: []string{"Fri"}, Timezone: "UTC"}, time.Date(2022, 6, 10, 23, 0, 0, 0, time.UTC), true},
+		{&TimeSchedule{TimeRange: []string{"22:00-02:00"}, Weekday: []string{"Fri"}, Timezone: "UTC"}, time.Date(2022, 6, 11, 1, 59, 0, 0, time.UTC), true},
+		{&TimeSchedule{TimeRange: []string{"22:00-02:00"}, Weekday: []string{"Fri"}, Timezone: "UTC"}, time.Date(2022, 6, 11, 2, 0, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"22:00-02:00"}, Weekday: []string{"Fri"}, Timezone: "UTC"}, time.Date(2022, 6, 10, 1, 0, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"22:00-02:00"}, Weekday: []string{"Fri"}, Timezone: "UTC"}, time.Date(2022, 6, 11, 23, 0, 0, 0, time.UTC), false},
+
+		// Time zones.
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "Asia/Shanghai"}, time.Date(2022, 6, 6, 9, 30, 0, 0, shanghai), true},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "Asia/Shanghai"}, time.Date(2022, 6, 6, 10, 0, 0, 0, time.UTC), false},
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "Asia/Shanghai"}, time.Date(2022, 6, 6, 1, 30, 0, 0, time.UTC), true},
+		{&TimeSchedule{Weekday: []string{"Mon"}, Timezone: "Asia/Shanghai"}, time.Date(2022, 6, 5, 20, 0, 0, 0, time.UTC), true},
+		{&TimeSchedule{Weekday: []string{"Mon"}, Timezone: "UTC"}, time.Date(2022, 6, 5, 20, 0, 0, 0, time.UTC), false},
+	}
+	for _, c := range cases {
+		matcher, err := NewScheduleMatcher(c.schedule)
+		common.Must(err)
+		now := c.now
+		matcher.now = func() time.Time { return now }
+		if actual := matcher.Apply(nil); actual != c.output {
+			t.Error(c.schedule.TimeRange, c.schedule.Weekday, " in ", c.schedule.Timezone, " at ", c.now, ": expected ", c.output, ", but got ", actual)
+		}
+	}
+}
+
+func TestScheduleMatcherInvalid(t *testing.T) {
+	cases := []struct {
+		schedule *TimeSchedule
+		err      string
+	}{
+		{&TimeSchedule{TimeRange: []string{"09:00-18:00"}, Timezone: "Mars/Olympus_Mons"}, "unknown time zone"},
+		{&TimeSchedule{TimeRange: []string{"09:00"}}, "invalid time range"},
+		{&TimeSchedule{TimeRange: []string{"09:00-25:00"}}, "invalid time range"},
+		{&TimeSchedule{TimeRange: []string{"09:00-09:00"}}, "whole day"},
+		{&TimeSchedule{Weekday: []string{"Someday"}}, "invalid weekday"},
+		{&TimeSchedule{}, "empty schedule"},
+	}
+	for _, c := range cases {
+		if _, err := NewScheduleMatcher(c.schedule); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Error("expected error ", c.err, ", but got ", err)
+		}
+	}
+}
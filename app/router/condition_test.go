@@ -15,10 +15,14 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/platform/filesystem"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/bittorrent"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/dns"
 	"github.com/v2fly/v2ray-core/v5/common/protocol/http"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/tls"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -336,6 +340,36 @@ func TestRoutingRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			rule: &router.RoutingRule{
+				AttributeRegexp: map[string]string{
+					"user-agent": "^curl/",
+					":method":    "^(GET|HEAD)$",
+				},
+			},
+			test: []ruleTest{
+				{
+					input:  withContent(&session.Content{Attributes: map[string]string{"user-agent": "curl/8.0.1", ":method": "GET"}}),
+					output: true,
+				},
+				{
+					input:  withContent(&session.Content{Attributes: map[string]string{"user-agent": "Mozilla/5.0", ":method": "GET"}}),
+					output: false,
+				},
+				{
+					input:  withContent(&session.Content{Attributes: map[string]string{"user-agent": "curl/8.0.1"}}),
+					output: false,
+				},
+				{
+					input:  withContent(&session.Content{Protocol: "tls"}),
+					output: false,
+				},
+				{
+					input:  withBackground(),
+					output: false,
+				},
+			},
+		},
 	}
 
 	for _, test := range cases {
@@ -351,6 +385,123 @@ func TestRoutingRule(t *testing.T) {
 	}
 }
 
+func TestProtocolMatcher(t *testing.T) {
+	cond, err := (&router.RoutingRule{Protocol: []string{"bittorrent", "tls"}}).BuildCondition()
+	common.Must(err)
+
+	cases := []struct {
+		input  routing.Context
+		output bool
+	}{
+		{
+			input:  withContent(&session.Content{Protocol: (&bittorrent.SniffHeader{}).Protocol()}),
+			output: true,
+		},
+		{
+			input:  withContent(&session.Content{Protocol: (&tls.SniffHeader{}).Protocol()}),
+			output: true,
+		},
+		{
+			input:  withContent(&session.Content{Protocol: (&http.SniffHeader{}).Protocol()}),
+			output: false,
+		},
+		{
+			input:  withContent(&session.Content{Protocol: (&dns.SniffHeader{}).Protocol()}),
+			output: false,
+		},
+		{
+			// Nothing is sniffed.
+			input:  withContent(&session.Content{}),
+			output: false,
+		},
+		{
+			input:  withBackground(),
+			output: false,
+		},
+	}
+	for _, c := range cases {
+		if actual := cond.Apply(c.input); actual != c.output {
+			t.Error("protocol ", c.input.GetProtocol(), ": expected ", c.output, ", but got ", actual)
+		}
+	}
+}
+
+func TestRegexDomainMatcher(t *testing.T) {
+	for _, matcherType := range []string{"mph", "linear"} {
+		matcher, err := router.NewDomainMatcher(matcherType, []*routercommon.Domain{
+			{Type: routercommon.Domain_Regex, Value: "^www\\.v2fly\\.org$"},
+			{Type: routercommon.Domain_Regex, Value: "ads[0-9]+"},
+		})
+		common.Must(err)
+
+		cases := []struct {
+			domain string
+			output bool
+		}{
+			{"www.v2fly.org", true},
+			{"www.v2fly.org.cn", false},
+			{"cdn.www.v2fly.org", false},
+			{"ads1.example.com", true},
+			{"example.com.ads42", true},
+			{"ads.example.com", false},
+		}
+		for _, c := range cases {
+			ctx := withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress(c.domain), 80)})
+			if actual := matcher.Apply(ctx); actual != c.output {
+				t.Error(matcherType, " matcher on ", c.domain, ": expected ", c.output, ", but got ", actual)
+			}
+		}
+	}
+
+	_, err := router.NewDomainMatcher("mph", []*routercommon.Domain{
+		{Type: routercommon.Domain_Regex, Value: "v2fly.(org"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "v2fly.(org") {
+		t.Error("expected error for invalid pattern, but got ", err)
+	}
+}
+
+func TestSourcePortMatcher(t *testing.T) {
+	portList := &cfgcommon.PortList{}
+	common.Must(portList.UnmarshalText("53,1000-2000,8080"))
+	cond, err := (&router.RoutingRule{SourcePortList: portList.Build()}).BuildCondition()
+	common.Must(err)
+
+	cases := []struct {
+		input  routing.Context
+		output bool
+	}{
+		{
+			input:  withInbound(&session.Inbound{Source: net.UDPDestination(net.LocalHostIP, 53)}),
+			output: true,
+		},
+		{
+			input:  withInbound(&session.Inbound{Source: net.TCPDestination(net.LocalHostIP, 1500)}),
+			output: true,
+		},
+		{
+			input:  withInbound(&session.Inbound{Source: net.TCPDestination(net.LocalHostIP, 8080)}),
+			output: true,
+		},
+		{
+			input:  withInbound(&session.Inbound{Source: net.TCPDestination(net.LocalHostIP, 443)}),
+			output: false,
+		},
+		{
+			input: &routing_session.Context{
+				Inbound:  &session.Inbound{Source: net.TCPDestination(net.LocalHostIP, 443)},
+				Outbound: &session.Outbound{Target: net.TCPDestination(net.LocalHostIP, 53)},
+			},
+			output: false,
+		},
+	}
+	for _, c := range cases {
+		if actual := cond.Apply(c.input); actual != c.output {
+			t.Error("source port ", c.input.GetSourcePort(), ": expected ", c.output, ", but got ", actual)
+		}
+	}
+}
+
 func loadGeoSite(country string) ([]*routercommon.Domain, error) {
 	geositeBytes, err := filesystem.ReadAsset("geosite.dat")
 	if err != nil {
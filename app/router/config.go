@@ -11,6 +11,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
@@ -35,6 +36,12 @@ func (r *Rule) Apply(ctx routing.Context) bool {
 }
 
 func (rr *RoutingRule) BuildCondition() (Condition, error) {
+	return rr.buildCondition(nil)
+}
+
+// buildCondition builds the condition of the rule, looking up PTR names with
+// client.
+func (rr *RoutingRule) buildCondition(client dns.Client) (Condition, error) {
 	conds := NewConditionChan()
 
 	if len(rr.Domain) > 0 {
@@ -121,6 +128,14 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(cond)
 	}
 
+	if len(rr.AttributeRegexp) > 0 {
+		cond, err := NewAttributeRegexpMatcher(rr.AttributeRegexp)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(cond)
+	}
+
 	if rr.UidList != nil && len(rr.UidList.Uid) > 0 {
 		conds.Add(NewUidMatcher(rr.UidList))
 	}
@@ -129,6 +144,22 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(NewWifiSSIDMatcher(rr.WifiSsidList))
 	}
 
+	if len(rr.PtrDomain) > 0 {
+		cond, err := NewPTRMatcher(rr.DomainMatcher, rr.PtrDomain, client)
+		if err != nil {
+			return nil, newError("failed to build PTR condition").Base(err)
+		}
+		conds.Add(cond)
+	}
+
+	if rr.Schedule != nil {
+		cond, err := NewScheduleMatcher(rr.Schedule)
+		if err != nil {
+			return nil, newError("failed to build schedule condition").Base(err)
+		}
+		conds.Add(cond)
+	}
+
 	if conds.Len() == 0 {
 		return nil, newError("this rule has no effective fields").AtWarning()
 	}
@@ -168,6 +199,20 @@ func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatch
 			ohm:       ohm, fallbackTag: br.FallbackTag,
 			strategy: leastLoadStrategy,
 		}, nil
+	case "weighted":
+		i, err := serial.GetInstanceOf(br.StrategySettings)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyWeightedConfig)
+		if !ok {
+			return nil, newError("not a StrategyWeightedConfig").AtError()
+		}
+		return &Balancer{
+			selectors: br.OutboundSelector,
+			ohm:       ohm, fallbackTag: br.FallbackTag,
+			strategy: &WeightedStrategy{config: s},
+		}, nil
 	case "random":
 		fallthrough
 	case "":
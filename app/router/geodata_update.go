@@ -0,0 +1,165 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/platform"
+	"github.com/v2fly/v2ray-core/v5/common/signal/done"
+)
+
+// geoDataUpdater downloads a geo data file periodically, and reloads the rules
+// using it once the file is verified and replaced.
+type geoDataUpdater struct {
+	config   *GeoDataUpdate
+	onUpdate func(file string) error
+	done     *done.Instance
+}
+
+func newGeoDataUpdater(config *GeoDataUpdate, onUpdate func(file string) error) (*geoDataUpdater, error) {
+	if config.File == "" {
+		return nil, newError("empty geo data file to update")
+	}
+	if config.Url == "" || config.ChecksumUrl == "" {
+		return nil, newError("both URL and checksum URL are required to update ", config.File)
+	}
+	if config.Interval <= 0 {
+		return nil, newError("invalid update interval of ", config.File, ": ", config.Interval)
+	}
+	return &geoDataUpdater{
+		config:   config,
+		onUpdate: onUpdate,
+		done:     done.New(),
+	}, nil
+}
+
+// checkGeoDataReloadable rejects geo IPs loaded without a code, such as the
+// geoip: rules of the v4 JSON format, as the updaters can't reload them.
+func checkGeoDataReloadable(rules []*RoutingRule) error {
+	for _, rule := range rules {
+		for _, geoips := range [][]*routercommon.GeoIP{rule.Geoip, rule.SourceGeoip} {
+			for _, geo := range geoips {
+				if geo.Code == "" && geo.CountryCode != "" {
+					return newError("geo IP ", geo.CountryCode, " has no code to reload it with after updates")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (u *geoDataUpdater) start() {
+	go u.run()
+}
+
+func (u *geoDataUpdater) close() {
+	u.done.Close()
+}
+
+func (u *geoDataUpdater) run() {
+	ticker := time.NewTicker(time.Duration(u.config.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.done.Wait():
+			return
+		case <-ticker.C:
+		}
+		if err := u.update(); err != nil {
+			newError("failed to update ", u.config.File, ", keeping the current one").Base(err).AtWarning().WriteToLog()
+		}
+	}
+}
+
+// update replaces the geo data file if a new version is available. The
+// current file is kept if the new one can't be downloaded, verified or
+// loaded.
+func (u *geoDataUpdater) update() error {
+	checksum, err := u.fetchChecksum()
+	if err != nil {
+		return err
+	}
+
+	location := platform.GetAssetLocation(u.config.File)
+	current, err := os.ReadFile(location)
+	if err == nil {
+		if hash := sha256.Sum256(current); bytes.Equal(hash[:], checksum) {
+			newError(u.config.File, " is up to date").AtDebug().WriteToLog()
+			return nil
+		}
+	} else {
+		current = nil
+	}
+
+	data, err := common.FetchHTTPContent(u.config.Url)
+	if err != nil {
+		return newError("failed to download ", u.config.Url).Base(err)
+	}
+	if hash := sha256.Sum256(data); !bytes.Equal(hash[:], checksum) {
+		return newError("checksum mismatch of ", u.config.Url, ": ", hex.EncodeToString(hash[:]))
+	}
+
+	if err := replaceFile(location, data); err != nil {
+		return err
+	}
+	if err := u.onUpdate(u.config.File); err != nil {
+		if current != nil {
+			if err := replaceFile(location, current); err != nil {
+				newError("failed to restore ", location).Base(err).AtError().WriteToLog()
+			}
+		}
+		return newError("failed to reload rules").Base(err)
+	}
+
+	newError(u.config.File, " is updated").AtInfo().WriteToLog()
+	return nil
+}
+
+func (u *geoDataUpdater) fetchChecksum() ([]byte, error) {
+	content, err := common.FetchHTTPContent(u.config.ChecksumUrl)
+	if err != nil {
+		return nil, newError("failed to download ", u.config.ChecksumUrl).Base(err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return nil, newError("empty checksum from ", u.config.ChecksumUrl)
+	}
+	checksum, err := hex.DecodeString(fields[0])
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, newError("invalid SHA-256 checksum from ", u.config.ChecksumUrl)
+	}
+	return checksum, nil
+}
+
+// replaceFile writes data into a temporary file and renames it to path, so
+// that the file is never seen partially written.
+func replaceFile(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return newError("failed to create temporary file").Base(err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return newError("failed to write ", file.Name()).Base(err)
+	}
+	if err := file.Close(); err != nil {
+		return newError("failed to write ", file.Name()).Base(err)
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return newError("failed to change file mode of ", file.Name()).Base(err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return newError("failed to replace ", path).Base(err)
+	}
+	return nil
+}
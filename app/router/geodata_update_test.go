@@ -0,0 +1,156 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"google.golang.org/protobuf/proto"
+
+	_ "github.com/v2fly/v2ray-core/v5/infra/conf/geodata/standard"
+)
+
+func marshalGeoIP(code string, ip net.IP, prefix uint32) []byte {
+	data, err := proto.Marshal(&routercommon.GeoIPList{
+		Entry: []*routercommon.GeoIP{{
+			CountryCode: code,
+			Cidr:        []*routercommon.CIDR{{Ip: ip, Prefix: prefix}},
+		}},
+	})
+	common.Must(err)
+	return data
+}
+
+type geoDataServer struct {
+	access   sync.Mutex
+	data     []byte
+	checksum string
+	requests int
+}
+
+func (s *geoDataServer) set(data []byte, checksum []byte) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	s.data = data
+	s.checksum = hex.EncodeToString(checksum) + "  geoip.dat\n"
+}
+
+func (s *geoDataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	switch r.URL.Path {
+	case "/geoip.dat":
+		s.requests++
+		w.Write(s.data)
+	case "/geoip.dat.sha256sum":
+		w.Write([]byte(s.checksum))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGeoDataUpdate(t *testing.T) {
+	assetDir := t.TempDir()
+	t.Setenv("v2ray.location.asset", assetDir)
+
+	oldData := marshalGeoIP("UPDATETEST", net.IP{1, 0, 0, 0}, 8)
+	newData := marshalGeoIP("UPDATETEST", net.IP{2, 0, 0, 0}, 8)
+	common.Must(os.WriteFile(filepath.Join(assetDir, "geoip.dat"), oldData, 0o644))
+
+	server := &geoDataServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	loader, err := newGeoDataLoader()
+	common.Must(err)
+	geoips := []*routercommon.GeoIP{{Code: "UPDATETEST"}}
+	common.Must(loadGeoIP(loader, geoips, ""))
+
+	config := &Config{
+		Rule: []*RoutingRule{{
+			TargetTag: &RoutingRule_Tag{Tag: "geoip"},
+			Geoip:     geoips,
+		}},
+		GeoDataUpdate: []*GeoDataUpdate{{
+			File:        "geoip.dat",
+			Url:         httpServer.URL + "/geoip.dat",
+			ChecksumUrl: httpServer.URL + "/geoip.dat.sha256sum",
+			Interval:    3600,
+		}},
+	}
+	r := new(Router)
+	common.Must(r.Init(context.Background(), config, nil, nil, nil))
+	updater := r.updaters[0]
+
+	matches := func(ip net.IP) bool {
+		ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.TCPDestination(net.IPAddress(ip), 80)})
+		route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+		return err == nil && route.GetOutboundTag() == "geoip"
+	}
+	if !matches(net.IP{1, 1, 1, 1}) || matches(net.IP{2, 2, 2, 2}) {
+		t.Fatal("unexpected initial geoip")
+	}
+
+	// Corrupted downloads are rejected.
+	badChecksum := sha256.Sum256([]byte("other"))
+	server.set(newData, badChecksum[:])
+	if err := updater.update(); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+	if !matches(net.IP{1, 1, 1, 1}) || matches(net.IP{2, 2, 2, 2}) {
+		t.Error("geoip is changed by a failed update")
+	}
+
+	checksum := sha256.Sum256(newData)
+	server.set(newData, checksum[:])
+	common.Must(updater.update())
+	if matches(net.IP{1, 1, 1, 1}) || !matches(net.IP{2, 2, 2, 2}) {
+		t.Error("geoip is not updated")
+	}
+	if content, err := os.ReadFile(filepath.Join(assetDir, "geoip.dat")); err != nil || string(content) != string(newData) {
+		t.Error("geoip.dat is not replaced")
+	}
+
+	// Nothing is downloaded if the file is up to date.
+	requests := server.requests
+	common.Must(updater.update())
+	if server.requests != requests {
+		t.Error("unexpected download of up to date file")
+	}
+}
+
+func TestGeoDataUpdateWithoutCode(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{{
+			TargetTag: &RoutingRule_Tag{Tag: "geoip"},
+			// Loaded when the config was built, as the geoip: rules of v4.
+			Geoip: []*routercommon.GeoIP{{
+				CountryCode: "UPDATETEST",
+				Cidr:        []*routercommon.CIDR{{Ip: []byte{1, 0, 0, 0}, Prefix: 8}},
+			}},
+		}},
+		GeoDataUpdate: []*GeoDataUpdate{{
+			File:        "geoip.dat",
+			Url:         "https://example.com/geoip.dat",
+			ChecksumUrl: "https://example.com/geoip.dat.sha256sum",
+			Interval:    3600,
+		}},
+	}
+	if err := new(Router).Init(context.Background(), config, nil, nil, nil); err == nil {
+		t.Error("expected error for geo IPs without a code")
+	}
+
+	config.GeoDataUpdate = nil
+	common.Must(new(Router).Init(context.Background(), config, nil, nil, nil))
+}
@@ -4,8 +4,10 @@ package router
 
 import (
 	"context"
+	"sync"
 
 	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/platform"
@@ -15,14 +17,24 @@ import (
 	routing_dns "github.com/v2fly/v2ray-core/v5/features/routing/dns"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/geodata"
+	"google.golang.org/protobuf/proto"
 )
 
 // Router is an implementation of routing.Router.
 type Router struct {
+	access         sync.RWMutex
 	domainStrategy DomainStrategy
 	rules          []*Rule
 	balancers      map[string]*Balancer
 	dns            dns.Client
+	updaters       []*geoDataUpdater
+
+	reloadAccess sync.Mutex
+	ruleConfig   []*RoutingRule
+
+	ctx        context.Context
+	ohm        outbound.Manager
+	dispatcher routing.Dispatcher
 }
 
 // Route is an implementation of routing.Route.
@@ -36,22 +48,58 @@ type Route struct {
 func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher) error {
 	r.domainStrategy = config.DomainStrategy
 	r.dns = d
+	r.ruleConfig = config.Rule
+	r.ctx = ctx
+	r.ohm = ohm
+	r.dispatcher = dispatcher
+
+	balancers, err := r.buildBalancers(config.BalancingRule)
+	if err != nil {
+		return err
+	}
+	r.balancers = balancers
+
+	rules, err := r.buildRules(config.Rule, balancers)
+	if err != nil {
+		return err
+	}
+	r.rules = rules
 
-	r.balancers = make(map[string]*Balancer, len(config.BalancingRule))
-	for _, rule := range config.BalancingRule {
-		balancer, err := rule.Build(ohm, dispatcher)
+	if len(config.GeoDataUpdate) > 0 {
+		if err := checkGeoDataReloadable(config.Rule); err != nil {
+			return err
+		}
+	}
+	for _, update := range config.GeoDataUpdate {
+		updater, err := newGeoDataUpdater(update, r.reloadGeoData)
 		if err != nil {
 			return err
 		}
-		balancer.InjectContext(ctx)
-		r.balancers[rule.Tag] = balancer
+		r.updaters = append(r.updaters, updater)
+	}
+
+	return nil
+}
+
+func (r *Router) buildBalancers(config []*BalancingRule) (map[string]*Balancer, error) {
+	balancers := make(map[string]*Balancer, len(config))
+	for _, rule := range config {
+		balancer, err := rule.Build(r.ohm, r.dispatcher)
+		if err != nil {
+			return nil, err
+		}
+		balancer.InjectContext(r.ctx)
+		balancers[rule.Tag] = balancer
 	}
+	return balancers, nil
+}
 
-	r.rules = make([]*Rule, 0, len(config.Rule))
-	for _, rule := range config.Rule {
-		cond, err := rule.BuildCondition()
+func (r *Router) buildRules(config []*RoutingRule, balancers map[string]*Balancer) ([]*Rule, error) {
+	rules := make([]*Rule, 0, len(config))
+	for _, rule := range config {
+		cond, err := rule.buildCondition(r.dns)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		rr := &Rule{
 			Condition: cond,
@@ -59,18 +107,127 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 		}
 		btag := rule.GetBalancingTag()
 		if len(btag) > 0 {
-			brule, found := r.balancers[btag]
+			brule, found := balancers[btag]
 			if !found {
-				return newError("balancer ", btag, " not found")
+				return nil, newError("balancer ", btag, " not found")
 			}
 			rr.Balancer = brule
 		}
-		r.rules = append(r.rules, rr)
+		rules = append(rules, rr)
+	}
+	return rules, nil
+}
+
+// reloadGeoData rebuilds the rules with geo data loaded from the updated file.
+// The current rules are kept if any of them fails to build.
+func (r *Router) reloadGeoData(file string) error {
+	r.reloadAccess.Lock()
+	defer r.reloadAccess.Unlock()
+
+	loader, err := newGeoDataLoader()
+	if err != nil {
+		return err
+	}
+
+	config := make([]*RoutingRule, 0, len(r.ruleConfig))
+	for _, rule := range r.ruleConfig {
+		rule := proto.Clone(rule).(*RoutingRule)
+		for _, geoips := range [][]*routercommon.GeoIP{rule.Geoip, rule.SourceGeoip} {
+			if err := loadGeoIP(loader, geoips, file); err != nil {
+				return err
+			}
+		}
+		if err := loadGeoSite(loader, rule.GeoDomain, file); err != nil {
+			return err
+		}
+		config = append(config, rule)
+	}
+
+	globalGeoIPContainerAccess.Lock()
+	for _, rule := range config {
+		for _, geoips := range [][]*routercommon.GeoIP{rule.Geoip, rule.SourceGeoip} {
+			for _, geo := range geoips {
+				if geo.Code != "" && geo.CountryCode != "" && geoFilePath(geo.FilePath, "geoip.dat") == file {
+					globalGeoIPContainer.invalidate(geo.CountryCode)
+				}
+			}
+		}
+	}
+	globalGeoIPContainerAccess.Unlock()
+
+	rules, err := r.buildRules(config, r.getBalancers())
+	if err != nil {
+		return err
 	}
 
+	r.access.Lock()
+	r.rules = rules
+	r.access.Unlock()
+	r.ruleConfig = config
 	return nil
 }
 
+// PrepareReload builds config into routing that replaces the current rules,
+// balancers and domain strategy when the returned function is called. Nothing
+// is changed if config fails to build. Overrides of balancers are kept for the
+// balancers with the same tags.
+func (r *Router) PrepareReload(config *Config) (func(), error) {
+	balancers, err := r.buildBalancers(config.BalancingRule)
+	if err != nil {
+		return nil, newError("failed to build balancers").Base(err)
+	}
+	rules, err := r.buildRules(config.Rule, balancers)
+	if err != nil {
+		return nil, newError("failed to build rules").Base(err)
+	}
+	return func() {
+		r.reloadAccess.Lock()
+		defer r.reloadAccess.Unlock()
+
+		r.access.Lock()
+		for tag, balancer := range balancers {
+			if old, found := r.balancers[tag]; found {
+				balancer.override.Put(old.override.Get())
+			}
+		}
+		r.domainStrategy = config.DomainStrategy
+		r.balancers = balancers
+		r.rules = rules
+		r.access.Unlock()
+		r.ruleConfig = config.Rule
+	}, nil
+}
+
+// Reload replaces the current routing with config. The current routing is kept
+// if config fails to build.
+func (r *Router) Reload(config *Config) error {
+	apply, err := r.PrepareReload(config)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+func (r *Router) getRules() (DomainStrategy, []*Rule) {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return r.domainStrategy, r.rules
+}
+
+func (r *Router) getBalancers() map[string]*Balancer {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	return r.balancers
+}
+
+func (r *Router) getBalancer(tag string) (*Balancer, bool) {
+	r.access.RLock()
+	defer r.access.RUnlock()
+	b, found := r.balancers[tag]
+	return b, found
+}
+
 // PickRoute implements routing.Router.
 func (r *Router) PickRoute(ctx routing.Context) (routing.Route, error) {
 	rule, ctx, err := r.pickRouteInternal(ctx)
@@ -90,24 +247,25 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 	// this prevents cycle resolving dead loop
 	skipDNSResolve := ctx.GetSkipDNSResolve()
 
-	if r.domainStrategy == DomainStrategy_IpOnDemand && !skipDNSResolve {
+	domainStrategy, rules := r.getRules()
+	if domainStrategy == DomainStrategy_IpOnDemand && !skipDNSResolve {
 		ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 	}
 
-	for _, rule := range r.rules {
+	for _, rule := range rules {
 		if rule.Apply(ctx) {
 			return rule, ctx, nil
 		}
 	}
 
-	if r.domainStrategy != DomainStrategy_IpIfNonMatch || len(ctx.GetTargetDomain()) == 0 || skipDNSResolve {
+	if domainStrategy != DomainStrategy_IpIfNonMatch || len(ctx.GetTargetDomain()) == 0 || skipDNSResolve {
 		return nil, ctx, common.ErrNoClue
 	}
 
 	ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 
 	// Try applying rules again if we have IPs.
-	for _, rule := range r.rules {
+	for _, rule := range rules {
 		if rule.Apply(ctx) {
 			return rule, ctx, nil
 		}
@@ -118,15 +276,24 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 
 // Start implements common.Runnable.
 func (r *Router) Start() error {
+	for _, updater := range r.updaters {
+		updater.start()
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (r *Router) Close() error {
+	for _, updater := range r.updaters {
+		updater.close()
+	}
+
 	// TODO: fix router leak
+	r.access.Lock()
 	r.balancers = nil
 	r.dns = nil
 	r.rules = nil
+	r.access.Unlock()
 	return nil
 }
 
@@ -145,6 +312,68 @@ func (r *Route) GetOutboundTag() string {
 	return r.outboundTag
 }
 
+func newGeoDataLoader() (geodata.Loader, error) {
+	geoloadername := platform.NewEnvFlag("v2ray.conf.geoloader").GetValue(func() string {
+		return "standard"
+	})
+
+	loader, err := geodata.GetGeoDataLoader(geoloadername)
+	if err != nil {
+		return nil, newError("unable to create geo data loader ").Base(err)
+	}
+	return loader, nil
+}
+
+func geoFilePath(filePath, defaultPath string) string {
+	if filePath != "" {
+		return filePath
+	}
+	return defaultPath
+}
+
+// loadGeoIP loads the CIDRs of geo IPs with a code. If file is not empty, only
+// geo IPs in that file are loaded.
+func loadGeoIP(loader geodata.Loader, geoips []*routercommon.GeoIP, file string) error {
+	for _, geo := range geoips {
+		if geo.Code == "" {
+			continue
+		}
+		filepath := geoFilePath(geo.FilePath, "geoip.dat")
+		if file != "" && filepath != file {
+			continue
+		}
+		if geo.FilePath == "" {
+			geo.CountryCode = geo.Code
+		}
+		var err error
+		geo.Cidr, err = loader.LoadIP(filepath, geo.Code)
+		if err != nil {
+			return newError("unable to load geoip").Base(err)
+		}
+	}
+	return nil
+}
+
+// loadGeoSite loads the domains of geo sites with a code. If file is not
+// empty, only geo sites in that file are loaded.
+func loadGeoSite(loader geodata.Loader, geosites []*routercommon.GeoSite, file string) error {
+	for _, geo := range geosites {
+		if geo.Code == "" {
+			continue
+		}
+		filepath := geoFilePath(geo.FilePath, "geosite.dat")
+		if file != "" && filepath != file {
+			continue
+		}
+		var err error
+		geo.Domain, err = loader.LoadGeoSiteWithAttr(filepath, geo.Code)
+		if err != nil {
+			return newError("unable to load geodomain").Base(err)
+		}
+	}
+	return nil
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		r := new(Router)
@@ -159,18 +388,11 @@ func init() {
 	common.Must(common.RegisterConfig((*SimplifiedConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		ctx = cfgcommon.NewConfigureLoadingContext(ctx)
 
-		geoloadername := platform.NewEnvFlag("v2ray.conf.geoloader").GetValue(func() string {
-			return "standard"
-		})
-
-		if loader, err := geodata.GetGeoDataLoader(geoloadername); err == nil {
-			cfgcommon.SetGeoDataLoader(ctx, loader)
-		} else {
-			return nil, newError("unable to create geo data loader ").Base(err)
+		geoLoader, err := newGeoDataLoader()
+		if err != nil {
+			return nil, err
 		}
-
-		cfgEnv := cfgcommon.GetConfigureLoadingEnvironment(ctx)
-		geoLoader := cfgEnv.GetGeoLoader()
+		cfgcommon.SetGeoDataLoader(ctx, geoLoader)
 
 		simplifiedConfig := config.(*SimplifiedConfig)
 
@@ -179,52 +401,18 @@ func init() {
 		for _, v := range simplifiedConfig.Rule {
 			rule := new(RoutingRule)
 
-			for _, geo := range v.Geoip {
-				if geo.Code != "" {
-					filepath := "geoip.dat"
-					if geo.FilePath != "" {
-						filepath = geo.FilePath
-					} else {
-						geo.CountryCode = geo.Code
-					}
-					var err error
-					geo.Cidr, err = geoLoader.LoadIP(filepath, geo.Code)
-					if err != nil {
-						return nil, newError("unable to load geoip").Base(err)
-					}
-				}
+			if err := loadGeoIP(geoLoader, v.Geoip, ""); err != nil {
+				return nil, err
 			}
 			rule.Geoip = v.Geoip
 
-			for _, geo := range v.SourceGeoip {
-				if geo.Code != "" {
-					filepath := "geoip.dat"
-					if geo.FilePath != "" {
-						filepath = geo.FilePath
-					} else {
-						geo.CountryCode = geo.Code
-					}
-					var err error
-					geo.Cidr, err = geoLoader.LoadIP(filepath, geo.Code)
-					if err != nil {
-						return nil, newError("unable to load geoip").Base(err)
-					}
-				}
+			if err := loadGeoIP(geoLoader, v.SourceGeoip, ""); err != nil {
+				return nil, err
 			}
 			rule.SourceGeoip = v.SourceGeoip
 
-			for _, geo := range v.GeoDomain {
-				if geo.Code != "" {
-					filepath := "geosite.dat"
-					if geo.FilePath != "" {
-						filepath = geo.FilePath
-					}
-					var err error
-					geo.Domain, err = geoLoader.LoadGeoSiteWithAttr(filepath, geo.Code)
-					if err != nil {
-						return nil, newError("unable to load geodomain").Base(err)
-					}
-				}
+			if err := loadGeoSite(geoLoader, v.GeoDomain, ""); err != nil {
+				return nil, err
 			}
 			if v.PortList != "" {
 				portList := &cfgcommon.PortList{}
@@ -249,9 +437,12 @@ func init() {
 			}
 			rule.Protocol = v.Protocol
 			rule.Attributes = v.Attributes
+			rule.AttributeRegexp = v.AttributeRegexp
 			rule.UserEmail = v.UserEmail
 			rule.InboundTag = v.InboundTag
 			rule.DomainMatcher = v.DomainMatcher
+			rule.Schedule = v.Schedule
+			rule.PtrDomain = v.PtrDomain
 			switch s := v.TargetTag.(type) {
 			case *SimplifiedRoutingRule_Tag:
 				rule.TargetTag = &RoutingRule_Tag{s.Tag}
@@ -265,6 +456,7 @@ func init() {
 			DomainStrategy: simplifiedConfig.DomainStrategy,
 			Rule:           routingRules,
 			BalancingRule:  simplifiedConfig.BalancingRule,
+			GeoDataUpdate:  simplifiedConfig.GeoDataUpdate,
 		}
 		return common.CreateObject(ctx, fullConfig)
 	}))
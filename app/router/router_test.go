@@ -246,3 +246,73 @@ func TestIPIfNonMatchIP(t *testing.T) {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
 }
+
+func TestRouterReload(t *testing.T) {
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "old",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+		},
+		BalancingRule: []*BalancingRule{
+			{
+				Tag:              "balance",
+				OutboundSelector: []string{"test-"},
+			},
+		},
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockDNS := mocks.NewDNSClient(mockCtl)
+
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, mockDNS, nil, nil))
+	common.Must(r.SetOverrideTarget("balance", "test-1"))
+
+	ctx := routing_session.AsRoutingContext(session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.TCPDestination(net.DomainAddress("v2fly.org"), 80)}))
+	pickTag := func() string {
+		route, err := r.PickRoute(ctx)
+		common.Must(err)
+		return route.GetOutboundTag()
+	}
+
+	invalid := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_BalancingTag{
+					BalancingTag: "missing",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+		},
+	}
+	if err := r.Reload(invalid); err == nil {
+		t.Error("expect error reloading rules with missing balancer")
+	}
+	if tag := pickTag(); tag != "old" {
+		t.Error("expect tag 'old' after failed reload, but actually ", tag)
+	}
+
+	common.Must(r.Reload(&Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{
+					Tag: "new",
+				},
+				Networks: []net.Network{net.Network_TCP},
+			},
+		},
+		BalancingRule: config.BalancingRule,
+	}))
+	if tag := pickTag(); tag != "new" {
+		t.Error("expect tag 'new' after reload, but actually ", tag)
+	}
+	if target, err := r.GetOverrideTarget("balance"); err != nil || target != "test-1" {
+		t.Error("expect override target kept after reload, but actually ", target, err)
+	}
+}
@@ -5,10 +5,13 @@ package router
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/observatory"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/features"
 	"github.com/v2fly/v2ray-core/v5/features/extension"
 )
@@ -18,6 +21,9 @@ type LeastPingStrategy struct {
 	observatory extension.Observatory
 
 	config *StrategyLeastPingConfig
+
+	access sync.Mutex
+	probed map[string]time.Time
 }
 
 func (l *LeastPingStrategy) GetPrincipleTarget(strings []string) []string {
@@ -45,24 +51,78 @@ func (l *LeastPingStrategy) PickOutbound(strings []string) string {
 		newError("cannot get observe report").Base(err).WriteToLog()
 		return ""
 	}
-	outboundsList := outboundList(strings)
 	if result, ok := observeReport.(*observatory.ObservationResult); ok {
-		status := result.Status
-		leastPing := int64(99999999)
-		selectedOutboundName := ""
-		for _, v := range status {
-			if outboundsList.contains(v.OutboundTag) && v.Alive && v.Delay < leastPing {
-				selectedOutboundName = v.OutboundTag
-				leastPing = v.Delay
-			}
-		}
-		return selectedOutboundName
+		return l.pickOutbound(strings, result.Status)
 	}
 
 	// No way to understand observeReport
 	return ""
 }
 
+// pickOutbound picks a random one among the alive candidates with the least
+// delay. If max age is set, a candidate without a known observation is picked
+// instead if it is not probed within max age.
+func (l *LeastPingStrategy) pickOutbound(candidates []string, status []*observatory.OutboundStatus) string {
+	maxAge := time.Duration(l.config.MaxAge)
+	now := time.Now()
+
+	outboundsList := outboundList(candidates)
+	known := make(map[string]bool, len(candidates))
+	leastPing := int64(99999999)
+	var selected []string
+	for _, v := range status {
+		if !outboundsList.contains(v.OutboundTag) {
+			continue
+		}
+		if maxAge > 0 && v.LastTryTime > 0 && now.Sub(time.Unix(v.LastTryTime, 0)) > maxAge {
+			continue
+		}
+		known[v.OutboundTag] = true
+		if !v.Alive {
+			continue
+		}
+		switch {
+		case v.Delay < leastPing:
+			selected = append(selected[:0], v.OutboundTag)
+			leastPing = v.Delay
+		case v.Delay == leastPing:
+			selected = append(selected, v.OutboundTag)
+		}
+	}
+
+	if maxAge > 0 {
+		if tag := l.pickProbe(candidates, known, now, maxAge); tag != "" {
+			newError("probing outbound ", tag, " without known observation").AtDebug().WriteToLog()
+			return tag
+		}
+	}
+
+	if len(selected) == 0 {
+		return ""
+	}
+	return selected[dice.Roll(len(selected))]
+}
+
+func (l *LeastPingStrategy) pickProbe(candidates []string, known map[string]bool, now time.Time, maxAge time.Duration) string {
+	l.access.Lock()
+	defer l.access.Unlock()
+
+	if l.probed == nil {
+		l.probed = make(map[string]time.Time)
+	}
+	for _, tag := range candidates {
+		if known[tag] {
+			continue
+		}
+		if probed, found := l.probed[tag]; found && now.Sub(probed) < maxAge {
+			continue
+		}
+		l.probed[tag] = now
+		return tag
+	}
+	return ""
+}
+
 type outboundList []string
 
 func (o outboundList) contains(name string) bool {
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+)
+
+type staticObservatory []*observatory.OutboundStatus
+
+func (o staticObservatory) GetObservation(context.Context) (proto.Message, error) {
+	return &observatory.ObservationResult{Status: o}, nil
+}
+
+func (staticObservatory) Type() interface{} {
+	return extension.ObservatoryType()
+}
+
+func (staticObservatory) Start() error {
+	return nil
+}
+
+func (staticObservatory) Close() error {
+	return nil
+}
+
+func TestLeastPingStrategy(t *testing.T) {
+	now := time.Now().Unix()
+	strategy := &LeastPingStrategy{
+		config: &StrategyLeastPingConfig{},
+		observatory: staticObservatory{
+			{OutboundTag: "a", Alive: true, Delay: 300, LastTryTime: now},
+			{OutboundTag: "b", Alive: true, Delay: 100, LastTryTime: now},
+			{OutboundTag: "c", Alive: false, Delay: 99999999, LastTryTime: now},
+			{OutboundTag: "d", Alive: true, Delay: 50, LastTryTime: now},
+		},
+	}
+
+	if tag := strategy.PickOutbound([]string{"a", "b", "c"}); tag != "b" {
+		t.Error("expected b, but got ", tag)
+	}
+	if tag := strategy.PickOutbound([]string{"c"}); tag != "" {
+		t.Error("expected no outbound, but got ", tag)
+	}
+}
+
+func TestLeastPingStrategyTie(t *testing.T) {
+	now := time.Now().Unix()
+	strategy := &LeastPingStrategy{
+		config: &StrategyLeastPingConfig{},
+		observatory: staticObservatory{
+			{OutboundTag: "a", Alive: true, Delay: 100, LastTryTime: now},
+			{OutboundTag: "b", Alive: true, Delay: 100, LastTryTime: now},
+			{OutboundTag: "c", Alive: true, Delay: 200, LastTryTime: now},
+		},
+	}
+
+	picked := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		picked[strategy.PickOutbound([]string{"a", "b", "c"})]++
+	}
+	if picked["a"] == 0 || picked["b"] == 0 || picked["c"] != 0 {
+		t.Error("unexpected picks: ", picked)
+	}
+}
+
+func TestLeastPingStrategyMaxAge(t *testing.T) {
+	now := time.Now()
+	strategy := &LeastPingStrategy{
+		config: &StrategyLeastPingConfig{MaxAge: int64(time.Minute)},
+		observatory: staticObservatory{
+			{OutboundTag: "fresh", Alive: true, Delay: 200, LastTryTime: now.Unix()},
+			{OutboundTag: "stale", Alive: true, Delay: 100, LastTryTime: now.Add(-time.Hour).Unix()},
+		},
+	}
+
+	// Stale and unobserved outbounds are probed once each.
+	probed := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		probed[strategy.PickOutbound([]string{"fresh", "stale", "new"})] = true
+	}
+	if !probed["stale"] || !probed["new"] {
+		t.Error("expected stale and new outbounds to be probed, but got ", probed)
+	}
+	for i := 0; i < 10; i++ {
+		if tag := strategy.PickOutbound([]string{"fresh", "stale", "new"}); tag != "fresh" {
+			t.Fatal("expected fresh, but got ", tag)
+		}
+	}
+}
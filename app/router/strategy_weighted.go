@@ -0,0 +1,60 @@
+//go:build !confonly
+// +build !confonly
+
+package router
+
+import (
+	"math/rand"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+// WeightedStrategy picks outbounds randomly, with chances in proportion to
+// their weights.
+type WeightedStrategy struct {
+	config *StrategyWeightedConfig
+}
+
+func (s *WeightedStrategy) weight(tag string) int64 {
+	weight, found := s.config.Weights[tag]
+	if !found {
+		return 1
+	}
+	if weight < 0 {
+		return 0
+	}
+	return int64(weight)
+}
+
+func (s *WeightedStrategy) GetPrincipleTarget(candidates []string) []string {
+	targets := make([]string, 0, len(candidates))
+	for _, tag := range candidates {
+		if s.weight(tag) > 0 {
+			targets = append(targets, tag)
+		}
+	}
+	return targets
+}
+
+func (s *WeightedStrategy) PickOutbound(candidates []string) string {
+	var total int64
+	for _, tag := range candidates {
+		total += s.weight(tag)
+	}
+	if total == 0 {
+		// goes to fallbackTag
+		return ""
+	}
+	n := rand.Int63n(total)
+	for _, tag := range candidates {
+		n -= s.weight(tag)
+		if n < 0 {
+			return tag
+		}
+	}
+	return ""
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*StrategyWeightedConfig)(nil), nil))
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestWeightedStrategy(t *testing.T) {
+	strategy := &WeightedStrategy{config: &StrategyWeightedConfig{
+		Weights: map[string]int32{"a": 1, "b": 3, "c": 6, "d": 0, "e": -5},
+	}}
+	candidates := []string{"a", "b", "c", "d", "e"}
+
+	const workers, iterations = 8, 20000
+	var access sync.Mutex
+	counts := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			picked := make(map[string]int)
+			for j := 0; j < iterations; j++ {
+				picked[strategy.PickOutbound(candidates)]++
+			}
+			access.Lock()
+			for tag, n := range picked {
+				counts[tag] += n
+			}
+			access.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	total := float64(workers * iterations)
+	for tag, expected := range map[string]float64{"a": 0.1, "b": 0.3, "c": 0.6, "d": 0, "e": 0} {
+		if actual := float64(counts[tag]) / total; math.Abs(actual-expected) > 0.01 {
+			t.Error("expected ", tag, " to be picked at ", expected, ", but actually at ", actual)
+		}
+	}
+}
+
+func TestWeightedStrategyDefaultWeight(t *testing.T) {
+	strategy := &WeightedStrategy{config: &StrategyWeightedConfig{
+		Weights: map[string]int32{"a": 0},
+	}}
+	if tag := strategy.PickOutbound([]string{"a", "b"}); tag != "b" {
+		t.Error("expected b, but got ", tag)
+	}
+	if tag := strategy.PickOutbound([]string{"a"}); tag != "" {
+		t.Error("expected no outbound, but got ", tag)
+	}
+	if targets := strategy.GetPrincipleTarget([]string{"a", "b", "c"}); len(targets) != 2 || targets[0] != "b" || targets[1] != "c" {
+		t.Error("unexpected principle targets: ", targets)
+	}
+}
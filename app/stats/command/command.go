@@ -5,20 +5,23 @@ package command
 import (
 	"context"
 	"runtime"
+	"sort"
 	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/stats"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/strmatcher"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
 	feature_stats "github.com/v2fly/v2ray-core/v5/features/stats"
 	grpc "google.golang.org/grpc"
 )
 
 // statsServer is an implementation of StatsService.
 type statsServer struct {
-	stats     feature_stats.Manager
-	startTime time.Time
+	stats      feature_stats.Manager
+	dispatcher routing.Dispatcher
+	startTime  time.Time
 }
 
 func NewStatsServer(manager feature_stats.Manager) StatsServiceServer {
@@ -31,6 +34,20 @@ func NewStatsServer(manager feature_stats.Manager) StatsServiceServer {
 func (s *statsServer) GetStats(ctx context.Context, request *GetStatsRequest) (*GetStatsResponse, error) {
 	c := s.stats.GetCounter(request.Name)
 	if c == nil {
+		if h := s.stats.GetHistogram(request.Name); h != nil {
+			return &GetStatsResponse{
+				Histogram: newHistogram(request.Name, h.Snapshot(request.Reset_)),
+			}, nil
+		}
+		// Online counters expire on their own, and are not reset.
+		if o := s.stats.GetOnlineCounter(request.Name); o != nil {
+			return &GetStatsResponse{
+				Stat: &Stat{
+					Name:  request.Name,
+					Value: o.Count(),
+				},
+			}, nil
+		}
 		return nil, newError(request.Name, " not found.")
 	}
 	var value int64
@@ -87,9 +104,36 @@ func (s *statsServer) QueryStats(ctx context.Context, request *QueryStatsRequest
 		return true
 	})
 
+	manager.VisitHistograms(func(name string, h feature_stats.Histogram) bool {
+		if mgroup.Size() == 0 || len(mgroup.Match(name)) > 0 {
+			response.Histogram = append(response.Histogram, newHistogram(name, h.Snapshot(request.Reset_)))
+		}
+		return true
+	})
+
+	manager.VisitOnlineCounters(func(name string, o feature_stats.OnlineCounter) bool {
+		if mgroup.Size() == 0 || len(mgroup.Match(name)) > 0 {
+			response.Stat = append(response.Stat, &Stat{
+				Name:  name,
+				Value: o.Count(),
+			})
+		}
+		return true
+	})
+
 	return response, nil
 }
 
+func newHistogram(name string, snapshot feature_stats.HistogramSnapshot) *Histogram {
+	return &Histogram{
+		Name:   name,
+		Bounds: snapshot.Bounds,
+		Counts: snapshot.Counts,
+		Count:  snapshot.Count,
+		Sum:    snapshot.Sum,
+	}
+}
+
 func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest) (*SysStatsResponse, error) {
 	var rtm runtime.MemStats
 	runtime.ReadMemStats(&rtm)
@@ -112,22 +156,62 @@ func (s *statsServer) GetSysStats(ctx context.Context, request *SysStatsRequest)
 	return response, nil
 }
 
+func (s *statsServer) GetConnections(ctx context.Context, request *GetConnectionsRequest) (*GetConnectionsResponse, error) {
+	tracker, ok := s.dispatcher.(routing.ConnectionTracker)
+	if !ok {
+		return nil, newError("dispatcher does not track connections")
+	}
+	now := time.Now()
+	connections := tracker.Connections()
+	response := &GetConnectionsResponse{
+		Connection: make([]*Connection, 0, len(connections)),
+	}
+	for _, c := range connections {
+		connection := &Connection{
+			Id:          c.ID,
+			InboundTag:  c.InboundTag,
+			OutboundTag: c.OutboundTag,
+			Uplink:      c.Uplink,
+			Downlink:    c.Downlink,
+			StartTime:   c.Start.Unix(),
+			Age:         now.Sub(c.Start).Milliseconds(),
+		}
+		if c.Source.IsValid() {
+			connection.Source = c.Source.String()
+		}
+		if c.Destination.IsValid() {
+			connection.Destination = c.Destination.String()
+		}
+		response.Connection = append(response.Connection, connection)
+	}
+	sort.Slice(response.Connection, func(i, j int) bool {
+		return response.Connection[i].Id < response.Connection[j].Id
+	})
+	return response, nil
+}
+
 func (s *statsServer) mustEmbedUnimplementedStatsServiceServer() {}
 
 type service struct {
 	statsManager feature_stats.Manager
+	dispatcher   routing.Dispatcher
 }
 
 func (s *service) Register(server *grpc.Server) {
-	RegisterStatsServiceServer(server, NewStatsServer(s.statsManager))
+	RegisterStatsServiceServer(server, &statsServer{
+		stats:      s.statsManager,
+		dispatcher: s.dispatcher,
+		startTime:  time.Now(),
+	})
 }
 
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
 		s := new(service)
 
-		core.RequireFeatures(ctx, func(sm feature_stats.Manager) {
+		core.RequireFeatures(ctx, func(sm feature_stats.Manager, d routing.Dispatcher) {
 			s.statsManager = sm
+			s.dispatcher = d
 		})
 
 		return s, nil
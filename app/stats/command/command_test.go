@@ -89,3 +89,48 @@ func TestQueryStats(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestHistogramStats(t *testing.T) {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	h, err := m.RegisterHistogram("test_histogram", []int64{10, 100})
+	common.Must(err)
+	h.Observe(5)
+	h.Observe(50)
+	h.Observe(500)
+	_, err = m.RegisterCounter("test_counter")
+	common.Must(err)
+
+	s := NewStatsServer(m)
+	expected := &Histogram{
+		Name:   "test_histogram",
+		Bounds: []int64{10, 100},
+		Counts: []int64{1, 1, 1},
+		Count:  3,
+		Sum:    555,
+	}
+
+	resp, err := s.QueryStats(context.Background(), &QueryStatsRequest{
+		Pattern: "histogram",
+	})
+	common.Must(err)
+	if len(resp.Stat) != 0 {
+		t.Error("unexpected counters: ", resp.Stat)
+	}
+	if r := cmp.Diff(resp.Histogram, []*Histogram{expected}, cmpopts.IgnoreUnexported(Histogram{})); r != "" {
+		t.Error(r)
+	}
+
+	getResp, err := s.GetStats(context.Background(), &GetStatsRequest{
+		Name:   "test_histogram",
+		Reset_: true,
+	})
+	common.Must(err)
+	if r := cmp.Diff(getResp.Histogram, expected, cmpopts.IgnoreUnexported(Histogram{})); r != "" {
+		t.Error(r)
+	}
+	if count := h.Snapshot(false).Count; count != 0 {
+		t.Error("expected the histogram to be reset, but got count ", count)
+	}
+}
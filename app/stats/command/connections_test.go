@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+type testDispatcher struct {
+	routing.Dispatcher
+	connections []*routing.Connection
+}
+
+func (d *testDispatcher) Connections() []*routing.Connection {
+	return d.connections
+}
+
+func TestGetConnections(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	s := &statsServer{dispatcher: &testDispatcher{connections: []*routing.Connection{
+		{
+			ID:          2,
+			InboundTag:  "socks",
+			Source:      net.TCPDestination(net.LocalHostIP, 10000),
+			Destination: net.UDPDestination(net.DomainAddress("v2fly.org"), 53),
+			Start:       start,
+		},
+		{
+			ID:          1,
+			InboundTag:  "socks",
+			OutboundTag: "direct",
+			Source:      net.TCPDestination(net.LocalHostIP, 10001),
+			Destination: net.TCPDestination(net.DomainAddress("v2fly.org"), 443),
+			Uplink:      100,
+			Downlink:    200,
+			Start:       start,
+		},
+	}}}
+
+	resp, err := s.GetConnections(context.Background(), &GetConnectionsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := cmp.Diff(resp.Connection, []*Connection{
+		{Id: 1, InboundTag: "socks", OutboundTag: "direct", Source: "tcp:127.0.0.1:10001", Destination: "tcp:v2fly.org:443", Uplink: 100, Downlink: 200, StartTime: start.Unix()},
+		{Id: 2, InboundTag: "socks", Source: "tcp:127.0.0.1:10000", Destination: "udp:v2fly.org:53", StartTime: start.Unix()},
+	}, cmpopts.IgnoreUnexported(Connection{}), cmpopts.IgnoreFields(Connection{}, "Age")); r != "" {
+		t.Error(r)
+	}
+	for _, c := range resp.Connection {
+		if c.Age < time.Minute.Milliseconds() {
+			t.Error("unexpected age of connection ", c.Id, ": ", c.Age)
+		}
+	}
+
+	s.dispatcher = nil
+	if _, err := s.GetConnections(context.Background(), &GetConnectionsRequest{}); err == nil {
+		t.Error("expected error without connection tracking dispatcher")
+	}
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+// DefaultLatencyBounds are bucket bounds for latencies in milliseconds, used
+// when a histogram is registered without bounds.
+var DefaultLatencyBounds = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Histogram is an implementation of stats.Histogram.
+type Histogram struct {
+	bounds []int64
+	counts []int64
+	count  int64
+	sum    int64
+}
+
+// NewHistogram creates a Histogram with the given bucket bounds, which must be
+// in ascending order.
+func NewHistogram(bounds []int64) (*Histogram, error) {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBounds
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, newError("histogram bounds are not in ascending order: ", bounds)
+		}
+	}
+	return &Histogram{
+		bounds: append([]int64(nil), bounds...),
+		counts: make([]int64, len(bounds)+1),
+	}, nil
+}
+
+// Observe implements stats.Histogram.
+func (h *Histogram) Observe(value int64) {
+	i := sort.Search(len(h.bounds), func(i int) bool {
+		return value <= h.bounds[i]
+	})
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.count, 1)
+	atomic.AddInt64(&h.sum, value)
+}
+
+// Snapshot implements stats.Histogram. Values observed while taking the
+// snapshot may be counted in either this snapshot or the next one, so Count
+// can differ from the total of Counts slightly.
+func (h *Histogram) Snapshot(reset bool) stats.HistogramSnapshot {
+	snapshot := stats.HistogramSnapshot{
+		Bounds: append([]int64(nil), h.bounds...),
+		Counts: make([]int64, len(h.counts)),
+	}
+	for i := range h.counts {
+		if reset {
+			snapshot.Counts[i] = atomic.SwapInt64(&h.counts[i], 0)
+		} else {
+			snapshot.Counts[i] = atomic.LoadInt64(&h.counts[i])
+		}
+	}
+	if reset {
+		snapshot.Count = atomic.SwapInt64(&h.count, 0)
+		snapshot.Sum = atomic.SwapInt64(&h.sum, 0)
+	} else {
+		snapshot.Count = atomic.LoadInt64(&h.count)
+		snapshot.Sum = atomic.LoadInt64(&h.sum)
+	}
+	return snapshot
+}
@@ -0,0 +1,90 @@
+package stats_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+func TestStatsHistogram(t *testing.T) {
+	raw, err := common.CreateObject(context.Background(), &Config{})
+	common.Must(err)
+
+	m := raw.(stats.Manager)
+	h, err := m.RegisterHistogram("test.histogram", []int64{10, 20, 50, 100})
+	common.Must(err)
+	if _, err := m.RegisterHistogram("test.histogram", nil); err == nil {
+		t.Error("expected error registering a histogram twice")
+	}
+	if m.GetHistogram("test.histogram") != h {
+		t.Error("failed to get the registered histogram")
+	}
+
+	// 1..100, observed concurrently, and one value above all bounds.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(start int64) {
+			defer wg.Done()
+			for v := start; v <= 100; v += 4 {
+				h.Observe(v)
+			}
+		}(int64(i + 1))
+	}
+	wg.Wait()
+	h.Observe(1000)
+
+	snapshot := h.Snapshot(false)
+	if !reflect.DeepEqual(snapshot.Counts, []int64{10, 10, 30, 50, 1}) {
+		t.Error("unexpected bucket counts: ", snapshot.Counts)
+	}
+	if snapshot.Count != 101 || snapshot.Sum != 5050+1000 {
+		t.Error("unexpected count ", snapshot.Count, " and sum ", snapshot.Sum)
+	}
+
+	for _, c := range []struct {
+		p     float64
+		value int64
+	}{
+		{0, 0},
+		{0.05, 5},
+		{0.5, 51},
+		{0.95, 96},
+		{1, 100},
+	} {
+		if v := snapshot.Percentile(c.p); v != c.value {
+			t.Error("expected percentile ", c.p, " to be ", c.value, ", but got ", v)
+		}
+	}
+
+	if snapshot := h.Snapshot(true); snapshot.Count != 101 {
+		t.Error("unexpected count before reset: ", snapshot.Count)
+	}
+	if snapshot := h.Snapshot(false); snapshot.Count != 0 || snapshot.Sum != 0 || !reflect.DeepEqual(snapshot.Counts, []int64{0, 0, 0, 0, 0}) {
+		t.Error("histogram is not reset: ", snapshot)
+	}
+	if v := h.Snapshot(false).Percentile(0.5); v != 0 {
+		t.Error("expected percentile of empty histogram to be 0, but got ", v)
+	}
+
+	common.Must(m.UnregisterHistogram("test.histogram"))
+	if m.GetHistogram("test.histogram") != nil {
+		t.Error("histogram is not unregistered")
+	}
+}
+
+func TestStatsHistogramBounds(t *testing.T) {
+	if _, err := NewHistogram([]int64{10, 10, 20}); err == nil {
+		t.Error("expected error for bounds not in ascending order")
+	}
+	h, err := NewHistogram(nil)
+	common.Must(err)
+	if bounds := h.Snapshot(false).Bounds; !reflect.DeepEqual(bounds, DefaultLatencyBounds) {
+		t.Error("expected default bounds, but got ", bounds)
+	}
+}
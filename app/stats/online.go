@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultOnlineWindow is the window of online counters registered without one.
+const DefaultOnlineWindow = 5 * time.Minute
+
+// onlineBuckets is the number of buckets a window is divided into.
+const onlineBuckets = 10
+
+type onlineBucket struct {
+	epoch int64
+	keys  map[string]struct{}
+}
+
+// OnlineCounter is an implementation of stats.OnlineCounter. Keys are recorded
+// in the bucket of the time they are seen, and buckets older than the window
+// are dropped, so a key expires between nine and ten tenths of the window
+// after it is last seen, and memory is bounded by the keys seen within the
+// window.
+type OnlineCounter struct {
+	access  sync.Mutex
+	width   int64
+	buckets [onlineBuckets]onlineBucket
+	now     func() time.Time
+}
+
+// NewOnlineCounter creates an OnlineCounter with the given window.
+func NewOnlineCounter(window time.Duration) (*OnlineCounter, error) {
+	if window == 0 {
+		window = DefaultOnlineWindow
+	}
+	if window < onlineBuckets {
+		return nil, newError("invalid online window: ", window)
+	}
+	return &OnlineCounter{
+		width: int64(window / onlineBuckets),
+		now:   time.Now,
+	}, nil
+}
+
+// expire drops the buckets out of the window, and returns the current epoch.
+func (c *OnlineCounter) expire() int64 {
+	epoch := c.now().UnixNano() / c.width
+	for i := range c.buckets {
+		if b := &c.buckets[i]; b.keys != nil && b.epoch <= epoch-onlineBuckets {
+			b.keys = nil
+		}
+	}
+	return epoch
+}
+
+// Add implements stats.OnlineCounter.
+func (c *OnlineCounter) Add(key string) {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	epoch := c.expire()
+	b := &c.buckets[epoch%onlineBuckets]
+	if b.keys == nil || b.epoch != epoch {
+		b.epoch = epoch
+		b.keys = make(map[string]struct{})
+	}
+	b.keys[key] = struct{}{}
+}
+
+func (c *OnlineCounter) keys() map[string]struct{} {
+	c.access.Lock()
+	defer c.access.Unlock()
+
+	c.expire()
+	keys := make(map[string]struct{})
+	for _, b := range c.buckets {
+		for key := range b.keys {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// Count implements stats.OnlineCounter.
+func (c *OnlineCounter) Count() int64 {
+	return int64(len(c.keys()))
+}
+
+// Keys implements stats.OnlineCounter. Keys are sorted.
+func (c *OnlineCounter) Keys() []string {
+	keys := c.keys()
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
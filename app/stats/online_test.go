@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+func TestOnlineCounter(t *testing.T) {
+	c, err := NewOnlineCounter(10 * time.Second)
+	common.Must(err)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	c.Add("1.1.1.1")
+	c.Add("2.2.2.2")
+	c.Add("1.1.1.1")
+	if v := c.Count(); v != 2 {
+		t.Error("expect 2 keys, but got ", v)
+	}
+
+	now = now.Add(5 * time.Second)
+	c.Add("3.3.3.3")
+	c.Add("1.1.1.1")
+	if r := cmp.Diff(c.Keys(), []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}); r != "" {
+		t.Error(r)
+	}
+
+	// 2.2.2.2 is out of the window, the others are seen since.
+	now = now.Add(5 * time.Second)
+	if r := cmp.Diff(c.Keys(), []string{"1.1.1.1", "3.3.3.3"}); r != "" {
+		t.Error(r)
+	}
+
+	now = now.Add(time.Minute)
+	if v := c.Count(); v != 0 {
+		t.Error("expect no keys, but got ", v)
+	}
+}
+
+func TestOnlineCounterRegistration(t *testing.T) {
+	m, err := NewManager(nil, &Config{})
+	common.Must(err)
+
+	c, err := m.RegisterOnlineCounter("user>>>online", 0)
+	common.Must(err)
+	c.Add("love@v2fly.org")
+	if _, err := m.RegisterOnlineCounter("user>>>online", 0); err == nil {
+		t.Error("expect error for duplicate online counter")
+	}
+	if _, err := m.RegisterOnlineCounter("invalid", time.Nanosecond); err == nil {
+		t.Error("expect error for invalid window")
+	}
+	if v := m.GetOnlineCounter("user>>>online").Count(); v != 1 {
+		t.Error("expect 1 key, but got ", v)
+	}
+	common.Must(m.UnregisterOnlineCounter("user>>>online"))
+	if m.GetOnlineCounter("user>>>online") != nil {
+		t.Error("unexpected online counter after unregistration")
+	}
+}
@@ -0,0 +1,156 @@
+package prometheus
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	feature_stats "github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+const metricPrefix = "v2ray_"
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	suffix string
+	labels []label
+	value  int64
+}
+
+type family struct {
+	metricType string
+	samples    []sample
+}
+
+type registry map[string]*family
+
+func (r registry) add(name string, metricType string, samples ...sample) {
+	f, found := r[name]
+	if !found {
+		f = &family{metricType: metricType}
+		r[name] = f
+	}
+	f.samples = append(f.samples, samples...)
+}
+
+// WriteMetrics writes all counters, histograms, online counters and channels of
+// manager to w in Prometheus text format.
+//
+// Traffic counters named like "inbound>>>tag>>>traffic>>>uplink" are
+// written as v2ray_traffic_uplink_bytes_total with dimension and target labels.
+// The online counter "user>>>online" is written as v2ray_online_users, and
+// the ones named like "user>>>email>>>online" as v2ray_user_online with a user
+// label. Other names are sanitized into metric names.
+func WriteMetrics(w io.Writer, manager *stats.Manager) error {
+	r := make(registry)
+	manager.VisitCounters(func(name string, c feature_stats.Counter) bool {
+		if parts := strings.Split(name, ">>>"); len(parts) == 4 && parts[2] == "traffic" {
+			r.add(metricName("traffic_"+parts[3]+"_bytes_total"), "counter", sample{
+				labels: []label{{"dimension", parts[0]}, {"target", parts[1]}},
+				value:  c.Value(),
+			})
+		} else {
+			r.add(metricName(name), "untyped", sample{value: c.Value()})
+		}
+		return true
+	})
+	manager.VisitHistograms(func(name string, h feature_stats.Histogram) bool {
+		snapshot := h.Snapshot(false)
+		samples := make([]sample, 0, len(snapshot.Counts)+2)
+		var cumulative int64
+		for i, count := range snapshot.Counts {
+			cumulative += count
+			le := "+Inf"
+			if i < len(snapshot.Bounds) {
+				le = strconv.FormatInt(snapshot.Bounds[i], 10)
+			}
+			samples = append(samples, sample{suffix: "_bucket", labels: []label{{"le", le}}, value: cumulative})
+		}
+		samples = append(samples,
+			sample{suffix: "_sum", value: snapshot.Sum},
+			sample{suffix: "_count", value: cumulative},
+		)
+		r.add(metricName(name), "histogram", samples...)
+		return true
+	})
+	manager.VisitOnlineCounters(func(name string, o feature_stats.OnlineCounter) bool {
+		switch parts := strings.Split(name, ">>>"); {
+		case name == "user>>>online":
+			r.add(metricName("online_users"), "gauge", sample{value: o.Count()})
+		case len(parts) == 3 && parts[0] == "user" && parts[2] == "online":
+			r.add(metricName("user_online"), "gauge", sample{
+				labels: []label{{"user", parts[1]}},
+				value:  o.Count(),
+			})
+		default:
+			r.add(metricName(name), "gauge", sample{value: o.Count()})
+		}
+		return true
+	})
+	manager.VisitChannels(func(name string, c feature_stats.Channel) bool {
+		r.add(metricName("channel_subscribers"), "gauge", sample{
+			labels: []label{{"channel", name}},
+			value:  int64(len(c.Subscribers())),
+		})
+		return true
+	})
+
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		f := r[name]
+		if f.metricType != "histogram" {
+			sort.SliceStable(f.samples, func(i, j int) bool {
+				return formatLabels(f.samples[i].labels) < formatLabels(f.samples[j].labels)
+			})
+		}
+		b.WriteString("# TYPE " + name + " " + f.metricType + "\n")
+		for _, s := range f.samples {
+			b.WriteString(name + s.suffix + formatLabels(s.labels) + " " + strconv.FormatInt(s.value, 10) + "\n")
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// metricName converts name into a valid metric name with the v2ray_ prefix.
+func metricName(name string) string {
+	var b strings.Builder
+	b.WriteString(metricPrefix)
+	underscore := true
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, l.name+`="`+labelValueReplacer.Replace(l.value)+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,132 @@
+package prometheus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+)
+
+func newTestManager() *stats.Manager {
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+
+	counters := map[string]int64{
+		"inbound>>>socks-in>>>traffic>>>uplink":    100,
+		"inbound>>>socks-in>>>traffic>>>downlink":  200,
+		"outbound>>>direct>>>traffic>>>uplink":     300,
+		"user>>>love@v2fly.org>>>traffic>>>uplink": 400,
+		"user>>>\"quoted\"\\>>>traffic>>>downlink": 500,
+		"dns.cache-hits": 7,
+	}
+	for name, value := range counters {
+		c, err := m.RegisterCounter(name)
+		common.Must(err)
+		c.Set(value)
+	}
+
+	h, err := m.RegisterHistogram("observatory>>>rtt", []int64{100, 500})
+	common.Must(err)
+	h.Observe(50)
+	h.Observe(200)
+	h.Observe(1000)
+
+	_, err = m.RegisterChannel("observatory>>>events")
+	common.Must(err)
+
+	online := map[string][]string{
+		"user>>>online":                   {"love@v2fly.org", "hello@v2fly.org"},
+		"user>>>love@v2fly.org>>>online":  {"10.0.0.1", "10.0.0.2"},
+		"user>>>hello@v2fly.org>>>online": {"10.0.0.3"},
+	}
+	for name, keys := range online {
+		o, err := m.RegisterOnlineCounter(name, 0)
+		common.Must(err)
+		for _, key := range keys {
+			o.Add(key)
+		}
+	}
+	return m
+}
+
+const expectedMetrics = `# TYPE v2ray_channel_subscribers gauge
+v2ray_channel_subscribers{channel="observatory>>>events"} 0
+# TYPE v2ray_dns_cache_hits untyped
+v2ray_dns_cache_hits 7
+# TYPE v2ray_observatory_rtt histogram
+v2ray_observatory_rtt_bucket{le="100"} 1
+v2ray_observatory_rtt_bucket{le="500"} 2
+v2ray_observatory_rtt_bucket{le="+Inf"} 3
+v2ray_observatory_rtt_sum 1250
+v2ray_observatory_rtt_count 3
+# TYPE v2ray_online_users gauge
+v2ray_online_users 2
+# TYPE v2ray_traffic_downlink_bytes_total counter
+v2ray_traffic_downlink_bytes_total{dimension="inbound",target="socks-in"} 200
+v2ray_traffic_downlink_bytes_total{dimension="user",target="\"quoted\"\\"} 500
+# TYPE v2ray_traffic_uplink_bytes_total counter
+v2ray_traffic_uplink_bytes_total{dimension="inbound",target="socks-in"} 100
+v2ray_traffic_uplink_bytes_total{dimension="outbound",target="direct"} 300
+v2ray_traffic_uplink_bytes_total{dimension="user",target="love@v2fly.org"} 400
+# TYPE v2ray_user_online gauge
+v2ray_user_online{user="hello@v2fly.org"} 1
+v2ray_user_online{user="love@v2fly.org"} 2
+`
+
+func TestWriteMetrics(t *testing.T) {
+	var b strings.Builder
+	common.Must(WriteMetrics(&b, newTestManager()))
+	if r := cmp.Diff(b.String(), expectedMetrics); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"simple":                "v2ray_simple",
+		"a.b-c":                 "v2ray_a_b_c",
+		"inbound>>>api>>>count": "v2ray_inbound_api_count",
+		">>>trailing>>>":        "v2ray_trailing",
+	} {
+		if actual := metricName(name); actual != expected {
+			t.Error("expected metric name ", expected, " for ", name, ", but got ", actual)
+		}
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	port := tcp.PickPort()
+	s := &service{
+		ctx: context.Background(),
+		config: &Config{
+			ListenAddr: "127.0.0.1",
+			ListenPort: int32(port),
+		},
+		stats: newTestManager(),
+	}
+	common.Must(s.Start())
+	defer s.Close()
+
+	resp, err := http.Get("http://" + net.TCPDestination(net.LocalHostIP, port).NetAddr() + defaultPath)
+	common.Must(err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status: ", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Error("unexpected content type: ", contentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	common.Must(err)
+	if r := cmp.Diff(string(body), expectedMetrics); r != "" {
+		t.Error(r)
+	}
+}
@@ -0,0 +1,108 @@
+package prometheus
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	feature_stats "github.com/v2fly/v2ray-core/v5/features/stats"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+const defaultPath = "/metrics"
+
+type service struct {
+	ctx    context.Context
+	config *Config
+	stats  feature_stats.Manager
+
+	access sync.Mutex
+	server *http.Server
+}
+
+// Type implements common.HasType.
+func (s *service) Type() interface{} {
+	return (*struct{})(nil)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	manager, ok := s.stats.(*stats.Manager)
+	if !ok {
+		http.Error(w, "metrics only work with its own stats.Manager", http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := WriteMetrics(w, manager); err != nil {
+		newError("failed to write metrics").Base(err).AtDebug().WriteToLog()
+	}
+}
+
+// Start implements common.Runnable.
+func (s *service) Start() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	var listener net.Listener
+	var err error
+	address := net.ParseAddress(s.config.ListenAddr)
+	switch {
+	case address.Family().IsIP():
+		listener, err = internet.ListenSystem(s.ctx, &net.TCPAddr{IP: address.IP(), Port: int(s.config.ListenPort)}, nil)
+	case strings.EqualFold(address.Domain(), "localhost"):
+		listener, err = internet.ListenSystem(s.ctx, &net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(s.config.ListenPort)}, nil)
+	default:
+		return newError("prometheus metrics cannot listen on the address: ", address)
+	}
+	if err != nil {
+		return newError("prometheus metrics cannot listen on the port ", s.config.ListenPort).Base(err)
+	}
+
+	path := s.config.Path
+	if path == "" {
+		path = defaultPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	s.server = &http.Server{Handler: mux}
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			newError("unable to serve prometheus metrics").Base(err).WriteToLog()
+		}
+	}(s.server)
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *service) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		s := &service{
+			ctx:    ctx,
+			config: config.(*Config),
+		}
+		if err := core.RequireFeatures(ctx, func(sm feature_stats.Manager) {
+			s.stats = sm
+		}); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}))
+}
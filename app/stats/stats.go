@@ -5,6 +5,7 @@ package stats
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/errors"
@@ -13,17 +14,21 @@ import (
 
 // Manager is an implementation of stats.Manager.
 type Manager struct {
-	access   sync.RWMutex
-	counters map[string]*Counter
-	channels map[string]*Channel
-	running  bool
+	access     sync.RWMutex
+	counters   map[string]*Counter
+	histograms map[string]*Histogram
+	online     map[string]*OnlineCounter
+	channels   map[string]*Channel
+	running    bool
 }
 
 // NewManager creates an instance of Statistics Manager.
 func NewManager(ctx context.Context, config *Config) (*Manager, error) {
 	m := &Manager{
-		counters: make(map[string]*Counter),
-		channels: make(map[string]*Channel),
+		counters:   make(map[string]*Counter),
+		histograms: make(map[string]*Histogram),
+		online:     make(map[string]*OnlineCounter),
+		channels:   make(map[string]*Channel),
 	}
 
 	return m, nil
@@ -83,6 +88,110 @@ func (m *Manager) VisitCounters(visitor func(string, stats.Counter) bool) {
 	}
 }
 
+// RegisterHistogram implements stats.Manager.
+func (m *Manager) RegisterHistogram(name string, bounds []int64) (stats.Histogram, error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		return nil, newError("Histogram ", name, " already registered.")
+	}
+	h, err := NewHistogram(bounds)
+	if err != nil {
+		return nil, err
+	}
+	newError("create new histogram ", name).AtDebug().WriteToLog()
+	m.histograms[name] = h
+	return h, nil
+}
+
+// UnregisterHistogram implements stats.Manager.
+func (m *Manager) UnregisterHistogram(name string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.histograms[name]; found {
+		newError("remove histogram ", name).AtDebug().WriteToLog()
+		delete(m.histograms, name)
+	}
+	return nil
+}
+
+// GetHistogram implements stats.Manager.
+func (m *Manager) GetHistogram(name string) stats.Histogram {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if h, found := m.histograms[name]; found {
+		return h
+	}
+	return nil
+}
+
+// VisitHistograms calls visitor function on all managed histograms.
+func (m *Manager) VisitHistograms(visitor func(string, stats.Histogram) bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for name, h := range m.histograms {
+		if !visitor(name, h) {
+			break
+		}
+	}
+}
+
+// RegisterOnlineCounter implements stats.Manager.
+func (m *Manager) RegisterOnlineCounter(name string, window time.Duration) (stats.OnlineCounter, error) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.online[name]; found {
+		return nil, newError("Online counter ", name, " already registered.")
+	}
+	c, err := NewOnlineCounter(window)
+	if err != nil {
+		return nil, err
+	}
+	newError("create new online counter ", name).AtDebug().WriteToLog()
+	m.online[name] = c
+	return c, nil
+}
+
+// UnregisterOnlineCounter implements stats.Manager.
+func (m *Manager) UnregisterOnlineCounter(name string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	if _, found := m.online[name]; found {
+		newError("remove online counter ", name).AtDebug().WriteToLog()
+		delete(m.online, name)
+	}
+	return nil
+}
+
+// GetOnlineCounter implements stats.Manager.
+func (m *Manager) GetOnlineCounter(name string) stats.OnlineCounter {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	if c, found := m.online[name]; found {
+		return c
+	}
+	return nil
+}
+
+// VisitOnlineCounters calls visitor function on all managed online counters.
+func (m *Manager) VisitOnlineCounters(visitor func(string, stats.OnlineCounter) bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for name, c := range m.online {
+		if !visitor(name, c) {
+			break
+		}
+	}
+}
+
 // RegisterChannel implements stats.Manager.
 func (m *Manager) RegisterChannel(name string) (stats.Channel, error) {
 	m.access.Lock()
@@ -124,6 +233,18 @@ func (m *Manager) GetChannel(name string) stats.Channel {
 	return nil
 }
 
+// VisitChannels calls visitor function on all managed channels.
+func (m *Manager) VisitChannels(visitor func(string, stats.Channel) bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for name, c := range m.channels {
+		if !visitor(name, c) {
+			break
+		}
+	}
+}
+
 // Start implements common.Runnable.
 func (m *Manager) Start() error {
 	m.access.Lock()
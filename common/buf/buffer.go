@@ -1,7 +1,11 @@
 package buf
 
 import (
+	"context"
 	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	B "github.com/sagernet/sing/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/net"
@@ -13,8 +17,56 @@ const (
 	// outline-ss-server/go-ss2: 16K + tag size for TCP, 64K for UDP
 	// clash: 20K
 	Size = 16 * 1024
+
+	maxSize = 64 * 1024
+)
+
+var (
+	defaultSize int32 = Size
+	sizeOnce    sync.Once
+
+	poolGets    uint64
+	poolEscapes uint64
 )
 
+// PoolStats returns the number of buffers taken from the pool, and the number
+// of buffers allocated outside the pool because they are larger than the
+// default size.
+func PoolStats() (gets, escapes uint64) {
+	return atomic.LoadUint64(&poolGets), atomic.LoadUint64(&poolEscapes)
+}
+
+// SetDefaultSize changes the capacity of buffers created by New and StackNew.
+// n must be between Size and 64K, as callers may fill a buffer from New with
+// up to Size bytes. It can only be called once, before any buffer is
+// allocated.
+func SetDefaultSize(n int32) error {
+	if n < Size || n > maxSize {
+		return newError("invalid buffer size: ", n)
+	}
+	set := false
+	sizeOnce.Do(func() {
+		atomic.StoreInt32(&defaultSize, n)
+		set = true
+	})
+	if !set {
+		return newError("buffer size can not be changed after buffers are allocated")
+	}
+	return nil
+}
+
+// DefaultSize returns the capacity of buffers created by New and StackNew.
+func DefaultSize() int32 {
+	return atomic.LoadInt32(&defaultSize)
+}
+
+func allocate() []byte {
+	// Freeze the default size.
+	sizeOnce.Do(func() {})
+	atomic.AddUint64(&poolGets, 1)
+	return B.Get(int(DefaultSize()))
+}
+
 // Buffer is a recyclable allocation of a byte array. Buffer.Release() recycles
 // the buffer into an internal buffer pool, in order to recreate a buffer more
 // quickly.
@@ -29,12 +81,16 @@ type Buffer struct {
 // New creates a Buffer with 0 length and 2K capacity.
 func New() *Buffer {
 	return &Buffer{
-		v: B.Get(Size),
+		v: allocate(),
 	}
 }
 
 func NewSize(size int32) *Buffer {
-	if size <= 128 || size > Size {
+	defaultSize := DefaultSize()
+	if size > defaultSize {
+		atomic.AddUint64(&poolEscapes, 1)
+	}
+	if size <= 128 || size > defaultSize {
 		return &Buffer{
 			v:         make([]byte, size),
 			unmanaged: true,
@@ -56,10 +112,21 @@ func FromBytes(data []byte) *Buffer {
 // This method is for buffers that is released in the same function.
 func StackNew() Buffer {
 	return Buffer{
-		v: B.Get(Size),
+		v: allocate(),
 	}
 }
 
+// Clone returns a new Buffer holding a copy of the content of this Buffer.
+func (b *Buffer) Clone() *Buffer {
+	nb := NewSize(b.Len())
+	nb.end = int32(copy(nb.v, b.Bytes()))
+	if b.Endpoint != nil {
+		endpoint := *b.Endpoint
+		nb.Endpoint = &endpoint
+	}
+	return nb
+}
+
 // Release recycles the buffer into an internal buffer pool.
 func (b *Buffer) Release() {
 	if b == nil || b.v == nil || b.unmanaged {
@@ -96,7 +163,7 @@ func (b *Buffer) Bytes() []byte {
 }
 
 // Extend increases the buffer size by n bytes, and returns the extended part.
-// It panics if result size is larger than buf.Size.
+// It panics if result size is larger than the capacity of the buffer.
 func (b *Buffer) Extend(n int32) []byte {
 	end := b.end + n
 	if end > int32(len(b.v)) {
@@ -189,8 +256,33 @@ func (b *Buffer) Write(data []byte) (int, error) {
 	return nBytes, nil
 }
 
-// WriteByte writes a single byte into the buffer.
+// AppendBytes writes all given byte slices into the buffer in order, and
+// returns the total number of bytes written. Buffers don't grow, so if the
+// content doesn't fit, it writes up to the capacity of the buffer and returns
+// io.ErrShortWrite.
+func (b *Buffer) AppendBytes(bytes ...[]byte) (int32, error) {
+	var total int32
+	for _, data := range bytes {
+		if len(data) == 0 {
+			continue
+		}
+		n := int32(copy(b.v[b.end:], data))
+		b.end += n
+		total += n
+		if n < int32(len(data)) {
+			return total, io.ErrShortWrite
+		}
+	}
+	return total, nil
+}
+
+// WriteByte writes a single byte into the buffer. It doesn't grow the buffer,
+// as buffers have a fixed capacity, so it returns an error when the buffer is
+// full, just as it does for a nil buffer.
 func (b *Buffer) WriteByte(v byte) error {
+	if b == nil {
+		return newError("nil buffer")
+	}
 	if b.IsFull() {
 		return newError("buffer full")
 	}
@@ -226,6 +318,16 @@ func (b *Buffer) ReadBytes(length int32) ([]byte, error) {
 	return nb, nil
 }
 
+// Peek returns the next n bytes without advancing the buffer. The returned
+// slice shares memory with the buffer, and is only valid until the next write
+// or Release.
+func (b *Buffer) Peek(n int32) ([]byte, error) {
+	if b.Len() < n {
+		return nil, newError("insufficient data: ", b.Len(), " < ", n)
+	}
+	return b.v[b.start : b.start+n], nil
+}
+
 // Read implements io.Reader.Read().
 func (b *Buffer) Read(data []byte) (int, error) {
 	if b.Len() == 0 {
@@ -240,6 +342,21 @@ func (b *Buffer) Read(data []byte) (int, error) {
 	return nBytes, nil
 }
 
+// WriteTo implements io.WriterTo. It writes the content of the buffer in a
+// single Write call, and advances the buffer by the number of bytes written.
+func (b *Buffer) WriteTo(writer io.Writer) (int64, error) {
+	if b.IsEmpty() {
+		return 0, nil
+	}
+	n, err := writer.Write(b.v[b.start:b.end])
+	if int32(n) == b.Len() {
+		b.Clear()
+	} else {
+		b.start += int32(n)
+	}
+	return int64(n), err
+}
+
 // ReadFrom implements io.ReaderFrom.
 func (b *Buffer) ReadFrom(reader io.Reader) (int64, error) {
 	n, err := reader.Read(b.v[b.end:])
@@ -247,6 +364,17 @@ func (b *Buffer) ReadFrom(reader io.Reader) (int64, error) {
 	return int64(n), err
 }
 
+// ReadAtMost reads at most size bytes from the given reader in a single Read call.
+func (b *Buffer) ReadAtMost(reader io.Reader, size int32) (int64, error) {
+	end := b.end + size
+	if end > int32(len(b.v)) {
+		end = int32(len(b.v))
+	}
+	n, err := reader.Read(b.v[b.end:end])
+	b.end += int32(n)
+	return int64(n), err
+}
+
 func (b *Buffer) ReadFromPacketConn(reader net.PacketConn) (int64, error) {
 	n, addr, err := reader.ReadFrom(b.v[b.end:])
 	if addr != nil {
@@ -291,6 +419,69 @@ func (b *Buffer) ReadFullFrom(reader io.Reader, size int32) (int64, error) {
 	return int64(n), err
 }
 
+type contextReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	default:
+	}
+	return r.Reader.Read(p)
+}
+
+// ReadFullFromContext reads exact size of bytes from given reader, or until
+// error occurs. The error of ctx is returned along with the bytes read so far
+// once it is done. If reader has a read deadline, such as net.Conn, a pending
+// Read is interrupted then by setting the deadline to the past, and the
+// deadline is cleared before returning. Otherwise the context is only checked
+// between Reads, and the caller has to close reader to interrupt a pending
+// Read.
+func (b *Buffer) ReadFullFromContext(ctx context.Context, reader io.Reader, size int32) (int64, error) {
+	// finish stops interrupting reader, and clears its deadline if it was
+	// interrupted. It must be called as soon as the read returns, so that a
+	// successful read is never followed by an interrupt.
+	finish := func() {}
+	deadliner, ok := reader.(interface{ SetReadDeadline(time.Time) error })
+	if done := ctx.Done(); ok && done != nil {
+		var access sync.Mutex
+		finished := false
+		interrupted := false
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-done:
+				access.Lock()
+				defer access.Unlock()
+				if !finished {
+					deadliner.SetReadDeadline(time.Now())
+					interrupted = true
+				}
+			case <-stop:
+			}
+		}()
+		finish = func() {
+			access.Lock()
+			finished = true
+			if interrupted {
+				deadliner.SetReadDeadline(time.Time{})
+			}
+			access.Unlock()
+			close(stop)
+		}
+	}
+
+	n, err := b.ReadFullFrom(&contextReader{ctx: ctx, Reader: reader}, size)
+	finish()
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return n, err
+}
+
 // String returns the string form of this Buffer.
 func (b *Buffer) String() string {
 	return string(b.Bytes())
@@ -0,0 +1,168 @@
+package buf_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	. "github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/crypto"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/net/udpovertcp"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/xudp"
+)
+
+// The default size can only be set before any buffer is allocated, so these
+// tests run in a process of their own.
+const setDefaultSizeEnv = "V2RAY_BUF_TEST_SET_DEFAULT_SIZE"
+
+// runInOwnProcess reruns the named test in a new process, and reports whether
+// the caller is that new process.
+func runInOwnProcess(t *testing.T, name string) bool {
+	if os.Getenv(setDefaultSizeEnv) != "" {
+		return true
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^"+name+"$")
+	cmd.Env = append(os.Environ(), setDefaultSizeEnv+"=1")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(err, "\n", string(output))
+	}
+	return false
+}
+
+func TestSetDefaultSize(t *testing.T) {
+	if !runInOwnProcess(t, "TestSetDefaultSize") {
+		return
+	}
+
+	if err := SetDefaultSize(Size - 1); err == nil {
+		t.Error("expect error for size smaller than ", Size)
+	}
+	if err := SetDefaultSize(128 * 1024); err == nil {
+		t.Error("expect error for size larger than 64K")
+	}
+	if err := SetDefaultSize(16384); err != nil {
+		t.Fatal(err)
+	}
+	if s := DefaultSize(); s != 16384 {
+		t.Error("expect default size 16384, but got ", s)
+	}
+
+	b := New()
+	defer b.Release()
+	if c := cap(b.Extend(DefaultSize())); c != 16384 {
+		t.Error("expect extending to 16384, but got ", c)
+	}
+	if !b.IsFull() {
+		t.Error("expect buffer of 16384 to be full")
+	}
+
+	s := StackNew()
+	defer s.Release()
+	if l := len(s.Extend(DefaultSize())); l != 16384 {
+		t.Error("expect extending to 16384, but got ", l)
+	}
+
+	if err := SetDefaultSize(32 * 1024); err == nil {
+		t.Error("expect error after buffers are allocated")
+	}
+	if s := DefaultSize(); s != 16384 {
+		t.Error("expect default size to stay 16384, but got ", s)
+	}
+}
+
+func TestLargeDefaultSize(t *testing.T) {
+	if !runInOwnProcess(t, "TestLargeDefaultSize") {
+		return
+	}
+
+	common.Must(SetDefaultSize(32 * 1024))
+
+	b := New()
+	defer b.Release()
+	if l := len(b.Extend(DefaultSize())); l != 32*1024 {
+		t.Error("expect extending to 32K, but got ", l)
+	}
+
+	payload := make([]byte, 8*1024)
+	common.Must2(rand.Read(payload))
+
+	t.Run("AEADStream", func(t *testing.T) {
+		key := make([]byte, 16)
+		common.Must2(rand.Read(key))
+		block, err := aes.NewCipher(key)
+		common.Must(err)
+		aead, err := cipher.NewGCM(block)
+		common.Must(err)
+		auth := &crypto.AEADAuthenticator{
+			AEAD:                    aead,
+			NonceGenerator:          crypto.GenerateStaticBytes(make([]byte, 12)),
+			AdditionalDataGenerator: crypto.GenerateEmptyBytes(),
+		}
+
+		cache := bytes.NewBuffer(nil)
+		writer := crypto.NewAuthenticationWriter(auth, crypto.PlainChunkSizeParser{}, cache, protocol.TransferTypeStream, nil)
+		common.Must(writer.WriteMultiBuffer(MergeBytes(nil, payload)))
+
+		reader := crypto.NewAuthenticationReader(auth, crypto.PlainChunkSizeParser{}, cache, protocol.TransferTypeStream, nil)
+		var mb MultiBuffer
+		for mb.Len() < int32(len(payload)) {
+			mb2, err := reader.ReadMultiBuffer()
+			common.Must(err)
+			mb, _ = MergeMulti(mb, mb2)
+		}
+		received := make([]byte, len(payload))
+		SplitBytes(mb, received)
+		if r := cmp.Diff(received, payload); r != "" {
+			t.Error(r)
+		}
+	})
+
+	dest := net.UDPDestination(net.LocalHostIP, 53)
+
+	t.Run("UDPOverTCP", func(t *testing.T) {
+		cache := bytes.NewBuffer(nil)
+		writer := udpovertcp.NewWriter(cache, &dest)
+		common.Must(writer.WriteMultiBuffer(MultiBuffer{FromBytes(payload)}))
+
+		mb, err := udpovertcp.NewReader(cache).ReadMultiBuffer()
+		common.Must(err)
+		if r := cmp.Diff(mb[0].Bytes(), payload); r != "" {
+			t.Error(r)
+		}
+		ReleaseMulti(mb)
+
+		large := NewSize(Size + 1)
+		large.Extend(Size + 1)
+		common.Must(writer.WriteMultiBuffer(MultiBuffer{large}))
+		if _, err := udpovertcp.NewReader(cache).ReadMultiBuffer(); err == nil {
+			t.Error("expect error for packet larger than ", Size)
+		}
+	})
+
+	t.Run("XUDP", func(t *testing.T) {
+		cache := bytes.NewBuffer(nil)
+		writer := xudp.NewPacketWriter(NewWriter(cache), net.Destination{})
+		packet := FromBytes(payload)
+		packet.Endpoint = &dest
+		common.Must(writer.WriteMultiBuffer(MultiBuffer{packet}))
+
+		mb, err := xudp.NewPacketReader(cache).ReadMultiBuffer()
+		common.Must(err)
+		if r := cmp.Diff(mb[0].Bytes(), payload); r != "" {
+			t.Error(r)
+		}
+		if mb[0].Endpoint == nil || *mb[0].Endpoint != dest {
+			t.Error("expect endpoint ", dest, ", but got ", mb[0].Endpoint)
+		}
+		ReleaseMulti(mb)
+	})
+}
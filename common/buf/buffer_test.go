@@ -2,12 +2,18 @@ package buf_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"io"
+	gonet "net"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/v2fly/v2ray-core/v5/common"
 	. "github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
 )
 
 func TestBufferClear(t *testing.T) {
@@ -78,6 +84,72 @@ func TestBufferByte(t *testing.T) {
 	}
 }
 
+func TestBufferWriteByteUntilFull(t *testing.T) {
+	b := New()
+	defer b.Release()
+
+	expected := make([]byte, 9000)
+	for i := range expected {
+		expected[i] = byte(i)
+		common.Must(b.WriteByte(byte(i)))
+	}
+	if diff := cmp.Diff(expected, b.Bytes()); diff != "" {
+		t.Error(diff)
+	}
+
+	b.Extend(Size - b.Len())
+	if err := b.WriteByte('a'); err == nil {
+		t.Error("expect error on full buffer")
+	}
+
+	var nilBuffer *Buffer
+	if err := nilBuffer.WriteByte('a'); err == nil {
+		t.Error("expect error on nil buffer")
+	}
+}
+
+func TestBufferPeek(t *testing.T) {
+	b := New()
+	defer b.Release()
+	common.Must2(b.WriteString("abcdef"))
+
+	peeked, err := b.Peek(3)
+	common.Must(err)
+	if string(peeked) != "abc" {
+		t.Error("unexpected peeked content ", string(peeked))
+	}
+
+	peeked, err = b.Peek(6)
+	common.Must(err)
+	if string(peeked) != "abcdef" {
+		t.Error("unexpected peeked content ", string(peeked))
+	}
+	if b.Len() != 6 {
+		t.Error("expect no data consumed, but got length ", b.Len())
+	}
+
+	if _, err := b.Peek(7); err == nil {
+		t.Error("expect error on insufficient data")
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	gets, escapes := PoolStats()
+
+	b := New()
+	b.Release()
+	large := NewSize(DefaultSize() + 1)
+	large.Release()
+
+	newGets, newEscapes := PoolStats()
+	if newGets <= gets {
+		t.Error("expect pool gets to advance from ", gets, ", but got ", newGets)
+	}
+	if newEscapes <= escapes {
+		t.Error("expect pool escapes to advance from ", escapes, ", but got ", newEscapes)
+	}
+}
+
 func TestBufferResize(t *testing.T) {
 	buffer := New()
 	defer buffer.Release()
@@ -149,6 +221,320 @@ func TestBufferReadFullFrom(t *testing.T) {
 	}
 }
 
+type cancelAfterRead struct {
+	io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelAfterRead) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.cancel()
+	return n, err
+}
+
+func TestBufferReadFullFromContext(t *testing.T) {
+	{
+		reader, writer := io.Pipe()
+		defer writer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		b := New()
+		defer b.Release()
+		n, err := b.ReadFullFromContext(ctx, reader, 100)
+		if err != context.Canceled {
+			t.Error("expect context canceled, but got ", err)
+		}
+		if n != 0 {
+			t.Error("expect reading 0 bytes, but actually ", n)
+		}
+	}
+
+	{
+		reader, writer := io.Pipe()
+		defer writer.Close()
+		go writer.Write([]byte("abcde"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		b := New()
+		defer b.Release()
+		n, err := b.ReadFullFromContext(ctx, &cancelAfterRead{Reader: reader, cancel: cancel}, 100)
+		if err != context.Canceled {
+			t.Error("expect context canceled, but got ", err)
+		}
+		if n != 5 || b.String() != "abcde" {
+			t.Error("unexpected partial read ", n, " ", b.String())
+		}
+	}
+
+	// Reads blocked on a stalled connection are interrupted, and the
+	// connection can be read from afterwards.
+	{
+		reader, writer := gonet.Pipe()
+		defer writer.Close()
+		go writer.Write([]byte("abc"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		b := New()
+		defer b.Release()
+		start := time.Now()
+		n, err := b.ReadFullFromContext(ctx, reader, 100)
+		if err != context.DeadlineExceeded {
+			t.Error("expect deadline exceeded, but got ", err)
+		}
+		if n != 3 || b.String() != "abc" {
+			t.Error("unexpected partial read ", n, " ", b.String())
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Error("expect the read to be interrupted, but it took ", elapsed)
+		}
+
+		go writer.Write([]byte("de"))
+		p := make([]byte, 2)
+		if _, err := io.ReadFull(reader, p); err != nil || string(p) != "de" {
+			t.Error("expect the connection to be readable after an interrupt, but got ", string(p), " ", err)
+		}
+	}
+
+	// Readers without a deadline are not closed.
+	{
+		reader, writer := io.Pipe()
+		defer writer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		b := New()
+		defer b.Release()
+		if _, err := b.ReadFullFromContext(ctx, reader, 100); err != context.Canceled {
+			t.Error("expect context canceled, but got ", err)
+		}
+
+		go writer.Write([]byte("abc"))
+		p := make([]byte, 3)
+		if _, err := io.ReadFull(reader, p); err != nil || string(p) != "abc" {
+			t.Error("expect the reader to stay open, but got ", string(p), " ", err)
+		}
+	}
+
+	{
+		b := New()
+		defer b.Release()
+		n, err := b.ReadFullFromContext(context.Background(), bytes.NewReader([]byte("abcdef")), 6)
+		common.Must(err)
+		if n != 6 || b.String() != "abcdef" {
+			t.Error("unexpected read ", n, " ", b.String())
+		}
+	}
+}
+
+func TestBufferReadAtMost(t *testing.T) {
+	payload := make([]byte, 1024)
+	common.Must2(rand.Read(payload))
+
+	{
+		reader := bytes.NewReader(payload)
+		b := New()
+		defer b.Release()
+
+		n, err := b.ReadAtMost(reader, 100)
+		common.Must(err)
+		if n != 100 {
+			t.Error("expect reading 100 bytes, but actually ", n)
+		}
+		if diff := cmp.Diff(payload[:100], b.Bytes()); diff != "" {
+			t.Error(diff)
+		}
+		if reader.Len() != 1024-100 {
+			t.Error("over read from reader: ", 1024-reader.Len())
+		}
+	}
+
+	{
+		reader := iotest.HalfReader(bytes.NewReader(payload))
+		b := New()
+		defer b.Release()
+
+		n, err := b.ReadAtMost(reader, 100)
+		common.Must(err)
+		if n != 50 {
+			t.Error("expect reading 50 bytes, but actually ", n)
+		}
+		if diff := cmp.Diff(payload[:50], b.Bytes()); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	{
+		b := New()
+		defer b.Release()
+		b.Extend(Size - 10)
+
+		n, err := b.ReadAtMost(bytes.NewReader(payload), 100)
+		common.Must(err)
+		if n != 10 {
+			t.Error("expect reading 10 bytes, but actually ", n)
+		}
+		if !b.IsFull() {
+			t.Error("expect full buffer")
+		}
+	}
+
+	{
+		b := New()
+		defer b.Release()
+
+		_, err := b.ReadAtMost(bytes.NewReader(nil), 100)
+		if err != io.EOF {
+			t.Error("expect EOF, but got ", err)
+		}
+	}
+}
+
+func TestBufferClone(t *testing.T) {
+	b := New()
+	common.Must2(b.WriteString("abcdef"))
+	b.Advance(1)
+	b.Endpoint = &net.Destination{
+		Network: net.Network_UDP,
+		Address: net.LocalHostIP,
+		Port:    net.Port(53),
+	}
+
+	clone := b.Clone()
+	defer clone.Release()
+	b.SetByte(0, 'x')
+	b.Endpoint.Port = net.Port(80)
+	b.Release()
+
+	if clone.String() != "bcdef" {
+		t.Error("unexpected clone content ", clone.String())
+	}
+	if clone.Endpoint == nil || clone.Endpoint.Port != 53 {
+		t.Error("unexpected clone endpoint ", clone.Endpoint)
+	}
+
+	source := New()
+	defer source.Release()
+	common.Must2(source.WriteString("abc"))
+	another := source.Clone()
+	defer another.Release()
+	another.SetByte(0, 'x')
+	if source.String() != "abc" {
+		t.Error("source buffer changed: ", source.String())
+	}
+}
+
+func TestBufferAppendBytes(t *testing.T) {
+	b := New()
+	defer b.Release()
+
+	n, err := b.AppendBytes([]byte("ab"), nil, []byte{}, []byte("cde"))
+	common.Must(err)
+	if n != 5 {
+		t.Error("expect writing 5 bytes, but actually ", n)
+	}
+	if b.String() != "abcde" {
+		t.Error("unexpected content ", b.String())
+	}
+
+	payload := make([]byte, Size)
+	n, err = b.AppendBytes(payload, []byte("f"))
+	if err != io.ErrShortWrite {
+		t.Error("expect short write, but got ", err)
+	}
+	if n != Size-5 {
+		t.Error("expect writing ", Size-5, " bytes, but actually ", n)
+	}
+	if !b.IsFull() {
+		t.Error("expect full buffer")
+	}
+}
+
+func TestBufferWriteBeyondSize(t *testing.T) {
+	first := make([]byte, Size/2+1024)
+	second := make([]byte, Size/2+1024)
+	common.Must2(rand.Read(first))
+	common.Must2(rand.Read(second))
+
+	b := New()
+	defer b.Release()
+
+	common.Must2(b.Write(first))
+	n, _ := b.Write(second)
+	if n != Size-len(first) {
+		t.Error("expect writing ", Size-len(first), " bytes, but actually ", n)
+	}
+
+	expected := append(append([]byte{}, first...), second[:n]...)
+	if diff := cmp.Diff(expected, b.Bytes()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+type limitedWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		p = p[:w.limit]
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestBufferWriteTo(t *testing.T) {
+	{
+		b := New()
+		defer b.Release()
+		common.Must2(b.WriteString("abcdef"))
+
+		var writer bytes.Buffer
+		n, err := b.WriteTo(&writer)
+		common.Must(err)
+		if n != 6 {
+			t.Error("expect writing 6 bytes, but actually ", n)
+		}
+		if writer.String() != "abcdef" {
+			t.Error("unexpected content ", writer.String())
+		}
+		if !b.IsEmpty() {
+			t.Error("expect empty buffer, but got ", b.Len())
+		}
+	}
+
+	{
+		b := New()
+		defer b.Release()
+		common.Must2(b.WriteString("abcdef"))
+
+		writer := &limitedWriter{limit: 4}
+		n, err := b.WriteTo(writer)
+		common.Must(err)
+		if n != 4 {
+			t.Error("expect writing 4 bytes, but actually ", n)
+		}
+		if writer.String() != "abcd" {
+			t.Error("unexpected content ", writer.String())
+		}
+		if b.String() != "ef" {
+			t.Error("unexpected remaining ", b.String())
+		}
+	}
+
+	{
+		var b *Buffer
+		n, err := b.WriteTo(&bytes.Buffer{})
+		if n != 0 || err != nil {
+			t.Error("expect no-op on nil buffer, but got ", n, err)
+		}
+	}
+}
+
 func BenchmarkNewBuffer(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		buffer := New()
@@ -195,6 +581,30 @@ func BenchmarkWrite32(b *testing.B) {
 	}
 }
 
+func BenchmarkWriteSlices8(b *testing.B) {
+	buffer := New()
+	payload := []byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 8; j++ {
+			_, _ = buffer.Write(payload)
+		}
+		buffer.Clear()
+	}
+}
+
+func BenchmarkAppendBytes8(b *testing.B) {
+	buffer := New()
+	payload := []byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.AppendBytes(payload, payload, payload, payload, payload, payload, payload, payload)
+		buffer.Clear()
+	}
+}
+
 func BenchmarkWriteByte2(b *testing.B) {
 	buffer := New()
 
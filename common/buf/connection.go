@@ -108,11 +108,12 @@ func (c *connection) Write(b []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 
-	if len(b)/Size+1 > 64*1024*1024 {
+	size := int(DefaultSize())
+	if len(b)/size+1 > 64*1024*1024 {
 		return 0, errors.New("value too large")
 	}
 	l := len(b)
-	sliceSize := l/Size + 1
+	sliceSize := l/size + 1
 	mb := make(MultiBuffer, 0, sliceSize)
 	mb = MergeBytes(mb, b)
 	return l, c.writer.WriteMultiBuffer(mb)
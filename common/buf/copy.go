@@ -1,9 +1,11 @@
 package buf
 
 import (
+	"context"
 	"io"
 	"time"
 
+	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/signal"
 )
@@ -108,6 +110,64 @@ func Copy(reader Reader, writer Writer, options ...CopyOption) error {
 	return nil
 }
 
+// CopyBytes copies from reader to writer until EOF, an error or ctx is done,
+// and returns the number of bytes copied. Unlike Copy, it reuses a single
+// Buffer for all reads, so that large transfers don't allocate for each of
+// them. Each read is at most size bytes, or 64K if size is not positive. If
+// ctx is done while a read is blocked, reader is interrupted, or closed if it
+// isn't Interruptible. It returns nil when EOF.
+func CopyBytes(ctx context.Context, reader io.Reader, writer io.Writer, size int32) (int64, error) {
+	if size <= 0 {
+		size = maxSize
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				common.Interrupt(reader)
+			case <-done:
+			}
+		}()
+	}
+
+	b := NewSize(size)
+	defer b.Release()
+	p := b.Extend(size)
+
+	var written int64
+	for {
+		n, err := reader.Read(p)
+		if n > 0 {
+			m, werr := writer.Write(p[:n])
+			written += int64(m)
+			if werr == nil && m < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				return written, writeError{werr}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				return written, nil
+			}
+			return written, readError{err}
+		}
+	}
+}
+
 var ErrNotTimeoutReader = newError("not a TimeoutReader")
 
 func CopyOnceTimeout(reader Reader, writer Writer, timeout time.Duration) error {
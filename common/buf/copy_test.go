@@ -1,11 +1,16 @@
 package buf_test
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"io"
+	gonet "net"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/testing/mocks"
@@ -53,6 +58,95 @@ func TestWriteError(t *testing.T) {
 	}
 }
 
+func TestCopyBytes(t *testing.T) {
+	payload := make([]byte, 100*1024+1)
+	common.Must2(rand.Read(payload))
+
+	var out bytes.Buffer
+	n, err := buf.CopyBytes(context.Background(), bytes.NewReader(payload), &out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) || !bytes.Equal(out.Bytes(), payload) {
+		t.Error("expected ", len(payload), " bytes copied, but got ", n)
+	}
+}
+
+func TestCopyBytesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	n, err := buf.CopyBytes(ctx, TestReader{}, io.Discard, buf.Size)
+	if err != context.Canceled || n != 0 {
+		t.Error("expected cancellation before copying, but got ", n, " bytes and ", err)
+	}
+}
+
+// readSizeRecorder records the largest read from it.
+type readSizeRecorder struct {
+	io.Reader
+	max int
+}
+
+func (r *readSizeRecorder) Read(b []byte) (int, error) {
+	if len(b) > r.max {
+		r.max = len(b)
+	}
+	return r.Reader.Read(b)
+}
+
+func TestCopyBytesSize(t *testing.T) {
+	payload := make([]byte, 10*1024)
+	common.Must2(rand.Read(payload))
+
+	reader := &readSizeRecorder{Reader: bytes.NewReader(payload)}
+	var out bytes.Buffer
+	n, err := buf.CopyBytes(context.Background(), reader, &out, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) || !bytes.Equal(out.Bytes(), payload) {
+		t.Error("expected ", len(payload), " bytes copied, but got ", n)
+	}
+	if reader.max != 1024 {
+		t.Error("expected reads of 1024 bytes, but got ", reader.max)
+	}
+}
+
+func TestCopyBytesCancelBlockedRead(t *testing.T) {
+	reader, writer := gonet.Pipe()
+	defer writer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*100, cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := buf.CopyBytes(ctx, reader, io.Discard, 0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Error("expected cancellation, but got ", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("blocked read not interrupted on cancellation")
+	}
+}
+
+func TestCopyBytesWriteError(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	mockWriter := mocks.NewWriter(mockCtl)
+	mockWriter.EXPECT().Write(gomock.Any()).Return(0, errors.New("error"))
+
+	_, err := buf.CopyBytes(context.Background(), rand.Reader, mockWriter, 0)
+	if !buf.IsWriteError(err) {
+		t.Error("expected to be WriteError, but got ", err)
+	}
+}
+
 type TestReader struct{}
 
 func (TestReader) Read(b []byte) (int, error) {
@@ -68,3 +162,23 @@ func BenchmarkCopy(b *testing.B) {
 		_ = buf.Copy(reader, writer)
 	}
 }
+
+const largeTransferSize = 16 * 1024 * 1024
+
+func BenchmarkCopyLargeTransfer(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(largeTransferSize)
+	for i := 0; i < b.N; i++ {
+		reader := buf.NewReader(io.LimitReader(TestReader{}, largeTransferSize))
+		_ = buf.Copy(reader, buf.NewWriter(io.Discard))
+	}
+}
+
+func BenchmarkCopyBytesLargeTransfer(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(largeTransferSize)
+	for i := 0; i < b.N; i++ {
+		reader := io.LimitReader(TestReader{}, largeTransferSize)
+		_, _ = buf.CopyBytes(context.Background(), reader, io.Discard, 0)
+	}
+}
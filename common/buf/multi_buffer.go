@@ -80,7 +80,7 @@ func ReadFrom(reader io.Reader) (MultiBuffer, error) {
 	mb := make(MultiBuffer, 0, 16)
 	for {
 		b := New()
-		_, err := b.ReadFullFrom(reader, Size)
+		_, err := b.ReadFullFrom(reader, DefaultSize())
 		if b.IsEmpty() {
 			b.Release()
 		} else {
@@ -144,7 +144,7 @@ func Compact(mb MultiBuffer) MultiBuffer {
 
 	for i := 1; i < len(mb); i++ {
 		curr := mb[i]
-		if last.Len()+curr.Len() > Size {
+		if curr.Len() > int32(len(last.v))-last.end {
 			mb2 = append(mb2, last)
 			last = curr
 		} else {
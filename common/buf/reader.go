@@ -201,6 +201,46 @@ func (r *SingleReader) ReadMultiBuffer() (MultiBuffer, error) {
 	return MultiBuffer{b}, err
 }
 
+// SizedReader is a Reader that reads at most Remaining bytes from the
+// underlying reader, one Buffer every time, and never reads past them. It
+// returns io.EOF once all bytes are read, or io.ErrUnexpectedEOF if the
+// underlying reader ends before.
+type SizedReader struct {
+	io.Reader
+	// Remaining is the number of bytes still allowed to read.
+	Remaining int64
+}
+
+// Upstream implements ReaderWrapper.
+func (r *SizedReader) Upstream() io.Reader {
+	return r.Reader
+}
+
+// ReadMultiBuffer implements Reader.
+func (r *SizedReader) ReadMultiBuffer() (MultiBuffer, error) {
+	if r.Remaining <= 0 {
+		return nil, io.EOF
+	}
+	size := int64(DefaultSize())
+	if r.Remaining < size {
+		size = r.Remaining
+	}
+
+	b := New()
+	n, err := b.ReadAtMost(r.Reader, int32(size))
+	r.Remaining -= n
+	if err == io.EOF && r.Remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	} else if err == io.EOF {
+		err = nil
+	}
+	if b.IsEmpty() {
+		b.Release()
+		return nil, err
+	}
+	return MultiBuffer{b}, err
+}
+
 // PacketReader is a Reader that read one Buffer every time.
 type PacketReader struct {
 	io.Reader
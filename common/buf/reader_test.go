@@ -109,6 +109,46 @@ func TestReadAtMost(t *testing.T) {
 	}
 }
 
+func TestSizedReader(t *testing.T) {
+	underlying := strings.NewReader(strings.Repeat("a", Size) + "bcdefg")
+	reader := &SizedReader{Reader: underlying, Remaining: Size + 3}
+
+	var out bytes.Buffer
+	common.Must(Copy(reader, NewWriter(&out)))
+	if out.String() != strings.Repeat("a", Size)+"bcd" {
+		t.Error("expected ", Size+3, " bytes, but got ", out.Len())
+	}
+
+	if mb, err := reader.ReadMultiBuffer(); err != io.EOF || !mb.IsEmpty() {
+		t.Error("expected EOF after the limit, but got ", mb, err)
+	}
+
+	// The bytes beyond the limit are left in the underlying reader.
+	rest, err := io.ReadAll(underlying)
+	common.Must(err)
+	if string(rest) != "efg" {
+		t.Error("expected the rest efg, but got ", string(rest))
+	}
+}
+
+func TestSizedReaderUnexpectedEOF(t *testing.T) {
+	reader := &SizedReader{Reader: strings.NewReader("abc"), Remaining: 5}
+
+	mb, err := reader.ReadMultiBuffer()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if s := mb.String(); s != "abc" {
+		t.Error("expected abc, but got ", s)
+	}
+	if err == nil {
+		_, err = reader.ReadMultiBuffer()
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF, but got ", err)
+	}
+}
+
 func TestPacketReader_ReadMultiBuffer(t *testing.T) {
 	const alpha = "abcefg"
 	buf := bytes.NewBufferString(alpha)
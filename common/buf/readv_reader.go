@@ -98,8 +98,8 @@ func (r *ReadVReader) readMulti() (MultiBuffer, error) {
 			break
 		}
 		end := nBytes
-		if end > Size {
-			end = Size
+		if size := int32(len(bs[nBuf].v)); end > size {
+			end = size
 		}
 		bs[nBuf].end = end
 		nBytes -= end
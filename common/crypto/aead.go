@@ -0,0 +1,62 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"io"
+
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// GenerateIncreasingNonceBigEndian returns a BytesGenerator that increases
+// nonce as a big endian counter before returning it, unlike
+// GenerateIncreasingNonce that counts in little endian.
+func GenerateIncreasingNonceBigEndian(nonce []byte) BytesGenerator {
+	c := append([]byte(nil), nonce...)
+	return func() []byte {
+		for i := len(c) - 1; i >= 0; i-- {
+			c[i]++
+			if c[i] != 0 {
+				break
+			}
+		}
+		return c
+	}
+}
+
+// NewChaCha20Poly1305Authenticator creates an AEADAuthenticator of
+// ChaCha20-Poly1305 with the given 32-byte key, taking the nonce of each chunk
+// from nonce.
+func NewChaCha20Poly1305Authenticator(key []byte, nonce BytesGenerator) (*AEADAuthenticator, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, newError("failed to create ChaCha20-Poly1305 cipher").Base(err)
+	}
+	return &AEADAuthenticator{
+		AEAD:                    aead,
+		NonceGenerator:          nonce,
+		AdditionalDataGenerator: GenerateEmptyBytes(),
+	}, nil
+}
+
+// NewAEADReader creates a reader of a stream of chunks sealed by aead, each
+// prefixed by its plain 2-byte size. nonce generates the nonce of each chunk.
+func NewAEADReader(aead cipher.AEAD, nonce BytesGenerator, reader io.Reader) *AuthenticationReader {
+	return NewAuthenticationReader(&AEADAuthenticator{
+		AEAD:                    aead,
+		NonceGenerator:          nonce,
+		AdditionalDataGenerator: GenerateEmptyBytes(),
+	}, PlainChunkSizeParser{}, reader, protocol.TransferTypeStream, nil)
+}
+
+// NewAEADWriter creates a writer of a stream read by NewAEADReader. Data is
+// split into chunks of at most chunkSize bytes, including the size and the
+// overhead of aead. chunkSize is capped by buf.Size, and must be larger than
+// the size and the overhead.
+func NewAEADWriter(aead cipher.AEAD, nonce BytesGenerator, writer io.Writer, chunkSize int32) *AuthenticationWriter {
+	return NewLimitedAuthenticationWriter(&AEADAuthenticator{
+		AEAD:                    aead,
+		NonceGenerator:          nonce,
+		AdditionalDataGenerator: GenerateEmptyBytes(),
+	}, PlainChunkSizeParser{}, writer, protocol.TransferTypeStream, nil, chunkSize)
+}
@@ -0,0 +1,118 @@
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	. "github.com/v2fly/v2ray-core/v5/common/crypto"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestChaCha20Poly1305Vector(t *testing.T) {
+	// RFC 8439, section 2.8.2.
+	key := mustDecodeHex("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce := mustDecodeHex("070000004041424344454647")
+	auth, err := NewChaCha20Poly1305Authenticator(key, GenerateStaticBytes(nonce))
+	common.Must(err)
+	auth.AdditionalDataGenerator = GenerateStaticBytes(mustDecodeHex("50515253c0c1c2c3c4c5c6c7"))
+
+	plainText := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	cipherText, err := auth.Seal(nil, plainText)
+	common.Must(err)
+	expected := mustDecodeHex("d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b61161ae10b594f09e26a7e902ecbd0600691")
+	if diff := cmp.Diff(expected, cipherText); diff != "" {
+		t.Error(diff)
+	}
+
+	opened, err := auth.Open(nil, cipherText)
+	common.Must(err)
+	if diff := cmp.Diff(plainText, opened); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGenerateIncreasingNonceBigEndian(t *testing.T) {
+	generator := GenerateIncreasingNonceBigEndian([]byte{0, 0, 0xFE})
+	for _, expected := range [][]byte{{0, 0, 0xFF}, {0, 1, 0}, {0, 1, 1}} {
+		if diff := cmp.Diff(expected, generator()); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	generator = GenerateIncreasingNonce([]byte{0xFE, 0, 0})
+	for _, expected := range [][]byte{{0xFF, 0, 0}, {0, 1, 0}, {1, 1, 0}} {
+		if diff := cmp.Diff(expected, generator()); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestAEADWriterVector(t *testing.T) {
+	key := mustDecodeHex("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	aead, err := chacha20poly1305.New(key)
+	common.Must(err)
+
+	var stream bytes.Buffer
+	writer := NewAEADWriter(aead, GenerateIncreasingNonceBigEndian(GenerateAEADNonceWithSize(12)()), &stream, buf.Size)
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, []byte("hello"))))
+
+	// The first chunk has size 5+16, sealed with the nonce of counter 1.
+	nonce := make([]byte, 12)
+	nonce[11] = 1
+	expected := append([]byte{0, 21}, aead.Seal(nil, nonce, []byte("hello"), nil)...)
+	if diff := cmp.Diff(expected, stream.Bytes()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAEADReaderWriterOverPipe(t *testing.T) {
+	key := make([]byte, chacha20poly1305.KeySize)
+	common.Must2(rand.Read(key))
+	aead, err := chacha20poly1305.New(key)
+	common.Must(err)
+
+	payload := make([]byte, 100*1024)
+	common.Must2(rand.Read(payload))
+
+	for _, chunkSize := range []int32{64, 1000, buf.Size} {
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			writer := NewAEADWriter(aead, GenerateIncreasingNonceBigEndian(make([]byte, 12)), pipeWriter, chunkSize)
+			// Write in pieces that don't align with the chunks.
+			for rest, n := payload, 1; len(rest) > 0; n = n*3 + 1 {
+				if n > len(rest) {
+					n = len(rest)
+				}
+				common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, rest[:n])))
+				rest = rest[n:]
+			}
+			common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{}))
+			pipeWriter.Close()
+		}()
+
+		reader := NewAEADReader(aead, GenerateIncreasingNonceBigEndian(make([]byte, 12)), pipeReader)
+		var received []byte
+		for {
+			mb, err := reader.ReadMultiBuffer()
+			if err == io.EOF {
+				break
+			}
+			common.Must(err)
+			for _, b := range mb {
+				if b.Len() > chunkSize {
+					t.Error("chunk larger than ", chunkSize, ": ", b.Len())
+				}
+			}
+			received = append(received, mb.String()...)
+			buf.ReleaseMulti(mb)
+		}
+		if !bytes.Equal(received, payload) {
+			t.Error("payload mismatch with chunk size ", chunkSize)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/cipher"
+	"encoding/binary"
 	"io"
 	"math"
 	"math/rand"
@@ -91,6 +92,34 @@ func (v *AEADAuthenticator) Seal(dst, plainText []byte) ([]byte, error) {
 	return v.AEAD.Seal(dst, iv, plainText, additionalData), nil
 }
 
+// framePaddingIndicatorSize is the size of the padding length at the start
+// of the chunks padded by FramePadding.
+const framePaddingIndicatorSize = 2
+
+// FramePadding is a PaddingLengthGenerator of random lengths between Min and
+// Max, inclusive. Unlike other generators, the padding is sealed within each
+// chunk after the payload, and the chunk starts with the length of the
+// padding, so that the reader doesn't need to predict it. A reader of such
+// chunks is created with a FramePadding as well, of which the range is unused.
+type FramePadding struct {
+	Min uint16
+	Max uint16
+}
+
+// MaxPaddingLen implements PaddingLengthGenerator. It includes the indicator
+// of the padding length.
+func (p *FramePadding) MaxPaddingLen() uint16 {
+	return p.Max + framePaddingIndicatorSize
+}
+
+// NextPaddingLen implements PaddingLengthGenerator.
+func (p *FramePadding) NextPaddingLen() uint16 {
+	if p.Max <= p.Min {
+		return p.Min
+	}
+	return p.Min + uint16(rand.Intn(int(p.Max-p.Min)+1))
+}
+
 type AuthenticationReader struct {
 	auth         Authenticator
 	reader       *buf.BufferedReader
@@ -98,6 +127,7 @@ type AuthenticationReader struct {
 	sizeBytes    []byte
 	transferType protocol.TransferType
 	padding      PaddingLengthGenerator
+	framePadding bool
 	size         uint16
 	paddingLen   uint16
 	hasSize      bool
@@ -112,6 +142,10 @@ func NewAuthenticationReader(auth Authenticator, sizeParser ChunkSizeDecoder, re
 		padding:      paddingLen,
 		sizeBytes:    make([]byte, sizeParser.SizeBytes()),
 	}
+	if _, ok := paddingLen.(*FramePadding); ok {
+		r.padding = nil
+		r.framePadding = true
+	}
 	if breader, ok := reader.(*buf.BufferedReader); ok {
 		r.reader = breader
 	} else {
@@ -151,6 +185,18 @@ func (r *AuthenticationReader) readBuffer(size int32, padding int32) (*buf.Buffe
 		return nil, err
 	}
 	b.Resize(0, int32(len(rb)))
+	if r.framePadding {
+		if b.Len() < framePaddingIndicatorSize {
+			b.Release()
+			return nil, newError("invalid chunk size: ", b.Len())
+		}
+		paddingLen := int32(binary.BigEndian.Uint16(b.BytesTo(framePaddingIndicatorSize)))
+		if paddingLen > b.Len()-framePaddingIndicatorSize {
+			b.Release()
+			return nil, newError("invalid padding size: ", paddingLen)
+		}
+		b.Resize(framePaddingIndicatorSize, b.Len()-paddingLen)
+	}
 	return b, nil
 }
 
@@ -168,7 +214,7 @@ func (r *AuthenticationReader) readInternal(soft bool, mb *buf.MultiBuffer) erro
 		return err
 	}
 
-	if size == uint16(r.auth.Overhead())+padding {
+	if !r.framePadding && size == uint16(r.auth.Overhead())+padding {
 		r.done = true
 		return io.EOF
 	}
@@ -184,6 +230,12 @@ func (r *AuthenticationReader) readInternal(soft bool, mb *buf.MultiBuffer) erro
 	if err != nil {
 		return err
 	}
+	if r.framePadding && b.IsEmpty() {
+		// The padded chunk without payload signals the end of stream.
+		b.Release()
+		r.done = true
+		return io.EOF
+	}
 	*mb = append(*mb, b)
 	return nil
 }
@@ -216,6 +268,7 @@ type AuthenticationWriter struct {
 	sizeParser    ChunkSizeEncoder
 	transferType  protocol.TransferType
 	padding       PaddingLengthGenerator
+	framePadding  *FramePadding
 	maxBufferSize int32
 }
 
@@ -231,13 +284,19 @@ func NewLimitedAuthenticationWriter(auth Authenticator, sizeParser ChunkSizeEnco
 		transferType:  transferType,
 		maxBufferSize: maxBufferSize,
 	}
-	if padding != nil {
+	if framePadding, ok := padding.(*FramePadding); ok {
+		w.framePadding = framePadding
+	} else if padding != nil {
 		w.padding = padding
 	}
 	return w
 }
 
 func (w *AuthenticationWriter) seal(b []byte) (*buf.Buffer, error) {
+	if w.framePadding != nil {
+		return w.sealWithFramePadding(b)
+	}
+
 	encryptedSize := int32(len(b) + w.auth.Overhead())
 	var paddingSize int32
 	if w.padding != nil {
@@ -250,7 +309,7 @@ func (w *AuthenticationWriter) seal(b []byte) (*buf.Buffer, error) {
 		return nil, newError("size too large: ", totalSize)
 	}
 
-	eb := buf.New()
+	eb := buf.NewSize(totalSize)
 	w.sizeParser.Encode(uint16(encryptedSize+paddingSize), eb.Extend(sizeBytes))
 	if _, err := w.auth.Seal(eb.Extend(encryptedSize)[:0], b); err != nil {
 		eb.Release()
@@ -265,11 +324,43 @@ func (w *AuthenticationWriter) seal(b []byte) (*buf.Buffer, error) {
 	return eb, nil
 }
 
+func (w *AuthenticationWriter) sealWithFramePadding(b []byte) (*buf.Buffer, error) {
+	sizeBytes := w.sizeParser.SizeBytes()
+	plainSize := framePaddingIndicatorSize + int32(len(b))
+	paddingSize := int32(w.framePadding.NextPaddingLen())
+	// Packets may be too large to be padded as much as desired.
+	if available := buf.Size - sizeBytes - int32(w.auth.Overhead()) - plainSize; paddingSize > available {
+		if available < 0 {
+			return nil, newError("size too large: ", len(b))
+		}
+		paddingSize = available
+	}
+	plainSize += paddingSize
+
+	plain := buf.NewSize(plainSize)
+	defer plain.Release()
+	binary.BigEndian.PutUint16(plain.Extend(framePaddingIndicatorSize), uint16(paddingSize))
+	common.Must2(plain.Write(b))
+	common.Must2(rand.Read(plain.Extend(paddingSize)))
+
+	encryptedSize := plainSize + int32(w.auth.Overhead())
+	eb := buf.NewSize(sizeBytes + encryptedSize)
+	w.sizeParser.Encode(uint16(encryptedSize), eb.Extend(sizeBytes))
+	if _, err := w.auth.Seal(eb.Extend(encryptedSize)[:0], plain.Bytes()); err != nil {
+		eb.Release()
+		return nil, err
+	}
+
+	return eb, nil
+}
+
 func (w *AuthenticationWriter) writeStream(mb buf.MultiBuffer) error {
 	defer buf.ReleaseMulti(mb)
 
 	var maxPadding int32
-	if w.padding != nil {
+	if w.framePadding != nil {
+		maxPadding = int32(w.framePadding.MaxPaddingLen())
+	} else if w.padding != nil {
 		maxPadding = int32(w.padding.MaxPaddingLen())
 	}
 
@@ -284,7 +375,7 @@ func (w *AuthenticationWriter) writeStream(mb buf.MultiBuffer) error {
 	sliceSize := len(mb) + 10
 	mb2Write := make(buf.MultiBuffer, 0, sliceSize)
 
-	temp := buf.New()
+	temp := buf.NewSize(payloadSize)
 	defer temp.Release()
 
 	rawBytes := temp.Extend(payloadSize)
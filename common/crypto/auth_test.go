@@ -140,3 +140,117 @@ func TestAuthenticationReaderWriterPacket(t *testing.T) {
 		t.Error("error: ", err)
 	}
 }
+
+func newTestAuthenticator(key []byte, iv []byte) *AEADAuthenticator {
+	block, err := aes.NewCipher(key)
+	common.Must(err)
+	aead, err := cipher.NewGCM(block)
+	common.Must(err)
+	return &AEADAuthenticator{
+		AEAD:                    aead,
+		NonceGenerator:          GenerateIncreasingNonce(iv),
+		AdditionalDataGenerator: GenerateEmptyBytes(),
+	}
+}
+
+func TestAuthenticationReaderWriterFramePadding(t *testing.T) {
+	key := make([]byte, 16)
+	common.Must2(rand.Read(key))
+	iv := make([]byte, 12)
+	common.Must2(rand.Read(iv))
+
+	const payloadSize = 1024 * 80
+	rawPayload := make([]byte, payloadSize)
+	common.Must2(rand.Read(rawPayload))
+
+	cache := bytes.NewBuffer(nil)
+	writer := NewAuthenticationWriter(newTestAuthenticator(key, iv), PlainChunkSizeParser{}, cache, protocol.TransferTypeStream, &FramePadding{Min: 100, Max: 1024})
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, rawPayload)))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{}))
+
+	// The range of the padding is only known to the writer.
+	reader := NewAuthenticationReader(newTestAuthenticator(key, iv), PlainChunkSizeParser{}, cache, protocol.TransferTypeStream, new(FramePadding))
+
+	var mb buf.MultiBuffer
+	for mb.Len() < payloadSize {
+		mb2, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		mb, _ = buf.MergeMulti(mb, mb2)
+	}
+
+	payload := make([]byte, payloadSize)
+	mb, _ = buf.SplitBytes(mb, payload)
+	buf.ReleaseMulti(mb)
+	if r := cmp.Diff(payload, rawPayload); r != "" {
+		t.Error(r)
+	}
+
+	_, err := reader.ReadMultiBuffer()
+	if err != io.EOF {
+		t.Error("error: ", err)
+	}
+}
+
+func TestFramePaddingLengths(t *testing.T) {
+	key := make([]byte, 16)
+	common.Must2(rand.Read(key))
+	iv := make([]byte, 12)
+	common.Must2(rand.Read(iv))
+
+	const (
+		packets     = 256
+		packetSize  = 100
+		minPadding  = 16
+		maxPadding  = 256
+		overhead    = 16 + 2 // The tag of GCM and the length of padding.
+		chunkLength = 2
+	)
+	cache := bytes.NewBuffer(nil)
+	writer := NewAuthenticationWriter(newTestAuthenticator(key, iv), PlainChunkSizeParser{}, cache, protocol.TransferTypePacket, &FramePadding{Min: minPadding, Max: maxPadding})
+	for i := 0; i < packets; i++ {
+		b := buf.New()
+		b.Extend(packetSize)
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+	}
+	// The padding is truncated for the largest packets.
+	b := buf.New()
+	b.Extend(buf.Size - overhead - chunkLength)
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+
+	chunks := bytes.NewReader(cache.Bytes())
+	lengths := make(map[int]int)
+	for i := 0; i < packets; i++ {
+		var size [chunkLength]byte
+		common.Must2(io.ReadFull(chunks, size[:]))
+		length := int(size[0])<<8 | int(size[1])
+		if length < packetSize+overhead+minPadding || length > packetSize+overhead+maxPadding {
+			t.Fatal("chunk length out of range: ", length)
+		}
+		lengths[length]++
+		common.Must2(chunks.Seek(int64(length), io.SeekCurrent))
+	}
+	// The chance of fewer distinct lengths when uniformly drawing 256 out of 241 is negligible.
+	if len(lengths) < 64 {
+		t.Error("expected randomized chunk lengths, but got ", len(lengths), " distinct lengths")
+	}
+
+	reader := NewAuthenticationReader(newTestAuthenticator(key, iv), PlainChunkSizeParser{}, cache, protocol.TransferTypePacket, new(FramePadding))
+	var received []int32
+	for len(received) <= packets {
+		mb, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		for _, b := range mb {
+			received = append(received, b.Len())
+		}
+		buf.ReleaseMulti(mb)
+	}
+	for i, size := range received {
+		expected := int32(packetSize)
+		if i == packets {
+			expected = buf.Size - overhead - chunkLength
+		}
+		if size != expected {
+			t.Error("packet ", i, ": expected size ", expected, ", but got ", size)
+		}
+	}
+}
@@ -22,7 +22,6 @@ type hasSeverity interface {
 // Error is an error object with underlying error.
 type Error struct {
 	pathObj  interface{}
-	prefix   []interface{}
 	message  []interface{}
 	inner    error
 	severity log.Severity
@@ -46,21 +45,22 @@ func (err *Error) pkgPath() string {
 
 // Error implements error.Error().
 func (err *Error) Error() string {
-	builder := strings.Builder{}
-	for _, prefix := range err.prefix {
-		builder.WriteByte('[')
-		builder.WriteString(serial.ToString(prefix))
-		builder.WriteString("] ")
-	}
-
 	path := err.pkgPath()
 	if len(path) > 0 {
-		builder.WriteString(path)
-		builder.WriteString(": ")
+		return path + ": " + err.Message()
 	}
+	return err.Message()
+}
 
-	msg := serial.Concat(err.message...)
-	builder.WriteString(msg)
+// Source returns the package the error comes from, relative to the module.
+func (err *Error) Source() string {
+	return err.pkgPath()
+}
+
+// Message returns the message of the error, followed by the inner errors.
+func (err *Error) Message() string {
+	builder := strings.Builder{}
+	builder.WriteString(serial.Concat(err.message...))
 
 	if err.inner != nil {
 		builder.WriteString(" > ")
@@ -136,18 +136,17 @@ func (err *Error) WriteToLog(opts ...ExportOption) {
 		opt(&holder)
 	}
 
-	if holder.SessionID > 0 {
-		err.prefix = append(err.prefix, holder.SessionID)
-	}
-
 	log.Record(&log.GeneralMessage{
-		Severity: GetSeverity(err),
-		Content:  err,
+		Severity:   GetSeverity(err),
+		Content:    err,
+		SessionID:  holder.SessionID,
+		Connection: holder.Connection,
 	})
 }
 
 type ExportOptionHolder struct {
-	SessionID uint32
+	SessionID  uint32
+	Connection *log.ConnectionContext
 }
 
 type ExportOption func(*ExportOptionHolder)
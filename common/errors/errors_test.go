@@ -1,13 +1,17 @@
 package errors_test
 
 import (
+	"context"
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	. "github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/log"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
 )
 
 func TestError(t *testing.T) {
@@ -59,3 +63,43 @@ func TestErrorMessage(t *testing.T) {
 		}
 	}
 }
+
+type recordingHandler struct {
+	messages []log.Message
+}
+
+func (h *recordingHandler) Handle(msg log.Message) {
+	h.messages = append(h.messages, msg)
+}
+
+func TestErrorWithContext(t *testing.T) {
+	handler := &recordingHandler{}
+	log.RegisterHandler(handler)
+
+	ctx := session.ContextWithID(context.Background(), 42)
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{
+		Tag:    "socks-in",
+		Source: net.TCPDestination(net.LocalHostIP, 10800),
+	})
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 443),
+	})
+	New("failed to dial").WithPathObj(e{}).WriteToLog(session.ExportContextToError(ctx))
+
+	if len(handler.messages) != 1 {
+		t.Fatal("expected 1 message, but got ", len(handler.messages))
+	}
+	if diff := cmp.Diff("[Info] [42] [socks-in tcp:127.0.0.1:10800 -> tcp:example.com:443] common/errors_test: failed to dial", handler.messages[0].String()); diff != "" {
+		t.Error(diff)
+	}
+
+	line, err := log.MarshalJSON(handler.messages[0], time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"inbound":"socks-in"`, `"to":"tcp:example.com:443"`, `"session":42`} {
+		if !strings.Contains(string(line), field) {
+			t.Error("expected ", field, " in ", string(line))
+		}
+	}
+}
@@ -27,6 +27,8 @@ type AccessMessage struct {
 	Reason interface{}
 	Email  string
 	Detour string
+	// SessionID is the ID of the connection, or zero.
+	SessionID uint32
 }
 
 func (m *AccessMessage) String() string {
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+)
+
+// sourcedContent is implemented by the contents that know the module they
+// come from, such as errors.
+type sourcedContent interface {
+	Source() string
+	Message() string
+}
+
+type jsonGeneralMessage struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	SessionID uint32 `json:"session,omitempty"`
+	Inbound   string `json:"inbound,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Message   string `json:"message"`
+}
+
+type jsonAccessMessage struct {
+	Time      string `json:"time"`
+	SessionID uint32 `json:"session,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Status    string `json:"status"`
+	Detour    string `json:"detour,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Email     string `json:"email,omitempty"`
+}
+
+// MarshalJSON encodes msg recorded at t as a JSON object. General messages
+// have the fields time, level, session, inbound, from, to, source and
+// message, where inbound, from and to describe the connection, and access
+// messages have time, session, from, to, status, detour, reason and email.
+// The session is the ID of the connection, which correlates the messages
+// about it, and is omitted if unknown.
+func MarshalJSON(msg Message, t time.Time) ([]byte, error) {
+	timestamp := t.Format(time.RFC3339Nano)
+	switch msg := msg.(type) {
+	case *GeneralMessage:
+		m := jsonGeneralMessage{
+			Time:      timestamp,
+			Level:     strings.ToLower(msg.Severity.String()),
+			SessionID: msg.SessionID,
+		}
+		if c := msg.Connection; c != nil {
+			m.Inbound = c.InboundTag
+			m.From = c.Source
+			m.To = c.Destination
+		}
+		if content, ok := msg.Content.(sourcedContent); ok {
+			m.Source = content.Source()
+			m.Message = content.Message()
+		} else {
+			m.Message = serial.ToString(msg.Content)
+		}
+		return json.Marshal(m)
+	case *AccessMessage:
+		return json.Marshal(jsonAccessMessage{
+			Time:      timestamp,
+			SessionID: msg.SessionID,
+			From:      serial.ToString(msg.From),
+			To:        serial.ToString(msg.To),
+			Status:    string(msg.Status),
+			Detour:    msg.Detour,
+			Reason:    serial.ToString(msg.Reason),
+			Email:     msg.Email,
+		})
+	default:
+		return json.Marshal(jsonGeneralMessage{
+			Time:    timestamp,
+			Message: msg.String(),
+		})
+	}
+}
+
+func formatJSON(msg Message, t time.Time) string {
+	b, err := MarshalJSON(msg, t)
+	if err != nil {
+		return msg.String()
+	}
+	return string(b)
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/v2fly/v2ray-core/v5/common/serial"
@@ -22,15 +23,66 @@ type Follower interface {
 	RemoveFollower(func(msg Message))
 }
 
+// HistoryFollower is a Follower that keeps recent messages.
+type HistoryFollower interface {
+	Follower
+	// AddFollowerWithHistory passes up to n recent messages to a follower,
+	// oldest first, and adds it. The follower gets no new message before the
+	// history.
+	AddFollowerWithHistory(f func(msg Message), n int)
+}
+
 // GeneralMessage is a general log message that can contain all kind of content.
 type GeneralMessage struct {
 	Severity Severity
 	Content  interface{}
+	// SessionID is the ID of the connection the message is about, or zero.
+	SessionID uint32
+	// Connection describes the connection the message is about, or is nil.
+	Connection *ConnectionContext
 }
 
 // String implements Message.
 func (m *GeneralMessage) String() string {
-	return serial.Concat("[", m.Severity, "] ", m.Content)
+	builder := strings.Builder{}
+	builder.WriteString(serial.Concat("[", m.Severity, "] "))
+	if m.SessionID > 0 {
+		builder.WriteString(serial.Concat("[", m.SessionID, "] "))
+	}
+	if m.Connection != nil {
+		builder.WriteString(serial.Concat("[", m.Connection, "] "))
+	}
+	builder.WriteString(serial.ToString(m.Content))
+	return builder.String()
+}
+
+// ConnectionContext is the context of a connection that is logged with the
+// messages about it.
+type ConnectionContext struct {
+	InboundTag  string
+	Source      string
+	Destination string
+}
+
+func (c *ConnectionContext) String() string {
+	builder := strings.Builder{}
+	for _, part := range []string{c.InboundTag, c.Source} {
+		if part == "" {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(part)
+	}
+	if c.Destination != "" {
+		if builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString("-> ")
+		builder.WriteString(c.Destination)
+	}
+	return builder.String()
 }
 
 // Record writes a message into log stream.
@@ -30,3 +30,20 @@ func TestLogRecord(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestConnectionContextString(t *testing.T) {
+	for _, tc := range []struct {
+		context  log.ConnectionContext
+		expected string
+	}{
+		{log.ConnectionContext{InboundTag: "in", Source: "tcp:1.2.3.4:5", Destination: "tcp:example.com:443"}, "in tcp:1.2.3.4:5 -> tcp:example.com:443"},
+		{log.ConnectionContext{InboundTag: "in", Destination: "tcp:example.com:443"}, "in -> tcp:example.com:443"},
+		{log.ConnectionContext{Source: "tcp:1.2.3.4:5"}, "tcp:1.2.3.4:5"},
+		{log.ConnectionContext{InboundTag: "in"}, "in"},
+		{log.ConnectionContext{Destination: "tcp:example.com:443"}, "-> tcp:example.com:443"},
+	} {
+		if diff := cmp.Diff(tc.expected, tc.context.String()); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
@@ -22,6 +22,7 @@ type WriterCreator func() Writer
 
 type generalLogger struct {
 	creator WriterCreator
+	format  func(Message, time.Time) string
 	buffer  chan Message
 	access  *semaphore.Instance
 	done    *done.Instance
@@ -29,14 +30,29 @@ type generalLogger struct {
 
 // NewLogger returns a generic log handler that can handle all type of messages.
 func NewLogger(logWriterCreator WriterCreator) Handler {
+	return newLogger(logWriterCreator, formatText)
+}
+
+// NewJSONLogger returns a log handler that writes each message as a line of
+// JSON, see MarshalJSON.
+func NewJSONLogger(logWriterCreator WriterCreator) Handler {
+	return newLogger(logWriterCreator, formatJSON)
+}
+
+func newLogger(logWriterCreator WriterCreator, format func(Message, time.Time) string) Handler {
 	return &generalLogger{
 		creator: logWriterCreator,
+		format:  format,
 		buffer:  make(chan Message, 16),
 		access:  semaphore.New(1),
 		done:    done.New(),
 	}
 }
 
+func formatText(msg Message, t time.Time) string {
+	return t.Format("2006/01/02 15:04:05 ") + msg.String()
+}
+
 func (l *generalLogger) run() {
 	defer l.access.Signal()
 
@@ -55,7 +71,7 @@ func (l *generalLogger) run() {
 		case <-l.done.Wait():
 			return
 		case msg := <-l.buffer:
-			logger.Write(msg.String() + platform.LineSeparator())
+			logger.Write(l.format(msg, time.Now()) + platform.LineSeparator())
 			dataWritten = true
 		case <-ticker.C:
 			if !dataWritten {
@@ -114,7 +130,7 @@ func (w *fileLogWriter) Close() error {
 func CreateStdoutLogWriter() WriterCreator {
 	return func() Writer {
 		return &consoleLogWriter{
-			logger: log.New(os.Stdout, "", log.Ldate|log.Ltime),
+			logger: log.New(os.Stdout, "", 0),
 		}
 	}
 }
@@ -123,7 +139,7 @@ func CreateStdoutLogWriter() WriterCreator {
 func CreateStderrLogWriter() WriterCreator {
 	return func() Writer {
 		return &consoleLogWriter{
-			logger: log.New(os.Stderr, "", log.Ldate|log.Ltime),
+			logger: log.New(os.Stderr, "", 0),
 		}
 	}
 }
@@ -142,7 +158,7 @@ func CreateFileLogWriter(path string) (WriterCreator, error) {
 		}
 		return &fileLogWriter{
 			file:   file,
-			logger: log.New(file, "", log.Ldate|log.Ltime),
+			logger: log.New(file, "", 0),
 		}
 	}, nil
 }
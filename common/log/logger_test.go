@@ -1,6 +1,7 @@
 package log_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	. "github.com/v2fly/v2ray-core/v5/common/log"
 )
 
@@ -37,3 +39,46 @@ func TestFileLogger(t *testing.T) {
 		t.Fatal("Expect log text contains 'Test Log', but actually: ", string(b))
 	}
 }
+
+func TestJSONLogger(t *testing.T) {
+	f, err := ioutil.TempFile("", "vtest")
+	common.Must(err)
+	path := f.Name()
+	common.Must(f.Close())
+
+	creator, err := CreateFileLogWriter(path)
+	common.Must(err)
+
+	handler := NewJSONLogger(creator)
+	handler.Handle(&GeneralMessage{Severity: Severity_Info, Content: errors.New("Test Log"), SessionID: 7})
+	handler.Handle(&AccessMessage{From: "127.0.0.1:1080", To: "tcp:example.com:443", Status: AccessAccepted, SessionID: 7})
+	time.Sleep(2 * time.Second)
+
+	common.Must(common.Close(handler))
+
+	f, err = os.Open(path)
+	common.Must(err)
+	defer f.Close()
+
+	b, err := buf.ReadAllToBytes(f)
+	common.Must(err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected 2 lines, but actually: ", string(b))
+	}
+	for _, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &m); err != nil {
+			t.Fatal("invalid JSON line: ", line, " ", err)
+		}
+		if m["session"] != float64(7) {
+			t.Error("expected session 7 in ", line)
+		}
+	}
+	if !strings.Contains(lines[0], `"message":"Test Log"`) || !strings.Contains(lines[0], `"level":"info"`) {
+		t.Error("unexpected general message: ", lines[0])
+	}
+	if !strings.Contains(lines[1], `"status":"accepted"`) {
+		t.Error("unexpected access message: ", lines[1])
+	}
+}
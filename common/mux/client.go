@@ -14,6 +14,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal/done"
 	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/common/xudp"
 	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/transport"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
@@ -53,6 +54,11 @@ func (m *ClientManager) DispatchSync(ctx context.Context, link *transport.Link)
 	return newError("unable to find an available mux client").AtWarning()
 }
 
+// Close implements common.Closable. It closes the workers of the picker.
+func (m *ClientManager) Close() error {
+	return common.Close(m.Picker)
+}
+
 type WorkerPicker interface {
 	PickAvailable() (*ClientWorker, error)
 }
@@ -63,6 +69,24 @@ type IncrementalWorkerPicker struct {
 	access      sync.Mutex
 	workers     []*ClientWorker
 	cleanupTask *task.Periodic
+	closed      bool
+}
+
+// Close implements common.Closable. It closes all workers, and no more are
+// created afterwards.
+func (p *IncrementalWorkerPicker) Close() error {
+	p.access.Lock()
+	defer p.access.Unlock()
+
+	p.closed = true
+	if p.cleanupTask != nil {
+		common.Must(p.cleanupTask.Close())
+	}
+	for _, w := range p.workers {
+		common.Must(w.done.Close())
+	}
+	p.workers = nil
+	return nil
 }
 
 func (p *IncrementalWorkerPicker) cleanupFunc() error {
@@ -112,6 +136,9 @@ func (p *IncrementalWorkerPicker) pickInternal() (*ClientWorker, bool, error) {
 
 	p.cleanup()
 
+	if p.closed {
+		return nil, false, newError("mux client closed")
+	}
 	worker, err := p.Factory.Create()
 	if err != nil {
 		return nil, false, err
@@ -188,10 +215,18 @@ func (f *DialingWorkerFactory) Create() (*ClientWorker, error) {
 }
 
 type ClientStrategy struct {
+	// MaxConcurrency is the max number of concurrent sub-connections.
 	MaxConcurrency uint32
-	MaxConnection  uint32
+	// MaxConnection is the total number of sub-connections before the worker
+	// stops accepting new ones.
+	MaxConnection uint32
+	// IdleTimeout is the time to keep the worker without sub-connections
+	// before closing it. defaultIdleTimeout is used if zero.
+	IdleTimeout time.Duration
 }
 
+const defaultIdleTimeout = time.Second * 16
+
 type ClientWorker struct {
 	sessionManager *SessionManager
 	link           transport.Link
@@ -233,7 +268,11 @@ func (m *ClientWorker) Closed() bool {
 }
 
 func (m *ClientWorker) monitor() {
-	timer := time.NewTicker(time.Second * 16)
+	idleTimeout := m.strategy.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+	timer := time.NewTicker(idleTimeout / 4)
 	defer timer.Stop()
 
 	for {
@@ -244,8 +283,7 @@ func (m *ClientWorker) monitor() {
 			common.Interrupt(m.link.Reader)
 			return
 		case <-timer.C:
-			size := m.sessionManager.Size()
-			if size == 0 && m.sessionManager.CloseIfNoSession() {
+			if m.sessionManager.CloseIfIdle(idleTimeout) {
 				common.Must(m.done.Close())
 			}
 		}
@@ -273,6 +311,9 @@ func fetchInput(ctx context.Context, s *Session, output buf.Writer) {
 	}
 	s.transferType = transferType
 	writer := NewWriter(s.ID, dest, output, transferType)
+	if transferType == protocol.TransferTypePacket {
+		writer.globalID = xudp.GlobalID(ctx)
+	}
 	defer s.Close()
 	defer writer.Close()
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/mux"
 	"github.com/v2fly/v2ray-core/v5/common/net"
@@ -114,3 +115,193 @@ func TestClientWorkerClose(t *testing.T) {
 
 	common.Must(w2.Close())
 }
+
+func TestClientWorkerConcurrency(t *testing.T) {
+	newWorker := func() *mux.ClientWorker {
+		r, w := pipe.New(pipe.WithoutSizeLimit())
+		worker, err := mux.NewClientWorker(transport.Link{
+			Reader: r,
+			Writer: w,
+		}, mux.ClientStrategy{
+			MaxConcurrency: 2,
+			MaxConnection:  128,
+		})
+		common.Must(err)
+		return worker
+	}
+	worker1 := newWorker()
+	worker2 := newWorker()
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	factory := mocks.NewMuxClientWorkerFactory(mockCtl)
+	gomock.InOrder(
+		factory.EXPECT().Create().Return(worker1, nil),
+		factory.EXPECT().Create().Return(worker2, nil),
+	)
+	manager := &mux.ClientManager{
+		Picker: &mux.IncrementalWorkerPicker{
+			Factory: factory,
+		},
+	}
+
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("www.v2fly.org"), 80),
+	})
+	var inputs []*pipe.Writer
+	for i := 0; i < 3; i++ {
+		r, w := pipe.New(pipe.WithoutSizeLimit())
+		defer w.Close()
+		inputs = append(inputs, w)
+		common.Must(manager.Dispatch(ctx, &transport.Link{Reader: r, Writer: w}))
+	}
+
+	if n := worker1.ActiveConnections(); n != 2 {
+		t.Error("expected 2 sub-connections on worker1, but got ", n)
+	}
+	if !worker1.IsFull() {
+		t.Error("expected worker1 to be full")
+	}
+	if n := worker2.ActiveConnections(); n != 1 {
+		t.Error("expected the third sub-connection on worker2, but got ", n)
+	}
+
+	// A slot of worker1 is freed after its sub-connection ends.
+	common.Must(inputs[0].Close())
+	deadline := time.Now().Add(time.Second)
+	for worker1.IsFull() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if worker1.IsFull() {
+		t.Error("expected worker1 to accept sub-connections after one ends")
+	}
+}
+
+func TestClientWorkerIdleTimeout(t *testing.T) {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	defer downlinkWriter.Close()
+
+	const idleTimeout = 200 * time.Millisecond
+	worker, err := mux.NewClientWorker(transport.Link{
+		Reader: downlinkReader,
+		Writer: uplinkWriter,
+	}, mux.ClientStrategy{
+		MaxConcurrency: 4,
+		MaxConnection:  128,
+		IdleTimeout:    idleTimeout,
+	})
+	common.Must(err)
+
+	closed := make(chan time.Time, 1)
+	go func() {
+		// Drain the frames until the worker closes the underlying connection.
+		buf.Copy(uplinkReader, buf.Discard)
+		closed <- time.Now()
+	}()
+
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("www.v2fly.org"), 80),
+	})
+	r, w := pipe.New(pipe.WithoutSizeLimit())
+	if !worker.Dispatch(ctx, &transport.Link{Reader: r, Writer: w}) {
+		t.Fatal("failed to dispatch")
+	}
+
+	// Sub-connections keep the worker alive.
+	time.Sleep(idleTimeout * 2)
+	if worker.Closed() {
+		t.Fatal("expected worker with sub-connections to stay open")
+	}
+
+	common.Must(w.Close())
+	idle := time.Now()
+	select {
+	case at := <-closed:
+		if elapsed := at.Sub(idle); elapsed < idleTimeout {
+			t.Error("expected worker to be closed after idle timeout, but closed in ", elapsed)
+		}
+	case <-time.After(idleTimeout * 5):
+		t.Fatal("expected idle worker to be closed")
+	}
+	if !worker.Closed() {
+		t.Error("expected worker to be closed")
+	}
+}
+
+func TestIncrementalPickerClose(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	r, w := pipe.New(pipe.WithoutSizeLimit())
+	worker, err := mux.NewClientWorker(transport.Link{Reader: r, Writer: w}, mux.ClientStrategy{
+		MaxConcurrency: 4,
+	})
+	common.Must(err)
+
+	factory := mocks.NewMuxClientWorkerFactory(mockCtl)
+	factory.EXPECT().Create().Return(worker, nil)
+	manager := &mux.ClientManager{
+		Enabled: true,
+		Picker:  &mux.IncrementalWorkerPicker{Factory: factory},
+	}
+	picked, err := manager.Picker.PickAvailable()
+	common.Must(err)
+	if picked != worker {
+		t.Fatal("unexpected worker")
+	}
+
+	common.Must(manager.Close())
+	if !worker.Closed() {
+		t.Error("expected worker to be closed")
+	}
+	if _, err := manager.Picker.PickAvailable(); err == nil {
+		t.Error("expected no worker after closing")
+	}
+}
+
+func TestClientWorkerIdleTimeoutAfterShortConnection(t *testing.T) {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	defer downlinkWriter.Close()
+
+	const idleTimeout = 200 * time.Millisecond
+	worker, err := mux.NewClientWorker(transport.Link{
+		Reader: downlinkReader,
+		Writer: uplinkWriter,
+	}, mux.ClientStrategy{
+		MaxConcurrency: 4,
+		MaxConnection:  128,
+		IdleTimeout:    idleTimeout,
+	})
+	common.Must(err)
+
+	closed := make(chan time.Time, 1)
+	go func() {
+		buf.Copy(uplinkReader, buf.Discard)
+		closed <- time.Now()
+	}()
+
+	// A sub-connection that starts and ends between two checks of the idle
+	// timer still resets the idle time.
+	time.Sleep(idleTimeout * 3 / 4)
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("www.v2fly.org"), 80),
+	})
+	r, w := pipe.New(pipe.WithoutSizeLimit())
+	active := time.Now()
+	if !worker.Dispatch(ctx, &transport.Link{Reader: r, Writer: w}) {
+		t.Fatal("failed to dispatch")
+	}
+	common.Must(w.Close())
+
+	select {
+	case at := <-closed:
+		if elapsed := at.Sub(active); elapsed < idleTimeout {
+			t.Error("expected worker to be closed after idle timeout since the last sub-connection, but closed in ", elapsed)
+		}
+	case <-time.After(idleTimeout * 5):
+		t.Fatal("expected idle worker to be closed")
+	}
+}
@@ -51,6 +51,8 @@ Frame format
 2 bytes - port
 n bytes - address
 
+8 bytes - global ID, for new UDP sessions with data only (XUDP)
+
 */
 
 type FrameMetadata struct {
@@ -58,6 +60,9 @@ type FrameMetadata struct {
 	SessionID     uint16
 	Option        bitmask.Byte
 	SessionStatus SessionStatus
+	// GlobalID identifies the source of a new UDP session, so that the server
+	// can resume it over another Mux connection. It is zero if unset.
+	GlobalID [8]byte
 }
 
 func (f FrameMetadata) WriteTo(b *buf.Buffer) error {
@@ -81,6 +86,10 @@ func (f FrameMetadata) WriteTo(b *buf.Buffer) error {
 		if err := addrParser.WriteAddressPort(b, f.Target.Address, f.Target.Port); err != nil {
 			return err
 		}
+
+		if f.Target.Network == net.Network_UDP && f.GlobalID != ([8]byte{}) {
+			common.Must2(b.Write(f.GlobalID[:]))
+		}
 	} else if b.Endpoint != nil {
 		b.WriteByte(byte(TargetNetworkUDP))
 		addrParser.WriteAddressPort(b, b.Endpoint.Address, b.Endpoint.Port)
@@ -121,6 +130,7 @@ func (f *FrameMetadata) UnmarshalFromBuffer(b *buf.Buffer) error {
 	f.SessionStatus = SessionStatus(b.Byte(2))
 	f.Option = bitmask.Byte(b.Byte(3))
 	f.Target.Network = net.Network_Unknown
+	f.GlobalID = [8]byte{}
 
 	if f.SessionStatus == SessionStatusNew || (f.SessionStatus == SessionStatusKeep && b.Len() != 4) {
 		if b.Len() < 8 {
@@ -144,5 +154,10 @@ func (f *FrameMetadata) UnmarshalFromBuffer(b *buf.Buffer) error {
 		}
 	}
 
+	if f.SessionStatus == SessionStatusNew && f.Option.Has(OptionData) &&
+		f.Target.Network == net.Network_UDP && b.Len() >= 8 {
+		copy(f.GlobalID[:], b.BytesTo(8))
+	}
+
 	return nil
 }
@@ -23,3 +23,52 @@ func BenchmarkFrameWrite(b *testing.B) {
 		writer.Clear()
 	}
 }
+
+func TestFrameGlobalID(t *testing.T) {
+	globalID := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	for _, tc := range []struct {
+		frame    mux.FrameMetadata
+		globalID [8]byte
+	}{
+		{
+			frame: mux.FrameMetadata{
+				Target:        net.UDPDestination(net.LocalHostIP, 53),
+				SessionID:     1,
+				SessionStatus: mux.SessionStatusNew,
+				Option:        mux.OptionData,
+				GlobalID:      globalID,
+			},
+			globalID: globalID,
+		},
+		{
+			// The global ID is only read from new sessions with data.
+			frame: mux.FrameMetadata{
+				Target:        net.UDPDestination(net.LocalHostIP, 53),
+				SessionID:     2,
+				SessionStatus: mux.SessionStatusNew,
+				GlobalID:      globalID,
+			},
+		},
+		{
+			frame: mux.FrameMetadata{
+				Target:        net.TCPDestination(net.LocalHostIP, 80),
+				SessionID:     3,
+				SessionStatus: mux.SessionStatusNew,
+				Option:        mux.OptionData,
+				GlobalID:      globalID,
+			},
+		},
+	} {
+		b := buf.New()
+		common.Must(tc.frame.WriteTo(b))
+		var frame mux.FrameMetadata
+		common.Must(frame.Unmarshal(b))
+		if frame.Target != tc.frame.Target || frame.SessionID != tc.frame.SessionID {
+			t.Error("unexpected frame: ", frame)
+		}
+		if frame.GlobalID != tc.globalID {
+			t.Error("expected global ID ", tc.globalID, ", but got ", frame.GlobalID)
+		}
+		b.Release()
+	}
+}
@@ -40,14 +40,16 @@ func (r *PacketReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 		return nil, newError("packet size too large: ", size)
 	}
 
-	b := buf.New()
+	b := buf.NewSize(int32(size))
 	if _, err := b.ReadFullFrom(r.reader, int32(size)); err != nil {
 		b.Release()
 		return nil, err
 	}
 	r.eof = true
 	if r.dest != nil && r.dest.Network == net.Network_UDP {
-		b.Endpoint = r.dest
+		// r.dest is reused for the following frames, so keep a copy.
+		dest := *r.dest
+		b.Endpoint = &dest
 	}
 	return buf.MultiBuffer{b}, nil
 }
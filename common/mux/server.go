@@ -158,6 +158,9 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 		}
 		ctx = log.ContextWithAccessMessage(ctx, msg)
 	}
+	if meta.Target.Network == net.Network_UDP && meta.GlobalID != ([8]byte{}) {
+		return w.handleXUDPNew(ctx, meta, reader)
+	}
 	link, err := w.dispatcher.Dispatch(ctx, meta.Target)
 	if err != nil {
 		if meta.Option.Has(OptionData) {
@@ -191,6 +194,50 @@ func (w *ServerWorker) handleStatusNew(ctx context.Context, meta *FrameMetadata,
 	return nil
 }
 
+// handleXUDPNew resumes the UDP session of the global ID in meta, or
+// dispatches a new one, so that the client can keep it across Mux connections.
+func (w *ServerWorker) handleXUDPNew(ctx context.Context, meta *FrameMetadata, reader *buf.BufferedReader) error {
+	s := &Session{
+		parent:       w.sessionManager,
+		ID:           meta.SessionID,
+		transferType: protocol.TransferTypePacket,
+		endpoint:     meta.Target,
+	}
+	for s.xudp == nil {
+		x, err := globalXUDPManager.acquire(newXUDPKey(ctx, meta.GlobalID), func() (*transport.Link, error) {
+			// The UDP session outlives the Mux connection, which cancels ctx
+			// when it closes, so it ends by xudpExpireTimeout instead.
+			return w.dispatcher.Dispatch(core.ToBackgroundDetachedContext(ctx), meta.Target)
+		})
+		if err != nil {
+			if meta.Option.Has(OptionData) {
+				buf.Copy(NewStreamReader(reader), buf.Discard)
+			}
+			return newError("failed to dispatch request.").Base(err)
+		}
+		s.input = x.link.Reader
+		s.output = x.link.Writer
+		w.sessionManager.Add(s)
+		if x.attach(s, w.link.Writer) {
+			s.xudp = x
+		} else {
+			// The session ended meanwhile, so dispatch a new one.
+			w.sessionManager.Remove(s.ID)
+		}
+	}
+	if !meta.Option.Has(OptionData) {
+		return nil
+	}
+
+	rr := s.NewReader(reader, &meta.Target)
+	if err := buf.Copy(rr, s.output); err != nil {
+		buf.Copy(rr, buf.Discard)
+		common.Interrupt(s.input)
+		return s.Close()
+	}
+	return nil
+}
+
 func (w *ServerWorker) handleStatusKeep(meta *FrameMetadata, reader *buf.BufferedReader) error {
 	if !meta.Option.Has(OptionData) {
 		return nil
@@ -2,6 +2,7 @@ package mux
 
 import (
 	"sync"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
@@ -14,12 +15,15 @@ type SessionManager struct {
 	sessions map[uint16]*Session
 	count    uint16
 	closed   bool
+	// lastActive is when a session was last added or removed.
+	lastActive time.Time
 }
 
 func NewSessionManager() *SessionManager {
 	return &SessionManager{
-		count:    0,
-		sessions: make(map[uint16]*Session, 16),
+		count:      0,
+		sessions:   make(map[uint16]*Session, 16),
+		lastActive: time.Now(),
 	}
 }
 
@@ -58,6 +62,7 @@ func (m *SessionManager) Allocate() *Session {
 		parent: m,
 	}
 	m.sessions[s.ID] = s
+	m.lastActive = time.Now()
 	return s
 }
 
@@ -71,6 +76,7 @@ func (m *SessionManager) Add(s *Session) {
 
 	m.count++
 	m.sessions[s.ID] = s
+	m.lastActive = time.Now()
 }
 
 func (m *SessionManager) Remove(id uint16) {
@@ -82,6 +88,7 @@ func (m *SessionManager) Remove(id uint16) {
 	}
 
 	delete(m.sessions, id)
+	m.lastActive = time.Now()
 
 	if len(m.sessions) == 0 {
 		m.sessions = make(map[uint16]*Session, 16)
@@ -116,6 +123,24 @@ func (m *SessionManager) CloseIfNoSession() bool {
 	return true
 }
 
+// CloseIfIdle closes the manager if it has had no session for at least
+// timeout, and returns whether it is closed.
+func (m *SessionManager) CloseIfIdle(timeout time.Duration) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.closed {
+		return true
+	}
+
+	if len(m.sessions) != 0 || time.Since(m.lastActive) < timeout {
+		return false
+	}
+
+	m.closed = true
+	return true
+}
+
 func (m *SessionManager) Close() error {
 	m.Lock()
 	defer m.Unlock()
@@ -127,6 +152,10 @@ func (m *SessionManager) Close() error {
 	m.closed = true
 
 	for _, s := range m.sessions {
+		if s.xudp != nil {
+			s.xudp.detach(s)
+			continue
+		}
 		common.Close(s.input)
 		common.Close(s.output)
 	}
@@ -144,10 +173,17 @@ type Session struct {
 	transferType protocol.TransferType
 	endpoint     net.Destination
 	sendEndpoint int
+	xudp         *xudpSession
 }
 
-// Close closes all resources associated with this session.
+// Close closes all resources associated with this session. A session resuming
+// an XUDP session only detaches from it, so that it can be resumed again.
 func (s *Session) Close() error {
+	if s.xudp != nil {
+		s.parent.Remove(s.ID)
+		s.xudp.detach(s)
+		return nil
+	}
 	common.Close(s.output)
 	common.Close(s.input)
 	s.parent.Remove(s.ID)
@@ -16,6 +16,7 @@ type Writer struct {
 	followup     bool
 	hasError     bool
 	transferType protocol.TransferType
+	globalID     [8]byte
 }
 
 func NewWriter(id uint16, dest net.Destination, writer buf.Writer, transferType protocol.TransferType) *Writer {
@@ -48,6 +49,7 @@ func (w *Writer) getNextFrameMeta() FrameMetadata {
 	} else {
 		w.followup = true
 		meta.SessionStatus = SessionStatusNew
+		meta.GlobalID = w.globalID
 	}
 
 	return meta
@@ -0,0 +1,190 @@
+package mux
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/transport"
+)
+
+// xudpExpireTimeout is how long a UDP session with a global ID is kept after
+// its Mux session ends, waiting for the client to resume it.
+const xudpExpireTimeout = time.Minute
+
+// xudpKey identifies a UDP session. Global IDs are chosen by clients, so they
+// are scoped to the inbound and user they arrive with.
+type xudpKey struct {
+	inboundTag string
+	email      string
+	globalID   [8]byte
+}
+
+func newXUDPKey(ctx context.Context, globalID [8]byte) xudpKey {
+	key := xudpKey{globalID: globalID}
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		key.inboundTag = inbound.Tag
+		if inbound.User != nil {
+			key.email = inbound.User.Email
+		}
+	}
+	return key
+}
+
+type xudpManager struct {
+	access   sync.Mutex
+	sessions map[xudpKey]*xudpSession
+}
+
+var globalXUDPManager = &xudpManager{
+	sessions: make(map[xudpKey]*xudpSession),
+}
+
+// acquire returns the UDP session of the key, calling dispatch to create it if
+// there is none.
+func (m *xudpManager) acquire(key xudpKey, dispatch func() (*transport.Link, error)) (*xudpSession, error) {
+	m.access.Lock()
+	x, found := m.sessions[key]
+	m.access.Unlock()
+	if found {
+		return x, nil
+	}
+
+	link, err := dispatch()
+	if err != nil {
+		return nil, err
+	}
+
+	m.access.Lock()
+	if x, found := m.sessions[key]; found {
+		m.access.Unlock()
+		common.Close(link.Writer)
+		common.Interrupt(link.Reader)
+		return x, nil
+	}
+	x = &xudpSession{
+		manager: m,
+		key:     key,
+		link:    link,
+	}
+	m.sessions[key] = x
+	m.access.Unlock()
+
+	go x.run()
+	return x, nil
+}
+
+func (m *xudpManager) remove(x *xudpSession) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if m.sessions[x.key] == x {
+		delete(m.sessions, x.key)
+	}
+}
+
+// xudpSession is a dispatched UDP connection with a global ID. It outlives the
+// Mux session it is attached to, so that the client can resume it over
+// another Mux connection.
+type xudpSession struct {
+	manager *xudpManager
+	key     xudpKey
+	link    *transport.Link
+
+	access   sync.Mutex
+	owner    *Session
+	response *Writer
+	expire   *time.Timer
+	closed   bool
+}
+
+// attach makes s the Mux session that receives the responses, detaching the
+// previous one. It returns false if the UDP session has ended.
+func (x *xudpSession) attach(s *Session, output buf.Writer) bool {
+	x.access.Lock()
+	if x.closed {
+		x.access.Unlock()
+		return false
+	}
+	previous, previousResponse := x.owner, x.response
+	x.owner = s
+	x.response = NewResponseWriter(s.ID, output, protocol.TransferTypePacket)
+	if x.expire != nil {
+		x.expire.Stop()
+		x.expire = nil
+	}
+	x.access.Unlock()
+
+	if previous != nil {
+		previous.parent.Remove(previous.ID)
+		previousResponse.Close()
+	}
+	return true
+}
+
+// detach stops sending the responses to s, and ends the UDP session if no Mux
+// session resumes it in time.
+func (x *xudpSession) detach(s *Session) {
+	x.access.Lock()
+	defer x.access.Unlock()
+	if x.owner != s || x.closed {
+		return
+	}
+	x.owner = nil
+	x.response = nil
+	x.expire = time.AfterFunc(xudpExpireTimeout, x.expireIfDetached)
+}
+
+func (x *xudpSession) expireIfDetached() {
+	x.access.Lock()
+	detached := x.owner == nil && !x.closed
+	x.access.Unlock()
+	if detached {
+		common.Interrupt(x.link.Writer)
+		common.Interrupt(x.link.Reader)
+	}
+}
+
+// WriteMultiBuffer implements buf.Writer. Responses are dropped while no Mux
+// session is attached.
+func (x *xudpSession) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	x.access.Lock()
+	defer x.access.Unlock()
+	if x.owner == nil {
+		buf.ReleaseMulti(mb)
+		return nil
+	}
+	writer := &endpointWrapperWriter{Writer: x.response, Session: x.owner}
+	if err := writer.WriteMultiBuffer(mb); err != nil {
+		newError("failed to write XUDP response to session ", x.owner.ID).Base(err).WriteToLog()
+	}
+	return nil
+}
+
+func (x *xudpSession) run() {
+	if err := buf.Copy(x.link.Reader, x); err != nil {
+		newError("XUDP session ends").Base(err).WriteToLog()
+	}
+
+	x.access.Lock()
+	x.closed = true
+	owner, response := x.owner, x.response
+	x.owner = nil
+	x.response = nil
+	if x.expire != nil {
+		x.expire.Stop()
+		x.expire = nil
+	}
+	x.access.Unlock()
+
+	x.manager.remove(x)
+	if owner != nil {
+		owner.parent.Remove(owner.ID)
+		response.Close()
+	}
+	common.Close(x.link.Writer)
+	common.Interrupt(x.link.Reader)
+}
@@ -0,0 +1,222 @@
+package mux_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/dice"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/mux"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+)
+
+// echoDispatcher sends every packet back with its endpoint, until the context
+// of the dispatch is done.
+type echoDispatcher struct {
+	dispatched int32
+}
+
+func (*echoDispatcher) Type() interface{} {
+	return routing.DispatcherType()
+}
+
+func (*echoDispatcher) Start() error {
+	return nil
+}
+
+func (*echoDispatcher) Close() error {
+	return nil
+}
+
+func (d *echoDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	atomic.AddInt32(&d.dispatched, 1)
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	go func() {
+		buf.Copy(uplinkReader, downlinkWriter)
+		downlinkWriter.Close()
+	}()
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			common.Interrupt(uplinkReader)
+			common.Interrupt(downlinkWriter)
+		}()
+	}
+	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+}
+
+func (*echoDispatcher) DispatchLink(ctx context.Context, dest net.Destination, outbound *transport.Link) error {
+	return errors.New("not implemented")
+}
+
+func (*echoDispatcher) DispatchConn(ctx context.Context, dest net.Destination, conn net.Conn, wait bool) error {
+	return errors.New("not implemented")
+}
+
+func newMuxConnection(ctx context.Context, d routing.Dispatcher) *mux.ClientWorker {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	_, err := mux.NewServerWorker(ctx, d, &transport.Link{
+		Reader: uplinkReader,
+		Writer: downlinkWriter,
+	})
+	common.Must(err)
+	worker, err := mux.NewClientWorker(transport.Link{
+		Reader: downlinkReader,
+		Writer: uplinkWriter,
+	}, mux.ClientStrategy{})
+	common.Must(err)
+	return worker
+}
+
+// udpSession dispatches a UDP sub-connection to target from source, and
+// returns the writer of its packets and the reader of the responses.
+func udpSession(worker *mux.ClientWorker, source, target net.Destination) (*pipe.Writer, *pipe.Reader) {
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Source: source})
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{Target: target})
+	inputReader, inputWriter := pipe.New(pipe.WithoutSizeLimit())
+	outputReader, outputWriter := pipe.New(pipe.WithoutSizeLimit())
+	if !worker.Dispatch(ctx, &transport.Link{Reader: inputReader, Writer: outputWriter}) {
+		panic("failed to dispatch")
+	}
+	return inputWriter, outputReader
+}
+
+func writePacket(writer buf.Writer, dest net.Destination) {
+	b := buf.New()
+	b.WriteString(dest.NetAddr())
+	b.Endpoint = &dest
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{b}))
+}
+
+// readPackets reads n packets, and returns their endpoints by payload.
+func readPackets(t *testing.T, reader *pipe.Reader, n int) map[string]*net.Destination {
+	endpoints := make(map[string]*net.Destination)
+	for len(endpoints) < n {
+		mb, err := reader.ReadMultiBufferTimeout(time.Second * 5)
+		if err != nil {
+			t.Fatal("failed to read responses: ", err)
+		}
+		for _, b := range mb {
+			endpoints[b.String()] = b.Endpoint
+		}
+		buf.ReleaseMulti(mb)
+	}
+	return endpoints
+}
+
+func TestXUDPMultipleDestinations(t *testing.T) {
+	d := &echoDispatcher{}
+	worker := newMuxConnection(context.Background(), d)
+
+	source := net.UDPDestination(net.LocalHostIP, net.Port(dice.RollUint16()))
+	targets := []net.Destination{
+		net.UDPDestination(net.DomainAddress("dns.google"), 53),
+		net.UDPDestination(net.ParseAddress("1.1.1.1"), 53),
+		net.UDPDestination(net.LocalHostIPv6, 443),
+	}
+	writer, reader := udpSession(worker, source, targets[0])
+	defer writer.Close()
+	for _, target := range targets {
+		writePacket(writer, target)
+	}
+
+	endpoints := readPackets(t, reader, len(targets))
+	for i, target := range targets {
+		endpoint, found := endpoints[target.NetAddr()]
+		if !found {
+			t.Error("missing response from ", target)
+			continue
+		}
+		if i == 0 {
+			// Responses from the target of the session carry no address.
+			if endpoint != nil && *endpoint != target {
+				t.Error("expected response from ", target, ", but got ", endpoint)
+			}
+			continue
+		}
+		if endpoint == nil || *endpoint != target {
+			t.Error("expected response from ", target, ", but got ", endpoint)
+		}
+	}
+	if n := worker.ActiveConnections(); n != 1 {
+		t.Error("expected 1 sub-connection, but got ", n)
+	}
+	if n := atomic.LoadInt32(&d.dispatched); n != 1 {
+		t.Error("expected 1 dispatched connection, but got ", n)
+	}
+}
+
+func TestXUDPResume(t *testing.T) {
+	d := &echoDispatcher{}
+	source := net.UDPDestination(net.LocalHostIP, net.Port(dice.RollUint16()))
+	target := net.UDPDestination(net.ParseAddress("8.8.8.8"), 53)
+	other := net.UDPDestination(net.ParseAddress("8.8.4.4"), 53)
+
+	// The inbound cancels the context of a Mux connection when it closes.
+	ctx, cancel := context.WithCancel(context.Background())
+	writer1, reader1 := udpSession(newMuxConnection(ctx, d), source, target)
+	writePacket(writer1, target)
+	readPackets(t, reader1, 1)
+	common.Must(writer1.Close())
+	cancel()
+
+	// The same source resumes the UDP session over another Mux connection.
+	writer2, reader2 := udpSession(newMuxConnection(context.Background(), d), source, target)
+	defer writer2.Close()
+	writePacket(writer2, target)
+	writePacket(writer2, other)
+	endpoints := readPackets(t, reader2, 2)
+	if endpoint := endpoints[other.NetAddr()]; endpoint == nil || *endpoint != other {
+		t.Error("expected response from ", other, ", but got ", endpoint)
+	}
+	if n := atomic.LoadInt32(&d.dispatched); n != 1 {
+		t.Error("expected the UDP session to be resumed, but dispatched ", n, " connections")
+	}
+
+	// Another source gets its own UDP session.
+	writer3, reader3 := udpSession(newMuxConnection(context.Background(), d), net.UDPDestination(net.LocalHostIP, source.Port+1), target)
+	defer writer3.Close()
+	writePacket(writer3, target)
+	readPackets(t, reader3, 1)
+	if n := atomic.LoadInt32(&d.dispatched); n != 2 {
+		t.Error("expected 2 dispatched connections, but got ", n)
+	}
+}
+
+func TestXUDPSourceWithTwoDestinations(t *testing.T) {
+	d := &echoDispatcher{}
+	worker := newMuxConnection(context.Background(), d)
+	source := net.UDPDestination(net.LocalHostIP, net.Port(dice.RollUint16()))
+	target1 := net.UDPDestination(net.ParseAddress("8.8.8.8"), 53)
+	target2 := net.UDPDestination(net.ParseAddress("1.1.1.1"), 53)
+
+	// The UDP inbound opens a connection for each destination of a source.
+	writer1, reader1 := udpSession(worker, source, target1)
+	defer writer1.Close()
+	writePacket(writer1, target1)
+	readPackets(t, reader1, 1)
+	writer2, reader2 := udpSession(worker, source, target2)
+	defer writer2.Close()
+	writePacket(writer2, target2)
+	readPackets(t, reader2, 1)
+
+	// Both keep working, each dispatched on its own.
+	writePacket(writer1, target1)
+	readPackets(t, reader1, 1)
+	if n := atomic.LoadInt32(&d.dispatched); n != 2 {
+		t.Error("expected 2 dispatched connections, but got ", n)
+	}
+	if n := worker.ActiveConnections(); n != 2 {
+		t.Error("expected 2 sub-connections, but got ", n)
+	}
+}
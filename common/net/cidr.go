@@ -0,0 +1,86 @@
+package net
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+)
+
+// MergeIPNets returns the smallest list of networks covering the same
+// addresses as nets, by removing the networks contained in others and merging
+// adjacent ones into their supernets. IPv4 networks come before IPv6 ones, and
+// both are sorted by address. Invalid networks are dropped.
+func MergeIPNets(nets []*IPNet) []*IPNet {
+	prefixes := make([]netip.Prefix, 0, len(nets))
+	for _, n := range nets {
+		if n == nil {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(n.IP)
+		if !ok {
+			continue
+		}
+		ones, bits := n.Mask.Size()
+		if bits == 0 {
+			continue
+		}
+		if addr.Is4In6() && bits == net.IPv4len*8 {
+			addr = addr.Unmap()
+		}
+		if addr.BitLen() != bits {
+			continue
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, ones).Masked())
+	}
+
+	merged := MergePrefixes(prefixes)
+	result := make([]*IPNet, 0, len(merged))
+	for _, prefix := range merged {
+		result = append(result, &IPNet{
+			IP:   prefix.Addr().AsSlice(),
+			Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+		})
+	}
+	return result
+}
+
+// MergePrefixes is MergeIPNets for prefixes. IPv4-mapped IPv6 prefixes are
+// treated as IPv6 ones.
+func MergePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix.IsValid() {
+			sorted = append(sorted, prefix.Masked())
+		}
+	}
+	// Supernets come before their subnets.
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	merged := make([]netip.Prefix, 0, len(sorted))
+	for _, prefix := range sorted {
+		if n := len(merged); n > 0 && merged[n-1].Overlaps(prefix) {
+			// Sorted as above, the prefix is contained in the last one.
+			continue
+		}
+		merged = append(merged, prefix)
+		// Merge the last two prefixes into their supernet as long as they are
+		// the two halves of it.
+		for n := len(merged); n >= 2; n = len(merged) {
+			lower, upper := merged[n-2], merged[n-1]
+			if lower.Bits() != upper.Bits() || lower.Bits() == 0 || lower.Addr().BitLen() != upper.Addr().BitLen() {
+				break
+			}
+			supernet, err := lower.Addr().Prefix(lower.Bits() - 1)
+			if err != nil || supernet.Addr() != lower.Addr() || !supernet.Contains(upper.Addr()) {
+				break
+			}
+			merged = append(merged[:n-2], supernet)
+		}
+	}
+	return merged
+}
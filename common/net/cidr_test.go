@@ -0,0 +1,76 @@
+package net_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	. "github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+func TestMergeIPNets(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Input  []string
+		Output []string
+	}{
+		{
+			Name:   "overlapping",
+			Input:  []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.3/32", "192.168.1.0/24", "192.168.0.0/16"},
+			Output: []string{"10.0.0.0/8", "192.168.0.0/16"},
+		},
+		{
+			Name:   "adjacent",
+			Input:  []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/23", "10.0.4.0/24"},
+			Output: []string{"10.0.0.0/22", "10.0.4.0/24"},
+		},
+		{
+			Name:   "adjacent but not siblings",
+			Input:  []string{"10.0.1.0/24", "10.0.2.0/24"},
+			Output: []string{"10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			Name:   "disjoint",
+			Input:  []string{"8.8.8.8/32", "1.1.1.1/32", "1.0.0.1/32"},
+			Output: []string{"1.0.0.1/32", "1.1.1.1/32", "8.8.8.8/32"},
+		},
+		{
+			Name:   "duplicated",
+			Input:  []string{"1.2.3.0/24", "1.2.3.4/24"},
+			Output: []string{"1.2.3.0/24"},
+		},
+		{
+			Name:   "whole space",
+			Input:  []string{"128.0.0.0/1", "0.0.0.0/1", "1.2.3.4/32"},
+			Output: []string{"0.0.0.0/0"},
+		},
+		{
+			Name:   "ipv6",
+			Input:  []string{"2001:db8::/33", "2001:db8:8000::/33", "2001:db8:1::/48", "fd00::1/128", "fc00::/8"},
+			Output: []string{"2001:db8::/32", "fc00::/8", "fd00::1/128"},
+		},
+		{
+			Name:   "mixed",
+			Input:  []string{"::/1", "0.0.0.0/1", "8000::/1", "128.0.0.0/2"},
+			Output: []string{"0.0.0.0/1", "128.0.0.0/2", "::/0"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var nets []*IPNet
+		for _, s := range testCase.Input {
+			_, ipNet, err := net.ParseCIDR(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nets = append(nets, ipNet)
+		}
+		var actual []string
+		for _, ipNet := range MergeIPNets(nets) {
+			actual = append(actual, ipNet.String())
+		}
+		if r := cmp.Diff(actual, testCase.Output); r != "" {
+			t.Error("for ", testCase.Name, ": ", r)
+		}
+	}
+}
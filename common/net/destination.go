@@ -138,6 +138,37 @@ func (d Destination) UDPAddr() *net.UDPAddr {
 	}
 }
 
+// NetworkAndDomainPreference returns the destinations to dial for the given
+// resolved IPs of this Destination. Address families are interleaved as
+// described in RFC 8305 (Happy Eyeballs), starting with IPv6 if preferIPv6 is
+// true, or IPv4 otherwise.
+func (d Destination) NetworkAndDomainPreference(ips []net.IP, preferIPv6 bool) []Destination {
+	var ip4s, ip6s []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			ip4s = append(ip4s, ip)
+		} else if len(ip) == net.IPv6len {
+			ip6s = append(ip6s, ip)
+		}
+	}
+
+	first, second := ip4s, ip6s
+	if preferIPv6 {
+		first, second = ip6s, ip4s
+	}
+
+	dests := make([]Destination, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			dests = append(dests, Destination{Network: d.Network, Address: IPAddress(first[i]), Port: d.Port})
+		}
+		if i < len(second) {
+			dests = append(dests, Destination{Network: d.Network, Address: IPAddress(second[i]), Port: d.Port})
+		}
+	}
+	return dests
+}
+
 // AsDestination converts current Endpoint into Destination.
 func (p *Endpoint) AsDestination() Destination {
 	return Destination{
@@ -108,3 +108,45 @@ func TestDestinationParse(t *testing.T) {
 		}
 	}
 }
+
+func TestDestinationNetworkAndDomainPreference(t *testing.T) {
+	dest := TCPDestination(DomainAddress("example.com"), 443)
+	ips := []IP{
+		ParseIP("1.1.1.1"),
+		ParseIP("1.0.0.1"),
+		ParseIP("2606:4700::1111"),
+		ParseIP("8.8.8.8"),
+	}
+
+	toStrings := func(dests []Destination) []string {
+		s := make([]string, 0, len(dests))
+		for _, d := range dests {
+			s = append(s, d.String())
+		}
+		return s
+	}
+
+	if r := cmp.Diff(toStrings(dest.NetworkAndDomainPreference(ips, true)), []string{
+		"tcp:[2606:4700::1111]:443",
+		"tcp:1.1.1.1:443",
+		"tcp:1.0.0.1:443",
+		"tcp:8.8.8.8:443",
+	}); r != "" {
+		t.Error(r)
+	}
+
+	if r := cmp.Diff(toStrings(dest.NetworkAndDomainPreference(ips, false)), []string{
+		"tcp:1.1.1.1:443",
+		"tcp:[2606:4700::1111]:443",
+		"tcp:1.0.0.1:443",
+		"tcp:8.8.8.8:443",
+	}); r != "" {
+		t.Error(r)
+	}
+
+	if r := cmp.Diff(toStrings(dest.NetworkAndDomainPreference(ips[2:3], false)), []string{
+		"tcp:[2606:4700::1111]:443",
+	}); r != "" {
+		t.Error(r)
+	}
+}
@@ -0,0 +1,138 @@
+package net
+
+import (
+	"context"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+)
+
+// HappyEyeballsDelay is the Connection Attempt Delay recommended by RFC 8305.
+const HappyEyeballsDelay = 250 * time.Millisecond
+
+// DialFunc dials a connection to the given destination.
+type DialFunc func(ctx context.Context, dest Destination) (Conn, error)
+
+// LookupIPFunc resolves the IPs of the given domain.
+type LookupIPFunc func(domain string) ([]IP, error)
+
+// DialHappyEyeballs dials dest as described in RFC 8305 (Happy Eyeballs). If
+// the address of dest is a domain, both its IPv4 and IPv6 addresses are
+// resolved by lookupIP, and dialed by DialStaggered alternating between the
+// families, starting with IPv6. Otherwise dest is dialed directly.
+func DialHappyEyeballs(ctx context.Context, dest Destination, lookupIP LookupIPFunc, dial DialFunc, delay time.Duration) (Conn, error) {
+	if !dest.Address.Family().IsDomain() {
+		return dial(ctx, dest)
+	}
+
+	ips, err := lookupIP(dest.Address.Domain())
+	if err != nil {
+		return nil, newError("failed to resolve ", dest.Address).Base(err)
+	}
+	dests := dest.NetworkAndDomainPreference(ips, true)
+	if len(dests) == 0 {
+		return nil, newError("no IP address for ", dest.Address)
+	}
+	return DialStaggered(ctx, dests, dial, delay)
+}
+
+type dialResult struct {
+	index int
+	conn  Conn
+	err   error
+}
+
+// DialStaggered dials the given destinations in order, starting a new attempt
+// every delay, or as soon as the previous one fails. The first established
+// connection is returned, and all other attempts are cancelled or closed. The
+// connection is not bound to the cancellation of ctx once established.
+// HappyEyeballsDelay is used if delay is not positive.
+func DialStaggered(ctx context.Context, dests []Destination, dial DialFunc, delay time.Duration) (Conn, error) {
+	if len(dests) == 0 {
+		return nil, newError("no destination to dial")
+	}
+	if delay <= 0 {
+		delay = HappyEyeballsDelay
+	}
+
+	results := make(chan dialResult)
+	done := make(chan struct{})
+	defer close(done)
+
+	// Attempts are cancelled by the loop below, instead of by ctx, so that
+	// the context of the winning attempt is left without anything to release.
+	cancels := make([]context.CancelFunc, 0, len(dests))
+	winner := -1
+	defer func() {
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+	}()
+
+	startNext := func() {
+		index := len(cancels)
+		attemptCtx, cancel := context.WithCancel(detachedContext{ctx})
+		cancels = append(cancels, cancel)
+		go func() {
+			conn, err := dial(attemptCtx, dests[index])
+			select {
+			case results <- dialResult{index: index, conn: conn, err: err}:
+			case <-done:
+				if conn != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+
+	startNext()
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var errs []error
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				winner = result.index
+				return result.conn, nil
+			}
+			errs = append(errs, result.err)
+			if len(cancels) < len(dests) {
+				startNext()
+				pending++
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if len(cancels) < len(dests) {
+				startNext()
+				pending++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, newError("failed to dial to any address").Base(errors.Combine(errs...))
+}
+
+// detachedContext carries the values of its parent, but is never done.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
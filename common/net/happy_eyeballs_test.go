@@ -0,0 +1,140 @@
+package net_test
+
+import (
+	"context"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	. "github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+type testDialer struct {
+	fail  map[string]bool
+	hang  map[string]bool
+	conns chan Conn
+}
+
+func (d *testDialer) Dial(ctx context.Context, dest Destination) (Conn, error) {
+	if d.hang[dest.NetAddr()] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if d.fail[dest.NetAddr()] {
+		return nil, errors.New("unreachable ", dest)
+	}
+	conn, peer := gonet.Pipe()
+	d.conns <- peer
+	return conn, nil
+}
+
+func TestDialStaggered(t *testing.T) {
+	dests := TCPDestination(DomainAddress("example.com"), 443).NetworkAndDomainPreference([]IP{
+		ParseIP("1.1.1.1"),
+		ParseIP("2606:4700::1111"),
+	}, true)
+
+	{
+		dialer := &testDialer{
+			hang:  map[string]bool{"[2606:4700::1111]:443": true},
+			conns: make(chan Conn, len(dests)),
+		}
+		start := time.Now()
+		conn, err := DialStaggered(context.Background(), dests, dialer.Dial, time.Millisecond*100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Millisecond*100 {
+			t.Error("expected the second attempt to start after the delay, but took ", elapsed)
+		}
+		conn.Close()
+	}
+
+	{
+		dialer := &testDialer{
+			fail:  map[string]bool{"[2606:4700::1111]:443": true, "1.1.1.1:443": true},
+			conns: make(chan Conn, len(dests)),
+		}
+		if _, err := DialStaggered(context.Background(), dests, dialer.Dial, 0); err == nil {
+			t.Error("expect error when all addresses are unreachable")
+		}
+	}
+}
+
+func TestDialStaggeredContext(t *testing.T) {
+	type key struct{}
+	dests := TCPDestination(DomainAddress("example.com"), 443).NetworkAndDomainPreference([]IP{
+		ParseIP("1.1.1.1"),
+		ParseIP("2606:4700::1111"),
+	}, true)
+	dialCtxs := make(chan context.Context, len(dests))
+	dial := func(ctx context.Context, dest Destination) (Conn, error) {
+		dialCtxs <- ctx
+		if dest.Address.Family().IsIPv6() {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		conn, _ := gonet.Pipe()
+		return conn, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	conn, err := DialStaggered(ctx, dests, dial, time.Millisecond*10)
+	common.Must(err)
+	defer conn.Close()
+	cancel()
+
+	loser, winner := <-dialCtxs, <-dialCtxs
+	if v := winner.Value(key{}); v != "value" {
+		t.Error("expected the values of ctx to be passed to the attempts, but got ", v)
+	}
+	select {
+	case <-loser.Done():
+	case <-time.After(time.Second):
+		t.Error("expected the losing attempt to be cancelled")
+	}
+	if winner.Err() != nil {
+		t.Error("expected the winning attempt to outlive ctx, but got ", winner.Err())
+	}
+}
+
+func TestDialHappyEyeballs(t *testing.T) {
+	// The server is reachable over IPv4 only.
+	listener, err := gonet.Listen("tcp4", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := Port(listener.Addr().(*gonet.TCPAddr).Port)
+
+	lookupIP := func(domain string) ([]IP, error) {
+		if domain != "example.com" {
+			return nil, errors.New("unknown domain ", domain)
+		}
+		return []IP{ParseIP("::1"), ParseIP("127.0.0.1")}, nil
+	}
+	var dialer gonet.Dialer
+	dial := func(ctx context.Context, dest Destination) (Conn, error) {
+		return dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	}
+
+	conn, err := DialHappyEyeballs(context.Background(), TCPDestination(DomainAddress("example.com"), port), lookupIP, dial, HappyEyeballsDelay)
+	common.Must(err)
+	defer conn.Close()
+	if addr := conn.RemoteAddr().(*gonet.TCPAddr); !addr.IP.Equal(ParseIP("127.0.0.1")) || Port(addr.Port) != port {
+		t.Error("expected connection to 127.0.0.1:", port, ", but got ", addr)
+	}
+
+	if _, err := DialHappyEyeballs(context.Background(), TCPDestination(DomainAddress("example.org"), port), lookupIP, dial, HappyEyeballsDelay); err == nil {
+		t.Error("expected error when the domain doesn't resolve")
+	}
+}
@@ -27,22 +27,24 @@ func NewBufferedReader(reader buf.Reader) *Reader {
 }
 
 func (r *Reader) ReadMultiBuffer() (buf.MultiBuffer, error) {
-	buffer := buf.New()
-	addr, port, err := addrParser.ReadAddressPort(buffer, r)
+	header := buf.New()
+	addr, port, err := addrParser.ReadAddressPort(header, r)
+	header.Release()
 	if err != nil {
-		buffer.Release()
 		return nil, err
 	}
 	endpoint := net.UDPDestination(addr, port)
-	buffer.Endpoint = &endpoint
 	var length uint16
 	err = binary.Read(r, binary.BigEndian, &length)
 	if err != nil {
-		buffer.Release()
 		return nil, err
 	}
-	buffer.Clear()
-	_, err = io.ReadFull(r, buffer.Extend(int32(length)))
+	if int32(length) > buf.Size {
+		return nil, newError("packet too large: ", length)
+	}
+	buffer := buf.NewSize(int32(length))
+	buffer.Endpoint = &endpoint
+	_, err = buffer.ReadFullFrom(r, int32(length))
 	if err != nil {
 		buffer.Release()
 		return nil, err
@@ -22,7 +22,7 @@ func NewServerConn(packetConn net.PacketConn) *ServerConn {
 }
 
 func (c *ServerConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
-	buffer := buf.New()
+	buffer := buf.NewSize(buf.Size)
 	n, addr, err := c.upstream.ReadFrom(buffer.Extend(buf.Size))
 	if err != nil {
 		buffer.Release()
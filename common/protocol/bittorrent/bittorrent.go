@@ -1,10 +1,9 @@
 package bittorrent
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
-	"math"
-	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
@@ -22,18 +21,40 @@ func (h *SniffHeader) Domain() string {
 
 var errNotBittorrent = errors.New("not bittorrent header")
 
+const handshakeProtocol = "\x13BitTorrent protocol"
+
+// SniffBittorrent recognizes the handshake of the peer wire protocol. It gives
+// up as soon as the bytes read so far don't match, so that other traffic isn't
+// held back waiting for more data.
 func SniffBittorrent(b []byte) (*SniffHeader, error) {
-	if len(b) < 20 {
+	if len(b) < len(handshakeProtocol) {
+		if len(b) == 0 || string(b) != handshakeProtocol[:len(b)] {
+			return nil, errNotBittorrent
+		}
 		return nil, common.ErrNoClue
 	}
 
-	if b[0] == 19 && string(b[1:20]) == "BitTorrent protocol" {
+	if string(b[:len(handshakeProtocol)]) == handshakeProtocol {
 		return &SniffHeader{}, nil
 	}
 
 	return nil, errNotBittorrent
 }
 
+const (
+	utpTypeData  = 0
+	utpTypeFin   = 1
+	utpTypeState = 2
+	utpTypeReset = 3
+	utpTypeSyn   = 4
+
+	// utpMaxWindow is the largest receive window taken as plausible.
+	utpMaxWindow = 1 << 26
+)
+
+// SniffUTP recognizes the header of uTP (BEP 29) packets. As the header has
+// little structure, implausible connection IDs and windows are rejected too:
+// WireGuard handshake initiations would otherwise pass as DATA packets.
 func SniffUTP(b []byte) (*SniffHeader, error) {
 	if len(b) < 20 {
 		return nil, common.ErrNoClue
@@ -45,7 +66,9 @@ func SniffUTP(b []byte) (*SniffHeader, error) {
 
 	if binary.Read(buffer, binary.BigEndian, &typeAndVersion) != nil {
 		return nil, common.ErrNoClue
-	} else if b[0]>>4&0xF > 4 || b[0]&0xF != 1 {
+	}
+	packetType := typeAndVersion >> 4 & 0xF
+	if packetType > utpTypeSyn || typeAndVersion&0xF != 1 {
 		return nil, errNotBittorrent
 	}
 
@@ -57,6 +80,16 @@ func SniffUTP(b []byte) (*SniffHeader, error) {
 		return nil, errNotBittorrent
 	}
 
+	// Connection ID, timestamps, window size and sequence numbers.
+	if common.Error2(buffer.ReadBytes(18)) != nil {
+		return nil, common.ErrNoClue
+	}
+	connectionID := binary.BigEndian.Uint16(b[2:])
+	window := binary.BigEndian.Uint32(b[12:])
+	if connectionID == 0 || window > utpMaxWindow {
+		return nil, errNotBittorrent
+	}
+
 	for extension != 0 {
 		if extension != 1 {
 			return nil, errNotBittorrent
@@ -74,17 +107,54 @@ func SniffUTP(b []byte) (*SniffHeader, error) {
 		}
 	}
 
-	if common.Error2(buffer.ReadBytes(2)) != nil {
-		return nil, common.ErrNoClue
+	switch packetType {
+	case utpTypeState, utpTypeReset, utpTypeSyn:
+		// These packets carry no payload.
+		if !buffer.IsEmpty() {
+			return nil, errNotBittorrent
+		}
+	case utpTypeData:
+		if buffer.IsEmpty() {
+			return nil, errNotBittorrent
+		}
 	}
 
-	var timestamp uint32
-	if err := binary.Read(buffer, binary.BigEndian, &timestamp); err != nil {
-		return nil, common.ErrNoClue
+	return &SniffHeader{}, nil
+}
+
+const udpTrackerProtocolID = 0x41727101980
+
+// SniffUDPTracker recognizes the connect request of the UDP tracker protocol
+// (BEP 15).
+func SniffUDPTracker(b []byte) (*SniffHeader, error) {
+	if len(b) != 16 {
+		return nil, errNotBittorrent
 	}
-	if math.Abs(float64(time.Now().UnixMicro()-int64(timestamp))) > float64(24*time.Hour) {
+
+	protocolID := binary.BigEndian.Uint64(b)
+	action := binary.BigEndian.Uint32(b[8:])
+	if protocolID != udpTrackerProtocolID || action != 0 {
 		return nil, errNotBittorrent
 	}
 
 	return &SniffHeader{}, nil
 }
+
+// SniffDHT recognizes the KRPC messages of the DHT protocol (BEP 5), which
+// are bencoded dictionaries with the node ID and the message type.
+func SniffDHT(b []byte) (*SniffHeader, error) {
+	if len(b) < 2 || b[0] != 'd' || b[len(b)-1] != 'e' {
+		return nil, errNotBittorrent
+	}
+
+	if !bytes.Contains(b, []byte("2:id20:")) {
+		return nil, errNotBittorrent
+	}
+	for _, messageType := range []string{"1:y1:q", "1:y1:r"} {
+		if bytes.Contains(b, []byte(messageType)) {
+			return &SniffHeader{}, nil
+		}
+	}
+
+	return nil, errNotBittorrent
+}
@@ -0,0 +1,93 @@
+package bittorrent_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/bittorrent"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	common.Must(err)
+	return b
+}
+
+var (
+	// A handshake with the reserved bytes, info hash and peer ID.
+	handshake = append([]byte("\x13BitTorrent protocol\x00\x00\x00\x00\x00\x10\x00\x05"), make([]byte, 40)...)
+	tlsHello  = decodeHex("160301020001000200030376e1")
+	// A uTP SYN, and a DATA packet with a selective ACK extension.
+	utpSyn  = decodeHex("41005a3e1a2b3c4d000000000010000000010000")
+	utpData = decodeHex("01015a3f1a2b3c4d00000bb800100000000200010004000000010000deadbeef")
+	// A WireGuard handshake initiation, which starts like a uTP DATA packet.
+	wireGuardInitiation = append(decodeHex("01000000c0ffee00"+"5d2b6e0af31c9b47e8d2a16f0c3e7b94"+"2a8f51c6d0e3b7a4986f1e2d5c0b3a79"), make([]byte, 108)...)
+	// A DNS query for v2fly.org.
+	dnsQuery = decodeHex("4100010000010000000000000576326679036f72670000010001")
+	// A UDP tracker connect request.
+	trackerConnect = decodeHex("0000041727101980000000000badcafe")
+	dhtPing        = []byte("d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe")
+	dhtResponse    = []byte("d1:rd2:id20:mnopqrstuvwxyz123456e1:t2:aa1:y1:re")
+)
+
+func TestSniffBittorrent(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		match   bool
+		noClue  bool
+	}{
+		{name: "handshake", payload: handshake, match: true},
+		{name: "partial handshake", payload: handshake[:10], noClue: true},
+		{name: "http", payload: []byte("GET / HTTP/1.1\r\nHost: v2fly.org\r\n\r\n")},
+		{name: "partial http", payload: []byte("GE")},
+		{name: "tls", payload: tlsHello},
+		{name: "wrong protocol", payload: []byte("\x13BitTorrent protocoL")},
+	} {
+		_, err := bittorrent.SniffBittorrent(tc.payload)
+		switch {
+		case tc.match && err != nil:
+			t.Error(tc.name, ": expected bittorrent, but got ", err)
+		case tc.noClue && err != common.ErrNoClue:
+			t.Error(tc.name, ": expected ErrNoClue, but got ", err)
+		case !tc.match && !tc.noClue && (err == nil || err == common.ErrNoClue):
+			// Other traffic must not wait for more data.
+			t.Error(tc.name, ": expected error, but got ", err)
+		}
+	}
+}
+
+func TestSniffUDP(t *testing.T) {
+	sniffers := map[string]func([]byte) (*bittorrent.SniffHeader, error){
+		"utp":     bittorrent.SniffUTP,
+		"tracker": bittorrent.SniffUDPTracker,
+		"dht":     bittorrent.SniffDHT,
+	}
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		sniffer string
+	}{
+		{"utp syn", utpSyn, "utp"},
+		{"utp data", utpData, "utp"},
+		{"tracker connect", trackerConnect, "tracker"},
+		{"dht ping", dhtPing, "dht"},
+		{"dht response", dhtResponse, "dht"},
+		{"dns", dnsQuery, ""},
+		{"wireguard initiation", wireGuardInitiation, ""},
+		{"quic", decodeHex("c70000000108b033575972cdd0f2000044d24f70b8a49a365a9d"), ""},
+		{"bencoded non-dht", []byte("d4:spaml1:a1:bee"), ""},
+	} {
+		for name, sniff := range sniffers {
+			header, err := sniff(tc.payload)
+			if name == tc.sniffer {
+				if err != nil || header.Protocol() != "bittorrent" {
+					t.Error(tc.name, ": expected ", name, " to recognize bittorrent, but got ", err)
+				}
+			} else if err == nil {
+				t.Error(tc.name, ": unexpected bittorrent from ", name)
+			}
+		}
+	}
+}
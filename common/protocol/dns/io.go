@@ -1,17 +1,16 @@
 package dns
 
 import (
-	"encoding/binary"
 	"sync"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
-	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"golang.org/x/net/dns/dnsmessage"
 )
 
 func PackMessage(msg *dnsmessage.Message) (*buf.Buffer, error) {
-	buffer := buf.New()
+	buffer := buf.NewSize(buf.Size)
 	rawBytes := buffer.Extend(buf.Size)
 	packed, err := msg.AppendPack(rawBytes[:0])
 	if err != nil {
@@ -80,29 +79,21 @@ func (r *UDPReader) Close() error {
 
 type TCPReader struct {
 	reader *buf.BufferedReader
+	frames *protocol.LengthPrefixedReader
 }
 
 func NewTCPReader(reader buf.Reader) *TCPReader {
+	bufferedReader := &buf.BufferedReader{
+		Reader: reader,
+	}
 	return &TCPReader{
-		reader: &buf.BufferedReader{
-			Reader: reader,
-		},
+		reader: bufferedReader,
+		frames: protocol.NewLengthPrefixedReader(bufferedReader),
 	}
 }
 
 func (r *TCPReader) ReadMessage() (*buf.Buffer, error) {
-	size, err := serial.ReadUint16(r.reader)
-	if err != nil {
-		return nil, err
-	}
-	if size > buf.Size {
-		return nil, newError("message size too large: ", size)
-	}
-	b := buf.New()
-	if _, err := b.ReadFullFrom(r.reader, int32(size)); err != nil {
-		return nil, err
-	}
-	return b, nil
+	return r.frames.ReadFrame()
 }
 
 func (r *TCPReader) Interrupt() {
@@ -134,10 +125,5 @@ func (w *TCPWriter) WriteMessage(b *buf.Buffer) error {
 		return nil
 	}
 
-	mb := make(buf.MultiBuffer, 0, 2)
-
-	size := buf.New()
-	binary.BigEndian.PutUint16(size.Extend(2), uint16(b.Len()))
-	mb = append(mb, size, b)
-	return w.WriteMultiBuffer(mb)
+	return protocol.NewLengthPrefixedWriter(w.Writer).WriteFrame(b)
 }
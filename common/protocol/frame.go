@@ -0,0 +1,169 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+)
+
+type FrameOption func(*frameOption)
+
+// FramePrefixSize sets the size in bytes of the length prefix of frames, which
+// is one of 1, 2 and 4. The default is 2.
+func FramePrefixSize(size int32) FrameOption {
+	if size != 1 && size != 2 && size != 4 {
+		panic("invalid frame prefix size")
+	}
+	return func(o *frameOption) {
+		o.prefixSize = size
+	}
+}
+
+// FrameByteOrder sets the byte order of the length prefix of frames. The
+// default is big endian.
+func FrameByteOrder(order binary.ByteOrder) FrameOption {
+	return func(o *frameOption) {
+		o.order = order
+	}
+}
+
+// MaxFrameSize sets the largest size of frames, not counting the prefix. The
+// default is buf.Size. It is capped by what the prefix can hold.
+func MaxFrameSize(size int32) FrameOption {
+	return func(o *frameOption) {
+		o.maxSize = size
+	}
+}
+
+type frameOption struct {
+	prefixSize int32
+	order      binary.ByteOrder
+	maxSize    int32
+}
+
+func newFrameOption(opts []FrameOption) *frameOption {
+	o := &frameOption{
+		prefixSize: 2,
+		order:      binary.BigEndian,
+		maxSize:    buf.Size,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if limit := int64(1)<<(8*o.prefixSize) - 1; int64(o.maxSize) > limit {
+		o.maxSize = int32(limit)
+	}
+	return o
+}
+
+func (o *frameOption) encode(size int32, b []byte) {
+	switch o.prefixSize {
+	case 1:
+		b[0] = byte(size)
+	case 2:
+		o.order.PutUint16(b, uint16(size))
+	case 4:
+		o.order.PutUint32(b, uint32(size))
+	}
+}
+
+func (o *frameOption) decode(b []byte) int64 {
+	switch o.prefixSize {
+	case 1:
+		return int64(b[0])
+	case 2:
+		return int64(o.order.Uint16(b))
+	default:
+		return int64(o.order.Uint32(b))
+	}
+}
+
+// LengthPrefixedReader reads frames that are prefixed by their length.
+type LengthPrefixedReader struct {
+	reader io.Reader
+	option *frameOption
+}
+
+// NewLengthPrefixedReader creates a LengthPrefixedReader reading frames from
+// reader.
+func NewLengthPrefixedReader(reader io.Reader, opts ...FrameOption) *LengthPrefixedReader {
+	return &LengthPrefixedReader{
+		reader: reader,
+		option: newFrameOption(opts),
+	}
+}
+
+// ReadFrame reads a frame, and returns its payload.
+func (r *LengthPrefixedReader) ReadFrame() (*buf.Buffer, error) {
+	prefix := buf.StackNew()
+	defer prefix.Release()
+
+	if _, err := prefix.ReadFullFrom(r.reader, r.option.prefixSize); err != nil {
+		return nil, err
+	}
+	size := r.option.decode(prefix.Bytes())
+	if size > int64(r.option.maxSize) {
+		return nil, newError("frame too large: ", size)
+	}
+
+	var b *buf.Buffer
+	if size <= int64(buf.DefaultSize()) {
+		b = buf.New()
+	} else {
+		b = buf.NewSize(int32(size))
+	}
+	if _, err := b.ReadFullFrom(r.reader, int32(size)); err != nil {
+		b.Release()
+		return nil, err
+	}
+	return b, nil
+}
+
+// ReadMultiBuffer implements buf.Reader. Each call reads a frame.
+func (r *LengthPrefixedReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	b, err := r.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return buf.MultiBuffer{b}, nil
+}
+
+// LengthPrefixedWriter writes frames that are prefixed by their length.
+type LengthPrefixedWriter struct {
+	writer buf.Writer
+	option *frameOption
+}
+
+// NewLengthPrefixedWriter creates a LengthPrefixedWriter writing frames to
+// writer.
+func NewLengthPrefixedWriter(writer buf.Writer, opts ...FrameOption) *LengthPrefixedWriter {
+	return &LengthPrefixedWriter{
+		writer: writer,
+		option: newFrameOption(opts),
+	}
+}
+
+// WriteFrame writes b as a frame. b is released in all cases.
+func (w *LengthPrefixedWriter) WriteFrame(b *buf.Buffer) error {
+	return w.WriteMultiBuffer(buf.MultiBuffer{b})
+}
+
+// WriteMultiBuffer implements buf.Writer. Each buffer is written as a frame.
+func (w *LengthPrefixedWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	for _, b := range mb {
+		if b.Len() > w.option.maxSize {
+			size := b.Len()
+			buf.ReleaseMulti(mb)
+			return newError("frame too large: ", size)
+		}
+	}
+
+	frames := make(buf.MultiBuffer, 0, len(mb)*2)
+	for _, b := range mb {
+		prefix := buf.New()
+		w.option.encode(b.Len(), prefix.Extend(w.option.prefixSize))
+		frames = append(frames, prefix, b)
+	}
+	return w.writer.WriteMultiBuffer(frames)
+}
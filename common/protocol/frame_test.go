@@ -0,0 +1,122 @@
+package protocol_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	. "github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+func TestLengthPrefixedRoundTrip(t *testing.T) {
+	testCases := []struct {
+		opts   []FrameOption
+		prefix []byte
+	}{
+		{
+			prefix: []byte{0, 5},
+		},
+		{
+			opts:   []FrameOption{FramePrefixSize(1)},
+			prefix: []byte{5},
+		},
+		{
+			opts:   []FrameOption{FramePrefixSize(2), FrameByteOrder(binary.LittleEndian)},
+			prefix: []byte{5, 0},
+		},
+		{
+			opts:   []FrameOption{FramePrefixSize(4)},
+			prefix: []byte{0, 0, 0, 5},
+		},
+		{
+			opts:   []FrameOption{FramePrefixSize(4), FrameByteOrder(binary.LittleEndian)},
+			prefix: []byte{5, 0, 0, 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		var stream bytes.Buffer
+		writer := NewLengthPrefixedWriter(buf.NewWriter(&stream), tc.opts...)
+		common.Must(writer.WriteFrame(buf.FromBytes([]byte("hello"))))
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{buf.FromBytes([]byte("v2ray")), buf.New()}))
+
+		expected := append(append([]byte{}, tc.prefix...), "hello"...)
+		if diff := cmp.Diff(expected, stream.Bytes()[:len(expected)]); diff != "" {
+			t.Error(diff)
+		}
+
+		reader := NewLengthPrefixedReader(&stream, tc.opts...)
+		for _, payload := range []string{"hello", "v2ray", ""} {
+			b, err := reader.ReadFrame()
+			common.Must(err)
+			if b.String() != payload {
+				t.Error("expected ", payload, ", but got ", b.String())
+			}
+			b.Release()
+		}
+		if _, err := reader.ReadFrame(); err != io.EOF {
+			t.Error("expected EOF, but got ", err)
+		}
+	}
+}
+
+func TestLengthPrefixedPartialRead(t *testing.T) {
+	stream := []byte{0, 3, 'f', 'o', 'o', 0, 6, 'b', 'a', 'r'}
+
+	// Frames arriving byte by byte are reassembled.
+	reader := NewLengthPrefixedReader(iotest.OneByteReader(bytes.NewReader(stream)))
+	b, err := reader.ReadFrame()
+	common.Must(err)
+	if b.String() != "foo" {
+		t.Error("expected foo, but got ", b.String())
+	}
+
+	// The second frame is truncated.
+	if _, err := reader.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF, but got ", err)
+	}
+
+	// So is a prefix.
+	reader = NewLengthPrefixedReader(bytes.NewReader([]byte{0}))
+	if _, err := reader.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF, but got ", err)
+	}
+}
+
+func TestLengthPrefixedOversize(t *testing.T) {
+	reader := NewLengthPrefixedReader(bytes.NewReader([]byte{0, 0, 0x10, 0}), FramePrefixSize(4), MaxFrameSize(1024))
+	if _, err := reader.ReadFrame(); err == nil {
+		t.Error("expected error on oversize frame")
+	}
+
+	var stream bytes.Buffer
+	writer := NewLengthPrefixedWriter(buf.NewWriter(&stream), MaxFrameSize(4))
+	if err := writer.WriteFrame(buf.FromBytes([]byte("hello"))); err == nil {
+		t.Error("expected error on oversize frame")
+	}
+	if stream.Len() != 0 {
+		t.Error("expected nothing written, but got ", stream.Bytes())
+	}
+
+	// A frame can't be larger than what its prefix holds.
+	writer = NewLengthPrefixedWriter(buf.NewWriter(&stream), FramePrefixSize(1))
+	if err := writer.WriteFrame(buf.FromBytes(make([]byte, 256))); err == nil {
+		t.Error("expected error on frame larger than 255 bytes")
+	}
+
+	// Frames larger than a buffer are read when allowed.
+	stream.Reset()
+	writer = NewLengthPrefixedWriter(buf.NewWriter(&stream), FramePrefixSize(4), MaxFrameSize(buf.Size*2))
+	common.Must(writer.WriteFrame(buf.FromBytes(make([]byte, buf.Size+1))))
+	reader = NewLengthPrefixedReader(&stream, FramePrefixSize(4), MaxFrameSize(buf.Size*2))
+	b, err := reader.ReadFrame()
+	common.Must(err)
+	if b.Len() != buf.Size+1 {
+		t.Error("expected ", buf.Size+1, " bytes, but got ", b.Len())
+	}
+}
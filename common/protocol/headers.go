@@ -40,6 +40,10 @@ const (
 	RequestOptionGlobalPadding bitmask.Byte = 0x08
 
 	RequestOptionAuthenticatedLength bitmask.Byte = 0x10
+
+	// RequestOptionFramePadding indicates each chunk of the payload in both directions is padded to a random length,
+	// with the length of padding in the chunk. It takes precedence over RequestOptionGlobalPadding.
+	RequestOptionFramePadding bitmask.Byte = 0x20
 )
 
 type RequestHeader struct {
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 )
 
@@ -47,12 +48,16 @@ func RemoveHopByHopHeaders(header http.Header) {
 }
 
 // ParseHost splits host and port from a raw string. Default port is used when raw string doesn't contain port.
+// IPv6 literals may be enclosed in brackets, as in "[2001:db8::1]:443", or be bare when no port is given.
 func ParseHost(rawHost string, defaultPort net.Port) (net.Destination, error) {
 	port := defaultPort
 	host, rawPort, err := net.SplitHostPort(rawHost)
 	if err != nil {
 		if addrError, ok := err.(*net.AddrError); ok && strings.Contains(addrError.Err, "missing port") {
 			host = rawHost
+		} else if ip := net.ParseIP(rawHost); ip != nil && ip.To4() == nil {
+			// A bare IPv6 literal without port.
+			return net.TCPDestination(net.IPAddress(ip), port), nil
 		} else {
 			return net.Destination{}, err
 		}
@@ -64,5 +69,14 @@ func ParseHost(rawHost string, defaultPort net.Port) (net.Destination, error) {
 		port = net.Port(intPort)
 	}
 
+	if strings.HasPrefix(rawHost, "[") {
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		ip := net.ParseIP(host)
+		if ip == nil || ip.To4() != nil {
+			return net.Destination{}, errors.New("invalid IPv6 literal: ", host)
+		}
+		return net.TCPDestination(net.IPAddress(ip), port), nil
+	}
+
 	return net.TCPDestination(net.ParseAddress(host), port), nil
 }
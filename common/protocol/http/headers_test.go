@@ -100,6 +100,42 @@ func TestParseHost(t *testing.T) {
 			DefaultPort: 443,
 			Destination: net.TCPDestination(net.ParseAddress("[2401:1bc0:51f0:ec08::1]"), 80),
 		},
+		{
+			RawHost:     "[2001:db8::1]",
+			DefaultPort: 443,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 443),
+		},
+		{
+			RawHost:     "[2001:db8::1]",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 80),
+		},
+		{
+			RawHost:     "[2001:db8::1]:",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 80),
+		},
+		{
+			RawHost:     "2001:db8::1",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 80),
+		},
+		{
+			RawHost: "[2001:db8::1",
+			Error:   true,
+		},
+		{
+			RawHost: "[v2fly.org]:443",
+			Error:   true,
+		},
+		{
+			RawHost: "[1.2.3.4]:443",
+			Error:   true,
+		},
+		{
+			RawHost: "[fe80::1%25eth0]:443",
+			Error:   true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -115,3 +151,40 @@ func TestParseHost(t *testing.T) {
 		}
 	}
 }
+
+func TestParseHostFromRequest(t *testing.T) {
+	testCases := []struct {
+		Request     string
+		DefaultPort net.Port
+		Destination net.Destination
+	}{
+		{
+			Request:     "CONNECT [2001:db8::1]:443 HTTP/1.1\r\nHost: [2001:db8::1]:443\r\n\r\n",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 443),
+		},
+		{
+			Request:     "GET http://[2001:db8::1]/ HTTP/1.1\r\nHost: [2001:db8::1]\r\n\r\n",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 80),
+		},
+		{
+			Request:     "GET / HTTP/1.1\r\nHost: [2001:db8::1]:8080\r\n\r\n",
+			DefaultPort: 80,
+			Destination: net.TCPDestination(net.ParseAddress("2001:db8::1"), 8080),
+		},
+	}
+
+	for _, testCase := range testCases {
+		request, err := http.ReadRequest(bufio.NewReader(strings.NewReader(testCase.Request)))
+		common.Must(err)
+		dest, err := ParseHost(request.Host, testCase.DefaultPort)
+		if err != nil {
+			t.Error("for request: ", testCase.Request, " unexpected error: ", err)
+			continue
+		}
+		if dest != testCase.Destination {
+			t.Error("for request: ", testCase.Request, " expected host: ", testCase.Destination.String(), " but got ", dest.String())
+		}
+	}
+}
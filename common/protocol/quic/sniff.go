@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"encoding/binary"
 	"io"
+	"sort"
 
 	"github.com/lucas-clemente/quic-go/quicvarint"
 	"github.com/marten-seemann/qtls-go1-18"
@@ -44,67 +45,127 @@ var (
 	}
 	errNotQuic        = errors.New("not quic")
 	errNotQuicInitial = errors.New("not initial packet")
+	errNotClientHello = errors.New("not client hello")
 )
 
+type cryptoFrame struct {
+	offset uint64
+	data   []byte
+}
+
+// SniffQUIC returns the server name in the ClientHello carried by the QUIC
+// Initial packets in b, which may be coalesced. It returns common.ErrNoClue if
+// the ClientHello continues in packets that are not in b yet.
 func SniffQUIC(b []byte) (*SniffHeader, error) {
-	buffer := buf.FromBytes(b)
-	typeByte, err := buffer.ReadByte()
-	if err != nil {
+	if len(b) == 0 {
 		return nil, errNotQuic
 	}
-	isLongHeader := typeByte&0x80 > 0
-	if !isLongHeader || typeByte&0x40 == 0 {
+	if b[0]&0x80 == 0 {
 		return nil, errNotQuicInitial
 	}
 
-	vb, err := buffer.ReadBytes(4)
-	if err != nil {
-		return nil, errNotQuic
+	var frames []cryptoFrame
+	for first := true; len(b) > 0; first = false {
+		if b[0]&0x80 == 0 {
+			// Only a short header packet can follow the long header ones.
+			break
+		}
+		packetFrames, rest, err := readLongHeaderPacket(b)
+		if err != nil {
+			if first {
+				return nil, err
+			}
+			break
+		}
+		frames = append(frames, packetFrames...)
+		b = rest
 	}
 
-	versionNumber := binary.BigEndian.Uint32(vb)
+	data := assembleCryptoData(frames)
+	if len(data) == 0 {
+		return &SniffHeader{domain: ""}, nil
+	}
+	if data[0] != 0x01 {
+		return nil, errNotClientHello
+	}
+	if len(data) < 4 {
+		return nil, common.ErrNoClue
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data) < 4+length {
+		return nil, common.ErrNoClue
+	}
+	tlsHdr := &ptls.SniffHeader{}
+	if err := ptls.ReadClientHello(data[:4+length], tlsHdr); err != nil {
+		return nil, err
+	}
+	return &SniffHeader{domain: tlsHdr.Domain()}, nil
+}
 
-	if versionNumber != 0 && typeByte&0x40 == 0 {
-		return nil, errNotQuic
-	} else if versionNumber != versionDraft29 && versionNumber != version1 {
-		return nil, errNotQuic
+// readLongHeaderPacket returns the CRYPTO frames in the long header packet at
+// the beginning of b, which are none unless it is an Initial packet, and the
+// packets following it.
+func readLongHeaderPacket(b []byte) ([]cryptoFrame, []byte, error) {
+	buffer := buf.FromBytes(b)
+	typeByte, err := buffer.ReadByte()
+	if err != nil {
+		return nil, nil, errNotQuic
+	}
+	if typeByte&0x40 == 0 {
+		return nil, nil, errNotQuic
 	}
 
-	if (typeByte&0x30)>>4 != 0x0 {
-		return nil, errNotQuicInitial
+	vb, err := buffer.ReadBytes(4)
+	if err != nil {
+		return nil, nil, errNotQuic
+	}
+	versionNumber := binary.BigEndian.Uint32(vb)
+	if versionNumber != versionDraft29 && versionNumber != version1 {
+		return nil, nil, errNotQuic
 	}
+	isInitial := (typeByte&0x30)>>4 == 0x0
 
 	var destConnID []byte
 	if l, err := buffer.ReadByte(); err != nil {
-		return nil, errNotQuic
+		return nil, nil, errNotQuic
 	} else if destConnID, err = buffer.ReadBytes(int32(l)); err != nil {
-		return nil, errNotQuic
+		return nil, nil, errNotQuic
 	}
 
 	if l, err := buffer.ReadByte(); err != nil {
-		return nil, errNotQuic
+		return nil, nil, errNotQuic
 	} else if common.Error2(buffer.ReadBytes(int32(l))) != nil {
-		return nil, errNotQuic
-	}
-
-	tokenLen, err := quicvarint.Read(buffer)
-	if err != nil || tokenLen > uint64(len(b)) {
-		return nil, errNotQuic
+		return nil, nil, errNotQuic
 	}
 
-	if _, err = buffer.ReadBytes(int32(tokenLen)); err != nil {
-		return nil, errNotQuic
+	if isInitial {
+		tokenLen, err := quicvarint.Read(buffer)
+		if err != nil || tokenLen > uint64(len(b)) {
+			return nil, nil, errNotQuic
+		}
+		if _, err = buffer.ReadBytes(int32(tokenLen)); err != nil {
+			return nil, nil, errNotQuic
+		}
 	}
 
 	packetLen, err := quicvarint.Read(buffer)
-	if err != nil {
-		return nil, errNotQuic
+	if err != nil || packetLen > uint64(buffer.Len()) {
+		return nil, nil, errNotQuic
 	}
 
 	hdrLen := len(b) - int(buffer.Len())
+	rest := b[hdrLen+int(packetLen):]
+	if !isInitial {
+		return nil, rest, nil
+	}
+	// The header protection is sampled 4 bytes after the packet number.
+	if packetLen < 4+16 {
+		return nil, nil, errNotQuicInitial
+	}
 
-	origPNBytes := make([]byte, 4)
-	copy(origPNBytes, b[hdrLen:hdrLen+4])
+	// Decrypt a copy, leaving the packet untouched.
+	packet := make([]byte, hdrLen+int(packetLen))
+	copy(packet, b)
 
 	var salt []byte
 	if versionNumber == version1 {
@@ -117,76 +178,105 @@ func SniffQUIC(b []byte) (*SniffHeader, error) {
 	hpKey := hkdfExpandLabel(initialSuite.Hash, secret, []byte{}, "quic hp", initialSuite.KeyLen)
 	block, err := aes.NewCipher(hpKey)
 	if err != nil {
-		return nil, err
-	}
-
-	cache := buf.New()
-	defer cache.Release()
-
-	mask := cache.Extend(int32(block.BlockSize()))
-	block.Encrypt(mask, b[hdrLen+4:hdrLen+4+16])
-	b[0] ^= mask[0] & 0xf
-	for i := range b[hdrLen : hdrLen+4] {
-		b[hdrLen+i] ^= mask[i+1]
-	}
-	packetNumberLength := b[0]&0x3 + 1
-	if packetNumberLength != 1 {
-		return nil, errNotQuicInitial
-	}
-	var packetNumber uint32
-	{
-		n, err := buffer.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		packetNumber = uint32(n)
+		return nil, nil, err
 	}
 
-	if packetNumber != 0 {
-		return nil, errNotQuicInitial
+	mask := make([]byte, block.BlockSize())
+	block.Encrypt(mask, packet[hdrLen+4:hdrLen+4+16])
+	packet[0] ^= mask[0] & 0xf
+	packetNumberLength := int(packet[0]&0x3 + 1)
+	var packetNumber uint64
+	for i := 0; i < packetNumberLength; i++ {
+		packet[hdrLen+i] ^= mask[i+1]
+		packetNumber = packetNumber<<8 | uint64(packet[hdrLen+i])
 	}
 
-	extHdrLen := hdrLen + int(packetNumberLength)
-	copy(b[extHdrLen:hdrLen+4], origPNBytes[packetNumberLength:])
-	data := b[extHdrLen : int(packetLen)+hdrLen]
-
+	extHdrLen := hdrLen + packetNumberLength
 	key := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, "quic key", 16)
 	iv := hkdfExpandLabel(crypto.SHA256, secret, []byte{}, "quic iv", 12)
 	cipher := qtls.AEADAESGCMTLS13(key, iv)
-	nonce := cache.Extend(int32(cipher.NonceSize()))
-	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(packetNumber))
-	decrypted, err := cipher.Open(b[extHdrLen:extHdrLen], nonce, data, b[:extHdrLen])
-	if err != nil {
-		return nil, err
-	}
-	buffer = buf.FromBytes(decrypted)
-	frameType, err := buffer.ReadByte()
+	nonce := make([]byte, cipher.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], packetNumber)
+	decrypted, err := cipher.Open(packet[extHdrLen:extHdrLen], nonce, packet[extHdrLen:], packet[:extHdrLen])
 	if err != nil {
-		return nil, io.ErrUnexpectedEOF
-	}
-	if frameType != 0x6 {
-		// not crypto frame
-		return &SniffHeader{domain: ""}, nil
+		return nil, nil, errNotQuicInitial
 	}
-	if common.Error2(quicvarint.Read(buffer)) != nil {
-		return nil, io.ErrUnexpectedEOF
-	}
-	dataLen, err := quicvarint.Read(buffer)
+
+	frames, err := readCryptoFrames(decrypted)
 	if err != nil {
-		return nil, io.ErrUnexpectedEOF
+		return nil, nil, err
 	}
-	if dataLen > uint64(buffer.Len()) {
-		return nil, io.ErrUnexpectedEOF
-	}
-	frameData, err := buffer.ReadBytes(int32(dataLen))
-	common.Must(err)
-	tlsHdr := &ptls.SniffHeader{}
-	err = ptls.ReadClientHello(frameData, tlsHdr)
-	if err != nil {
-		return nil, err
+	return frames, rest, nil
+}
+
+// readCryptoFrames returns the CRYPTO frames in the payload of an Initial
+// packet, skipping the other frames allowed there.
+func readCryptoFrames(b []byte) ([]cryptoFrame, error) {
+	buffer := buf.FromBytes(b)
+	var frames []cryptoFrame
+	for !buffer.IsEmpty() {
+		frameType, err := buffer.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		switch frameType {
+		case 0x00, 0x01: // PADDING, PING
+		case 0x02, 0x03: // ACK
+			// Largest acknowledged, ACK delay, ACK range count and first ACK range.
+			var values [4]uint64
+			for i := range values {
+				if values[i], err = quicvarint.Read(buffer); err != nil {
+					return nil, io.ErrUnexpectedEOF
+				}
+			}
+			fields := values[2] * 2
+			if frameType == 0x03 {
+				// ECN counts
+				fields += 3
+			}
+			for i := uint64(0); i < fields; i++ {
+				if _, err := quicvarint.Read(buffer); err != nil {
+					return nil, io.ErrUnexpectedEOF
+				}
+			}
+		case 0x06: // CRYPTO
+			offset, err := quicvarint.Read(buffer)
+			if err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			length, err := quicvarint.Read(buffer)
+			if err != nil || length > uint64(buffer.Len()) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			data, err := buffer.ReadBytes(int32(length))
+			common.Must(err)
+			frames = append(frames, cryptoFrame{offset: offset, data: data})
+		case 0x1c: // CONNECTION_CLOSE
+			return frames, nil
+		default:
+			return nil, errNotQuicInitial
+		}
 	}
+	return frames, nil
+}
 
-	return &SniffHeader{domain: tlsHdr.Domain()}, nil
+// assembleCryptoData returns the beginning of the crypto stream that frames
+// cover without gaps.
+func assembleCryptoData(frames []cryptoFrame) []byte {
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].offset < frames[j].offset
+	})
+	var data []byte
+	for _, frame := range frames {
+		end := frame.offset + uint64(len(frame.data))
+		if frame.offset > uint64(len(data)) {
+			break
+		}
+		if end > uint64(len(data)) {
+			data = append(data, frame.data[uint64(len(data))-frame.offset:]...)
+		}
+	}
+	return data
 }
 
 func hkdfExpandLabel(hash crypto.Hash, secret, context []byte, label string, length int) []byte {
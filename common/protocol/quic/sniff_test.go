@@ -1,6 +1,7 @@
 package quic_test
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -16,3 +17,74 @@ func TestSniffQUIC(t *testing.T) {
 		t.Error("failed")
 	}
 }
+
+const (
+	// Initial packets sent by quic-go, for the server name v2fly.org in QUIC
+	// v1, www.v2fly.org in draft-29, and split.v2fly.org with a ClientHello
+	// split across two packets.
+	quicV1Initial      = "c7000000010da569614a77274b38c4fbc3c349000044cdc935287165cce0b6bd4c2a672460aad6591ae99672c1e9f8ac9a9983143d08c7e9ec5c78141656171c0013c4c0b1112840a455e4036ed69a9041984ef8ae0f8e4a37d3be47abfc512d9b8067b8c3f0560da2765362e828a5fe4d6261633826fc088083762829cbad8b6ce40618ae8c304362cecf43b0f32f4d673987952bba04cdc254c7500da9f83419445295b78c728af717b3a63d14934886b092f66e4bf11c3ce15f4588c98c4783d2b2f3f0a98b9fc1d3386e17ca0c7250c09946de44875816c02390850e0e76687866bb3dc5edfee43646efabbf5707a217419cf89544b19ea250a223f15fa46d4d8e7667e12c710446681a588da6a1b11d94009dc350cd92c2188e6233347f9af10243befa3fb1d58d39298b127289f1897cc0320193b64777019a65e6bb5cbae628a393f94d71035125aace61b6358db95c2467d6013472dc7bc27beff95d821a9850e043974d55033d7ffc5e98456af61fc1f128fd3eceb1928a0b356a4d9143aa285399983f761a0926796df0948a0eb672ec58cdb0b83894866a06fea92a6355bf4be7d655d4221ed06550d1a86495509a62de65310ef99be6f6332e49e1676a49a59ba449e6afee8043f060dbf71cd74d499d3d5520287cbdd26961ca4b55447d8f5a45fc04376c37d3b1a3ed6a3e337feb42e2b76a834daf75c9db8f3c1bdd27d9938c069e8b7e76e9a63e2b9fa2bacefa068ee12bb9f337f33e4393478417143d49455f06438018ff45fbd53b092f9b6068f8eea9d1007e1a96ff2f5e7e12a14af1c3f6302567a9531303b2f9240476fc3a5ac638d965a75808dc5ee5f8a797d74bb6298c5d369c849bb1514aaaf227d7437ffd7b431bc502e464060071177f3d167403020089b83fe385321420b49e5b4894771cadb5db73c2e251b9b883a3cee4044230c76126ee7246661a73c5b4caa89c4c8668d110bcfeae32701b9501644e5c3f96390506e93431dc347b176e581ac7acfef6cafc1616e278e3e3b1064d17b401dd61bef360a53cafafc5f5199cb7ac3e719ee799e8993923d135f3dc156f21c9ada9474e17c824ae3933fb5edbf888b9399b61cfd1da136ea10f4ce4087bf5e64f80235a0dc26225799af0ddaf4b400555875636f42a8d854efcd716c49af9e160831d451aa6e88f1d27438d85827856fbdda2d2ffe942562ac52ef028a388795f20b82061b1965f89c8f054f94fc887006f880cd5ea7c1da1ec7c17fb7ce1db65a4f26327eed1433eac9962fa7d3d24cc28da305f827b1845457fcffe3deeeed8b17ba4edd7980e3b31a45668c2f2d971e29eb1ce8d6c9728e40a8581678d9522cbb23e8004ad874afe168154ac0cf1c865328dd12b7983776993c567ec2ce8d9db3a9994750f9917fee67b5fc2ce2e5a649d32c6f3d56aacdcb9a849199b009c599c7bc9b459e6ffc2eea38bba76c88ea28341fa132c546785fca1b199149eb04083245561e6bafd0bd2c8714e808a0f587c5458ad246f46df19e9809ee5bfe8e50a645238cc907fac62349ce6b50825526b73e07bf02996b9a8e80044be08d9be449b9535d1ba966320f76e265664305afd3e532edb689112b04f64f4000c993cb52ff63344e69fa0e4b5a38b7fcc876592b43c4414ebd83fac69fd088f3ee6f4bccc16aa796a1428602a1c9c45ee5d20fa21615e724dc3b8afa796b0ffd12318a9eb6e32584229888bf9f17112b1c62e0f8063ce7fd241792b0c"
+	quicDraft29Initial = "cdff00001d0eb26f404903dfad356ea0a679939e000044ccc11e225681866eed5953f03fdd96b98fee385666472532cf913ed4423946dbdc3214fa5ead279042761b666bd0b45f9f6b9e093467f07120266bcc13379365499bf3ef9e59fb4aa560515011ccc3f32c7c31b1fcae3c9cfb0c653ff7706b15dc18b5a46be3bff646c007bb7811783e610c237636423841e7708d3d151d5e04628c5769659ec447638ebd36fbc57ec20f713c4f3539c8e60be8b914ecced9b5b1c6f03a5f9075a11de81d7953fbebc02a0e01c0cc31c52f8b05498aef830c6542f30b5a235d7be936b6b19e3235e9ed4d23c7e657bc99b167b56d82d98ab606abf97c332f22f2fa75943df2592f20e71d8e6383ca8e94ba93834cd1777f799321ccc62de451029e99db4c55a2600347d606c74c8153e3aec6fb0961fff65e5e0a0873b9ab3732fd515e6dd24cd2773efcb58d20060cb0fc376bdfa080873be768c7984bf0b8f3c260cc0ef59b3c96353732eea814be15a159792b53974052c0fcd5dcefac394b3995407ba83af73671d9af8d5ee933d192a516bcbb3de91cc3fa3a54d23bdaacb0f92abfdb9d7ceb27defffa4974ec3c563db2bacff979fbd7a4afd54f462e404e9aa274136626e02d80defe439f3fecf8b34b8d979a127b9d48925bb78c59bf363e280c4fe54cb76326aa3c62ff6a58a9dd6558129e0730935d937240c9ec5403766e7c6210b741f847c0ca78b82f101276b45a20fd40c215e652af333e9101566f323e7befc7d6cf3b0cc7c32f0a5ab37a383e38f69a5de47ef96d4685712bf9b048f182f3c82c86756c87520fedc80f39479001994e6089a6dc59d6e10526ba926ca6c1e9b956ae70e93cc0f33a7c0b6e06c6fc392189941adf2a1db67df57fc3ed7cd473e18dc6bb0d07948e53cc49fb653efa3315f6dd9bc5fc9f32faffd10896f9175df0f82a203b6c3295ddbfbd0cc98ea7c93539f53b313c0da1b73fe7cdb7652f6dfd042db91ed2e64f1476105b706747d3bd634ee50400523f995446d28138ae9032089436af9bed48141b8956577afd75f871200ec13c12c0ea8ff4ed5f902a845f0752381522625ba2c2f13e408f010e667533a2a16daf7e21d8589d3ade40ff3c9c645ba65a19970377f579dc3889b9fcf227e0a71b641783a88b2970634e37a32872e1ec11a835dbabb1408fc17ced6203963bd453009c612d1e8484989feca2dda909a37a5a514922dda4082f7131342ad72ee3fefe7d77e1c6a1b191a3490a1f9aeba4e11f746128244ab9f5a19271d25307525896c181e809c8cf726abe02f19ee5d2a6579f00a16154aa7989eb915e8ab92e71d2f668d1f3f018a521b00dae6b9e880b4d4f5c18a8a70ea440417b3272ed229b5d74695fac45096deee60eef78413c6ecc5d578935ae410e66ffb385b368304ea868eced10592a923a15490e1f326cc7770f1582c9972933a0b72115528fdbdf01f32624cf3012565c65e2260680d10caea20e1b7838fe4e343a76f47b803c8ebbfe2783456ec5a4ab7e9b5dbabefc0ec744466931cb2e6888691638edf7475e84c5f787f50b4159593ddba37cec0f8ac65bffda8a7ffd3ae9830906a5206decbf123e6673af3d40469b898e33da22b7089fd3439c451571370deb8c14e5f6a45c4c2ed17857472b7a4b8cabe3b645576a933a73d2507a54445a1dbd6e5a4ebc5b3a0ebadefa58521942720ed591dc9dae0ab84ccc49e020d0a95d1b40941c13ed86e6fe90602c2446a3"
+	quicSplitInitial1  = "ce000000010dbe6b2cc48dc3b31d8849d21861000044cd8426904e152d0072026ced981c75699792ea6a34b7dca3341b906605b370be0e63e980136159e50d2534f5c7861427a16e498c3053b36922181047cff564057317c2b9d621c635ed61eaadaa7ca3274b92d3a2da1e02ec7faf1e335cabaa12798f97671feadbd36771957e184d502d69f27a254af1f0f6ddcbe14396aaf373feb20c94abba7942bf1d37f12469c989e2ff50c6b0e239d0a94f128e8928b09427c2228d5145482174eca38e577ff52fab71bb11c608627ad0a1d12f69011a99a2c16f87b02e1067c1e151b38c939059a6504db765ad7723fdd1fe985c3a2544a331087b144579312dba8bad531e524b382f0da5ee0f0c63634a10ca4238f3a997e1f1b32431d7751786b5e53e0f55202266b1068eef5b4b178d922951f6bb467264c1ad339d2505cf0411ba99379d3edd7cc78d2e86cd138ae876dad00f51611904ad48bafb5621ddc46bc1ef8c8afea750b040fc5591bbacbe89236fda58710f203cd7cab89412523213b5ac1acd40cec82c6972cf167b4fbea8af23a7722f62c21f98ab1b0c5f22d2ffbe9c0cb950393d5e59cdb95344ab4b80d95efdfe7619a847a8a46cd0d0491f61a37961a2b19024f304046e01146be91cd7d13ee19e67b04b0dd2c3ab138a462df15adabe907b7662ee6341dc79ca0b6b6f80033b01661842d631d34067531961daf9824ce9af7e3a2d79fdea0eeba37ede24a3c95b5e9999d2a53cde622f7f81c051384192ee6d2bd6e2a32d4bb67c5b035c02cfcb41c5c3b2e6e750852bb50b3794e02dad46faf65c6660b9072343915272262c8ba878e74be673287af743a00290e3e53e965dbd236b5db0ae6576ca1c5f90c6da5d69512d5b2ac844534648de6991509ae758cae8c73cc4880e42000b78972aaa1128a7cb5761a417dee3a0b70b76f25cbfd4d5437b871ea7b4beefedee1f9ba4f9dff2c8b78409a5403e1fd849cc47d246799019a64bf154faf3a35573cf4bb41aeaf51dc42500a809f7545e29b2ec7fbc6643a22eab4292e2b14cf8bb31135d5a294859d346f196f0177735ec7bd55ef5f88076f8b155cca701dbcfa770d93d4aa30360c4c5643b1aa0c47c296fe75e1dcb894b1144c1d7a778e6798b8b6f2b1e7a74aa44bb07cd7d5e7d0c5fe06a382a54a8b452048bbb6ff9efc45857a56f9f88f65511a436b62d05c980f5b8a8ebd7963e40eefb3011b1f3cda725ad321edc55161d16ac1700091490267394a54a47036f858ac8f90e37c722a7f52cd071e27f2d607bdd87903db4b3d32307f93ef632edcd108da66e30b124b702db02069682fbc8313d791253e00ba417f586dc81344419ae171e3e9e9ccaf1a8a3527c4ddce17f2def7f08a30ae31fd833d3b1b7fd23972429ea8dc6811ce1cff87452c1df015449bcaa84789b7bd4a3ebf42822aa3eed41e6061de244b41d9ffda3d42556adfaddc08bb6f20dd0834805e9bb8d34a3c8457a15baefd2a7ef87a63d5d25e2ccefc020783d06af6caa2fc28ec7254fe6ee5bd93c6103ac7103e9c0a0e43ca28dd7f53b7f3560013bf7a35ece0da3df8646277d00322ea6805a55be6910d04c15399583d5774830d4c7db5aef01fd1f770a21d1d1f765b62036a83540de1c6c5fb6ee709c7f1b1b22f9494e92b1b22080213157f20a652188741d7d7d3d24d308b7407e7537c2401e0f7fd8d6f82bc22f12b2f1bc6fb422ce440df8959643d3dfffde254e0ff868343ed620"
+	quicSplitInitial2  = "c3000000010dbe6b2cc48dc3b31d8849d21861000044cd055ac24abd95d8574ab5c6f6681ce16ca5c70c5363eb359dc847fe8660b90cfd0abb8521523e7870eb840a9c116c7eb473cfa90036402833b2cb6e66c30a64738f86d227d75b00d8b00c16fc630bc6cc782b4eed41ec74036e8b8341c46f5e82b9114424521b75d29d77c8ce4bb20a56c3422d4c31d4481964315ce60ca1d468f0efe4551c3d8279c91b87e2d68d47e9a184c1230cd2c7a1f2ea415c4f2c962e734d8da978509b02e2d10036538a114e1943f16db798e6aa7800621e418f6a69741dc1bb56011d13972d2bc393b934f59b6a54d6558d245e59f6ccac4d3555984288554dde9aa266ede18b5aeda4d4d65d36e5479ec138f54777da27951983eb91c1b3d1a2b924843c84940c1911341b5c781aa89996baf96b40e557fba8b39e6f2935f34e22bd549aae635590205d8c359a0009f712a2b15cc7d98b50a9508bb6bffaef20c0a1afb022c7bad3bc2ce776663a00c70f44ed01999c18ef885acbfb34e31588a26cb9e09d40849ea5200130b955514dc34092d07b7bbf2192edba0830c5c653889fe6f1ffcdc513ba4b3273050f71ab36eccb7ef89c8cbeb05d81832d87ceda50769eda0990f6a3e6cab435d0564147316768fec70ecd98bd5b758a674cc56e20452514c5394453ec4208debc41e4a3c727960585c65e16acb7a4cc7078aacdefb9da08415ace7ea6ac01bdc6c21fb1bf8e505f3226d9aed54a9e3322c44fa8addb8a15a076329edb3ed9d38f4091d34040e98d820630b99d1c64b4d23488c16ddc3cb4f2a7dfbca451feeb67096b6561531778a5d1795ca604f06f34142bbe74e11a6f5bdb118051b315dd4be8d15ef8ddb42401889c25313f522598dc743dbc88629c247034b71b86d1abb8bbaae3c3386977ac79ddb4caa3ce61ef6828e3eb357a820e953742aa44992c41d56a6b765d64443d64df7fab6a5b41a4937685d16bb488a414d8ef58642823f38200c35a69bffc2e51c7e920229a1dd46be6ecb7e9720b8938a5d836980637a5a0791c92a44cf4b79db2ea66f32d68391f43c4ab8e38b24af29b342de14b8b5e28502ee0ef321160727277d8569f3e531af6e5e53207ee64393211a0d9b26d890045c3531e5ff0224845f24ef78f65d22d63ce85385643ad8a475fc73ea21491a09937c2b11c4b11b1a47cef9f2868e6a9b89663e0dade3eb461b735798b72ed04ddb615a8dfea42e852aad66165167f534f328e6c4383bead9a3dd75c9422335018f9fe0eee124f4d1200e3f9334ada7662fe40f7e94d8b91205087f6a6a4f87905569801b1221844b6f0f4f31c321e10896310e313c3dda41bff5037e8f96b04cca72dec4942a285ba515cea5f9f1f94ae2930ad841350725e9bf7bf3c1280c2e0174b6cdf6d8680b11df397aa10c9da719497cf5e7a6f2dbbd67d12b9358be1c25381eac4313ac6ab21958abced39a8a5190de61fc08974272ce08224c0b2ab0811c530b7d93b2fad5954ba989c3b5d00a7a4481b168087d32010e73642b8510926c7443392421b61ca46ce0fac1e84fb2af052af76014099f8e696133beb47d384d5e72f4f584cd60c73d41ab8fc21ad53ab7e8701a1a7303f05f3f8ee5367e070c00abaa9fb654f5a18a0a73ab195c0c0e2511f0ec15452a106a2982f1354fff268188dbdcc3b5bbde1dad42c3e190dba023b672e21bb190671f0b0a012a65c9847b226333be5aa307e1640aa3dc3a484b5cb5044f06a1a38"
+)
+
+func decodeHex(s ...string) []byte {
+	var b []byte
+	for _, v := range s {
+		d, err := hex.DecodeString(v)
+		common.Must(err)
+		b = append(b, d...)
+	}
+	return b
+}
+
+func TestSniffQUICVersions(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		packets []byte
+		domain  string
+	}{
+		{"v1", decodeHex(quicV1Initial), "v2fly.org"},
+		{"draft-29", decodeHex(quicDraft29Initial), "www.v2fly.org"},
+		{"coalesced", decodeHex(quicSplitInitial1, quicSplitInitial2), "split.v2fly.org"},
+		{"coalesced in any order", decodeHex(quicSplitInitial2, quicSplitInitial1), "split.v2fly.org"},
+	} {
+		packets := append([]byte(nil), tc.packets...)
+		header, err := quic.SniffQUIC(packets)
+		if err != nil {
+			t.Error(tc.name, ": ", err)
+			continue
+		}
+		if header.Domain() != tc.domain {
+			t.Error(tc.name, ": expected ", tc.domain, ", but got ", header.Domain())
+		}
+		if !bytes.Equal(packets, tc.packets) {
+			t.Error(tc.name, ": packets modified")
+		}
+	}
+}
+
+func TestSniffQUICIncomplete(t *testing.T) {
+	if _, err := quic.SniffQUIC(decodeHex(quicSplitInitial1)); err != common.ErrNoClue {
+		t.Error("expected ErrNoClue for a partial ClientHello, but got ", err)
+	}
+}
+
+func TestSniffQUICInvalid(t *testing.T) {
+	unsupported := decodeHex(quicV1Initial)
+	unsupported[4] = 0x2
+
+	shortHeader := decodeHex(quicV1Initial)
+	shortHeader[0] &^= 0x80
+
+	for name, packet := range map[string][]byte{
+		"unsupported version": unsupported,
+		"short header":        shortHeader,
+		"truncated":           decodeHex(quicV1Initial)[:100],
+		"empty":               {},
+	} {
+		if _, err := quic.SniffQUIC(packet); err == nil || err == common.ErrNoClue {
+			t.Error(name, ": expected error, but got ", err)
+		}
+	}
+}
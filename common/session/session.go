@@ -4,8 +4,10 @@ package session
 import (
 	"context"
 	"math/rand"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/log"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 )
@@ -33,6 +35,30 @@ func ExportIDToError(ctx context.Context) errors.ExportOption {
 	}
 }
 
+// ExportContextToError transfers session.ID, as well as the inbound tag, the
+// source and the destination of the connection into an error object, for
+// logging purpose. This can be used with error.WriteToLog().
+func ExportContextToError(ctx context.Context) errors.ExportOption {
+	id := IDFromContext(ctx)
+	connection := &log.ConnectionContext{}
+	if inbound := InboundFromContext(ctx); inbound != nil {
+		connection.InboundTag = inbound.Tag
+		if inbound.Source.IsValid() {
+			connection.Source = inbound.Source.String()
+		}
+	}
+	if outbound := OutboundFromContext(ctx); outbound != nil && outbound.Target.IsValid() {
+		connection.Destination = outbound.Target.String()
+	}
+	if *connection == (log.ConnectionContext{}) {
+		connection = nil
+	}
+	return func(h *errors.ExportOptionHolder) {
+		h.SessionID = uint32(id)
+		h.Connection = connection
+	}
+}
+
 // Inbound is the metadata of an inbound connection.
 type Inbound struct {
 	// Source address of the inbound connection.
@@ -68,6 +94,8 @@ type SniffingRequest struct {
 	Enabled                        bool
 	MetadataOnly                   bool
 	RouteOnly                      bool
+	// Timeout is the time to wait for content to sniff. A default is used if zero.
+	Timeout time.Duration
 }
 
 // Content is the metadata of the connection content.
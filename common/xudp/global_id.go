@@ -0,0 +1,42 @@
+package xudp
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"lukechampine.com/blake3"
+)
+
+var globalIDKey = func() []byte {
+	key := make([]byte, 32)
+	common.Must2(rand.Read(key))
+	return key
+}()
+
+// GlobalID returns the XUDP global ID of the UDP connection in ctx. It is
+// derived from the inbound tag, user and source, and the outbound target, as
+// the UDP inbound opens a connection for each destination of a source, and is
+// zero if ctx has no UDP inbound source.
+func GlobalID(ctx context.Context) (id [8]byte) {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || !inbound.Source.IsValid() || inbound.Source.Network != net.Network_UDP {
+		return
+	}
+	h := blake3.New(8, globalIDKey)
+	h.Write([]byte(inbound.Tag))
+	h.Write([]byte{0})
+	if inbound.User != nil {
+		h.Write([]byte(inbound.User.Email))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(inbound.Source.String()))
+	h.Write([]byte{0})
+	if outbound := session.OutboundFromContext(ctx); outbound != nil && outbound.Target.IsValid() {
+		h.Write([]byte(outbound.Target.String()))
+	}
+	copy(id[:], h.Sum(nil))
+	return
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 )
 
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
 var addrParser = protocol.NewAddressParser(
 	protocol.AddressFamilyByte(byte(protocol.AddressTypeIPv4), net.AddressFamilyIPv4),
 	protocol.AddressFamilyByte(byte(protocol.AddressTypeDomain), net.AddressFamilyDomain),
@@ -36,7 +38,7 @@ func (w *PacketWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 			continue
 		}
 
-		eb := buf.New()
+		eb := buf.NewSize(length + 666)
 		eb.Write([]byte{0, 0, 0, 0})
 		if w.Dest.Network == net.Network_UDP {
 			eb.WriteByte(1) // New
@@ -88,12 +90,16 @@ func (r *PacketReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 		if l < 4 {
 			return nil, io.EOF
 		}
-		b := buf.New()
+		if l > buf.Size {
+			return nil, newError("frame metadata too large: ", l)
+		}
+		b := buf.NewSize(l)
 		if _, err := b.ReadFullFrom(r.Reader, l); err != nil {
 			b.Release()
 			return nil, err
 		}
 		discard := false
+		hasData := b.Byte(3) == 1
 		switch b.Byte(2) {
 		case 2:
 			if l != 4 {
@@ -115,21 +121,29 @@ func (r *PacketReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 			b.Release()
 			return nil, io.EOF
 		}
-		if b.Byte(3) == 1 {
+		if hasData {
 			if _, err := io.ReadFull(r.Reader, r.cache); err != nil {
 				b.Release()
 				return nil, err
 			}
 			length := int32(r.cache[0])<<8 | int32(r.cache[1])
 			if length > 0 {
-				b.Clear()
-				if _, err := b.ReadFullFrom(r.Reader, length); err != nil {
+				if length > buf.Size {
 					b.Release()
+					return nil, newError("packet too large: ", length)
+				}
+				data := buf.NewSize(length)
+				data.Endpoint = b.Endpoint
+				b.Release()
+				if _, err := data.ReadFullFrom(r.Reader, length); err != nil {
+					data.Release()
 					return nil, err
 				}
 				if !discard {
-					return buf.MultiBuffer{b}, nil
+					return buf.MultiBuffer{data}, nil
 				}
+				data.Release()
+				continue
 			}
 		}
 		b.Release()
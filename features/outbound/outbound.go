@@ -27,6 +27,16 @@ type HandlerSelector interface {
 	Select([]string) []string
 }
 
+// HandlerReplacer is a Manager that replaces handlers at runtime.
+type HandlerReplacer interface {
+	// ReplaceHandlers adds handlers, replacing the existing ones with the same tags, and removes the
+	// handlers of the removed tags at once. Handlers already added are kept as they are. The default
+	// handler is kept, unless its tag is replaced, in which case the new handler becomes the default,
+	// or removed, in which case the first of handlers becomes the default.
+	// Replaced and removed handlers are closed once their connections have drained.
+	ReplaceHandlers(ctx context.Context, handlers []Handler, removed []string) error
+}
+
 // Manager is a feature that manages outbound.Handlers.
 //
 // v2ray:api:stable
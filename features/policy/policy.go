@@ -27,6 +27,8 @@ type Stats struct {
 	UserUplink bool
 	// Whether or not to enable stat counter for user downlink traffic.
 	UserDownlink bool
+	// Whether or not to enable online counters of users and their source IPs.
+	UserOnline bool
 }
 
 // Buffer contains settings for internal buffer.
@@ -35,6 +37,20 @@ type Buffer struct {
 	PerConnection int32
 }
 
+// Bandwidth contains limits for user throughput. All connections of a user
+// share the limits, as do all connections of users without an email on the
+// same level.
+type Bandwidth struct {
+	// Maximum uplink throughput per user, in bytes per second. 0 for unlimited.
+	Uplink uint64
+	// Maximum downlink throughput per user, in bytes per second. 0 for unlimited.
+	Downlink uint64
+	// Maximum bytes of uplink traffic allowed in a burst. 0 for the limit of one second.
+	UplinkBurst uint64
+	// Maximum bytes of downlink traffic allowed in a burst. 0 for the limit of one second.
+	DownlinkBurst uint64
+}
+
 // SystemStats contains stat policy settings on system level.
 type SystemStats struct {
 	// Whether or not to enable stat counter for uplink traffic in inbound handlers.
@@ -45,6 +61,8 @@ type SystemStats struct {
 	OutboundUplink bool
 	// Whether or not to enable stat counter for downlink traffic in outbound handlers.
 	OutboundDownlink bool
+	// Whether or not to record dial latencies of outbound handlers in histograms.
+	OutboundDialLatency bool
 }
 
 // System contains policy settings at system level.
@@ -55,9 +73,14 @@ type System struct {
 
 // Session is session based settings for controlling V2Ray requests. It contains various settings (or limits) that may differ for different users in the context.
 type Session struct {
-	Timeouts Timeout // Timeout settings
-	Stats    Stats
-	Buffer   Buffer
+	Timeouts  Timeout // Timeout settings
+	Stats     Stats
+	Buffer    Buffer
+	Bandwidth Bandwidth
+	// Maximum concurrent connections of users in this level. 0 for unlimited.
+	MaxConnections uint32
+	// Tag of the DNS server for lookups of users in this level. Empty for the default servers.
+	DNSServer string
 }
 
 // Manager is a feature that provides Policy for the given user by its id or level.
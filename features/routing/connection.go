@@ -0,0 +1,26 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+// Connection is a snapshot of a connection being dispatched.
+type Connection struct {
+	ID          uint64
+	InboundTag  string
+	OutboundTag string
+	Source      net.Destination
+	Destination net.Destination
+	// Uplink and Downlink are the bytes transferred so far.
+	Uplink   int64
+	Downlink int64
+	Start    time.Time
+}
+
+// ConnectionTracker is a Dispatcher that keeps track of its live connections.
+type ConnectionTracker interface {
+	// Connections returns the live connections.
+	Connections() []*Connection
+}
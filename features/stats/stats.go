@@ -4,6 +4,8 @@ package stats
 
 import (
 	"context"
+	"math"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/features"
@@ -21,6 +23,81 @@ type Counter interface {
 	Add(int64) int64
 }
 
+// Histogram is the interface for stats histograms, which count observed values
+// in buckets.
+//
+// v2ray:api:beta
+type Histogram interface {
+	// Observe records a value in the bucket it falls in.
+	Observe(int64)
+	// Snapshot returns the current distribution of the histogram, and resets it if reset is true.
+	Snapshot(reset bool) HistogramSnapshot
+}
+
+// HistogramSnapshot is the distribution of a Histogram at some point.
+type HistogramSnapshot struct {
+	// Bounds are the inclusive upper bounds of buckets in ascending order.
+	Bounds []int64
+	// Counts are the numbers of values in each bucket. It has one more element than Bounds, for values above the last bound.
+	Counts []int64
+	// Count is the number of all values.
+	Count int64
+	// Sum is the sum of all values.
+	Sum int64
+}
+
+// Percentile estimates the value below which the fraction p of values fall,
+// by interpolating linearly within the bucket. Values above the last bound are
+// estimated as the last bound.
+func (s HistogramSnapshot) Percentile(p float64) int64 {
+	var total int64
+	for _, count := range s.Counts {
+		total += count
+	}
+	if total == 0 || len(s.Bounds) == 0 {
+		return 0
+	}
+	if p < 0 {
+		p = 0
+	} else if p > 1 {
+		p = 1
+	}
+	rank := p * float64(total)
+	var cumulative int64
+	for i, count := range s.Counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(s.Bounds) {
+			break
+		}
+		var lower int64
+		if i > 0 {
+			lower = s.Bounds[i-1]
+		} else if s.Bounds[0] < 0 {
+			lower = s.Bounds[0]
+		}
+		upper := s.Bounds[i]
+		fraction := (rank - float64(cumulative)) / float64(count)
+		return lower + int64(math.Round(fraction*float64(upper-lower)))
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+// OnlineCounter is the interface for counters of distinct keys, such as user
+// emails or source IPs, seen within a sliding window.
+//
+// v2ray:api:beta
+type OnlineCounter interface {
+	// Add records that the key is seen now.
+	Add(key string)
+	// Count returns the number of distinct keys seen within the window.
+	Count() int64
+	// Keys returns the distinct keys seen within the window.
+	Keys() []string
+}
+
 // Channel is the interface for stats channel.
 //
 // v2ray:api:stable
@@ -71,6 +148,20 @@ type Manager interface {
 	// GetCounter returns a counter by its identifier.
 	GetCounter(string) Counter
 
+	// RegisterHistogram registers a new histogram with the given bucket bounds to the manager. The identifier string must not be empty, and unique among other histograms.
+	RegisterHistogram(string, []int64) (Histogram, error)
+	// UnregisterHistogram unregisters a histogram from the manager by its identifier.
+	UnregisterHistogram(string) error
+	// GetHistogram returns a histogram by its identifier.
+	GetHistogram(string) Histogram
+
+	// RegisterOnlineCounter registers a new online counter with the given window to the manager. The identifier string must not be empty, and unique among other online counters.
+	RegisterOnlineCounter(string, time.Duration) (OnlineCounter, error)
+	// UnregisterOnlineCounter unregisters an online counter from the manager by its identifier.
+	UnregisterOnlineCounter(string) error
+	// GetOnlineCounter returns an online counter by its identifier.
+	GetOnlineCounter(string) OnlineCounter
+
 	// RegisterChannel registers a new channel to the manager. The identifier string must not be empty, and unique among other channels.
 	RegisterChannel(string) (Channel, error)
 	// UnregisterCounter unregisters a channel from the manager by its identifier.
@@ -89,6 +180,26 @@ func GetOrRegisterCounter(m Manager, name string) (Counter, error) {
 	return m.RegisterCounter(name)
 }
 
+// GetOrRegisterHistogram tries to get the Histogram first. If not exist, it then tries to create a new histogram with the given bucket bounds.
+func GetOrRegisterHistogram(m Manager, name string, bounds []int64) (Histogram, error) {
+	histogram := m.GetHistogram(name)
+	if histogram != nil {
+		return histogram, nil
+	}
+
+	return m.RegisterHistogram(name, bounds)
+}
+
+// GetOrRegisterOnlineCounter tries to get the OnlineCounter first. If not exist, it then tries to create a new online counter with the given window.
+func GetOrRegisterOnlineCounter(m Manager, name string, window time.Duration) (OnlineCounter, error) {
+	counter := m.GetOnlineCounter(name)
+	if counter != nil {
+		return counter, nil
+	}
+
+	return m.RegisterOnlineCounter(name, window)
+}
+
 // GetOrRegisterChannel tries to get the StatChannel first. If not exist, it then tries to create a new channel.
 func GetOrRegisterChannel(m Manager, name string) (Channel, error) {
 	channel := m.GetChannel(name)
@@ -129,6 +240,36 @@ func (NoopManager) GetCounter(string) Counter {
 	return nil
 }
 
+// RegisterHistogram implements Manager.
+func (NoopManager) RegisterHistogram(string, []int64) (Histogram, error) {
+	return nil, newError("not implemented")
+}
+
+// UnregisterHistogram implements Manager.
+func (NoopManager) UnregisterHistogram(string) error {
+	return nil
+}
+
+// GetHistogram implements Manager.
+func (NoopManager) GetHistogram(string) Histogram {
+	return nil
+}
+
+// RegisterOnlineCounter implements Manager.
+func (NoopManager) RegisterOnlineCounter(string, time.Duration) (OnlineCounter, error) {
+	return nil, newError("not implemented")
+}
+
+// UnregisterOnlineCounter implements Manager.
+func (NoopManager) UnregisterOnlineCounter(string) error {
+	return nil
+}
+
+// GetOnlineCounter implements Manager.
+func (NoopManager) GetOnlineCounter(string) OnlineCounter {
+	return nil
+}
+
 // RegisterChannel implements Manager.
 func (NoopManager) RegisterChannel(string) (Channel, error) {
 	return nil, newError("not implemented")
@@ -9,6 +9,7 @@ type MuxConfig struct {
 	Enabled        bool   `json:"enabled"`
 	Concurrency    int16  `json:"concurrency"`
 	PacketEncoding string `json:"packetEncoding"`
+	IdleTimeout    uint32 `json:"idleTimeout"`
 }
 
 // Build creates MultiplexingConfig, Concurrency < 0 completely disables mux.
@@ -34,5 +35,6 @@ func (m *MuxConfig) Build() *proxyman.MultiplexingConfig {
 		Enabled:        m.Enabled,
 		Concurrency:    con,
 		PacketEncoding: packetEncoding,
+		IdleTimeout:    m.IdleTimeout,
 	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/duration"
 )
 
 //go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
@@ -14,6 +15,7 @@ type SniffingConfig struct {
 	DestOverride *cfgcommon.StringList `json:"destOverride"`
 	MetadataOnly bool                  `json:"metadataOnly"`
 	RouteOnly    bool                  `json:"routeOnly"`
+	Timeout      duration.Duration     `json:"timeout"`
 }
 
 // Build implements Buildable.
@@ -39,5 +41,6 @@ func (c *SniffingConfig) Build() (*proxyman.SniffingConfig, error) {
 		DestinationOverride: p,
 		MetadataOnly:        c.MetadataOnly,
 		RouteOnly:           c.RouteOnly,
+		Timeout:             int64(c.Timeout),
 	}, nil
 }
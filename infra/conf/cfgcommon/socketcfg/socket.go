@@ -3,17 +3,28 @@ package socketcfg
 import (
 	"strings"
 
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/duration"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 )
 
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
 type SocketConfig struct {
-	Mark                 uint32 `json:"mark"`
-	TFO                  *bool  `json:"tcpFastOpen"`
-	TProxy               string `json:"tproxy"`
-	AcceptProxyProtocol  bool   `json:"acceptProxyProtocol"`
-	TCPKeepAliveInterval int32  `json:"tcpKeepAliveInterval"`
-	TCPKeepAliveIdle     int32  `json:"tcpKeepAliveIdle"`
-	TFOQueueLength       uint32 `json:"tcpFastOpenQueueLength"`
+	Mark                  uint32            `json:"mark"`
+	TFO                   *bool             `json:"tcpFastOpen"`
+	TProxy                string            `json:"tproxy"`
+	AcceptProxyProtocol   bool              `json:"acceptProxyProtocol"`
+	TCPKeepAliveInterval  int32             `json:"tcpKeepAliveInterval"`
+	TCPKeepAliveIdle      int32             `json:"tcpKeepAliveIdle"`
+	TFOQueueLength        uint32            `json:"tcpFastOpenQueueLength"`
+	SendBufferSize        int32             `json:"sendBufferSize"`
+	ReceiveBufferSize     int32             `json:"receiveBufferSize"`
+	DialTimeout           duration.Duration `json:"dialTimeout"`
+	DialerProxy           string            `json:"dialerProxy"`
+	BindToDevice          string            `json:"bindToDevice"`
+	SourceAddress         string            `json:"sourceAddress"`
+	ProxyProtocolOptional bool              `json:"proxyProtocolOptional"`
 }
 
 // Build implements Buildable.
@@ -32,6 +43,26 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		tfoQueueLength = 4096
 	}
 
+	if c.SendBufferSize < 0 {
+		return nil, newError("invalid sendBufferSize: ", c.SendBufferSize)
+	}
+	if c.ReceiveBufferSize < 0 {
+		return nil, newError("invalid receiveBufferSize: ", c.ReceiveBufferSize)
+	}
+
+	if c.DialTimeout < 0 {
+		return nil, newError("invalid dialTimeout: ", c.DialTimeout)
+	}
+
+	var sourceAddress []byte
+	if c.SourceAddress != "" {
+		address := net.ParseAddress(c.SourceAddress)
+		if !address.Family().IsIP() {
+			return nil, newError("invalid sourceAddress: ", c.SourceAddress)
+		}
+		sourceAddress = address.IP()
+	}
+
 	var tproxy internet.SocketConfig_TProxyMode
 	switch strings.ToLower(c.TProxy) {
 	case "tproxy":
@@ -43,12 +74,19 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 	}
 
 	return &internet.SocketConfig{
-		Mark:                 c.Mark,
-		Tfo:                  tfoSettings,
-		TfoQueueLength:       tfoQueueLength,
-		Tproxy:               tproxy,
-		AcceptProxyProtocol:  c.AcceptProxyProtocol,
-		TcpKeepAliveInterval: c.TCPKeepAliveInterval,
-		TcpKeepAliveIdle:     c.TCPKeepAliveIdle,
+		Mark:                  c.Mark,
+		Tfo:                   tfoSettings,
+		TfoQueueLength:        tfoQueueLength,
+		Tproxy:                tproxy,
+		AcceptProxyProtocol:   c.AcceptProxyProtocol,
+		TcpKeepAliveInterval:  c.TCPKeepAliveInterval,
+		TcpKeepAliveIdle:      c.TCPKeepAliveIdle,
+		SendBufferSize:        c.SendBufferSize,
+		ReceiveBufferSize:     c.ReceiveBufferSize,
+		DialTimeout:           int64(c.DialTimeout),
+		DialerProxy:           c.DialerProxy,
+		BindToDevice:          c.BindToDevice,
+		SourceAddress:         sourceAddress,
+		ProxyProtocolOptional: c.ProxyProtocolOptional,
 	}, nil
 }
@@ -13,14 +13,17 @@ import (
 //go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
 
 type TLSConfig struct {
-	Insecure                         bool                  `json:"allowInsecure"`
-	Certs                            []*TLSCertConfig      `json:"certificates"`
-	ServerName                       string                `json:"serverName"`
-	ALPN                             *cfgcommon.StringList `json:"alpn"`
-	EnableSessionResumption          bool                  `json:"enableSessionResumption"`
-	DisableSystemRoot                bool                  `json:"disableSystemRoot"`
-	PinnedPeerCertificateChainSha256 *[]string             `json:"pinnedPeerCertificateChainSha256"`
-	VerifyClientCertificate          bool                  `json:"verifyClientCertificate"`
+	Insecure                             bool                  `json:"allowInsecure"`
+	Certs                                []*TLSCertConfig      `json:"certificates"`
+	ServerName                           string                `json:"serverName"`
+	ALPN                                 *cfgcommon.StringList `json:"alpn"`
+	EnableSessionResumption              bool                  `json:"enableSessionResumption"`
+	DisableSystemRoot                    bool                  `json:"disableSystemRoot"`
+	PinnedPeerCertificateChainSha256     *[]string             `json:"pinnedPeerCertificateChainSha256"`
+	PinnedPeerCertificatePublicKeySha256 *[]string             `json:"pinnedPeerCertificatePublicKeySha256"`
+	VerifyClientCertificate              bool                  `json:"verifyClientCertificate"`
+	ECHConfigList                        string                `json:"echConfigList"`
+	ECHDNSServer                         string                `json:"echDnsServer"`
 }
 
 // Build implements Buildable.
@@ -57,15 +60,41 @@ func (c *TLSConfig) Build() (proto.Message, error) {
 		}
 	}
 
+	if c.PinnedPeerCertificatePublicKeySha256 != nil {
+		config.PinnedPeerCertificatePublicKeySha256 = [][]byte{}
+		for _, v := range *c.PinnedPeerCertificatePublicKeySha256 {
+			hashValue, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, err
+			}
+			config.PinnedPeerCertificatePublicKeySha256 = append(config.PinnedPeerCertificatePublicKeySha256, hashValue)
+		}
+	}
+
+	if c.ECHConfigList != "" {
+		configList, err := base64.StdEncoding.DecodeString(c.ECHConfigList)
+		if err != nil {
+			return nil, newError("invalid ECH config list").Base(err)
+		}
+		config.EchConfigList = configList
+	}
+	config.EchDnsServer = c.ECHDNSServer
+
 	return config, nil
 }
 
+type TLSExternalKeyConfig struct {
+	Provider string `json:"provider"`
+	KeyID    string `json:"keyId"`
+}
+
 type TLSCertConfig struct {
-	CertFile string   `json:"certificateFile"`
-	CertStr  []string `json:"certificate"`
-	KeyFile  string   `json:"keyFile"`
-	KeyStr   []string `json:"key"`
-	Usage    string   `json:"usage"`
+	CertFile    string                `json:"certificateFile"`
+	CertStr     []string              `json:"certificate"`
+	KeyFile     string                `json:"keyFile"`
+	KeyStr      []string              `json:"key"`
+	ExternalKey *TLSExternalKeyConfig `json:"externalKey"`
+	Usage       string                `json:"usage"`
 }
 
 // Build implements Buildable.
@@ -77,6 +106,7 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 		return nil, newError("failed to parse certificate").Base(err)
 	}
 	certificate.Certificate = cert
+	certificate.CertificateFile = c.CertFile
 
 	if len(c.KeyFile) > 0 || len(c.KeyStr) > 0 {
 		key, err := readFileOrString(c.KeyFile, c.KeyStr)
@@ -84,6 +114,20 @@ func (c *TLSCertConfig) Build() (*tls.Certificate, error) {
 			return nil, newError("failed to parse key").Base(err)
 		}
 		certificate.Key = key
+		certificate.KeyFile = c.KeyFile
+	}
+
+	if c.ExternalKey != nil {
+		if len(certificate.Key) > 0 {
+			return nil, newError("key and external key are mutually exclusive")
+		}
+		if c.ExternalKey.Provider == "" {
+			return nil, newError("empty external key provider")
+		}
+		certificate.ExternalKey = &tls.ExternalKey{
+			Provider: c.ExternalKey.Provider,
+			KeyId:    c.ExternalKey.KeyID,
+		}
 	}
 
 	switch strings.ToLower(c.Usage) {
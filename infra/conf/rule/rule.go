@@ -201,6 +201,43 @@ func toCidrList(ctx context.Context, ips cfgcommon.StringList) ([]*routercommon.
 	return geoipList, nil
 }
 
+type ScheduleConfig struct {
+	TimeRange *cfgcommon.StringList `json:"timeRange"`
+	Weekday   *cfgcommon.StringList `json:"weekday"`
+	Timezone  string                `json:"timezone"`
+}
+
+func (c *ScheduleConfig) Build() *router.TimeSchedule {
+	schedule := &router.TimeSchedule{
+		Timezone: c.Timezone,
+	}
+	if c.TimeRange != nil {
+		schedule.TimeRange = *c.TimeRange
+	}
+	if c.Weekday != nil {
+		schedule.Weekday = *c.Weekday
+	}
+	return schedule
+}
+
+// AttributesConfig is the "attrs" condition of a rule, either a Starlark
+// expression of attrs, or an object of regular expressions of attributes by
+// name.
+type AttributesConfig struct {
+	Expression string
+	Regexp     map[string]string
+}
+
+func (c *AttributesConfig) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.Expression); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &c.Regexp); err != nil {
+		return newError("attrs must be an expression or an object of regular expressions").Base(err)
+	}
+	return nil
+}
+
 func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRule, error) {
 	type RawFieldRule struct {
 		RouterRule
@@ -214,10 +251,12 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		User         *cfgcommon.StringList  `json:"user"`
 		InboundTag   *cfgcommon.StringList  `json:"inboundTag"`
 		Protocols    *cfgcommon.StringList  `json:"protocol"`
-		Attributes   string                 `json:"attrs"`
+		Attributes   *AttributesConfig      `json:"attrs"`
 		UidList      *cfgcommon.UidList     `json:"uidList"`
 		WifiSSIDList *cfgcommon.StringList  `json:"ssidList"`
 		NetworkType  string                 `json:"networkType"`
+		Schedule     *ScheduleConfig        `json:"schedule"`
+		PTRDomain    *cfgcommon.StringList  `json:"ptrDomain"`
 	}
 	rawFieldRule := new(RawFieldRule)
 	err := json.Unmarshal(msg, rawFieldRule)
@@ -263,6 +302,16 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		}
 	}
 
+	if rawFieldRule.PTRDomain != nil {
+		for _, domain := range *rawFieldRule.PTRDomain {
+			rules, err := parseDomainRule(ctx, domain)
+			if err != nil {
+				return nil, newError("failed to parse PTR domain rule: ", domain).Base(err)
+			}
+			rule.PtrDomain = append(rule.PtrDomain, rules...)
+		}
+	}
+
 	if rawFieldRule.IP != nil {
 		geoipList, err := toCidrList(ctx, *rawFieldRule.IP)
 		if err != nil {
@@ -309,8 +358,9 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		}
 	}
 
-	if len(rawFieldRule.Attributes) > 0 {
-		rule.Attributes = rawFieldRule.Attributes
+	if rawFieldRule.Attributes != nil {
+		rule.Attributes = rawFieldRule.Attributes.Expression
+		rule.AttributeRegexp = rawFieldRule.Attributes.Regexp
 	}
 
 	if rawFieldRule.UidList != nil && len(*rawFieldRule.UidList) > 0 {
@@ -321,6 +371,10 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		rule.WifiSsidList = *rawFieldRule.WifiSSIDList
 	}
 
+	if rawFieldRule.Schedule != nil {
+		rule.Schedule = rawFieldRule.Schedule.Build()
+	}
+
 	return rule, nil
 }
 
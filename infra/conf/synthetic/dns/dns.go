@@ -19,13 +19,18 @@ import (
 )
 
 type NameServerConfig struct {
-	Address      *cfgcommon.Address
-	ClientIP     *cfgcommon.Address
-	Port         uint16
-	SkipFallback bool
-	Domains      []string
-	ExpectIPs    cfgcommon.StringList
-	Concurrency  bool
+	Address            *cfgcommon.Address
+	ClientIP           *cfgcommon.Address
+	ClientIPPrefixV4   *uint32
+	ClientIPPrefixV6   *uint32
+	ClientIPFromSource bool
+	Port               uint16
+	SkipFallback       bool
+	Domains            []string
+	ExpectIPs          cfgcommon.StringList
+	Concurrency        bool
+	QueryStrategy      string
+	Tag                string
 
 	cfgctx context.Context
 }
@@ -36,22 +41,32 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) (err error) {
 		c.Address = &address
 	} else {
 		var advanced struct {
-			Address      *cfgcommon.Address   `json:"address"`
-			ClientIP     *cfgcommon.Address   `json:"clientIp"`
-			Port         uint16               `json:"port"`
-			SkipFallback bool                 `json:"skipFallback"`
-			Domains      []string             `json:"domains"`
-			ExpectIPs    cfgcommon.StringList `json:"expectIps"`
-			Concurrency  bool                 `json:"concurrency"`
+			Address            *cfgcommon.Address   `json:"address"`
+			ClientIP           *cfgcommon.Address   `json:"clientIp"`
+			ClientIPPrefixV4   *uint32              `json:"clientIpPrefixV4"`
+			ClientIPPrefixV6   *uint32              `json:"clientIpPrefixV6"`
+			ClientIPFromSource bool                 `json:"clientIpFromSource"`
+			Port               uint16               `json:"port"`
+			SkipFallback       bool                 `json:"skipFallback"`
+			Domains            []string             `json:"domains"`
+			ExpectIPs          cfgcommon.StringList `json:"expectIps"`
+			Concurrency        bool                 `json:"concurrency"`
+			QueryStrategy      string               `json:"queryStrategy"`
+			Tag                string               `json:"tag"`
 		}
 		if err = json.Unmarshal(data, &advanced); err == nil {
 			c.Address = advanced.Address
 			c.ClientIP = advanced.ClientIP
+			c.ClientIPPrefixV4 = advanced.ClientIPPrefixV4
+			c.ClientIPPrefixV6 = advanced.ClientIPPrefixV6
+			c.ClientIPFromSource = advanced.ClientIPFromSource
 			c.Port = advanced.Port
 			c.SkipFallback = advanced.SkipFallback
 			c.Domains = advanced.Domains
 			c.ExpectIPs = advanced.ExpectIPs
 			c.Concurrency = advanced.Concurrency
+			c.QueryStrategy = advanced.QueryStrategy
+			c.Tag = advanced.Tag
 		}
 	}
 
@@ -131,6 +146,12 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		}
 		myClientIP = []byte(c.ClientIP.IP())
 	}
+	if c.ClientIPPrefixV4 != nil && *c.ClientIPPrefixV4 > 32 {
+		return nil, newError("invalid IPv4 client subnet prefix: ", *c.ClientIPPrefixV4)
+	}
+	if c.ClientIPPrefixV6 != nil && *c.ClientIPPrefixV6 > 128 {
+		return nil, newError("invalid IPv6 client subnet prefix: ", *c.ClientIPPrefixV6)
+	}
 
 	return &dns.NameServer{
 		Address: &net.Endpoint{
@@ -138,15 +159,32 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 			Address: c.Address.Build(),
 			Port:    uint32(c.Port),
 		},
-		ClientIp:          myClientIP,
-		SkipFallback:      c.SkipFallback,
-		PrioritizedDomain: domains,
-		Geoip:             geoipList,
-		OriginalRules:     originalRules,
-		Concurrency:       c.Concurrency,
+		ClientIp:           myClientIP,
+		ClientIpPrefixV4:   c.ClientIPPrefixV4,
+		ClientIpPrefixV6:   c.ClientIPPrefixV6,
+		ClientIpFromSource: c.ClientIPFromSource,
+		SkipFallback:       c.SkipFallback,
+		PrioritizedDomain:  domains,
+		Geoip:              geoipList,
+		OriginalRules:      originalRules,
+		Concurrency:        c.Concurrency,
+		QueryStrategy:      parseQueryStrategy(c.QueryStrategy),
+		Tag:                c.Tag,
 	}, nil
 }
 
+// parseQueryStrategy parses the query strategy of the servers, UseIP if unknown.
+func parseQueryStrategy(s string) dns.QueryStrategy {
+	switch strings.ToLower(s) {
+	case "useip4", "useipv4", "use_ip4", "use_ipv4", "use_ip_v4", "use-ip4", "use-ipv4", "use-ip-v4":
+		return dns.QueryStrategy_USE_IP4
+	case "useip6", "useipv6", "use_ip6", "use_ipv6", "use_ip_v6", "use-ip6", "use-ipv6", "use-ip-v6":
+		return dns.QueryStrategy_USE_IP6
+	default:
+		return dns.QueryStrategy_USE_IP
+	}
+}
+
 var typeMap = map[routercommon.Domain_Type]dns.DomainMatchingType{
 	routercommon.Domain_Full:       dns.DomainMatchingType_Full,
 	routercommon.Domain_RootDomain: dns.DomainMatchingType_Subdomain,
@@ -165,6 +203,10 @@ type DNSConfig struct { // nolint: revive
 	DisableFallback        bool                    `json:"disableFallback"`
 	DisableFallbackIfMatch bool                    `json:"disableFallbackIfMatch"`
 	DisableExpire          bool                    `json:"disableExpire"`
+	DisableNegativeCache   bool                    `json:"disableNegativeCache"`
+	NegativeCacheMaxTTL    uint32                  `json:"negativeCacheMaxTtl"`
+	DNSSECValidation       bool                    `json:"dnssecValidation"`
+	DNSSECTrustAnchor      []string                `json:"dnssecTrustAnchor"`
 	cfgctx                 context.Context
 }
 
@@ -188,30 +230,36 @@ func (h *HostAddress) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func getHostMapping(ha *HostAddress) *dns.HostMapping {
+// appendHostMappings appends the mappings of domain of the given type to ha to
+// mappings. The IP addresses of ha are in one mapping, and each domain in one
+// more.
+func appendHostMappings(mappings []*dns.HostMapping, ha *HostAddress, t dns.DomainMatchingType, domain string) []*dns.HostMapping {
+	addrs := ha.addrs
 	if ha.addr != nil {
-		if ha.addr.Family().IsDomain() {
-			return &dns.HostMapping{
-				ProxiedDomain: ha.addr.Domain(),
-			}
-		}
-		return &dns.HostMapping{
-			Ip: [][]byte{ha.addr.IP()},
-		}
+		addrs = []*cfgcommon.Address{ha.addr}
 	}
 
-	ips := make([][]byte, 0, len(ha.addrs))
-	for _, addr := range ha.addrs {
+	var ips [][]byte
+	var proxied []*dns.HostMapping
+	for _, addr := range addrs {
 		if addr.Family().IsDomain() {
-			return &dns.HostMapping{
+			proxied = append(proxied, &dns.HostMapping{
+				Type:          t,
+				Domain:        domain,
 				ProxiedDomain: addr.Domain(),
-			}
+			})
+			continue
 		}
 		ips = append(ips, []byte(addr.IP()))
 	}
-	return &dns.HostMapping{
-		Ip: ips,
+	if len(ips) > 0 || len(proxied) == 0 {
+		mappings = append(mappings, &dns.HostMapping{
+			Type:   t,
+			Domain: domain,
+			Ip:     ips,
+		})
 	}
+	return append(mappings, proxied...)
 }
 
 func (c *DNSConfig) BuildV5(ctx context.Context) (*dns.Config, error) {
@@ -244,6 +292,10 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		DisableFallback:        c.DisableFallback,
 		DisableFallbackIfMatch: c.DisableFallbackIfMatch,
 		DisableExpire:          c.DisableExpire,
+		DisableNegativeCache:   c.DisableNegativeCache,
+		NegativeCacheMaxTtl:    c.NegativeCacheMaxTTL,
+		DnssecValidation:       c.DNSSECValidation,
+		DnssecTrustAnchor:      c.DNSSECTrustAnchor,
 	}
 
 	if c.ClientIP != nil {
@@ -253,15 +305,7 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		config.ClientIp = []byte(c.ClientIP.IP())
 	}
 
-	config.QueryStrategy = dns.QueryStrategy_USE_IP
-	switch strings.ToLower(c.QueryStrategy) {
-	case "useip", "use_ip", "use-ip":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP
-	case "useip4", "useipv4", "use_ip4", "use_ipv4", "use_ip_v4", "use-ip4", "use-ipv4", "use-ip-v4":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP4
-	case "useip6", "useipv6", "use_ip6", "use_ipv6", "use_ip_v6", "use-ip6", "use-ipv6", "use-ip-v6":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP6
-	}
+	config.QueryStrategy = parseQueryStrategy(c.QueryStrategy)
 
 	for _, server := range c.Servers {
 		server.cfgctx = c.cfgctx
@@ -288,10 +332,7 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 				if len(domainName) == 0 {
 					return nil, newError("empty domain type of rule: ", domain)
 				}
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Subdomain
-				mapping.Domain = domainName
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Subdomain, domainName)
 
 			case strings.HasPrefix(domain, "geosite:"):
 				listName := domain[8:]
@@ -303,10 +344,7 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 					return nil, newError("failed to load geosite: ", listName).Base(err)
 				}
 				for _, d := range geositeList {
-					mapping := getHostMapping(c.Hosts[domain])
-					mapping.Type = typeMap[d.Type]
-					mapping.Domain = d.Value
-					mappings = append(mappings, mapping)
+					mappings = appendHostMappings(mappings, c.Hosts[domain], typeMap[d.Type], d.Value)
 				}
 
 			case strings.HasPrefix(domain, "regexp:"):
@@ -314,43 +352,33 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 				if len(regexpVal) == 0 {
 					return nil, newError("empty regexp type of rule: ", domain)
 				}
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Regex
-				mapping.Domain = regexpVal
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Regex, regexpVal)
 
 			case strings.HasPrefix(domain, "keyword:"):
 				keywordVal := domain[8:]
 				if len(keywordVal) == 0 {
 					return nil, newError("empty keyword type of rule: ", domain)
 				}
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Keyword
-				mapping.Domain = keywordVal
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Keyword, keywordVal)
 
 			case strings.HasPrefix(domain, "full:"):
 				fullVal := domain[5:]
 				if len(fullVal) == 0 {
 					return nil, newError("empty full domain type of rule: ", domain)
 				}
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Full
-				mapping.Domain = fullVal
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Full, fullVal)
 
 			case strings.HasPrefix(domain, "dotless:"):
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Regex
+				var regexpVal string
 				switch substr := domain[8:]; {
 				case substr == "":
-					mapping.Domain = "^[^.]*$"
+					regexpVal = "^[^.]*$"
 				case !strings.Contains(substr, "."):
-					mapping.Domain = "^[^.]*" + substr + "[^.]*$"
+					regexpVal = "^[^.]*" + substr + "[^.]*$"
 				default:
 					return nil, newError("substr in dotless rule should not contain a dot: ", substr)
 				}
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Regex, regexpVal)
 
 			case strings.HasPrefix(domain, "ext:"):
 				kv := strings.Split(domain[4:], ":")
@@ -364,17 +392,11 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 					return nil, newError("failed to load domain list: ", list, " from ", filename).Base(err)
 				}
 				for _, d := range geositeList {
-					mapping := getHostMapping(c.Hosts[domain])
-					mapping.Type = typeMap[d.Type]
-					mapping.Domain = d.Value
-					mappings = append(mappings, mapping)
+					mappings = appendHostMappings(mappings, c.Hosts[domain], typeMap[d.Type], d.Value)
 				}
 
 			default:
-				mapping := getHostMapping(c.Hosts[domain])
-				mapping.Type = dns.DomainMatchingType_Full
-				mapping.Domain = domain
-				mappings = append(mappings, mapping)
+				mappings = appendHostMappings(mappings, c.Hosts[domain], dns.DomainMatchingType_Full, domain)
 			}
 		}
 
@@ -18,6 +18,7 @@ type LogConfig struct { // nolint: revive
 	AccessLog string `json:"access"`
 	ErrorLog  string `json:"error"`
 	LogLevel  string `json:"loglevel"`
+	LogFormat string `json:"format"`
 }
 
 func (v *LogConfig) Build() *log.Config {
@@ -42,6 +43,11 @@ func (v *LogConfig) Build() *log.Config {
 		config.Error.Type = log.LogType_File
 	}
 
+	if strings.ToLower(v.LogFormat) == "json" {
+		config.Access.Format = log.LogFormat_JSON
+		config.Error.Format = log.LogFormat_JSON
+	}
+
 	level := strings.ToLower(v.LogLevel)
 	switch level {
 	case "debug":
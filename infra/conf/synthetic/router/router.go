@@ -52,6 +52,8 @@ func (r *BalancingRule) Build() (*router.BalancingRule, error) {
 		strategy = strategyLeastLoad
 	case strategyLeastPing:
 		strategy = "leastping"
+	case strategyWeighted:
+		strategy = strategyWeighted
 	default:
 		return nil, newError("unknown balancing strategy: " + r.Strategy.Type)
 	}
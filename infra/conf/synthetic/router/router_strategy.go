@@ -12,12 +12,14 @@ const (
 	strategyRandom    string = "random"
 	strategyLeastLoad string = "leastload"
 	strategyLeastPing string = "leastping"
+	strategyWeighted  string = "weighted"
 )
 
 var strategyConfigLoader = loader.NewJSONConfigLoader(loader.ConfigCreatorCache{
 	strategyRandom:    func() interface{} { return new(strategyEmptyConfig) },
 	strategyLeastLoad: func() interface{} { return new(strategyLeastLoadConfig) },
 	strategyLeastPing: func() interface{} { return new(strategyLeastPingConfig) },
+	strategyWeighted:  func() interface{} { return new(strategyWeightedConfig) },
 }, "type", "settings")
 
 type strategyEmptyConfig struct{}
@@ -48,15 +50,18 @@ type HealthCheckSettings struct {
 	Interval      duration.Duration `json:"interval"`
 	SamplingCount int               `json:"sampling"`
 	Timeout       duration.Duration `json:"timeout"`
+	// response status codes considered as success
+	ExpectedStatus []uint32 `json:"expectedStatus"`
 }
 
 func (h HealthCheckSettings) Build() (proto.Message, error) {
 	return &burst.HealthPingConfig{
-		Destination:   h.Destination,
-		Connectivity:  h.Connectivity,
-		Interval:      int64(h.Interval),
-		Timeout:       int64(h.Timeout),
-		SamplingCount: int32(h.SamplingCount),
+		Destination:    h.Destination,
+		Connectivity:   h.Connectivity,
+		Interval:       int64(h.Interval),
+		Timeout:        int64(h.Timeout),
+		SamplingCount:  int32(h.SamplingCount),
+		ExpectedStatus: h.ExpectedStatus,
 	}, nil
 }
 
@@ -92,8 +97,22 @@ func (v *strategyLeastLoadConfig) Build() (proto.Message, error) {
 
 type strategyLeastPingConfig struct {
 	ObserverTag string `json:"observerTag,omitempty"`
+	// observations older than it are considered unknown
+	MaxAge duration.Duration `json:"maxAge,omitempty"`
 }
 
 func (s strategyLeastPingConfig) Build() (proto.Message, error) {
-	return &router.StrategyLeastPingConfig{ObserverTag: s.ObserverTag}, nil
+	if s.MaxAge < 0 {
+		return nil, newError("negative maxAge of leastping strategy")
+	}
+	return &router.StrategyLeastPingConfig{ObserverTag: s.ObserverTag, MaxAge: int64(s.MaxAge)}, nil
+}
+
+type strategyWeightedConfig struct {
+	// weights of the outbounds by tag, 1 if absent
+	Weights map[string]int32 `json:"weights,omitempty"`
+}
+
+func (s strategyWeightedConfig) Build() (proto.Message, error) {
+	return &router.StrategyWeightedConfig{Weights: s.Weights}, nil
 }
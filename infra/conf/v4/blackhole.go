@@ -22,12 +22,38 @@ func (*HTTPResponse) Build() (proto.Message, error) {
 	return new(blackhole.HTTPResponse), nil
 }
 
+type RandomResponse struct {
+	MinLength uint32 `json:"minLength"`
+	MaxLength uint32 `json:"maxLength"`
+}
+
+func (v *RandomResponse) Build() (proto.Message, error) {
+	if v.MaxLength != 0 && v.MaxLength < v.MinLength {
+		return nil, newError("Config: Blackhole random response maxLength is smaller than minLength.")
+	}
+	if v.MinLength > blackhole.MaxRandomResponseLength || v.MaxLength > blackhole.MaxRandomResponseLength {
+		return nil, newError("Config: Blackhole random response length is larger than ", blackhole.MaxRandomResponseLength, ".")
+	}
+	return &blackhole.RandomResponse{
+		MinLength: v.MinLength,
+		MaxLength: v.MaxLength,
+	}, nil
+}
+
 type BlackholeConfig struct {
-	Response json.RawMessage `json:"response"`
+	Response      json.RawMessage `json:"response"`
+	MinCloseDelay uint32          `json:"minCloseDelay"`
+	MaxCloseDelay uint32          `json:"maxCloseDelay"`
 }
 
 func (v *BlackholeConfig) Build() (proto.Message, error) {
-	config := new(blackhole.Config)
+	if v.MaxCloseDelay != 0 && v.MaxCloseDelay < v.MinCloseDelay {
+		return nil, newError("Config: Blackhole maxCloseDelay is smaller than minCloseDelay.")
+	}
+	config := &blackhole.Config{
+		MinCloseDelayMs: v.MinCloseDelay,
+		MaxCloseDelayMs: v.MaxCloseDelay,
+	}
 	if v.Response != nil {
 		response, _, err := configLoader.Load(v.Response)
 		if err != nil {
@@ -45,8 +71,9 @@ func (v *BlackholeConfig) Build() (proto.Message, error) {
 
 var configLoader = loader.NewJSONConfigLoader(
 	loader.ConfigCreatorCache{
-		"none": func() interface{} { return new(NoneResponse) },
-		"http": func() interface{} { return new(HTTPResponse) },
+		"none":   func() interface{} { return new(NoneResponse) },
+		"http":   func() interface{} { return new(HTTPResponse) },
+		"random": func() interface{} { return new(RandomResponse) },
 	},
 	"type",
 	"")
@@ -27,6 +27,26 @@ func TestHTTPResponseJSON(t *testing.T) {
 				Response: serial.ToTypedMessage(&blackhole.HTTPResponse{}),
 			},
 		},
+		{
+			Input: `{
+				"response": {
+					"type": "random",
+					"minLength": 16,
+					"maxLength": 512
+				},
+				"minCloseDelay": 100,
+				"maxCloseDelay": 3000
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &blackhole.Config{
+				Response: serial.ToTypedMessage(&blackhole.RandomResponse{
+					MinLength: 16,
+					MaxLength: 512,
+				}),
+				MinCloseDelayMs: 100,
+				MaxCloseDelayMs: 3000,
+			},
+		},
 		{
 			Input:  `{}`,
 			Parser: testassist.LoadJSON(creator),
@@ -34,3 +54,17 @@ func TestHTTPResponseJSON(t *testing.T) {
 		},
 	})
 }
+
+func TestRandomResponseTooLong(t *testing.T) {
+	for _, response := range []*v4.RandomResponse{
+		{MinLength: blackhole.MaxRandomResponseLength + 1, MaxLength: blackhole.MaxRandomResponseLength + 1},
+		{MinLength: 16, MaxLength: blackhole.MaxRandomResponseLength + 1},
+	} {
+		if _, err := response.Build(); err == nil {
+			t.Error("expected error for ", response.MinLength, "-", response.MaxLength, " bytes")
+		}
+	}
+	if _, err := (&v4.RandomResponse{MaxLength: blackhole.MaxRandomResponseLength}).Build(); err != nil {
+		t.Error(err)
+	}
+}
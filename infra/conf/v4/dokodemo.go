@@ -7,12 +7,13 @@ import (
 )
 
 type DokodemoConfig struct {
-	Host         *cfgcommon.Address     `json:"address"`
-	PortValue    uint16                 `json:"port"`
-	NetworkList  *cfgcommon.NetworkList `json:"network"`
-	TimeoutValue uint32                 `json:"timeout"`
-	Redirect     bool                   `json:"followRedirect"`
-	UserLevel    uint32                 `json:"userLevel"`
+	Host           *cfgcommon.Address     `json:"address"`
+	PortValue      uint16                 `json:"port"`
+	NetworkList    *cfgcommon.NetworkList `json:"network"`
+	TimeoutValue   uint32                 `json:"timeout"`
+	Redirect       bool                   `json:"followRedirect"`
+	UserLevel      uint32                 `json:"userLevel"`
+	SniffedAddress bool                   `json:"overrideWithSniffedDomain"`
 }
 
 func (v *DokodemoConfig) Build() (proto.Message, error) {
@@ -25,5 +26,6 @@ func (v *DokodemoConfig) Build() (proto.Message, error) {
 	config.Timeout = v.TimeoutValue
 	config.FollowRedirect = v.Redirect
 	config.UserLevel = v.UserLevel
+	config.OverrideWithSniffedDomain = v.SniffedAddress
 	return config, nil
 }
@@ -6,12 +6,17 @@ import (
 )
 
 type GunConfig struct {
-	ServiceName         string `json:"serviceName"`
-	Mode                string `json:"mode"`
-	IdleTimeout         int32  `json:"idle_timeout"`
-	HealthCheckTimeout  int32  `json:"health_check_timeout"`
-	PermitWithoutStream bool   `json:"permit_without_stream"`
-	InitialWindowsSize  int32  `json:"initial_windows_size"`
+	ServiceName            string `json:"serviceName"`
+	Mode                   string `json:"mode"`
+	IdleTimeout            int32  `json:"idle_timeout"`
+	HealthCheckTimeout     int32  `json:"health_check_timeout"`
+	PermitWithoutStream    bool   `json:"permit_without_stream"`
+	InitialWindowsSize     int32  `json:"initial_windows_size"`
+	MaxConnsPerStream      int32  `json:"max_connections_per_stream"`
+	InitialConnWindow      int32  `json:"initial_conn_window_size"`
+	ServerKeepaliveTime    int32  `json:"server_keepalive_time"`
+	ServerKeepaliveTimeout int32  `json:"server_keepalive_timeout"`
+	DrainTimeout           int32  `json:"drain_timeout"`
 }
 
 func (g GunConfig) Build() (proto.Message, error) {
@@ -23,6 +28,8 @@ func (g GunConfig) Build() (proto.Message, error) {
 		mode = grpc.Mode_Multi
 	case "raw":
 		mode = grpc.Mode_Raw
+	case "mux":
+		mode = grpc.Mode_Mux
 	default:
 		return nil, newError("undefined grpc mode: ", g.Mode)
 	}
@@ -36,12 +43,36 @@ func (g GunConfig) Build() (proto.Message, error) {
 	if g.InitialWindowsSize < 0 {
 		g.InitialWindowsSize = 0
 	}
-	return &grpc.Config{
-		ServiceName:         g.ServiceName,
-		Mode:                mode,
-		IdleTimeout:         g.IdleTimeout,
-		HealthCheckTimeout:  g.HealthCheckTimeout,
-		PermitWithoutStream: g.PermitWithoutStream,
-		InitialWindowsSize:  g.InitialWindowsSize,
-	}, nil
+	if g.MaxConnsPerStream < 0 {
+		g.MaxConnsPerStream = 0
+	}
+	if g.InitialConnWindow < 0 {
+		g.InitialConnWindow = 0
+	}
+	if g.ServerKeepaliveTime < 0 {
+		g.ServerKeepaliveTime = 0
+	}
+	if g.ServerKeepaliveTimeout < 0 {
+		g.ServerKeepaliveTimeout = 0
+	}
+	if g.DrainTimeout < 0 {
+		g.DrainTimeout = 0
+	}
+	config := &grpc.Config{
+		ServiceName:             g.ServiceName,
+		Mode:                    mode,
+		IdleTimeout:             g.IdleTimeout,
+		HealthCheckTimeout:      g.HealthCheckTimeout,
+		PermitWithoutStream:     g.PermitWithoutStream,
+		InitialWindowsSize:      g.InitialWindowsSize,
+		MaxConnectionsPerStream: g.MaxConnsPerStream,
+		InitialConnWindowSize:   g.InitialConnWindow,
+		ServerKeepaliveTime:     g.ServerKeepaliveTime,
+		ServerKeepaliveTimeout:  g.ServerKeepaliveTimeout,
+		DrainTimeout:            g.DrainTimeout,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, newError("invalid grpc config").Base(err)
+	}
+	return config, nil
 }
@@ -27,6 +27,7 @@ type HTTPServerConfig struct {
 	Accounts    []*HTTPAccount `json:"accounts"`
 	Transparent bool           `json:"allowTransparent"`
 	UserLevel   uint32         `json:"userLevel"`
+	Realm       string         `json:"realm"`
 }
 
 func (c *HTTPServerConfig) Build() (proto.Message, error) {
@@ -34,6 +35,7 @@ func (c *HTTPServerConfig) Build() (proto.Message, error) {
 		Timeout:          c.Timeout,
 		AllowTransparent: c.Transparent,
 		UserLevel:        c.UserLevel,
+		Realm:            c.Realm,
 	}
 
 	if len(c.Accounts) > 0 {
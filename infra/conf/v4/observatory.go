@@ -14,11 +14,33 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
+// ProbeTargetConfig is the probe settings for selected outbounds.
+type ProbeTargetConfig struct {
+	SubjectSelector []string          `json:"subjectSelector"`
+	URL             string            `json:"url"`
+	ExpectedStatus  []uint32          `json:"expectedStatus"`
+	Timeout         duration.Duration `json:"timeout"`
+}
+
+func buildProbeTargets(configs []*ProbeTargetConfig) []*observatory.ProbeTarget {
+	targets := make([]*observatory.ProbeTarget, 0, len(configs))
+	for _, c := range configs {
+		targets = append(targets, &observatory.ProbeTarget{
+			SubjectSelector: c.SubjectSelector,
+			Url:             c.URL,
+			ExpectedStatus:  c.ExpectedStatus,
+			Timeout:         int64(c.Timeout),
+		})
+	}
+	return targets
+}
+
 type ObservatoryConfig struct {
-	SubjectSelector   []string          `json:"subjectSelector"`
-	ProbeURL          string            `json:"probeURL"`
-	ProbeInterval     duration.Duration `json:"probeInterval"`
-	EnableConcurrency bool              `json:"enableConcurrency"`
+	SubjectSelector   []string             `json:"subjectSelector"`
+	ProbeURL          string               `json:"probeURL"`
+	ProbeInterval     duration.Duration    `json:"probeInterval"`
+	EnableConcurrency bool                 `json:"enableConcurrency"`
+	ProbeTargets      []*ProbeTargetConfig `json:"probeTargets"`
 }
 
 func (o *ObservatoryConfig) Build() (proto.Message, error) {
@@ -27,19 +49,25 @@ func (o *ObservatoryConfig) Build() (proto.Message, error) {
 		ProbeUrl:          o.ProbeURL,
 		ProbeInterval:     int64(o.ProbeInterval),
 		EnableConcurrency: o.EnableConcurrency,
+		ProbeTarget:       buildProbeTargets(o.ProbeTargets),
 	}, nil
 }
 
 type BurstObservatoryConfig struct {
 	SubjectSelector []string `json:"subjectSelector"`
 	// health check settings
-	HealthCheck *router.HealthCheckSettings `json:"pingConfig,omitempty"`
+	HealthCheck  *router.HealthCheckSettings `json:"pingConfig,omitempty"`
+	ProbeTargets []*ProbeTargetConfig        `json:"probeTargets"`
 }
 
 func (b BurstObservatoryConfig) Build() (proto.Message, error) {
 	result, err := b.HealthCheck.Build()
 	if err == nil {
-		return &burst.Config{SubjectSelector: b.SubjectSelector, PingConfig: result.(*burst.HealthPingConfig)}, nil
+		return &burst.Config{
+			SubjectSelector: b.SubjectSelector,
+			PingConfig:      result.(*burst.HealthPingConfig),
+			ProbeTarget:     buildProbeTargets(b.ProbeTargets),
+		}, nil
 	}
 	return nil, err
 }
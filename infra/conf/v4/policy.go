@@ -11,7 +11,14 @@ type Policy struct {
 	DownlinkOnly      *uint32 `json:"downlinkOnly"`
 	StatsUserUplink   bool    `json:"statsUserUplink"`
 	StatsUserDownlink bool    `json:"statsUserDownlink"`
+	StatsUserOnline   bool    `json:"statsUserOnline"`
 	BufferSize        *int32  `json:"bufferSize"`
+	UplinkBandwidth   uint64  `json:"uplinkBandwidth"`
+	DownlinkBandwidth uint64  `json:"downlinkBandwidth"`
+	UplinkBurst       uint64  `json:"uplinkBurst"`
+	DownlinkBurst     uint64  `json:"downlinkBurst"`
+	MaxConnections    uint32  `json:"maxConnections"`
+	DNSServer         string  `json:"dnsServer"`
 }
 
 func (t *Policy) Build() (*policy.Policy, error) {
@@ -34,7 +41,10 @@ func (t *Policy) Build() (*policy.Policy, error) {
 		Stats: &policy.Policy_Stats{
 			UserUplink:   t.StatsUserUplink,
 			UserDownlink: t.StatsUserDownlink,
+			UserOnline:   t.StatsUserOnline,
 		},
+		MaxConnections: t.MaxConnections,
+		DnsServer:      t.DNSServer,
 	}
 
 	if t.BufferSize != nil {
@@ -47,23 +57,34 @@ func (t *Policy) Build() (*policy.Policy, error) {
 		}
 	}
 
+	if t.UplinkBandwidth > 0 || t.DownlinkBandwidth > 0 {
+		p.Bandwidth = &policy.Policy_Bandwidth{
+			Uplink:        t.UplinkBandwidth,
+			Downlink:      t.DownlinkBandwidth,
+			UplinkBurst:   t.UplinkBurst,
+			DownlinkBurst: t.DownlinkBurst,
+		}
+	}
+
 	return p, nil
 }
 
 type SystemPolicy struct {
-	StatsInboundUplink    bool `json:"statsInboundUplink"`
-	StatsInboundDownlink  bool `json:"statsInboundDownlink"`
-	StatsOutboundUplink   bool `json:"statsOutboundUplink"`
-	StatsOutboundDownlink bool `json:"statsOutboundDownlink"`
+	StatsInboundUplink       bool `json:"statsInboundUplink"`
+	StatsInboundDownlink     bool `json:"statsInboundDownlink"`
+	StatsOutboundUplink      bool `json:"statsOutboundUplink"`
+	StatsOutboundDownlink    bool `json:"statsOutboundDownlink"`
+	StatsOutboundDialLatency bool `json:"statsOutboundDialLatency"`
 }
 
 func (p *SystemPolicy) Build() (*policy.SystemPolicy, error) {
 	return &policy.SystemPolicy{
 		Stats: &policy.SystemPolicy_Stats{
-			InboundUplink:    p.StatsInboundUplink,
-			InboundDownlink:  p.StatsInboundDownlink,
-			OutboundUplink:   p.StatsOutboundUplink,
-			OutboundDownlink: p.StatsOutboundDownlink,
+			InboundUplink:       p.StatsInboundUplink,
+			InboundDownlink:     p.StatsInboundDownlink,
+			OutboundUplink:      p.StatsOutboundUplink,
+			OutboundDownlink:    p.StatsOutboundDownlink,
+			OutboundDialLatency: p.StatsOutboundDialLatency,
 		},
 	}, nil
 }
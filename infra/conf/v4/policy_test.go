@@ -38,3 +38,20 @@ func TestBufferSize(t *testing.T) {
 		}
 	}
 }
+
+func TestBandwidth(t *testing.T) {
+	p, err := (&v4.Policy{}).Build()
+	common.Must(err)
+	if p.Bandwidth != nil {
+		t.Error("expected no bandwidth limit, but got ", p.Bandwidth)
+	}
+
+	p, err = (&v4.Policy{
+		UplinkBandwidth: 1024 * 1024,
+		DownlinkBurst:   4096,
+	}).Build()
+	common.Must(err)
+	if p.Bandwidth.Uplink != 1024*1024 || p.Bandwidth.Downlink != 0 || p.Bandwidth.UplinkBurst != 0 || p.Bandwidth.DownlinkBurst != 4096 {
+		t.Error("unexpected bandwidth limit: ", p.Bandwidth)
+	}
+}
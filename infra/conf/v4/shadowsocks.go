@@ -11,23 +11,35 @@ import (
 	"github.com/v2fly/v2ray-core/v5/proxy/shadowsocks_sing"
 )
 
+type ShadowsocksUserConfig struct {
+	Password string `json:"password"`
+	Level    byte   `json:"level"`
+	Email    string `json:"email"`
+}
+
 type ShadowsocksServerConfig struct {
-	Cipher      string                 `json:"method"`
-	Password    string                 `json:"password"`
-	UDP         bool                   `json:"udp"`
-	Level       byte                   `json:"level"`
-	Email       string                 `json:"email"`
-	NetworkList *cfgcommon.NetworkList `json:"network"`
-	IVCheck     bool                   `json:"ivCheck"`
-	Plugin      string                 `json:"plugin"`
-	PluginOpts  string                 `json:"pluginOpts"`
-	PluginArgs  *cfgcommon.StringList  `json:"pluginArgs"`
+	Cipher      string                   `json:"method"`
+	Password    string                   `json:"password"`
+	UDP         bool                     `json:"udp"`
+	Level       byte                     `json:"level"`
+	Email       string                   `json:"email"`
+	NetworkList *cfgcommon.NetworkList   `json:"network"`
+	IVCheck     bool                     `json:"ivCheck"`
+	Plugin      string                   `json:"plugin"`
+	PluginOpts  string                   `json:"pluginOpts"`
+	PluginArgs  *cfgcommon.StringList    `json:"pluginArgs"`
+	Clients     []*ShadowsocksUserConfig `json:"clients"`
+	UDPTimeout  uint32                   `json:"udpTimeout"`
 }
 
 func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 	config := new(shadowsocks.ServerConfig)
 	config.UdpEnabled = v.UDP
 	config.Network = v.NetworkList.Build()
+	config.UdpTimeout = v.UDPTimeout
+	if v.UDPTimeout > shadowsocks.MaxUDPTimeout {
+		return nil, newError("Shadowsocks UDP timeout is longer than ", shadowsocks.MaxUDPTimeout, " seconds.")
+	}
 
 	if v.Password == "" {
 		return nil, newError("Shadowsocks password is not specified.")
@@ -47,6 +59,20 @@ func (v *ShadowsocksServerConfig) Build() (proto.Message, error) {
 		Account: serial.ToTypedMessage(account),
 	}
 
+	for _, client := range v.Clients {
+		if client.Password == "" {
+			return nil, newError("Shadowsocks password is not specified for client ", client.Email)
+		}
+		config.Users = append(config.Users, &protocol.User{
+			Email: client.Email,
+			Level: uint32(client.Level),
+			Account: serial.ToTypedMessage(&shadowsocks.Account{
+				Password:   client.Password,
+				CipherType: account.CipherType,
+			}),
+		})
+	}
+
 	config.Plugin = v.Plugin
 	config.PluginOpts = v.PluginOpts
 	if v.PluginArgs != nil && len(*v.PluginArgs) > 0 {
@@ -34,5 +34,53 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 				Network: []net.Network{net.Network_TCP},
 			},
 		},
+		{
+			Input: `{
+				"method": "2022-blake3-aes-128-gcm",
+				"password": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+				"clients": [
+					{
+						"password": "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=",
+						"email": "love@v2fly.org",
+						"level": 1
+					}
+				]
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &shadowsocks.ServerConfig{
+				User: &protocol.User{
+					Account: serial.ToTypedMessage(&shadowsocks.Account{
+						CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM_2022,
+						Password:   "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+					}),
+				},
+				Users: []*protocol.User{
+					{
+						Email: "love@v2fly.org",
+						Level: 1,
+						Account: serial.ToTypedMessage(&shadowsocks.Account{
+							CipherType: shadowsocks.CipherType_BLAKE3_AES_128_GCM_2022,
+							Password:   "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=",
+						}),
+					},
+				},
+				Network: []net.Network{net.Network_TCP},
+			},
+		},
 	})
 }
+
+func TestShadowsocksServerConfigUDPTimeout(t *testing.T) {
+	config := &v4.ShadowsocksServerConfig{
+		Cipher:     "aes-256-gcm",
+		Password:   "v2ray-password",
+		UDPTimeout: shadowsocks.MaxUDPTimeout,
+	}
+	if _, err := config.Build(); err != nil {
+		t.Error(err)
+	}
+	config.UDPTimeout++
+	if _, err := config.Build(); err == nil {
+		t.Error("expected error for UDP timeout of ", config.UDPTimeout, " seconds")
+	}
+}
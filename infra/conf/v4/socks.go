@@ -29,12 +29,13 @@ const (
 )
 
 type SocksServerConfig struct {
-	AuthMethod string             `json:"auth"`
-	Accounts   []*SocksAccount    `json:"accounts"`
-	UDP        bool               `json:"udp"`
-	Host       *cfgcommon.Address `json:"ip"`
-	Timeout    uint32             `json:"timeout"`
-	UserLevel  uint32             `json:"userLevel"`
+	AuthMethod         string             `json:"auth"`
+	Accounts           []*SocksAccount    `json:"accounts"`
+	UDP                bool               `json:"udp"`
+	Host               *cfgcommon.Address `json:"ip"`
+	Timeout            uint32             `json:"timeout"`
+	UserLevel          uint32             `json:"userLevel"`
+	FragmentBufferSize uint32             `json:"udpFragmentBufferSize"`
 }
 
 func (v *SocksServerConfig) Build() (proto.Message, error) {
@@ -57,6 +58,10 @@ func (v *SocksServerConfig) Build() (proto.Message, error) {
 	}
 
 	config.UdpEnabled = v.UDP
+	config.UdpFragmentBufferSize = v.FragmentBufferSize
+	if v.FragmentBufferSize > socks.MaxFragmentBufferSize {
+		return nil, newError("Socks UDP fragment buffer size is larger than ", socks.MaxFragmentBufferSize, " bytes.")
+	}
 	if v.Host != nil {
 		config.Address = v.Host.Build()
 	}
@@ -73,9 +78,10 @@ type SocksRemoteConfig struct {
 }
 
 type SocksClientConfig struct {
-	Servers []*SocksRemoteConfig `json:"servers"`
-	Version string               `json:"version"`
-	UoT     bool                 `json:"uot"`
+	Servers            []*SocksRemoteConfig `json:"servers"`
+	Version            string               `json:"version"`
+	UoT                bool                 `json:"uot"`
+	ForwardCredentials bool                 `json:"forwardCredentials"`
 }
 
 func (v *SocksClientConfig) Build() (proto.Message, error) {
@@ -114,5 +120,6 @@ func (v *SocksClientConfig) Build() (proto.Message, error) {
 		config.Server[idx] = server
 	}
 	config.UdpOverTcp = v.UoT
+	config.ForwardCredentials = v.ForwardCredentials
 	return config, nil
 }
@@ -92,3 +92,14 @@ func TestSocksOutboundConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestSocksInboundFragmentBufferSize(t *testing.T) {
+	config := &v4.SocksServerConfig{FragmentBufferSize: socks.MaxFragmentBufferSize}
+	if _, err := config.Build(); err != nil {
+		t.Error(err)
+	}
+	config.FragmentBufferSize++
+	if _, err := config.Build(); err == nil {
+		t.Error("expected error for UDP fragment buffer size of ", config.FragmentBufferSize, " bytes")
+	}
+}
@@ -2,6 +2,7 @@ package v4
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/golang/protobuf/proto"
@@ -43,10 +44,18 @@ type KCPConfig struct {
 	UpCap           *uint32         `json:"uplinkCapacity"`
 	DownCap         *uint32         `json:"downlinkCapacity"`
 	Congestion      *bool           `json:"congestion"`
+	CongestionAlgo  string          `json:"congestionAlgorithm"`
 	ReadBufferSize  *uint32         `json:"readBufferSize"`
 	WriteBufferSize *uint32         `json:"writeBufferSize"`
 	HeaderConfig    json.RawMessage `json:"header"`
 	Seed            *string         `json:"seed"`
+	SeedRotation    uint32          `json:"seedRotationInterval"`
+	FEC             *KCPFECConfig   `json:"fec"`
+}
+
+type KCPFECConfig struct {
+	DataShards   uint32 `json:"dataShards"`
+	ParityShards uint32 `json:"parityShards"`
 }
 
 // Build implements Buildable.
@@ -76,6 +85,14 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 	if c.Congestion != nil {
 		config.Congestion = *c.Congestion
 	}
+	switch strings.ToLower(c.CongestionAlgo) {
+	case "", "loss":
+		config.CongestionAlgorithm = kcp.CongestionAlgorithm_LossBased
+	case "delay":
+		config.CongestionAlgorithm = kcp.CongestionAlgorithm_DelayBased
+	default:
+		return nil, newError("unknown mKCP congestion algorithm: ", c.CongestionAlgo).AtError()
+	}
 	if c.ReadBufferSize != nil {
 		size := *c.ReadBufferSize
 		if size > 0 {
@@ -105,15 +122,32 @@ func (c *KCPConfig) Build() (proto.Message, error) {
 	}
 
 	if c.Seed != nil {
-		config.Seed = &kcp.EncryptionSeed{Seed: *c.Seed}
+		config.Seed = &kcp.EncryptionSeed{
+			Seed:             *c.Seed,
+			RotationInterval: c.SeedRotation,
+		}
+	} else if c.SeedRotation > 0 {
+		return nil, newError("mKCP seed rotation requires a seed").AtError()
+	}
+
+	if c.FEC != nil {
+		config.Fec = &kcp.FEC{
+			DataShards:   c.FEC.DataShards,
+			ParityShards: c.FEC.ParityShards,
+		}
+		if _, _, err := config.GetFECShards(); err != nil {
+			return nil, newError("invalid mKCP FEC config").Base(err).AtError()
+		}
 	}
 
 	return config, nil
 }
 
 type TCPConfig struct {
-	HeaderConfig        json.RawMessage `json:"header"`
-	AcceptProxyProtocol bool            `json:"acceptProxyProtocol"`
+	HeaderConfig          json.RawMessage `json:"header"`
+	AcceptProxyProtocol   bool            `json:"acceptProxyProtocol"`
+	SendProxyProtocol     bool            `json:"sendProxyProtocol"`
+	ProxyProtocolOptional bool            `json:"proxyProtocolOptional"`
 }
 
 // Build implements Buildable.
@@ -133,6 +167,12 @@ func (c *TCPConfig) Build() (proto.Message, error) {
 	if c.AcceptProxyProtocol {
 		config.AcceptProxyProtocol = c.AcceptProxyProtocol
 	}
+	if c.ProxyProtocolOptional {
+		config.ProxyProtocolOptional = c.ProxyProtocolOptional
+	}
+	if c.SendProxyProtocol {
+		config.SendProxyProtocol = c.SendProxyProtocol
+	}
 	return config, nil
 }
 
@@ -143,6 +183,9 @@ type WebSocketConfig struct {
 	MaxEarlyData         int32             `json:"maxEarlyData"`
 	UseBrowserForwarding bool              `json:"useBrowserForwarding"`
 	EarlyDataHeaderName  string            `json:"earlyDataHeaderName"`
+	MaxEarlyDataSize     int32             `json:"maxEarlyDataSize"`
+	EnableCompression    bool              `json:"enableCompression"`
+	BrowserBridgeURL     string            `json:"browserBridgeUrl"`
 }
 
 // Build implements Buildable.
@@ -161,6 +204,9 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 		MaxEarlyData:         c.MaxEarlyData,
 		UseBrowserForwarding: c.UseBrowserForwarding,
 		EarlyDataHeaderName:  c.EarlyDataHeaderName,
+		MaxEarlyDataSize:     c.MaxEarlyDataSize,
+		EnableCompression:    c.EnableCompression,
+		BrowserBridgeUrl:     c.BrowserBridgeURL,
 	}
 	if c.AcceptProxyProtocol {
 		config.AcceptProxyProtocol = c.AcceptProxyProtocol
@@ -169,16 +215,23 @@ func (c *WebSocketConfig) Build() (proto.Message, error) {
 }
 
 type HTTPConfig struct {
-	Host    *cfgcommon.StringList            `json:"host"`
-	Path    string                           `json:"path"`
-	Method  string                           `json:"method"`
-	Headers map[string]*cfgcommon.StringList `json:"headers"`
+	Host            *cfgcommon.StringList            `json:"host"`
+	Path            string                           `json:"path"`
+	Method          string                           `json:"method"`
+	Headers         map[string]*cfgcommon.StringList `json:"headers"`
+	ReadIdleTimeout int32                            `json:"readIdleTimeout"`
+	PingTimeout     int32                            `json:"pingTimeout"`
 }
 
 // Build implements Buildable.
 func (c *HTTPConfig) Build() (proto.Message, error) {
 	config := &http.Config{
-		Path: c.Path,
+		Path:            c.Path,
+		ReadIdleTimeout: c.ReadIdleTimeout,
+		PingTimeout:     c.PingTimeout,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, newError("invalid HTTP config").Base(err).AtError()
 	}
 	if c.Host != nil {
 		config.Host = []string(*c.Host)
@@ -204,15 +257,19 @@ func (c *HTTPConfig) Build() (proto.Message, error) {
 }
 
 type QUICConfig struct {
-	Header   json.RawMessage `json:"header"`
-	Security string          `json:"security"`
-	Key      string          `json:"key"`
+	Header           json.RawMessage `json:"header"`
+	Security         string          `json:"security"`
+	Key              string          `json:"key"`
+	EnableEarlyData  bool            `json:"enableEarlyData"`
+	ProcessEarlyData bool            `json:"processEarlyData"`
 }
 
 // Build implements Buildable.
 func (c *QUICConfig) Build() (proto.Message, error) {
 	config := &quic.Config{
-		Key: c.Key,
+		Key:              c.Key,
+		EnableEarlyData:  c.EnableEarlyData,
+		ProcessEarlyData: c.ProcessEarlyData,
 	}
 
 	if len(c.Header) > 0 {
@@ -248,15 +305,27 @@ type DomainSocketConfig struct {
 	Path     string `json:"path"`
 	Abstract bool   `json:"abstract"`
 	Padding  bool   `json:"padding"`
+	FileMode string `json:"fileMode"`
 }
 
 // Build implements Buildable.
 func (c *DomainSocketConfig) Build() (proto.Message, error) {
-	return &domainsocket.Config{
+	config := &domainsocket.Config{
 		Path:     c.Path,
 		Abstract: c.Abstract,
 		Padding:  c.Padding,
-	}, nil
+	}
+	if c.FileMode != "" {
+		mode, err := strconv.ParseUint(c.FileMode, 8, 32)
+		if err != nil {
+			return nil, newError("invalid domain socket file mode: ", c.FileMode).Base(err)
+		}
+		config.FileMode = uint32(mode)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, newError("invalid domain socket config").Base(err)
+	}
+	return config, nil
 }
 
 type TransportProtocol string
@@ -46,6 +46,18 @@ func TestSocketConfig(t *testing.T) {
 				TfoQueueLength: 1024,
 			},
 		},
+		{
+			Input: `{
+				"sendBufferSize": 4194304,
+				"receiveBufferSize": 8388608
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				TfoQueueLength:    4096,
+				SendBufferSize:    4194304,
+				ReceiveBufferSize: 8388608,
+			},
+		},
 	})
 }
 
@@ -86,6 +98,12 @@ func TestTransportConfig(t *testing.T) {
 					"mtu": 1200,
 					"header": {
 						"type": "none"
+					},
+					"seed": "abcd",
+					"seedRotationInterval": 300,
+					"fec": {
+						"dataShards": 10,
+						"parityShards": 3
 					}
 				},
 				"wsSettings": {
@@ -148,6 +166,14 @@ func TestTransportConfig(t *testing.T) {
 						Settings: serial.ToTypedMessage(&kcp.Config{
 							Mtu:          &kcp.MTU{Value: 1200},
 							HeaderConfig: serial.ToTypedMessage(&noop.Config{}),
+							Seed: &kcp.EncryptionSeed{
+								Seed:             "abcd",
+								RotationInterval: 300,
+							},
+							Fec: &kcp.FEC{
+								DataShards:   10,
+								ParityShards: 3,
+							},
 						}),
 					},
 					{
@@ -30,6 +30,8 @@ type VLessInboundConfig struct {
 	Decryption string                  `json:"decryption"`
 	Fallback   json.RawMessage         `json:"fallback"`
 	Fallbacks  []*VLessInboundFallback `json:"fallbacks"`
+
+	RequireSecureTransport bool `json:"requireSecureTransport"`
 }
 
 // Build implements Buildable
@@ -58,6 +60,7 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		return nil, newError(`VLESS settings: please add/set "decryption":"none" to every settings`)
 	}
 	config.Decryption = c.Decryption
+	config.RequireSecureTransport = c.RequireSecureTransport
 
 	if c.Fallback != nil {
 		return nil, newError(`VLESS settings: please use "fallbacks":[{}] instead of "fallback":{}`)
@@ -129,11 +132,14 @@ type VLessOutboundVnext struct {
 type VLessOutboundConfig struct {
 	Vnext          []*VLessOutboundVnext `json:"vnext"`
 	PacketEncoding string                `json:"packetEncoding"`
+
+	RequireSecureTransport bool `json:"requireSecureTransport"`
 }
 
 // Build implements Buildable
 func (c *VLessOutboundConfig) Build() (proto.Message, error) {
 	config := new(outbound.Config)
+	config.RequireSecureTransport = c.RequireSecureTransport
 
 	if len(c.Vnext) == 0 {
 		return nil, newError(`VLESS settings: "vnext" is empty`)
@@ -15,10 +15,16 @@ import (
 )
 
 type VMessAccount struct {
-	ID          string `json:"id"`
-	AlterIds    uint16 `json:"alterId"`
-	Security    string `json:"security"`
-	Experiments string `json:"experiments"`
+	ID          string              `json:"id"`
+	AlterIds    uint16              `json:"alterId"`
+	Security    string              `json:"security"`
+	Experiments string              `json:"experiments"`
+	Padding     *VMessPaddingConfig `json:"padding"`
+}
+
+type VMessPaddingConfig struct {
+	Min uint32 `json:"min"`
+	Max uint32 `json:"max"`
 }
 
 // Build implements Buildable
@@ -38,7 +44,7 @@ func (a *VMessAccount) Build() *vmess.Account {
 	default:
 		st = protocol.SecurityType_AUTO
 	}
-	return &vmess.Account{
+	account := &vmess.Account{
 		Id:      a.ID,
 		AlterId: uint32(a.AlterIds),
 		SecuritySettings: &protocol.SecurityConfig{
@@ -46,6 +52,11 @@ func (a *VMessAccount) Build() *vmess.Account {
 		},
 		TestsEnabled: a.Experiments,
 	}
+	if a.Padding != nil {
+		account.PaddingMin = a.Padding.Min
+		account.PaddingMax = a.Padding.Max
+	}
+	return account
 }
 
 type VMessDetourConfig struct {
@@ -82,12 +93,16 @@ type VMessInboundConfig struct {
 	Defaults     *VMessDefaultConfig `json:"default"`
 	DetourConfig *VMessDetourConfig  `json:"detour"`
 	SecureOnly   bool                `json:"disableInsecureEncryption"`
+	ReplayWindow uint32              `json:"replayWindowSeconds"`
+	NoLegacy     bool                `json:"disableLegacyHeader"`
 }
 
 // Build implements Buildable
 func (c *VMessInboundConfig) Build() (proto.Message, error) {
 	config := &inbound.Config{
 		SecureEncryptionOnly: c.SecureOnly,
+		ReplayWindowSeconds:  c.ReplayWindow,
+		DisableLegacyHeader:  c.NoLegacy,
 	}
 
 	if c.Defaults != nil {
@@ -126,11 +141,14 @@ type VMessOutboundTarget struct {
 type VMessOutboundConfig struct {
 	Receivers      []*VMessOutboundTarget `json:"vnext"`
 	PacketEncoding string                 `json:"packetEncoding"`
+	FramePadding   bool                   `json:"framePadding"`
 }
 
 // Build implements Buildable
 func (c *VMessOutboundConfig) Build() (proto.Message, error) {
-	config := new(outbound.Config)
+	config := &outbound.Config{
+		FramePadding: c.FramePadding,
+	}
 
 	if len(c.Receivers) == 0 {
 		return nil, newError("0 VMess receiver configured")
@@ -88,7 +88,9 @@ func TestVMessInbound(t *testing.T) {
 				"detour": {
 					"to": "tag_to_detour"
 				},
-				"disableInsecureEncryption": true
+				"disableInsecureEncryption": true,
+				"replayWindowSeconds": 300,
+				"disableLegacyHeader": true
 			}`,
 			Parser: testassist.LoadJSON(creator),
 			Output: &inbound.Config{
@@ -113,6 +115,8 @@ func TestVMessInbound(t *testing.T) {
 					To: "tag_to_detour",
 				},
 				SecureEncryptionOnly: true,
+				ReplayWindowSeconds:  300,
+				DisableLegacyHeader:  true,
 			},
 		},
 	})
@@ -26,6 +26,9 @@ Arguments:
 	-restart 
 		Restart the logger
 
+	-history <number>
+		Print the given number of recent logs before following
+
 	-s, -server <server:port>
 		The API server address. Default 127.0.0.1:8080
 
@@ -35,6 +38,7 @@ Arguments:
 Example:
 
     {{.Exec}} {{.LongName}}
+    {{.Exec}} {{.LongName}} --history=100
     {{.Exec}} {{.LongName}} --restart
 `,
 	Run: executeLog,
@@ -42,7 +46,9 @@ Example:
 
 func executeLog(cmd *base.Command, args []string) {
 	var restart bool
+	var history uint
 	cmd.Flag.BoolVar(&restart, "restart", false, "")
+	cmd.Flag.UintVar(&history, "history", 0, "")
 	setSharedFlags(cmd)
 	cmd.Flag.Parse(args)
 
@@ -50,7 +56,7 @@ func executeLog(cmd *base.Command, args []string) {
 		restartLogger()
 		return
 	}
-	followLogger()
+	followLogger(uint32(history))
 }
 
 func restartLogger() {
@@ -64,11 +70,11 @@ func restartLogger() {
 	}
 }
 
-func followLogger() {
+func followLogger(history uint32) {
 	conn, ctx, close := dialAPIServerWithoutTimeout()
 	defer close()
 	client := logService.NewLoggerServiceClient(conn)
-	r := &logService.FollowLogRequest{}
+	r := &logService.FollowLogRequest{History: history}
 	stream, err := client.FollowLog(ctx, r)
 	if err != nil {
 		base.Fatalf("failed to follow logger: %s", err)
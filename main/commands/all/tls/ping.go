@@ -121,6 +121,11 @@ func showCert() func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) er
 	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 		hash := v2tls.GenerateCertChainHash(rawCerts)
 		fmt.Println("Certificate Chain Hash: ", base64.StdEncoding.EncodeToString(hash))
+		for _, rawCert := range rawCerts {
+			if cert, err := x509.ParseCertificate(rawCert); err == nil {
+				fmt.Println("Certificate Public Key Hash: ", base64.StdEncoding.EncodeToString(v2tls.GenerateCertPublicKeyHash(cert)))
+			}
+		}
 		return nil
 	}
 }
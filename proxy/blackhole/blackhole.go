@@ -17,6 +17,7 @@ import (
 // Handler is an outbound connection that silently swallow the entire payload.
 type Handler struct {
 	response ResponseConfig
+	config   *Config
 }
 
 // New creates a new blackhole handler.
@@ -25,28 +26,48 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.MaxCloseDelayMs != 0 && config.MaxCloseDelayMs < config.MinCloseDelayMs {
+		return nil, newError("max close delay is smaller than min close delay")
+	}
+	if r, ok := response.(*RandomResponse); ok && r.MaxLength != 0 && r.MaxLength < r.MinLength {
+		return nil, newError("max response length is smaller than min response length")
+	}
 	return &Handler{
 		response: response,
+		config:   config,
 	}, nil
 }
 
 // Process implements OutboundHandler.Dispatch().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	nBytes := h.response.WriteTo(link.Writer)
-	if nBytes > 0 {
-		// Sleep a little here to make sure the response is sent to client.
-		time.Sleep(time.Second)
-	}
+	h.waitBeforeClose(ctx, nBytes)
 	return nil
 }
 
 func (h *Handler) ProcessConn(ctx context.Context, conn net.Conn, dialer internet.Dialer) error {
 	nBytes := h.response.WriteTo(buf.NewWriter(conn))
-	if nBytes > 0 {
+	h.waitBeforeClose(ctx, nBytes)
+	return nil
+}
+
+// waitBeforeClose holds the connection open for the configured close delay,
+// returning early if ctx is cancelled.
+func (h *Handler) waitBeforeClose(ctx context.Context, nBytes int32) {
+	delay := h.config.GetCloseDelay()
+	if delay == 0 && nBytes > 0 {
 		// Sleep a little here to make sure the response is sent to client.
-		time.Sleep(time.Second)
+		delay = time.Second
+	}
+	if delay == 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
 	}
-	return nil
 }
 
 func init() {
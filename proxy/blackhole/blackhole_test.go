@@ -3,6 +3,7 @@ package blackhole_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
@@ -38,3 +39,55 @@ func TestBlackHoleHTTPResponse(t *testing.T) {
 		t.Error("expect http response, but nothing")
 	}
 }
+
+func TestBlackHoleCloseDelay(t *testing.T) {
+	handler, err := blackhole.New(context.Background(), &blackhole.Config{
+		MinCloseDelayMs: 100,
+		MaxCloseDelayMs: 200,
+	})
+	common.Must(err)
+
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	link := transport.Link{
+		Reader: reader,
+		Writer: writer,
+	}
+
+	start := time.Now()
+	common.Must(handler.Process(context.Background(), &link, nil))
+	elapsed := time.Since(start)
+	if elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Error("unexpected close delay: ", elapsed)
+	}
+}
+
+func TestBlackHoleCloseDelayCancel(t *testing.T) {
+	handler, err := blackhole.New(context.Background(), &blackhole.Config{
+		MinCloseDelayMs: 60000,
+	})
+	common.Must(err)
+
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	link := transport.Link{
+		Reader: reader,
+		Writer: writer,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	common.Must(handler.Process(ctx, &link, nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("close delay is not interrupted by context: ", elapsed)
+	}
+}
+
+func TestBlackHoleInvalidCloseDelay(t *testing.T) {
+	if _, err := blackhole.New(context.Background(), &blackhole.Config{
+		MinCloseDelayMs: 200,
+		MaxCloseDelayMs: 100,
+	}); err == nil {
+		t.Error("expect error for inverted close delay range")
+	}
+}
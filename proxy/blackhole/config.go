@@ -1,8 +1,12 @@
 package blackhole
 
 import (
+	"crypto/rand"
+	"time"
+
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 )
 
@@ -16,6 +20,10 @@ Content-Length: 0
 `
 )
 
+// MaxRandomResponseLength is the largest number of bytes a RandomResponse
+// writes. Longer lengths are cut to it.
+const MaxRandomResponseLength = 64 * 1024
+
 // ResponseConfig is the configuration for blackhole responses.
 type ResponseConfig interface {
 	// WriteTo writes predefined response to the give buffer.
@@ -34,6 +42,43 @@ func (*HTTPResponse) WriteTo(writer buf.Writer) int32 {
 	return n
 }
 
+// WriteTo implements ResponseConfig.WriteTo().
+func (r *RandomResponse) WriteTo(writer buf.Writer) int32 {
+	length := pick(r.MinLength, r.MaxLength)
+	if length > MaxRandomResponseLength {
+		length = MaxRandomResponseLength
+	}
+	var mb buf.MultiBuffer
+	for remaining := int32(length); remaining > 0; {
+		b := buf.New()
+		n := remaining
+		if size := buf.DefaultSize(); n > size {
+			n = size
+		}
+		common.Must2(b.ReadFullFrom(rand.Reader, n))
+		mb = append(mb, b)
+		remaining -= n
+	}
+	if mb.IsEmpty() {
+		return 0
+	}
+	writer.WriteMultiBuffer(mb)
+	return int32(length)
+}
+
+// pick returns a random value in [min, max], or min if max is not larger.
+func pick(min, max uint32) uint32 {
+	if max <= min {
+		return min
+	}
+	return min + uint32(dice.Roll(int(max-min)+1))
+}
+
+// GetCloseDelay returns the delay to wait before closing a connection.
+func (c *Config) GetCloseDelay() time.Duration {
+	return time.Duration(pick(c.MinCloseDelayMs, c.MaxCloseDelayMs)) * time.Millisecond
+}
+
 // GetInternalResponse converts response settings from proto to internal data structure.
 func (c *Config) GetInternalResponse() (ResponseConfig, error) {
 	if c.GetResponse() == nil {
@@ -2,8 +2,10 @@ package blackhole_test
 
 import (
 	"bufio"
+	"bytes"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
@@ -25,3 +27,39 @@ func TestHTTPResponse(t *testing.T) {
 		t.Error("expected status code 403, but got ", response.StatusCode)
 	}
 }
+
+func TestRandomResponse(t *testing.T) {
+	response := &RandomResponse{MinLength: 10, MaxLength: 40000}
+	for i := 0; i < 20; i++ {
+		var buffer bytes.Buffer
+		n := response.WriteTo(buf.NewWriter(&buffer))
+		if n < 10 || n > 40000 {
+			t.Fatal("unexpected response length: ", n)
+		}
+		if buffer.Len() != int(n) {
+			t.Fatal("written ", buffer.Len(), " bytes, but reported ", n)
+		}
+	}
+}
+
+func TestRandomResponseMaxLength(t *testing.T) {
+	response := &RandomResponse{MinLength: 1 << 20, MaxLength: 1 << 30}
+	var buffer bytes.Buffer
+	if n := response.WriteTo(buf.NewWriter(&buffer)); n != MaxRandomResponseLength || buffer.Len() != MaxRandomResponseLength {
+		t.Error("expect response of ", MaxRandomResponseLength, " bytes, but got ", n, " and ", buffer.Len(), " written")
+	}
+}
+
+func TestCloseDelay(t *testing.T) {
+	config := &Config{MinCloseDelayMs: 100, MaxCloseDelayMs: 300}
+	for i := 0; i < 100; i++ {
+		if d := config.GetCloseDelay(); d < 100*time.Millisecond || d > 300*time.Millisecond {
+			t.Fatal("close delay out of range: ", d)
+		}
+	}
+
+	config = &Config{MinCloseDelayMs: 100}
+	if d := config.GetCloseDelay(); d != 100*time.Millisecond {
+		t.Error("expect fixed close delay, but got ", d)
+	}
+}
@@ -252,7 +252,7 @@ func (h *Handler) handleIPQuery(ctx context.Context, id uint16, qType dnsmessage
 		}
 	}
 
-	b := buf.New()
+	b := buf.NewSize(buf.Size)
 	rawBytes := b.Extend(buf.Size)
 	builder := dnsmessage.NewBuilder(rawBytes[:0], dnsmessage.Header{
 		ID:                 id,
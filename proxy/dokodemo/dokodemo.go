@@ -84,6 +84,33 @@ func (d *Door) policy() policy.Session {
 	return p
 }
 
+// sniffedDomainProtocols are the sniffed protocols whose domain carries the server name.
+var sniffedDomainProtocols = []string{"tls", "quic"}
+
+// requestSniffedDomainOverride asks the dispatcher to replace the destination
+// address with the sniffed server name, if sniffing is enabled.
+func requestSniffedDomainOverride(ctx context.Context) {
+	content := session.ContentFromContext(ctx)
+	if content == nil || !content.SniffingRequest.Enabled {
+		return
+	}
+	// The slice may be shared with the inbound's sniffing config, so copy it.
+	protocols := append([]string(nil), content.SniffingRequest.OverrideDestinationForProtocol...)
+	for _, p := range sniffedDomainProtocols {
+		found := false
+		for _, existing := range protocols {
+			if existing == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			protocols = append(protocols, p)
+		}
+	}
+	content.SniffingRequest.OverrideDestinationForProtocol = protocols
+}
+
 type hasHandshakeAddress interface {
 	HandshakeAddress() net.Address
 }
@@ -120,6 +147,10 @@ func (d *Door) Process(ctx context.Context, network net.Network, conn internet.C
 		}
 	}
 
+	if d.config.OverrideWithSniffedDomain {
+		requestSniffedDomainOverride(ctx)
+	}
+
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
 		From:   conn.RemoteAddr(),
 		To:     dest,
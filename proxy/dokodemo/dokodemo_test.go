@@ -0,0 +1,112 @@
+package dokodemo
+
+import (
+	"context"
+	"crypto/tls"
+	gonet "net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/app/dispatcher"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/testing/mocks"
+	"github.com/v2fly/v2ray-core/v5/transport"
+)
+
+// recordingHandler records the context of the connections dispatched to it,
+// and closes them.
+type recordingHandler struct {
+	ctx context.Context
+}
+
+func (*recordingHandler) Start() error { return nil }
+func (*recordingHandler) Close() error { return nil }
+func (*recordingHandler) Tag() string  { return "" }
+
+func (h *recordingHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	h.ctx = ctx
+	common.Close(link.Writer)
+	common.Interrupt(link.Reader)
+}
+
+// sendTLSClientHello sends a TLS ClientHello for serverName to conn.
+func sendTLSClientHello(serverName string) func(conn gonet.Conn) {
+	return func(conn gonet.Conn) {
+		tls.Client(conn, &tls.Config{ServerName: serverName}).Handshake()
+	}
+}
+
+// sendSSHBanner sends content that no sniffer recognizes to conn.
+func sendSSHBanner(conn gonet.Conn) {
+	conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+}
+
+func TestOverrideWithSniffedDomain(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	configured := []string{"http"}
+	original := net.TCPDestination(net.LocalHostIP, 8443)
+	sniffed := net.TCPDestination(net.DomainAddress("www.v2fly.org"), 8443)
+	cases := []struct {
+		override  bool
+		sniffing  bool
+		send      func(conn gonet.Conn)
+		protocols []string
+		dest      net.Destination
+	}{
+		{override: true, sniffing: true, send: sendTLSClientHello("www.v2fly.org"), protocols: []string{"http", "tls", "quic"}, dest: sniffed},
+		{override: true, sniffing: true, send: sendSSHBanner, protocols: []string{"http", "tls", "quic"}, dest: original},
+		{override: true, sniffing: false, send: sendTLSClientHello("www.v2fly.org"), protocols: []string{"http"}, dest: original},
+		{override: false, sniffing: true, send: sendTLSClientHello("www.v2fly.org"), protocols: []string{"http"}, dest: original},
+	}
+
+	for _, c := range cases {
+		handler := new(recordingHandler)
+		mockOhm := mocks.NewOutboundManager(mockCtl)
+		mockOhm.EXPECT().GetDefaultHandler().Return(handler).AnyTimes()
+		d := new(dispatcher.DefaultDispatcher)
+		common.Must(d.Init(&dispatcher.Config{}, mockOhm, nil, policy.DefaultManager{}, nil))
+
+		door := new(Door)
+		common.Must(door.Init(&Config{
+			Address:                   net.NewIPOrDomain(net.LocalHostIP),
+			Port:                      8443,
+			Networks:                  []net.Network{net.Network_TCP},
+			OverrideWithSniffedDomain: c.override,
+		}, policy.DefaultManager{}, nil))
+
+		content := &session.Content{
+			SniffingRequest: session.SniffingRequest{
+				Enabled:                        c.sniffing,
+				OverrideDestinationForProtocol: configured,
+			},
+		}
+		ctx := session.ContextWithContent(context.Background(), content)
+		ctx = session.ContextWithInbound(ctx, &session.Inbound{})
+
+		conn, peer := gonet.Pipe()
+		go c.send(peer)
+		common.Must(door.Process(ctx, net.Network_TCP, conn, d))
+		conn.Close()
+		peer.Close()
+
+		if handler.ctx == nil {
+			t.Fatal("connection not dispatched")
+		}
+		if dest := session.OutboundFromContext(handler.ctx).Target; dest != c.dest {
+			t.Error("expected destination ", c.dest, ", but got ", dest)
+		}
+		actual := session.ContentFromContext(handler.ctx).SniffingRequest.OverrideDestinationForProtocol
+		if r := cmp.Diff(actual, c.protocols); r != "" {
+			t.Error(r)
+		}
+		if r := cmp.Diff(configured, []string{"http"}); r != "" {
+			t.Error("inbound sniffing config modified: ", r)
+		}
+	}
+}
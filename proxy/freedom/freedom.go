@@ -6,7 +6,6 @@ import (
 	"context"
 	"time"
 
-	"github.com/sagernet/sing/common/bufio"
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
@@ -66,7 +65,7 @@ func (h *Handler) policy() policy.Session {
 	return p
 }
 
-func (h *Handler) resolveIP(ctx context.Context, domain string, localAddr net.Address) net.Address {
+func (h *Handler) lookupIP(ctx context.Context, domain string, localAddr net.Address) []net.IP {
 	lookupFunc := h.dns.LookupIP
 	if h.config.DomainStrategy == Config_USE_IP4 || (localAddr != nil && localAddr.Family().IsIPv4()) {
 		if lookupIPv4, ok := h.dns.(dns.IPv4Lookup); ok {
@@ -82,10 +81,57 @@ func (h *Handler) resolveIP(ctx context.Context, domain string, localAddr net.Ad
 	if err != nil {
 		newError("failed to get IP address for domain ", domain).Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
-	if len(ips) == 0 {
-		return nil
+	return ips
+}
+
+func hasDualStack(ips []net.IP) bool {
+	var hasIPv4, hasIPv6 bool
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
 	}
-	return net.IPAddress(ips[dice.Roll(len(ips))])
+	return hasIPv4 && hasIPv6
+}
+
+func (h *Handler) dial(ctx context.Context, dialer internet.Dialer, destination net.Destination) (internet.Connection, error) {
+	var conn internet.Connection
+	err := retry.ExponentialBackoff(5, 100).On(func() error {
+		dialDest := destination
+		if h.config.useIP() && dialDest.Address.Family().IsDomain() {
+			ips := h.lookupIP(ctx, dialDest.Address.Domain(), dialer.Address())
+			if dialDest.Network == net.Network_TCP && hasDualStack(ips) {
+				dests := dialDest.NetworkAndDomainPreference(ips, true)
+				newError("dialing to ", dialDest, " over ", len(dests), " addresses").WriteToLog(session.ExportIDToError(ctx))
+				rawConn, err := net.DialStaggered(ctx, dests, func(ctx context.Context, dest net.Destination) (net.Conn, error) {
+					return dialer.Dial(ctx, dest)
+				}, net.HappyEyeballsDelay)
+				if err != nil {
+					return err
+				}
+				conn = rawConn
+				return nil
+			}
+			if len(ips) > 0 {
+				dialDest = net.Destination{
+					Network: dialDest.Network,
+					Address: net.IPAddress(ips[dice.Roll(len(ips))]),
+					Port:    dialDest.Port,
+				}
+				newError("dialing to ", dialDest).WriteToLog(session.ExportIDToError(ctx))
+			}
+		}
+
+		rawConn, err := dialer.Dial(ctx, dialDest)
+		if err != nil {
+			return err
+		}
+		conn = rawConn
+		return nil
+	})
+	return conn, err
 }
 
 func isValidAddress(addr *net.IPOrDomain) bool {
@@ -118,28 +164,7 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	input := link.Reader
 	output := link.Writer
 
-	var conn internet.Connection
-	err := retry.ExponentialBackoff(5, 100).On(func() error {
-		dialDest := destination
-		if h.config.useIP() && dialDest.Address.Family().IsDomain() {
-			ip := h.resolveIP(ctx, dialDest.Address.Domain(), dialer.Address())
-			if ip != nil {
-				dialDest = net.Destination{
-					Network: dialDest.Network,
-					Address: ip,
-					Port:    dialDest.Port,
-				}
-				newError("dialing to ", dialDest).WriteToLog(session.ExportIDToError(ctx))
-			}
-		}
-
-		rawConn, err := dialer.Dial(ctx, dialDest)
-		if err != nil {
-			return err
-		}
-		conn = rawConn
-		return nil
-	})
+	conn, err := h.dial(ctx, dialer, destination)
 	if err != nil {
 		return newError("failed to open connection to ", destination).Base(err)
 	}
@@ -208,28 +233,7 @@ func (h *Handler) ProcessConn(ctx context.Context, conn net.Conn, dialer interne
 	}
 	newError("opening connection to ", destination).WriteToLog(session.ExportIDToError(ctx))
 
-	var outboundConn internet.Connection
-	err := retry.ExponentialBackoff(5, 100).On(func() error {
-		dialDest := destination
-		if h.config.useIP() && dialDest.Address.Family().IsDomain() {
-			ip := h.resolveIP(ctx, dialDest.Address.Domain(), dialer.Address())
-			if ip != nil {
-				dialDest = net.Destination{
-					Network: dialDest.Network,
-					Address: ip,
-					Port:    dialDest.Port,
-				}
-				newError("dialing to ", dialDest).WriteToLog(session.ExportIDToError(ctx))
-			}
-		}
-
-		rawConn, err := dialer.Dial(ctx, dialDest)
-		if err != nil {
-			return err
-		}
-		outboundConn = rawConn
-		return nil
-	})
+	outboundConn, err := h.dial(ctx, dialer, destination)
 	if err != nil {
 		return newError("failed to open connection to ", destination).Base(err)
 	}
@@ -237,7 +241,7 @@ func (h *Handler) ProcessConn(ctx context.Context, conn net.Conn, dialer interne
 	connElem := net.AddConnection(outboundConn)
 	defer net.RemoveConnection(connElem)
 
-	return bufio.CopyConn(ctx, conn, outboundConn)
+	return internet.CopyConn(ctx, conn, outboundConn)
 }
 
 func newPacketReader(conn net.Conn) buf.Reader {
@@ -264,7 +268,7 @@ type packetReader struct {
 }
 
 func (r *packetReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
-	b := buf.New()
+	b := buf.NewSize(buf.Size)
 	b.Resize(0, buf.Size)
 	n, d, err := r.conn.ReadFrom(b.Bytes())
 	if err != nil {
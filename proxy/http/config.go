@@ -1,6 +1,8 @@
 package http
 
 import (
+	"strings"
+
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 )
 
@@ -26,3 +28,17 @@ func (sc *ServerConfig) HasAccount(username, password string) bool {
 	}
 	return p == password
 }
+
+// defaultRealm is the realm of the authentication challenge if not configured.
+const defaultRealm = "proxy"
+
+// AuthenticateChallenge returns the value of the Proxy-Authenticate header
+// sent to clients without valid credentials.
+func (sc *ServerConfig) AuthenticateChallenge() string {
+	realm := sc.Realm
+	if realm == "" {
+		realm = defaultRealm
+	}
+	realm = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(realm)
+	return "Basic realm=\"" + realm + "\""
+}
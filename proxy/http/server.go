@@ -111,7 +111,7 @@ Start:
 	if len(s.config.Accounts) > 0 {
 		user, pass, ok := parseBasicAuth(request.Header.Get("Proxy-Authorization"))
 		if !ok || !s.config.HasAccount(user, pass) {
-			return common.Error2(conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"proxy\"\r\n\r\n")))
+			return s.writeAuthenticationRequired(conn)
 		}
 		if inbound != nil {
 			inbound.User.Email = user
@@ -146,9 +146,9 @@ Start:
 		return s.handleConnect(ctx, request, reader, conn, dest, dispatcher)
 	}
 
-	keepAlive := (strings.TrimSpace(strings.ToLower(request.Header.Get("Proxy-Connection"))) == "keep-alive")
+	keepAlive := shouldKeepAlive(request)
 
-	err = s.handlePlainHTTP(ctx, request, conn, dest, dispatcher)
+	err = s.handlePlainHTTP(ctx, request, conn, dest, dispatcher, keepAlive)
 	if err == errWaitAnother {
 		if keepAlive {
 			goto Start
@@ -178,9 +178,42 @@ func (s *Server) handleConnect(ctx context.Context, _ *http.Request, reader *buf
 	return dispatcher.DispatchConn(ctx, dest, conn, true)
 }
 
+// writeAuthenticationRequired asks the client for credentials, and closes the
+// connection afterwards as the request body, if any, is not read.
+func (s *Server) writeAuthenticationRequired(writer io.Writer) error {
+	response := &http.Response{
+		Status:        "Proxy Authentication Required",
+		StatusCode:    http.StatusProxyAuthRequired,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(make(map[string][]string)),
+		Body:          nil,
+		ContentLength: 0,
+		Close:         true,
+	}
+	response.Header.Set("Proxy-Authenticate", s.config.AuthenticateChallenge())
+	response.Header.Set("Proxy-Connection", "close")
+	response.Header.Set("Connection", "close")
+	return response.Write(writer)
+}
+
 var errWaitAnother = newError("keep alive")
 
-func (s *Server) handlePlainHTTP(ctx context.Context, request *http.Request, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher) error {
+// shouldKeepAlive reports whether the client connection may be reused after
+// request. HTTP/1.1 connections persist unless the client asks to close them,
+// HTTP/1.0 ones only if it asks to keep them alive.
+func shouldKeepAlive(request *http.Request) bool {
+	switch strings.TrimSpace(strings.ToLower(request.Header.Get("Proxy-Connection"))) {
+	case "keep-alive":
+		return true
+	case "close":
+		return false
+	}
+	return !request.Close
+}
+
+func (s *Server) handlePlainHTTP(ctx context.Context, request *http.Request, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher, keepAlive bool) error {
 	if !s.config.AllowTransparent && request.URL.Host == "" {
 		// RFC 2068 (HTTP/1.1) requires URL to be absolute URL in HTTP proxy.
 		response := &http.Response{
@@ -247,12 +280,14 @@ func (s *Server) handlePlainHTTP(ctx context.Context, request *http.Request, wri
 		response, err := http.ReadResponse(responseReader, request)
 		if err == nil {
 			http_proto.RemoveHopByHopHeaders(response.Header)
-			if response.ContentLength >= 0 {
+			if keepAlive && response.ContentLength >= 0 {
 				response.Header.Set("Proxy-Connection", "keep-alive")
 				response.Header.Set("Connection", "keep-alive")
 				response.Header.Set("Keep-Alive", "timeout=4")
 				response.Close = false
 			} else {
+				response.Header.Set("Proxy-Connection", "close")
+				response.Header.Set("Connection", "close")
 				response.Close = true
 				result = nil
 			}
@@ -272,6 +307,7 @@ func (s *Server) handlePlainHTTP(ctx context.Context, request *http.Request, wri
 			}
 			response.Header.Set("Connection", "close")
 			response.Header.Set("Proxy-Connection", "close")
+			result = nil
 		}
 		if err := response.Write(writer); err != nil {
 			return newError("failed to write response").Base(err).AtWarning()
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	gonet "net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+func TestShouldKeepAlive(t *testing.T) {
+	testCases := []struct {
+		Request   string
+		KeepAlive bool
+	}{
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.1\r\nHost: v2fly.org\r\n\r\n",
+			KeepAlive: true,
+		},
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.1\r\nHost: v2fly.org\r\nConnection: close\r\n\r\n",
+			KeepAlive: false,
+		},
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.1\r\nHost: v2fly.org\r\nProxy-Connection: close\r\n\r\n",
+			KeepAlive: false,
+		},
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.0\r\nHost: v2fly.org\r\n\r\n",
+			KeepAlive: false,
+		},
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.0\r\nHost: v2fly.org\r\nProxy-Connection: keep-alive\r\n\r\n",
+			KeepAlive: true,
+		},
+		{
+			Request:   "GET http://v2fly.org/ HTTP/1.0\r\nHost: v2fly.org\r\nConnection: keep-alive\r\n\r\n",
+			KeepAlive: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		request, err := http.ReadRequest(bufio.NewReader(strings.NewReader(testCase.Request)))
+		common.Must(err)
+		if keepAlive := shouldKeepAlive(request); keepAlive != testCase.KeepAlive {
+			t.Error("for request: ", testCase.Request, " expected keep-alive ", testCase.KeepAlive, " but got ", keepAlive)
+		}
+	}
+}
+
+// connectRecorder records the user of the connections dispatched to it.
+type connectRecorder struct {
+	routing.Dispatcher
+	email string
+}
+
+func (r *connectRecorder) DispatchConn(ctx context.Context, _ net.Destination, conn net.Conn, _ bool) error {
+	r.email = session.InboundFromContext(ctx).User.Email
+	return conn.Close()
+}
+
+// roundTrip sends request to s, and returns the response to it.
+func roundTrip(s *Server, dispatcher routing.Dispatcher, request string) *http.Response {
+	client, server := gonet.Pipe()
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		ctx := session.ContextWithInbound(context.Background(), &session.Inbound{})
+		done <- s.Process(ctx, net.Network_TCP, server, dispatcher)
+		server.Close()
+	}()
+
+	common.Must2(client.Write([]byte(request)))
+	response, err := http.ReadResponse(bufio.NewReader(client), nil)
+	common.Must(err)
+	client.Close()
+	<-done
+	return response
+}
+
+func TestAuthenticationRequired(t *testing.T) {
+	testCases := []struct {
+		Realm     string
+		Challenge string
+	}{
+		{
+			Realm:     "",
+			Challenge: `Basic realm="proxy"`,
+		},
+		{
+			Realm:     `my "home" proxy`,
+			Challenge: `Basic realm="my \"home\" proxy"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		s := &Server{
+			config: &ServerConfig{
+				Accounts: map[string]string{"user": "pass"},
+				Realm:    testCase.Realm,
+			},
+			policyManager: policy.DefaultManager{},
+		}
+		response := roundTrip(s, nil, "CONNECT v2fly.org:443 HTTP/1.1\r\nHost: v2fly.org:443\r\n\r\n")
+		if response.StatusCode != http.StatusProxyAuthRequired {
+			t.Error("expected status 407, but got ", response.Status)
+		}
+		if challenge := response.Header.Get("Proxy-Authenticate"); challenge != testCase.Challenge {
+			t.Error("expected challenge ", testCase.Challenge, ", but got ", challenge)
+		}
+		if response.ContentLength != 0 || !response.Close {
+			t.Error("expected an empty response closing the connection, but got ", response.Header)
+		}
+	}
+}
+
+func TestAuthenticatedUser(t *testing.T) {
+	s := &Server{
+		config: &ServerConfig{
+			Accounts: map[string]string{"user": "pass"},
+		},
+		policyManager: policy.DefaultManager{},
+	}
+	recorder := new(connectRecorder)
+	// dXNlcjpwYXNz is the base64 of "user:pass".
+	response := roundTrip(s, recorder, "CONNECT v2fly.org:443 HTTP/1.1\r\nHost: v2fly.org:443\r\nProxy-Authorization: Basic dXNlcjpwYXNz\r\n\r\n")
+	if response.StatusCode != http.StatusOK {
+		t.Error("expected status 200, but got ", response.Status)
+	}
+	if recorder.email != "user" {
+		t.Error("expected the connection of user, but got ", recorder.email)
+	}
+}
@@ -47,6 +47,12 @@ type UserManager interface {
 	RemoveUser(context.Context, string) error
 }
 
+// StreamSettingsChecker is the interface for Inbounds and Outbounds that depend on the stream settings of
+// their handlers. The handlers fail to be created if CheckStreamSettings returns an error.
+type StreamSettingsChecker interface {
+	CheckStreamSettings(*internet.MemoryStreamConfig) error
+}
+
 type GetInbound interface {
 	GetInbound() Inbound
 }
@@ -35,6 +35,11 @@ type MemoryAccount struct {
 	Cipher Cipher
 	Key    []byte
 
+	// IdentityKeys are the keys of the servers on the path to the user's
+	// server, in order. Each of them gets an identity header announcing the
+	// next key, see SIP023.
+	IdentityKeys [][]byte
+
 	replayFilter antireplay.GeneralizedReplayFilter
 
 	UoT              bool
@@ -386,23 +391,34 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 		return nil, newError("failed to get cipher").Base(err)
 	}
 	var key []byte
+	var identityKeys [][]byte
 	if !c.Family().IsSpec2022() {
 		key = passwordToCipherKey([]byte(a.Password), c.KeySize())
 	} else {
-		key, err = base64.StdEncoding.DecodeString(a.Password)
-		if err != nil {
-			return nil, newError("failed to decode password as key").Base(err)
+		passwords := strings.Split(a.Password, ":")
+		for _, password := range passwords {
+			k, err := base64.StdEncoding.DecodeString(password)
+			if err != nil {
+				return nil, newError("failed to decode password as key").Base(err)
+			}
+			if len(k) != 32 {
+				return nil, newError("bad key")
+			}
+			identityKeys = append(identityKeys, k)
 		}
-		if len(key) != 32 {
-			return nil, newError("bad key")
+		if len(identityKeys) > 1 && c.Family() != CipherFamilyAEADSpec2022UDPBlock {
+			return nil, newError("identity headers require an AES cipher")
 		}
+		key = identityKeys[len(identityKeys)-1]
+		identityKeys = identityKeys[:len(identityKeys)-1]
 	}
 	return &MemoryAccount{
-		Cipher: c,
-		Key:    key,
+		Cipher:       c,
+		Key:          key,
+		IdentityKeys: identityKeys,
 		replayFilter: func() antireplay.GeneralizedReplayFilter {
 			if c.Family().IsSpec2022() {
-				return antireplay.NewReplayFilter(30)
+				return antireplay.NewReplayFilter(MaxTimeDifference)
 			}
 			if a.IvCheck {
 				return antireplay.NewBloomRing()
@@ -278,6 +278,14 @@ type ServerConfig struct {
 	Plugin         string                    `protobuf:"bytes,5,opt,name=plugin,proto3" json:"plugin,omitempty"`
 	PluginOpts     string                    `protobuf:"bytes,6,opt,name=plugin_opts,json=pluginOpts,proto3" json:"plugin_opts,omitempty"`
 	PluginArgs     []string                  `protobuf:"bytes,7,rep,name=plugin_args,json=pluginArgs,proto3" json:"plugin_args,omitempty"`
+	// Users share the port of a Shadowsocks 2022 AES server. Each connection
+	// selects its user by identity header, and user then holds the server key.
+	Users []*protocol.User `protobuf:"bytes,8,rep,name=users,proto3" json:"users,omitempty"`
+	// Idle timeout of UDP sessions in seconds, 300 by default. A session
+	// relays the packets from a client, and is closed when idle for longer.
+	// Timeouts over 300 seconds are rejected, as the UDP listener of the
+	// inbound closes sessions idle for 300 seconds regardless.
+	UdpTimeout uint32 `protobuf:"varint,9,opt,name=udp_timeout,json=udpTimeout,proto3" json:"udp_timeout,omitempty"`
 }
 
 func (x *ServerConfig) Reset() {
@@ -362,6 +370,20 @@ func (x *ServerConfig) GetPluginArgs() []string {
 	return nil
 }
 
+func (x *ServerConfig) GetUsers() []*protocol.User {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *ServerConfig) GetUdpTimeout() uint32 {
+	if x != nil {
+		return x.UdpTimeout
+	}
+	return 0
+}
+
 type ClientConfig struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -471,7 +493,7 @@ var file_proxy_shadowsocks_config_proto_rawDesc = []byte{
 	0x64, 0x5f, 0x69, 0x76, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x5f, 0x65, 0x6e, 0x74, 0x72, 0x6f, 0x70,
 	0x79, 0x18, 0x91, 0xbf, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x1e, 0x65, 0x78, 0x70, 0x65, 0x72,
 	0x69, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x64, 0x75, 0x63, 0x65, 0x64, 0x49, 0x76, 0x48, 0x65,
-	0x61, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x22, 0xd1, 0x02, 0x0a, 0x0c, 0x53, 0x65,
+	0x61, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x22, 0xaa, 0x03, 0x0a, 0x0c, 0x53, 0x65,
 	0x72, 0x76, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x23, 0x0a, 0x0b, 0x75, 0x64,
 	0x70, 0x5f, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x42,
 	0x02, 0x18, 0x01, 0x52, 0x0a, 0x75, 0x64, 0x70, 0x45, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12,
@@ -492,78 +514,83 @@ var file_proxy_shadowsocks_config_proto_rawDesc = []byte{
 	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x5f, 0x6f, 0x70, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x4f, 0x70, 0x74, 0x73, 0x12, 0x1f, 0x0a, 0x0b,
 	0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x5f, 0x61, 0x72, 0x67, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28,
-	0x09, 0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x41, 0x72, 0x67, 0x73, 0x22, 0xfa, 0x01,
-	0x0a, 0x0c, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x42,
-	0x0a, 0x06, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a,
-	0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x6d,
-	0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2e, 0x53, 0x65, 0x72, 0x76,
-	0x65, 0x72, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x06, 0x73, 0x65, 0x72, 0x76,
-	0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6c,
-	0x75, 0x67, 0x69, 0x6e, 0x5f, 0x6f, 0x70, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x4f, 0x70, 0x74, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x70,
-	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x5f, 0x61, 0x72, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
-	0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x41, 0x72, 0x67, 0x73, 0x12, 0x4c, 0x0a, 0x22,
-	0x65, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x72, 0x65, 0x64, 0x75, 0x63,
-	0x65, 0x64, 0x5f, 0x69, 0x76, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x5f, 0x65, 0x6e, 0x74, 0x72, 0x6f,
-	0x70, 0x79, 0x18, 0x91, 0xbf, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x1e, 0x65, 0x78, 0x70, 0x65,
-	0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x64, 0x75, 0x63, 0x65, 0x64, 0x49, 0x76, 0x48,
-	0x65, 0x61, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x2a, 0xe6, 0x05, 0x0a, 0x0a, 0x43,
-	0x69, 0x70, 0x68, 0x65, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b,
-	0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32,
-	0x38, 0x5f, 0x47, 0x43, 0x4d, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31,
-	0x39, 0x32, 0x5f, 0x47, 0x43, 0x4d, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f,
-	0x32, 0x35, 0x36, 0x5f, 0x47, 0x43, 0x4d, 0x10, 0x03, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x48, 0x41,
-	0x43, 0x48, 0x41, 0x32, 0x30, 0x5f, 0x49, 0x45, 0x54, 0x46, 0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x31,
-	0x33, 0x30, 0x35, 0x10, 0x04, 0x12, 0x1b, 0x0a, 0x17, 0x58, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41,
-	0x32, 0x30, 0x5f, 0x49, 0x45, 0x54, 0x46, 0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x31, 0x33, 0x30, 0x35,
-	0x10, 0x05, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x06, 0x12, 0x0f, 0x0a, 0x0b,
-	0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x54, 0x52, 0x10, 0x07, 0x12, 0x0f, 0x0a,
-	0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x54, 0x52, 0x10, 0x08, 0x12, 0x0f,
-	0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x54, 0x52, 0x10, 0x09, 0x12,
-	0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x0a,
-	0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x10,
-	0x0b, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x46, 0x42,
-	0x10, 0x0c, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46,
-	0x42, 0x38, 0x10, 0x0d, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f,
-	0x43, 0x46, 0x42, 0x38, 0x10, 0x0e, 0x12, 0x10, 0x0a, 0x0c, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35,
-	0x36, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x0f, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f,
-	0x31, 0x32, 0x38, 0x5f, 0x4f, 0x46, 0x42, 0x10, 0x10, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53,
-	0x5f, 0x31, 0x39, 0x32, 0x5f, 0x4f, 0x46, 0x42, 0x10, 0x11, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45,
-	0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x4f, 0x46, 0x42, 0x10, 0x12, 0x12, 0x07, 0x0a, 0x03, 0x52,
-	0x43, 0x34, 0x10, 0x13, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x43, 0x34, 0x5f, 0x4d, 0x44, 0x35, 0x10,
-	0x14, 0x12, 0x0a, 0x0a, 0x06, 0x42, 0x46, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x15, 0x12, 0x0d, 0x0a,
-	0x09, 0x43, 0x41, 0x53, 0x54, 0x35, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x16, 0x12, 0x0b, 0x0a, 0x07,
-	0x44, 0x45, 0x53, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x17, 0x12, 0x0c, 0x0a, 0x08, 0x49, 0x44, 0x45,
-	0x41, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x18, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x43, 0x32, 0x5f, 0x43,
-	0x46, 0x42, 0x10, 0x19, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x45, 0x45, 0x44, 0x5f, 0x43, 0x46, 0x42,
-	0x10, 0x1a, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31,
-	0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x1b, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x41, 0x4d, 0x45,
-	0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x1c, 0x12, 0x14,
-	0x0a, 0x10, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43,
-	0x46, 0x42, 0x10, 0x1d, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41,
-	0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x1e, 0x12, 0x15, 0x0a, 0x11, 0x43,
-	0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x38,
-	0x10, 0x1f, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x32,
-	0x35, 0x36, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x20, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x41, 0x4c,
-	0x53, 0x41, 0x32, 0x30, 0x10, 0x21, 0x12, 0x0c, 0x0a, 0x08, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41,
-	0x32, 0x30, 0x10, 0x22, 0x12, 0x11, 0x0a, 0x0d, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30,
-	0x5f, 0x49, 0x45, 0x54, 0x46, 0x10, 0x23, 0x12, 0x0d, 0x0a, 0x09, 0x58, 0x43, 0x48, 0x41, 0x43,
-	0x48, 0x41, 0x32, 0x30, 0x10, 0x24, 0x12, 0x1b, 0x0a, 0x17, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x33,
-	0x5f, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x47, 0x43, 0x4d, 0x5f, 0x32, 0x30, 0x32,
-	0x32, 0x10, 0x25, 0x12, 0x1b, 0x0a, 0x17, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x33, 0x5f, 0x41, 0x45,
-	0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x47, 0x43, 0x4d, 0x5f, 0x32, 0x30, 0x32, 0x32, 0x10, 0x26,
-	0x12, 0x21, 0x0a, 0x1d, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x33, 0x5f, 0x43, 0x48, 0x41, 0x43, 0x48,
-	0x41, 0x32, 0x30, 0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x31, 0x33, 0x30, 0x35, 0x5f, 0x32, 0x30, 0x32,
-	0x32, 0x10, 0x27, 0x42, 0x75, 0x0a, 0x20, 0x63, 0x6f, 0x6d, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79,
-	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x73, 0x68, 0x61, 0x64,
-	0x6f, 0x77, 0x73, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x01, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75,
-	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x76, 0x32, 0x66, 0x6c, 0x79, 0x2f, 0x76, 0x32, 0x72, 0x61,
-	0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x76, 0x35, 0x2f, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f,
-	0x73, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x73, 0x6f, 0x63, 0x6b, 0x73, 0xaa, 0x02, 0x1c, 0x56, 0x32,
-	0x52, 0x61, 0x79, 0x2e, 0x43, 0x6f, 0x72, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x53,
-	0x68, 0x61, 0x64, 0x6f, 0x77, 0x73, 0x6f, 0x63, 0x6b, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x09, 0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x41, 0x72, 0x67, 0x73, 0x12, 0x36, 0x0a,
+	0x05, 0x75, 0x73, 0x65, 0x72, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x76,
+	0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2e, 0x55, 0x73, 0x65, 0x72, 0x52, 0x05,
+	0x75, 0x73, 0x65, 0x72, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x75, 0x64, 0x70, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x75, 0x64, 0x70, 0x54,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x22, 0xfa, 0x01, 0x0a, 0x0c, 0x43, 0x6c, 0x69, 0x65, 0x6e,
+	0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x42, 0x0a, 0x06, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e,
+	0x63, 0x6f, 0x72, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x63, 0x6f, 0x6c, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x45, 0x6e, 0x64, 0x70, 0x6f,
+	0x69, 0x6e, 0x74, 0x52, 0x06, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x70,
+	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x6c, 0x75,
+	0x67, 0x69, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x5f, 0x6f, 0x70,
+	0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e,
+	0x4f, 0x70, 0x74, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x5f, 0x61,
+	0x72, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x6c, 0x75, 0x67, 0x69,
+	0x6e, 0x41, 0x72, 0x67, 0x73, 0x12, 0x4c, 0x0a, 0x22, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d,
+	0x65, 0x6e, 0x74, 0x5f, 0x72, 0x65, 0x64, 0x75, 0x63, 0x65, 0x64, 0x5f, 0x69, 0x76, 0x5f, 0x68,
+	0x65, 0x61, 0x64, 0x5f, 0x65, 0x6e, 0x74, 0x72, 0x6f, 0x70, 0x79, 0x18, 0x91, 0xbf, 0x05, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x1e, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x65, 0x64, 0x75, 0x63, 0x65, 0x64, 0x49, 0x76, 0x48, 0x65, 0x61, 0x64, 0x45, 0x6e, 0x74, 0x72,
+	0x6f, 0x70, 0x79, 0x2a, 0xe6, 0x05, 0x0a, 0x0a, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12,
+	0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x47, 0x43, 0x4d, 0x10, 0x01,
+	0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x47, 0x43, 0x4d, 0x10,
+	0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x47, 0x43, 0x4d,
+	0x10, 0x03, 0x12, 0x1a, 0x0a, 0x16, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x5f, 0x49,
+	0x45, 0x54, 0x46, 0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x31, 0x33, 0x30, 0x35, 0x10, 0x04, 0x12, 0x1b,
+	0x0a, 0x17, 0x58, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x5f, 0x49, 0x45, 0x54, 0x46,
+	0x5f, 0x50, 0x4f, 0x4c, 0x59, 0x31, 0x33, 0x30, 0x35, 0x10, 0x05, 0x12, 0x08, 0x0a, 0x04, 0x4e,
+	0x4f, 0x4e, 0x45, 0x10, 0x06, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38,
+	0x5f, 0x43, 0x54, 0x52, 0x10, 0x07, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39,
+	0x32, 0x5f, 0x43, 0x54, 0x52, 0x10, 0x08, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x32,
+	0x35, 0x36, 0x5f, 0x43, 0x54, 0x52, 0x10, 0x09, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f,
+	0x31, 0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x0a, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53,
+	0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x0b, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45,
+	0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x0c, 0x12, 0x10, 0x0a, 0x0c, 0x41,
+	0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x0d, 0x12, 0x10, 0x0a,
+	0x0c, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x0e, 0x12,
+	0x10, 0x0a, 0x0c, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10,
+	0x0f, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x4f, 0x46, 0x42,
+	0x10, 0x10, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x39, 0x32, 0x5f, 0x4f, 0x46,
+	0x42, 0x10, 0x11, 0x12, 0x0f, 0x0a, 0x0b, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x4f,
+	0x46, 0x42, 0x10, 0x12, 0x12, 0x07, 0x0a, 0x03, 0x52, 0x43, 0x34, 0x10, 0x13, 0x12, 0x0b, 0x0a,
+	0x07, 0x52, 0x43, 0x34, 0x5f, 0x4d, 0x44, 0x35, 0x10, 0x14, 0x12, 0x0a, 0x0a, 0x06, 0x42, 0x46,
+	0x5f, 0x43, 0x46, 0x42, 0x10, 0x15, 0x12, 0x0d, 0x0a, 0x09, 0x43, 0x41, 0x53, 0x54, 0x35, 0x5f,
+	0x43, 0x46, 0x42, 0x10, 0x16, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x53, 0x5f, 0x43, 0x46, 0x42,
+	0x10, 0x17, 0x12, 0x0c, 0x0a, 0x08, 0x49, 0x44, 0x45, 0x41, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x18,
+	0x12, 0x0b, 0x0a, 0x07, 0x52, 0x43, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x19, 0x12, 0x0c, 0x0a,
+	0x08, 0x53, 0x45, 0x45, 0x44, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x1a, 0x12, 0x14, 0x0a, 0x10, 0x43,
+	0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46, 0x42, 0x10,
+	0x1b, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31, 0x39,
+	0x32, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x1c, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x41, 0x4d, 0x45, 0x4c,
+	0x4c, 0x49, 0x41, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x46, 0x42, 0x10, 0x1d, 0x12, 0x15, 0x0a,
+	0x11, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x31, 0x32, 0x38, 0x5f, 0x43, 0x46,
+	0x42, 0x38, 0x10, 0x1e, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41,
+	0x5f, 0x31, 0x39, 0x32, 0x5f, 0x43, 0x46, 0x42, 0x38, 0x10, 0x1f, 0x12, 0x15, 0x0a, 0x11, 0x43,
+	0x41, 0x4d, 0x45, 0x4c, 0x4c, 0x49, 0x41, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x43, 0x46, 0x42, 0x38,
+	0x10, 0x20, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x41, 0x4c, 0x53, 0x41, 0x32, 0x30, 0x10, 0x21, 0x12,
+	0x0c, 0x0a, 0x08, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x10, 0x22, 0x12, 0x11, 0x0a,
+	0x0d, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x5f, 0x49, 0x45, 0x54, 0x46, 0x10, 0x23,
+	0x12, 0x0d, 0x0a, 0x09, 0x58, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x10, 0x24, 0x12,
+	0x1b, 0x0a, 0x17, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x33, 0x5f, 0x41, 0x45, 0x53, 0x5f, 0x31, 0x32,
+	0x38, 0x5f, 0x47, 0x43, 0x4d, 0x5f, 0x32, 0x30, 0x32, 0x32, 0x10, 0x25, 0x12, 0x1b, 0x0a, 0x17,
+	0x42, 0x4c, 0x41, 0x4b, 0x45, 0x33, 0x5f, 0x41, 0x45, 0x53, 0x5f, 0x32, 0x35, 0x36, 0x5f, 0x47,
+	0x43, 0x4d, 0x5f, 0x32, 0x30, 0x32, 0x32, 0x10, 0x26, 0x12, 0x21, 0x0a, 0x1d, 0x42, 0x4c, 0x41,
+	0x4b, 0x45, 0x33, 0x5f, 0x43, 0x48, 0x41, 0x43, 0x48, 0x41, 0x32, 0x30, 0x5f, 0x50, 0x4f, 0x4c,
+	0x59, 0x31, 0x33, 0x30, 0x35, 0x5f, 0x32, 0x30, 0x32, 0x32, 0x10, 0x27, 0x42, 0x75, 0x0a, 0x20,
+	0x63, 0x6f, 0x6d, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x78, 0x79, 0x2e, 0x73, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x73, 0x6f, 0x63, 0x6b, 0x73,
+	0x50, 0x01, 0x5a, 0x30, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x76,
+	0x32, 0x66, 0x6c, 0x79, 0x2f, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f,
+	0x76, 0x35, 0x2f, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f, 0x73, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x73,
+	0x6f, 0x63, 0x6b, 0x73, 0xaa, 0x02, 0x1c, 0x56, 0x32, 0x52, 0x61, 0x79, 0x2e, 0x43, 0x6f, 0x72,
+	0x65, 0x2e, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x53, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x73, 0x6f,
+	0x63, 0x6b, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -595,12 +622,13 @@ var file_proxy_shadowsocks_config_proto_depIdxs = []int32{
 	4, // 1: v2ray.core.proxy.shadowsocks.ServerConfig.user:type_name -> v2ray.core.common.protocol.User
 	5, // 2: v2ray.core.proxy.shadowsocks.ServerConfig.network:type_name -> v2ray.core.common.net.Network
 	6, // 3: v2ray.core.proxy.shadowsocks.ServerConfig.packet_encoding:type_name -> v2ray.core.net.packetaddr.PacketAddrType
-	7, // 4: v2ray.core.proxy.shadowsocks.ClientConfig.server:type_name -> v2ray.core.common.protocol.ServerEndpoint
-	5, // [5:5] is the sub-list for method output_type
-	5, // [5:5] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	4, // 4: v2ray.core.proxy.shadowsocks.ServerConfig.users:type_name -> v2ray.core.common.protocol.User
+	7, // 5: v2ray.core.proxy.shadowsocks.ClientConfig.server:type_name -> v2ray.core.common.protocol.ServerEndpoint
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_proxy_shadowsocks_config_proto_init() }
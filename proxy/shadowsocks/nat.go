@@ -0,0 +1,140 @@
+package shadowsocks
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+// defaultUDPTimeout is the idle timeout of UDP sessions if not configured.
+const defaultUDPTimeout = 5 * time.Minute
+
+// MaxUDPTimeout is the longest idle timeout of UDP sessions in seconds. The
+// UDP listener of the inbound closes the sessions idle for 5 minutes anyway.
+const MaxUDPTimeout = 5 * 60
+
+// natEntry is the UDP session of a client, which relays its packets.
+type natEntry struct {
+	lastActivity int64 // in unix nanoseconds
+	close        func()
+}
+
+// natTable tracks the UDP sessions of a server, and expires the sessions idle
+// for longer than the timeout.
+type natTable struct {
+	timeout time.Duration
+	now     func() time.Time
+	checker *task.Periodic
+
+	access  sync.Mutex
+	entries map[*natEntry]struct{}
+
+	active  stats.Counter
+	created stats.Counter
+	expired stats.Counter
+}
+
+func newNATTable(timeout time.Duration) *natTable {
+	if timeout <= 0 {
+		timeout = defaultUDPTimeout
+	}
+	t := &natTable{
+		timeout: timeout,
+		now:     time.Now,
+		entries: make(map[*natEntry]struct{}),
+	}
+	interval := timeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	t.checker = &task.Periodic{
+		Interval: interval,
+		Execute:  t.clean,
+	}
+	return t
+}
+
+// registerCounters registers the counters of the sessions of inbound tag, of
+// which the names are "inbound>>>[tag]>>>udp>>>active", "created" and
+// "expired" in the end.
+func (t *natTable) registerCounters(m stats.Manager, tag string) {
+	prefix := "inbound>>>" + tag + ">>>udp>>>"
+	t.active, _ = stats.GetOrRegisterCounter(m, prefix+"active")
+	t.created, _ = stats.GetOrRegisterCounter(m, prefix+"created")
+	t.expired, _ = stats.GetOrRegisterCounter(m, prefix+"expired")
+}
+
+// add adds a session, which is closed by calling close once expired.
+func (t *natTable) add(close func()) *natEntry {
+	e := &natEntry{
+		lastActivity: t.now().UnixNano(),
+		close:        close,
+	}
+	t.access.Lock()
+	t.entries[e] = struct{}{}
+	t.access.Unlock()
+
+	if t.created != nil {
+		t.created.Add(1)
+	}
+	if t.active != nil {
+		t.active.Add(1)
+	}
+	if err := t.checker.Start(); err != nil {
+		newError("failed to start UDP session checker").Base(err).WriteToLog()
+	}
+	return e
+}
+
+// update marks the session active.
+func (t *natTable) update(e *natEntry) {
+	atomic.StoreInt64(&e.lastActivity, t.now().UnixNano())
+}
+
+// remove removes the session, and returns whether it was in the table.
+func (t *natTable) remove(e *natEntry) bool {
+	t.access.Lock()
+	_, found := t.entries[e]
+	delete(t.entries, e)
+	t.access.Unlock()
+
+	if found && t.active != nil {
+		t.active.Add(-1)
+	}
+	return found
+}
+
+func (t *natTable) clean() error {
+	deadline := t.now().Add(-t.timeout).UnixNano()
+	var expired []*natEntry
+
+	t.access.Lock()
+	if len(t.entries) == 0 {
+		t.access.Unlock()
+		return newError("no more UDP sessions. stopping...")
+	}
+	for e := range t.entries {
+		if atomic.LoadInt64(&e.lastActivity) < deadline {
+			expired = append(expired, e)
+		}
+	}
+	t.access.Unlock()
+
+	for _, e := range expired {
+		if t.remove(e) {
+			if t.expired != nil {
+				t.expired.Add(1)
+			}
+			e.close()
+		}
+	}
+	return nil
+}
+
+// Close stops expiring the sessions.
+func (t *natTable) Close() error {
+	return t.checker.Close()
+}
@@ -0,0 +1,68 @@
+package shadowsocks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestNATTableExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	table := newNATTable(time.Minute)
+	table.now = clock.Now
+	defer table.Close()
+
+	m, err := stats.NewManager(context.Background(), &stats.Config{})
+	common.Must(err)
+	table.registerCounters(m, "ss-in")
+	counter := func(name string) int64 {
+		return m.GetCounter("inbound>>>ss-in>>>udp>>>" + name).Value()
+	}
+
+	closed := make(map[int]bool)
+	entries := make([]*natEntry, 3)
+	for i := range entries {
+		i := i
+		entries[i] = table.add(func() { closed[i] = true })
+	}
+	if created, active := counter("created"), counter("active"); created != 3 || active != 3 {
+		t.Fatal("expected 3 created and active sessions, but got ", created, " and ", active)
+	}
+
+	clock.now = clock.now.Add(40 * time.Second)
+	table.update(entries[0])
+	clock.now = clock.now.Add(40 * time.Second)
+	common.Must(table.clean())
+	if !closed[1] || !closed[2] || closed[0] {
+		t.Error("expected the idle sessions to be closed, but got ", closed)
+	}
+	if expired, active := counter("expired"), counter("active"); expired != 2 || active != 1 {
+		t.Error("expected 2 expired sessions and 1 active, but got ", expired, " and ", active)
+	}
+
+	// Sessions ending by themselves are not expired.
+	if !table.remove(entries[0]) || table.remove(entries[0]) {
+		t.Error("expected the session to be removed once")
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if err := table.clean(); err == nil {
+		t.Error("expected the checker to stop without sessions")
+	}
+	if closed[0] {
+		t.Error("removed session closed")
+	}
+	if expired, active, created := counter("expired"), counter("active"), counter("created"); expired != 2 || active != 0 || created != 3 {
+		t.Error("unexpected counters: ", expired, " expired, ", active, " active, ", created, " created")
+	}
+}
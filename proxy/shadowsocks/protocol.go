@@ -28,14 +28,19 @@ const (
 )
 
 // ReadTCPSession reads a Shadowsocks TCP session from the given reader, returns its header and remaining parts.
-func ReadTCPSession(user *protocol.MemoryUser, reader io.Reader, conn *ProtocolConn) (*protocol.RequestHeader, []byte, buf.Reader, error) {
+// If validator is not nil, the session is expected to carry an identity header
+// selecting one of its users, and user holds the server key.
+func ReadTCPSession(user *protocol.MemoryUser, validator *Validator, reader io.Reader, conn *ProtocolConn) (*protocol.RequestHeader, []byte, buf.Reader, error) {
 	account := user.Account.(*MemoryAccount)
+	serverAccount := account
 
 	var iv []byte
 	var drainer drain.Drainer
 
 	cipherFamily := account.Cipher.Family()
-	if !cipherFamily.IsSpec2022() {
+	// Sessions with an identity header are drained too, so that a wrong
+	// identity can't be told apart by how soon the connection is closed.
+	if !cipherFamily.IsSpec2022() || validator != nil {
 		hashkdf := hmac.New(sha256.New, []byte("SSBSKDF"))
 		hashkdf.Write(account.Key)
 
@@ -63,6 +68,22 @@ func ReadTCPSession(user *protocol.MemoryUser, reader io.Reader, conn *ProtocolC
 		iv = append([]byte(nil), buffer.BytesTo(ivLen)...)
 	}
 
+	if validator != nil && cipherFamily.IsSpec2022() {
+		if _, err := buffer.ReadFullFrom(reader, IdentityHeaderSize); err != nil {
+			drainer.AcknowledgeReceive(int(buffer.Len()))
+			return nil, nil, nil, drain.WithError(drainer, reader, newError("failed to read identity header").Base(err))
+		}
+		var hash [IdentityHeaderSize]byte
+		copy(hash[:], buffer.BytesFrom(ivLen))
+		openIdentityHeader(hash[:], account.Key, iv)
+		user = validator.Get(hash)
+		if user == nil {
+			drainer.AcknowledgeReceive(int(buffer.Len()))
+			return nil, nil, nil, drain.WithError(drainer, reader, newError("unknown identity"))
+		}
+		account = user.Account.(*MemoryAccount)
+	}
+
 	r, err := account.Cipher.NewDecryptionReader(account.Key, iv, reader)
 	if err != nil {
 		if drainer != nil {
@@ -99,12 +120,12 @@ func ReadTCPSession(user *protocol.MemoryUser, reader io.Reader, conn *ProtocolC
 			return nil, nil, nil, newError("bad request type")
 		}
 		epoch := int64(binary.BigEndian.Uint64(buffer.BytesRange(1, 1+8)))
-		if math.Abs(float64(time.Now().Unix()-epoch)) > 30 {
+		if math.Abs(float64(time.Now().Unix()-epoch)) > MaxTimeDifference {
 			return nil, nil, nil, newError("bad timestamp")
 		}
 	}
 
-	if ivError := account.CheckIV(iv); ivError != nil {
+	if ivError := serverAccount.CheckIV(iv); ivError != nil {
 		if drainer != nil {
 			drainer.AcknowledgeReceive(int(buffer.Len()))
 		}
@@ -163,6 +184,20 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer, iv []byt
 		}
 	}
 
+	if len(account.IdentityKeys) > 0 {
+		headers := make([]byte, IdentityHeaderSize*len(account.IdentityKeys))
+		for i, identityKey := range account.IdentityKeys {
+			nextKey := account.Key
+			if i+1 < len(account.IdentityKeys) {
+				nextKey = account.IdentityKeys[i+1]
+			}
+			sealIdentityHeader(headers[i*IdentityHeaderSize:(i+1)*IdentityHeaderSize], identityKey, iv, nextKey)
+		}
+		if err := buf.WriteAllBytes(writer, headers); err != nil {
+			return nil, newError("failed to write identity headers").Base(err)
+		}
+	}
+
 	w, err := account.Cipher.NewEncryptionWriter(account.Key, iv, writer)
 	if err != nil {
 		return nil, newError("failed to create encoding stream").Base(err).AtError()
@@ -185,18 +220,20 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer, iv []byt
 
 	if cipherFamily.IsSpec2022() {
 		paddingLen := header.Extend(2)
+		// Without an initial payload the header must carry random padding.
+		err = buf.ErrNotTimeoutReader
 		if reader != nil {
-			if err = buf.CopyOnceTimeout(reader, buf.NewWriter(header), time.Millisecond*100); err != nil {
-				if err == buf.ErrNotTimeoutReader || err == buf.ErrReadTimeout {
-					pLen := mrand.Intn(MaxPaddingLength)
-					binary.BigEndian.PutUint16(paddingLen, uint16(pLen))
-					common.Must2(header.ReadFullFrom(rand.Reader, int32(pLen)))
-				} else {
-					return nil, newError("failed to write request payload").Base(err).AtWarning()
-				}
-			} else {
-				binary.BigEndian.PutUint16(paddingLen, uint16(0))
-			}
+			err = buf.CopyOnceTimeout(reader, buf.NewWriter(header), time.Millisecond*100)
+		}
+		switch err {
+		case nil:
+			binary.BigEndian.PutUint16(paddingLen, uint16(0))
+		case buf.ErrNotTimeoutReader, buf.ErrReadTimeout:
+			pLen := mrand.Intn(MaxPaddingLength)
+			binary.BigEndian.PutUint16(paddingLen, uint16(pLen))
+			common.Must2(header.ReadFullFrom(rand.Reader, int32(pLen)))
+		default:
+			return nil, newError("failed to write request payload").Base(err).AtWarning()
 		}
 		if err := w.WriteMultiBuffer(buf.MultiBuffer{header}); err != nil {
 			return nil, newError("failed to write header").Base(err)
@@ -269,7 +306,7 @@ func ReadTCPResponse(user *protocol.MemoryUser, command protocol.RequestCommand,
 			return nil, newError("bad response type")
 		}
 		epoch := int64(binary.BigEndian.Uint64(header.BytesRange(1, 1+8)))
-		if math.Abs(float64(time.Now().Unix()-epoch)) > 30 {
+		if math.Abs(float64(time.Now().Unix()-epoch)) > MaxTimeDifference {
 			return nil, newError("bad timestamp")
 		}
 		if bytes.Compare(requestIv, header.BytesFrom(1+8)) != 0 {
@@ -436,7 +473,7 @@ func DecodeUDPPacket(user *protocol.MemoryUser, payload *buf.Buffer, session *ud
 		if err != nil {
 			return nil, nil, err
 		}
-		if math.Abs(float64(uint64(time.Now().Unix())-epoch)) > 30 {
+		if math.Abs(float64(time.Now().Unix()-int64(epoch))) > MaxTimeDifference {
 			return nil, nil, newError("bad timestamp")
 		}
 		if session.headerType == HeaderTypeClient {
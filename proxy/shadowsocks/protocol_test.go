@@ -2,6 +2,8 @@ package shadowsocks_test
 
 import (
 	"crypto/rand"
+	"encoding/base64"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -45,7 +47,7 @@ func TestUDPEncoding(t *testing.T) {
 	encodedData, err := EncodeUDPPacket(request, data.Bytes(), nil, nil)
 	common.Must(err)
 
-	decodedRequest, decodedData, err := DecodeUDPPacket(request.User, encodedData, nil)
+	decodedRequest, decodedData, err := DecodeUDPPacket(request.User, encodedData, nil, nil)
 	common.Must(err)
 
 	if r := cmp.Diff(decodedData.Bytes(), data.Bytes()); r != "" {
@@ -135,7 +137,7 @@ func TestTCPRequest(t *testing.T) {
 
 		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{data}))
 
-		decodedRequest, _, reader, err := ReadTCPSession(request.User, cache, nil)
+		decodedRequest, _, reader, err := ReadTCPSession(request.User, nil, cache, nil)
 		common.Must(err)
 		if equalRequestHeader(decodedRequest, request) == false {
 			t.Error("different request")
@@ -153,6 +155,208 @@ func TestTCPRequest(t *testing.T) {
 	}
 }
 
+func TestTCPRequestAndResponse2022(t *testing.T) {
+	key := make([]byte, 32)
+	common.Must2(rand.Read(key))
+	user := &protocol.MemoryUser{
+		Email: "love@v2fly.org",
+		Account: toAccount(&Account{
+			Password:   base64.StdEncoding.EncodeToString(key),
+			CipherType: CipherType_BLAKE3_AES_256_GCM_2022,
+		}),
+	}
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: net.DomainAddress("v2fly.org"),
+		Port:    1234,
+		User:    user,
+	}
+	account := user.Account.(*MemoryAccount)
+
+	requestIV := make([]byte, account.Cipher.IVSize())
+	common.Must2(rand.Read(requestIV))
+
+	cache := buf.New()
+	defer cache.Release()
+
+	writer, err := WriteTCPRequest(request, cache, requestIV, nil, nil)
+	common.Must(err)
+	data := buf.New()
+	common.Must2(data.WriteString("test request"))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{data}))
+
+	decodedRequest, decodedIV, reader, err := ReadTCPSession(user, nil, cache, nil)
+	common.Must(err)
+	if equalRequestHeader(decodedRequest, request) == false {
+		t.Error("different request")
+	}
+	if r := cmp.Diff(decodedIV, requestIV); r != "" {
+		t.Error("request salt: ", r)
+	}
+	payload, err := reader.ReadMultiBuffer()
+	common.Must(err)
+	if payload.String() != "test request" {
+		t.Error("unexpected request payload: ", payload.String())
+	}
+	buf.ReleaseMulti(payload)
+
+	cache.Clear()
+
+	responseIV := make([]byte, account.Cipher.IVSize())
+	common.Must2(rand.Read(responseIV))
+	writer, err = WriteTCPResponse(request, cache, requestIV, responseIV, nil)
+	common.Must(err)
+	data = buf.New()
+	common.Must2(data.WriteString("test response"))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{data}))
+
+	reader, err = ReadTCPResponse(user, protocol.RequestCommandTCP, cache, requestIV, nil)
+	common.Must(err)
+	payload, err = reader.ReadMultiBuffer()
+	common.Must(err)
+	if payload.String() != "test response" {
+		t.Error("unexpected response payload: ", payload.String())
+	}
+	buf.ReleaseMulti(payload)
+
+	// A replayed request salt must be rejected.
+	cache.Clear()
+	writer, err = WriteTCPRequest(request, cache, requestIV, nil, nil)
+	common.Must(err)
+	if _, _, _, err := ReadTCPSession(user, nil, cache, nil); err == nil {
+		t.Error("expected replayed salt to be rejected")
+	}
+}
+
+func TestTCPResponse2022BadRequestSalt(t *testing.T) {
+	key := make([]byte, 32)
+	common.Must2(rand.Read(key))
+	user := &protocol.MemoryUser{
+		Account: toAccount(&Account{
+			Password:   base64.StdEncoding.EncodeToString(key),
+			CipherType: CipherType_BLAKE3_AES_256_GCM_2022,
+		}),
+	}
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: net.LocalHostIP,
+		Port:    1234,
+		User:    user,
+	}
+
+	requestIV := make([]byte, SaltSize)
+	common.Must2(rand.Read(requestIV))
+	responseIV := make([]byte, SaltSize)
+	common.Must2(rand.Read(responseIV))
+
+	cache := buf.New()
+	defer cache.Release()
+
+	writer, err := WriteTCPResponse(request, cache, requestIV, responseIV, nil)
+	common.Must(err)
+	data := buf.New()
+	common.Must2(data.WriteString("test response"))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{data}))
+
+	otherIV := make([]byte, SaltSize)
+	common.Must2(rand.Read(otherIV))
+	if _, err := ReadTCPResponse(user, protocol.RequestCommandTCP, cache, otherIV, nil); err == nil {
+		t.Error("expected response with mismatched request salt to be rejected")
+	}
+}
+
+func TestTCPRequestMultiUser2022(t *testing.T) {
+	newKey := func() string {
+		key := make([]byte, 32)
+		common.Must2(rand.Read(key))
+		return base64.StdEncoding.EncodeToString(key)
+	}
+	serverKey := newKey()
+	serverUser := &protocol.MemoryUser{
+		Account: toAccount(&Account{
+			Password:   serverKey,
+			CipherType: CipherType_BLAKE3_AES_256_GCM_2022,
+		}),
+	}
+
+	validator := new(Validator)
+	userKeys := map[string]string{
+		"alice@v2fly.org": newKey(),
+		"bob@v2fly.org":   newKey(),
+	}
+	for email, key := range userKeys {
+		common.Must(validator.Add(&protocol.MemoryUser{
+			Email: email,
+			Level: 1,
+			Account: toAccount(&Account{
+				Password:   key,
+				CipherType: CipherType_BLAKE3_AES_256_GCM_2022,
+			}),
+		}))
+	}
+
+	runTest := func(email string, clientKey string) error {
+		request := &protocol.RequestHeader{
+			Version: Version,
+			Command: protocol.RequestCommandTCP,
+			Address: net.DomainAddress("v2fly.org"),
+			Port:    1234,
+			User: &protocol.MemoryUser{
+				Account: toAccount(&Account{
+					Password:   serverKey + ":" + clientKey,
+					CipherType: CipherType_BLAKE3_AES_256_GCM_2022,
+				}),
+			},
+		}
+
+		iv := make([]byte, SaltSize)
+		common.Must2(rand.Read(iv))
+
+		cache := buf.New()
+		defer cache.Release()
+
+		writer, err := WriteTCPRequest(request, cache, iv, nil, nil)
+		common.Must(err)
+		data := buf.New()
+		common.Must2(data.WriteString(email))
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{data}))
+
+		decodedRequest, _, reader, err := ReadTCPSession(serverUser, validator, cache, nil)
+		if err != nil {
+			return err
+		}
+		if decodedRequest.User.Email != email {
+			t.Error("expected user ", email, " but got ", decodedRequest.User.Email)
+		}
+		if decodedRequest.User.Level != 1 {
+			t.Error("unexpected user level: ", decodedRequest.User.Level)
+		}
+		if decodedRequest.Destination() != request.Destination() {
+			t.Error("unexpected destination: ", decodedRequest.Destination())
+		}
+		payload, err := reader.ReadMultiBuffer()
+		common.Must(err)
+		if payload.String() != email {
+			t.Error("unexpected payload: ", payload.String())
+		}
+		buf.ReleaseMulti(payload)
+		return nil
+	}
+
+	for email, key := range userKeys {
+		common.Must(runTest(email, key))
+	}
+
+	// An unknown identity is drained like any other bad request.
+	if err := runTest("eve@v2fly.org", newKey()); err == nil {
+		t.Error("expected unknown user to be rejected")
+	} else if !strings.Contains(err.Error(), "drain") {
+		t.Error("expected unknown user to be drained, but got ", err)
+	}
+}
+
 func TestUDPReaderWriter(t *testing.T) {
 	user := &protocol.MemoryUser{
 		Account: toAccount(&Account{
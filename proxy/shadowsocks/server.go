@@ -25,6 +25,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/features/inbound"
 	"github.com/v2fly/v2ray-core/v5/features/policy"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/udp"
 )
@@ -32,7 +33,10 @@ import (
 type Server struct {
 	config        *ServerConfig
 	user          *protocol.MemoryUser
+	validator     *Validator
 	policyManager policy.Manager
+	stats         stats.Manager
+	nat           *natTable
 	tag           string
 	pluginTag     string
 
@@ -45,9 +49,13 @@ type Server struct {
 
 func (s *Server) Initialize(self inbound.Handler) {
 	s.tag = self.Tag()
+	if s.tag != "" && s.stats != nil {
+		s.nat.registerCounters(s.stats, s.tag)
+	}
 }
 
 func (s *Server) Close() error {
+	common.Close(s.nat)
 	if s.plugin != nil {
 		return s.plugin.Close()
 	}
@@ -64,12 +72,44 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	if err != nil {
 		return nil, newError("failed to parse user account").Base(err)
 	}
+	if config.UdpTimeout > MaxUDPTimeout {
+		return nil, newError("UDP timeout is longer than ", MaxUDPTimeout, " seconds")
+	}
 
 	v := core.MustFromContext(ctx)
 	s := &Server{
 		config:        config,
 		user:          mUser,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		nat:           newNATTable(time.Duration(config.UdpTimeout) * time.Second),
+	}
+	if statsManager, ok := v.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+		s.stats = statsManager
+	}
+
+	if len(config.Users) > 0 {
+		account := mUser.Account.(*MemoryAccount)
+		if account.Cipher.Family() != CipherFamilyAEADSpec2022UDPBlock {
+			return nil, newError("multiple users require a Shadowsocks 2022 AES cipher")
+		}
+		for _, network := range s.Network() {
+			if network == net.Network_UDP {
+				return nil, newError("UDP is not supported with multiple users")
+			}
+		}
+		s.validator = new(Validator)
+		for _, user := range config.Users {
+			u, err := user.ToMemoryUser()
+			if err != nil {
+				return nil, newError("failed to parse user account").Base(err)
+			}
+			if c := u.Account.(*MemoryAccount).Cipher; c.Family() != account.Cipher.Family() || c.KeySize() != account.Cipher.KeySize() {
+				return nil, newError("user ", u.Email, " does not match the server cipher")
+			}
+			if err := s.validator.Add(u); err != nil {
+				return nil, newError("failed to add user").Base(err)
+			}
+		}
 	}
 
 	if config.Plugin != "" {
@@ -172,7 +212,15 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 
 	us := newUDPSession(true)
 
+	ctx, cancel := context.WithCancel(ctx)
+	entry := s.nat.add(func() {
+		newError("UDP session expired").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+		cancel()
+		conn.Close()
+	})
+
 	udpServer := udpDispatcherConstructor(dispatcher, func(ctx context.Context, packet *udp_proto.Packet) {
+		s.nat.update(entry)
 		var request *protocol.RequestHeader
 		if packet.Source.IsValid() {
 			request = &protocol.RequestHeader{
@@ -207,6 +255,11 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 		panic("no inbound metadata")
 	}
 	inbound.User = s.user
+	defer func() {
+		s.nat.remove(entry)
+		cancel()
+		udpServer.Close()
+	}()
 
 	reader := buf.NewPacketReader(conn)
 	for {
@@ -214,6 +267,12 @@ func (s *Server) handlerUDPPayload(ctx context.Context, conn internet.Connection
 		if err != nil {
 			break
 		}
+		if ctx.Err() != nil {
+			// The packets left behind the expired session are dropped.
+			buf.ReleaseMulti(mpayload)
+			break
+		}
+		s.nat.update(entry)
 
 		for _, payload := range mpayload {
 			var (
@@ -305,7 +364,7 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 	}
 
 	bufferedReader := buf.BufferedReader{Reader: buf.NewReader(conn)}
-	request, requestIV, bodyReader, err := ReadTCPSession(s.user, &bufferedReader, protocolConn)
+	request, requestIV, bodyReader, err := ReadTCPSession(s.user, s.validator, &bufferedReader, protocolConn)
 	if err != nil {
 		log.Record(&log.AccessMessage{
 			From:   conn.RemoteAddr(),
@@ -317,7 +376,8 @@ func (s *Server) handleConnection(ctx context.Context, conn internet.Connection,
 	}
 	conn.SetReadDeadline(time.Time{})
 
-	inbound.User = s.user
+	inbound.User = request.User
+	sessionPolicy = s.policyManager.ForLevel(request.User.Level)
 
 	dest := request.Destination()
 	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
@@ -21,6 +21,11 @@ const (
 	PacketNonceSize       = 24
 	MinRequestHeaderSize  = 1 + 8
 	MinResponseHeaderSize = MinRequestHeaderSize + SaltSize
+	IdentityHeaderSize    = aes.BlockSize
+
+	// MaxTimeDifference is the maximum accepted skew, in seconds, between a
+	// header timestamp and the local clock.
+	MaxTimeDifference = 30
 )
 
 var _ Cipher = (*AEAD2022Cipher)(nil)
@@ -144,6 +149,37 @@ func deriveKey(secret, salt, outKey []byte) {
 	blake3.DeriveKey(outKey, "shadowsocks 2022 session subkey", sessionKey)
 }
 
+func deriveIdentityKey(secret, salt, outKey []byte) {
+	identityKey := make([]byte, len(secret)+len(salt))
+	copy(identityKey, secret)
+	copy(identityKey[len(secret):], salt)
+	blake3.DeriveKey(outKey, "shadowsocks 2022 identity subkey", identityKey)
+}
+
+// identityHash returns the value an identity header carries for the given key.
+func identityHash(key []byte) [IdentityHeaderSize]byte {
+	var hash [IdentityHeaderSize]byte
+	sum := blake3.Sum512(key)
+	copy(hash[:], sum[:IdentityHeaderSize])
+	return hash
+}
+
+// sealIdentityHeader writes into header the identity header announcing nextKey,
+// encrypted with a subkey of identityKey and salt.
+func sealIdentityHeader(header, identityKey, salt, nextKey []byte) {
+	subkey := make([]byte, len(identityKey))
+	deriveIdentityKey(identityKey, salt, subkey)
+	hash := identityHash(nextKey)
+	createAes(subkey).Encrypt(header, hash[:])
+}
+
+// openIdentityHeader decrypts header in place using a subkey of identityKey and salt.
+func openIdentityHeader(header, identityKey, salt []byte) {
+	subkey := make([]byte, len(identityKey))
+	deriveIdentityKey(identityKey, salt, subkey)
+	createAes(subkey).Decrypt(header, header)
+}
+
 type udpSession struct {
 	sessionId           uint64
 	packetId            uint64
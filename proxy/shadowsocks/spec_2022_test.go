@@ -0,0 +1,37 @@
+package shadowsocks
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	secret := make([]byte, 32)
+	salt := make([]byte, 32)
+	for i := range secret {
+		secret[i] = byte(i)
+		salt[i] = byte(32 + i)
+	}
+
+	cases := []struct {
+		keySize  int
+		expected string
+	}{
+		{
+			keySize:  16,
+			expected: "8180421f8f56092ca7544a64ff852536",
+		},
+		{
+			keySize:  32,
+			expected: "374fca03e4dae7f998fd7e59c1edfcc8e3197f4db1c19ca1671be3b66a92ddda",
+		},
+	}
+
+	for _, c := range cases {
+		subkey := make([]byte, c.keySize)
+		deriveKey(secret[:c.keySize], salt[:c.keySize], subkey)
+		if actual := hex.EncodeToString(subkey); actual != c.expected {
+			t.Error("key size ", c.keySize, ": expected ", c.expected, " but got ", actual)
+		}
+	}
+}
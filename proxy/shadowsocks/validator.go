@@ -0,0 +1,64 @@
+package shadowsocks
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+// Validator stores Shadowsocks 2022 users sharing one server key, indexed by
+// the identity hash of their keys.
+type Validator struct {
+	// email maps the lower-cased email of a user to the user, for Del.
+	email sync.Map
+	// users maps the identity hash of the key of a user to the user, which is
+	// looked up by the identity header of every session.
+	users sync.Map
+}
+
+// Add a Shadowsocks 2022 user. Its key must be unique, and its Email must be
+// empty or unique.
+func (v *Validator) Add(u *protocol.MemoryUser) error {
+	account, ok := u.Account.(*MemoryAccount)
+	if !ok || !account.Cipher.Family().IsSpec2022() {
+		return newError("user ", u.Email, " is not a Shadowsocks 2022 user")
+	}
+	hash := identityHash(account.Key)
+	if _, loaded := v.users.Load(hash); loaded {
+		return newError("user ", u.Email, " has a duplicate key")
+	}
+	if u.Email != "" {
+		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
+		if loaded {
+			return newError("User ", u.Email, " already exists.")
+		}
+	}
+	v.users.Store(hash, u)
+	return nil
+}
+
+// Del a Shadowsocks 2022 user by its non-empty Email.
+func (v *Validator) Del(e string) error {
+	if e == "" {
+		return newError("Email must not be empty.")
+	}
+	le := strings.ToLower(e)
+	u, _ := v.email.Load(le)
+	if u == nil {
+		return newError("User ", e, " not found.")
+	}
+	v.email.Delete(le)
+	v.users.Delete(identityHash(u.(*protocol.MemoryUser).Account.(*MemoryAccount).Key))
+	return nil
+}
+
+// Get a Shadowsocks 2022 user by the identity hash of its key, or nil if no
+// user has the key.
+func (v *Validator) Get(hash [IdentityHeaderSize]byte) *protocol.MemoryUser {
+	u, _ := v.users.Load(hash)
+	if u != nil {
+		return u.(*protocol.MemoryUser)
+	}
+	return nil
+}
@@ -295,9 +295,9 @@ func (w *PipeConnWrapper) Write(p []byte) (n int, err error) {
 		pLen := len(p)
 		for pLen > 0 {
 			buffer := buf.New()
-			if pLen > buf.Size {
-				_, err = buffer.Write(p[:buf.Size])
-				p = p[buf.Size:]
+			if size := int(buf.DefaultSize()); pLen > size {
+				_, err = buffer.Write(p[:size])
+				p = p[size:]
 			} else {
 				buffer.Write(p)
 			}
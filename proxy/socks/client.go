@@ -30,6 +30,8 @@ type Client struct {
 	version       Version
 	dns           dns.Client
 	uot           bool
+
+	forwardCredentials bool
 }
 
 // NewClient create a new Socks5 client based on the given config.
@@ -56,6 +58,7 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 		c.dns = v.GetFeature(dns.ClientType()).(dns.Client)
 	}
 	c.uot = config.UdpOverTcp
+	c.forwardCredentials = config.ForwardCredentials
 
 	return c, nil
 }
@@ -135,7 +138,7 @@ func (c *Client) ProcessConn(ctx context.Context, conn net.Conn, dialer internet
 	}
 
 	p := c.policyManager.ForLevel(0)
-	user := server.PickUser()
+	user := c.pickUser(ctx, server)
 	if user != nil {
 		request.User = user
 		p = c.policyManager.ForLevel(user.Level)
@@ -161,6 +164,32 @@ func (c *Client) ProcessConn(ctx context.Context, conn net.Conn, dialer internet
 	return bufio.CopyConn(ctx, conn, outboundConn)
 }
 
+// pickUser returns the user to authenticate to server with. If credentials
+// forwarding is enabled, the credentials the inbound authenticated the
+// downstream client with take precedence over the configured ones.
+func (c *Client) pickUser(ctx context.Context, server *protocol.ServerSpec) *protocol.MemoryUser {
+	user := server.PickUser()
+	if !c.forwardCredentials {
+		return user
+	}
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.User == nil {
+		return user
+	}
+	account, ok := inbound.User.Account.(*Account)
+	if !ok {
+		return user
+	}
+	forwarded := &protocol.MemoryUser{
+		Email:   inbound.User.Email,
+		Account: account,
+	}
+	if user != nil {
+		forwarded.Level = user.Level
+	}
+	return forwarded
+}
+
 // Process implements proxy.Outbound.Process.
 func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	outbound := session.OutboundFromContext(ctx)
@@ -238,7 +267,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		}
 	}
 
-	user := server.PickUser()
+	user := c.pickUser(ctx, server)
 	if user != nil {
 		request.User = user
 		p = c.policyManager.ForLevel(user.Level)
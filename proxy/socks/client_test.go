@@ -0,0 +1,96 @@
+package socks
+
+import (
+	"context"
+	gonet "net"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+)
+
+func TestClientForwardCredentials(t *testing.T) {
+	server := protocol.NewServerSpec(net.TCPDestination(net.LocalHostIP, 1080), protocol.AlwaysValid(), &protocol.MemoryUser{
+		Level:   1,
+		Account: &Account{Username: "static", Password: "static-password"},
+	})
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{
+		User: &protocol.MemoryUser{
+			Email:   "alice",
+			Account: &Account{Username: "alice", Password: "alice-password"},
+		},
+	})
+
+	cases := []struct {
+		client   *Client
+		ctx      context.Context
+		username string
+		password string
+	}{
+		{&Client{forwardCredentials: true}, ctx, "alice", "alice-password"},
+		{&Client{forwardCredentials: true}, context.Background(), "static", "static-password"},
+		{&Client{}, ctx, "static", "static-password"},
+	}
+
+	for _, c := range cases {
+		user := c.client.pickUser(c.ctx, server)
+		if user.Level != 1 {
+			t.Error("unexpected user level: ", user.Level)
+		}
+
+		upstream := &ServerSession{
+			config: &ServerConfig{
+				AuthType: AuthType_PASSWORD,
+				Accounts: map[string]string{
+					"alice":  "alice-password",
+					"static": "static-password",
+				},
+			},
+			address: net.LocalHostIP,
+			port:    1080,
+		}
+
+		// A pipe would deadlock, as both ends write before reading.
+		listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientConn, err := gonet.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverConn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.Close()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := ClientHandshake(&protocol.RequestHeader{
+				Version: socks5Version,
+				Command: protocol.RequestCommandTCP,
+				Address: net.DomainAddress("v2fly.org"),
+				Port:    443,
+				User:    user,
+			}, clientConn, clientConn)
+			clientConn.Close()
+			done <- err
+		}()
+
+		request, err := upstream.Handshake(serverConn, serverConn)
+		if err != nil {
+			t.Fatal("upstream handshake failed: ", err)
+		}
+		serverConn.Close()
+		if err := <-done; err != nil {
+			t.Error("client handshake failed: ", err)
+		}
+
+		account := request.User.Account.(*Account)
+		if account.Username != c.username || account.Password != c.password {
+			t.Error("expected ", c.username, ":", c.password, " but got ", account.Username, ":", account.Password)
+		}
+	}
+}
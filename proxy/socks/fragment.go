@@ -0,0 +1,124 @@
+package socks
+
+import (
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+const (
+	fragmentEnd      = 0x80
+	fragmentPosition = 0x7f
+
+	// DefaultFragmentTimeout is how long a partial datagram is kept. RFC 1928
+	// requires no less than 5 seconds.
+	DefaultFragmentTimeout = 5 * time.Second
+	// DefaultFragmentBufferSize is the largest datagram a FragmentQueue reassembles by default.
+	DefaultFragmentBufferSize = 65535
+	// MaxFragmentBufferSize is the largest buffer size of a FragmentQueue,
+	// enough for all 127 fragment positions of 65535 bytes each.
+	MaxFragmentBufferSize = fragmentPosition * 65535
+)
+
+// FragmentQueue reassembles fragmented SOCKS5 UDP datagrams of one association,
+// as described in RFC 1928 section 7. It is not safe for concurrent use.
+type FragmentQueue struct {
+	// Timeout is how long fragments of an incomplete datagram are kept.
+	Timeout time.Duration
+	// MaxSize is the maximum number of payload bytes buffered for one datagram.
+	MaxSize int32
+
+	request   *protocol.RequestHeader
+	fragments [fragmentPosition + 1]*buf.Buffer
+	last      byte
+	size      int32
+	deadline  time.Time
+}
+
+// NewFragmentQueue creates a FragmentQueue with the given buffer limit, or
+// DefaultFragmentBufferSize if maxSize is 0.
+func NewFragmentQueue(maxSize int32) *FragmentQueue {
+	if maxSize == 0 {
+		maxSize = DefaultFragmentBufferSize
+	}
+	return &FragmentQueue{
+		Timeout: DefaultFragmentTimeout,
+		MaxSize: maxSize,
+	}
+}
+
+// Add queues the payload of a fragment with the given FRAG field and header.
+// It returns the reassembled datagram once all of its fragments have
+// arrived, or nil if the datagram is still incomplete. The queue takes
+// ownership of payload.
+func (q *FragmentQueue) Add(frag byte, request *protocol.RequestHeader, payload *buf.Buffer) (*protocol.RequestHeader, *buf.Buffer, error) {
+	position := frag & fragmentPosition
+	if position == 0 {
+		payload.Release()
+		return nil, nil, newError("invalid fragment position 0")
+	}
+
+	now := time.Now()
+	if q.request != nil && (now.After(q.deadline) || q.request.Destination() != request.Destination()) {
+		newError("abandoning incomplete UDP datagram to ", q.request.Destination()).AtDebug().WriteToLog()
+		q.Reset()
+	}
+	if q.request == nil {
+		q.request = request
+		q.deadline = now.Add(q.Timeout)
+	}
+
+	if q.fragments[position] != nil || (q.last != 0 && position > q.last) {
+		payload.Release()
+		q.Reset()
+		return nil, nil, newError("inconsistent fragment ", position)
+	}
+	if q.size+payload.Len() > q.MaxSize {
+		payload.Release()
+		q.Reset()
+		return nil, nil, newError("fragmented datagram exceeds ", q.MaxSize, " bytes")
+	}
+	if frag&fragmentEnd != 0 {
+		for p := position + 1; p <= fragmentPosition; p++ {
+			if q.fragments[p] != nil {
+				payload.Release()
+				q.Reset()
+				return nil, nil, newError("fragment found after final fragment ", position)
+			}
+		}
+		q.last = position
+	}
+	q.fragments[position] = payload
+	q.size += payload.Len()
+
+	if q.last == 0 {
+		return nil, nil, nil
+	}
+	for p := byte(1); p <= q.last; p++ {
+		if q.fragments[p] == nil {
+			return nil, nil, nil
+		}
+	}
+
+	datagram := buf.NewSize(q.size)
+	for p := byte(1); p <= q.last; p++ {
+		datagram.Write(q.fragments[p].Bytes())
+	}
+	request = q.request
+	q.Reset()
+	return request, datagram, nil
+}
+
+// Reset drops any queued fragments.
+func (q *FragmentQueue) Reset() {
+	for i, b := range q.fragments {
+		if b != nil {
+			b.Release()
+			q.fragments[i] = nil
+		}
+	}
+	q.request = nil
+	q.last = 0
+	q.size = 0
+}
@@ -0,0 +1,139 @@
+package socks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	. "github.com/v2fly/v2ray-core/v5/proxy/socks"
+)
+
+var fragmentDest = &protocol.RequestHeader{
+	Address: net.LocalHostIP,
+	Port:    53,
+}
+
+func addFragment(t *testing.T, queue *FragmentQueue, frag byte, data string) (*protocol.RequestHeader, *buf.Buffer, error) {
+	packet, err := EncodeUDPPacket(fragmentDest, []byte(data))
+	common.Must(err)
+	packet.Bytes()[2] = frag
+
+	request, decodedFrag, err := DecodeUDPFragment(packet)
+	common.Must(err)
+	if decodedFrag != frag {
+		t.Fatal("unexpected FRAG field: ", decodedFrag)
+	}
+	return queue.Add(frag, request, packet)
+}
+
+func TestFragmentQueueInOrder(t *testing.T) {
+	queue := NewFragmentQueue(0)
+
+	for i, data := range []string{"first ", "second "} {
+		_, datagram, err := addFragment(t, queue, byte(i+1), data)
+		common.Must(err)
+		if datagram != nil {
+			t.Fatal("datagram completed early")
+		}
+	}
+	request, datagram, err := addFragment(t, queue, 3|0x80, "third")
+	common.Must(err)
+	if datagram == nil {
+		t.Fatal("datagram not completed")
+	}
+	defer datagram.Release()
+	if datagram.String() != "first second third" {
+		t.Error("unexpected datagram: ", datagram.String())
+	}
+	if request.Destination() != net.UDPDestination(net.LocalHostIP, 53) {
+		t.Error("unexpected destination: ", request.Destination())
+	}
+}
+
+func TestFragmentQueueOutOfOrder(t *testing.T) {
+	queue := NewFragmentQueue(0)
+
+	for _, f := range []struct {
+		frag byte
+		data string
+	}{
+		{3 | 0x80, "c"},
+		{1, "a"},
+	} {
+		_, datagram, err := addFragment(t, queue, f.frag, f.data)
+		common.Must(err)
+		if datagram != nil {
+			t.Fatal("datagram completed early")
+		}
+	}
+	_, datagram, err := addFragment(t, queue, 2, "b")
+	common.Must(err)
+	if datagram == nil {
+		t.Fatal("datagram not completed")
+	}
+	defer datagram.Release()
+	if datagram.String() != "abc" {
+		t.Error("unexpected datagram: ", datagram.String())
+	}
+}
+
+func TestFragmentQueueTimeout(t *testing.T) {
+	queue := NewFragmentQueue(0)
+	queue.Timeout = 10 * time.Millisecond
+
+	_, _, err := addFragment(t, queue, 1, "stale")
+	common.Must(err)
+	time.Sleep(20 * time.Millisecond)
+
+	_, datagram, err := addFragment(t, queue, 2|0x80, "fresh")
+	common.Must(err)
+	if datagram != nil {
+		t.Error("expected expired fragments to be dropped, got ", datagram.String())
+	}
+
+	_, datagram, err = addFragment(t, queue, 1, "renewed ")
+	common.Must(err)
+	if datagram == nil {
+		t.Fatal("datagram not completed")
+	}
+	defer datagram.Release()
+	if datagram.String() != "renewed fresh" {
+		t.Error("unexpected datagram: ", datagram.String())
+	}
+}
+
+func TestFragmentQueueBufferLimit(t *testing.T) {
+	queue := NewFragmentQueue(8)
+
+	_, _, err := addFragment(t, queue, 1, "12345")
+	common.Must(err)
+	if _, _, err := addFragment(t, queue, 2|0x80, "67890"); err == nil {
+		t.Error("expected datagram over the buffer limit to be rejected")
+	}
+	if _, _, err := addFragment(t, queue, 1|0x80, "123456789"); err == nil {
+		t.Error("expected fragment over the buffer limit to be rejected")
+	}
+
+	_, datagram, err := addFragment(t, queue, 1|0x80, "1234")
+	common.Must(err)
+	if datagram == nil || datagram.String() != "1234" {
+		t.Error("expected queue to recover after rejection")
+	}
+	if datagram != nil {
+		datagram.Release()
+	}
+}
+
+func TestDecodeUDPPacketRejectsFragment(t *testing.T) {
+	packet, err := EncodeUDPPacket(fragmentDest, []byte("data"))
+	common.Must(err)
+	defer packet.Release()
+	packet.Bytes()[2] = 1
+
+	if _, err := DecodeUDPPacket(packet); err == nil {
+		t.Error("expected fragment to be rejected")
+	}
+}
@@ -0,0 +1,89 @@
+package socks
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+func TestSocks4Handshake(t *testing.T) {
+	granted := []byte{0x00, socks4RequestGranted, 0, 0, 0, 0, 0, 0}
+	rejected := []byte{0x00, socks4RequestRejected, 0, 0, 0, 0, 0, 0}
+
+	cases := []struct {
+		name     string
+		request  []byte
+		reply    []byte
+		address  net.Address
+		port     net.Port
+		userID   string
+		rejected bool
+	}{
+		{
+			name:    "socks4 connect",
+			request: []byte{socks4Version, cmdTCPConnect, 0x01, 0xBB, 1, 2, 3, 4, 'u', 's', 'e', 'r', 0x00},
+			reply:   granted,
+			address: net.IPAddress([]byte{1, 2, 3, 4}),
+			port:    443,
+			userID:  "user",
+		},
+		{
+			name:    "socks4a connect",
+			request: append([]byte{socks4Version, cmdTCPConnect, 0x00, 0x50, 0, 0, 0, 1, 0x00}, []byte("www.v2fly.org\x00")...),
+			reply:   granted,
+			address: net.DomainAddress("www.v2fly.org"),
+			port:    80,
+		},
+		{
+			name:     "socks4 bind",
+			request:  []byte{socks4Version, cmdTCPBind, 0x00, 0x50, 1, 2, 3, 4, 0x00},
+			reply:    rejected,
+			rejected: true,
+		},
+		{
+			name:     "socks4 zero address",
+			request:  []byte{socks4Version, cmdTCPConnect, 0x00, 0x50, 0, 0, 0, 0, 0x00},
+			reply:    rejected,
+			rejected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			session := &ServerSession{
+				config:  &ServerConfig{AuthType: AuthType_NO_AUTH},
+				address: net.LocalHostIP,
+				port:    1080,
+			}
+			reply := bytes.NewBuffer(nil)
+			request, err := session.Handshake(bytes.NewReader(c.request), reply)
+			if !bytes.Equal(reply.Bytes(), c.reply) {
+				t.Errorf("expected reply %v, but got %v", c.reply, reply.Bytes())
+			}
+			if c.rejected {
+				if err == nil {
+					t.Error("expected request to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if request.Version != socks4Version || request.Command != protocol.RequestCommandTCP {
+				t.Error("unexpected request: ", request)
+			}
+			if request.Address != c.address || request.Port != c.port {
+				t.Error("expected destination ", c.address, ":", c.port, ", but got ", request.Destination())
+			}
+			// The user id is not authenticated, so it doesn't make a user.
+			if request.User != nil {
+				t.Error("unexpected user: ", request.User)
+			}
+			if session.userID != c.userID {
+				t.Error("expected user id ", c.userID, ", but got ", session.userID)
+			}
+		})
+	}
+}
@@ -44,6 +44,9 @@ type ServerSession struct {
 	address       net.Address
 	port          net.Port
 	clientAddress net.Address
+	// userID is the user id of a SOCKS4 request. It is chosen by the client
+	// and not authenticated, so it is not taken as the user.
+	userID string
 }
 
 func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
@@ -66,14 +69,24 @@ func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer)
 		buffer.Release()
 	}
 
-	if _, err := ReadUntilNull(reader); /* user id */ err != nil {
-		return nil, err
+	userID, err := ReadUntilNull(reader)
+	if err != nil {
+		return nil, newError("failed to read user id").Base(err)
 	}
-	if address.IP()[0] == 0x00 {
+	// Socks 4a sends the domain after the user id, with the IP of 0.0.0.x where x is not zero.
+	if ip := address.IP(); ip[0] == 0x00 && ip[1] == 0x00 && ip[2] == 0x00 {
+		if ip[3] == 0x00 {
+			writeSocks4Response(writer, socks4RequestRejected, net.AnyIP, net.Port(0))
+			return nil, newError("invalid destination: ", address)
+		}
 		domain, err := ReadUntilNull(reader)
 		if err != nil {
 			return nil, newError("failed to read domain for socks 4a").Base(err)
 		}
+		if domain == "" {
+			writeSocks4Response(writer, socks4RequestRejected, net.AnyIP, net.Port(0))
+			return nil, newError("empty domain for socks 4a")
+		}
 		address = net.DomainAddress(domain)
 	}
 
@@ -85,22 +98,26 @@ func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer)
 			Port:    port,
 			Version: socks4Version,
 		}
+		s.userID = userID
 		if err := writeSocks4Response(writer, socks4RequestGranted, net.AnyIP, net.Port(0)); err != nil {
 			return nil, err
 		}
 		return request, nil
+	case cmdTCPBind:
+		writeSocks4Response(writer, socks4RequestRejected, net.AnyIP, net.Port(0))
+		return nil, newError("TCP bind is not supported.")
 	default:
 		writeSocks4Response(writer, socks4RequestRejected, net.AnyIP, net.Port(0))
 		return nil, newError("unsupported command: ", cmd)
 	}
 }
 
-func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer) (username string, err error) {
+func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer) (account *Account, err error) {
 	buffer := buf.StackNew()
 	defer buffer.Release()
 
 	if _, err = buffer.ReadFullFrom(reader, int32(nMethod)); err != nil {
-		return "", newError("failed to read auth methods").Base(err)
+		return nil, newError("failed to read auth methods").Base(err)
 	}
 
 	var expectedAuth byte = authNotRequired
@@ -110,39 +127,36 @@ func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer)
 
 	if !hasAuthMethod(expectedAuth, buffer.BytesRange(0, int32(nMethod))) {
 		writeSocks5AuthenticationResponse(writer, socks5Version, authNoMatchingMethod)
-		return "", newError("no matching auth method")
+		return nil, newError("no matching auth method")
 	}
 
 	if err := writeSocks5AuthenticationResponse(writer, socks5Version, expectedAuth); err != nil {
-		return "", newError("failed to write auth response").Base(err)
+		return nil, newError("failed to write auth response").Base(err)
 	}
 
 	if expectedAuth == authPassword {
 		username, password, err := ReadUsernamePassword(reader)
 		if err != nil {
-			return "", newError("failed to read username and password for authentication").Base(err)
+			return nil, newError("failed to read username and password for authentication").Base(err)
 		}
 
 		if !s.config.HasAccount(username, password) {
 			writeSocks5AuthenticationResponse(writer, 0x01, 0xFF)
-			return "", newError("invalid username or password")
+			return nil, newError("invalid username or password")
 		}
 
 		if err := writeSocks5AuthenticationResponse(writer, 0x01, 0x00); err != nil {
-			return "", newError("failed to write auth response").Base(err)
+			return nil, newError("failed to write auth response").Base(err)
 		}
-		return username, nil
+		return &Account{Username: username, Password: password}, nil
 	}
 
-	return "", nil
+	return nil, nil
 }
 
 func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
-	var (
-		username string
-		err      error
-	)
-	if username, err = s.auth5(nMethod, reader, writer); err != nil {
+	account, err := s.auth5(nMethod, reader, writer)
+	if err != nil {
 		return nil, err
 	}
 
@@ -158,8 +172,8 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 	}
 
 	request := new(protocol.RequestHeader)
-	if username != "" {
-		request.User = &protocol.MemoryUser{Email: username}
+	if account != nil {
+		request.User = &protocol.MemoryUser{Email: account.Username, Account: account}
 	}
 	switch cmd {
 	case cmdTCPConnect, cmdTorResolve, cmdTorResolvePTR:
@@ -328,25 +342,39 @@ func DecodeUDPPacket(packet *buf.Buffer) (*protocol.RequestHeader, error) {
 	if packet.Len() < 5 {
 		return nil, newError("insufficient length of packet.")
 	}
+
+	// packet[0] and packet[1] are reserved
+	if packet.Byte(2) != 0 /* fragments */ {
+		return nil, newError("discarding fragmented payload.")
+	}
+
+	request, _, err := DecodeUDPFragment(packet)
+	return request, err
+}
+
+// DecodeUDPFragment decodes the header of a UDP packet that may be a fragment,
+// and returns its FRAG field. The header is removed from packet.
+func DecodeUDPFragment(packet *buf.Buffer) (*protocol.RequestHeader, byte, error) {
+	if packet.Len() < 5 {
+		return nil, 0, newError("insufficient length of packet.")
+	}
 	request := &protocol.RequestHeader{
 		Version: socks5Version,
 		Command: protocol.RequestCommandUDP,
 	}
 
 	// packet[0] and packet[1] are reserved
-	if packet.Byte(2) != 0 /* fragments */ {
-		return nil, newError("discarding fragmented payload.")
-	}
+	frag := packet.Byte(2)
 
 	packet.Advance(3)
 
 	addr, port, err := AddrParser.ReadAddressPort(nil, packet)
 	if err != nil {
-		return nil, newError("failed to read UDP header").Base(err)
+		return nil, 0, newError("failed to read UDP header").Base(err)
 	}
 	request.Address = addr
 	request.Port = port
-	return request, nil
+	return request, frag, nil
 }
 
 func EncodeUDPPacket(request *protocol.RequestHeader, data []byte) (*buf.Buffer, error) {
@@ -32,6 +32,9 @@ type Server struct {
 
 // NewServer creates a new Server object.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
+	if config.UdpFragmentBufferSize > MaxFragmentBufferSize {
+		return nil, newError("UDP fragment buffer size is larger than ", MaxFragmentBufferSize, " bytes")
+	}
 	v := core.MustFromContext(ctx)
 	s := &Server{
 		config:        config,
@@ -111,6 +114,15 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 	}
 	if request.User != nil {
 		inbound.User.Email = request.User.Email
+		inbound.User.Account = request.User.Account
+	}
+	if svrSession.userID != "" {
+		content := session.ContentFromContext(ctx)
+		if content == nil {
+			content = new(session.Content)
+			ctx = session.ContextWithContent(ctx, content)
+		}
+		content.SetAttribute("socks4-userid", svrSession.userID)
 	}
 
 	if err := conn.SetReadDeadline(time.Time{}); err != nil {
@@ -258,6 +270,9 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 		newError("client UDP connection from ", inbound.Source).WriteToLog(session.ExportIDToError(ctx))
 	}
 
+	fragments := NewFragmentQueue(int32(s.config.UdpFragmentBufferSize))
+	defer fragments.Reset()
+
 	reader := buf.NewPacketReader(conn)
 	for {
 		mpayload, err := reader.ReadMultiBuffer()
@@ -266,13 +281,27 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 		}
 
 		for _, payload := range mpayload {
-			request, err := DecodeUDPPacket(payload)
+			request, frag, err := DecodeUDPFragment(payload)
 			if err != nil {
 				newError("failed to parse UDP request").Base(err).WriteToLog(session.ExportIDToError(ctx))
 				payload.Release()
 				continue
 			}
 
+			if frag == 0 {
+				// A standalone datagram abandons any pending reassembly.
+				fragments.Reset()
+			} else {
+				request, payload, err = fragments.Add(frag, request, payload)
+				if err != nil {
+					newError("failed to reassemble UDP request").Base(err).WriteToLog(session.ExportIDToError(ctx))
+					continue
+				}
+				if payload == nil {
+					continue
+				}
+			}
+
 			if payload.IsEmpty() {
 				payload.Release()
 				continue
@@ -188,7 +188,7 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		var reader buf.Reader
 		if network == net.Network_UDP {
 			reader = &PacketReader{
-				Reader: conn,
+				Reader: &buf.BufferedReader{Reader: buf.NewReader(conn)},
 			}
 		} else {
 			reader = buf.NewReader(conn)
@@ -140,6 +140,7 @@ func (w *PacketWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 			return err
 		}
 	}
+	buf.ReleaseMulti(mb)
 	return nil
 }
 
@@ -151,14 +152,21 @@ func (w *PacketWriter) WriteMultiBufferWithMetadata(mb buf.MultiBuffer, dest net
 			return err
 		}
 	}
+	buf.ReleaseMulti(mb)
 	return nil
 }
 
 func (w *PacketWriter) writePacket(payload []byte, dest net.Destination) (int, error) { // nolint: unparam
+	length := len(payload)
+	if length > maxLength {
+		// The peer would reject the whole connection, so drop only this packet.
+		newError("dropping oversize UDP packet to ", dest, ": ", length, " bytes").AtWarning().WriteToLog()
+		return 0, nil
+	}
+
 	buffer := buf.StackNew()
 	defer buffer.Release()
 
-	length := len(payload)
 	lengthBuf := [2]byte{}
 	binary.BigEndian.PutUint16(lengthBuf[:], uint16(length))
 	if err := addrParser.WriteAddressPort(&buffer, dest.Address, dest.Port); err != nil {
@@ -292,7 +300,7 @@ func (r *PacketReader) ReadMultiBufferWithMetadata() (*PacketPayload, error) {
 	dest := net.UDPDestination(addr, port)
 	var mb buf.MultiBuffer
 	for remain > 0 {
-		length := buf.Size
+		length := int(buf.DefaultSize())
 		if remain < length {
 			length = remain
 		}
@@ -1,7 +1,10 @@
 package trojan_test
 
 import (
+	"bytes"
+	"io"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/v2fly/v2ray-core/v5/common"
@@ -89,3 +92,83 @@ func TestUDPRequest(t *testing.T) {
 		t.Error("data: ", r)
 	}
 }
+
+func TestUDPPacketStream(t *testing.T) {
+	packets := []struct {
+		dest    net.Destination
+		payload string
+	}{
+		{net.UDPDestination(net.LocalHostIP, 53), "first"},
+		{net.UDPDestination(net.LocalHostIPv6, 443), "second"},
+		{net.UDPDestination(net.DomainAddress("v2fly.org"), 123), "third"},
+	}
+
+	stream := bytes.NewBuffer(nil)
+	writer := &PacketWriter{Writer: stream}
+	for _, p := range packets {
+		b := buf.New()
+		common.Must2(b.WriteString(p.payload))
+		common.Must(writer.WriteMultiBufferWithMetadata(buf.MultiBuffer{b}, p.dest))
+	}
+	data := stream.Bytes()
+
+	cases := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{"concatenated", bytes.NewReader(data)},
+		{"split", iotest.OneByteReader(bytes.NewReader(data))},
+		{"buffered split", &buf.BufferedReader{Reader: buf.NewReader(iotest.HalfReader(bytes.NewReader(data)))}},
+	}
+	for _, c := range cases {
+		reader := &PacketReader{Reader: c.reader}
+		for _, p := range packets {
+			payload, err := reader.ReadMultiBufferWithMetadata()
+			if err != nil {
+				t.Fatal(c.name, ": ", err)
+			}
+			if r := cmp.Diff(payload.Target, p.dest); r != "" {
+				t.Error(c.name, ": destination: ", r)
+			}
+			if payload.Buffer.String() != p.payload {
+				t.Error(c.name, ": unexpected payload: ", payload.Buffer.String())
+			}
+			if payload.Buffer[0].Endpoint == nil || *payload.Buffer[0].Endpoint != p.dest {
+				t.Error(c.name, ": endpoint not set on buffer")
+			}
+			buf.ReleaseMulti(payload.Buffer)
+		}
+		if _, err := reader.ReadMultiBuffer(); err == nil {
+			t.Error(c.name, ": expected error at end of stream")
+		}
+	}
+
+	reader := &PacketReader{Reader: bytes.NewReader(data[:len(data)-1])}
+	for range packets[:len(packets)-1] {
+		payload, err := reader.ReadMultiBufferWithMetadata()
+		common.Must(err)
+		buf.ReleaseMulti(payload.Buffer)
+	}
+	if _, err := reader.ReadMultiBufferWithMetadata(); err == nil {
+		t.Error("expected error on truncated packet")
+	}
+}
+
+func TestUDPOversizePacketDropped(t *testing.T) {
+	stream := bytes.NewBuffer(nil)
+	writer := &PacketWriter{Writer: stream, Target: net.UDPDestination(net.LocalHostIP, 53)}
+
+	large := buf.New()
+	large.Extend(8193)
+	small := buf.New()
+	common.Must2(small.WriteString("small"))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{large, small}))
+
+	reader := &PacketReader{Reader: stream}
+	mb, err := reader.ReadMultiBuffer()
+	common.Must(err)
+	if mb.String() != "small" {
+		t.Error("unexpected payload: ", mb.String())
+	}
+	buf.ReleaseMulti(mb)
+}
@@ -153,11 +153,14 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 		return newError("unable to set read deadline").Base(err).AtWarning()
 	}
 
+	apfb := s.fallbacks
+	isfb := apfb != nil
+
 	first := buf.New()
 	defer first.Release()
 
 	firstLen, err := first.ReadFrom(conn)
-	if err != nil {
+	if err != nil && !(isfb && isTimeout(err)) {
 		return newError("failed to read first request").Base(err)
 	}
 	newError("firstLen = ", firstLen).AtInfo().WriteToLog(sid)
@@ -169,11 +172,13 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 
 	var user *protocol.MemoryUser
 
-	apfb := s.fallbacks
-	isfb := apfb != nil
-
 	shouldFallback := false
-	if firstLen < 58 || first.Byte(56) != '\r' {
+	if err != nil {
+		// Clients sending nothing in time are left to the fallback, which
+		// times out them as it would without Trojan in front of it.
+		err = newError("no request in time").Base(err)
+		shouldFallback = true
+	} else if firstLen < 58 || first.Byte(56) != '\r' {
 		// invalid protocol
 		err = newError("not trojan protocol")
 		log.Record(&log.AccessMessage{
@@ -288,8 +293,6 @@ func (s *Server) handleUDPPayload(ctx context.Context, clientReader *PacketReade
 	inbound := session.InboundFromContext(ctx)
 	user := inbound.User
 
-	var dest *net.Destination
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -321,13 +324,11 @@ func (s *Server) handleUDPPayload(ctx context.Context, clientReader *PacketReade
 			}
 			newError("tunnelling request to ", destination).WriteToLog(session.ExportIDToError(ctx))
 
-			if dest == nil {
-				dest = &destination
-			}
-
-			udpServer.Dispatch(currentPacketCtx, *dest, b) // first packet
+			// Every packet carries its own destination, as trojan-go clients
+			// may address several peers over one association.
+			udpServer.Dispatch(currentPacketCtx, destination, b)
 			for _, payload := range mb2 {
-				udpServer.Dispatch(currentPacketCtx, *dest, payload)
+				udpServer.Dispatch(currentPacketCtx, *payload.Endpoint, payload)
 			}
 		}
 	}
@@ -571,3 +572,8 @@ func (s *Server) fallback(ctx context.Context, sid errors.ExportOption, err erro
 
 	return nil
 }
+
+func isTimeout(err error) bool {
+	nerr, ok := errors.Cause(err).(net.Error)
+	return ok && nerr.Timeout()
+}
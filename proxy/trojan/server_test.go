@@ -0,0 +1,121 @@
+package trojan
+
+import (
+	"context"
+	"io"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+)
+
+type shortHandshakePolicy struct {
+	policy.DefaultManager
+}
+
+func (m shortHandshakePolicy) ForLevel(level uint32) policy.Session {
+	p := m.DefaultManager.ForLevel(level)
+	p.Timeouts.Handshake = 200 * time.Millisecond
+	return p
+}
+
+const decoyPrefix = "decoy: "
+
+// startDecoy starts a backend that reads requests of size bytes, and sends
+// them back after decoyPrefix.
+func startDecoy(size int) gonet.Listener {
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				received := make([]byte, size)
+				if _, err := io.ReadFull(conn, received); err != nil {
+					return
+				}
+				conn.Write(append([]byte(decoyPrefix), received...))
+			}()
+		}
+	}()
+	return listener
+}
+
+func newFallbackServer(dest string) *Server {
+	validator := new(Validator)
+	account, err := (&Account{Password: "password"}).AsAccount()
+	common.Must(err)
+	common.Must(validator.Add(&protocol.MemoryUser{Email: "love@v2fly.org", Account: account}))
+
+	return &Server{
+		policyManager: shortHandshakePolicy{},
+		validator:     validator,
+		fallbacks: map[string]map[string]map[string]*Fallback{
+			"": {"": {"": {Type: "tcp", Dest: dest}}},
+		},
+	}
+}
+
+// probe sends request to the server after delay, and returns the response.
+func probe(t *testing.T, server *Server, request []byte, delay time.Duration) []byte {
+	conn, peer := gonet.Pipe()
+	defer peer.Close()
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{})
+	go func() {
+		server.Process(ctx, net.Network_TCP, conn, nil)
+		conn.Close()
+	}()
+
+	time.Sleep(delay)
+	common.Must(peer.SetDeadline(time.Now().Add(5 * time.Second)))
+	common.Must2(peer.Write(request))
+	response := make([]byte, len(decoyPrefix)+len(request))
+	if _, err := io.ReadFull(peer, response); err != nil {
+		t.Fatal("failed to read response: ", err)
+	}
+	return response
+}
+
+func TestFallbackToDecoy(t *testing.T) {
+	cases := []struct {
+		name    string
+		request []byte
+		delay   time.Duration
+	}{
+		{
+			name:    "bad password",
+			request: append(hexSha224("wrong password"), []byte("\r\n\x01\x01\x7f\x00\x00\x01\x00\x50\r\nGET / HTTP/1.1\r\n\r\n")...),
+		},
+		{
+			name:    "not trojan",
+			request: []byte("GET / HTTP/1.1\r\nHost: www.v2fly.org\r\n\r\n"),
+		},
+		{
+			// Longer than the handshake timeout of shortHandshakePolicy.
+			name:    "silent client",
+			request: []byte("GET / HTTP/1.1\r\n\r\n"),
+			delay:   500 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			decoy := startDecoy(len(c.request))
+			defer decoy.Close()
+
+			response := probe(t, newFallbackServer(decoy.Addr().String()), c.request, c.delay)
+			if expected := decoyPrefix + string(c.request); string(response) != expected {
+				t.Errorf("expected the decoy to receive the original bytes %q, but got %q", expected, response)
+			}
+		})
+	}
+}
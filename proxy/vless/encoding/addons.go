@@ -97,7 +97,7 @@ func (w *MultiLengthPacketWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
 		if length == 0 || length+2 > buf.Size {
 			continue
 		}
-		eb := buf.New()
+		eb := buf.NewSize(length + 2)
 		if err := eb.WriteByte(byte(length >> 8)); err != nil {
 			eb.Release()
 			continue
@@ -169,11 +169,11 @@ func (r *LengthPacketReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
 	}
 	length := int32(r.cache[0])<<8 | int32(r.cache[1])
 	// fmt.Println("Read", length)
-	mb := make(buf.MultiBuffer, 0, length/buf.Size+1)
+	mb := make(buf.MultiBuffer, 0, length/buf.DefaultSize()+1)
 	for length > 0 {
 		size := length
-		if size > buf.Size {
-			size = buf.Size
+		if size > buf.DefaultSize() {
+			size = buf.DefaultSize()
 		}
 		length -= size
 		b := buf.New()
@@ -73,7 +73,8 @@ type Handler struct {
 	policyManager         policy.Manager
 	validator             *vless.Validator
 	dns                   dns.Client
-	fallbacks             map[string]map[string]map[string]*Fallback // or nil
+	fallbacks             map[string]map[string]map[string][]*Fallback // or nil
+	requireSecure         bool
 	// regexps               map[string]*regexp.Regexp       // or nil
 }
 
@@ -85,6 +86,7 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
 		validator:             new(vless.Validator),
 		dns:                   dc,
+		requireSecure:         config.RequireSecureTransport,
 	}
 
 	for _, user := range config.Clients {
@@ -98,16 +100,17 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 	}
 
 	if config.Fallbacks != nil {
-		handler.fallbacks = make(map[string]map[string]map[string]*Fallback)
+		handler.fallbacks = make(map[string]map[string]map[string][]*Fallback)
 		// handler.regexps = make(map[string]*regexp.Regexp)
 		for _, fb := range config.Fallbacks {
 			if handler.fallbacks[fb.Name] == nil {
-				handler.fallbacks[fb.Name] = make(map[string]map[string]*Fallback)
+				handler.fallbacks[fb.Name] = make(map[string]map[string][]*Fallback)
 			}
 			if handler.fallbacks[fb.Name][fb.Alpn] == nil {
-				handler.fallbacks[fb.Name][fb.Alpn] = make(map[string]*Fallback)
+				handler.fallbacks[fb.Name][fb.Alpn] = make(map[string][]*Fallback)
 			}
-			handler.fallbacks[fb.Name][fb.Alpn][fb.Path] = fb
+			// fallbacks with the same name, alpn and path are tried in order
+			handler.fallbacks[fb.Name][fb.Alpn][fb.Path] = append(handler.fallbacks[fb.Name][fb.Alpn][fb.Path], fb)
 			/*
 				if fb.Path != "" {
 					if r, err := regexp.Compile(fb.Path); err != nil {
@@ -123,7 +126,7 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 				if name != "" {
 					for alpn := range handler.fallbacks[""] {
 						if apfb[alpn] == nil {
-							apfb[alpn] = make(map[string]*Fallback)
+							apfb[alpn] = make(map[string][]*Fallback)
 						}
 					}
 				}
@@ -175,6 +178,11 @@ func (h *Handler) RemoveUser(ctx context.Context, e string) error {
 	return h.validator.Del(e)
 }
 
+// CheckStreamSettings implements proxy.StreamSettingsChecker.
+func (h *Handler) CheckStreamSettings(settings *internet.MemoryStreamConfig) error {
+	return vless.CheckStreamSecurity(settings, h.requireSecure)
+}
+
 // Network implements proxy.Inbound.Network().
 func (*Handler) Network() []net.Network {
 	return []net.Network{net.Network_TCP, net.Network_UNIX}
@@ -316,8 +324,8 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 					}
 				}
 			}
-			fb := pfb[path]
-			if fb == nil {
+			fbs := pfb[path]
+			if len(fbs) == 0 {
 				return newError(`failed to find the default "path" config`).AtWarning()
 			}
 
@@ -325,16 +333,9 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 			timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
 			ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
 
-			var conn net.Conn
-			if err := retry.ExponentialBackoff(5, 100).On(func() error {
-				var dialer net.Dialer
-				conn, err = dialer.DialContext(ctx, fb.Type, fb.Dest)
-				if err != nil {
-					return err
-				}
-				return nil
-			}); err != nil {
-				return newError("failed to dial to " + fb.Dest).Base(err).AtWarning()
+			conn, fb, err := dialFallback(ctx, fbs)
+			if err != nil {
+				return err
 			}
 			defer conn.Close()
 
@@ -578,3 +579,27 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 
 	return nil
 }
+
+// dialFallback dials the given fallbacks in order, and returns the connection
+// to the first one reachable.
+func dialFallback(ctx context.Context, fbs []*Fallback) (net.Conn, *Fallback, error) {
+	var errs []error
+	for _, fb := range fbs {
+		var conn net.Conn
+		err := retry.ExponentialBackoff(5, 100).On(func() error {
+			var dialer net.Dialer
+			rawConn, err := dialer.DialContext(ctx, fb.Type, fb.Dest)
+			if err != nil {
+				return err
+			}
+			conn = rawConn
+			return nil
+		})
+		if err == nil {
+			return conn, fb, nil
+		}
+		newError("failed to dial to fallback " + fb.Dest).Base(err).AtInfo().WriteToLog(session.ExportIDToError(ctx))
+		errs = append(errs, err)
+	}
+	return nil, nil, newError("failed to dial to " + fbs[len(fbs)-1].Dest).Base(errors.Combine(errs...)).AtWarning()
+}
@@ -0,0 +1,93 @@
+package inbound
+
+import (
+	"context"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/log"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+)
+
+func TestDialFallbackChain(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	unreachable := tcp.PickPort()
+
+	fbs := []*Fallback{
+		{Type: "tcp", Dest: "127.0.0.1:" + unreachable.String()},
+		{Type: "tcp", Dest: dest.NetAddr()},
+	}
+	conn, fb, err := dialFallback(context.Background(), fbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if fb != fbs[1] {
+		t.Error("expect the second fallback to be used, but got ", fb.Dest)
+	}
+
+	if _, _, err := dialFallback(context.Background(), fbs[:1]); err == nil {
+		t.Error("expect error when no fallback is reachable")
+	}
+}
+
+type recordingHandler struct {
+	messages []*log.GeneralMessage
+}
+
+func (h *recordingHandler) Handle(msg log.Message) {
+	if msg, ok := msg.(*log.GeneralMessage); ok {
+		h.messages = append(h.messages, msg)
+	}
+}
+
+func TestCheckStreamSettings(t *testing.T) {
+	logs := &recordingHandler{}
+	log.RegisterHandler(logs)
+
+	insecure := []*internet.MemoryStreamConfig{
+		nil,
+		{ProtocolName: "tcp"},
+		{ProtocolName: "websocket"},
+	}
+	secure := []*internet.MemoryStreamConfig{
+		{ProtocolName: "tcp", SecurityType: "v2ray.core.transport.internet.tls.Config", SecuritySettings: &tls.Config{}},
+		{ProtocolName: "quic"},
+	}
+
+	h := &Handler{}
+	for _, settings := range insecure {
+		logs.messages = nil
+		if err := h.CheckStreamSettings(settings); err != nil {
+			t.Error("expect warning only, but got ", err)
+		}
+		if len(logs.messages) != 1 || logs.messages[0].Severity != log.Severity_Warning {
+			t.Error("expect a warning for ", settings, ", but got ", logs.messages)
+		}
+	}
+
+	strict := &Handler{requireSecure: true}
+	for _, settings := range insecure {
+		if err := strict.CheckStreamSettings(settings); err == nil {
+			t.Error("expect error for ", settings)
+		}
+	}
+
+	logs.messages = nil
+	for _, settings := range secure {
+		if err := strict.CheckStreamSettings(settings); err != nil {
+			t.Error("unexpected error for ", settings, ": ", err)
+		}
+	}
+	if len(logs.messages) != 0 {
+		t.Error("unexpected warnings: ", logs.messages)
+	}
+}
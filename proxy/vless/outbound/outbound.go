@@ -67,6 +67,7 @@ type Handler struct {
 	serverPicker   protocol.ServerPicker
 	policyManager  policy.Manager
 	packetEncoding packetaddr.PacketAddrType
+	requireSecure  bool
 }
 
 // New creates a new VLess outbound handler.
@@ -86,11 +87,17 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		serverPicker:   protocol.NewRoundRobinServerPicker(serverList),
 		policyManager:  v.GetFeature(policy.ManagerType()).(policy.Manager),
 		packetEncoding: config.PacketEncoding,
+		requireSecure:  config.RequireSecureTransport,
 	}
 
 	return handler, nil
 }
 
+// CheckStreamSettings implements proxy.StreamSettingsChecker.
+func (h *Handler) CheckStreamSettings(settings *internet.MemoryStreamConfig) error {
+	return vless.CheckStreamSecurity(settings, h.requireSecure)
+}
+
 // Process implements proxy.Outbound.Process().
 func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
 	var rec *protocol.ServerSpec
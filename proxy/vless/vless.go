@@ -5,6 +5,10 @@
 // clients with 'socks' for proxying.
 package vless
 
+import (
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
 //go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
 
 const (
@@ -12,3 +16,34 @@ const (
 	XRD = "xtls-rprx-direct"
 	XRS = "xtls-rprx-splice"
 )
+
+// IsStreamSecure returns whether the connections of the stream settings are
+// secured by a security layer such as TLS, or by the transport itself.
+func IsStreamSecure(settings *internet.MemoryStreamConfig) bool {
+	if settings == nil {
+		return false
+	}
+	if settings.SecurityType != "" {
+		return true
+	}
+	switch settings.ProtocolName {
+	case "quic", "domainsocket":
+		// QUIC is always encrypted, and Unix domain sockets never leave the host.
+		return true
+	default:
+		return false
+	}
+}
+
+// CheckStreamSecurity warns about the stream settings without security, as
+// VLESS doesn't encrypt the traffic by itself, or refuses them if strict.
+func CheckStreamSecurity(settings *internet.MemoryStreamConfig, strict bool) error {
+	if IsStreamSecure(settings) {
+		return nil
+	}
+	if strict {
+		return newError("VLESS requires a security layer such as TLS in stream settings")
+	}
+	newError("VLESS is used without a security layer such as TLS in stream settings, so the traffic is NOT encrypted").AtWarning().WriteToLog()
+	return nil
+}
@@ -6,6 +6,7 @@ package vmess
 import (
 	"strings"
 
+	"github.com/v2fly/v2ray-core/v5/common/crypto"
 	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/uuid"
@@ -22,8 +23,13 @@ type MemoryAccount struct {
 
 	AuthenticatedLengthExperiment bool
 	NoTerminationSignal           bool
+	// FramePadding is the padding of the frames, or nil if not enabled.
+	FramePadding *crypto.FramePadding
 }
 
+// maxFramePadding is the maximum length of the padding of each frame.
+const maxFramePadding = 1024
+
 // AnyValidID returns an ID that is either the main ID or one of the alternative IDs if any.
 func (a *MemoryAccount) AnyValidID() *protocol.ID {
 	if len(a.AlterIDs) == 0 {
@@ -56,11 +62,22 @@ func (a *Account) AsAccount() (protocol.Account, error) {
 	if strings.Contains(a.TestsEnabled, "NoTerminationSignal") {
 		NoTerminationSignal = true
 	}
+	var framePadding *crypto.FramePadding
+	if a.PaddingMax > 0 {
+		if a.PaddingMax > maxFramePadding || a.PaddingMin > a.PaddingMax {
+			return nil, newError("invalid padding range: ", a.PaddingMin, "-", a.PaddingMax).AtError()
+		}
+		framePadding = &crypto.FramePadding{
+			Min: uint16(a.PaddingMin),
+			Max: uint16(a.PaddingMax),
+		}
+	}
 	return &MemoryAccount{
 		ID:                            protoID,
 		AlterIDs:                      protocol.NewAlterIDs(protoID, uint16(a.AlterId)),
 		Security:                      a.SecuritySettings.GetSecurityType(),
 		AuthenticatedLengthExperiment: AuthenticatedLength,
 		NoTerminationSignal:           NoTerminationSignal,
+		FramePadding:                  framePadding,
 	}, nil
 }
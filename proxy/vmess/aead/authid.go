@@ -65,13 +65,24 @@ func (aidd *AuthIDDecoder) Decode(data [16]byte) (int64, uint32, int32, []byte)
 	return t, zero, rand, data[:]
 }
 
+// DefaultReplayWindow is the default time window in seconds, in which an auth
+// ID is accepted and remembered for replay detection.
+const DefaultReplayWindow = 120
+
 func NewAuthIDDecoderHolder() *AuthIDDecoderHolder {
-	return &AuthIDDecoderHolder{make(map[string]*AuthIDDecoderItem), antireplay.NewReplayFilter(120)}
+	return NewAuthIDDecoderHolderWithWindow(DefaultReplayWindow)
+}
+
+// NewAuthIDDecoderHolderWithWindow creates an AuthIDDecoderHolder that accepts
+// auth IDs whose timestamp is within window seconds from now.
+func NewAuthIDDecoderHolderWithWindow(window int64) *AuthIDDecoderHolder {
+	return &AuthIDDecoderHolder{make(map[string]*AuthIDDecoderItem), antireplay.NewReplayFilter(window), window}
 }
 
 type AuthIDDecoderHolder struct {
 	decoders map[string]*AuthIDDecoderItem
 	filter   *antireplay.ReplayFilter
+	window   int64
 }
 
 type AuthIDDecoderItem struct {
@@ -86,6 +97,13 @@ func NewAuthIDDecoderItem(key [16]byte, ticket interface{}) *AuthIDDecoderItem {
 	}
 }
 
+// SetReplayWindow changes the time window in seconds, in which an auth ID is
+// accepted. Auth IDs seen before are forgotten.
+func (a *AuthIDDecoderHolder) SetReplayWindow(window int64) {
+	a.window = window
+	a.filter = antireplay.NewReplayFilter(window)
+}
+
 func (a *AuthIDDecoderHolder) AddUser(key [16]byte, ticket interface{}) {
 	a.decoders[string(key[:])] = NewAuthIDDecoderItem(key, ticket)
 }
@@ -105,7 +123,7 @@ func (a *AuthIDDecoderHolder) Match(authID [16]byte) (interface{}, error) {
 			continue
 		}
 
-		if math.Abs(math.Abs(float64(t))-float64(time.Now().Unix())) > 120 {
+		if math.Abs(math.Abs(float64(t))-float64(time.Now().Unix())) > float64(a.window) {
 			continue
 		}
 
@@ -125,3 +125,32 @@ func TestCreateAuthIDAndDecodeSuperMassive(t *testing.T) {
 
 	fmt.Println(after.Sub(before).Seconds())
 }
+
+func TestCreateAuthIDAndDecodeWithReplayWindow(t *testing.T) {
+	key := KDF16([]byte("Demo Key for Auth ID Test"), "Demo Path for Auth ID Test")
+	var keyw [16]byte
+	copy(keyw[:], key)
+
+	AuthDecoder := NewAuthIDDecoderHolderWithWindow(600)
+	AuthDecoder.AddUser(keyw, "Demo User")
+
+	authid := CreateAuthID(key, time.Now().Unix()-300)
+	res, err := AuthDecoder.Match(authid)
+	assert.Equal(t, "Demo User", res)
+	assert.Nil(t, err)
+
+	res, err = AuthDecoder.Match(authid)
+	assert.Equal(t, ErrReplay, err)
+	assert.Nil(t, res)
+
+	authid2 := CreateAuthID(key, time.Now().Unix()-900)
+	res2, err2 := AuthDecoder.Match(authid2)
+	assert.Equal(t, ErrNotFound, err2)
+	assert.Nil(t, res2)
+
+	AuthDecoder.SetReplayWindow(30)
+	authid3 := CreateAuthID(key, time.Now().Unix()-60)
+	res3, err3 := AuthDecoder.Match(authid3)
+	assert.Equal(t, ErrNotFound, err3)
+	assert.Nil(t, res3)
+}
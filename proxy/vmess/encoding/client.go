@@ -142,7 +142,9 @@ func (c *ClientSession) EncodeRequestBody(request *protocol.RequestHeader, write
 		sizeParser = NewShakeSizeParser(c.requestBodyIV[:])
 	}
 	var padding crypto.PaddingLengthGenerator
-	if request.Option.Has(protocol.RequestOptionGlobalPadding) {
+	if request.Option.Has(protocol.RequestOptionFramePadding) {
+		padding = framePaddingOf(request)
+	} else if request.Option.Has(protocol.RequestOptionGlobalPadding) {
 		var ok bool
 		padding, ok = sizeParser.(crypto.PaddingLengthGenerator)
 		if !ok {
@@ -315,7 +317,9 @@ func (c *ClientSession) DecodeResponseBody(request *protocol.RequestHeader, read
 		sizeParser = NewShakeSizeParser(c.responseBodyIV[:])
 	}
 	var padding crypto.PaddingLengthGenerator
-	if request.Option.Has(protocol.RequestOptionGlobalPadding) {
+	if request.Option.Has(protocol.RequestOptionFramePadding) {
+		padding = new(crypto.FramePadding)
+	} else if request.Option.Has(protocol.RequestOptionGlobalPadding) {
 		var ok bool
 		padding, ok = sizeParser.(crypto.PaddingLengthGenerator)
 		if !ok {
@@ -1,8 +1,10 @@
 package encoding
 
 import (
+	"github.com/v2fly/v2ray-core/v5/common/crypto"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
 )
 
 //go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
@@ -17,3 +19,17 @@ var addrParser = protocol.NewAddressParser(
 	protocol.AddressFamilyByte(byte(protocol.AddressTypeIPv6), net.AddressFamilyIPv6),
 	protocol.PortThenAddress(),
 )
+
+// defaultFramePadding is the padding of the frames sent with
+// RequestOptionFramePadding, if the account of the user doesn't set one.
+var defaultFramePadding = &crypto.FramePadding{Max: 64}
+
+// framePaddingOf returns the padding of the frames sent for request.
+func framePaddingOf(request *protocol.RequestHeader) *crypto.FramePadding {
+	if request.User != nil {
+		if account, ok := request.User.Account.(*vmess.MemoryAccount); ok && account.FramePadding != nil {
+			return account.FramePadding
+		}
+	}
+	return defaultFramePadding
+}
@@ -1,7 +1,10 @@
 package encoding_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -154,3 +157,148 @@ func TestMuxRequest(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+func TestLegacyRequestRejectedWhenAEADForced(t *testing.T) {
+	user := &protocol.MemoryUser{
+		Level: 0,
+		Email: "test@v2fly.org",
+	}
+	id := uuid.New()
+	account := &vmess.Account{
+		Id:      id.String(),
+		AlterId: 4,
+	}
+	user.Account = toAccount(account)
+
+	expectedRequest := &protocol.RequestHeader{
+		Version:  1,
+		User:     user,
+		Command:  protocol.RequestCommandTCP,
+		Address:  net.DomainAddress("www.v2fly.org"),
+		Port:     net.Port(443),
+		Security: protocol.SecurityType_AES128_GCM,
+	}
+
+	buffer := buf.New()
+	client := NewClientSession(context.TODO(), false, protocol.DefaultIDHash, 0)
+	common.Must(client.EncodeRequestHeader(expectedRequest, buffer))
+
+	payload := make([]byte, 8192)
+	common.Must2(rand.Read(payload))
+	reader := bytes.NewReader(append(buffer.Bytes(), payload...))
+	total := reader.Len()
+
+	sessionHistory := NewSessionHistory()
+	defer common.Close(sessionHistory)
+
+	userValidator := vmess.NewTimedUserValidator(protocol.DefaultIDHash)
+	userValidator.Add(user)
+	defer common.Close(userValidator)
+
+	server := NewServerSession(userValidator, sessionHistory)
+	server.SetAEADForced(true)
+	if _, err := server.DecodeRequestHeader(reader); err == nil {
+		t.Fatal("expect legacy request to be rejected")
+	}
+	if read := total - reader.Len(); read <= protocol.IDBytesLen {
+		t.Error("expect connection to be drained, but only ", read, " bytes were read")
+	}
+}
+
+func TestFramePaddingBody(t *testing.T) {
+	user := &protocol.MemoryUser{
+		Level: 0,
+		Email: "test@v2fly.org",
+	}
+	id := uuid.New()
+	account := &vmess.Account{
+		Id:         id.String(),
+		PaddingMin: 32,
+		PaddingMax: 512,
+	}
+	user.Account = toAccount(account)
+
+	request := &protocol.RequestHeader{
+		Version:  1,
+		User:     user,
+		Command:  protocol.RequestCommandTCP,
+		Option:   protocol.RequestOptionChunkStream | protocol.RequestOptionChunkMasking | protocol.RequestOptionFramePadding,
+		Address:  net.DomainAddress("www.v2fly.org"),
+		Port:     net.Port(443),
+		Security: protocol.SecurityType_CHACHA20_POLY1305,
+	}
+
+	payload := make([]byte, 1024*20)
+	common.Must2(rand.Read(payload))
+
+	requestBuffer := bytes.NewBuffer(nil)
+	client := NewClientSession(context.TODO(), true, protocol.DefaultIDHash, 0)
+	common.Must(client.EncodeRequestHeader(request, requestBuffer))
+	requestWriter, err := client.EncodeRequestBody(request, requestBuffer)
+	common.Must(err)
+	common.Must(requestWriter.WriteMultiBuffer(buf.MergeBytes(nil, payload)))
+	common.Must(requestWriter.WriteMultiBuffer(buf.MultiBuffer{}))
+
+	sessionHistory := NewSessionHistory()
+	defer common.Close(sessionHistory)
+
+	userValidator := vmess.NewTimedUserValidator(protocol.DefaultIDHash)
+	userValidator.Add(user)
+	defer common.Close(userValidator)
+
+	server := NewServerSession(userValidator, sessionHistory)
+	actualRequest, err := server.DecodeRequestHeader(requestBuffer)
+	common.Must(err)
+	if !actualRequest.Option.Has(protocol.RequestOptionFramePadding) {
+		t.Fatal("frame padding not requested")
+	}
+	requestReader, err := server.DecodeRequestBody(actualRequest, requestBuffer)
+	common.Must(err)
+	if r := cmp.Diff(readAll(t, requestReader), payload); r != "" {
+		t.Error(r)
+	}
+
+	responseBuffer := bytes.NewBuffer(nil)
+	server.EncodeResponseHeader(&protocol.ResponseHeader{}, responseBuffer)
+	responseWriter, err := server.EncodeResponseBody(actualRequest, responseBuffer)
+	common.Must(err)
+	common.Must(responseWriter.WriteMultiBuffer(buf.MergeBytes(nil, payload)))
+	common.Must(responseWriter.WriteMultiBuffer(buf.MultiBuffer{}))
+
+	common.Must2(client.DecodeResponseHeader(responseBuffer))
+	responseReader, err := client.DecodeResponseBody(request, responseBuffer)
+	common.Must(err)
+	if r := cmp.Diff(readAll(t, responseReader), payload); r != "" {
+		t.Error(r)
+	}
+}
+
+func readAll(t *testing.T, reader buf.Reader) []byte {
+	var mb buf.MultiBuffer
+	for {
+		mb2, err := reader.ReadMultiBuffer()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("failed to read: ", err)
+		}
+		mb, _ = buf.MergeMulti(mb, mb2)
+	}
+	b := make([]byte, mb.Len())
+	mb, _ = buf.SplitBytes(mb, b)
+	buf.ReleaseMulti(mb)
+	return b
+}
+
+func TestInvalidFramePadding(t *testing.T) {
+	id := uuid.New()
+	_, err := (&vmess.Account{
+		Id:         id.String(),
+		PaddingMin: 64,
+		PaddingMax: 32,
+	}).AsAccount()
+	if err == nil {
+		t.Error("expected error for invalid padding range")
+	}
+}
@@ -34,14 +34,22 @@ type sessionID struct {
 // SessionHistory keeps track of historical session ids, to prevent replay attacks.
 type SessionHistory struct {
 	sync.RWMutex
-	cache map[sessionID]time.Time
-	task  *task.Periodic
+	cache  map[sessionID]time.Time
+	task   *task.Periodic
+	expire time.Duration
 }
 
 // NewSessionHistory creates a new SessionHistory object.
 func NewSessionHistory() *SessionHistory {
+	return NewSessionHistoryWithExpiration(time.Minute * 3)
+}
+
+// NewSessionHistoryWithExpiration creates a new SessionHistory object, which
+// remembers session ids for the given duration.
+func NewSessionHistoryWithExpiration(expire time.Duration) *SessionHistory {
 	h := &SessionHistory{
-		cache: make(map[sessionID]time.Time, 128),
+		cache:  make(map[sessionID]time.Time, 128),
+		expire: expire,
 	}
 	h.task = &task.Periodic{
 		Interval: time.Second * 30,
@@ -63,7 +71,7 @@ func (h *SessionHistory) addIfNotExits(session sessionID) bool {
 		return false
 	}
 
-	h.cache[session] = time.Now().Add(time.Minute * 3)
+	h.cache[session] = time.Now().Add(h.expire)
 	h.Unlock()
 	common.Must(h.task.Start())
 	return true
@@ -310,7 +318,9 @@ func (s *ServerSession) DecodeRequestBody(request *protocol.RequestHeader, reade
 		sizeParser = NewShakeSizeParser(s.requestBodyIV[:])
 	}
 	var padding crypto.PaddingLengthGenerator
-	if request.Option.Has(protocol.RequestOptionGlobalPadding) {
+	if request.Option.Has(protocol.RequestOptionFramePadding) {
+		padding = new(crypto.FramePadding)
+	} else if request.Option.Has(protocol.RequestOptionGlobalPadding) {
 		var ok bool
 		padding, ok = sizeParser.(crypto.PaddingLengthGenerator)
 		if !ok {
@@ -457,7 +467,9 @@ func (s *ServerSession) EncodeResponseBody(request *protocol.RequestHeader, writ
 		sizeParser = NewShakeSizeParser(s.responseBodyIV[:])
 	}
 	var padding crypto.PaddingLengthGenerator
-	if request.Option.Has(protocol.RequestOptionGlobalPadding) {
+	if request.Option.Has(protocol.RequestOptionFramePadding) {
+		padding = framePaddingOf(request)
+	} else if request.Option.Has(protocol.RequestOptionGlobalPadding) {
 		var ok bool
 		padding, ok = sizeParser.(crypto.PaddingLengthGenerator)
 		if !ok {
@@ -109,6 +109,7 @@ type Handler struct {
 	detours               *DetourConfig
 	sessionHistory        *encoding.SessionHistory
 	secure                bool
+	legacyDisabled        bool
 }
 
 // New creates a new VMess inbound handler.
@@ -122,6 +123,14 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		usersByEmail:          newUserByEmail(config.GetDefaultValue()),
 		sessionHistory:        encoding.NewSessionHistory(),
 		secure:                config.SecureEncryptionOnly,
+		legacyDisabled:        config.DisableLegacyHeader,
+	}
+
+	if window := config.ReplayWindowSeconds; window > 0 {
+		handler.clients.SetAEADReplayWindow(int64(window))
+		if expire := time.Duration(window) * time.Second; expire > time.Minute*3 {
+			handler.sessionHistory = encoding.NewSessionHistoryWithExpiration(expire)
+		}
 	}
 
 	for _, user := range config.User {
@@ -228,7 +237,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 
 	reader := &buf.BufferedReader{Reader: buf.NewReader(connection)}
 	svrSession := encoding.NewServerSession(h.clients, h.sessionHistory)
-	svrSession.SetAEADForced(aeadForced)
+	svrSession.SetAEADForced(aeadForced || h.legacyDisabled)
 	request, err := svrSession.DecodeRequestHeader(reader)
 	if err != nil {
 		if errors.Cause(err) != io.EOF {
@@ -0,0 +1,117 @@
+package inbound
+
+import (
+	"context"
+	gonet "net"
+	"testing"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	_ "github.com/v2fly/v2ray-core/v5/app/proxyman/inbound"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/uuid"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess/encoding"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// recordingDispatcher records the destination of the requests dispatched to
+// it, and responds with nothing.
+type recordingDispatcher struct {
+	routing.Dispatcher
+	dest *net.Destination
+}
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	d.dest = &dest
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithoutSizeLimit())
+	downlinkReader, downlinkWriter := pipe.New()
+	go buf.Copy(uplinkReader, buf.Discard)
+	downlinkWriter.Close()
+	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+}
+
+// processLegacyRequest sends a request with a legacy header to a handler
+// built from config, and returns the destination it dispatches.
+func processLegacyRequest(config *Config, user *protocol.MemoryUser) *net.Destination {
+	instance, err := core.New(&core.Config{
+		App: []*anypb.Any{serial.ToTypedMessage(&proxyman.InboundConfig{})},
+	})
+	common.Must(err)
+	ctx := core.WithContext(context.Background(), instance)
+	handler, err := New(ctx, config)
+	common.Must(err)
+	defer handler.Close()
+
+	request := &protocol.RequestHeader{
+		Version:  1,
+		User:     user,
+		Command:  protocol.RequestCommandTCP,
+		Option:   protocol.RequestOptionChunkStream,
+		Address:  net.DomainAddress("www.v2fly.org"),
+		Port:     net.Port(443),
+		Security: protocol.SecurityType_AES128_GCM,
+	}
+	client, server := gonet.Pipe()
+	go func() {
+		defer client.Close()
+		session := encoding.NewClientSession(context.TODO(), false, protocol.DefaultIDHash, 0)
+		writer := buf.NewBufferedWriter(buf.NewWriter(client))
+		if err := session.EncodeRequestHeader(request, writer); err != nil {
+			return
+		}
+		body, err := session.EncodeRequestBody(request, writer)
+		if err != nil {
+			return
+		}
+		body.WriteMultiBuffer(buf.MergeBytes(nil, []byte("ping")))
+		body.WriteMultiBuffer(buf.MultiBuffer{})
+		writer.SetBuffered(false)
+	}()
+
+	dispatcher := new(recordingDispatcher)
+	ctx = session.ContextWithInbound(ctx, &session.Inbound{})
+	handler.Process(ctx, net.Network_TCP, server, dispatcher)
+	server.Close()
+	return dispatcher.dest
+}
+
+func TestDisableLegacyHeader(t *testing.T) {
+	// Legacy headers are only accepted with v2ray.vmess.aead.forced=false.
+	defer func(forced bool) {
+		aeadForced = forced
+	}(aeadForced)
+	aeadForced = false
+
+	id := uuid.New()
+	account := &vmess.Account{
+		Id:      id.String(),
+		AlterId: 4,
+	}
+	memoryAccount, err := account.AsAccount()
+	common.Must(err)
+	user := &protocol.MemoryUser{
+		Email:   "test@v2fly.org",
+		Account: memoryAccount,
+	}
+	users := []*protocol.User{{
+		Email:   user.Email,
+		Account: serial.ToTypedMessage(account),
+	}}
+	expected := net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443)
+
+	if dest := processLegacyRequest(&Config{User: users}, user); dest == nil || *dest != expected {
+		t.Error("expect legacy header to be accepted, but got destination ", dest)
+	}
+	if dest := processLegacyRequest(&Config{User: users, DisableLegacyHeader: true}, user); dest != nil {
+		t.Error("expect legacy header to be rejected, but got destination ", *dest)
+	}
+}
@@ -35,6 +35,7 @@ type Handler struct {
 	serverPicker   protocol.ServerPicker
 	policyManager  policy.Manager
 	packetEncoding packetaddr.PacketAddrType
+	framePadding   bool
 }
 
 // New creates a new VMess outbound handler.
@@ -54,6 +55,7 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		serverPicker:   protocol.NewRoundRobinServerPicker(serverList),
 		policyManager:  v.GetFeature(policy.ManagerType()).(policy.Manager),
 		packetEncoding: config.PacketEncoding,
+		framePadding:   config.FramePadding,
 	}
 
 	return handler, nil
@@ -97,36 +99,8 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 		command = protocol.RequestCommandMux
 	}
 
-	user := rec.PickUser()
-	request := &protocol.RequestHeader{
-		Version: encoding.Version,
-		User:    user,
-		Command: command,
-		Address: target.Address,
-		Port:    target.Port,
-		Option:  protocol.RequestOptionChunkStream,
-	}
-
+	request := h.newRequestHeader(rec.PickUser(), command, target)
 	account := request.User.Account.(*vmess.MemoryAccount)
-	request.Security = account.Security
-
-	if request.Security == protocol.SecurityType_AES128_GCM || request.Security == protocol.SecurityType_NONE || request.Security == protocol.SecurityType_CHACHA20_POLY1305 {
-		request.Option.Set(protocol.RequestOptionChunkMasking)
-	}
-
-	if shouldEnablePadding(request.Security) && request.Option.Has(protocol.RequestOptionChunkMasking) {
-		request.Option.Set(protocol.RequestOptionGlobalPadding)
-	}
-
-	if request.Security == protocol.SecurityType_ZERO {
-		request.Security = protocol.SecurityType_NONE
-		request.Option.Clear(protocol.RequestOptionChunkStream)
-		request.Option.Clear(protocol.RequestOptionChunkMasking)
-	}
-
-	if account.AuthenticatedLengthExperiment {
-		request.Option.Set(protocol.RequestOptionAuthenticatedLength)
-	}
 
 	input := link.Reader
 	output := link.Writer
@@ -232,6 +206,46 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 	return nil
 }
 
+// newRequestHeader creates the header of a request of user to target.
+func (h *Handler) newRequestHeader(user *protocol.MemoryUser, command protocol.RequestCommand, target net.Destination) *protocol.RequestHeader {
+	request := &protocol.RequestHeader{
+		Version: encoding.Version,
+		User:    user,
+		Command: command,
+		Address: target.Address,
+		Port:    target.Port,
+		Option:  protocol.RequestOptionChunkStream,
+	}
+
+	account := request.User.Account.(*vmess.MemoryAccount)
+	request.Security = account.Security
+
+	if request.Security == protocol.SecurityType_AES128_GCM || request.Security == protocol.SecurityType_NONE || request.Security == protocol.SecurityType_CHACHA20_POLY1305 {
+		request.Option.Set(protocol.RequestOptionChunkMasking)
+	}
+
+	if shouldEnablePadding(request.Security) && request.Option.Has(protocol.RequestOptionChunkMasking) {
+		request.Option.Set(protocol.RequestOptionGlobalPadding)
+	}
+
+	if h.framePadding && account.FramePadding != nil && (request.Security == protocol.SecurityType_AES128_GCM || request.Security == protocol.SecurityType_CHACHA20_POLY1305) {
+		request.Option.Set(protocol.RequestOptionFramePadding)
+		request.Option.Clear(protocol.RequestOptionGlobalPadding)
+	}
+
+	if request.Security == protocol.SecurityType_ZERO {
+		request.Security = protocol.SecurityType_NONE
+		request.Option.Clear(protocol.RequestOptionChunkStream)
+		request.Option.Clear(protocol.RequestOptionChunkMasking)
+	}
+
+	if account.AuthenticatedLengthExperiment {
+		request.Option.Set(protocol.RequestOptionAuthenticatedLength)
+	}
+
+	return request
+}
+
 var (
 	enablePadding = false
 	aeadDisabled  = false
@@ -0,0 +1,92 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/uuid"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess/encoding"
+)
+
+func newPaddingUser() *protocol.MemoryUser {
+	id := uuid.New()
+	account, err := (&vmess.Account{
+		Id: id.String(),
+		SecuritySettings: &protocol.SecurityConfig{
+			Type: protocol.SecurityType_CHACHA20_POLY1305,
+		},
+		PaddingMin: 32,
+		PaddingMax: 512,
+	}).AsAccount()
+	common.Must(err)
+	return &protocol.MemoryUser{
+		Email:   "test@v2fly.org",
+		Account: account,
+	}
+}
+
+func TestFramePaddingOptIn(t *testing.T) {
+	user := newPaddingUser()
+	target := net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443)
+
+	request := (&Handler{framePadding: true}).newRequestHeader(user, protocol.RequestCommandTCP, target)
+	if !request.Option.Has(protocol.RequestOptionFramePadding) {
+		t.Error("expect frame padding when enabled in the outbound")
+	}
+
+	request = (&Handler{}).newRequestHeader(user, protocol.RequestCommandTCP, target)
+	if request.Option.Has(protocol.RequestOptionFramePadding) {
+		t.Fatal("expect no frame padding unless enabled in the outbound")
+	}
+
+	payload := make([]byte, 1024*20)
+	common.Must2(rand.Read(payload))
+
+	stream := bytes.NewBuffer(nil)
+	client := encoding.NewClientSession(context.TODO(), true, protocol.DefaultIDHash, 0)
+	common.Must(client.EncodeRequestHeader(request, stream))
+	writer, err := client.EncodeRequestBody(request, stream)
+	common.Must(err)
+	common.Must(writer.WriteMultiBuffer(buf.MergeBytes(nil, payload)))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{}))
+
+	sessionHistory := encoding.NewSessionHistory()
+	defer common.Close(sessionHistory)
+	userValidator := vmess.NewTimedUserValidator(protocol.DefaultIDHash)
+	userValidator.Add(user)
+	defer common.Close(userValidator)
+
+	// A server without frame padding support ignores the option, and reads
+	// the stream as it is.
+	server := encoding.NewServerSession(userValidator, sessionHistory)
+	actualRequest, err := server.DecodeRequestHeader(stream)
+	common.Must(err)
+	actualRequest.Option.Clear(protocol.RequestOptionFramePadding)
+	reader, err := server.DecodeRequestBody(actualRequest, stream)
+	common.Must(err)
+
+	var mb buf.MultiBuffer
+	for {
+		mb2, err := reader.ReadMultiBuffer()
+		if err == io.EOF {
+			break
+		}
+		common.Must(err)
+		mb, _ = buf.MergeMulti(mb, mb2)
+	}
+	actual := make([]byte, mb.Len())
+	mb, _ = buf.SplitBytes(mb, actual)
+	buf.ReleaseMulti(mb)
+	if r := cmp.Diff(actual, payload); r != "" {
+		t.Error(r)
+	}
+}
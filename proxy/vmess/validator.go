@@ -132,6 +132,15 @@ func (v *TimedUserValidator) updateUserHash() {
 	}
 }
 
+// SetAEADReplayWindow sets the time window in seconds, in which a VMessAEAD
+// auth ID is accepted and remembered for replay detection.
+func (v *TimedUserValidator) SetAEADReplayWindow(seconds int64) {
+	v.Lock()
+	defer v.Unlock()
+
+	v.aeadDecoderHolder.SetReplayWindow(seconds)
+}
+
 func (v *TimedUserValidator) Add(u *protocol.MemoryUser) error {
 	v.Lock()
 	defer v.Unlock()
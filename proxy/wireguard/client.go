@@ -472,7 +472,7 @@ type udpConn struct {
 }
 
 func (c *udpConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
-	buffer := buf.New()
+	buffer := buf.NewSize(buf.Size)
 	n, addr, err := c.ReadFrom(buffer.Extend(buf.Size))
 	if err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package scenarios
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/rand"
@@ -81,6 +82,67 @@ func TestHttpConformance(t *testing.T) {
 	}
 }
 
+func TestHttpKeepAlive(t *testing.T) {
+	httpServerPort := tcp.PickPort()
+	httpServer := &v2httptest.Server{
+		Port:        httpServerPort,
+		PathHandler: make(map[string]http.HandlerFunc),
+	}
+	_, err := httpServer.Start()
+	common.Must(err)
+	defer httpServer.Close()
+
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&v2http.ServerConfig{}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{
+		IP:   []byte{127, 0, 0, 1},
+		Port: int(serverPort),
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", "http://127.0.0.1:"+httpServerPort.String()+"/", nil)
+		common.Must(err)
+		common.Must(req.WriteProxy(conn))
+
+		resp, err := http.ReadResponse(reader, req)
+		if err != nil {
+			t.Fatal("request ", i, ": ", err)
+		}
+		content, err := io.ReadAll(resp.Body)
+		common.Must(err)
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatal("request ", i, " status: ", resp.StatusCode)
+		}
+		if string(content) != "Home" {
+			t.Fatal("request ", i, " body: ", string(content))
+		}
+	}
+}
+
 func TestHttpError(t *testing.T) {
 	tcpServer := tcp.Server{
 		MsgProcessor: func(msg []byte) []byte {
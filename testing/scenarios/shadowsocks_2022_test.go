@@ -1,6 +1,7 @@
 package scenarios
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -293,3 +294,112 @@ func TestShadowsocksBlake3AES128GCMUDP(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestShadowsocksBlake3AES256GCMMultiUserTCP(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverKey := "UVTughS+Q5PgnUQaeg4KD+DYv0wn+eKqCdwjtsGzKBA="
+	userKeys := []string{
+		"rK5AryPeGQ0QTuHD0R9UenvVt8Ijym2jvmv1Hfes2fs=",
+		"Gb2Vusf/cdz2zhgOlGh5f3WhIEqje2p9MHbnMcFDmAM=",
+	}
+
+	serverConfig := &shadowsocks.ServerConfig{
+		User: &protocol.User{
+			Account: serial.ToTypedMessage(&shadowsocks.Account{
+				Password:   serverKey,
+				CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM_2022,
+			}),
+		},
+		Network: []net.Network{net.Network_TCP},
+	}
+	for i, key := range userKeys {
+		serverConfig.Users = append(serverConfig.Users, &protocol.User{
+			Email: "user" + strconv.Itoa(i) + "@v2fly.org",
+			Account: serial.ToTypedMessage(&shadowsocks.Account{
+				Password:   key,
+				CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM_2022,
+			}),
+		})
+	}
+
+	serverPort := tcp.PickPort()
+	configs := []*core.Config{
+		{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortRange: net.SinglePortRange(serverPort),
+						Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(serverConfig),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+				},
+			},
+		},
+	}
+
+	var clientPorts []net.Port
+	for _, key := range userKeys {
+		clientPort := tcp.PickPort()
+		clientPorts = append(clientPorts, clientPort)
+		configs = append(configs, &core.Config{
+			Inbound: []*core.InboundHandlerConfig{
+				{
+					ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+						PortRange: net.SinglePortRange(clientPort),
+						Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					}),
+					ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+						Address:  net.NewIPOrDomain(dest.Address),
+						Port:     uint32(dest.Port),
+						Networks: []net.Network{net.Network_TCP},
+					}),
+				},
+			},
+			Outbound: []*core.OutboundHandlerConfig{
+				{
+					ProxySettings: serial.ToTypedMessage(&shadowsocks.ClientConfig{
+						Server: []*protocol.ServerEndpoint{
+							{
+								Address: net.NewIPOrDomain(net.LocalHostIP),
+								Port:    uint32(serverPort),
+								User: []*protocol.User{
+									{
+										Account: serial.ToTypedMessage(&shadowsocks.Account{
+											Password:   serverKey + ":" + key,
+											CipherType: shadowsocks.CipherType_BLAKE3_AES_256_GCM_2022,
+										}),
+									},
+								},
+							},
+						},
+					}),
+				},
+			},
+		})
+	}
+
+	servers, err := InitializeServerConfigs(configs...)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	var errGroup errgroup.Group
+	for _, clientPort := range clientPorts {
+		for i := 0; i < 5; i++ {
+			errGroup.Go(testTCPConn(clientPort, 1024*1024, time.Second*20))
+		}
+	}
+	if err := errGroup.Wait(); err != nil {
+		t.Error(err)
+	}
+}
@@ -66,7 +66,9 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *MemoryStrea
 	return nil, newError("unknown network ", dest.Network)
 }
 
-// DialSystem calls system dialer to create a network connection.
+// DialSystem calls system dialer to create a network connection. If the socket
+// settings have a dialer proxy, the connection is dialed through that outbound
+// instead.
 func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
 	var src net.Address
 	if outbound := session.OutboundFromContext(ctx); outbound != nil {
@@ -77,6 +79,10 @@ func DialSystem(ctx context.Context, dest net.Destination, sockopt *SocketConfig
 		return DialTaggedOutbound(ctx, dest, transportLayerOutgoingTag)
 	}
 
+	if sockopt != nil && sockopt.DialerProxy != "" {
+		return DialTaggedOutbound(ctx, dest, sockopt.DialerProxy)
+	}
+
 	return effectiveSystemDialer.Dial(ctx, src, dest, sockopt)
 }
 
@@ -91,6 +97,10 @@ func DialSystemDNS(ctx context.Context, dest net.Destination, sockopt *SocketCon
 		return DialTaggedOutbound(ctx, dest, transportLayerOutgoingTag)
 	}
 
+	if sockopt != nil && sockopt.DialerProxy != "" {
+		return DialTaggedOutbound(ctx, dest, sockopt.DialerProxy)
+	}
+
 	return effectiveSystemDNSDialer.Dial(ctx, src, dest, sockopt)
 }
 
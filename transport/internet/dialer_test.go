@@ -2,6 +2,7 @@ package internet_test
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -9,6 +10,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
 	. "github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
 )
 
 func TestDialWithLocalAddr(t *testing.T) {
@@ -24,3 +26,39 @@ func TestDialWithLocalAddr(t *testing.T) {
 	}
 	conn.Close()
 }
+
+func TestDialWithDialerProxy(t *testing.T) {
+	server := &tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := server.Start()
+	common.Must(err)
+	defer server.Close()
+
+	var dialedTag string
+	var dialedDest net.Destination
+	defer func(dialer tagged.DialFunc) {
+		tagged.Dialer = dialer
+	}(tagged.Dialer)
+	tagged.Dialer = func(ctx context.Context, dest net.Destination, tag string) (net.Conn, error) {
+		dialedTag = tag
+		dialedDest = dest
+		return DialSystem(ctx, dest, nil)
+	}
+
+	conn, err := DialSystem(context.Background(), dest, &SocketConfig{DialerProxy: "upstream"})
+	common.Must(err)
+	defer conn.Close()
+	if dialedTag != "upstream" || dialedDest != dest {
+		t.Error("expected dial to ", dest, " through upstream, but got ", dialedDest, " through ", dialedTag)
+	}
+
+	common.Must2(conn.Write([]byte("ping")))
+	b := make([]byte, 4)
+	common.Must2(io.ReadFull(conn, b))
+	if string(b) != "ping" {
+		t.Error("unexpected response: ", string(b))
+	}
+}
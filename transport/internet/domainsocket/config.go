@@ -1,6 +1,8 @@
 package domainsocket
 
 import (
+	"fmt"
+
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
@@ -11,6 +13,17 @@ const (
 	sizeofSunPath = 108
 )
 
+// Validate checks that the settings can be used together.
+func (c *Config) Validate() error {
+	if c.FileMode&^0o777 != 0 {
+		return newError("invalid file mode: ", fmt.Sprintf("%#o", c.FileMode))
+	}
+	if c.Abstract && c.FileMode != 0 {
+		return newError("file mode is not applicable to abstract domain socket")
+	}
+	return nil
+}
+
 func (c *Config) GetUnixAddr() (*net.UnixAddr, error) {
 	path := c.Path
 	if path == "" {
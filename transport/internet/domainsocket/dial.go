@@ -26,7 +26,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	}
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		return tls.Client(conn, config.GetTLSConfig(tls.WithDestination(dest))), nil
+		return tls.Client(conn, config.GetTLSConfigWithContext(ctx, tls.WithDestination(dest))), nil
 	} else if config := xtls.ConfigFromStreamSettings(streamSettings); config != nil {
 		return xtls.Client(conn, config.GetXTLSConfig(xtls.WithDestination(dest))), nil
 	}
@@ -30,6 +30,9 @@ type Listener struct {
 
 func Listen(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, handler internet.ConnHandler) (internet.Listener, error) {
 	settings := streamSettings.ProtocolSettings.(*Config)
+	if err := settings.Validate(); err != nil {
+		return nil, newError("invalid domain socket config").Base(err)
+	}
 	addr, err := settings.GetUnixAddr()
 	if err != nil {
 		return nil, err
@@ -40,6 +43,13 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		return nil, newError("failed to listen domain socket").Base(err).AtWarning()
 	}
 
+	if settings.FileMode != 0 {
+		if err := os.Chmod(settings.Path, os.FileMode(settings.FileMode)); err != nil {
+			unixListener.Close()
+			return nil, newError("failed to set file mode of domain socket").Base(err).AtWarning()
+		}
+	}
+
 	ln := &Listener{
 		addr:    addr,
 		ln:      unixListener,
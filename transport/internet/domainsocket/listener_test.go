@@ -5,6 +5,7 @@ package domainsocket_test
 
 import (
 	"context"
+	"os"
 	"runtime"
 	"testing"
 
@@ -91,3 +92,52 @@ func TestListenAbstract(t *testing.T) {
 		t.Error("expected response as 'RequestResponse' but got ", b.String())
 	}
 }
+
+func TestListenWithFileMode(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	ctx := context.Background()
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path:     "/tmp/ts3-mode",
+			FileMode: 0o660,
+		},
+	}
+	listener, err := Listen(ctx, nil, net.Port(0), streamSettings, func(conn internet.Connection) {
+		conn.Close()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	info, err := os.Stat("/tmp/ts3-mode")
+	common.Must(err)
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Error("expected socket file but got mode ", info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != 0o660 {
+		t.Errorf("expected file mode 0660 but got %#o", perm)
+	}
+}
+
+func TestListenAbstractWithFileMode(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	streamSettings := &internet.MemoryStreamConfig{
+		ProtocolName: "domainsocket",
+		ProtocolSettings: &Config{
+			Path:     "/tmp/ts3-mode",
+			Abstract: true,
+			FileMode: 0o660,
+		},
+	}
+	if _, err := Listen(context.Background(), nil, net.Port(0), streamSettings, func(conn internet.Connection) {
+		conn.Close()
+	}); err == nil {
+		t.Error("expected error for file mode on abstract domain socket")
+	}
+}
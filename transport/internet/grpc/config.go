@@ -5,7 +5,30 @@ import (
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 )
 
-const protocolName = "gun"
+const (
+	protocolName = "gun"
+
+	// minWindowSize is the smallest flow-control window gRPC accepts. Smaller
+	// windows are raised to it.
+	minWindowSize = 65535
+)
+
+// Validate checks the flow-control, keepalive and shutdown settings.
+func (c *Config) Validate() error {
+	if c.InitialWindowsSize < 0 || c.InitialConnWindowSize < 0 {
+		return newError("negative flow-control window size")
+	}
+	if c.IdleTimeout < 0 || c.HealthCheckTimeout < 0 {
+		return newError("negative client keepalive settings")
+	}
+	if c.ServerKeepaliveTime < 0 || c.ServerKeepaliveTimeout < 0 {
+		return newError("negative server keepalive settings")
+	}
+	if c.DrainTimeout < 0 {
+		return newError("negative drain timeout")
+	}
+	return nil
+}
 
 func init() {
 	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
@@ -21,7 +21,6 @@ import (
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
 )
 
 func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
@@ -47,12 +46,15 @@ var (
 
 func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (net.Conn, error) {
 	grpcSettings := streamSettings.ProtocolSettings.(*Config)
+	if err := grpcSettings.Validate(); err != nil {
+		return nil, err
+	}
 
 	config := tls.ConfigFromStreamSettings(streamSettings)
 	dialOption := grpc.WithInsecure()
 
 	if config != nil {
-		dialOption = grpc.WithTransportCredentials(credentials.NewTLS(config.GetTLSConfig()))
+		dialOption = grpc.WithTransportCredentials(credentials.NewTLS(config.GetTLSConfigWithContext(ctx)))
 	}
 
 	conn, canceller, err := getGrpcClient(ctx, dest, dialOption, grpcSettings)
@@ -85,10 +87,71 @@ func dialgRPC(ctx context.Context, dest net.Destination, streamSettings *interne
 		}
 		conn, _ := encoding.NewRawConn(gunService)
 		return conn, nil
+	case Mode_Mux:
+		conn, err := dialMux(ctx, client.(encoding.GunServiceClientX), dest, grpcSettings)
+		if err != nil {
+			canceller()
+			return nil, newError("Cannot dial grpc").Base(err)
+		}
+		return conn, nil
 	}
 	return nil, io.EOF
 }
 
+type muxSessionKey struct {
+	dest        net.Destination
+	serviceName string
+}
+
+var (
+	globalMuxSessions      map[muxSessionKey][]*encoding.MuxSession
+	globalMuxSessionAccess sync.Mutex
+)
+
+const defaultMaxConnectionsPerStream = 8
+
+// dialMux opens a connection on an existing mux stream to dest, or on a new
+// stream if all existing ones are full.
+func dialMux(ctx context.Context, client encoding.GunServiceClientX, dest net.Destination, grpcSettings *Config) (net.Conn, error) {
+	maxConns := int(grpcSettings.MaxConnectionsPerStream)
+	if maxConns <= 0 {
+		maxConns = defaultMaxConnectionsPerStream
+	}
+
+	globalMuxSessionAccess.Lock()
+	defer globalMuxSessionAccess.Unlock()
+
+	if globalMuxSessions == nil {
+		globalMuxSessions = make(map[muxSessionKey][]*encoding.MuxSession)
+	}
+
+	key := muxSessionKey{dest: dest, serviceName: grpcSettings.ServiceName}
+	sessions := globalMuxSessions[key][:0]
+	for _, session := range globalMuxSessions[key] {
+		if !session.IsClosed() {
+			sessions = append(sessions, session)
+		}
+	}
+	globalMuxSessions[key] = sessions
+
+	for _, session := range sessions {
+		if session.ActiveConns() >= maxConns {
+			continue
+		}
+		if conn, err := session.OpenConn(); err == nil {
+			return conn, nil
+		}
+	}
+
+	stream, err := client.TunMuxCustomName(core.ToBackgroundDetachedContext(ctx), grpcSettings.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	session := encoding.NewMuxClientSession(stream)
+	globalMuxSessions[key] = append(sessions, session)
+	return session.OpenConn()
+}
+
 func getGrpcClient(ctx context.Context, dest net.Destination, dialOption grpc.DialOption, grpcSettings *Config) (*grpc.ClientConn, dialerCanceller, error) {
 	globalDialerAccess.Lock()
 	defer globalDialerAccess.Unlock()
@@ -136,16 +199,7 @@ func getGrpcClient(ctx context.Context, dest net.Destination, dialOption grpc.Di
 		}),
 		dialOption,
 	}
-	if grpcSettings.IdleTimeout > 0 || grpcSettings.HealthCheckTimeout > 0 || grpcSettings.PermitWithoutStream {
-		grpcOptions = append(grpcOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                time.Second * time.Duration(grpcSettings.IdleTimeout),
-			Timeout:             time.Second * time.Duration(grpcSettings.HealthCheckTimeout),
-			PermitWithoutStream: grpcSettings.PermitWithoutStream,
-		}))
-	}
-	if grpcSettings.InitialWindowsSize > 0 {
-		grpcOptions = append(grpcOptions, grpc.WithInitialWindowSize(grpcSettings.InitialWindowsSize))
-	}
+	grpcOptions = append(grpcOptions, grpcSettings.dialOptions()...)
 	conn, err := grpc.Dial(dest.Address.String()+":"+dest.Port.String(), grpcOptions...)
 	globalDialerMap[dest] = conn
 	return conn, canceller, err
@@ -43,6 +43,15 @@ func ServerDesc(name string) grpc.ServiceDesc {
 				ServerStreams: true,
 				ClientStreams: true,
 			},
+			{
+				StreamName: "TunMux",
+				Handler: func(srv interface{}, stream grpc.ServerStream) error {
+					ServeMuxSession(stream, srv.(ConnHandler).HandleConn)
+					return nil
+				},
+				ServerStreams: true,
+				ClientStreams: true,
+			},
 		},
 		Metadata: "gun.proto",
 	}
@@ -65,12 +74,17 @@ func (c *gunServiceClient) TunRawCustomName(ctx context.Context, name string, op
 	return c.cc.NewStream(ctx, &ServerDesc(name).Streams[2], "/"+name+"/TunRaw", opts...)
 }
 
+func (c *gunServiceClient) TunMuxCustomName(ctx context.Context, name string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.cc.NewStream(ctx, &ServerDesc(name).Streams[3], "/"+name+"/TunMux", opts...)
+}
+
 var _ GunServiceClientX = (*gunServiceClient)(nil)
 
 type GunServiceClientX interface {
 	TunCustomName(ctx context.Context, name string, opts ...grpc.CallOption) (GunService_TunClient, error)
 	TunMultiCustomName(ctx context.Context, name string, opts ...grpc.CallOption) (grpc.ClientStream, error)
 	TunRawCustomName(ctx context.Context, name string, opts ...grpc.CallOption) (grpc.ClientStream, error)
+	TunMuxCustomName(ctx context.Context, name string, opts ...grpc.CallOption) (grpc.ClientStream, error)
 }
 
 func RegisterGunServiceServerX(s *grpc.Server, srv GunServiceServer, name string) {
@@ -0,0 +1,343 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/signal/done"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+	"google.golang.org/grpc/peer"
+)
+
+// Every Hunk in a mux session carries one frame:
+//
+//	| connection id (4 bytes, big endian) | frame type (1 byte) | payload |
+//
+// The payload of a window frame is the number of bytes, 4 bytes big endian,
+// the receiver has consumed and the sender may send in addition.
+const (
+	muxFrameNew    byte = 0x01
+	muxFrameData   byte = 0x02
+	muxFrameClose  byte = 0x03
+	muxFrameWindow byte = 0x04
+
+	muxFrameHeaderSize = 5
+
+	// muxConnWindowSize is the amount of data a connection may send before
+	// the peer has consumed any of it. The receiving session never blocks on
+	// a connection, so a slow one doesn't hold up the others.
+	muxConnWindowSize = 512 * 1024
+)
+
+// MuxSession carries several connections over a single gRPC stream.
+type MuxSession struct {
+	stream  Stream
+	handler func(internet.Connection)
+	remote  net.Addr
+
+	sendAccess sync.Mutex
+
+	access sync.Mutex
+	conns  map[uint32]*muxConn
+	nextID uint32
+
+	done *done.Instance
+}
+
+func newMuxSession(stream Stream, handler func(internet.Connection)) *MuxSession {
+	s := &MuxSession{
+		stream:  stream,
+		handler: handler,
+		conns:   make(map[uint32]*muxConn),
+		done:    done.New(),
+		remote: &net.TCPAddr{
+			IP:   []byte{0, 0, 0, 0},
+			Port: 0,
+		},
+	}
+	if pr, ok := peer.FromContext(stream.Context()); ok {
+		s.remote = pr.Addr
+	}
+	return s
+}
+
+// NewMuxClientSession creates a session on the client side of stream.
+// Connections are opened with OpenConn.
+func NewMuxClientSession(stream Stream) *MuxSession {
+	s := newMuxSession(stream, nil)
+	go s.run()
+	return s
+}
+
+// ServeMuxSession passes every connection opened by the peer on stream to
+// handler. It returns when the stream ends.
+func ServeMuxSession(stream Stream, handler func(internet.Connection)) {
+	newMuxSession(stream, handler).run()
+}
+
+// OpenConn opens a new connection in the session.
+func (s *MuxSession) OpenConn() (internet.Connection, error) {
+	s.access.Lock()
+	if s.done.Done() {
+		s.access.Unlock()
+		return nil, newError("mux session is closed")
+	}
+	s.nextID++
+	c := s.newConnLocked(s.nextID)
+	s.access.Unlock()
+
+	if err := s.send(c.id, muxFrameNew, nil); err != nil {
+		c.Close()
+		return nil, newError("failed to open connection in mux session").Base(err)
+	}
+	return c.connection(), nil
+}
+
+// ActiveConns returns the number of open connections in the session.
+func (s *MuxSession) ActiveConns() int {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return len(s.conns)
+}
+
+// IsClosed returns true if the underlying stream has ended.
+func (s *MuxSession) IsClosed() bool {
+	return s.done.Done()
+}
+
+// Close closes the session and all connections in it.
+func (s *MuxSession) Close() error {
+	s.access.Lock()
+	if s.done.Done() {
+		s.access.Unlock()
+		return nil
+	}
+	s.done.Close()
+	conns := s.conns
+	s.conns = make(map[uint32]*muxConn)
+	s.access.Unlock()
+
+	for _, c := range conns {
+		c.closeLocal()
+	}
+	if c, ok := s.stream.(SendCloser); ok {
+		return c.CloseSend()
+	}
+	return nil
+}
+
+func (s *MuxSession) newConnLocked(id uint32) *muxConn {
+	reader, writer := pipe.New(pipe.WithoutSizeLimit())
+	c := &muxConn{
+		id:         id,
+		session:    s,
+		reader:     reader,
+		writer:     writer,
+		done:       done.New(),
+		sendWindow: muxConnWindowSize,
+	}
+	c.windowUpdated = sync.NewCond(&c.windowAccess)
+	s.conns[id] = c
+	return c
+}
+
+func (s *MuxSession) get(id uint32) *muxConn {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.conns[id]
+}
+
+func (s *MuxSession) remove(id uint32) bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if _, found := s.conns[id]; !found {
+		return false
+	}
+	delete(s.conns, id)
+	return true
+}
+
+func (s *MuxSession) send(id uint32, frameType byte, payload []byte) error {
+	data := make([]byte, muxFrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(data, id)
+	data[4] = frameType
+	copy(data[muxFrameHeaderSize:], payload)
+
+	s.sendAccess.Lock()
+	defer s.sendAccess.Unlock()
+	return s.stream.SendMsg(&Hunk{Data: data})
+}
+
+func (s *MuxSession) run() {
+	defer s.Close()
+
+	for {
+		hunk := new(Hunk)
+		if err := s.stream.RecvMsg(hunk); err != nil {
+			if err != io.EOF && !s.done.Done() {
+				newError("mux session ended").Base(err).WriteToLog()
+			}
+			return
+		}
+		if len(hunk.Data) < muxFrameHeaderSize {
+			newError("invalid mux frame of ", len(hunk.Data), " bytes").AtWarning().WriteToLog()
+			return
+		}
+
+		id := binary.BigEndian.Uint32(hunk.Data)
+		payload := hunk.Data[muxFrameHeaderSize:]
+		switch hunk.Data[4] {
+		case muxFrameNew:
+			if s.handler == nil {
+				continue
+			}
+			s.access.Lock()
+			if _, found := s.conns[id]; found || s.done.Done() {
+				s.access.Unlock()
+				continue
+			}
+			c := s.newConnLocked(id)
+			s.access.Unlock()
+			s.handler(c.connection())
+		case muxFrameData:
+			c := s.get(id)
+			if c == nil || len(payload) == 0 {
+				continue
+			}
+			if atomic.AddInt32(&c.buffered, int32(len(payload))) > muxConnWindowSize {
+				newError("mux connection ", id, " exceeded its window").AtWarning().WriteToLog()
+				c.Close()
+				continue
+			}
+			if err := c.writer.WriteMultiBuffer(buf.MergeBytes(nil, payload)); err != nil {
+				// The connection is closed locally, drop its data.
+				continue
+			}
+		case muxFrameWindow:
+			if c := s.get(id); c != nil && len(payload) >= 4 {
+				c.addSendWindow(int32(binary.BigEndian.Uint32(payload)))
+			}
+		case muxFrameClose:
+			if c := s.get(id); c != nil && s.remove(id) {
+				c.closeLocal()
+			}
+		default:
+			newError("unknown mux frame type ", hunk.Data[4]).AtWarning().WriteToLog()
+			return
+		}
+	}
+}
+
+type muxConn struct {
+	id      uint32
+	session *MuxSession
+	reader  *pipe.Reader
+	writer  *pipe.Writer
+	done    *done.Instance
+
+	// buffered is the amount of received data not read yet, and consumed
+	// the amount read but not returned to the peer in a window frame.
+	buffered int32
+	consumed int32
+
+	windowAccess  sync.Mutex
+	windowUpdated *sync.Cond
+	sendWindow    int32
+}
+
+func (c *muxConn) connection() internet.Connection {
+	return buf.NewConnection(
+		buf.ConnectionOutputMulti(c),
+		buf.ConnectionInputMulti(c),
+		buf.ConnectionOnClose(c),
+		buf.ConnectionRemoteAddr(c.session.remote),
+	)
+}
+
+func (c *muxConn) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := c.reader.ReadMultiBuffer()
+	if n := mb.Len(); n > 0 {
+		atomic.AddInt32(&c.buffered, -n)
+		if consumed := atomic.AddInt32(&c.consumed, n); consumed >= muxConnWindowSize/2 && !c.done.Done() {
+			atomic.AddInt32(&c.consumed, -consumed)
+			var increment [4]byte
+			binary.BigEndian.PutUint32(increment[:], uint32(consumed))
+			if err := c.session.send(c.id, muxFrameWindow, increment[:]); err != nil {
+				newError("failed to update window of mux connection").Base(err).WriteToLog()
+			}
+		}
+	}
+	return mb, err
+}
+
+// acquireSendWindow waits until the peer accepts more data, and takes up to
+// size bytes of the window.
+func (c *muxConn) acquireSendWindow(size int32) (int32, error) {
+	c.windowAccess.Lock()
+	defer c.windowAccess.Unlock()
+
+	for c.sendWindow <= 0 {
+		if c.done.Done() {
+			return 0, io.ErrClosedPipe
+		}
+		c.windowUpdated.Wait()
+	}
+	if size > c.sendWindow {
+		size = c.sendWindow
+	}
+	c.sendWindow -= size
+	return size, nil
+}
+
+func (c *muxConn) addSendWindow(size int32) {
+	c.windowAccess.Lock()
+	c.sendWindow += size
+	c.windowAccess.Unlock()
+	c.windowUpdated.Broadcast()
+}
+
+func (c *muxConn) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	defer buf.ReleaseMulti(mb)
+	if c.done.Done() {
+		return io.ErrClosedPipe
+	}
+
+	for _, b := range mb {
+		for data := b.Bytes(); len(data) > 0; {
+			n, err := c.acquireSendWindow(int32(len(data)))
+			if err != nil {
+				return err
+			}
+			if err := c.session.send(c.id, muxFrameData, data[:n]); err != nil {
+				return newError("failed to write to mux session").Base(err)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// Close closes the connection and notifies the peer.
+func (c *muxConn) Close() error {
+	if c.done.Done() {
+		return nil
+	}
+	c.closeLocal()
+	if c.session.remove(c.id) {
+		return c.session.send(c.id, muxFrameClose, nil)
+	}
+	return nil
+}
+
+func (c *muxConn) closeLocal() {
+	c.windowAccess.Lock()
+	c.done.Close()
+	c.windowAccess.Unlock()
+	c.windowUpdated.Broadcast()
+	c.writer.Close()
+}
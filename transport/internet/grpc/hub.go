@@ -5,6 +5,7 @@ package grpc
 
 import (
 	"context"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
@@ -14,6 +15,8 @@ import (
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var _ encoding.GunServiceServer = (*Listener)(nil)
@@ -26,7 +29,8 @@ type Listener struct {
 	config  *Config
 	locker  *internet.FileLocker // for unix domain socket
 
-	s *grpc.Server
+	s      *grpc.Server
+	health *health.Server
 }
 
 func (l Listener) Tun(server encoding.GunService_TunServer) error {
@@ -47,8 +51,31 @@ func (l Listener) HandleConn(connection internet.Connection) {
 	l.handler(connection)
 }
 
+// Close stops accepting new streams and closes the in-flight ones. If a drain
+// timeout is configured, it waits for them to finish within the timeout first.
 func (l Listener) Close() error {
-	l.s.Stop()
+	// Tell load balancers to stop sending new streams.
+	l.health.Shutdown()
+
+	drainTimeout := l.config.drainTimeout()
+	if drainTimeout == 0 {
+		l.s.Stop()
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		l.s.GracefulStop()
+		close(stopped)
+	}()
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+		newError("closing the remaining streams after draining for ", drainTimeout).AtInfo().WriteToLog(session.ExportIDToError(l.ctx))
+		l.s.Stop()
+	}
 	return nil
 }
 
@@ -58,6 +85,9 @@ func (l Listener) Addr() net.Addr {
 
 func Listen(ctx context.Context, address net.Address, port net.Port, settings *internet.MemoryStreamConfig, handler internet.ConnHandler) (internet.Listener, error) {
 	grpcSettings := settings.ProtocolSettings.(*Config)
+	if err := grpcSettings.Validate(); err != nil {
+		return nil, err
+	}
 	var listener *Listener
 	if port == net.Port(0) { // unix
 		listener = &Listener{
@@ -83,14 +113,18 @@ func Listen(ctx context.Context, address net.Address, port net.Port, settings *i
 
 	config := tls.ConfigFromStreamSettings(settings)
 
-	var s *grpc.Server
-	if config == nil {
-		s = grpc.NewServer()
-	} else {
+	options := grpcSettings.serverOptions()
+	if config != nil {
 		// gRPC server may silently ignore TLS errors
-		s = grpc.NewServer(grpc.Creds(credentials.NewTLS(config.GetTLSConfig(tls.WithNextProto("h2")))))
+		options = append(options, grpc.Creds(credentials.NewTLS(config.GetTLSConfig(tls.WithNextProto("h2")))))
 	}
+	s := grpc.NewServer(options...)
 	listener.s = s
+	// The health service reports NOT_SERVING until the server listens.
+	listener.health = health.NewServer()
+	listener.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	listener.health.SetServingStatus(grpcSettings.ServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, listener.health)
 
 	if settings.SocketSettings != nil && settings.SocketSettings.AcceptProxyProtocol {
 		newError("accepting PROXY protocol").AtWarning().WriteToLog(session.ExportIDToError(ctx))
@@ -124,6 +158,8 @@ func Listen(ctx context.Context, address net.Address, port net.Port, settings *i
 		}
 
 		encoding.RegisterGunServiceServerX(s, listener, grpcSettings.ServiceName)
+		listener.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+		listener.health.SetServingStatus(grpcSettings.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
 
 		if err = s.Serve(streamListener); err != nil {
 			newError("Listener for grpc ended").Base(err).WriteToLog()
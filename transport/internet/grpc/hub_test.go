@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func listenEcho(t *testing.T, config *Config) (internet.Listener, net.Destination) {
+	port := tcp.PickPort()
+	listener, err := Listen(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: config,
+	}, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	return listener, net.TCPDestination(net.LocalHostIP, port)
+}
+
+func echo(conn net.Conn, payload string) error {
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		return err
+	}
+	b := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return err
+	}
+	if string(b) != payload {
+		return newError("unexpected response: ", string(b))
+	}
+	return nil
+}
+
+func TestHealthService(t *testing.T) {
+	listener, dest := listenEcho(t, &Config{ServiceName: "health-test"})
+
+	clientConn, err := grpc.Dial(dest.NetAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	common.Must(err)
+	defer clientConn.Close()
+	client := grpc_health_v1.NewHealthClient(clientConn)
+
+	for _, service := range []string{"", "health-test"} {
+		var status grpc_health_v1.HealthCheckResponse_ServingStatus
+		// The server starts listening in the background.
+		for i := 0; i < 50 && status != grpc_health_v1.HealthCheckResponse_SERVING; i++ {
+			resp, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service}, grpc.WaitForReady(true))
+			common.Must(err)
+			status = resp.Status
+			if status != grpc_health_v1.HealthCheckResponse_SERVING {
+				time.Sleep(time.Millisecond * 100)
+			}
+		}
+		if status != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Error("unexpected status of service \"", service, "\": ", status)
+		}
+	}
+	if _, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"}); err == nil {
+		t.Error("expected an error for unknown services")
+	}
+
+	common.Must(listener.Close())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Error("expected the closed server not serving")
+	}
+}
+
+func TestShutdownDrainsStreams(t *testing.T) {
+	listener, dest := listenEcho(t, &Config{ServiceName: "drain-test", DrainTimeout: 5})
+
+	// The client stream ends with the context of the connection.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := Dial(ctx, dest, &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: &Config{ServiceName: "drain-test"},
+	})
+	common.Must(err)
+	common.Must(echo(conn, "before shutdown"))
+
+	closed := make(chan struct{})
+	start := time.Now()
+	go func() {
+		common.Must(listener.Close())
+		close(closed)
+	}()
+	time.Sleep(time.Millisecond * 200)
+
+	// The in-flight stream keeps working while draining.
+	select {
+	case <-closed:
+		t.Fatal("closed with an in-flight stream")
+	default:
+	}
+	common.Must(echo(conn, "while draining"))
+
+	// The server stops once the stream finishes.
+	cancel()
+	select {
+	case <-closed:
+		if elapsed := time.Since(start); elapsed >= time.Second*5 {
+			t.Error("expected to close before the drain timeout, but took ", elapsed)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("not closed after the stream finished")
+	}
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	listener, dest := listenEcho(t, &Config{ServiceName: "drain-timeout-test", DrainTimeout: 1})
+
+	conn, err := Dial(context.Background(), dest, &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: &Config{ServiceName: "drain-timeout-test"},
+	})
+	common.Must(err)
+	defer conn.Close()
+	common.Must(echo(conn, "before shutdown"))
+
+	start := time.Now()
+	common.Must(listener.Close())
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > time.Second*3 {
+		t.Error("expected to close after the drain timeout, but took ", elapsed)
+	}
+
+	// The remaining stream is closed.
+	if err := echo(conn, "after shutdown"); err == nil {
+		t.Error("expected the stream closed after the drain timeout")
+	}
+}
+
+func TestShutdownWithoutDrainTimeout(t *testing.T) {
+	listener, dest := listenEcho(t, &Config{ServiceName: "no-drain-test"})
+
+	conn, err := Dial(context.Background(), dest, &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: &Config{ServiceName: "no-drain-test"},
+	})
+	common.Must(err)
+	defer conn.Close()
+	common.Must(echo(conn, "before shutdown"))
+
+	start := time.Now()
+	common.Must(listener.Close())
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Error("expected to close at once, but took ", elapsed)
+	}
+
+	if err := echo(conn, "after shutdown"); err == nil {
+		t.Error("expected the stream closed")
+	}
+}
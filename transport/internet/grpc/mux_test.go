@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+func TestMuxModeSharesStreams(t *testing.T) {
+	port := tcp.PickPort()
+	settings := &internet.MemoryStreamConfig{
+		ProtocolName: protocolName,
+		ProtocolSettings: &Config{
+			ServiceName:             "mux-test",
+			Mode:                    Mode_Mux,
+			MaxConnectionsPerStream: 4,
+		},
+	}
+
+	listener, err := Listen(context.Background(), net.LocalHostIP, port, settings, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	dest := net.TCPDestination(net.LocalHostIP, port)
+	const total = 20
+	conns := make([]net.Conn, 0, total)
+	for i := 0; i < total; i++ {
+		conn, err := Dial(context.Background(), dest, settings)
+		common.Must(err)
+		conns = append(conns, conn)
+	}
+
+	globalMuxSessionAccess.Lock()
+	sessions := len(globalMuxSessions[muxSessionKey{dest: dest, serviceName: "mux-test"}])
+	globalMuxSessionAccess.Unlock()
+	if sessions != total/4 {
+		t.Error("expect ", total/4, " streams, but got ", sessions)
+	}
+
+	for i, conn := range conns {
+		payload := []byte(fmt.Sprint("connection ", i))
+		common.Must2(conn.Write(payload))
+		b := make([]byte, len(payload))
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != string(payload) {
+			t.Error("unexpected response on connection ", i, ": ", string(b))
+		}
+	}
+
+	for _, conn := range conns {
+		common.Must(conn.Close())
+	}
+
+	conn, err := Dial(context.Background(), dest, settings)
+	common.Must(err)
+	defer conn.Close()
+
+	globalMuxSessionAccess.Lock()
+	sessions = len(globalMuxSessions[muxSessionKey{dest: dest, serviceName: "mux-test"}])
+	globalMuxSessionAccess.Unlock()
+	if sessions != total/4 {
+		t.Error("idle streams are not reused, got ", sessions, " streams")
+	}
+}
+
+func TestMuxModeSlowConnection(t *testing.T) {
+	port := tcp.PickPort()
+	settings := &internet.MemoryStreamConfig{
+		ProtocolName: protocolName,
+		ProtocolSettings: &Config{
+			ServiceName:             "mux-slow-test",
+			Mode:                    Mode_Mux,
+			MaxConnectionsPerStream: 2,
+		},
+	}
+
+	payload := make([]byte, 4*1024*1024)
+	listener, err := Listen(context.Background(), net.LocalHostIP, port, settings, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			b := make([]byte, 4)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return
+			}
+			if string(b) == "slow" {
+				conn.Write(payload)
+				return
+			}
+			conn.Write(b)
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	dest := net.TCPDestination(net.LocalHostIP, port)
+	slow, err := Dial(context.Background(), dest, settings)
+	common.Must(err)
+	defer slow.Close()
+	common.Must2(slow.Write([]byte("slow")))
+
+	// Let the server fill up the window of the connection that is never read.
+	time.Sleep(time.Millisecond * 500)
+
+	fast, err := Dial(context.Background(), dest, settings)
+	common.Must(err)
+	defer fast.Close()
+
+	echoed := make(chan error, 1)
+	go func() {
+		if _, err := fast.Write([]byte("fast")); err != nil {
+			echoed <- err
+			return
+		}
+		b := make([]byte, 4)
+		_, err := io.ReadFull(fast, b)
+		echoed <- err
+	}()
+
+	select {
+	case err := <-echoed:
+		common.Must(err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("a connection that is not read blocks the others in the stream")
+	}
+
+	// The slow connection still receives all of its data.
+	b := make([]byte, len(payload))
+	common.Must2(io.ReadFull(slow, b))
+}
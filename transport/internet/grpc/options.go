@@ -0,0 +1,81 @@
+//go:build !confonly
+// +build !confonly
+
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+func (c *Config) clientKeepaliveParameters() (keepalive.ClientParameters, bool) {
+	if c.IdleTimeout <= 0 && c.HealthCheckTimeout <= 0 && !c.PermitWithoutStream {
+		return keepalive.ClientParameters{}, false
+	}
+	return keepalive.ClientParameters{
+		Time:                time.Second * time.Duration(c.IdleTimeout),
+		Timeout:             time.Second * time.Duration(c.HealthCheckTimeout),
+		PermitWithoutStream: c.PermitWithoutStream,
+	}, true
+}
+
+func (c *Config) serverKeepaliveParameters() (keepalive.ServerParameters, bool) {
+	if c.ServerKeepaliveTime <= 0 && c.ServerKeepaliveTimeout <= 0 {
+		return keepalive.ServerParameters{}, false
+	}
+	return keepalive.ServerParameters{
+		Time:    time.Second * time.Duration(c.ServerKeepaliveTime),
+		Timeout: time.Second * time.Duration(c.ServerKeepaliveTimeout),
+	}, true
+}
+
+// drainTimeout returns the time to wait for in-flight streams on shutdown, or
+// 0 if they are closed at once.
+func (c *Config) drainTimeout() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 0
+	}
+	return time.Second * time.Duration(c.DrainTimeout)
+}
+
+// windowSize returns size, or minWindowSize with a warning if size is smaller,
+// as gRPC ignores such windows.
+func windowSize(name string, size int32) int32 {
+	if size < minWindowSize {
+		newError(name, " ", size, " is too small, using ", minWindowSize).AtWarning().WriteToLog()
+		return minWindowSize
+	}
+	return size
+}
+
+// dialOptions returns the dial options for flow control and keepalive.
+func (c *Config) dialOptions() []grpc.DialOption {
+	var options []grpc.DialOption
+	if params, ok := c.clientKeepaliveParameters(); ok {
+		options = append(options, grpc.WithKeepaliveParams(params))
+	}
+	if c.InitialWindowsSize > 0 {
+		options = append(options, grpc.WithInitialWindowSize(windowSize("initial window size", c.InitialWindowsSize)))
+	}
+	if c.InitialConnWindowSize > 0 {
+		options = append(options, grpc.WithInitialConnWindowSize(windowSize("initial connection window size", c.InitialConnWindowSize)))
+	}
+	return options
+}
+
+// serverOptions returns the server options for flow control and keepalive.
+func (c *Config) serverOptions() []grpc.ServerOption {
+	var options []grpc.ServerOption
+	if params, ok := c.serverKeepaliveParameters(); ok {
+		options = append(options, grpc.KeepaliveParams(params))
+	}
+	if c.InitialWindowsSize > 0 {
+		options = append(options, grpc.InitialWindowSize(windowSize("initial window size", c.InitialWindowsSize)))
+	}
+	if c.InitialConnWindowSize > 0 {
+		options = append(options, grpc.InitialConnWindowSize(windowSize("initial connection window size", c.InitialConnWindowSize)))
+	}
+	return options
+}
@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+func TestCustomFlowControlAndKeepalive(t *testing.T) {
+	config := &Config{
+		ServiceName:            "options-test",
+		IdleTimeout:            30,
+		HealthCheckTimeout:     10,
+		InitialWindowsSize:     1 << 20,
+		InitialConnWindowSize:  4 << 20,
+		ServerKeepaliveTime:    60,
+		ServerKeepaliveTimeout: 15,
+	}
+	common.Must(config.Validate())
+
+	client, ok := config.clientKeepaliveParameters()
+	if !ok || client.Time != 30*time.Second || client.Timeout != 10*time.Second {
+		t.Error("unexpected client keepalive: ", client)
+	}
+	server, ok := config.serverKeepaliveParameters()
+	if !ok || server.Time != 60*time.Second || server.Timeout != 15*time.Second {
+		t.Error("unexpected server keepalive: ", server)
+	}
+	if n := len(config.dialOptions()); n != 3 {
+		t.Error("expect 3 dial options, but got ", n)
+	}
+	if n := len(config.serverOptions()); n != 3 {
+		t.Error("expect 3 server options, but got ", n)
+	}
+
+	port := tcp.PickPort()
+	settings := &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: config,
+	}
+	listener, err := Listen(context.Background(), net.LocalHostIP, port, settings, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			b := make([]byte, 4)
+			if _, err := conn.Read(b); err == nil {
+				conn.Write(b)
+			}
+		}()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, port), settings)
+	common.Must(err)
+	defer conn.Close()
+	common.Must2(conn.Write([]byte("ping")))
+	b := make([]byte, 4)
+	common.Must2(conn.Read(b))
+	if string(b) != "ping" {
+		t.Error("unexpected response: ", string(b))
+	}
+}
+
+func TestDefaultFlowControlAndKeepalive(t *testing.T) {
+	config := &Config{}
+	common.Must(config.Validate())
+	if _, ok := config.clientKeepaliveParameters(); ok {
+		t.Error("client keepalive should be disabled by default")
+	}
+	if _, ok := config.serverKeepaliveParameters(); ok {
+		t.Error("server keepalive should use gRPC defaults")
+	}
+	if len(config.dialOptions()) != 0 || len(config.serverOptions()) != 0 {
+		t.Error("expect no options by default")
+	}
+}
+
+func TestSmallWindowSize(t *testing.T) {
+	config := &Config{InitialWindowsSize: 1024, InitialConnWindowSize: 1}
+	common.Must(config.Validate())
+	if size := windowSize("initial window size", config.InitialWindowsSize); size != minWindowSize {
+		t.Error("expect window size to be raised to ", minWindowSize, ", but got ", size)
+	}
+	if size := windowSize("initial window size", 1<<20); size != 1<<20 {
+		t.Error("expect window size to be kept, but got ", size)
+	}
+	if n := len(config.dialOptions()); n != 2 {
+		t.Error("expect 2 dial options, but got ", n)
+	}
+}
+
+func TestInvalidFlowControlAndKeepalive(t *testing.T) {
+	for _, config := range []*Config{
+		{InitialWindowsSize: -1},
+		{InitialConnWindowSize: -1},
+		{IdleTimeout: -1},
+		{ServerKeepaliveTimeout: -1},
+		{DrainTimeout: -1},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Error("expect error for ", config)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"time"
+
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
@@ -40,6 +42,25 @@ func (c *Config) getNormalizedPath() string {
 	return c.Path
 }
 
+// Validate checks the health check settings.
+func (c *Config) Validate() error {
+	if c.ReadIdleTimeout < 0 {
+		return newError("negative read idle timeout: ", c.ReadIdleTimeout)
+	}
+	if c.PingTimeout < 0 {
+		return newError("negative ping timeout: ", c.PingTimeout)
+	}
+	return nil
+}
+
+func (c *Config) getReadIdleTimeout() time.Duration {
+	return time.Duration(c.ReadIdleTimeout) * time.Second
+}
+
+func (c *Config) getPingTimeout() time.Duration {
+	return time.Duration(c.PingTimeout) * time.Second
+}
+
 func init() {
 	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
 		return new(Config)
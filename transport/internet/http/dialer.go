@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/common"
@@ -17,28 +18,41 @@ import (
 	"golang.org/x/net/http2"
 )
 
+// dialerConf identifies the HTTP clients that can be shared between
+// connections.
+type dialerConf struct {
+	dest            net.Destination
+	readIdleTimeout time.Duration
+	pingTimeout     time.Duration
+}
+
 var (
-	globalDialerMap    map[net.Destination]*http.Client
+	globalDialerMap    map[dialerConf]*http.Client
 	globalDialerAccess sync.Mutex
 )
 
 type dialerCanceller func()
 
-func getHTTPClient(ctx context.Context, dest net.Destination, tlsSettings *tls.Config, streamSettings *internet.MemoryStreamConfig) (*http.Client, dialerCanceller) {
+func getHTTPClient(ctx context.Context, dest net.Destination, httpSettings *Config, tlsSettings *tls.Config, streamSettings *internet.MemoryStreamConfig) (*http.Client, dialerCanceller) {
 	globalDialerAccess.Lock()
 	defer globalDialerAccess.Unlock()
 
+	conf := dialerConf{
+		dest:            dest,
+		readIdleTimeout: httpSettings.getReadIdleTimeout(),
+		pingTimeout:     httpSettings.getPingTimeout(),
+	}
 	canceller := func() {
 		globalDialerAccess.Lock()
 		defer globalDialerAccess.Unlock()
-		delete(globalDialerMap, dest)
+		delete(globalDialerMap, conf)
 	}
 
 	if globalDialerMap == nil {
-		globalDialerMap = make(map[net.Destination]*http.Client)
+		globalDialerMap = make(map[dialerConf]*http.Client)
 	}
 
-	if client, found := globalDialerMap[dest]; found {
+	if client, found := globalDialerMap[conf]; found {
 		return client, canceller
 	}
 
@@ -78,25 +92,42 @@ func getHTTPClient(ctx context.Context, dest net.Destination, tlsSettings *tls.C
 			}
 			return cn, nil
 		},
-		TLSClientConfig: tlsSettings.GetTLSConfig(tls.WithDestination(dest)),
+		TLSClientConfig: tlsSettings.GetTLSConfigWithContext(ctx, tls.WithDestination(dest)),
+		ReadIdleTimeout: conf.readIdleTimeout,
+		PingTimeout:     conf.pingTimeout,
 	}
 
 	client := &http.Client{
 		Transport: transport,
 	}
 
-	globalDialerMap[dest] = client
+	globalDialerMap[conf] = client
 	return client, canceller
 }
 
+// requestBody interrupts the pipe when the HTTP client closes it. The client
+// does so when the underlying connection is lost, and only fails the response
+// body once it stops reading the request body.
+type requestBody struct {
+	*buf.BufferedReader
+}
+
+func (b *requestBody) Close() error {
+	b.Interrupt()
+	return nil
+}
+
 // Dial dials a new TCP connection to the given destination.
 func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.MemoryStreamConfig) (internet.Connection, error) {
 	httpSettings := streamSettings.ProtocolSettings.(*Config)
+	if err := httpSettings.Validate(); err != nil {
+		return nil, newError("invalid http transport config").Base(err)
+	}
 	tlsConfig := tls.ConfigFromStreamSettings(streamSettings)
 	if tlsConfig == nil {
 		return nil, newError("TLS must be enabled for http transport.").AtWarning()
 	}
-	client, canceller := getHTTPClient(ctx, dest, tlsConfig, streamSettings)
+	client, canceller := getHTTPClient(ctx, dest, httpSettings, tlsConfig, streamSettings)
 
 	opts := pipe.OptionsFromContext(ctx)
 	preader, pwriter := pipe.New(opts...)
@@ -118,7 +149,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 	request := &http.Request{
 		Method: httpMethod,
 		Host:   httpSettings.getRandomHost(),
-		Body:   breader,
+		Body:   &requestBody{BufferedReader: breader},
 		URL: &url.URL{
 			Scheme: "https",
 			Host:   dest.NetAddr(),
@@ -3,6 +3,10 @@ package http_test
 import (
 	"context"
 	"crypto/rand"
+	gotls "crypto/tls"
+	"io"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +19,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 	. "github.com/v2fly/v2ray-core/v5/transport/internet/http"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+	"golang.org/x/net/http2"
 )
 
 func TestHTTPConnection(t *testing.T) {
@@ -91,3 +96,98 @@ func TestHTTPConnection(t *testing.T) {
 		t.Error(r)
 	}
 }
+
+// stallingConn stops delivering received data once stalled, like a peer that
+// died without closing the connection.
+type stallingConn struct {
+	net.Conn
+	stalled chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (c *stallingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	select {
+	case <-c.stalled:
+		<-c.closed
+		return 0, io.EOF
+	default:
+		return n, err
+	}
+}
+
+func (c *stallingConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+func TestHTTPHealthCheck(t *testing.T) {
+	port := tcp.PickPort()
+	tlsConfig := (&tls.Config{
+		Certificate:  []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil, cert.CommonName("www.v2fly.org")))},
+		NextProtocol: []string{http2.NextProtoTLS},
+	}).GetTLSConfig()
+
+	listener, err := net.Listen("tcp", net.TCPDestination(net.LocalHostIP, port).NetAddr())
+	common.Must(err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			rawConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn := &stallingConn{
+				Conn:    rawConn,
+				stalled: make(chan struct{}),
+				closed:  make(chan struct{}),
+			}
+			tlsConn := gotls.Server(conn, tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				continue
+			}
+			server := &http2.Server{}
+			go server.ServeConn(tlsConn, &http2.ServeConnOpts{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+					w.(http.Flusher).Flush()
+					close(conn.stalled)
+					<-r.Context().Done()
+				}),
+			})
+		}
+	}()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName: "http",
+		ProtocolSettings: &Config{
+			ReadIdleTimeout: 1,
+			PingTimeout:     1,
+		},
+		SecurityType: "tls",
+		SecuritySettings: &tls.Config{
+			ServerName:    "www.v2fly.org",
+			AllowInsecure: true,
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1024))
+		readErr <- err
+	}()
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Error("expected read error from stalled connection")
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("stalled connection is not closed")
+	}
+}
@@ -2,6 +2,7 @@ package kcp
 
 import (
 	"crypto/cipher"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
@@ -60,12 +61,29 @@ func (c *Config) GetReadBufferSize() uint32 {
 
 // GetSecurity returns the security settings.
 func (c *Config) GetSecurity() (cipher.AEAD, error) {
+	if c.Seed != nil && c.Seed.RotationInterval > 0 {
+		interval := time.Duration(c.Seed.RotationInterval) * time.Second
+		return newSeedRotation(c.Seed.Seed, interval).newAEAD(), nil
+	}
 	if c.Seed != nil {
 		return NewAEADAESGCMBasedOnSeed(c.Seed.Seed), nil
 	}
 	return NewSimpleAuthenticator(), nil
 }
 
+// GetFECShards returns the numbers of data and parity shards of FEC groups,
+// or zeros if FEC is disabled.
+func (c *Config) GetFECShards() (int, int, error) {
+	if c == nil || c.Fec == nil {
+		return 0, 0, nil
+	}
+	dataShards, parityShards := c.Fec.DataShards, c.Fec.ParityShards
+	if dataShards == 0 || parityShards == 0 || dataShards+parityShards > 255 {
+		return 0, 0, newError("invalid FEC shards: ", dataShards, " data and ", parityShards, " parity")
+	}
+	return int(dataShards), int(parityShards), nil
+}
+
 func (c *Config) GetPackerHeader() (internet.PacketHeader, error) {
 	if c.HeaderConfig != nil {
 		rawConfig, err := serial.GetInstanceOf(c.HeaderConfig)
@@ -0,0 +1,108 @@
+package kcp
+
+// CongestionControl limits the number of segments in flight on a connection.
+type CongestionControl interface {
+	// OnRoundTrip is called with each RTT sample, in milliseconds.
+	OnRoundTrip(rtt uint32)
+	// OnPacketLoss is called after each flush with the percentage of
+	// segments that had to be retransmitted.
+	OnPacketLoss(lossRate uint32)
+	// Window returns the number of segments that may be in flight, given
+	// limit, the window allowed by the config and the peer.
+	Window(limit uint32) uint32
+}
+
+const minCongestionWindow = 16
+
+// NewCongestionControl creates the CongestionControl for algorithm, for a
+// connection that allows inFlightSize segments in flight.
+func NewCongestionControl(algorithm CongestionAlgorithm, inFlightSize uint32) CongestionControl {
+	switch algorithm {
+	case CongestionAlgorithm_DelayBased:
+		return &delayBasedCongestion{
+			window:    inFlightSize,
+			maxWindow: 2 * inFlightSize,
+		}
+	default:
+		return &lossBasedCongestion{
+			window:    inFlightSize,
+			maxWindow: 2 * inFlightSize,
+		}
+	}
+}
+
+func clampWindow(window, maxWindow uint32) uint32 {
+	if window < minCongestionWindow {
+		window = minCongestionWindow
+	}
+	if window > maxWindow {
+		window = maxWindow
+	}
+	return window
+}
+
+type lossBasedCongestion struct {
+	window    uint32
+	maxWindow uint32
+}
+
+func (c *lossBasedCongestion) OnRoundTrip(uint32) {}
+
+func (c *lossBasedCongestion) OnPacketLoss(lossRate uint32) {
+	if lossRate >= 15 {
+		c.window = 3 * c.window / 4
+	} else if lossRate <= 5 {
+		c.window += c.window / 4
+	}
+	c.window = clampWindow(c.window, c.maxWindow)
+}
+
+func (c *lossBasedCongestion) Window(limit uint32) uint32 {
+	if limit > c.window {
+		return c.window
+	}
+	return limit
+}
+
+// delayBasedCongestion estimates the queue built up along the path from the
+// difference between the current and the minimum RTT, in the spirit of
+// TCP Vegas, and grows the window while that queue stays short.
+type delayBasedCongestion struct {
+	window    uint32
+	maxWindow uint32
+	minRTT    uint32
+}
+
+func (c *delayBasedCongestion) OnRoundTrip(rtt uint32) {
+	if rtt == 0 {
+		rtt = 1
+	}
+	if c.minRTT == 0 || rtt < c.minRTT {
+		c.minRTT = rtt
+	}
+
+	// Segments queued in the network.
+	queued := c.window * (rtt - c.minRTT) / rtt
+	switch {
+	case queued < 4:
+		c.window += c.window/8 + 1
+	case queued > 16:
+		c.window -= queued / 4
+	}
+	c.window = clampWindow(c.window, c.maxWindow)
+}
+
+func (c *delayBasedCongestion) OnPacketLoss(lossRate uint32) {
+	// Random loss is expected on the links this is meant for. Only back off
+	// when most of the window is lost.
+	if lossRate >= 50 {
+		c.window = clampWindow(c.window/2, c.maxWindow)
+	}
+}
+
+func (c *delayBasedCongestion) Window(limit uint32) uint32 {
+	if limit > c.window {
+		return c.window
+	}
+	return limit
+}
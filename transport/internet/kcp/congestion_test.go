@@ -0,0 +1,87 @@
+package kcp_test
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/kcp"
+)
+
+// simulateLink runs a congestion controller for a number of round trips over
+// a link carrying capacity segments per round trip with a base RTT of 100ms
+// and random loss. It returns the number of segments delivered and the
+// average RTT.
+func simulateLink(cc CongestionControl, capacity uint32, lossPercent int, rounds int) (uint32, uint32) {
+	const baseRTT = 100
+	const limit = 1024
+
+	rng := rand.New(rand.NewSource(1))
+	var delivered, queue, totalRTT uint32
+	for i := 0; i < rounds; i++ {
+		window := cc.Window(limit)
+
+		queue += window
+		sent := queue
+		if sent > capacity {
+			sent = capacity
+		}
+		queue -= sent
+
+		var lost uint32
+		for j := uint32(0); j < sent; j++ {
+			if rng.Intn(100) < lossPercent {
+				lost++
+			}
+		}
+		delivered += sent - lost
+
+		rtt := baseRTT + queue*baseRTT/capacity
+		totalRTT += rtt
+		cc.OnPacketLoss(lost * 100 / window)
+		cc.OnRoundTrip(rtt)
+	}
+	return delivered, totalRTT / uint32(rounds)
+}
+
+func TestCongestionOnLossyLink(t *testing.T) {
+	lossDelivered, _ := simulateLink(NewCongestionControl(CongestionAlgorithm_LossBased, 128), 200, 20, 500)
+	delayDelivered, _ := simulateLink(NewCongestionControl(CongestionAlgorithm_DelayBased, 128), 200, 20, 500)
+
+	if delayDelivered < 2*lossDelivered {
+		t.Error("delay based congestion delivered ", delayDelivered, " segments, loss based ", lossDelivered)
+	}
+}
+
+func TestCongestionOnCleanLink(t *testing.T) {
+	lossDelivered, lossRTT := simulateLink(NewCongestionControl(CongestionAlgorithm_LossBased, 128), 200, 0, 500)
+	delayDelivered, delayRTT := simulateLink(NewCongestionControl(CongestionAlgorithm_DelayBased, 128), 200, 0, 500)
+
+	if delayDelivered*10 < lossDelivered*9 {
+		t.Error("delay based congestion delivered ", delayDelivered, " segments, loss based ", lossDelivered)
+	}
+	if delayRTT > lossRTT {
+		t.Error("delay based congestion built a longer queue: ", delayRTT, "ms vs ", lossRTT, "ms")
+	}
+}
+
+func TestCongestionWindowBounds(t *testing.T) {
+	for _, algorithm := range []CongestionAlgorithm{CongestionAlgorithm_LossBased, CongestionAlgorithm_DelayBased} {
+		cc := NewCongestionControl(algorithm, 64)
+		for i := 0; i < 100; i++ {
+			cc.OnPacketLoss(100)
+		}
+		if w := cc.Window(1024); w < 16 {
+			t.Error(algorithm, ": window shrank below minimum: ", w)
+		}
+		for i := 0; i < 100; i++ {
+			cc.OnPacketLoss(0)
+			cc.OnRoundTrip(100)
+		}
+		if w := cc.Window(1024); w > 128 {
+			t.Error(algorithm, ": window grew beyond maximum: ", w)
+		}
+		if w := cc.Window(10); w != 10 {
+			t.Error(algorithm, ": window exceeds limit: ", w)
+		}
+	}
+}
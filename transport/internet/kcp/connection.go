@@ -195,7 +195,10 @@ type Connection struct {
 	receivingWorker *ReceivingWorker
 	sendingWorker   *SendingWorker
 
-	output SegmentWriter
+	output       SegmentWriter
+	seedRotation *rotatingAEAD
+	fec          *fecWriter
+	fecDecoder   *fecDecoder
 
 	dataUpdater *Updater
 	pingUpdater *Updater
@@ -205,6 +208,17 @@ type Connection struct {
 func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, config *Config) *Connection {
 	newError("#", meta.Conversation, " creating connection to ", meta.RemoteAddr).WriteToLog()
 
+	var seedRotation *rotatingAEAD
+	if w, ok := writer.(*KCPPacketWriter); ok {
+		seedRotation, _ = w.Security.(*rotatingAEAD)
+	}
+	var fec *fecWriter
+	if dataShards, parityShards, err := config.GetFECShards(); err == nil && dataShards > 0 {
+		if fec, err = newFECWriter(writer, dataShards, parityShards); err == nil {
+			writer = fec
+		}
+	}
+
 	conn := &Connection{
 		meta:       meta,
 		closer:     closer,
@@ -218,6 +232,12 @@ func NewConnection(meta ConnMetadata, writer PacketWriter, closer io.Closer, con
 			rto:    100,
 			minRtt: config.GetTTIValue(),
 		},
+		seedRotation: seedRotation,
+	}
+
+	if fec != nil {
+		conn.fec = fec
+		conn.fecDecoder = newFECDecoder()
 	}
 
 	conn.receivingWorker = NewReceivingWorker(conn)
@@ -543,6 +563,33 @@ func (c *Connection) HandleOption(opt SegmentOption) {
 	if (opt & SegmentOptionClose) == SegmentOptionClose {
 		c.OnPeerClosed()
 	}
+	if (opt&SegmentOptionSeedRotation) == SegmentOptionSeedRotation && c.seedRotation != nil {
+		if c.seedRotation.confirm() {
+			newError("#", c.meta.Conversation, " peer supports seed rotation").AtDebug().WriteToLog()
+		}
+	}
+	if (opt&SegmentOptionFEC) == SegmentOptionFEC && c.fec != nil {
+		if c.fec.confirm() {
+			newError("#", c.meta.Conversation, " peer supports FEC").AtDebug().WriteToLog()
+		}
+	}
+}
+
+// segmentOption returns the option of outgoing segments. Rotation and FEC
+// are not announced on closing segments, as older peers only recognize those
+// when no other option is set.
+func (c *Connection) segmentOption() SegmentOption {
+	if c.State() == StateReadyToClose {
+		return SegmentOptionClose
+	}
+	var opt SegmentOption
+	if c.seedRotation != nil {
+		opt |= SegmentOptionSeedRotation
+	}
+	if c.fec != nil {
+		opt |= SegmentOptionFEC
+	}
+	return opt
 }
 
 func (c *Connection) OnPeerClosed() {
@@ -589,7 +636,7 @@ func (c *Connection) Input(segments []Segment) {
 					c.SetState(StateTerminated)
 				}
 			}
-			if seg.Option == SegmentOptionClose || seg.Command() == CommandTerminate {
+			if (seg.Option&SegmentOptionClose) == SegmentOptionClose || seg.Command() == CommandTerminate {
 				c.dataInput.Signal()
 				c.dataOutput.Signal()
 			}
@@ -597,6 +644,11 @@ func (c *Connection) Input(segments []Segment) {
 			c.receivingWorker.ProcessSendingNext(seg.SendingNext)
 			c.roundTrip.UpdatePeerRTO(seg.PeerRTO, current)
 			seg.Release()
+		case *FECSegment:
+			if c.fecDecoder != nil {
+				c.Input(c.fecDecoder.Decode(seg))
+			}
+			seg.Release()
 		default:
 		}
 	}
@@ -652,9 +704,7 @@ func (c *Connection) Ping(current uint32, cmd Command) {
 	seg.ReceivingNext = c.receivingWorker.NextNumber()
 	seg.SendingNext = c.sendingWorker.FirstUnacknowledged()
 	seg.PeerRTO = c.roundTrip.Timeout()
-	if c.State() == StateReadyToClose {
-		seg.Option = SegmentOptionClose
-	}
+	seg.Option = c.segmentOption()
 	c.output.Write(seg)
 	atomic.StoreUint32(&c.lastPingTime, current)
 	seg.Release()
@@ -48,6 +48,10 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	dest.Network = net.Network_UDP
 	newError("dialing mKCP to ", dest).WriteToLog()
 
+	if _, _, err := streamSettings.ProtocolSettings.(*Config).GetFECShards(); err != nil {
+		return nil, newError("failed to create FEC").Base(err)
+	}
+
 	rawConn, err := internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
 	if err != nil {
 		return nil, newError("failed to dial to dest: ", err).AtWarning().Base(err)
@@ -85,7 +89,7 @@ func DialKCP(ctx context.Context, dest net.Destination, streamSettings *internet
 	var iConn internet.Connection = session
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		iConn = tls.Client(iConn, config.GetTLSConfig(tls.WithDestination(dest)))
+		iConn = tls.Client(iConn, config.GetTLSConfigWithContext(ctx, tls.WithDestination(dest)))
 	} else if config := xtls.ConfigFromStreamSettings(streamSettings); config != nil {
 		iConn = xtls.Client(iConn, config.GetXTLSConfig(xtls.WithDestination(dest)))
 	}
@@ -0,0 +1,289 @@
+package kcp
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/klauspost/reedsolomon"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+)
+
+// fecGroupCacheSize is the number of recent groups a fecDecoder keeps shards of.
+const fecGroupCacheSize = 16
+
+// fecWriter sends packets as data shards of FEC groups, each followed by the
+// parity shards of its group, once the peer confirms that it supports FEC.
+// Until then packets are sent as they are. It is not safe for concurrent use,
+// which is guaranteed by the SegmentWriter on top of it.
+type fecWriter struct {
+	writer       PacketWriter
+	encoder      reedsolomon.Encoder
+	dataShards   int
+	parityShards int
+	confirmed    uint32
+
+	group  uint32
+	shards []*buf.Buffer
+	// encoded is reused to encode the shards of each group.
+	encoded [][]byte
+}
+
+func newFECWriter(writer PacketWriter, dataShards, parityShards int) (*fecWriter, error) {
+	encoder, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, newError("failed to create FEC encoder").Base(err)
+	}
+	return &fecWriter{
+		writer:       writer,
+		encoder:      encoder,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		encoded:      make([][]byte, dataShards+parityShards),
+	}, nil
+}
+
+// confirm switches to FEC packets. It returns true on the first call.
+func (w *fecWriter) confirm() bool {
+	return atomic.CompareAndSwapUint32(&w.confirmed, 0, 1)
+}
+
+func (w *fecWriter) isConfirmed() bool {
+	return atomic.LoadUint32(&w.confirmed) == 1
+}
+
+// Overhead implements PacketWriter. The FEC header is counted even before
+// confirmation, so that the size of segments doesn't change afterwards.
+func (w *fecWriter) Overhead() int {
+	return w.writer.Overhead() + FECSegmentOverhead + 2
+}
+
+func (w *fecWriter) Write(b []byte) (int, error) {
+	if !w.isConfirmed() || len(b) < 4 {
+		return w.writer.Write(b)
+	}
+
+	shard := buf.New()
+	binary.BigEndian.PutUint16(shard.Extend(2), uint16(len(b)))
+	shard.Write(b)
+
+	conv := binary.BigEndian.Uint16(b)
+	if err := w.writeShard(conv, SegmentOption(b[3]), len(w.shards), shard.Bytes()); err != nil {
+		shard.Release()
+		return 0, err
+	}
+	w.shards = append(w.shards, shard)
+	if len(w.shards) == w.dataShards {
+		w.writeParity(conv)
+	}
+	return len(b), nil
+}
+
+func (w *fecWriter) writeShard(conv uint16, opt SegmentOption, index int, shard []byte) error {
+	seg := &FECSegment{
+		Conv:         conv,
+		Option:       opt,
+		Group:        w.group,
+		Index:        byte(index),
+		DataShards:   byte(w.dataShards),
+		ParityShards: byte(w.parityShards),
+		Shard:        shard,
+	}
+	b := buf.New()
+	defer b.Release()
+
+	seg.Serialize(b.Extend(seg.ByteSize()))
+	_, err := w.writer.Write(b.Bytes())
+	return err
+}
+
+// writeParity sends the parity shards of the current group and starts the
+// next one. Data shards are padded to the longest one of the group. Lost
+// parity shards are not resent, as lost data is eventually retransmitted.
+func (w *fecWriter) writeParity(conv uint16) {
+	defer func() {
+		for i, shard := range w.shards {
+			shard.Release()
+			w.shards[i] = nil
+		}
+		for i := range w.encoded {
+			w.encoded[i] = nil
+		}
+		w.group++
+		w.shards = w.shards[:0]
+	}()
+
+	var size int32
+	for _, shard := range w.shards {
+		if shard.Len() > size {
+			size = shard.Len()
+		}
+	}
+	for _, shard := range w.shards {
+		padding := shard.Extend(size - shard.Len())
+		for i := range padding {
+			padding[i] = 0
+		}
+	}
+	for i := w.dataShards; i < len(w.encoded); i++ {
+		parity := buf.New()
+		parity.Extend(size)
+		w.shards = append(w.shards, parity)
+	}
+	for i, shard := range w.shards {
+		w.encoded[i] = shard.Bytes()
+	}
+	if err := w.encoder.Encode(w.encoded); err != nil {
+		newError("failed to encode FEC group ", w.group).Base(err).AtDebug().WriteToLog()
+		return
+	}
+	for i := w.dataShards; i < len(w.encoded); i++ {
+		if err := w.writeShard(conv, SegmentOptionFEC, i, w.encoded[i]); err != nil {
+			newError("failed to send FEC parity").Base(err).AtDebug().WriteToLog()
+			return
+		}
+	}
+}
+
+func padShard(shard []byte, size int) []byte {
+	if len(shard) >= size {
+		return shard
+	}
+	padded := make([]byte, size)
+	copy(padded, shard)
+	return padded
+}
+
+type fecGroup struct {
+	shards   [][]byte
+	received int
+	done     bool
+}
+
+// fecDecoder returns the segments in FEC shards. Data shards are returned as
+// soon as they arrive, and missing ones of a group are recovered once as many
+// shards as the group has data shards have arrived. The numbers of shards are
+// taken from each segment, so peers don't have to agree on them. It is not
+// safe for concurrent use.
+type fecDecoder struct {
+	encoders map[[2]byte]reedsolomon.Encoder
+	groups   map[uint32]*fecGroup
+	order    []uint32
+}
+
+func newFECDecoder() *fecDecoder {
+	return &fecDecoder{
+		encoders: make(map[[2]byte]reedsolomon.Encoder),
+		groups:   make(map[uint32]*fecGroup),
+	}
+}
+
+func (d *fecDecoder) encoder(dataShards, parityShards byte) reedsolomon.Encoder {
+	key := [2]byte{dataShards, parityShards}
+	if encoder, found := d.encoders[key]; found {
+		return encoder
+	}
+	encoder, err := reedsolomon.New(int(dataShards), int(parityShards))
+	if err != nil {
+		newError("failed to create FEC decoder").Base(err).AtDebug().WriteToLog()
+		return nil
+	}
+	d.encoders[key] = encoder
+	return encoder
+}
+
+func (d *fecDecoder) getGroup(seg *FECSegment) *fecGroup {
+	if g, found := d.groups[seg.Group]; found {
+		return g
+	}
+	g := &fecGroup{
+		shards: make([][]byte, int(seg.DataShards)+int(seg.ParityShards)),
+	}
+	d.groups[seg.Group] = g
+	d.order = append(d.order, seg.Group)
+	if len(d.order) > fecGroupCacheSize {
+		delete(d.groups, d.order[0])
+		d.order = d.order[1:]
+	}
+	return g
+}
+
+// Decode returns the segments of the given shard, and the ones recovered
+// with it.
+func (d *fecDecoder) Decode(seg *FECSegment) []Segment {
+	g := d.getGroup(seg)
+	total := int(seg.DataShards) + int(seg.ParityShards)
+	if g.done || len(g.shards) != total || g.shards[seg.Index] != nil {
+		return nil
+	}
+	g.shards[seg.Index] = seg.Shard
+	g.received++
+
+	var result []Segment
+	if !seg.IsParity() {
+		result = unwrapShard(seg.Shard)
+	}
+	if g.received < int(seg.DataShards) {
+		return result
+	}
+
+	var missing []int
+	for i := 0; i < int(seg.DataShards); i++ {
+		if g.shards[i] == nil {
+			missing = append(missing, i)
+		}
+	}
+	g.done = true
+	defer func() {
+		g.shards = nil
+	}()
+	if len(missing) == 0 {
+		return result
+	}
+
+	encoder := d.encoder(seg.DataShards, seg.ParityShards)
+	if encoder == nil {
+		return result
+	}
+	size := 0
+	for _, shard := range g.shards {
+		if len(shard) > size {
+			size = len(shard)
+		}
+	}
+	shards := make([][]byte, total)
+	for i, shard := range g.shards {
+		if shard != nil {
+			shards[i] = padShard(shard, size)
+		}
+	}
+	if err := encoder.ReconstructData(shards); err != nil {
+		newError("failed to recover FEC group ", seg.Group).Base(err).AtDebug().WriteToLog()
+		return result
+	}
+	for _, i := range missing {
+		result = append(result, unwrapShard(shards[i])...)
+	}
+	return result
+}
+
+// unwrapShard returns the segments in a data shard. Nested FEC segments are
+// dropped.
+func unwrapShard(shard []byte) []Segment {
+	if len(shard) < 2 {
+		return nil
+	}
+	size := int(binary.BigEndian.Uint16(shard))
+	if size > len(shard)-2 {
+		return nil
+	}
+	segments := readSegments(shard[2 : 2+size])
+	result := segments[:0]
+	for _, seg := range segments {
+		if seg.Command() == CommandFEC {
+			seg.Release()
+			continue
+		}
+		result = append(result, seg)
+	}
+	return result
+}
@@ -0,0 +1,282 @@
+package kcp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+type packetCollector struct {
+	packets [][]byte
+}
+
+func (*packetCollector) Overhead() int {
+	return 0
+}
+
+func (c *packetCollector) Write(b []byte) (int, error) {
+	c.packets = append(c.packets, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+// decodeFEC feeds the packets to a fecDecoder, and returns the numbers of the
+// data segments in them.
+func decodeFEC(packets [][]byte) []uint32 {
+	decoder := newFECDecoder()
+	var numbers []uint32
+	for _, packet := range packets {
+		for _, seg := range readSegments(packet) {
+			seg, ok := seg.(*FECSegment)
+			if !ok {
+				continue
+			}
+			for _, seg := range decoder.Decode(seg) {
+				if seg, ok := seg.(*DataSegment); ok {
+					numbers = append(numbers, seg.Number)
+				}
+			}
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+func writeDataSegments(t *testing.T, count int, dataShards, parityShards int) [][]byte {
+	collector := new(packetCollector)
+	writer, err := newFECWriter(collector, dataShards, parityShards)
+	common.Must(err)
+	writer.confirm()
+
+	segmentWriter := NewSegmentWriter(writer)
+	for i := 0; i < count; i++ {
+		seg := NewDataSegment()
+		seg.Conv = 1
+		seg.Number = uint32(i)
+		// Segments of different sizes are padded in parity shards.
+		common.Must2(seg.Data().ReadFullFrom(rand.Reader, int32(10+i*7)))
+		common.Must(segmentWriter.Write(seg))
+		seg.Release()
+	}
+	if n := count/dataShards*(dataShards+parityShards) + count%dataShards; len(collector.packets) != n {
+		t.Fatal("expect ", n, " packets, but got ", len(collector.packets))
+	}
+	return collector.packets
+}
+
+func TestFECRecoversLostPackets(t *testing.T) {
+	packets := writeDataSegments(t, 20, 4, 2)
+
+	// Lose two packets of every group of six, data or parity.
+	var received [][]byte
+	for i, packet := range packets {
+		switch i % 6 {
+		case 1, 3 + i/6%3:
+			continue
+		}
+		received = append(received, packet)
+	}
+
+	var expected []uint32
+	for i := 0; i < 20; i++ {
+		expected = append(expected, uint32(i))
+	}
+	if r := cmp.Diff(decodeFEC(received), expected); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestFECLossBeyondParity(t *testing.T) {
+	packets := writeDataSegments(t, 8, 4, 2)
+
+	// Lose three data packets of the first group.
+	received := append([][]byte{packets[3]}, packets[6:]...)
+	if r := cmp.Diff(decodeFEC(received), []uint32{3, 4, 5, 6, 7}); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestFECSegment(t *testing.T) {
+	seg := &FECSegment{
+		Conv:         1,
+		Option:       SegmentOptionFEC,
+		Group:        0x01020304,
+		Index:        5,
+		DataShards:   4,
+		ParityShards: 2,
+		Shard:        []byte("shard"),
+	}
+	b := make([]byte, seg.ByteSize())
+	seg.Serialize(b)
+
+	parsed, extra := ReadSegment(b)
+	if len(extra) != 0 {
+		t.Error("unexpected extra bytes: ", extra)
+	}
+	if r := cmp.Diff(parsed.(*FECSegment), seg); r != "" {
+		t.Error(r)
+	}
+
+	// Index out of range.
+	b[8] = 6
+	if parsed, _ := ReadSegment(b); parsed != nil {
+		t.Error("unexpected segment: ", parsed)
+	}
+}
+
+// fecTestLink delivers packets to a connection, dropping some of them.
+type fecTestLink struct {
+	packets chan []byte
+	drop    func(n int, b []byte) bool
+	count   int
+	fec     uint32
+	dropped uint32
+}
+
+func newFECTestLink(drop func(n int, b []byte) bool) *fecTestLink {
+	return &fecTestLink{
+		packets: make(chan []byte, 4096),
+		drop:    drop,
+	}
+}
+
+func (l *fecTestLink) Write(b []byte) (int, error) {
+	l.count++
+	if Command(b[2]) == CommandFEC {
+		atomic.AddUint32(&l.fec, 1)
+	}
+	if l.drop(l.count, b) {
+		atomic.AddUint32(&l.dropped, 1)
+	} else {
+		l.packets <- append([]byte(nil), b...)
+	}
+	return len(b), nil
+}
+
+func (l *fecTestLink) Close() error {
+	return nil
+}
+
+func (l *fecTestLink) deliver(conn *Connection) {
+	reader := new(KCPPacketReader)
+	for b := range l.packets {
+		if segments := reader.Read(b); len(segments) > 0 {
+			conn.Input(segments)
+		}
+	}
+}
+
+func (l *fecTestLink) fecPackets() uint32 {
+	return atomic.LoadUint32(&l.fec)
+}
+
+func (l *fecTestLink) droppedPackets() uint32 {
+	return atomic.LoadUint32(&l.dropped)
+}
+
+func dropEvery(k int) func(n int, b []byte) bool {
+	return func(n int, _ []byte) bool {
+		return n%k == 0
+	}
+}
+
+// dropLastDataShard drops the last data shard of each FEC group. It is
+// followed by the parity shards of the group right away, so it can always be
+// recovered.
+func dropLastDataShard(_ int, b []byte) bool {
+	seg, _ := ReadSegment(b)
+	fec, ok := seg.(*FECSegment)
+	return ok && !fec.IsParity() && fec.Index == fec.DataShards-1
+}
+
+// transfer sends a payload from a connection of clientConfig to one of
+// serverConfig, over links that drop packets as given. It returns the links,
+// and the number of segments the client has resent.
+func transfer(t *testing.T, clientConfig, serverConfig *Config, drop func(n int, b []byte) bool) (*fecTestLink, *fecTestLink, uint32) {
+	toServer := newFECTestLink(drop)
+	toClient := newFECTestLink(drop)
+	client := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{Writer: toServer}, toServer, clientConfig)
+	server := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{Writer: toClient}, toClient, serverConfig)
+	defer client.Terminate()
+	defer server.Terminate()
+	go toServer.deliver(server)
+	go toClient.deliver(client)
+
+	// Exchange a byte first, so that both sides know whether the peer
+	// supports FEC before the payload is sent.
+	b := make([]byte, 1)
+	client.SetReadDeadline(time.Now().Add(10 * time.Second))
+	server.SetReadDeadline(time.Now().Add(10 * time.Second))
+	common.Must2(client.Write(b))
+	common.Must2(io.ReadFull(server, b))
+	common.Must2(server.Write(b))
+	common.Must2(io.ReadFull(client, b))
+
+	payload := make([]byte, 256*1024)
+	common.Must2(rand.Read(payload))
+	go client.Write(payload)
+
+	received := make([]byte, len(payload))
+	if _, err := io.ReadFull(server, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, payload) {
+		t.Error("unexpected payload")
+	}
+	client.sendingWorker.Lock()
+	defer client.sendingWorker.Unlock()
+	return toServer, toClient, client.sendingWorker.window.Retransmits()
+}
+
+func TestFECConnection(t *testing.T) {
+	config := &Config{Fec: &FEC{DataShards: 10, ParityShards: 3}}
+	toServer, toClient, retransmits := transfer(t, config, config, dropLastDataShard)
+	if toServer.fecPackets() == 0 || toClient.fecPackets() == 0 {
+		t.Error("expect FEC packets in both directions, but got ", toServer.fecPackets(), " and ", toClient.fecPackets())
+	}
+	if toServer.droppedPackets() == 0 {
+		t.Error("expect data shards to be dropped")
+	}
+	if retransmits != 0 {
+		t.Error("expect lost data to be recovered without retransmission, but got ", retransmits, " retransmits")
+	}
+}
+
+func TestConnectionRetransmitsWithoutFEC(t *testing.T) {
+	_, _, retransmits := transfer(t, &Config{}, &Config{}, dropEvery(10))
+	if retransmits == 0 {
+		t.Error("expect retransmits over a lossy link without FEC")
+	}
+}
+
+func TestFECMismatchedShards(t *testing.T) {
+	toServer, toClient, _ := transfer(t, &Config{Fec: &FEC{DataShards: 10, ParityShards: 3}}, &Config{Fec: &FEC{DataShards: 4, ParityShards: 1}}, dropEvery(10))
+	if toServer.fecPackets() == 0 || toClient.fecPackets() == 0 {
+		t.Error("expect FEC packets in both directions, but got ", toServer.fecPackets(), " and ", toClient.fecPackets())
+	}
+}
+
+func TestFECFallback(t *testing.T) {
+	toServer, toClient, _ := transfer(t, &Config{Fec: &FEC{DataShards: 10, ParityShards: 3}}, &Config{}, func(int, []byte) bool { return false })
+	if toServer.fecPackets() != 0 || toClient.fecPackets() != 0 {
+		t.Error("unexpected FEC packets with a peer without FEC")
+	}
+}
+
+func TestInvalidFECShards(t *testing.T) {
+	for _, fec := range []*FEC{
+		{DataShards: 10},
+		{ParityShards: 3},
+		{DataShards: 200, ParityShards: 100},
+	} {
+		if _, _, err := (&Config{Fec: fec}).GetFECShards(); err == nil {
+			t.Error("expect error for ", fec)
+		}
+	}
+}
@@ -43,6 +43,10 @@ func (r *KCPPacketReader) Read(b []byte) []Segment {
 		}
 		b = out
 	}
+	return readSegments(b)
+}
+
+func readSegments(b []byte) []Segment {
 	var result []Segment
 	for len(b) > 0 {
 		seg, x := ReadSegment(b)
@@ -82,3 +82,44 @@ func TestDialAndListen(t *testing.T) {
 		t.Error("active connections: ", v)
 	}
 }
+
+func TestDialAndListenWithSeedRotation(t *testing.T) {
+	serverConfig := &Config{
+		Seed: &EncryptionSeed{Seed: "seed", RotationInterval: 60},
+	}
+	listener, err := NewListener(context.Background(), net.LocalHostIP, net.Port(0), &internet.MemoryStreamConfig{
+		ProtocolName:     "mkcp",
+		ProtocolSettings: serverConfig,
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+			common.Must2(io.Copy(c, c))
+		}(conn)
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	port := net.Port(listener.Addr().(*net.UDPAddr).Port)
+
+	for _, clientSeed := range []*EncryptionSeed{
+		{Seed: "seed", RotationInterval: 60},
+		{Seed: "seed"},
+	} {
+		clientConn, err := DialKCP(context.Background(), net.UDPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+			ProtocolName:     "mkcp",
+			ProtocolSettings: &Config{Seed: clientSeed},
+		})
+		common.Must(err)
+
+		clientSend := make([]byte, 256*1024)
+		common.Must2(rand.Read(clientSend))
+		go clientConn.Write(clientSend)
+
+		clientReceived := make([]byte, len(clientSend))
+		common.Must2(io.ReadFull(clientConn, clientReceived))
+		if r := cmp.Diff(clientReceived, clientSend); r != "" {
+			t.Error("rotation interval ", clientSeed.RotationInterval, ": ", r)
+		}
+		clientConn.Close()
+	}
+}
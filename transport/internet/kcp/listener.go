@@ -35,6 +35,9 @@ type Listener struct {
 
 func NewListener(ctx context.Context, address net.Address, port net.Port, streamSettings *internet.MemoryStreamConfig, addConn internet.ConnHandler) (*Listener, error) {
 	kcpSettings := streamSettings.ProtocolSettings.(*Config)
+	if _, _, err := kcpSettings.GetFECShards(); err != nil {
+		return nil, newError("failed to create FEC").Base(err).AtError()
+	}
 	header, err := kcpSettings.GetPackerHeader()
 	if err != nil {
 		return nil, newError("failed to create packet header").Base(err).AtError()
@@ -118,13 +121,18 @@ func (l *Listener) OnReceive(payload *buf.Buffer, src net.Destination) {
 			Port: int(src.Port),
 		}
 		localAddr := l.hub.Addr()
+		security := l.security
+		if rotating, ok := security.(*rotatingAEAD); ok {
+			// Each connection switches to rotating keys on its own.
+			security = rotating.rotation.newAEAD()
+		}
 		conn = NewConnection(ConnMetadata{
 			LocalAddr:    localAddr,
 			RemoteAddr:   remoteAddr,
 			Conversation: conv,
 		}, &KCPPacketWriter{
 			Header:   l.header,
-			Security: l.security,
+			Security: security,
 			Writer:   writer,
 		}, writer, l.config)
 		var netConn internet.Connection = conn
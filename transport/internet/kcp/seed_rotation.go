@@ -0,0 +1,157 @@
+package kcp
+
+import (
+	"crypto/cipher"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+)
+
+// seedRotation derives the keys used by rotating connections. Keys are
+// bound to epochs of the wall clock, so peers agree on the current key
+// without exchanging any state beyond the announcement of support.
+type seedRotation struct {
+	seed     string
+	interval time.Duration
+	now      func() time.Time
+	base     cipher.AEAD
+
+	access sync.Mutex
+	keys   atomic.Value // *epochKeys
+}
+
+// epochKeys are the keys of an epoch and of the ones adjacent to it.
+type epochKeys struct {
+	epoch    int64
+	previous cipher.AEAD
+	current  cipher.AEAD
+	next     cipher.AEAD
+}
+
+func newSeedRotation(seed string, interval time.Duration) *seedRotation {
+	return &seedRotation{
+		seed:     seed,
+		interval: interval,
+		now:      time.Now,
+		base:     NewAEADAESGCMBasedOnSeed(seed),
+	}
+}
+
+func (r *seedRotation) epoch() int64 {
+	return r.now().UnixNano() / int64(r.interval)
+}
+
+// currentKeys returns the keys around the current epoch. They are only
+// derived, under the lock, when the epoch changes.
+func (r *seedRotation) currentKeys() *epochKeys {
+	epoch := r.epoch()
+	if keys, _ := r.keys.Load().(*epochKeys); keys != nil && keys.epoch == epoch {
+		return keys
+	}
+
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	last, _ := r.keys.Load().(*epochKeys)
+	if last != nil && last.epoch == epoch {
+		return last
+	}
+	keys := &epochKeys{
+		epoch:    epoch,
+		previous: r.key(last, epoch-1),
+		current:  r.key(last, epoch),
+		next:     r.key(last, epoch+1),
+	}
+	r.keys.Store(keys)
+	return keys
+}
+
+// key returns the key of the given epoch, reusing the one in last if any.
+func (r *seedRotation) key(last *epochKeys, epoch int64) cipher.AEAD {
+	if last != nil {
+		switch epoch - last.epoch {
+		case -1:
+			return last.previous
+		case 0:
+			return last.current
+		case 1:
+			return last.next
+		}
+	}
+	return NewAEADAESGCMBasedOnSeed(r.seed + "#" + strconv.FormatInt(epoch, 10))
+}
+
+// newAEAD returns a cipher.AEAD for a single connection.
+func (r *seedRotation) newAEAD() *rotatingAEAD {
+	return &rotatingAEAD{rotation: r}
+}
+
+// rotatingAEAD seals with the key derived from the seed until the peer
+// confirms that it supports rotation, and with the key of the current epoch
+// afterwards. It opens packets sealed with either.
+type rotatingAEAD struct {
+	rotation  *seedRotation
+	confirmed uint32
+}
+
+// confirm switches sealing to the rotating keys. It returns true on the first
+// call.
+func (a *rotatingAEAD) confirm() bool {
+	return atomic.CompareAndSwapUint32(&a.confirmed, 0, 1)
+}
+
+func (a *rotatingAEAD) isConfirmed() bool {
+	return atomic.LoadUint32(&a.confirmed) == 1
+}
+
+func (a *rotatingAEAD) NonceSize() int {
+	return a.rotation.base.NonceSize()
+}
+
+func (a *rotatingAEAD) Overhead() int {
+	return a.rotation.base.Overhead()
+}
+
+func (a *rotatingAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if !a.isConfirmed() {
+		return a.rotation.base.Seal(dst, nonce, plaintext, additionalData)
+	}
+	return a.rotation.currentKeys().current.Seal(dst, nonce, plaintext, additionalData)
+}
+
+// Open tries the key of the current epoch, the key derived from the seed and
+// the keys of the adjacent epochs, in that order. As the plaintext may
+// overwrite the ciphertext, the ciphertext is copied before the first
+// attempt.
+func (a *rotatingAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	keys := a.rotation.currentKeys()
+	candidates := [...]cipher.AEAD{
+		keys.current,
+		a.rotation.base,
+		keys.previous,
+		keys.next,
+	}
+
+	var original buf.Buffer
+	if size := int32(len(ciphertext)); size > buf.DefaultSize() {
+		original = *buf.NewSize(size)
+	} else {
+		original = buf.StackNew()
+	}
+	defer original.Release()
+	original.Write(ciphertext)
+
+	var lastErr error
+	for _, key := range candidates {
+		out, err := key.Open(dst, nonce, ciphertext, additionalData)
+		if err == nil {
+			return out, nil
+		}
+		lastErr = err
+		copy(ciphertext, original.Bytes())
+	}
+	return nil, lastErr
+}
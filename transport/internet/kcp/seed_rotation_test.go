@@ -0,0 +1,157 @@
+package kcp
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestSeedRotation(clock *fakeClock) *seedRotation {
+	r := newSeedRotation("test seed", time.Minute)
+	r.now = clock.Now
+	return r
+}
+
+func seal(a cipher.AEAD, payload []byte) ([]byte, []byte) {
+	nonce := make([]byte, a.NonceSize())
+	common.Must2(rand.Read(nonce))
+	return nonce, a.Seal(nil, nonce, payload, nil)
+}
+
+func TestSeedRotationLockstep(t *testing.T) {
+	clientClock := &fakeClock{now: time.Unix(1600000000, 0)}
+	serverClock := &fakeClock{now: clientClock.now.Add(10 * time.Second)}
+	client := newTestSeedRotation(clientClock).newAEAD()
+	server := newTestSeedRotation(serverClock).newAEAD()
+	client.confirm()
+	server.confirm()
+
+	static := NewAEADAESGCMBasedOnSeed("test seed")
+	payload := []byte("mkcp payload")
+
+	for i := 0; i < 5; i++ {
+		nonce, sealed := seal(client, payload)
+		if _, err := static.Open(nil, nonce, sealed, nil); err == nil {
+			t.Fatal("rotated packet opened with the static key in round ", i)
+		}
+		opened, err := server.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			t.Fatal("failed to open client packet in round ", i, ": ", err)
+		}
+		if !bytes.Equal(opened, payload) {
+			t.Fatal("unexpected payload: ", opened)
+		}
+
+		nonce, sealed = seal(server, payload)
+		if _, err := client.Open(nil, nonce, sealed, nil); err != nil {
+			t.Fatal("failed to open server packet in round ", i, ": ", err)
+		}
+
+		clientClock.now = clientClock.now.Add(time.Minute)
+		serverClock.now = serverClock.now.Add(time.Minute)
+	}
+}
+
+func TestSeedRotationRejectsDistantEpoch(t *testing.T) {
+	clientClock := &fakeClock{now: time.Unix(1600000000, 0)}
+	serverClock := &fakeClock{now: clientClock.now.Add(3 * time.Minute)}
+	client := newTestSeedRotation(clientClock).newAEAD()
+	server := newTestSeedRotation(serverClock).newAEAD()
+	client.confirm()
+
+	nonce, sealed := seal(client, []byte("mkcp payload"))
+	if _, err := server.Open(nil, nonce, sealed, nil); err == nil {
+		t.Error("packet from a distant epoch is accepted")
+	}
+}
+
+func TestSeedRotationWithStaticPeer(t *testing.T) {
+	rotating := newSeedRotation("test seed", time.Minute).newAEAD()
+	static := NewAEADAESGCMBasedOnSeed("test seed")
+	payload := []byte("mkcp payload")
+
+	// Without confirmation, the rotating side keeps the static key.
+	nonce, sealed := seal(rotating, payload)
+	if _, err := static.Open(nil, nonce, sealed, nil); err != nil {
+		t.Fatal("static peer failed to open packet: ", err)
+	}
+
+	nonce, sealed = seal(static, payload)
+	opened, err := rotating.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil {
+		t.Fatal("failed to open packet from static peer: ", err)
+	}
+	if !bytes.Equal(opened, payload) {
+		t.Error("unexpected payload: ", opened)
+	}
+}
+
+type noOpCloser struct{}
+
+func (noOpCloser) Close() error {
+	return nil
+}
+
+func TestConnectionSeedRotationOption(t *testing.T) {
+	rotating := newSeedRotation("test seed", time.Minute).newAEAD()
+	conn := NewConnection(ConnMetadata{Conversation: 1}, &KCPPacketWriter{
+		Security: rotating,
+		Writer:   buf.DiscardBytes,
+	}, noOpCloser{}, &Config{})
+	defer conn.Terminate()
+
+	if opt := conn.segmentOption(); opt != SegmentOptionSeedRotation {
+		t.Error("unexpected segment option: ", opt)
+	}
+	if rotating.isConfirmed() {
+		t.Fatal("rotation confirmed before the peer announced it")
+	}
+
+	conn.HandleOption(0)
+	if rotating.isConfirmed() {
+		t.Fatal("rotation confirmed without announcement")
+	}
+	conn.HandleOption(SegmentOptionSeedRotation)
+	if !rotating.isConfirmed() {
+		t.Error("rotation not confirmed after announcement")
+	}
+
+	static := NewConnection(ConnMetadata{Conversation: 2}, &KCPPacketWriter{
+		Security: NewAEADAESGCMBasedOnSeed("test seed"),
+		Writer:   buf.DiscardBytes,
+	}, noOpCloser{}, &Config{})
+	defer static.Terminate()
+
+	if opt := static.segmentOption(); opt != 0 {
+		t.Error("unexpected segment option without rotation: ", opt)
+	}
+}
+
+func TestSeedRotationKeyCache(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	r := newTestSeedRotation(clock)
+
+	keys := r.currentKeys()
+	if r.currentKeys() != keys {
+		t.Error("expect keys to be cached within an epoch")
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	next := r.currentKeys()
+	if next.previous != keys.current || next.current != keys.next {
+		t.Error("expect keys of the previous epochs to be reused")
+	}
+}
@@ -18,12 +18,19 @@ const (
 	CommandTerminate Command = 2
 	// CommandPing indicates a ping.
 	CommandPing Command = 3
+	// CommandFEC indicates a FECSegment.
+	CommandFEC Command = 4
 )
 
 type SegmentOption byte
 
 const (
 	SegmentOptionClose SegmentOption = 1
+	// SegmentOptionSeedRotation announces that the sender accepts packets
+	// sealed with rotating keys.
+	SegmentOptionSeedRotation SegmentOption = 2
+	// SegmentOptionFEC announces that the sender accepts FEC packets.
+	SegmentOptionFEC SegmentOption = 4
 )
 
 type Segment interface {
@@ -273,6 +280,82 @@ func (s *CmdOnlySegment) Serialize(b []byte) {
 
 func (*CmdOnlySegment) Release() {}
 
+const (
+	FECSegmentOverhead = 11
+)
+
+// FECSegment is a shard of a FEC group. A data shard carries a packet of
+// other segments, prefixed by its length, and a parity shard carries the
+// parity of the data shards of its group. A FECSegment takes the rest of the
+// packet.
+type FECSegment struct {
+	Conv         uint16
+	Option       SegmentOption
+	Group        uint32
+	Index        byte
+	DataShards   byte
+	ParityShards byte
+	Shard        []byte
+}
+
+func NewFECSegment() *FECSegment {
+	return new(FECSegment)
+}
+
+func (s *FECSegment) parse(conv uint16, cmd Command, opt SegmentOption, buf []byte) (bool, []byte) {
+	s.Conv = conv
+	s.Option = opt
+	if len(buf) < 7 {
+		return false, nil
+	}
+
+	s.Group = binary.BigEndian.Uint32(buf)
+	buf = buf[4:]
+
+	s.Index = buf[0]
+	s.DataShards = buf[1]
+	s.ParityShards = buf[2]
+	buf = buf[3:]
+
+	if s.DataShards == 0 || s.ParityShards == 0 || int(s.Index) >= int(s.DataShards)+int(s.ParityShards) {
+		return false, nil
+	}
+	s.Shard = append([]byte(nil), buf...)
+
+	return true, nil
+}
+
+func (s *FECSegment) Conversation() uint16 {
+	return s.Conv
+}
+
+func (*FECSegment) Command() Command {
+	return CommandFEC
+}
+
+func (s *FECSegment) IsParity() bool {
+	return s.Index >= s.DataShards
+}
+
+func (s *FECSegment) ByteSize() int32 {
+	return FECSegmentOverhead + int32(len(s.Shard))
+}
+
+func (s *FECSegment) Serialize(b []byte) {
+	binary.BigEndian.PutUint16(b, s.Conv)
+	b[2] = byte(CommandFEC)
+	b[3] = byte(s.Option)
+	binary.BigEndian.PutUint32(b[4:], s.Group)
+	b[8] = s.Index
+	b[9] = s.DataShards
+	b[10] = s.ParityShards
+	copy(b[11:], s.Shard)
+}
+
+func (s *FECSegment) Release() {
+	s.Shard = nil
+}
+
 func ReadSegment(buf []byte) (Segment, []byte) {
 	if len(buf) < 4 {
 		return nil, nil
@@ -291,6 +374,8 @@ func ReadSegment(buf []byte) (Segment, []byte) {
 		seg = NewDataSegment()
 	case CommandACK:
 		seg = NewAckSegment()
+	case CommandFEC:
+		seg = NewFECSegment()
 	default:
 		seg = NewCmdOnlySegment()
 	}
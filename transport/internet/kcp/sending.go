@@ -3,6 +3,7 @@ package kcp
 import (
 	"container/list"
 	"sync"
+	"sync/atomic"
 
 	"github.com/v2fly/v2ray-core/v5/common/buf"
 )
@@ -10,6 +11,7 @@ import (
 type SendingWindow struct {
 	cache             *list.List
 	totalInFlightSize uint32
+	retransmits       uint32
 	writer            SegmentWriter
 	onPacketLoss      func(uint32)
 }
@@ -50,6 +52,11 @@ func (sw *SendingWindow) Push(number uint32, b *buf.Buffer) {
 	sw.cache.PushBack(seg)
 }
 
+// Retransmits returns the number of times segments have been resent.
+func (sw *SendingWindow) Retransmits() uint32 {
+	return atomic.LoadUint32(&sw.retransmits)
+}
+
 func (sw *SendingWindow) FirstNumber() uint32 {
 	return sw.cache.Front().Value.(*DataSegment).Number
 }
@@ -112,6 +119,7 @@ func (sw *SendingWindow) Flush(current uint32, rto uint32, maxInFlightSize uint3
 			sw.totalInFlightSize++
 		} else {
 			lost++
+			atomic.AddUint32(&sw.retransmits, 1)
 		}
 		segment.timeout = current + rto
 
@@ -157,7 +165,7 @@ type SendingWorker struct {
 	firstUnacknowledged        uint32
 	nextNumber                 uint32
 	remoteNextNumber           uint32
-	controlWindow              uint32
+	congestion                 CongestionControl
 	fastResend                 uint32
 	windowSize                 uint32
 	firstUnacknowledgedUpdated bool
@@ -169,9 +177,11 @@ func NewSendingWorker(kcp *Connection) *SendingWorker {
 		conn:             kcp,
 		fastResend:       2,
 		remoteNextNumber: 32,
-		controlWindow:    kcp.Config.GetSendingInFlightSize(),
 		windowSize:       kcp.Config.GetSendingBufferSize(),
 	}
+	if kcp.Config.Congestion {
+		worker.congestion = NewCongestionControl(kcp.Config.CongestionAlgorithm, kcp.Config.GetSendingInFlightSize())
+	}
 	worker.window = NewSendingWindow(worker, worker.OnPacketLoss)
 	return worker
 }
@@ -253,6 +263,9 @@ func (w *SendingWorker) ProcessSegment(current uint32, seg *AckSegment, rto uint
 		w.window.HandleFastAck(maxack, rto)
 		if current-seg.Timestamp < 10000 {
 			w.conn.roundTrip.Update(current-seg.Timestamp, current)
+			if w.congestion != nil {
+				w.congestion.OnRoundTrip(current - seg.Timestamp)
+			}
 		}
 	}
 }
@@ -279,30 +292,17 @@ func (w *SendingWorker) Write(seg Segment) error {
 
 	dataSeg.Conv = w.conn.meta.Conversation
 	dataSeg.SendingNext = w.firstUnacknowledged
-	dataSeg.Option = 0
-	if w.conn.State() == StateReadyToClose {
-		dataSeg.Option = SegmentOptionClose
-	}
+	dataSeg.Option = w.conn.segmentOption()
 
 	return w.conn.output.Write(dataSeg)
 }
 
 func (w *SendingWorker) OnPacketLoss(lossRate uint32) {
-	if !w.conn.Config.Congestion || w.conn.roundTrip.Timeout() == 0 {
+	if w.congestion == nil || w.conn.roundTrip.Timeout() == 0 {
 		return
 	}
 
-	if lossRate >= 15 {
-		w.controlWindow = 3 * w.controlWindow / 4
-	} else if lossRate <= 5 {
-		w.controlWindow += w.controlWindow / 4
-	}
-	if w.controlWindow < 16 {
-		w.controlWindow = 16
-	}
-	if w.controlWindow > 2*w.conn.Config.GetSendingInFlightSize() {
-		w.controlWindow = 2 * w.conn.Config.GetSendingInFlightSize()
-	}
+	w.congestion.OnPacketLoss(lossRate)
 }
 
 func (w *SendingWorker) Flush(current uint32) {
@@ -317,8 +317,8 @@ func (w *SendingWorker) Flush(current uint32) {
 	if cwnd > w.remoteNextNumber-w.firstUnacknowledged {
 		cwnd = w.remoteNextNumber - w.firstUnacknowledged
 	}
-	if w.conn.Config.Congestion && cwnd > w.controlWindow {
-		cwnd = w.controlWindow
+	if w.congestion != nil {
+		cwnd = w.congestion.Window(cwnd)
 	}
 
 	cwnd *= 20 // magic
@@ -42,6 +42,7 @@ func (c *sessionContext) openStream(destAddr net.Addr) (*interConn, error) {
 type clientSessions struct {
 	access   sync.Mutex
 	sessions map[net.Destination][]*sessionContext
+	store    SessionStore
 	cleanup  *task.Periodic
 }
 
@@ -114,7 +115,7 @@ func (s *clientSessions) cleanSessions() error {
 	return nil
 }
 
-func (s *clientSessions) openConnection(destAddr net.Addr, config *Config, tlsConfig *tls.Config, sockopt *internet.SocketConfig) (internet.Connection, error) {
+func (s *clientSessions) openConnection(ctx context.Context, destAddr net.Addr, config *Config, tlsConfig *tls.Config, sockopt *internet.SocketConfig) (internet.Connection, error) {
 	s.access.Lock()
 	defer s.access.Unlock()
 
@@ -146,6 +147,9 @@ func (s *clientSessions) openConnection(destAddr net.Addr, config *Config, tlsCo
 		return nil, err
 	}
 
+	// quic-go doesn't migrate connections. It always advertises
+	// disable_active_migration and doesn't validate new paths, so sessions are
+	// tied to the local address they are dialed from.
 	quicConfig := &quic.Config{
 		ConnectionIDLength:   12,
 		HandshakeIdleTimeout: time.Second * 8,
@@ -159,7 +163,19 @@ func (s *clientSessions) openConnection(destAddr net.Addr, config *Config, tlsCo
 		return nil, err
 	}
 
-	session, err := quic.DialContext(context.Background(), conn, destAddr, "", tlsConfig.GetTLSConfig(tls.WithDestination(dest)), quicConfig)
+	var session quic.Connection
+	tlsConf := tlsConfig.GetTLSConfigWithContext(ctx, tls.WithDestination(dest))
+	if config.EnableEarlyData {
+		quicConfig.TokenStore = s.store.TokenStore()
+		tlsConf.ClientSessionCache = &destinationSessionCache{
+			cache: s.store.SessionCache(),
+			dest:  dest.NetAddr(),
+		}
+		tlsConf.SessionTicketsDisabled = false
+		session, err = quic.DialEarlyContext(context.Background(), conn, destAddr, "", tlsConf, quicConfig)
+	} else {
+		session, err = quic.DialContext(context.Background(), conn, destAddr, "", tlsConf, quicConfig)
+	}
 	if err != nil {
 		conn.Close()
 		return nil, err
@@ -177,6 +193,7 @@ var client clientSessions
 
 func init() {
 	client.sessions = make(map[net.Destination][]*sessionContext)
+	client.store = NewMemorySessionStore(128)
 	client.cleanup = &task.Periodic{
 		Interval: time.Minute,
 		Execute:  client.cleanSessions,
@@ -209,7 +226,7 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 
 	config := streamSettings.ProtocolSettings.(*Config)
 
-	return client.openConnection(destAddr, config, tlsConfig, streamSettings.SocketSettings)
+	return client.openConnection(ctx, destAddr, config, tlsConfig, streamSettings.SocketSettings)
 }
 
 func init() {
@@ -0,0 +1,101 @@
+package quic
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/udp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+)
+
+func TestEarlyDataResumption(t *testing.T) {
+	t.Run("HoldEarlyData", func(t *testing.T) {
+		testEarlyDataResumption(t, false)
+	})
+	t.Run("ProcessEarlyData", func(t *testing.T) {
+		testEarlyDataResumption(t, true)
+	})
+}
+
+func testEarlyDataResumption(t *testing.T, processEarlyData bool) {
+	port := udp.PickPort()
+
+	listener, err := Listen(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: &Config{EnableEarlyData: true, ProcessEarlyData: processEarlyData},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			Certificate: []*tls.Certificate{
+				tls.ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.v2fly.org"))),
+			},
+		},
+	}, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	SetSessionStore(NewMemorySessionStore(4))
+	defer SetSessionStore(NewMemorySessionStore(128))
+
+	dest := net.UDPDestination(net.LocalHostIP, port)
+	settings := &internet.MemoryStreamConfig{
+		ProtocolName:     protocolName,
+		ProtocolSettings: &Config{EnableEarlyData: true},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			ServerName:    "www.v2fly.org",
+			AllowInsecure: true,
+		},
+	}
+
+	roundTrip := func() {
+		conn, err := Dial(context.Background(), dest, settings)
+		common.Must(err)
+		defer conn.Close()
+
+		common.Must2(conn.Write([]byte("ping")))
+		b := make([]byte, 4)
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != "ping" {
+			t.Fatal("unexpected response: ", string(b))
+		}
+	}
+
+	used0RTT := func() bool {
+		client.access.Lock()
+		defer client.access.Unlock()
+		sessions := client.sessions[dest]
+		return sessions[len(sessions)-1].session.ConnectionState().TLS.Used0RTT
+	}
+
+	closeSessions := func() {
+		client.access.Lock()
+		defer client.access.Unlock()
+		for _, s := range client.sessions[dest] {
+			common.Must(s.session.CloseWithError(0, ""))
+			common.Must(s.rawConn.Close())
+		}
+		delete(client.sessions, dest)
+	}
+
+	roundTrip()
+	if used0RTT() {
+		t.Error("first connection should not use 0-RTT")
+	}
+	closeSessions()
+
+	roundTrip()
+	if !used0RTT() {
+		t.Error("resumed connection did not use 0-RTT")
+	}
+	closeSessions()
+}
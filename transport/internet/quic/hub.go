@@ -13,15 +13,37 @@ import (
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 )
 
+// earlyListener adapts quic.EarlyListener to quic.Listener.
+type earlyListener struct {
+	quic.EarlyListener
+}
+
+func (l earlyListener) Accept(ctx context.Context) (quic.Connection, error) {
+	return l.EarlyListener.Accept(ctx)
+}
+
 // Listener is an internet.Listener that listens for TCP connections.
 type Listener struct {
 	rawConn  *sysConn
 	listener quic.Listener
 	done     *done.Instance
 	addConn  internet.ConnHandler
+
+	processEarlyData bool
 }
 
 func (l *Listener) acceptStreams(session quic.Connection) {
+	// Streams sent in 0-RTT can be replayed by an attacker, who can't complete
+	// the handshake though. Hold them until the handshake is done, so that
+	// replayed data never reaches the proxy.
+	if earlySession, ok := session.(quic.EarlyConnection); ok && !l.processEarlyData {
+		select {
+		case <-earlySession.HandshakeComplete().Done():
+		case <-session.Context().Done():
+			return
+		}
+	}
+
 	for {
 		stream, err := session.AcceptStream(context.Background())
 		if err != nil {
@@ -115,7 +137,16 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		return nil, err
 	}
 
-	qListener, err := quic.Listen(conn, tlsConfig.GetTLSConfig(), quicConfig)
+	var qListener quic.Listener
+	tlsConf := tlsConfig.GetTLSConfig()
+	if config.EnableEarlyData {
+		tlsConf.SessionTicketsDisabled = false
+		var eListener quic.EarlyListener
+		eListener, err = quic.ListenEarly(conn, tlsConf, quicConfig)
+		qListener = earlyListener{eListener}
+	} else {
+		qListener, err = quic.Listen(conn, tlsConf, quicConfig)
+	}
 	if err != nil {
 		conn.Close()
 		return nil, err
@@ -126,6 +157,8 @@ func Listen(ctx context.Context, address net.Address, port net.Port, streamSetti
 		rawConn:  conn,
 		listener: qListener,
 		addConn:  handler,
+
+		processEarlyData: config.ProcessEarlyData,
 	}
 
 	go listener.keepAccepting()
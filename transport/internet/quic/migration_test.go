@@ -0,0 +1,157 @@
+package quic_test
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/udp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/quic"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+)
+
+// rebindingRelay relays packets between a client and a server, and can change
+// the local address it sends to the server from, like a NAT rebinding changes
+// the address of the client.
+type rebindingRelay struct {
+	sync.Mutex
+	listener *net.UDPConn
+	server   *net.UDPAddr
+	upstream *net.UDPConn
+	client   net.Addr
+}
+
+func newRebindingRelay(server *net.UDPAddr) (*rebindingRelay, error) {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	if err != nil {
+		return nil, err
+	}
+	r := &rebindingRelay{listener: listener, server: server}
+	if err := r.rebind(); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	go r.relayToServer()
+	return r, nil
+}
+
+func (r *rebindingRelay) addr() *net.UDPAddr {
+	return r.listener.LocalAddr().(*net.UDPAddr)
+}
+
+// rebind closes the socket to the server, and sends from a new one.
+func (r *rebindingRelay) rebind() error {
+	upstream, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.LocalHostIP.IP()})
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	if r.upstream != nil {
+		r.upstream.Close()
+	}
+	r.upstream = upstream
+	r.Unlock()
+	go r.relayToClient(upstream)
+	return nil
+}
+
+func (r *rebindingRelay) relayToServer() {
+	b := make([]byte, 2048)
+	for {
+		n, addr, err := r.listener.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		r.Lock()
+		r.client = addr
+		upstream := r.upstream
+		r.Unlock()
+		upstream.WriteTo(b[:n], r.server)
+	}
+}
+
+func (r *rebindingRelay) relayToClient(upstream *net.UDPConn) {
+	b := make([]byte, 2048)
+	for {
+		n, _, err := upstream.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		r.Lock()
+		client := r.client
+		r.Unlock()
+		r.listener.WriteTo(b[:n], client)
+	}
+}
+
+func (r *rebindingRelay) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.upstream.Close()
+	return r.listener.Close()
+}
+
+func TestQuicConnectionWithoutMigration(t *testing.T) {
+	port := udp.PickPort()
+
+	listener, err := quic.Listen(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName:     "quic",
+		ProtocolSettings: &quic.Config{},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			Certificate: []*tls.Certificate{
+				tls.ParseCertificate(cert.MustGenerate(nil, cert.DNSNames("www.v2fly.org"))),
+			},
+		},
+	}, func(conn internet.Connection) {
+		go func() {
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	relay, err := newRebindingRelay(&net.UDPAddr{IP: net.LocalHostIP.IP(), Port: int(port)})
+	common.Must(err)
+	defer relay.Close()
+
+	conn, err := quic.Dial(context.Background(), net.UDPDestination(net.LocalHostIP, net.Port(relay.addr().Port)), &internet.MemoryStreamConfig{
+		ProtocolName:     "quic",
+		ProtocolSettings: &quic.Config{},
+		SecurityType:     "tls",
+		SecuritySettings: &tls.Config{
+			ServerName:    "www.v2fly.org",
+			AllowInsecure: true,
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	b1 := make([]byte, 1024)
+	common.Must2(rand.Read(b1))
+	b2 := make([]byte, len(b1))
+	common.Must2(conn.Write(b1))
+	common.Must(conn.SetReadDeadline(time.Now().Add(time.Second * 5)))
+	common.Must2(io.ReadFull(conn, b2))
+	if r := cmp.Diff(b2, b1); r != "" {
+		t.Fatal(r)
+	}
+
+	// The server keeps answering the old address, so the connection stops
+	// working once the address of the client changes.
+	common.Must(relay.rebind())
+	common.Must2(conn.Write(b1))
+	common.Must(conn.SetReadDeadline(time.Now().Add(time.Second * 2)))
+	if _, err := io.ReadFull(conn, b2); err == nil {
+		t.Error("expect connection not to migrate to the new address")
+	}
+}
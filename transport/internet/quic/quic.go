@@ -11,6 +11,13 @@ import (
 // * use bytespool in buffer_pool.go
 // * set MaxReceivePacketSize to 1452 - 32 (16 bytes auth, 16 bytes head)
 //
+// DATAGRAM frames (RFC 9221) are not enabled, and UDP traffic goes through
+// streams like everything else. Proxy protocols frame UDP packets into a
+// stream whose encoding depends on the order of the bytes, so sending it in
+// unreliable datagrams would break on the first lost one. Relaying UDP in
+// datagrams needs the proxies to hand self-contained packets to the transport.
+// It also needs quic-go to be updated first: v0.28.1 refuses to build with
+// Go 1.20 or later.
 //
 
 const (
@@ -0,0 +1,64 @@
+package quic
+
+import (
+	gotls "crypto/tls"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// SessionStore keeps TLS session tickets and QUIC address validation tokens
+// between connections, so that reconnecting to a server can use 0-RTT.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// SessionCache returns the cache of TLS session tickets.
+	SessionCache() gotls.ClientSessionCache
+	// TokenStore returns the store of QUIC address validation tokens.
+	TokenStore() quic.TokenStore
+}
+
+type memorySessionStore struct {
+	sessionCache gotls.ClientSessionCache
+	tokenStore   quic.TokenStore
+}
+
+func (s *memorySessionStore) SessionCache() gotls.ClientSessionCache {
+	return s.sessionCache
+}
+
+func (s *memorySessionStore) TokenStore() quic.TokenStore {
+	return s.tokenStore
+}
+
+// NewMemorySessionStore returns a SessionStore that keeps sessions of up to
+// capacity servers in memory.
+func NewMemorySessionStore(capacity int) SessionStore {
+	return &memorySessionStore{
+		sessionCache: gotls.NewLRUClientSessionCache(capacity),
+		tokenStore:   quic.NewLRUTokenStore(capacity, 4),
+	}
+}
+
+// SetSessionStore replaces the store used by the dialer for 0-RTT
+// resumption, for example with one backed by persistent storage.
+func SetSessionStore(store SessionStore) {
+	client.access.Lock()
+	defer client.access.Unlock()
+
+	client.store = store
+}
+
+// destinationSessionCache scopes session tickets to a single server. All QUIC
+// servers without TLS settings share the same internal server name, so the
+// TLS cache key alone is not enough to tell them apart.
+type destinationSessionCache struct {
+	cache gotls.ClientSessionCache
+	dest  string
+}
+
+func (c *destinationSessionCache) Get(sessionKey string) (*gotls.ClientSessionState, bool) {
+	return c.cache.Get(c.dest + "|" + sessionKey)
+}
+
+func (c *destinationSessionCache) Put(sessionKey string, cs *gotls.ClientSessionState) {
+	c.cache.Put(c.dest+"|"+sessionKey, cs)
+}
@@ -16,7 +16,7 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		switch config.Tfo {
 		case SocketConfig_Enable:
 			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN, TCP_FASTOPEN_CLIENT); err != nil {
-				return err
+				warnTFOFailure(newError("failed to set TCP_FASTOPEN, fallback to normal connect").Base(err))
 			}
 		case SocketConfig_Disable:
 			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN, 0); err != nil {
@@ -34,6 +34,10 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		}
 	}
 
+	if err := applyBufferSizeOptions(fd, config); err != nil {
+		return err
+	}
+
 	return nil
 }
 
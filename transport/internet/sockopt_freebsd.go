@@ -134,7 +134,7 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		switch config.Tfo {
 		case SocketConfig_Enable:
 			if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, unix.TCP_FASTOPEN, 1); err != nil {
-				return newError("failed to set TCP_FASTOPEN_CONNECT=1").Base(err)
+				warnTFOFailure(newError("failed to set TCP_FASTOPEN_CONNECT=1, fallback to normal connect").Base(err))
 			}
 		case SocketConfig_Disable:
 			if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, unix.TCP_FASTOPEN, 0); err != nil {
@@ -163,6 +163,10 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 			}
 		}
 	}
+
+	if err := applyBufferSizeOptions(fd, config); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -40,7 +40,21 @@ func bindAddr(fd uintptr, ip []byte, port uint32) error {
 	return syscall.Bind(int(fd), sockaddr)
 }
 
+func bindToDevice(fd uintptr, config *SocketConfig) error {
+	if config.BindToDevice == "" {
+		return nil
+	}
+	if err := unix.BindToDevice(int(fd), config.BindToDevice); err != nil {
+		return newError("failed to set SO_BINDTODEVICE=", config.BindToDevice).Base(err)
+	}
+	return nil
+}
+
 func applyOutboundSocketOptions(network string, address string, fd uintptr, config *SocketConfig) error {
+	if err := bindToDevice(fd, config); err != nil {
+		return err
+	}
+
 	if config.Mark != 0 {
 		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, int(config.Mark)); err != nil {
 			return newError("failed to set SO_MARK").Base(err)
@@ -50,8 +64,12 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 	if isTCPSocket(network) {
 		switch config.Tfo {
 		case SocketConfig_Enable:
+			// With TCP_FASTOPEN_CONNECT, connect returns at once and the first
+			// write is sent in the SYN. The kernel falls back to a normal
+			// handshake itself if it has no cookie of the server, or the SYN
+			// with data is dropped on the path.
 			if err := syscall.SetsockoptInt(int(fd), syscall.SOL_TCP, TCP_FASTOPEN_CONNECT, 1); err != nil {
-				return newError("failed to set TCP_FASTOPEN_CONNECT=1").Base(err)
+				warnTFOFailure(newError("failed to set TCP_FASTOPEN_CONNECT=1, fallback to normal connect").Base(err))
 			}
 		case SocketConfig_Disable:
 			if err := syscall.SetsockoptInt(int(fd), syscall.SOL_TCP, TCP_FASTOPEN_CONNECT, 0); err != nil {
@@ -78,10 +96,18 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		}
 	}
 
+	if err := applyBufferSizeOptions(fd, config); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig) error {
+	if err := bindToDevice(fd, config); err != nil {
+		return err
+	}
+
 	if config.Mark != 0 {
 		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, int(config.Mark)); err != nil {
 			return newError("failed to set SO_MARK").Base(err)
@@ -2,13 +2,16 @@ package internet_test
 
 import (
 	"context"
+	"io"
 	"syscall"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
 	. "github.com/v2fly/v2ray-core/v5/transport/internet"
+	"golang.org/x/sys/unix"
 )
 
 func TestSockOptMark(t *testing.T) {
@@ -40,3 +43,166 @@ func TestSockOptMark(t *testing.T) {
 	})
 	common.Must(err)
 }
+
+func TestSockOptBufferSize(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	const size = 8192
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{SendBufferSize: size, ReceiveBufferSize: size})
+	common.Must(err)
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	common.Must(err)
+	err = rawConn.Control(func(fd uintptr) {
+		for _, opt := range []int{syscall.SO_SNDBUF, syscall.SO_RCVBUF} {
+			v, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+			common.Must(err)
+			// Linux reports twice the requested value.
+			if v != size*2 {
+				t.Error("unexpected buffer size ", v, " want ", size*2)
+			}
+		}
+	})
+	common.Must(err)
+}
+
+func getTFOConnect(t *testing.T, conn net.Conn) int {
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	common.Must(err)
+	var v int
+	common.Must(rawConn.Control(func(fd uintptr) {
+		v, err = syscall.GetsockoptInt(int(fd), syscall.SOL_TCP, TCP_FASTOPEN_CONNECT)
+		if err != nil {
+			t.Skip("TCP_FASTOPEN_CONNECT is not supported: ", err)
+		}
+	}))
+	return v
+}
+
+func TestSockOptTFO(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	cases := []struct {
+		env     string
+		sockopt *SocketConfig
+		tfo     int
+	}{
+		{sockopt: &SocketConfig{Tfo: SocketConfig_Enable}, tfo: 1},
+		{sockopt: nil, tfo: 0},
+		{env: "enable", sockopt: nil, tfo: 1},
+		{env: "enable", sockopt: &SocketConfig{Tfo: SocketConfig_Disable}, tfo: 0},
+		{env: "disable", sockopt: &SocketConfig{Mark: 0}, tfo: 0},
+	}
+	for _, test := range cases {
+		t.Setenv("V2RAY_TCP_FASTOPEN", test.env)
+		dialer := DefaultSystemDialer{}
+		conn, err := dialer.Dial(context.Background(), nil, dest, test.sockopt)
+		common.Must(err)
+		if v := getTFOConnect(t, conn); v != test.tfo {
+			t.Error("env ", test.env, " sockopt ", test.sockopt, ": unexpected TCP_FASTOPEN_CONNECT ", v, " want ", test.tfo)
+		}
+		conn.Close()
+	}
+}
+
+func TestTCPFastOpenFallback(t *testing.T) {
+	// The server doesn't accept TFO, so the client falls back to a normal
+	// handshake.
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dialer := DefaultSystemDialer{}
+	for i := 0; i < 2; i++ {
+		conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{Tfo: SocketConfig_Enable})
+		common.Must(err)
+
+		payload := []byte("abcd")
+		common.Must2(conn.Write(payload))
+		b := make([]byte, len(payload))
+		common.Must2(io.ReadFull(conn, b))
+		if r := cmp.Diff(b, payload); r != "" {
+			t.Error(r)
+		}
+		conn.Close()
+	}
+}
+
+func TestSockOptSourceAddress(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{SourceAddress: []byte{127, 0, 0, 2}})
+	common.Must(err)
+	defer conn.Close()
+
+	if ip := conn.LocalAddr().(*net.TCPAddr).IP; !ip.Equal(net.IP{127, 0, 0, 2}) {
+		t.Error("unexpected local address ", ip)
+	}
+
+	if _, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{SourceAddress: net.ParseIP("::1")}); err == nil {
+		t.Error("expected an error of mismatched address families")
+	}
+}
+
+func TestSockOptBindToDevice(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{BindToDevice: "lo"})
+	common.Must(err)
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	common.Must(err)
+	common.Must(rawConn.Control(func(fd uintptr) {
+		device, err := unix.GetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE)
+		if err != nil {
+			t.Skip("SO_BINDTODEVICE is not supported: ", err)
+		}
+		if device == "" {
+			t.Skip("requires CAP_NET_RAW")
+		}
+		if device != "lo" {
+			t.Error("unexpected device ", device)
+		}
+	}))
+	if ip := conn.LocalAddr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		t.Error("unexpected local address ", ip)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package internet
+
+import (
+	"runtime"
+	"syscall"
+)
+
+func applyBufferSizeOptions(fd uintptr, config *SocketConfig) error {
+	if err := setBufferSize(fd, syscall.SO_SNDBUF, "SO_SNDBUF", config.SendBufferSize); err != nil {
+		return err
+	}
+	return setBufferSize(fd, syscall.SO_RCVBUF, "SO_RCVBUF", config.ReceiveBufferSize)
+}
+
+func setBufferSize(fd uintptr, opt int, name string, size int32) error {
+	if size == 0 {
+		return nil
+	}
+	if size < 0 {
+		return newError("invalid ", name, " size: ", size)
+	}
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, opt, int(size)); err != nil {
+		return newError("failed to set ", name).Base(err)
+	}
+	actual, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, opt)
+	if err != nil {
+		return nil
+	}
+	// Linux doubles the requested value to leave room for bookkeeping overhead.
+	if runtime.GOOS == "linux" {
+		actual /= 2
+	}
+	if actual != int(size) {
+		newError(name, " clamped by kernel from ", size, " to ", actual).AtWarning().WriteToLog()
+	}
+	return nil
+}
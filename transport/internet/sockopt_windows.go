@@ -23,7 +23,10 @@ func setTFO(fd syscall.Handle, settings SocketConfig_TCPFastOpenState) error {
 func applyOutboundSocketOptions(network string, address string, fd uintptr, config *SocketConfig) error {
 	if isTCPSocket(network) {
 		if err := setTFO(syscall.Handle(fd), config.Tfo); err != nil {
-			return err
+			if config.Tfo != SocketConfig_Enable {
+				return newError("failed to set TCP_FASTOPEN=0").Base(err)
+			}
+			warnTFOFailure(newError("failed to set TCP_FASTOPEN, fallback to normal connect").Base(err))
 		}
 		if config.TcpKeepAliveInterval > 0 {
 			if err := syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1); err != nil {
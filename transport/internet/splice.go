@@ -0,0 +1,82 @@
+package internet
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing/common/bufio"
+	"github.com/sagernet/sing/common/rw"
+	"github.com/sagernet/sing/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+// CopyConn relays data between conn and dest in both directions like
+// bufio.CopyConn, splicing each direction where possible.
+func CopyConn(ctx context.Context, conn net.Conn, dest net.Conn) error {
+	defer conn.Close()
+	defer dest.Close()
+	return task.Run(ctx, func() error {
+		defer rw.CloseRead(conn)
+		defer rw.CloseWrite(dest)
+		return copyStream(dest, conn)
+	}, func() error {
+		defer rw.CloseRead(dest)
+		defer rw.CloseWrite(conn)
+		return copyStream(conn, dest)
+	})
+}
+
+func copyStream(dst, src net.Conn) error {
+	if _, handled, err := Splice(dst, src); handled {
+		return err
+	}
+	_, err := bufio.Copy(dst, src)
+	return err
+}
+
+// Splice copies data from src to dst until EOF with TCPConn.ReadFrom, which
+// splices in the kernel on Linux, if both are TCP connections that are relayed
+// as they are, that is, with at most stat counters on top of them. Counters
+// are updated with the bytes copied once ReadFrom returns. It returns false
+// without copying anything if either connection transforms data, in which
+// case data has to be copied through buffers.
+func Splice(dst, src net.Conn) (written int64, handled bool, err error) {
+	dstConn, dstCounters := unwrapStatCounterConn(dst, false)
+	srcConn, srcCounters := unwrapStatCounterConn(src, true)
+	dstTCP, ok := dstConn.(*net.TCPConn)
+	if !ok {
+		return 0, false, nil
+	}
+	srcTCP, ok := srcConn.(*net.TCPConn)
+	if !ok {
+		return 0, false, nil
+	}
+	written, err = dstTCP.ReadFrom(srcTCP)
+	for _, counter := range srcCounters {
+		counter.Add(written)
+	}
+	for _, counter := range dstCounters {
+		counter.Add(written)
+	}
+	return written, true, err
+}
+
+// unwrapStatCounterConn returns the connection under the StatCounterConns on
+// top of conn, and their read or write counters.
+func unwrapStatCounterConn(conn net.Conn, read bool) (net.Conn, []stats.Counter) {
+	var counters []stats.Counter
+	for {
+		statConn, ok := conn.(*StatCounterConn)
+		if !ok {
+			return conn, counters
+		}
+		counter := statConn.WriteCounter
+		if read {
+			counter = statConn.ReadCounter
+		}
+		if counter != nil {
+			counters = append(counters, counter)
+		}
+		conn = statConn.Connection
+	}
+}
@@ -0,0 +1,127 @@
+package internet_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+// tcpPair returns the two ends of a TCP connection.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		common.Must(err)
+		accepted <- conn
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	t.Cleanup(func() { conn.Close() })
+	server := <-accepted
+	t.Cleanup(func() { server.Close() })
+	return conn.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+// xorConn is a connection that transforms the data read from it.
+type xorConn struct {
+	net.Conn
+}
+
+func (c xorConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	for i := range b[:n] {
+		b[i] ^= 0xff
+	}
+	return n, err
+}
+
+func TestSplice(t *testing.T) {
+	client, src := tcpPair(t)
+	dst, server := tcpPair(t)
+	uplink := new(stats.Counter)
+	downlink := new(stats.Counter)
+
+	payload := make([]byte, 4*1024*1024)
+	common.Must2(rand.Read(payload))
+	go func() {
+		common.Must2(client.Write(payload))
+		common.Must(client.Close())
+	}()
+
+	type result struct {
+		written int64
+		handled bool
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		written, handled, err := Splice(&StatCounterConn{Connection: dst, WriteCounter: downlink}, &StatCounterConn{Connection: src, ReadCounter: uplink})
+		done <- result{written, handled, err}
+	}()
+
+	received := make([]byte, len(payload))
+	common.Must2(io.ReadFull(server, received))
+	if !bytes.Equal(received, payload) {
+		t.Error("unexpected payload")
+	}
+	r := <-done
+	if !r.handled || r.err != nil || r.written != int64(len(payload)) {
+		t.Fatal("unexpected splice result: ", r.written, " ", r.handled, " ", r.err)
+	}
+	if uplink.Value() != int64(len(payload)) || downlink.Value() != int64(len(payload)) {
+		t.Error("unexpected counters: ", uplink.Value(), " ", downlink.Value())
+	}
+}
+
+func TestSpliceFallback(t *testing.T) {
+	_, src := tcpPair(t)
+	dst, _ := tcpPair(t)
+
+	if written, handled, err := Splice(dst, xorConn{src}); handled || written != 0 || err != nil {
+		t.Error("expected fallback for a transforming connection, but got ", written, " ", handled, " ", err)
+	}
+}
+
+func TestCopyConnFallback(t *testing.T) {
+	client, src := tcpPair(t)
+	dst, server := tcpPair(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- CopyConn(context.Background(), xorConn{src}, dst)
+	}()
+
+	payload := make([]byte, 64*1024)
+	common.Must2(rand.Read(payload))
+	common.Must2(client.Write(payload))
+	received := make([]byte, len(payload))
+	common.Must2(io.ReadFull(server, received))
+	for i := range received {
+		if received[i] != payload[i]^0xff {
+			t.Fatal("unexpected payload at ", i)
+		}
+	}
+
+	// Data of the other direction goes through untransformed.
+	common.Must2(server.Write([]byte("hello")))
+	b := make([]byte, 5)
+	common.Must2(io.ReadFull(client, b))
+	if string(b) != "hello" {
+		t.Error("unexpected response: ", string(b))
+	}
+
+	common.Must(client.Close())
+	common.Must(server.Close())
+	<-done
+}
@@ -14,6 +14,10 @@ var (
 	effectiveSystemDNSDialer SystemDialer = &DefaultSystemDialer{}
 )
 
+// defaultDialTimeout bounds the time to connect outbound connections, if the
+// socket settings don't set a timeout.
+const defaultDialTimeout = time.Second * 16
+
 type SystemDialer interface {
 	Dial(ctx context.Context, source net.Address, destination net.Destination, sockopt *SocketConfig) (net.Conn, error)
 }
@@ -44,7 +48,29 @@ func hasBindAddr(sockopt *SocketConfig) bool {
 	return sockopt != nil && len(sockopt.BindAddress) > 0 && sockopt.BindPort > 0
 }
 
+// sourceAddress returns the address to send from to dest, which is src, or the
+// source address of the socket settings if src is not set.
+func sourceAddress(src net.Address, dest net.Destination, sockopt *SocketConfig) (net.Address, error) {
+	if (src == nil || src == net.AnyIP) && sockopt != nil && len(sockopt.SourceAddress) > 0 {
+		src = net.IPAddress(sockopt.SourceAddress)
+		if src == nil {
+			return nil, newError("invalid source address: ", sockopt.SourceAddress)
+		}
+	}
+	if src == nil || src == net.AnyIP || !dest.Address.Family().IsIP() {
+		return src, nil
+	}
+	if src.Family() != dest.Address.Family() {
+		return nil, newError("source address ", src, " doesn't match the address family of ", dest)
+	}
+	return src, nil
+}
+
 func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest net.Destination, sockopt *SocketConfig) (net.Conn, error) {
+	src, err := sourceAddress(src, dest, sockopt)
+	if err != nil {
+		return nil, err
+	}
 	if dest.Network == net.Network_UDP && !hasBindAddr(sockopt) {
 		srcAddr := resolveSrcAddr(net.Network_UDP, src)
 		if srcAddr == nil {
@@ -66,12 +92,17 @@ func (d *DefaultSystemDialer) Dial(ctx context.Context, src net.Address, dest ne
 			Dest: destAddr,
 		}, nil
 	}
+	sockopt = withDefaultTFO(sockopt)
 	goStdKeepAlive := time.Duration(0)
 	if sockopt != nil && sockopt.TcpKeepAliveIdle != 0 {
 		goStdKeepAlive = time.Duration(-1)
 	}
+	timeout := defaultDialTimeout
+	if sockopt != nil && sockopt.DialTimeout > 0 {
+		timeout = time.Duration(sockopt.DialTimeout)
+	}
 	dialer := &net.Dialer{
-		Timeout:   time.Second * 16,
+		Timeout:   timeout,
 		LocalAddr: resolveSrcAddr(dest.Network, src),
 		KeepAlive: goStdKeepAlive,
 	}
@@ -0,0 +1,66 @@
+package internet_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+// blackhole returns a destination that never completes the handshake. It is a
+// listener with a full accept queue, so Linux drops the SYNs sent to it.
+func blackhole(t *testing.T) net.Destination {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	common.Must(err)
+	t.Cleanup(func() { syscall.Close(fd) })
+	common.Must(syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}))
+	common.Must(syscall.Listen(fd, 0))
+	sa, err := syscall.Getsockname(fd)
+	common.Must(err)
+	dest := net.TCPDestination(net.LocalHostIP, net.Port(sa.(*syscall.SockaddrInet4).Port))
+
+	conn, err := net.Dial("tcp", dest.NetAddr())
+	common.Must(err)
+	t.Cleanup(func() { conn.Close() })
+	return dest
+}
+
+func TestDialTimeout(t *testing.T) {
+	dest := blackhole(t)
+	dialer := DefaultSystemDialer{}
+
+	start := time.Now()
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{DialTimeout: int64(time.Millisecond * 200)})
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected dial to time out")
+	}
+	if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Fatal("expected timeout, but got ", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*200 || elapsed > time.Second*2 {
+		t.Error("expected dial to time out after 200ms, but took ", elapsed)
+	}
+}
+
+func TestDialTimeoutWithContextDeadline(t *testing.T) {
+	dest := blackhole(t)
+	dialer := DefaultSystemDialer{}
+
+	// The deadline of the context is shorter than the dial timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialer.Dial(ctx, nil, dest, &SocketConfig{DialTimeout: int64(time.Minute)})
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected dial to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Error("expected dial to time out with the context, but took ", elapsed)
+	}
+}
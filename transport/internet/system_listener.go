@@ -79,7 +79,11 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 
 	l, err = lc.Listen(ctx, network, address)
 	if sockopt != nil && sockopt.AcceptProxyProtocol {
-		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return proxyproto.REQUIRE, nil }
+		policy := proxyproto.REQUIRE
+		if sockopt.ProxyProtocolOptional {
+			policy = proxyproto.USE
+		}
+		policyFunc := func(upstream net.Addr) (proxyproto.Policy, error) { return policy, nil }
 		l = &proxyproto.Listener{Listener: l, Policy: policyFunc}
 	}
 	return l, err
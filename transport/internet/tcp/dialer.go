@@ -20,8 +20,16 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		return nil, err
 	}
 
+	tcpSettings := streamSettings.ProtocolSettings.(*Config)
+	if tcpSettings.SendProxyProtocol {
+		if _, err := proxyProtocolHeader(ctx).WriteTo(conn); err != nil {
+			conn.Close()
+			return nil, newError("failed to send PROXY protocol header").Base(err)
+		}
+	}
+
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
-		tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+		tlsConfig := config.GetTLSConfigWithContext(ctx, tls.WithDestination(dest))
 		/*
 			if config.IsExperiment8357() {
 				conn = tls.UClient(conn, tlsConfig)
@@ -34,7 +42,6 @@ func Dial(ctx context.Context, dest net.Destination, streamSettings *internet.Me
 		conn = xtls.Client(conn, config.GetXTLSConfig(xtls.WithDestination(dest)))
 	}
 
-	tcpSettings := streamSettings.ProtocolSettings.(*Config)
 	if tcpSettings.HeaderSettings != nil {
 		headerConfig, err := serial.GetInstanceOf(tcpSettings.HeaderSettings)
 		if err != nil {
@@ -0,0 +1,106 @@
+package tcp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
+)
+
+var proxyProtocolSignature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+func proxyProtocolV2(command, family byte, addresses ...[]byte) []byte {
+	var body []byte
+	for _, a := range addresses {
+		body = append(body, a...)
+	}
+	header := append([]byte(nil), proxyProtocolSignature...)
+	header = append(header, command, family, byte(len(body)>>8), byte(len(body)))
+	return append(header, body...)
+}
+
+func TestDialWithProxyProtocol(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	dest := net.DestinationFromAddr(listener.Addr())
+
+	testCases := []struct {
+		inbound *session.Inbound
+		header  []byte
+	}{
+		{
+			inbound: &session.Inbound{
+				Source:  net.TCPDestination(net.ParseAddress("192.0.2.1"), 1234),
+				Gateway: net.TCPDestination(net.ParseAddress("198.51.100.2"), 443),
+			},
+			header: proxyProtocolV2(0x21, 0x11,
+				[]byte{192, 0, 2, 1}, []byte{198, 51, 100, 2}, []byte{0x04, 0xD2, 0x01, 0xBB}),
+		},
+		{
+			inbound: &session.Inbound{
+				Source:  net.TCPDestination(net.ParseAddress("2001:db8::1"), 1234),
+				Gateway: net.TCPDestination(net.ParseAddress("2001:db8::2"), 443),
+			},
+			header: proxyProtocolV2(0x21, 0x21,
+				net.ParseAddress("2001:db8::1").IP(), net.ParseAddress("2001:db8::2").IP(), []byte{0x04, 0xD2, 0x01, 0xBB}),
+		},
+		{
+			inbound: &session.Inbound{
+				Source:  net.TCPDestination(net.ParseAddress("192.0.2.1"), 1234),
+				Gateway: net.TCPDestination(net.ParseAddress("2001:db8::2"), 443),
+			},
+			header: proxyProtocolV2(0x21, 0x21,
+				net.ParseAddress("::ffff:192.0.2.1").IP().To16(), net.ParseAddress("2001:db8::2").IP(), []byte{0x04, 0xD2, 0x01, 0xBB}),
+		},
+		{
+			inbound: nil,
+			header:  proxyProtocolV2(0x20, 0x00),
+		},
+		{
+			inbound: &session.Inbound{Tag: "bridge"},
+			header:  proxyProtocolV2(0x20, 0x00),
+		},
+	}
+
+	payload := []byte("payload")
+	for _, tc := range testCases {
+		ctx := context.Background()
+		if tc.inbound != nil {
+			ctx = session.ContextWithInbound(ctx, tc.inbound)
+		}
+
+		received := make(chan []byte, 1)
+		go func(size int) {
+			conn, err := listener.Accept()
+			common.Must(err)
+			defer conn.Close()
+			b := make([]byte, size)
+			common.Must2(io.ReadFull(conn, b))
+			received <- b
+		}(len(tc.header) + len(payload))
+
+		conn, err := Dial(ctx, dest, &internet.MemoryStreamConfig{
+			ProtocolName:     "tcp",
+			ProtocolSettings: &Config{SendProxyProtocol: true},
+		})
+		common.Must(err)
+		common.Must2(conn.Write(payload))
+
+		b := <-received
+		conn.Close()
+		if r := cmp.Diff(b[:len(tc.header)], tc.header); r != "" {
+			t.Error("unexpected header: ", r)
+		}
+		if !bytes.Equal(b[len(tc.header):], payload) {
+			t.Error("unexpected payload: ", b[len(tc.header):])
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pires/go-proxyproto"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
@@ -39,6 +40,9 @@ func ListenTCP(ctx context.Context, address net.Address, port net.Port, streamSe
 			streamSettings.SocketSettings = &internet.SocketConfig{}
 		}
 		streamSettings.SocketSettings.AcceptProxyProtocol = l.config.AcceptProxyProtocol
+		if l.config.ProxyProtocolOptional {
+			streamSettings.SocketSettings.ProxyProtocolOptional = true
+		}
 	}
 	var listener net.Listener
 	var err error
@@ -109,17 +113,39 @@ func (v *Listener) keepAccepting() {
 			continue
 		}
 
-		if v.tlsConfig != nil {
-			conn = tls.Server(conn, v.tlsConfig)
-		} else if v.xtlsConfig != nil {
-			conn = xtls.Server(conn, v.xtlsConfig)
-		}
-		if v.authConfig != nil {
-			conn = v.authConfig.Server(conn)
+		if proxyConn, ok := conn.(*proxyproto.Conn); ok {
+			go v.handleProxyProtocolConn(proxyConn)
+			continue
 		}
+		v.handleConn(conn)
+	}
+}
 
-		v.addConn(internet.Connection(conn))
+// handleProxyProtocolConn reads the PROXY protocol header of conn before
+// passing it on, so that its remote address is the one of the original
+// client. Connections with a malformed header are closed, and so are the ones
+// without a header unless it is optional.
+func (v *Listener) handleProxyProtocolConn(conn *proxyproto.Conn) {
+	// The first read parses the header, and returns the error of it if any.
+	if _, err := conn.Read(nil); err != nil {
+		newError("rejecting connection from ", conn.Raw().RemoteAddr(), " without valid PROXY protocol header").Base(err).AtWarning().WriteToLog()
+		conn.Close()
+		return
 	}
+	v.handleConn(conn)
+}
+
+func (v *Listener) handleConn(conn net.Conn) {
+	if v.tlsConfig != nil {
+		conn = tls.Server(conn, v.tlsConfig)
+	} else if v.xtlsConfig != nil {
+		conn = xtls.Server(conn, v.xtlsConfig)
+	}
+	if v.authConfig != nil {
+		conn = v.authConfig.Server(conn)
+	}
+
+	v.addConn(internet.Connection(conn))
 }
 
 // Addr implements internet.Listener.Addr.
@@ -0,0 +1,111 @@
+package tcp_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
+)
+
+func TestListenWithProxyProtocol(t *testing.T) {
+	t.Run("Required", func(t *testing.T) {
+		testListenWithProxyProtocol(t, false)
+	})
+	t.Run("Optional", func(t *testing.T) {
+		testListenWithProxyProtocol(t, true)
+	})
+}
+
+func testListenWithProxyProtocol(t *testing.T, optional bool) {
+	port := tcp.PickPort()
+	conns := make(chan internet.Connection, 1)
+	listener, err := ListenTCP(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName:     "tcp",
+		ProtocolSettings: &Config{AcceptProxyProtocol: true, ProxyProtocolOptional: optional},
+	}, func(conn internet.Connection) {
+		conns <- conn
+	})
+	common.Must(err)
+	defer listener.Close()
+
+	v2Header, err := proxyproto.HeaderProxyFromAddrs(2,
+		&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234},
+		&net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+	).Format()
+	common.Must(err)
+
+	testCases := []struct {
+		name     string
+		header   []byte
+		source   string
+		rejected bool
+	}{
+		{
+			name:   "v1",
+			header: []byte("PROXY TCP4 192.0.2.1 198.51.100.2 1234 443\r\n"),
+			source: "192.0.2.1:1234",
+		},
+		{
+			name:   "v2",
+			header: v2Header,
+			source: "[2001:db8::1]:1234",
+		},
+		{
+			name:     "malformed",
+			header:   []byte("PROXY TCP4 192.0.2.1\r\n"),
+			rejected: true,
+		},
+		{
+			name:     "missing",
+			rejected: !optional,
+		},
+	}
+
+	payload := []byte("payload")
+	for _, tc := range testCases {
+		client, err := net.Dial("tcp", net.TCPDestination(net.LocalHostIP, port).NetAddr())
+		common.Must(err)
+		common.Must2(client.Write(append(append([]byte(nil), tc.header...), payload...)))
+		if tc.source == "" {
+			tc.source = client.LocalAddr().String()
+		}
+
+		if tc.rejected {
+			client.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+				t.Error(tc.name, ": expected connection to be closed, but got ", err)
+			}
+			select {
+			case <-conns:
+				t.Error(tc.name, ": connection is accepted")
+			default:
+			}
+			client.Close()
+			continue
+		}
+
+		var conn internet.Connection
+		select {
+		case conn = <-conns:
+		case <-time.After(5 * time.Second):
+			t.Fatal(tc.name, ": connection is not accepted")
+		}
+		if addr := conn.RemoteAddr().String(); addr != tc.source {
+			t.Error(tc.name, ": unexpected remote address ", addr)
+		}
+		b := make([]byte, len(payload))
+		common.Must2(io.ReadFull(conn, b))
+		if string(b) != string(payload) {
+			t.Error(tc.name, ": unexpected payload ", string(b))
+		}
+		conn.Close()
+		client.Close()
+	}
+}
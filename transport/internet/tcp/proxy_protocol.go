@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"context"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+)
+
+// proxyProtocolHeader returns the PROXY protocol v2 header describing the
+// inbound connection of ctx. The destination is the local address of the
+// inbound connection, or the inbound gateway if the connection is not known.
+// A LOCAL header is returned if either address is unknown or not an IP
+// address, as for the reverse bridge which has no inbound connection.
+func proxyProtocolHeader(ctx context.Context) *proxyproto.Header {
+	header := &proxyproto.Header{
+		Version:           2,
+		Command:           proxyproto.LOCAL,
+		TransportProtocol: proxyproto.UNSPEC,
+	}
+
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil {
+		return header
+	}
+	source := inbound.Source
+	destination := inbound.Gateway
+	if inbound.Conn != nil {
+		if addr, ok := inbound.Conn.LocalAddr().(*net.TCPAddr); ok {
+			destination = net.DestinationFromAddr(addr)
+		}
+	}
+	if source.Address == nil || destination.Address == nil ||
+		!source.Address.Family().IsIP() || !destination.Address.Family().IsIP() {
+		return header
+	}
+
+	header.Command = proxyproto.PROXY
+	// Both addresses must be of the same family, IPv4 addresses are mapped
+	// to IPv6 when the other one is IPv6.
+	if source.Address.Family().IsIPv4() && destination.Address.Family().IsIPv4() {
+		header.TransportProtocol = proxyproto.TCPv4
+	} else {
+		header.TransportProtocol = proxyproto.TCPv6
+	}
+	header.SourceAddr = source.TCPAddr()
+	header.DestinationAddr = destination.TCPAddr()
+	return header
+}
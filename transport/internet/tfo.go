@@ -0,0 +1,53 @@
+package internet
+
+import (
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/platform"
+	"google.golang.org/protobuf/proto"
+)
+
+var tfoFlag = platform.NewEnvFlag("v2ray.tcp.fastopen")
+
+var tfoFailureOnce sync.Once
+
+// warnTFOFailure logs that TFO can't be enabled on outbound connections. It
+// is only logged for the first connection, as the others fail the same way.
+func warnTFOFailure(err *errors.Error) {
+	tfoFailureOnce.Do(func() {
+		err.AtWarning().WriteToLog()
+	})
+}
+
+// defaultTFOState returns the TFO state of outbound connections whose socket
+// settings leave it as is. It is set globally by the environment variable
+// v2ray.tcp.fastopen, to "enable" or "disable".
+func defaultTFOState() SocketConfig_TCPFastOpenState {
+	switch tfoFlag.GetValue(func() string { return "" }) {
+	case "enable", "true":
+		return SocketConfig_Enable
+	case "disable", "false":
+		return SocketConfig_Disable
+	default:
+		return SocketConfig_AsIs
+	}
+}
+
+// withDefaultTFO returns sockopt with the global TFO state applied, if sockopt
+// doesn't set one. sockopt itself is not modified.
+func withDefaultTFO(sockopt *SocketConfig) *SocketConfig {
+	if sockopt != nil && sockopt.Tfo != SocketConfig_AsIs {
+		return sockopt
+	}
+	state := defaultTFOState()
+	if state == SocketConfig_AsIs {
+		return sockopt
+	}
+	if sockopt == nil {
+		return &SocketConfig{Tfo: state}
+	}
+	sockopt = proto.Clone(sockopt).(*SocketConfig)
+	sockopt.Tfo = state
+	return sockopt
+}
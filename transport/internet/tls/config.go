@@ -1,6 +1,7 @@
 package tls
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/tls"
 	"crypto/x509"
@@ -44,19 +45,36 @@ func (c *Config) loadSelfCertPool(usage Certificate_Usage) (*x509.CertPool, erro
 
 // BuildCertificates builds a list of TLS certificates from proto definition.
 func (c *Config) BuildCertificates() []tls.Certificate {
+	certs, _ := c.buildCertificates()
+	return certs
+}
+
+// buildCertificates also returns the certificates that are loaded from files
+// and should be reloaded when the files change.
+func (c *Config) buildCertificates() ([]tls.Certificate, []*reloadableCertificate) {
 	certs := make([]tls.Certificate, 0, len(c.Certificate))
+	var reloadable []*reloadableCertificate
 	for _, entry := range c.Certificate {
 		if entry.Usage != Certificate_ENCIPHERMENT {
 			continue
 		}
-		keyPair, err := tls.X509KeyPair(entry.Certificate, entry.Key)
+		var keyPair tls.Certificate
+		var err error
+		if entry.ExternalKey != nil {
+			keyPair, err = externalKeyPair(entry.Certificate, entry.ExternalKey)
+		} else {
+			keyPair, err = tls.X509KeyPair(entry.Certificate, entry.Key)
+		}
 		if err != nil {
 			newError("ignoring invalid X509 key pair").Base(err).AtWarning().WriteToLog()
 			continue
 		}
 		certs = append(certs, keyPair)
+		if entry.CertificateFile != "" || entry.KeyFile != "" {
+			reloadable = append(reloadable, newReloadableCertificate(entry, keyPair))
+		}
 	}
-	return certs
+	return certs, reloadable
 }
 
 func isCertificateExpired(c *tls.Certificate) bool {
@@ -183,19 +201,52 @@ func (c *Config) parseServerName() string {
 
 func (c *Config) verifyPeerCert(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 	if c.PinnedPeerCertificateChainSha256 != nil {
-		hashValue := GenerateCertChainHash(rawCerts)
-		for _, v := range c.PinnedPeerCertificateChainSha256 {
+		if err := c.verifyPeerCertChain(rawCerts); err != nil {
+			return err
+		}
+	}
+	if c.PinnedPeerCertificatePublicKeySha256 != nil {
+		if err := c.verifyPeerPublicKey(rawCerts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) verifyPeerCertChain(rawCerts [][]byte) error {
+	hashValue := GenerateCertChainHash(rawCerts)
+	for _, v := range c.PinnedPeerCertificateChainSha256 {
+		if hmac.Equal(hashValue, v) {
+			return nil
+		}
+	}
+	return newError("peer cert is unrecognized: ", base64.StdEncoding.EncodeToString(hashValue))
+}
+
+func (c *Config) verifyPeerPublicKey(rawCerts [][]byte) error {
+	for _, rawCert := range rawCerts {
+		peerCert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return newError("failed to parse peer cert").Base(err)
+		}
+		hashValue := GenerateCertPublicKeyHash(peerCert)
+		for _, v := range c.PinnedPeerCertificatePublicKeySha256 {
 			if hmac.Equal(hashValue, v) {
 				return nil
 			}
 		}
-		return newError("peer cert is unrecognized: ", base64.StdEncoding.EncodeToString(hashValue))
 	}
-	return nil
+	return newError("peer public key is unrecognized")
 }
 
 // GetTLSConfig converts this Config into tls.Config.
 func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
+	return c.GetTLSConfigWithContext(context.Background(), opts...)
+}
+
+// GetTLSConfigWithContext converts this Config into tls.Config. ECH configs
+// are fetched through the routing of the V2Ray instance in ctx, if any.
+func (c *Config) GetTLSConfigWithContext(ctx context.Context, opts ...Option) *tls.Config {
 	root, err := c.getCertPool()
 	if err != nil {
 		newError("failed to load system root certificate").AtError().Base(err).WriteToLog()
@@ -230,13 +281,17 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 		opt(config)
 	}
 
-	config.Certificates = c.BuildCertificates()
+	var reloadable []*reloadableCertificate
+	config.Certificates, reloadable = c.buildCertificates()
 	config.BuildNameToCertificate()
 
 	caCerts := c.getCustomCA()
 	if len(caCerts) > 0 {
 		config.GetCertificate = getGetCertificateFunc(config, caCerts)
 	}
+	if len(reloadable) > 0 {
+		config.GetCertificate = getReloadableGetCertificateFunc(reloadable, config.GetCertificate)
+	}
 
 	if sn := c.parseServerName(); len(sn) > 0 {
 		config.ServerName = sn
@@ -249,6 +304,8 @@ func (c *Config) GetTLSConfig(opts ...Option) *tls.Config {
 	if c.VerifyClientCertificate {
 		config.ClientAuth = tls.RequireAndVerifyClientCert
 	}
+
+	c.applyECH(ctx, config)
 	return config
 }
 
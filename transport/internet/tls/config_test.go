@@ -3,6 +3,7 @@ package tls_test
 import (
 	gotls "crypto/tls"
 	"crypto/x509"
+	gonet "net"
 	"testing"
 	"time"
 
@@ -72,6 +73,53 @@ func TestInsecureCertificates(t *testing.T) {
 	}
 }
 
+func TestPinnedPeerCertificatePublicKey(t *testing.T) {
+	serverCert := cert.MustGenerate(nil, cert.CommonName("www.v2fly.org"), cert.DNSNames("www.v2fly.org"))
+	otherCert := cert.MustGenerate(nil, cert.CommonName("www.v2fly.org"), cert.DNSNames("www.v2fly.org"))
+	serverConfig := (&Config{
+		Certificate: []*Certificate{ParseCertificate(serverCert)},
+	}).GetTLSConfig()
+
+	publicKeyHash := func(c *cert.Certificate) []byte {
+		x509Cert, err := x509.ParseCertificate(c.Certificate)
+		common.Must(err)
+		return GenerateCertPublicKeyHash(x509Cert)
+	}
+
+	testCases := []struct {
+		name     string
+		insecure bool
+		pins     [][]byte
+		accepted bool
+	}{
+		{"match", true, [][]byte{publicKeyHash(otherCert), publicKeyHash(serverCert)}, true},
+		{"mismatch", true, [][]byte{publicKeyHash(otherCert)}, false},
+		{"untrusted", false, [][]byte{publicKeyHash(serverCert)}, false},
+	}
+	for _, tc := range testCases {
+		clientConfig := (&Config{
+			ServerName:                           "www.v2fly.org",
+			AllowInsecure:                        tc.insecure,
+			PinnedPeerCertificatePublicKeySha256: tc.pins,
+		}).GetTLSConfig()
+
+		clientRaw, serverRaw := gonet.Pipe()
+		go func() {
+			gotls.Server(serverRaw, serverConfig).Handshake()
+			serverRaw.Close()
+		}()
+		err := gotls.Client(clientRaw, clientConfig).Handshake()
+		clientRaw.Close()
+
+		if tc.accepted && err != nil {
+			t.Error(tc.name, ": handshake failed: ", err)
+		}
+		if !tc.accepted && err == nil {
+			t.Error(tc.name, ": expected handshake to be rejected")
+		}
+	}
+}
+
 func BenchmarkCertificateIssuing(b *testing.B) {
 	certificate := ParseCertificate(cert.MustGenerate(nil, cert.Authority(true), cert.KeyUsage(x509.KeyUsageCertSign)))
 	certificate.Usage = Certificate_AUTHORITY_ISSUE
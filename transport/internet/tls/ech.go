@@ -0,0 +1,65 @@
+//go:build go1.23
+// +build go1.23
+
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// applyECH enables Encrypted Client Hello on config if it is configured.
+func (c *Config) applyECH(ctx context.Context, config *tls.Config) {
+	if len(c.EchConfigList) == 0 && c.EchDnsServer == "" {
+		return
+	}
+
+	serverName := config.ServerName
+	configList := c.EchConfigList
+	if len(configList) == 0 {
+		var err error
+		configList, err = globalECHConfigCache.get(ctx, c.EchDnsServer, serverName)
+		if err != nil {
+			newError("failed to fetch ECH config of ", serverName).Base(err).AtWarning().WriteToLog()
+			// An empty list fails the handshake instead of sending the server
+			// name in clear.
+			configList = []byte{}
+		}
+	}
+	config.EncryptedClientHelloConfigList = configList
+	config.MinVersion = tls.VersionTLS13
+
+	insecure := config.InsecureSkipVerify
+	roots := config.RootCAs
+	config.EncryptedClientHelloRejectionVerify = func(state tls.ConnectionState) error {
+		newError("ECH is rejected by ", state.ServerName, " for ", serverName).AtWarning().WriteToLog()
+		if len(c.EchConfigList) == 0 {
+			globalECHConfigCache.invalidate(c.EchDnsServer, serverName)
+		}
+		if insecure {
+			return nil
+		}
+		return verifyECHPublicName(state, roots)
+	}
+}
+
+// verifyECHPublicName verifies the certificate presented for the public name
+// of the ECH config when the server rejects ECH.
+func verifyECHPublicName(state tls.ConnectionState, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return newError("no certificate from ECH provider")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       state.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		return newError("failed to verify certificate of ECH provider ", state.ServerName).Base(err)
+	}
+	return nil
+}
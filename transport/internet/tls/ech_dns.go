@@ -0,0 +1,239 @@
+package tls
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsTypeHTTPS  = dnsmessage.Type(65)
+	svcParamECH   = 5
+	echQueryLimit = 5 * time.Second
+	echMinTTL     = time.Minute
+)
+
+type echConfigEntry struct {
+	configList []byte
+	expire     time.Time
+}
+
+// echConfigCache keeps ECHConfigLists fetched over DNS, keyed by DNS server
+// and server name.
+type echConfigCache struct {
+	access  sync.Mutex
+	entries map[string]echConfigEntry
+}
+
+var globalECHConfigCache = &echConfigCache{
+	entries: make(map[string]echConfigEntry),
+}
+
+// get returns the cached ECHConfigList of serverName, or queries dnsServer
+// for it through the routing of the instance in ctx. The lock is not held
+// during the query, so lookups of other names and invalidations don't wait
+// for it.
+func (c *echConfigCache) get(ctx context.Context, dnsServer, serverName string) ([]byte, error) {
+	key := dnsServer + "|" + serverName
+
+	c.access.Lock()
+	entry, found := c.entries[key]
+	c.access.Unlock()
+	if found && time.Now().Before(entry.expire) {
+		return entry.configList, nil
+	}
+
+	if isECHQueryContext(ctx) {
+		// The query itself is sent over a connection that needs an ECH config.
+		return nil, newError("ECH config of ", serverName, " is needed to query ", dnsServer, ", route the DNS server through another outbound")
+	}
+	ctx, cancel := context.WithTimeout(contextWithECHQuery(core.ToBackgroundDetachedContext(ctx)), echQueryLimit)
+	defer cancel()
+	configList, ttl, err := queryECHConfigList(ctx, dnsServer, serverName)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < echMinTTL {
+		ttl = echMinTTL
+	}
+	expire := time.Now().Add(ttl)
+
+	c.access.Lock()
+	defer c.access.Unlock()
+	// Another query may have finished in the meantime with a later expiry.
+	if entry, found := c.entries[key]; found && entry.expire.After(expire) {
+		return entry.configList, nil
+	}
+	c.entries[key] = echConfigEntry{
+		configList: configList,
+		expire:     expire,
+	}
+	return configList, nil
+}
+
+// invalidate drops the cached ECHConfigList of serverName, so that the next
+// connection fetches it again.
+func (c *echConfigCache) invalidate(dnsServer, serverName string) {
+	c.access.Lock()
+	delete(c.entries, dnsServer+"|"+serverName)
+	c.access.Unlock()
+}
+
+// queryECHConfigList looks up the HTTPS record of serverName with a DNS over
+// HTTPS GET request and returns the ECHConfigList in it. The request is
+// dialed with echDialContext.
+func queryECHConfigList(ctx context.Context, dnsServer, serverName string) ([]byte, time.Duration, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(serverName, ".") + ".")
+	if err != nil {
+		return nil, 0, newError("invalid server name ", serverName).Base(err)
+	}
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  dnsTypeHTTPS,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, newError("failed to pack DNS query").Base(err)
+	}
+
+	url := dnsServer
+	if strings.Contains(url, "?") {
+		url += "&dns="
+	} else {
+		url += "?dns="
+	}
+	url += base64.RawURLEncoding.EncodeToString(packed)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, newError("invalid DNS server ", dnsServer).Base(err)
+	}
+	req.Header.Set("Accept", "application/dns-message")
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext:       echDialContext,
+			DisableKeepAlives: true,
+			ForceAttemptHTTP2: true,
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, newError("failed to query ", dnsServer).Base(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, newError("unexpected status ", resp.StatusCode, " from ", dnsServer)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, 0, newError("failed to read DNS response").Base(err)
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(body); err != nil {
+		return nil, 0, newError("failed to parse DNS response").Base(err)
+	}
+	for _, answer := range response.Answers {
+		if answer.Header.Type != dnsTypeHTTPS {
+			continue
+		}
+		resource, ok := answer.Body.(*dnsmessage.UnknownResource)
+		if !ok {
+			continue
+		}
+		if configList := parseHTTPSRecordECH(resource.Data); configList != nil {
+			return configList, time.Duration(answer.Header.TTL) * time.Second, nil
+		}
+	}
+	return nil, 0, newError("no ECH config in HTTPS record of ", serverName)
+}
+
+type echQueryKey struct{}
+
+func contextWithECHQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, echQueryKey{}, true)
+}
+
+// isECHQueryContext returns true if ctx belongs to a connection carrying an
+// ECH config query.
+func isECHQueryContext(ctx context.Context) bool {
+	_, ok := ctx.Value(echQueryKey{}).(bool)
+	return ok
+}
+
+// echDialContext dials the DNS server for ECH config queries through the
+// routing of the instance in ctx, or directly without an instance.
+func echDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dest, err := net.ParseDestination(network + ":" + addr)
+	if err != nil {
+		return nil, err
+	}
+	if core.FromContext(ctx) == nil {
+		return internet.DialSystem(ctx, dest, nil)
+	}
+
+	var dispatcher routing.Dispatcher
+	if err := core.RequireFeatures(ctx, func(d routing.Dispatcher) {
+		dispatcher = d
+	}); err != nil {
+		return nil, newError("failed to get dispatcher for ECH config queries").Base(err)
+	}
+	link, err := dispatcher.Dispatch(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+	return buf.NewConnection(buf.ConnectionInputMulti(link.Writer), buf.ConnectionOutputMulti(link.Reader)), nil
+}
+
+// parseHTTPSRecordECH returns the value of the ech parameter in the RDATA of
+// an HTTPS record, as defined in RFC 9460.
+func parseHTTPSRecordECH(data []byte) []byte {
+	if len(data) < 2 || binary.BigEndian.Uint16(data) == 0 {
+		// Alias mode records have no parameters.
+		return nil
+	}
+	data = data[2:]
+	// Target name, which is never compressed.
+	for {
+		if len(data) == 0 {
+			return nil
+		}
+		labelLen := int(data[0])
+		data = data[1:]
+		if labelLen == 0 {
+			break
+		}
+		if len(data) < labelLen {
+			return nil
+		}
+		data = data[labelLen:]
+	}
+	for len(data) >= 4 {
+		key := binary.BigEndian.Uint16(data)
+		valueLen := int(binary.BigEndian.Uint16(data[2:]))
+		data = data[4:]
+		if len(data) < valueLen {
+			return nil
+		}
+		if key == svcParamECH {
+			return data[:valueLen]
+		}
+		data = data[valueLen:]
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package tls
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/transport"
+)
+
+// recordingDispatcher records the destinations dispatched to it and rejects
+// them.
+type recordingDispatcher struct {
+	dests chan net.Destination
+}
+
+func (*recordingDispatcher) Type() interface{} { return routing.DispatcherType() }
+func (*recordingDispatcher) Start() error      { return nil }
+func (*recordingDispatcher) Close() error      { return nil }
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	d.dests <- dest
+	return nil, newError("rejected")
+}
+
+func (d *recordingDispatcher) DispatchLink(ctx context.Context, dest net.Destination, outbound *transport.Link) error {
+	return newError("rejected")
+}
+
+func (d *recordingDispatcher) DispatchConn(ctx context.Context, dest net.Destination, conn net.Conn, wait bool) error {
+	return newError("rejected")
+}
+
+func TestECHQueryThroughRouting(t *testing.T) {
+	instance, err := core.New(&core.Config{})
+	common.Must(err)
+	dispatcher := &recordingDispatcher{dests: make(chan net.Destination, 1)}
+	common.Must(instance.AddFeature(dispatcher))
+	ctx := core.WithContext(context.Background(), instance)
+
+	cache := &echConfigCache{entries: make(map[string]echConfigEntry)}
+	if _, err := cache.get(ctx, "https://doh.v2fly.org/dns-query", "www.v2fly.org"); err == nil {
+		t.Error("expected the query to fail with a rejecting dispatcher")
+	}
+	select {
+	case dest := <-dispatcher.dests:
+		if dest != net.TCPDestination(net.DomainAddress("doh.v2fly.org"), 443) {
+			t.Error("unexpected destination: ", dest)
+		}
+	default:
+		t.Error("expected the query dispatched through routing")
+	}
+}
+
+func TestECHQueryInsideQuery(t *testing.T) {
+	var requests int32
+	dnsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dnsServer.Close()
+
+	cache := &echConfigCache{entries: make(map[string]echConfigEntry)}
+	if _, err := cache.get(contextWithECHQuery(context.Background()), dnsServer.URL, "www.v2fly.org"); err == nil {
+		t.Error("expected an error for a query over a connection of another query")
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Error("expected no request, but got ", n)
+	}
+}
+
+func TestECHQueryWithoutLock(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	dnsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dnsServer.Close()
+
+	cache := &echConfigCache{entries: map[string]echConfigEntry{
+		dnsServer.URL + "|cached.v2fly.org": {
+			configList: []byte{1},
+			expire:     time.Now().Add(time.Minute),
+		},
+	}}
+	queryDone := make(chan struct{})
+	go func() {
+		defer close(queryDone)
+		cache.get(context.Background(), dnsServer.URL, "www.v2fly.org")
+	}()
+	defer func() {
+		close(release)
+		<-queryDone
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		t.Fatal("query not sent")
+	}
+
+	// The query is in progress, and the cache is still usable.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if configList, err := cache.get(context.Background(), dnsServer.URL, "cached.v2fly.org"); err != nil || len(configList) != 1 {
+			t.Error("unexpected cached ECHConfigList: ", configList, " ", err)
+		}
+		cache.invalidate(dnsServer.URL, "cached.v2fly.org")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("cache blocked by the query in progress")
+	}
+}
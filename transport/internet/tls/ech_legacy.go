@@ -0,0 +1,22 @@
+//go:build !go1.23
+// +build !go1.23
+
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// applyECH fails the handshake if ECH is configured, as it is not supported
+// by crypto/tls before Go 1.23.
+func (c *Config) applyECH(_ context.Context, config *tls.Config) {
+	if len(c.EchConfigList) == 0 && c.EchDnsServer == "" {
+		return
+	}
+	newError("ECH requires Go 1.23 or later").AtError().WriteToLog()
+	// No version is left to negotiate, so the handshake fails before the
+	// server name is sent in clear.
+	config.MinVersion = tls.VersionTLS13
+	config.MaxVersion = tls.VersionTLS12
+}
@@ -0,0 +1,226 @@
+//go:build go1.24
+// +build go1.24
+
+package tls_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	gonet "net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	echPublicName = "public.v2fly.org"
+	echSecretName = "secret.v2fly.org"
+)
+
+func appendUint16(b []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+// generateECHKey returns an ECH key and the ECHConfigList containing its
+// config, using DHKEM(X25519, HKDF-SHA256), HKDF-SHA256 and AES-128-GCM.
+func generateECHKey() (gotls.EncryptedClientHelloKey, []byte) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	common.Must(err)
+
+	var contents []byte
+	contents = append(contents, 1)
+	contents = appendUint16(contents, 0x0020)
+	contents = appendUint16(contents, uint16(len(key.PublicKey().Bytes())))
+	contents = append(contents, key.PublicKey().Bytes()...)
+	contents = appendUint16(contents, 4)
+	contents = appendUint16(contents, 0x0001)
+	contents = appendUint16(contents, 0x0001)
+	contents = append(contents, 0)
+	contents = append(contents, byte(len(echPublicName)))
+	contents = append(contents, echPublicName...)
+	contents = appendUint16(contents, 0)
+
+	config := appendUint16(nil, 0xfe0d)
+	config = appendUint16(config, uint16(len(contents)))
+	config = append(config, contents...)
+
+	configList := appendUint16(nil, uint16(len(config)))
+	configList = append(configList, config...)
+
+	return gotls.EncryptedClientHelloKey{
+		Config:     config,
+		PrivateKey: key.Bytes(),
+	}, configList
+}
+
+// recordingConn keeps the first bytes received by the server, which hold the
+// outer ClientHello.
+type recordingConn struct {
+	gonet.Conn
+	access   sync.Mutex
+	received []byte
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.access.Lock()
+	if len(c.received) < 4096 {
+		c.received = append(c.received, b[:n]...)
+	}
+	c.access.Unlock()
+	return n, err
+}
+
+func (c *recordingConn) clientHello() []byte {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return append([]byte(nil), c.received...)
+}
+
+// handshake runs a TLS handshake between clientConfig and serverConfig and
+// returns the server connection state, the bytes received by the server and
+// the client error.
+func handshake(clientConfig, serverConfig *gotls.Config) (gotls.ConnectionState, []byte, error) {
+	clientRaw, serverRaw := gonet.Pipe()
+	recorder := &recordingConn{Conn: serverRaw}
+	server := gotls.Server(recorder, serverConfig)
+	client := gotls.Client(clientRaw, clientConfig)
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		server.Handshake()
+		serverRaw.Close()
+	}()
+	err := client.Handshake()
+	clientRaw.Close()
+	<-serverDone
+	return server.ConnectionState(), recorder.clientHello(), err
+}
+
+func echServerConfig(keys ...gotls.EncryptedClientHelloKey) *gotls.Config {
+	certificate := ParseCertificate(cert.MustGenerate(nil, cert.CommonName(echPublicName), cert.DNSNames(echPublicName, echSecretName)))
+	return (&Config{
+		Certificate: []*Certificate{certificate},
+	}).GetTLSConfig(func(config *gotls.Config) {
+		config.EncryptedClientHelloKeys = keys
+	})
+}
+
+func TestECH(t *testing.T) {
+	key, configList := generateECHKey()
+
+	clientConfig := (&Config{
+		ServerName:    echSecretName,
+		AllowInsecure: true,
+		EchConfigList: configList,
+	}).GetTLSConfig()
+
+	state, clientHello, err := handshake(clientConfig, echServerConfig(key))
+	if err != nil {
+		t.Fatal("handshake failed: ", err)
+	}
+	if !state.ECHAccepted {
+		t.Error("ECH is not accepted")
+	}
+	if state.ServerName != echSecretName {
+		t.Error("unexpected inner server name: ", state.ServerName)
+	}
+	if !bytes.Contains(clientHello, []byte(echPublicName)) {
+		t.Error("public name not found in outer ClientHello")
+	}
+	if bytes.Contains(clientHello, []byte(echSecretName)) {
+		t.Error("real server name is sent in clear")
+	}
+}
+
+func TestECHRejected(t *testing.T) {
+	_, configList := generateECHKey()
+
+	clientConfig := (&Config{
+		ServerName:    echSecretName,
+		AllowInsecure: true,
+		EchConfigList: configList,
+	}).GetTLSConfig()
+
+	_, clientHello, err := handshake(clientConfig, echServerConfig())
+	var rejection *gotls.ECHRejectionError
+	if !errors.As(err, &rejection) {
+		t.Error("expected ECH rejection, but got ", err)
+	}
+	if bytes.Contains(clientHello, []byte(echSecretName)) {
+		t.Error("real server name is sent in clear")
+	}
+}
+
+func TestECHFromDNS(t *testing.T) {
+	_, configList := generateECHKey()
+
+	var rdata []byte
+	rdata = appendUint16(rdata, 1)
+	rdata = append(rdata, 0)
+	rdata = appendUint16(rdata, 1) // alpn
+	rdata = appendUint16(rdata, 3)
+	rdata = append(rdata, 2, 'h', '2')
+	rdata = appendUint16(rdata, 5) // ech
+	rdata = appendUint16(rdata, uint16(len(configList)))
+	rdata = append(rdata, configList...)
+
+	dnsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		packed, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		common.Must(err)
+		var query dnsmessage.Message
+		common.Must(query.Unpack(packed))
+
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+		}
+		if query.Questions[0].Name.String() == echSecretName+"." {
+			response.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{
+					Name:  query.Questions[0].Name,
+					Type:  query.Questions[0].Type,
+					Class: dnsmessage.ClassINET,
+					TTL:   300,
+				},
+				Body: &dnsmessage.UnknownResource{
+					Type: query.Questions[0].Type,
+					Data: rdata,
+				},
+			}}
+		}
+		b, err := response.Pack()
+		common.Must(err)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(b)
+	}))
+	defer dnsServer.Close()
+
+	tlsConfig := (&Config{
+		ServerName:   echSecretName,
+		EchDnsServer: dnsServer.URL + "/dns-query",
+	}).GetTLSConfig()
+	if !bytes.Equal(tlsConfig.EncryptedClientHelloConfigList, configList) {
+		t.Error("unexpected ECHConfigList: ", tlsConfig.EncryptedClientHelloConfigList)
+	}
+
+	tlsConfig = (&Config{
+		ServerName:   "www.v2fly.org",
+		EchDnsServer: dnsServer.URL + "/dns-query",
+	}).GetTLSConfig()
+	if tlsConfig.EncryptedClientHelloConfigList == nil || len(tlsConfig.EncryptedClientHelloConfigList) != 0 {
+		t.Error("expected empty ECHConfigList without HTTPS record, but got ", tlsConfig.EncryptedClientHelloConfigList)
+	}
+}
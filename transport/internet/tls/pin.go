@@ -2,6 +2,7 @@ package tls
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 )
@@ -34,3 +35,9 @@ func GenerateCertChainHash(rawCerts [][]byte) []byte {
 	}
 	return hashValue
 }
+
+// GenerateCertPublicKeyHash returns the sha256 hash of the subject public key info of cert.
+func GenerateCertPublicKeyHash(cert *x509.Certificate) []byte {
+	out := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return out[:]
+}
@@ -0,0 +1,125 @@
+package tls
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateCheckInterval is the minimum interval between two checks of the
+// certificate files.
+var certificateCheckInterval = time.Second * 10
+
+// reloadableCertificate is a certificate loaded from files. It is reloaded
+// when the files are modified.
+type reloadableCertificate struct {
+	entry *Certificate
+
+	access      sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+func newReloadableCertificate(entry *Certificate, certificate tls.Certificate) *reloadableCertificate {
+	r := &reloadableCertificate{
+		entry:       entry,
+		certificate: &certificate,
+		lastCheck:   time.Now(),
+	}
+	r.certModTime, _ = modTime(entry.CertificateFile)
+	r.keyModTime, _ = modTime(entry.KeyFile)
+	return r
+}
+
+func modTime(filename string) (time.Time, error) {
+	if filename == "" {
+		return time.Time{}, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func readFile(filename string, fallback []byte) ([]byte, error) {
+	if filename == "" {
+		return fallback, nil
+	}
+	return os.ReadFile(filename)
+}
+
+// get returns the current certificate, reloading it first if the files are
+// modified. The old certificate is kept until both files form a valid pair
+// again, as they may not be updated at the same time.
+func (r *reloadableCertificate) get() *tls.Certificate {
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	if time.Since(r.lastCheck) < certificateCheckInterval {
+		return r.certificate
+	}
+	r.lastCheck = time.Now()
+
+	certModTime, err := modTime(r.entry.CertificateFile)
+	if err != nil {
+		newError("failed to stat certificate file ", r.entry.CertificateFile).Base(err).AtWarning().WriteToLog()
+		return r.certificate
+	}
+	keyModTime, err := modTime(r.entry.KeyFile)
+	if err != nil {
+		newError("failed to stat key file ", r.entry.KeyFile).Base(err).AtWarning().WriteToLog()
+		return r.certificate
+	}
+	if certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.certificate
+	}
+
+	certificate, err := r.load()
+	if err != nil {
+		newError("failed to reload certificate ", r.entry.CertificateFile, ", keeping the old one").Base(err).AtWarning().WriteToLog()
+		return r.certificate
+	}
+	newError("certificate ", r.entry.CertificateFile, " reloaded").AtInfo().WriteToLog()
+	r.certificate = &certificate
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	return r.certificate
+}
+
+func (r *reloadableCertificate) load() (tls.Certificate, error) {
+	certPEM, err := readFile(r.entry.CertificateFile, r.entry.Certificate)
+	if err != nil {
+		return tls.Certificate{}, newError("failed to read certificate").Base(err)
+	}
+	if r.entry.ExternalKey != nil {
+		return externalKeyPair(certPEM, r.entry.ExternalKey)
+	}
+	keyPEM, err := readFile(r.entry.KeyFile, r.entry.Key)
+	if err != nil {
+		return tls.Certificate{}, newError("failed to read key").Base(err)
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// getReloadableGetCertificateFunc returns a GetCertificate callback that
+// prefers the reloadable certificates, and falls back to next. Without a
+// match, it returns no certificate, so that crypto/tls chooses one of
+// Config.Certificates.
+func getReloadableGetCertificateFunc(certs []*reloadableCertificate, next func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		for _, cert := range certs {
+			certificate := cert.get()
+			if hello.SupportsCertificate(certificate) == nil {
+				return certificate, nil
+			}
+		}
+		if next != nil {
+			return next(hello)
+		}
+		return nil, nil
+	}
+}
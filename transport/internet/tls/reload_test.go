@@ -0,0 +1,116 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/tls"
+	gonet "net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
+)
+
+func writeCertificateFile(filename string, content []byte, modTime time.Time) {
+	common.Must(os.WriteFile(filename, content, 0o600))
+	common.Must(os.Chtimes(filename, modTime, modTime))
+}
+
+func servedCertificate(t *testing.T, serverConfig *tls.Config, serverName string) []byte {
+	clientRaw, serverRaw := gonet.Pipe()
+	go func() {
+		tls.Server(serverRaw, serverConfig).Handshake()
+		serverRaw.Close()
+	}()
+	client := tls.Client(clientRaw, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	defer clientRaw.Close()
+	if err := client.Handshake(); err != nil {
+		t.Fatal("handshake failed: ", err)
+	}
+	return client.ConnectionState().PeerCertificates[0].Raw
+}
+
+func TestCertificateReload(t *testing.T) {
+	defer func(interval time.Duration) {
+		certificateCheckInterval = interval
+	}(certificateCheckInterval)
+	certificateCheckInterval = 0
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	oldCert := cert.MustGenerate(nil, cert.CommonName("www.v2fly.org"), cert.DNSNames("www.v2fly.org"))
+	newCert := cert.MustGenerate(nil, cert.CommonName("www.v2fly.org"), cert.DNSNames("www.v2fly.org"))
+	oldCertPEM, oldKeyPEM := oldCert.ToPEM()
+	newCertPEM, newKeyPEM := newCert.ToPEM()
+
+	modTime := time.Now().Add(-time.Hour)
+	writeCertificateFile(certFile, oldCertPEM, modTime)
+	writeCertificateFile(keyFile, oldKeyPEM, modTime)
+
+	serverConfig := (&Config{
+		Certificate: []*Certificate{{
+			Certificate:     oldCertPEM,
+			Key:             oldKeyPEM,
+			CertificateFile: certFile,
+			KeyFile:         keyFile,
+		}},
+	}).GetTLSConfig()
+
+	if !bytes.Equal(servedCertificate(t, serverConfig, "www.v2fly.org"), oldCert.Certificate) {
+		t.Fatal("unexpected certificate before reload")
+	}
+
+	// Only the certificate is renewed, the old pair is kept.
+	writeCertificateFile(certFile, newCertPEM, modTime.Add(time.Minute))
+	if !bytes.Equal(servedCertificate(t, serverConfig, "www.v2fly.org"), oldCert.Certificate) {
+		t.Error("expected old certificate with mismatched key")
+	}
+
+	writeCertificateFile(keyFile, newKeyPEM, modTime.Add(time.Minute))
+	if !bytes.Equal(servedCertificate(t, serverConfig, "www.v2fly.org"), newCert.Certificate) {
+		t.Error("expected new certificate after reload")
+	}
+}
+
+func TestCertificateReloadOtherName(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	reloadableCert := cert.MustGenerate(nil, cert.CommonName("www.v2fly.org"), cert.DNSNames("www.v2fly.org"))
+	otherCert := cert.MustGenerate(nil, cert.CommonName("www.example.com"), cert.DNSNames("www.example.com"))
+	reloadableCertPEM, reloadableKeyPEM := reloadableCert.ToPEM()
+	otherCertPEM, otherKeyPEM := otherCert.ToPEM()
+	writeCertificateFile(certFile, reloadableCertPEM, time.Now())
+	writeCertificateFile(keyFile, reloadableKeyPEM, time.Now())
+
+	serverConfig := (&Config{
+		Certificate: []*Certificate{
+			{
+				Certificate:     reloadableCertPEM,
+				Key:             reloadableKeyPEM,
+				CertificateFile: certFile,
+				KeyFile:         keyFile,
+			},
+			{
+				Certificate: otherCertPEM,
+				Key:         otherKeyPEM,
+			},
+		},
+	}).GetTLSConfig()
+
+	// Names of other certificates are left to crypto/tls.
+	if !bytes.Equal(servedCertificate(t, serverConfig, "www.example.com"), otherCert.Certificate) {
+		t.Error("expected the certificate of www.example.com")
+	}
+	if !bytes.Equal(servedCertificate(t, serverConfig, "www.v2fly.org"), reloadableCert.Certificate) {
+		t.Error("expected the reloadable certificate")
+	}
+}
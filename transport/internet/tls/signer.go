@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+)
+
+// SignerProvider provides private keys that are kept outside of V2Ray, e.g.
+// in a hardware security module. All private key operations are done by the
+// returned crypto.Signer.
+type SignerProvider interface {
+	// Signer returns the signer of the key with the given identifier.
+	Signer(keyID string) (crypto.Signer, error)
+}
+
+var (
+	signerProviders       = make(map[string]SignerProvider)
+	signerProvidersAccess sync.RWMutex
+)
+
+// RegisterSignerProvider registers a SignerProvider to be referred by name in
+// ExternalKey.
+//
+// v2ray:api:beta
+func RegisterSignerProvider(name string, provider SignerProvider) error {
+	signerProvidersAccess.Lock()
+	defer signerProvidersAccess.Unlock()
+
+	if _, found := signerProviders[name]; found {
+		return newError(name, " is already registered").AtError()
+	}
+	signerProviders[name] = provider
+	return nil
+}
+
+func getSignerProvider(name string) (SignerProvider, bool) {
+	signerProvidersAccess.RLock()
+	defer signerProvidersAccess.RUnlock()
+
+	provider, found := signerProviders[name]
+	return provider, found
+}
+
+// externalKeyPair builds a certificate whose private key is the signer of
+// key. The signer must match the public key of the leaf certificate.
+func externalKeyPair(certPEM []byte, key *ExternalKey) (tls.Certificate, error) {
+	var certificate tls.Certificate
+	for {
+		var block *pem.Block
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			certificate.Certificate = append(certificate.Certificate, block.Bytes)
+		}
+	}
+	if len(certificate.Certificate) == 0 {
+		return certificate, newError("no certificate found")
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return certificate, newError("failed to parse certificate").Base(err)
+	}
+
+	provider, found := getSignerProvider(key.Provider)
+	if !found {
+		return certificate, newError("unknown signer provider: ", key.Provider)
+	}
+	signer, err := provider.Signer(key.KeyId)
+	if err != nil {
+		return certificate, newError("failed to get signer from ", key.Provider).Base(err)
+	}
+	publicKey, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(signer.Public()) {
+		return certificate, newError("signer from ", key.Provider, " does not match the certificate")
+	}
+
+	certificate.PrivateKey = signer
+	certificate.Leaf = leaf
+	return certificate, nil
+}
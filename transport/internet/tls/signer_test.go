@@ -0,0 +1,121 @@
+package tls_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	gotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	gonet "net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+)
+
+type countingSigner struct {
+	key   *ecdsa.PrivateKey
+	count int32
+}
+
+func (s *countingSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *countingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	atomic.AddInt32(&s.count, 1)
+	return s.key.Sign(rand, digest, opts)
+}
+
+type testSignerProvider map[string]crypto.Signer
+
+func (p testSignerProvider) Signer(keyID string) (crypto.Signer, error) {
+	if signer, found := p[keyID]; found {
+		return signer, nil
+	}
+	return nil, io.EOF
+}
+
+func generateSignerCertificate(key *ecdsa.PrivateKey) []byte {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.v2fly.org"},
+		DNSNames:     []string{"www.v2fly.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	common.Must(err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestExternalSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	common.Must(err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	common.Must(err)
+
+	signer := &countingSigner{key: key}
+	common.Must(RegisterSignerProvider("test-signer", testSignerProvider{
+		"key":   signer,
+		"other": &countingSigner{key: otherKey},
+	}))
+	if err := RegisterSignerProvider("test-signer", testSignerProvider{}); err == nil {
+		t.Error("expected error on duplicate provider")
+	}
+
+	certPEM := generateSignerCertificate(key)
+	serverConfig := (&Config{
+		Certificate: []*Certificate{{
+			Certificate: certPEM,
+			ExternalKey: &ExternalKey{Provider: "test-signer", KeyId: "key"},
+		}},
+	}).GetTLSConfig()
+	if len(serverConfig.Certificates) != 1 {
+		t.Fatal("expected 1 certificate, but got ", len(serverConfig.Certificates))
+	}
+
+	clientRaw, serverRaw := gonet.Pipe()
+	server := gotls.Server(serverRaw, serverConfig)
+	go func() {
+		server.Handshake()
+		serverRaw.Close()
+	}()
+	client := gotls.Client(clientRaw, (&Config{
+		ServerName:    "www.v2fly.org",
+		AllowInsecure: true,
+	}).GetTLSConfig())
+	if err := client.Handshake(); err != nil {
+		t.Fatal("handshake failed: ", err)
+	}
+	clientRaw.Close()
+
+	if atomic.LoadInt32(&signer.count) == 0 {
+		t.Error("external signer is not used")
+	}
+
+	for _, key := range []*ExternalKey{
+		{Provider: "test-signer", KeyId: "other"},
+		{Provider: "test-signer", KeyId: "unknown"},
+		{Provider: "unknown", KeyId: "key"},
+	} {
+		config := &Config{
+			Certificate: []*Certificate{{
+				Certificate: certPEM,
+				ExternalKey: key,
+			}},
+		}
+		if certs := config.BuildCertificates(); len(certs) != 0 {
+			t.Error("expected invalid external key ", key.Provider, "/", key.KeyId, " to be ignored")
+		}
+	}
+}
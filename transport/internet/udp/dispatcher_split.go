@@ -33,7 +33,18 @@ type Dispatcher struct {
 	callback   ResponseCallback
 }
 
+// Close closes the connection to the destination, releasing the packets not
+// yet sent or received.
 func (v *Dispatcher) Close() error {
+	v.Lock()
+	defer v.Unlock()
+
+	if v.conn != nil {
+		v.conn.cancel()
+		common.Interrupt(v.conn.link.Reader)
+		common.Interrupt(v.conn.link.Writer)
+		v.conn = nil
+	}
 	return nil
 }
 
@@ -164,7 +175,7 @@ func (c *dispatcherConn) ReadFrom(p []byte) (int, net.Addr, error) {
 }
 
 func (c *dispatcherConn) WriteTo(p []byte, addr net.Addr) (int, error) {
-	buffer := buf.New()
+	buffer := buf.NewSize(buf.Size)
 	raw := buffer.Extend(buf.Size)
 	n := copy(raw, p)
 	buffer.Resize(0, int32(n))
@@ -8,6 +8,7 @@ import (
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol/udp"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
@@ -24,6 +25,14 @@ func (d *TestDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*t
 	return d.OnDispatch(ctx, dest)
 }
 
+func (*TestDispatcher) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
+	return errors.New("not implemented")
+}
+
+func (*TestDispatcher) DispatchConn(ctx context.Context, dest net.Destination, conn net.Conn, wait bool) error {
+	return errors.New("not implemented")
+}
+
 func (d *TestDispatcher) Start() error {
 	return nil
 }
@@ -84,3 +93,28 @@ func TestSameDestinationDispatching(t *testing.T) {
 		t.Error("msgCount: ", v)
 	}
 }
+
+func TestDispatcherClose(t *testing.T) {
+	uplinkReader, uplinkWriter := pipe.New(pipe.WithSizeLimit(1024))
+	downlinkReader, downlinkWriter := pipe.New(pipe.WithSizeLimit(1024))
+	defer downlinkWriter.Close()
+
+	td := &TestDispatcher{
+		OnDispatch: func(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+			return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+		},
+	}
+	dispatcher := NewSplitDispatcher(td, func(ctx context.Context, packet *udp.Packet) {
+		packet.Payload.Release()
+	})
+
+	b := buf.New()
+	b.WriteString("abcd")
+	dispatcher.Dispatch(context.Background(), net.UDPDestination(net.LocalHostIP, 53), b)
+	common.Must(dispatcher.Close())
+
+	// The connection to the destination ends without waiting for its timeout.
+	if _, err := uplinkReader.ReadMultiBufferTimeout(time.Second); err == nil {
+		t.Error("expected the uplink to be closed")
+	}
+}
@@ -82,7 +82,7 @@ func (h *Hub) start() {
 	oobBytes := make([]byte, 256)
 
 	for {
-		buffer := buf.New()
+		buffer := buf.NewSize(buf.Size)
 		var noob int
 		var addr *net.UDPAddr
 		rawBytes := buffer.Extend(buf.Size)
@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// bridgeForwarder dials WebSocket connections through a local browser bridge,
+// which completes them in the browser.
+type bridgeForwarder struct {
+	ctx      context.Context
+	endpoint string
+}
+
+// DialWebsocket implements extension.BrowserForwarder.
+func (f *bridgeForwarder) DialWebsocket(uri string, header http.Header) (io.ReadWriteCloser, error) {
+	endpoint, err := url.Parse(f.endpoint)
+	if err != nil {
+		return nil, newError("invalid browser bridge URL: ", f.endpoint).Base(err)
+	}
+	query := endpoint.Query()
+	query.Set("url", uri)
+	for k, v := range header {
+		// Browsers don't allow setting other headers of WebSocket requests.
+		if k != "Sec-Websocket-Protocol" {
+			return nil, newError("unsupported header used, only Sec-Websocket-Protocol is supported for browser bridge")
+		}
+		query.Set("protocol", v[0])
+	}
+	endpoint.RawQuery = query.Encode()
+
+	dialer := &websocket.Dialer{
+		ReadBufferSize:   4 * 1024,
+		WriteBufferSize:  4 * 1024,
+		HandshakeTimeout: time.Second * 8,
+	}
+	conn, resp, err := dialer.DialContext(f.ctx, endpoint.String(), nil) // nolint: bodyclose
+	if err != nil {
+		var reason string
+		if resp != nil {
+			reason = resp.Status
+		}
+		return nil, newError("failed to dial browser bridge (", f.endpoint, "): ", reason).Base(err)
+	}
+	return newConnection(conn, conn.RemoteAddr()), nil
+}
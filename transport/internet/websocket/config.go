@@ -1,13 +1,23 @@
 package websocket
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 )
 
-const protocolName = "websocket"
+const (
+	protocolName = "websocket"
+
+	defaultMaxEarlyDataSize = 2048
+)
 
 func (c *Config) GetNormalizedPath() string {
 	path := c.Path
@@ -20,14 +30,75 @@ func (c *Config) GetNormalizedPath() string {
 	return path
 }
 
+// earlyDataSizeLimit returns the largest early data the server accepts.
+func (c *Config) earlyDataSizeLimit() int {
+	if c.MaxEarlyDataSize > 0 {
+		return int(c.MaxEarlyDataSize)
+	}
+	if c.MaxEarlyData > defaultMaxEarlyDataSize {
+		return int(c.MaxEarlyData)
+	}
+	return defaultMaxEarlyDataSize
+}
+
+// GetRequestHeader returns the headers for a new request. Placeholders in
+// header values are expanded on every call, see expandHeaderValue.
 func (c *Config) GetRequestHeader() http.Header {
 	header := http.Header{}
 	for _, h := range c.Header {
-		header.Add(h.Key, h.Value)
+		header.Add(h.Key, expandHeaderValue(h.Value))
 	}
 	return header
 }
 
+var requestCounter uint64
+
+// expandHeaderValue replaces the following placeholders in value:
+//   - {{rand}}: 16 random hex digits
+//   - {{timestamp}}: current Unix time in seconds
+//   - {{counter}}: number of requests made so far by this process
+//
+// Unknown or unterminated placeholders are left as is.
+func expandHeaderValue(value string) string {
+	if !strings.Contains(value, "{{") {
+		return value
+	}
+
+	var builder strings.Builder
+	for {
+		start := strings.Index(value, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(value[start+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+
+		var expanded string
+		switch value[start+2 : end] {
+		case "rand":
+			var b [8]byte
+			common.Must2(rand.Read(b[:]))
+			expanded = hex.EncodeToString(b[:])
+		case "timestamp":
+			expanded = strconv.FormatInt(time.Now().Unix(), 10)
+		case "counter":
+			expanded = strconv.FormatUint(atomic.AddUint64(&requestCounter, 1), 10)
+		default:
+			builder.WriteString(value[:start+2])
+			value = value[start+2:]
+			continue
+		}
+		builder.WriteString(value[:start])
+		builder.WriteString(expanded)
+		value = value[end+2:]
+	}
+	builder.WriteString(value)
+	return builder.String()
+}
+
 func init() {
 	common.Must(internet.RegisterProtocolConfigCreator(protocolName, func() interface{} {
 		return new(Config)
@@ -0,0 +1,61 @@
+package websocket_test
+
+import (
+	"testing"
+
+	. "github.com/v2fly/v2ray-core/v5/transport/internet/websocket"
+)
+
+func TestRequestHeaderPlaceholder(t *testing.T) {
+	config := &Config{
+		Header: []*Header{
+			{Key: "X-Static", Value: "plain"},
+			{Key: "X-Rand", Value: "r-{{rand}}"},
+			{Key: "X-Counter", Value: "{{counter}}"},
+			{Key: "X-Time", Value: "{{timestamp}}"},
+		},
+	}
+
+	first := config.GetRequestHeader()
+	second := config.GetRequestHeader()
+
+	if v := first.Get("X-Static"); v != "plain" {
+		t.Error("static header changed: ", v)
+	}
+	for _, key := range []string{"X-Rand", "X-Counter"} {
+		if first.Get(key) == second.Get(key) {
+			t.Error(key, " is not expanded per request: ", first.Get(key))
+		}
+	}
+	if v := first.Get("X-Rand"); len(v) != len("r-")+16 {
+		t.Error("unexpected X-Rand value: ", v)
+	}
+	if v := first.Get("X-Time"); v == "" || v == "{{timestamp}}" {
+		t.Error("timestamp is not expanded: ", v)
+	}
+}
+
+func TestRequestHeaderMalformedPlaceholder(t *testing.T) {
+	for _, value := range []string{
+		"{{rand",
+		"rand}}",
+		"{{unknown}}",
+		"{{}}",
+		"{ {rand}}",
+	} {
+		config := &Config{
+			Header: []*Header{{Key: "X-Test", Value: value}},
+		}
+		if v := config.GetRequestHeader().Get("X-Test"); v != value {
+			t.Error("malformed placeholder ", value, " expanded to ", v)
+		}
+	}
+
+	config := &Config{
+		Header: []*Header{{Key: "X-Test", Value: "{{unknown}}-{{rand}}"}},
+	}
+	v := config.GetRequestHeader().Get("X-Test")
+	if len(v) != len("{{unknown}}-")+16 || v[:len("{{unknown}}-")] != "{{unknown}}-" {
+		t.Error("unexpected expansion: ", v)
+	}
+}
@@ -40,16 +40,17 @@ func dialWebsocket(ctx context.Context, dest net.Destination, streamSettings *in
 		NetDial: func(network, addr string) (net.Conn, error) {
 			return internet.DialSystem(ctx, dest, streamSettings.SocketSettings)
 		},
-		ReadBufferSize:   4 * 1024,
-		WriteBufferSize:  4 * 1024,
-		HandshakeTimeout: time.Second * 8,
+		ReadBufferSize:    4 * 1024,
+		WriteBufferSize:   4 * 1024,
+		HandshakeTimeout:  time.Second * 8,
+		EnableCompression: wsSettings.EnableCompression,
 	}
 
 	protocol := "ws"
 
 	if config := tls.ConfigFromStreamSettings(streamSettings); config != nil {
 		protocol = "wss"
-		dialer.TLSClientConfig = config.GetTLSConfig(tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
+		dialer.TLSClientConfig = config.GetTLSConfigWithContext(ctx, tls.WithDestination(dest), tls.WithNextProto("http/1.1"))
 	}
 
 	host := dest.NetAddr()
@@ -58,13 +59,17 @@ func dialWebsocket(ctx context.Context, dest net.Destination, streamSettings *in
 	}
 	uri := protocol + "://" + host + wsSettings.GetNormalizedPath()
 
-	if wsSettings.UseBrowserForwarding {
+	if wsSettings.UseBrowserForwarding || wsSettings.BrowserBridgeUrl != "" {
 		var forwarder extension.BrowserForwarder
-		err := core.RequireFeatures(ctx, func(Forwarder extension.BrowserForwarder) {
-			forwarder = Forwarder
-		})
-		if err != nil {
-			return nil, newError("cannot find browser forwarder service").Base(err)
+		if wsSettings.BrowserBridgeUrl != "" {
+			forwarder = &bridgeForwarder{ctx: ctx, endpoint: wsSettings.BrowserBridgeUrl}
+		} else {
+			err := core.RequireFeatures(ctx, func(Forwarder extension.BrowserForwarder) {
+				forwarder = Forwarder
+			})
+			if err != nil {
+				return nil, newError("cannot find browser forwarder service").Base(err)
+			}
 		}
 		if wsSettings.MaxEarlyData != 0 {
 			return newRelayedConnectionWithDelayedDial(&dialerWithEarlyDataRelayed{
@@ -23,8 +23,10 @@ import (
 type requestHandler struct {
 	path                string
 	ln                  *Listener
+	upgrader            *websocket.Upgrader
 	earlyDataEnabled    bool
 	earlyDataHeaderName string
+	maxEarlyDataSize    int
 }
 
 var upgrader = &websocket.Upgrader{
@@ -37,7 +39,7 @@ var upgrader = &websocket.Upgrader{
 }
 
 func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	var earlyData io.Reader
+	var earlyDataStr string
 	if !h.earlyDataEnabled { // nolint: gocritic
 		if request.URL.Path != h.path {
 			writer.WriteHeader(http.StatusNotFound)
@@ -48,19 +50,27 @@ func (h *requestHandler) ServeHTTP(writer http.ResponseWriter, request *http.Req
 			writer.WriteHeader(http.StatusNotFound)
 			return
 		}
-		earlyDataStr := request.Header.Get(h.earlyDataHeaderName)
-		earlyData = base64.NewDecoder(base64.RawURLEncoding, bytes.NewReader([]byte(earlyDataStr)))
+		earlyDataStr = request.Header.Get(h.earlyDataHeaderName)
 	} else {
 		if strings.HasPrefix(request.URL.RequestURI(), h.path) {
-			earlyDataStr := request.URL.RequestURI()[len(h.path):]
-			earlyData = base64.NewDecoder(base64.RawURLEncoding, bytes.NewReader([]byte(earlyDataStr)))
+			earlyDataStr = request.URL.RequestURI()[len(h.path):]
 		} else {
 			writer.WriteHeader(http.StatusNotFound)
 			return
 		}
 	}
 
-	conn, err := upgrader.Upgrade(writer, request, nil)
+	var earlyData io.Reader
+	if h.earlyDataEnabled {
+		if size := base64.RawURLEncoding.DecodedLen(len(earlyDataStr)); size > h.maxEarlyDataSize {
+			newError("rejected WebSocket request with ", size, " bytes of early data, limit is ", h.maxEarlyDataSize).AtWarning().WriteToLog()
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		earlyData = base64.NewDecoder(base64.RawURLEncoding, bytes.NewReader([]byte(earlyDataStr)))
+	}
+
+	conn, err := h.upgrader.Upgrade(writer, request, nil)
 	if err != nil {
 		newError("failed to convert to WebSocket connection").Base(err).WriteToLog()
 		return
@@ -146,12 +156,17 @@ func ListenWS(ctx context.Context, address net.Address, port net.Port, streamSet
 		earlyDataHeaderName = wsSettings.EarlyDataHeaderName
 	}
 
+	wsUpgrader := *upgrader
+	wsUpgrader.EnableCompression = wsSettings.EnableCompression
+
 	l.server = http.Server{
 		Handler: &requestHandler{
 			path:                wsSettings.GetNormalizedPath(),
 			ln:                  l,
+			upgrader:            &wsUpgrader,
 			earlyDataEnabled:    useEarlyData,
 			earlyDataHeaderName: earlyDataHeaderName,
+			maxEarlyDataSize:    wsSettings.earlyDataSizeLimit(),
 		},
 		ReadHeaderTimeout: time.Second * 4,
 		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
@@ -1,11 +1,19 @@
 package websocket_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
@@ -146,3 +154,252 @@ func Test_listenWSAndDial_TLS(t *testing.T) {
 		t.Error("end: ", end, " start: ", start)
 	}
 }
+
+func TestEarlyDataSizeLimit(t *testing.T) {
+	received := make(chan string, 1)
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, 13150, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:             "ws",
+			MaxEarlyData:     1,
+			MaxEarlyDataSize: 16,
+		},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+
+			// The client closes the connection right after the handshake.
+			b, _ := io.ReadAll(c)
+			received <- string(b)
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	for _, size := range []int{15, 16, 17} {
+		payload := strings.Repeat("a", size)
+		uri := "ws://127.0.0.1:13150/ws" + base64.RawURLEncoding.EncodeToString([]byte(payload))
+		conn, resp, err := websocket.DefaultDialer.Dial(uri, nil) // nolint: bodyclose
+		if size > 16 {
+			if err == nil {
+				conn.Close()
+				t.Fatal("expect early data of ", size, " bytes to be rejected")
+			}
+			if resp == nil || resp.StatusCode != http.StatusBadRequest {
+				t.Fatal("unexpected response for oversized early data: ", resp)
+			}
+			continue
+		}
+		common.Must(err)
+		conn.Close()
+		if r := <-received; r != payload {
+			t.Error("early data of ", size, " bytes: got ", r)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	const port = 13151
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:              "ws",
+			EnableCompression: true,
+		},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+			io.Copy(c, c)
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	wsConn, resp, err := websocket.DefaultDialer.Dial("ws://127.0.0.1:13151/ws", http.Header{
+		"Sec-WebSocket-Extensions": []string{"permessage-deflate; server_no_context_takeover; client_no_context_takeover"},
+	}) // nolint: bodyclose
+	common.Must(err)
+	wsConn.Close()
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Error("compression is not negotiated: ", ext)
+	}
+
+	compressible := []byte(strings.Repeat("v2ray compression test ", 1024))
+	incompressible := make([]byte, 8192)
+	common.Must2(rand.Read(incompressible))
+
+	for _, clientCompression := range []bool{true, false} {
+		conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+			ProtocolName: "websocket",
+			ProtocolSettings: &Config{
+				Path:              "ws",
+				EnableCompression: clientCompression,
+			},
+		})
+		common.Must(err)
+
+		for _, payload := range [][]byte{compressible, incompressible} {
+			common.Must2(conn.Write(payload))
+			b := make([]byte, len(payload))
+			common.Must2(io.ReadFull(conn, b))
+			if !bytes.Equal(b, payload) {
+				t.Error("payload mismatch, client compression: ", clientCompression)
+			}
+		}
+		common.Must(conn.Close())
+	}
+}
+
+func TestCompressionFallback(t *testing.T) {
+	const port = 13152
+	listen, err := ListenWS(context.Background(), net.LocalHostIP, port, &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path: "ws",
+		},
+	}, func(conn internet.Connection) {
+		go func(c internet.Connection) {
+			defer c.Close()
+			io.Copy(c, c)
+		}(conn)
+	})
+	common.Must(err)
+	defer listen.Close()
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, port), &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:              "ws",
+			EnableCompression: true,
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	payload := []byte(strings.Repeat("a", 4096))
+	common.Must2(conn.Write(payload))
+	b := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, b))
+	if !bytes.Equal(b, payload) {
+		t.Error("payload mismatch")
+	}
+}
+
+// startEchoBridge starts a browser bridge which echoes messages instead of
+// relaying them, and reports the query of each request.
+func startEchoBridge(t *testing.T) (string, <-chan url.Values) {
+	queries := make(chan url.Values, 4)
+	upgrader := websocket.Upgrader{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := upgrader.Upgrade(writer, request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		query := request.URL.Query()
+		queries <- query
+
+		// The echoed early data comes first.
+		if protocol := query.Get("protocol"); protocol != "" {
+			earlyData, err := base64.RawURLEncoding.DecodeString(protocol)
+			if err != nil || conn.WriteMessage(websocket.BinaryMessage, earlyData) != nil {
+				return
+			}
+		}
+		for {
+			messageType, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, b); err != nil {
+				return
+			}
+		}
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return "ws://" + listener.Addr().String() + "/dial", queries
+}
+
+func TestDialBrowserBridge(t *testing.T) {
+	bridge, queries := startEchoBridge(t)
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.DomainAddress("example.com"), 80), &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:             "ws",
+			BrowserBridgeUrl: bridge,
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	payload := []byte("Test browser bridge")
+	common.Must2(conn.Write(payload))
+	b := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, b))
+	if !bytes.Equal(b, payload) {
+		t.Error("payload mismatch: ", string(b))
+	}
+	query := <-queries
+	if u := query.Get("url"); u != "ws://example.com/ws" {
+		t.Error("unexpected URL for the bridge: ", u)
+	}
+	if p := query.Get("protocol"); p != "" {
+		t.Error("unexpected protocol for the bridge: ", p)
+	}
+}
+
+func TestDialBrowserBridgeWithEarlyData(t *testing.T) {
+	bridge, queries := startEchoBridge(t)
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, 13153), &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:                "ws",
+			MaxEarlyData:        2048,
+			EarlyDataHeaderName: "Sec-WebSocket-Protocol",
+			BrowserBridgeUrl:    bridge,
+		},
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	payload := []byte("Test early data")
+	common.Must2(conn.Write(payload))
+	b := make([]byte, len(payload))
+	common.Must2(io.ReadFull(conn, b))
+	if !bytes.Equal(b, payload) {
+		t.Error("payload mismatch: ", string(b))
+	}
+	query := <-queries
+	if u := query.Get("url"); u != "ws://127.0.0.1:13153/ws" {
+		t.Error("unexpected URL for the bridge: ", u)
+	}
+	if p := query.Get("protocol"); p != base64.RawURLEncoding.EncodeToString(payload) {
+		t.Error("unexpected protocol for the bridge: ", p)
+	}
+}
+
+func TestDialBrowserBridgeUnsupportedHeader(t *testing.T) {
+	bridge, _ := startEchoBridge(t)
+
+	conn, err := Dial(context.Background(), net.TCPDestination(net.LocalHostIP, 13153), &internet.MemoryStreamConfig{
+		ProtocolName: "websocket",
+		ProtocolSettings: &Config{
+			Path:             "ws",
+			Header:           []*Header{{Key: "User-Agent", Value: "v2ray"}},
+			MaxEarlyData:     2048,
+			BrowserBridgeUrl: bridge,
+		},
+	})
+	common.Must(err)
+
+	// The connection is dialed with the early data.
+	if _, err := conn.Write([]byte("Test")); err == nil {
+		conn.Close()
+		t.Error("expected an error of unsupported headers")
+	}
+}
@@ -130,16 +130,25 @@ func addInboundHandlers(server *Instance, configs []*InboundHandlerConfig) error
 	return nil
 }
 
-func AddOutboundHandler(server *Instance, config *OutboundHandlerConfig) error {
-	outboundManager := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+// CreateOutboundHandler creates an outbound handler from config without adding it to the outbound manager.
+func CreateOutboundHandler(server *Instance, config *OutboundHandlerConfig) (outbound.Handler, error) {
 	proxyEnv := server.env.ProxyEnvironment("o" + config.Tag)
 	rawHandler, err := CreateObjectWithEnvironment(server, config, proxyEnv)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	handler, ok := rawHandler.(outbound.Handler)
 	if !ok {
-		return newError("not an OutboundHandler")
+		return nil, newError("not an OutboundHandler")
+	}
+	return handler, nil
+}
+
+func AddOutboundHandler(server *Instance, config *OutboundHandlerConfig) error {
+	outboundManager := server.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	handler, err := CreateOutboundHandler(server, config)
+	if err != nil {
+		return err
 	}
 	if err := outboundManager.AddHandler(server.ctx, handler); err != nil {
 		return err